@@ -0,0 +1,223 @@
+package gitlabapimock_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xanzy/go-gitlab"
+
+	gitlabapimock "github.com/arkadiusjonczek/go-gitlab-api-mock"
+)
+
+func Test_Approvals_ProjectApprovalRule_RequiresEligibleApprovers(t *testing.T) {
+	gitlabMock := gitlabapimock.NewGitlabMock()
+	defer gitlabMock.Close()
+
+	user1, _ := gitlabMock.AddUser("Peter Pan", "peter.pan", "peter.pan@telekom.de")
+	user2, _ := gitlabMock.AddUser("Petra Pan", "petra.pan", "petra.pan@telekom.de")
+	user3, _ := gitlabMock.AddUser("Captain Hook", "captain.hook", "captain.hook@telekom.de")
+	user4, _ := gitlabMock.AddUser("Tinker Bell", "tinker.bell", "tinker.bell@telekom.de")
+	gitlabMock.AddPersonalAccessToken(user1, "token1", "token1", []string{"api"})
+	gitlabMock.AddPersonalAccessToken(user2, "token2", "token2", []string{"api"})
+	gitlabMock.AddPersonalAccessToken(user3, "token3", "token3", []string{"api"})
+	gitlabMock.AddPersonalAccessToken(user4, "token4", "token4", []string{"api"})
+
+	group1 := gitlabMock.AddGroup("group1")
+	security := gitlabMock.AddGroup("security")
+	gitlabMock.AddGroupMember(&gitlab.GroupMember{ID: user3.ID, AccessLevel: gitlab.DeveloperPermissions}, security)
+	gitlabMock.AddGroupMember(&gitlab.GroupMember{ID: user4.ID, AccessLevel: gitlab.DeveloperPermissions}, security)
+
+	project1 := gitlabMock.AddProject("project1", group1)
+	gitlabMock.AddProjectMember(&gitlab.ProjectMember{ID: user1.ID, AccessLevel: gitlab.DeveloperPermissions}, project1)
+	gitlabMock.AddProjectMember(&gitlab.ProjectMember{ID: user2.ID, AccessLevel: gitlab.MaintainerPermissions}, project1)
+	gitlabMock.AddProjectMember(&gitlab.ProjectMember{ID: user3.ID, AccessLevel: gitlab.ReporterPermissions}, project1)
+	gitlabMock.AddProjectMember(&gitlab.ProjectMember{ID: user4.ID, AccessLevel: gitlab.DeveloperPermissions}, project1)
+
+	_, err := gitlabMock.CommitFiles(project1, "main", "Initial commit", map[string]string{"README.md": "# project1\n"}, nil)
+	require.NoError(t, err)
+	_, err = gitlabMock.CommitFiles(project1, "feature1", "Add feature1", map[string]string{"feature1.txt": "feature1\n"}, user1)
+	require.NoError(t, err)
+
+	server := initGitlabApiMockServer(gitlabMock)
+
+	go server.ListenAndServe()
+	defer server.Close()
+
+	time.Sleep(1 * time.Second)
+
+	gitlabClient1, err := initGitlabClientWithToken("token1")
+	require.NoError(t, err)
+	gitlabClient2, err := initGitlabClientWithToken("token2")
+	require.NoError(t, err)
+	gitlabClient3, err := initGitlabClientWithToken("token3")
+	require.NoError(t, err)
+	gitlabClient4, err := initGitlabClientWithToken("token4")
+	require.NoError(t, err)
+
+	createProjectLevelRuleOptions := &gitlab.CreateProjectLevelRuleOptions{
+		Name:              gitlab.Ptr("Security"),
+		ApprovalsRequired: gitlab.Ptr(1),
+		GroupIDs:          &[]int{security.ID},
+	}
+
+	_, response, err := gitlabClient1.Projects.CreateProjectApprovalRule(1, createProjectLevelRuleOptions)
+
+	require.Error(t, err)
+	require.Equal(t, 403, response.StatusCode)
+
+	rule, response, err := gitlabClient2.Projects.CreateProjectApprovalRule(1, createProjectLevelRuleOptions)
+
+	require.NoError(t, err)
+	require.Equal(t, 201, response.StatusCode)
+	require.Equal(t, "regular", rule.RuleType)
+	require.Len(t, rule.EligibleApprovers, 1)
+	require.Equal(t, "tinker.bell", rule.EligibleApprovers[0].Username)
+
+	_, response, err = gitlabClient2.Projects.CreateProjectApprovalRule(1, createProjectLevelRuleOptions)
+
+	require.Error(t, err)
+	require.Equal(t, 400, response.StatusCode)
+
+	mergeRequest, _, err := gitlabClient1.MergeRequests.CreateMergeRequest(1, &gitlab.CreateMergeRequestOptions{
+		Title:        gitlab.Ptr("Add feature1"),
+		SourceBranch: gitlab.Ptr("feature1"),
+		TargetBranch: gitlab.Ptr("main"),
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, "not_approved", mergeRequest.DetailedMergeStatus)
+
+	approvals, _, err := gitlabClient2.MergeRequestApprovals.ApproveMergeRequest(1, mergeRequest.IID, nil)
+
+	require.NoError(t, err)
+	require.False(t, approvals.Approved)
+	require.Equal(t, 1, approvals.ApprovalsLeft)
+	require.Len(t, approvals.ApprovalRulesLeft, 1)
+
+	_, response, err = gitlabClient3.MergeRequestApprovals.ApproveMergeRequest(1, mergeRequest.IID, nil)
+
+	require.Error(t, err)
+	require.Equal(t, 401, response.StatusCode)
+
+	approvals, _, err = gitlabClient4.MergeRequestApprovals.ApproveMergeRequest(1, mergeRequest.IID, nil)
+
+	require.NoError(t, err)
+	require.True(t, approvals.Approved)
+	require.Equal(t, 0, approvals.ApprovalsLeft)
+
+	approvalState, _, err := gitlabClient1.MergeRequestApprovals.GetApprovalState(1, mergeRequest.IID)
+
+	require.NoError(t, err)
+	require.False(t, approvalState.ApprovalRulesOverwritten)
+	require.Len(t, approvalState.Rules, 1)
+	require.True(t, approvalState.Rules[0].Approved)
+	require.Equal(t, rule.ID, approvalState.Rules[0].SourceRule.ID)
+	require.Len(t, approvalState.Rules[0].ApprovedBy, 1)
+	require.Equal(t, "tinker.bell", approvalState.Rules[0].ApprovedBy[0].Username)
+
+	mergeRequest, _, err = gitlabClient1.MergeRequests.GetMergeRequest(1, mergeRequest.IID, nil)
+
+	require.NoError(t, err)
+	require.Equal(t, "mergeable", mergeRequest.DetailedMergeStatus)
+
+	_, err = gitlabClient4.MergeRequestApprovals.UnapproveMergeRequest(1, mergeRequest.IID)
+
+	require.NoError(t, err)
+
+	mergeRequest, _, err = gitlabClient1.MergeRequests.GetMergeRequest(1, mergeRequest.IID, nil)
+
+	require.NoError(t, err)
+	require.Equal(t, "not_approved", mergeRequest.DetailedMergeStatus)
+}
+
+func Test_Approvals_MergeRequestApprovalRule_OverwritesProjectRules(t *testing.T) {
+	gitlabMock := gitlabapimock.NewGitlabMock()
+	defer gitlabMock.Close()
+
+	user1, _ := gitlabMock.AddUser("Peter Pan", "peter.pan", "peter.pan@telekom.de")
+	user2, _ := gitlabMock.AddUser("Petra Pan", "petra.pan", "petra.pan@telekom.de")
+
+	group1 := gitlabMock.AddGroup("group1")
+	gitlabMock.AddGroupMember(&gitlab.GroupMember{ID: user1.ID, AccessLevel: gitlab.DeveloperPermissions}, group1)
+	gitlabMock.AddGroupMember(&gitlab.GroupMember{ID: user2.ID, AccessLevel: gitlab.MaintainerPermissions}, group1)
+
+	project1 := gitlabMock.AddProject("project1", group1)
+
+	_, err := gitlabMock.CommitFiles(project1, "main", "Initial commit", map[string]string{"README.md": "# project1\n"}, nil)
+	require.NoError(t, err)
+	_, err = gitlabMock.CommitFiles(project1, "feature1", "Add feature1", map[string]string{"feature1.txt": "feature1\n"}, user1)
+	require.NoError(t, err)
+
+	_, err = gitlabMock.AddProjectApprovalRule(project1, &gitlab.CreateProjectLevelRuleOptions{
+		Name:              gitlab.Ptr("All Members"),
+		RuleType:          gitlab.Ptr("any_approver"),
+		ApprovalsRequired: gitlab.Ptr(1),
+	})
+	require.NoError(t, err)
+
+	mergeRequest, err := gitlabMock.AddMergeRequest(project1, &gitlab.CreateMergeRequestOptions{
+		Title:        gitlab.Ptr("Add feature1"),
+		SourceBranch: gitlab.Ptr("feature1"),
+		TargetBranch: gitlab.Ptr("main"),
+	}, user1)
+	require.NoError(t, err)
+
+	server := initGitlabApiMockServer(gitlabMock)
+
+	go server.ListenAndServe()
+	defer server.Close()
+
+	time.Sleep(1 * time.Second)
+
+	gitlabClient, err := initGitlabClient()
+
+	require.NoError(t, err)
+
+	rules, _, err := gitlabClient.MergeRequestApprovals.GetApprovalRules(1, mergeRequest.IID)
+
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	require.Equal(t, "any_approver", rules[0].RuleType)
+	require.Len(t, rules[0].EligibleApprovers, 2)
+
+	rule, response, err := gitlabClient.MergeRequestApprovals.CreateApprovalRule(1, mergeRequest.IID, &gitlab.CreateMergeRequestApprovalRuleOptions{
+		Name:              gitlab.Ptr("Maintainer"),
+		ApprovalsRequired: gitlab.Ptr(1),
+		UserIDs:           &[]int{user2.ID},
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, 201, response.StatusCode)
+	require.Len(t, rule.EligibleApprovers, 1)
+	require.Equal(t, "petra.pan", rule.EligibleApprovers[0].Username)
+
+	rule, _, err = gitlabClient.MergeRequestApprovals.UpdateApprovalRule(1, mergeRequest.IID, rule.ID, &gitlab.UpdateMergeRequestApprovalRuleOptions{
+		ApprovalsRequired: gitlab.Ptr(2),
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, 2, rule.ApprovalsRequired)
+
+	approvals, _, err := gitlabClient.MergeRequestApprovals.GetConfiguration(1, mergeRequest.IID)
+
+	require.NoError(t, err)
+	require.Equal(t, 3, approvals.ApprovalsRequired)
+
+	response, err = gitlabClient.MergeRequestApprovals.DeleteApprovalRule(1, mergeRequest.IID, rules[0].ID)
+
+	require.NoError(t, err)
+	require.Equal(t, 204, response.StatusCode)
+
+	approvalState, _, err := gitlabClient.MergeRequestApprovals.GetApprovalState(1, mergeRequest.IID)
+
+	require.NoError(t, err)
+	require.True(t, approvalState.ApprovalRulesOverwritten)
+	require.Len(t, approvalState.Rules, 1)
+	require.Equal(t, "Maintainer", approvalState.Rules[0].Name)
+
+	projectRules, _, err := gitlabClient.Projects.GetProjectApprovalRules(1, nil)
+
+	require.NoError(t, err)
+	require.Len(t, projectRules, 1)
+}
@@ -0,0 +1,225 @@
+package gitlabapimock_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xanzy/go-gitlab"
+
+	gitlabapimock "github.com/arkadiusjonczek/go-gitlab-api-mock"
+)
+
+func Test_Artifacts_UploadAndDownload_ExtractsFilesAndExpires(t *testing.T) {
+	gitlabMock := gitlabapimock.NewGitlabMock()
+	defer gitlabMock.Close()
+
+	group1 := gitlabMock.AddGroup("group1")
+	project1 := gitlabMock.AddProject("project1", group1)
+
+	_, err := gitlabMock.CommitFiles(project1, "main", "Initial commit", map[string]string{"README.md": "# project1\n"}, nil)
+	require.NoError(t, err)
+
+	gitlabMock.SetPipelineJobs(project1, []gitlabapimock.PipelineJob{
+		{Name: "build", Stage: "build"},
+		{Name: "test", Stage: "test"},
+	})
+
+	pipeline, err := gitlabMock.AddPipeline(project1, "main", nil, nil)
+	require.NoError(t, err)
+
+	build, _ := gitlabMock.GetPipelineJob(pipeline, "build")
+	require.NoError(t, gitlabMock.AdvanceJob(build))
+
+	gitlabMock.AppendJobTrace(build, "$ make\n")
+	gitlabMock.AppendJobTrace(build, "Build succeeded\n")
+
+	require.NoError(t, gitlabMock.AddJobArtifacts(build, map[string]string{
+		"bin/app":         "binary",
+		"reports/cov.txt": "coverage: 87%\n",
+	}, "1 week"))
+	require.NoError(t, gitlabMock.AdvanceJob(build))
+
+	server := initGitlabApiMockServer(gitlabMock)
+
+	go server.ListenAndServe()
+	defer server.Close()
+
+	time.Sleep(1 * time.Second)
+
+	gitlabClient, err := initGitlabClient()
+
+	require.NoError(t, err)
+
+	trace, _, err := gitlabClient.Jobs.GetTraceFile(project1.ID, build.ID)
+
+	require.NoError(t, err)
+
+	traceContent, _ := io.ReadAll(trace)
+	require.Equal(t, "$ make\nBuild succeeded\n", string(traceContent))
+
+	job, _, err := gitlabClient.Jobs.GetJob(project1.ID, build.ID)
+
+	require.NoError(t, err)
+	require.Equal(t, "artifacts.zip", job.ArtifactsFile.Filename)
+	require.WithinDuration(t, time.Now().Add(7*24*time.Hour), *job.ArtifactsExpireAt, time.Minute)
+
+	archive, _, err := gitlabClient.Jobs.GetJobArtifacts(project1.ID, build.ID)
+
+	require.NoError(t, err)
+
+	archiveReader, err := zip.NewReader(archive, archive.Size())
+	require.NoError(t, err)
+	require.Len(t, archiveReader.File, 2)
+
+	file, _, err := gitlabClient.Jobs.DownloadSingleArtifactsFile(project1.ID, build.ID, "reports/cov.txt")
+
+	require.NoError(t, err)
+
+	fileContent, _ := io.ReadAll(file)
+	require.Equal(t, "coverage: 87%\n", string(fileContent))
+
+	_, response, err := gitlabClient.Jobs.DownloadSingleArtifactsFile(project1.ID, build.ID, "reports/missing.txt")
+
+	require.Error(t, err)
+	require.Equal(t, 404, response.StatusCode)
+
+	// The artifacts of a ref are only available from successful pipelines.
+	_, response, err = gitlabClient.Jobs.DownloadArtifactsFile(project1.ID, "main", &gitlab.DownloadArtifactsFileOptions{Job: gitlab.Ptr("build")})
+
+	require.Error(t, err)
+	require.Equal(t, 404, response.StatusCode)
+
+	// Runners upload artifacts with the token of the running job.
+	testJob, _ := gitlabMock.GetPipelineJob(pipeline, "test")
+
+	require.Equal(t, 403, uploadArtifacts(t, testJob.ID, gitlabMock.GetJobToken(testJob), "1 day").StatusCode)
+
+	require.NoError(t, gitlabMock.AdvanceJob(testJob))
+
+	require.Equal(t, 403, uploadArtifacts(t, testJob.ID, "invalid", "1 day").StatusCode)
+	require.Equal(t, 201, uploadArtifacts(t, testJob.ID, gitlabMock.GetJobToken(testJob), "1 day").StatusCode)
+	require.Equal(t, "artifacts.zip", testJob.ArtifactsFile.Filename)
+
+	require.NoError(t, gitlabMock.AdvanceJob(testJob))
+
+	archive, _, err = gitlabClient.Jobs.DownloadArtifactsFile(project1.ID, "main", &gitlab.DownloadArtifactsFileOptions{Job: gitlab.Ptr("build")})
+
+	require.NoError(t, err)
+	require.Positive(t, archive.Size())
+
+	file, _, err = gitlabClient.Jobs.DownloadSingleArtifactsFileByTagOrBranch(project1.ID, "main", "bin/app", &gitlab.DownloadArtifactsFileOptions{Job: gitlab.Ptr("build")})
+
+	require.NoError(t, err)
+
+	fileContent, _ = io.ReadAll(file)
+	require.Equal(t, "binary", string(fileContent))
+
+	// Expired artifacts are removed, kept artifacts never expire.
+	require.NoError(t, gitlabMock.AddJobArtifacts(testJob, map[string]string{"junit.xml": "<testsuites/>"}, "1 hour"))
+
+	job, _, err = gitlabClient.Jobs.KeepArtifacts(project1.ID, build.ID)
+
+	require.NoError(t, err)
+	require.Nil(t, job.ArtifactsExpireAt)
+
+	gitlabMock.AdvanceClock(2 * time.Hour)
+
+	_, response, err = gitlabClient.Jobs.GetJobArtifacts(project1.ID, testJob.ID)
+
+	require.Error(t, err)
+	require.Equal(t, 404, response.StatusCode)
+
+	job, _, err = gitlabClient.Jobs.GetJob(project1.ID, testJob.ID)
+
+	require.NoError(t, err)
+	require.Empty(t, job.ArtifactsFile.Filename)
+}
+
+func uploadArtifacts(t *testing.T, jobID int, token string, expireIn string) *http.Response {
+	archive := new(bytes.Buffer)
+
+	archiveWriter := zip.NewWriter(archive)
+	fileWriter, err := archiveWriter.Create("junit.xml")
+	require.NoError(t, err)
+	_, err = fileWriter.Write([]byte("<testsuites/>"))
+	require.NoError(t, err)
+	require.NoError(t, archiveWriter.Close())
+
+	body := new(bytes.Buffer)
+
+	writer := multipart.NewWriter(body)
+	require.NoError(t, writer.WriteField("expire_in", expireIn))
+	formFile, err := writer.CreateFormFile("file", "artifacts.zip")
+	require.NoError(t, err)
+	_, err = formFile.Write(archive.Bytes())
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	request, err := http.NewRequest(http.MethodPost, fmt.Sprintf("http://%s/api/v4/jobs/%d/artifacts", GitlabHost, jobID), body)
+	require.NoError(t, err)
+
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+	request.Header.Set("JOB-TOKEN", token)
+
+	response, err := http.DefaultClient.Do(request)
+	require.NoError(t, err)
+	defer response.Body.Close()
+
+	return response
+}
+
+func Test_Artifacts_JobToken_OnlyAcceptedByJobRoutes(t *testing.T) {
+	gitlabMock := gitlabapimock.NewGitlabMock()
+	defer gitlabMock.Close()
+
+	user1, _ := gitlabMock.AddUser("Peter Pan", "peter.pan", "peter.pan@telekom.de")
+	gitlabMock.AddPersonalAccessToken(user1, "token1", "token1", []string{"api"})
+
+	group1 := gitlabMock.AddGroup("group1")
+	project1 := gitlabMock.AddProject("project1", group1)
+	gitlabMock.AddProjectMember(&gitlab.ProjectMember{ID: user1.ID, AccessLevel: gitlab.MaintainerPermissions}, project1)
+
+	_, err := gitlabMock.CommitFiles(project1, "main", "Initial commit", map[string]string{"README.md": "# project1\n"}, user1)
+	require.NoError(t, err)
+
+	pipeline, err := gitlabMock.AddPipeline(project1, "main", []gitlabapimock.PipelineJob{{Name: "build", Stage: "build"}}, user1)
+	require.NoError(t, err)
+
+	build, _ := gitlabMock.GetPipelineJob(pipeline, "build")
+	require.NoError(t, gitlabMock.AdvanceJob(build))
+
+	server := initGitlabApiMockServer(gitlabMock)
+
+	go server.ListenAndServe()
+	defer server.Close()
+
+	time.Sleep(1 * time.Second)
+
+	jobClient, err := gitlab.NewJobClient(gitlabMock.GetJobToken(build), gitlab.WithBaseURL(fmt.Sprintf("http://%s", GitlabHost)))
+	require.NoError(t, err)
+
+	job, _, err := jobClient.Jobs.GetJob(project1.ID, build.ID)
+
+	require.NoError(t, err)
+	require.Equal(t, "build", job.Name)
+
+	_, response, err := jobClient.Projects.GetProject(project1.ID, nil)
+
+	require.Error(t, err)
+	require.Equal(t, 401, response.StatusCode)
+
+	// The token of a finished job is rejected.
+	require.NoError(t, gitlabMock.AdvanceJob(build))
+
+	_, response, err = jobClient.Jobs.GetJob(project1.ID, build.ID)
+
+	require.Error(t, err)
+	require.Equal(t, 401, response.StatusCode)
+}
@@ -0,0 +1,259 @@
+package gitlabapimock_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xanzy/go-gitlab"
+
+	gitlabapimock "github.com/arkadiusjonczek/go-gitlab-api-mock"
+)
+
+const ciConfig = `
+stages:
+  - build
+  - test
+  - deploy
+
+workflow:
+  rules:
+    - if: $CI_COMMIT_BRANCH =~ /^wip-/
+      when: never
+    - when: always
+
+variables:
+  DEPLOY_TARGET: staging
+
+.rules-default-branch:
+  rules:
+    - if: $CI_COMMIT_BRANCH == $CI_DEFAULT_BRANCH
+      when: manual
+
+compile:
+  stage: build
+  script: make
+
+docs:
+  stage: build
+  script: make docs
+  only:
+    changes:
+      - docs/**/*.md
+
+unit:
+  stage: test
+  script: make test
+  needs: [compile]
+
+lint:
+  stage: test
+  script: make lint
+  needs: []
+  allow_failure: true
+
+release-notes:
+  stage: test
+  script: make notes
+  only:
+    - tags
+
+integration:
+  stage: test
+  script: make integration
+  except:
+    variables:
+      - $SKIP_INTEGRATION == "true"
+
+deploy:
+  stage: deploy
+  script: make deploy
+  extends: .rules-default-branch
+  needs:
+    - job: unit
+    - job: release-notes
+      optional: true
+
+notify:
+  stage: .post
+  script: make notify
+  rules:
+    - if: $DEPLOY_TARGET == "production"
+    - if: $CI_PIPELINE_SOURCE == "api"
+      when: on_failure
+`
+
+func Test_CI_CreatePipelineFromConfig_BuildsJobGraph(t *testing.T) {
+	gitlabMock := gitlabapimock.NewGitlabMock()
+	defer gitlabMock.Close()
+
+	group1 := gitlabMock.AddGroup("group1")
+	project1 := gitlabMock.AddProject("project1", group1)
+
+	_, err := gitlabMock.CommitFiles(project1, "main", "Add CI config", map[string]string{".gitlab-ci.yml": ciConfig}, nil)
+	require.NoError(t, err)
+
+	_, err = gitlabMock.CommitFiles(project1, "wip-feature", "Work in progress", map[string]string{"wip.txt": "wip\n"}, nil)
+	require.NoError(t, err)
+
+	server := initGitlabApiMockServer(gitlabMock)
+
+	go server.ListenAndServe()
+	defer server.Close()
+
+	time.Sleep(1 * time.Second)
+
+	gitlabClient, err := initGitlabClient()
+
+	require.NoError(t, err)
+
+	_, response, err := gitlabClient.Pipelines.CreatePipeline(project1.ID, &gitlab.CreatePipelineOptions{Ref: gitlab.Ptr("wip-feature")})
+
+	require.Error(t, err)
+	require.Equal(t, 400, response.StatusCode)
+	require.ErrorContains(t, err, "Pipeline filtered out by workflow rules.")
+
+	pipeline, response, err := gitlabClient.Pipelines.CreatePipeline(project1.ID, &gitlab.CreatePipelineOptions{
+		Ref: gitlab.Ptr("main"),
+		Variables: &[]*gitlab.PipelineVariableOptions{
+			{Key: gitlab.Ptr("SKIP_INTEGRATION"), Value: gitlab.Ptr("true")},
+		},
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, 201, response.StatusCode)
+
+	jobs, _, err := gitlabClient.Jobs.ListPipelineJobs(project1.ID, pipeline.ID, nil)
+
+	require.NoError(t, err)
+
+	statuses := map[string]string{}
+	stages := []string{}
+	for _, job := range jobs {
+		statuses[job.Name] = job.Status
+		stages = append(stages, job.Stage)
+	}
+
+	require.Equal(t, map[string]string{
+		"compile": "pending",
+		"docs":    "pending",
+		"unit":    "created",
+		"lint":    "pending",
+		"deploy":  "created",
+		"notify":  "created",
+	}, statuses)
+	require.Equal(t, []string{"build", "build", "test", "test", "deploy", ".post"}, stages)
+
+	mockPipeline := gitlabMock.GetPipelines(project1)[0]
+
+	compile, _ := gitlabMock.GetPipelineJob(mockPipeline, "compile")
+	require.NoError(t, gitlabMock.AdvanceJob(compile))
+	require.NoError(t, gitlabMock.AdvanceJob(compile))
+
+	unit, _ := gitlabMock.GetPipelineJob(mockPipeline, "unit")
+	require.Equal(t, "pending", unit.Status)
+
+	require.NoError(t, gitlabMock.AdvanceJob(unit))
+	require.NoError(t, gitlabMock.AdvanceJob(unit))
+
+	// deploy only needs unit, so it does not wait for the other jobs of the test stage.
+	deploy, _ := gitlabMock.GetPipelineJob(mockPipeline, "deploy")
+	require.Equal(t, "manual", deploy.Status)
+
+	docs, _ := gitlabMock.GetPipelineJob(mockPipeline, "docs")
+	require.NoError(t, gitlabMock.AdvanceJob(docs))
+	require.NoError(t, gitlabMock.AdvanceJob(docs))
+
+	lint, _ := gitlabMock.GetPipelineJob(mockPipeline, "lint")
+	require.NoError(t, gitlabMock.SetJobStatus(lint, gitlab.Running))
+	require.NoError(t, gitlabMock.SetJobStatus(lint, gitlab.Failed))
+
+	// notify runs on failure only, and the deploy stage is blocked by the manual job.
+	notify, _ := gitlabMock.GetPipelineJob(mockPipeline, "notify")
+	require.Equal(t, "created", notify.Status)
+	require.Equal(t, "manual", mockPipeline.Status)
+
+	_, response, err = gitlabClient.Jobs.PlayJob(project1.ID, deploy.ID, nil)
+
+	require.NoError(t, err)
+	require.Equal(t, 200, response.StatusCode)
+
+	require.NoError(t, gitlabMock.AdvanceJob(deploy))
+	require.NoError(t, gitlabMock.AdvanceJob(deploy))
+
+	require.Equal(t, "skipped", notify.Status)
+	require.Equal(t, "success", mockPipeline.Status)
+
+	_, err = gitlabMock.CommitFiles(project1, "main", "Break needs", map[string]string{".gitlab-ci.yml": "build:\n  script: make\npackage:\n  script: make package\n  only: [tags]\ndeploy:\n  script: make deploy\n  needs: [package]\n"}, nil)
+	require.NoError(t, err)
+
+	_, err = gitlabMock.AddPipeline(project1, "main", nil, nil)
+	require.ErrorContains(t, err, "'deploy' job needs 'package' job, but 'package' does not exist in the pipeline.")
+}
+
+func Test_CI_Lint_ValidatesConfigWithIncludes(t *testing.T) {
+	gitlabMock := gitlabapimock.NewGitlabMock()
+	defer gitlabMock.Close()
+
+	group1 := gitlabMock.AddGroup("group1")
+	project1 := gitlabMock.AddProject("project1", group1)
+
+	_, err := gitlabMock.CommitFiles(project1, "main", "Add CI config", map[string]string{
+		".gitlab-ci.yml":    "include:\n  - local: /ci/build.yml\n\ntest:\n  script: make test\n  needs: [build]\n",
+		"ci/build.yml":      "variables:\n  GOFLAGS: -mod=mod\n\nbuild:\n  stage: build\n  script: make\n",
+		"ci/deploy.yml":     "deploy:\n  stage: deploy\n  script: make deploy\n  rules:\n    - when: manual\n",
+		"ci/unreadable.yml": "build: [\n",
+	}, nil)
+	require.NoError(t, err)
+
+	server := initGitlabApiMockServer(gitlabMock)
+
+	go server.ListenAndServe()
+	defer server.Close()
+
+	time.Sleep(1 * time.Second)
+
+	gitlabClient, err := initGitlabClient()
+
+	require.NoError(t, err)
+
+	result, _, err := gitlabClient.Validate.ProjectLint(project1.ID, &gitlab.ProjectLintOptions{})
+
+	require.NoError(t, err)
+	require.True(t, result.Valid)
+	require.Empty(t, result.Errors)
+	require.Equal(t, "variables:\n    GOFLAGS: -mod=mod\nbuild:\n    script: make\n    stage: build\ntest:\n    needs:\n        - build\n    script: make test\n", result.MergedYaml)
+
+	lint := func(content string) *gitlab.ProjectLintResult {
+		result, _, err := gitlabClient.Validate.ProjectNamespaceLint(project1.ID, &gitlab.ProjectNamespaceLintOptions{Content: gitlab.Ptr(content)})
+		require.NoError(t, err)
+
+		return result
+	}
+
+	result = lint("include: /ci/deploy.yml\n")
+	require.True(t, result.Valid)
+	require.Equal(t, []string{"jobs:deploy may allow multiple pipelines to run for a single action due to `rules:when` clause with no `workflow:rules` - read more: https://docs.gitlab.com/ee/ci/troubleshooting.html#pipeline-warnings"}, result.Warnings)
+
+	result = lint("include: /ci/missing.yml\n")
+	require.False(t, result.Valid)
+	require.Equal(t, []string{"Local file `/ci/missing.yml` does not exist!"}, result.Errors)
+
+	result = lint("include: /ci/unreadable.yml\n")
+	require.Equal(t, []string{"Included file `/ci/unreadable.yml` does not have valid YAML syntax!"}, result.Errors)
+
+	result = lint("build:\n  script: make\n  artefacts:\n    paths: [bin]\n")
+	require.Equal(t, []string{"jobs:build config contains unknown keys: artefacts"}, result.Errors)
+
+	result = lint("build:\n  stage: compile\n  script: make\n")
+	require.Equal(t, []string{"jobs:build chosen stage compile does not exist; available stages are .pre, build, test, deploy, .post"}, result.Errors)
+
+	result = lint("a:\n  script: make\n  needs: [b]\nb:\n  script: make\n  needs: [a]\n")
+	require.Equal(t, []string{"The pipeline has circular dependencies"}, result.Errors)
+
+	legacyResult, _, err := gitlabClient.Validate.Lint(&gitlab.LintOptions{Content: "include: /ci/build.yml\n"})
+
+	require.NoError(t, err)
+	require.Equal(t, "invalid", legacyResult.Status)
+	require.Equal(t, []string{"Local file `/ci/build.yml` does not have project!"}, legacyResult.Errors)
+}
@@ -0,0 +1,189 @@
+package gitlabapimock_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xanzy/go-gitlab"
+
+	gitlabapimock "github.com/arkadiusjonczek/go-gitlab-api-mock"
+)
+
+// requestRegistryToken requests a container registry token for the scope and returns the status code and the
+// access claim of the token.
+func requestRegistryToken(t *testing.T, username string, password string, scope string) (int, []map[string]interface{}) {
+	t.Helper()
+
+	request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("http://%s/jwt/auth?service=container_registry&scope=%s", GitlabHost, scope), nil)
+	require.NoError(t, err)
+
+	request.SetBasicAuth(username, password)
+
+	response, err := http.DefaultClient.Do(request)
+	require.NoError(t, err)
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return response.StatusCode, nil
+	}
+
+	var body struct {
+		Token string `json:"token"`
+	}
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&body))
+
+	parts := strings.Split(body.Token, ".")
+	require.Len(t, parts, 3)
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	require.NoError(t, err)
+
+	var claims struct {
+		Subject string                   `json:"sub"`
+		Access  []map[string]interface{} `json:"access"`
+	}
+	require.NoError(t, json.Unmarshal(payload, &claims))
+	require.Equal(t, username, claims.Subject)
+
+	return response.StatusCode, claims.Access
+}
+
+func Test_DeployTokens_AuthenticateGitAndRegistry(t *testing.T) {
+	gitlabMock := gitlabapimock.NewGitlabMock()
+	defer gitlabMock.Close()
+
+	gitlabMock.SetClock(time.Date(2024, time.March, 1, 9, 30, 0, 0, time.UTC))
+
+	admin, _ := gitlabMock.AddUser("Administrator", "root", "admin@telekom.de")
+	admin.IsAdmin = true
+	user1, _ := gitlabMock.AddUser("Peter Pan", "peter.pan", "peter.pan@telekom.de")
+	gitlabMock.AddPersonalAccessToken(admin, "admin", "admin-token", []string{"api"})
+	gitlabMock.AddPersonalAccessToken(user1, "token1", "token1", []string{"api"})
+
+	group1 := gitlabMock.AddGroup("group1")
+	project1 := gitlabMock.AddProject("project1", group1)
+	gitlabMock.AddProject("project2", group1)
+	project3 := gitlabMock.AddProject("project3", gitlabMock.AddGroup("group2"))
+	gitlabMock.AddProjectMember(&gitlab.ProjectMember{ID: user1.ID, AccessLevel: gitlab.MaintainerPermissions}, project1)
+	gitlabMock.AddGroupMember(&gitlab.GroupMember{ID: user1.ID, AccessLevel: gitlab.MaintainerPermissions}, group1)
+
+	for _, project := range []*gitlab.Project{project1, project3} {
+		_, err := gitlabMock.CommitFiles(project, "main", "Initial commit", map[string]string{"README.md": "# readme\n"}, nil)
+		require.NoError(t, err)
+	}
+
+	server := initGitlabApiMockServer(gitlabMock)
+
+	go server.ListenAndServe()
+	defer server.Close()
+
+	time.Sleep(1 * time.Second)
+
+	adminClient, err := initGitlabClientWithToken("admin-token")
+	require.NoError(t, err)
+
+	gitlabClient1, err := initGitlabClientWithToken("token1")
+	require.NoError(t, err)
+
+	_, resp, err := gitlabClient1.DeployTokens.CreateProjectDeployToken(project1.ID, &gitlab.CreateProjectDeployTokenOptions{
+		Name:   gitlab.Ptr("deploy"),
+		Scopes: &[]string{"write_repository"},
+	})
+	require.Error(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	expiresAt := time.Date(2024, time.March, 8, 0, 0, 0, 0, time.UTC)
+	projectToken, _, err := gitlabClient1.DeployTokens.CreateProjectDeployToken(project1.ID, &gitlab.CreateProjectDeployTokenOptions{
+		Name:      gitlab.Ptr("deploy"),
+		Scopes:    &[]string{"read_repository", "read_registry"},
+		ExpiresAt: &expiresAt,
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, projectToken.Token)
+	require.Equal(t, fmt.Sprintf("gitlab+deploy-token-%d", projectToken.ID), projectToken.Username)
+
+	token, _, err := gitlabClient1.DeployTokens.GetProjectDeployToken(project1.ID, projectToken.ID)
+	require.NoError(t, err)
+	require.Empty(t, token.Token)
+	require.False(t, token.Expired)
+
+	// Deploy tokens pull the repositories of their project but never push.
+	dir := t.TempDir()
+	credentials := fmt.Sprintf("%s:%s", projectToken.Username, projectToken.Token)
+
+	output, err := runGit(t, dir, "clone", fmt.Sprintf("http://%s@%s/group1/project1.git", credentials, GitlabHost), "project1")
+	require.NoError(t, err, output)
+
+	output, err = runGit(t, dir, "clone", fmt.Sprintf("http://%s@%s/group2/project3.git", credentials, GitlabHost), "project3")
+	require.Error(t, err, output)
+	require.Contains(t, output, "403")
+
+	output, err = runGit(t, dir, "clone", fmt.Sprintf("http://someone:%s@%s/group1/project1.git", projectToken.Token, GitlabHost), "someone")
+	require.Error(t, err, output)
+	require.Contains(t, output, "Authentication failed")
+
+	output, err = runGit(t, dir+"/project1", "push", "origin", "main:feature")
+	require.Error(t, err, output)
+	require.Contains(t, output, "403")
+
+	statusCode, access := requestRegistryToken(t, projectToken.Username, projectToken.Token, "repository:group1/project1/image:pull,push")
+	require.Equal(t, http.StatusOK, statusCode)
+	require.Equal(t, []map[string]interface{}{{"type": "repository", "name": "group1/project1/image", "actions": []interface{}{"pull"}}}, access)
+
+	statusCode, _ = requestRegistryToken(t, projectToken.Username, projectToken.Token, "repository:group2/project3:pull")
+	require.Equal(t, http.StatusForbidden, statusCode)
+
+	// Group deploy tokens give access to all projects of the group.
+	groupToken, _, err := gitlabClient1.DeployTokens.CreateGroupDeployToken(group1.ID, &gitlab.CreateGroupDeployTokenOptions{
+		Name:     gitlab.Ptr("registry"),
+		Username: gitlab.Ptr("registry-bot"),
+		Scopes:   &[]string{"read_registry", "write_registry"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "registry-bot", groupToken.Username)
+
+	statusCode, access = requestRegistryToken(t, "registry-bot", groupToken.Token, "repository:group1/project2:pull,push")
+	require.Equal(t, http.StatusOK, statusCode)
+	require.Equal(t, []interface{}{"pull", "push"}, access[0]["actions"])
+
+	output, err = runGit(t, dir, "clone", fmt.Sprintf("http://registry-bot:%s@%s/group1/project1.git", groupToken.Token, GitlabHost), "group")
+	require.Error(t, err, output)
+	require.Contains(t, output, "403")
+
+	statusCode, _ = requestRegistryToken(t, "peter.pan", "token1", "repository:group1/project2:push")
+	require.Equal(t, http.StatusOK, statusCode)
+
+	// Deleting a deploy token revokes it.
+	_, err = gitlabClient1.DeployTokens.DeleteGroupDeployToken(group1.ID, groupToken.ID)
+	require.NoError(t, err)
+
+	statusCode, _ = requestRegistryToken(t, "registry-bot", groupToken.Token, "repository:group1/project2:pull")
+	require.Equal(t, http.StatusUnauthorized, statusCode)
+
+	groupTokens, _, err := gitlabClient1.DeployTokens.ListGroupDeployTokens(group1.ID, nil)
+	require.NoError(t, err)
+	require.Len(t, groupTokens, 1)
+	require.True(t, groupTokens[0].Revoked)
+
+	_, resp, err = gitlabClient1.DeployTokens.ListAllDeployTokens()
+	require.Error(t, err)
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	// Tokens expire at their expiry date.
+	gitlabMock.AdvanceClock(7 * 24 * time.Hour)
+
+	output, err = runGit(t, dir, "clone", fmt.Sprintf("http://%s@%s/group1/project1.git", credentials, GitlabHost), "expired")
+	require.Error(t, err, output)
+
+	allTokens, _, err := adminClient.DeployTokens.ListAllDeployTokens()
+	require.NoError(t, err)
+	require.Len(t, allTokens, 2)
+	require.True(t, allTokens[0].Expired)
+	require.False(t, allTokens[0].Revoked)
+}
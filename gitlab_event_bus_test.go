@@ -0,0 +1,94 @@
+package gitlabapimock_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xanzy/go-gitlab"
+
+	gitlabapimock "github.com/arkadiusjonczek/go-gitlab-api-mock"
+)
+
+func Test_EventBus_PublishesMemberChanges(t *testing.T) {
+	gitlabMock := gitlabapimock.NewGitlabMock()
+	defer gitlabMock.Close()
+
+	var created []string
+	unsubscribe := gitlabMock.Subscribe(func(event gitlabapimock.Event) {
+		if userCreated, ok := event.(gitlabapimock.UserCreated); ok {
+			created = append(created, userCreated.User.Username)
+		}
+	})
+
+	user1, _ := gitlabMock.AddUser("Peter Pan", "peter.pan", "peter.pan@telekom.de")
+	user2, _ := gitlabMock.AddUser("Captain Hook", "captain.hook", "captain.hook@telekom.de")
+	gitlabMock.AddPersonalAccessToken(user1, "token1", "token1", []string{"api"})
+
+	unsubscribe()
+
+	_, _ = gitlabMock.AddUser("Wendy Darling", "wendy.darling", "wendy.darling@telekom.de")
+	require.Equal(t, []string{"peter.pan", "captain.hook"}, created)
+
+	group1 := gitlabMock.AddGroup("group1")
+	project1 := gitlabMock.AddProject("project1", group1)
+	gitlabMock.AddProjectMember(&gitlab.ProjectMember{ID: user1.ID, AccessLevel: gitlab.MaintainerPermissions}, project1)
+
+	events, stop := gitlabMock.Events()
+	defer stop()
+
+	server := initGitlabApiMockServer(gitlabMock)
+
+	go server.ListenAndServe()
+	defer server.Close()
+
+	time.Sleep(1 * time.Second)
+
+	gitlabClient1, err := initGitlabClientWithToken("token1")
+	require.NoError(t, err)
+
+	go func() {
+		_, _, _ = gitlabClient1.ProjectMembers.AddProjectMember(project1.ID, &gitlab.AddProjectMemberOptions{UserID: user2.ID, AccessLevel: gitlab.Ptr(gitlab.DeveloperPermissions)})
+		_, _, _ = gitlabClient1.ProjectMembers.EditProjectMember(project1.ID, user2.ID, &gitlab.EditProjectMemberOptions{AccessLevel: gitlab.Ptr(gitlab.MaintainerPermissions)})
+		_, _ = gitlabClient1.ProjectMembers.DeleteProjectMember(project1.ID, user2.ID)
+	}()
+
+	// The test blocks until the changes were made instead of polling the API.
+	next := func() gitlabapimock.Event {
+		select {
+		case event := <-events:
+			return event
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "no event received")
+			return nil
+		}
+	}
+
+	memberAdded, ok := next().(gitlabapimock.ProjectMemberAdded)
+	require.True(t, ok)
+	require.Equal(t, project1.ID, memberAdded.Project.ID)
+	require.Equal(t, user2.ID, memberAdded.Member.ID)
+
+	accessChanged, ok := next().(gitlabapimock.ProjectMemberAccessChanged)
+	require.True(t, ok)
+	require.Equal(t, gitlab.DeveloperPermissions, accessChanged.OldAccessLevel)
+	require.Equal(t, gitlab.MaintainerPermissions, accessChanged.NewAccessLevel)
+	require.Equal(t, gitlab.MaintainerPermissions, accessChanged.Member.AccessLevel)
+
+	// Events carry copies, the edit does not change the member of the earlier event.
+	require.Equal(t, gitlab.DeveloperPermissions, memberAdded.Member.AccessLevel)
+
+	memberRemoved, ok := next().(gitlabapimock.MemberRemoved)
+	require.True(t, ok)
+	require.Equal(t, project1.ID, memberRemoved.Project.ID)
+	require.Nil(t, memberRemoved.Group)
+	require.Equal(t, user2.ID, memberRemoved.UserID)
+
+	// Stopping the subscription closes the channel.
+	stop()
+
+	gitlabMock.AddGroup("group2")
+
+	_, open := <-events
+	require.False(t, open)
+}
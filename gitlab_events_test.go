@@ -0,0 +1,136 @@
+package gitlabapimock_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xanzy/go-gitlab"
+
+	gitlabapimock "github.com/arkadiusjonczek/go-gitlab-api-mock"
+)
+
+func Test_Events_ListActivityAndAuditEvents(t *testing.T) {
+	gitlabMock := gitlabapimock.NewGitlabMock()
+	defer gitlabMock.Close()
+
+	gitlabMock.SetClock(time.Date(2024, time.March, 1, 9, 30, 0, 0, time.UTC))
+
+	user1, _ := gitlabMock.AddUser("Peter Pan", "peter.pan", "peter.pan@telekom.de")
+	user2, _ := gitlabMock.AddUser("Captain Hook", "captain.hook", "captain.hook@telekom.de")
+	gitlabMock.AddPersonalAccessToken(user1, "token1", "token1", []string{"api"})
+	gitlabMock.AddPersonalAccessToken(user2, "token2", "token2", []string{"api"})
+
+	group1 := gitlabMock.AddGroup("group1")
+	gitlabMock.AddGroupMember(&gitlab.GroupMember{ID: user1.ID, AccessLevel: gitlab.OwnerPermissions}, group1)
+	project1 := gitlabMock.AddProject("project1", group1)
+
+	server := initGitlabApiMockServer(gitlabMock)
+
+	go server.ListenAndServe()
+	defer server.Close()
+
+	time.Sleep(1 * time.Second)
+
+	gitlabClient1, err := initGitlabClientWithToken("token1")
+	require.NoError(t, err)
+
+	gitlabClient2, err := initGitlabClientWithToken("token2")
+	require.NoError(t, err)
+
+	issue, _, err := gitlabClient1.Issues.CreateIssue(project1.ID, &gitlab.CreateIssueOptions{Title: gitlab.Ptr("issue1")})
+	require.NoError(t, err)
+
+	gitlabMock.AdvanceClock(24 * time.Hour)
+
+	_, _, err = gitlabClient1.Notes.CreateIssueNote(project1.ID, issue.IID, &gitlab.CreateIssueNoteOptions{Body: gitlab.Ptr("note1")})
+	require.NoError(t, err)
+
+	_, _, err = gitlabClient1.ProjectMembers.AddProjectMember(project1.ID, &gitlab.AddProjectMemberOptions{UserID: user2.ID, AccessLevel: gitlab.Ptr(gitlab.DeveloperPermissions)})
+	require.NoError(t, err)
+
+	// Adding a member twice doesn't record the member again.
+	_, resp, err := gitlabClient1.ProjectMembers.AddProjectMember(project1.ID, &gitlab.AddProjectMemberOptions{UserID: user2.ID, AccessLevel: gitlab.Ptr(gitlab.DeveloperPermissions)})
+	require.Error(t, err)
+	require.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	_, _, err = gitlabClient1.ProjectMembers.EditProjectMember(project1.ID, user2.ID, &gitlab.EditProjectMemberOptions{AccessLevel: gitlab.Ptr(gitlab.MaintainerPermissions)})
+	require.NoError(t, err)
+
+	events, _, err := gitlabClient1.Events.ListCurrentUserContributionEvents(nil)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.Equal(t, "commented on", events[0].ActionName)
+	require.Equal(t, "Note", events[0].TargetType)
+	require.Equal(t, "note1", events[0].Note.Body)
+	require.Equal(t, "opened", events[1].ActionName)
+	require.Equal(t, "Issue", events[1].TargetType)
+	require.Equal(t, "issue1", events[1].TargetTitle)
+	require.Equal(t, "peter.pan", events[1].Author.Username)
+
+	events, _, err = gitlabClient1.Events.ListCurrentUserContributionEvents(&gitlab.ListContributionEventsOptions{
+		TargetType: gitlab.Ptr(gitlab.IssueEventTargetType),
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, issue.ID, events[0].TargetID)
+
+	before := gitlab.ISOTime(time.Date(2024, time.March, 2, 0, 0, 0, 0, time.UTC))
+	events, _, err = gitlabClient1.Events.ListCurrentUserContributionEvents(&gitlab.ListContributionEventsOptions{Before: &before})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, "opened", events[0].ActionName)
+
+	events, _, err = gitlabClient2.Users.ListUserContributionEvents("captain.hook", &gitlab.ListContributionEventsOptions{
+		Action: gitlab.Ptr(gitlab.JoinedEventType),
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, project1.ID, events[0].ProjectID)
+
+	projectEvents, _, err := gitlabClient2.Events.ListProjectVisibleEvents(project1.ID, nil)
+	require.NoError(t, err)
+	require.Len(t, projectEvents, 3)
+	require.Equal(t, "joined", projectEvents[0].ActionName)
+
+	_, resp, err = gitlabClient1.Users.ListUserContributionEvents("wendy.darling", nil)
+	require.Error(t, err)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// Only maintainers read the audit events of projects and only owners the ones of groups.
+	auditEvents, _, err := gitlabClient2.AuditEvents.ListProjectAuditEvents(project1.ID, nil)
+	require.NoError(t, err)
+	require.Len(t, auditEvents, 2)
+	require.Equal(t, "member_updated", auditEvents[0].EventType)
+	require.Equal(t, user1.ID, auditEvents[0].AuthorID)
+	require.Equal(t, "access_level", auditEvents[0].Details.Change)
+	require.Equal(t, "Developer", auditEvents[0].Details.From)
+	require.Equal(t, "Maintainer", auditEvents[0].Details.To)
+	require.EqualValues(t, user2.ID, auditEvents[0].Details.TargetID)
+	require.Equal(t, "member_created", auditEvents[1].EventType)
+
+	auditEvent, _, err := gitlabClient2.AuditEvents.GetProjectAuditEvent(project1.ID, auditEvents[1].ID)
+	require.NoError(t, err)
+	require.Equal(t, "Developer", auditEvent.Details.As)
+
+	_, resp, err = gitlabClient2.AuditEvents.ListGroupAuditEvents(group1.ID, nil)
+	require.Error(t, err)
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	auditEvents, _, err = gitlabClient1.AuditEvents.ListGroupAuditEvents(group1.ID, nil)
+	require.NoError(t, err)
+	require.Len(t, auditEvents, 1)
+	require.Equal(t, "member_created", auditEvents[0].EventType)
+
+	createdAfter := time.Date(2024, time.March, 3, 0, 0, 0, 0, time.UTC)
+	auditEvents, _, err = gitlabClient1.AuditEvents.ListGroupAuditEvents(group1.ID, &gitlab.ListAuditEventsOptions{CreatedAfter: &createdAfter})
+	require.NoError(t, err)
+	require.Empty(t, auditEvents)
+
+	_, resp, err = gitlabClient1.AuditEvents.ListInstanceAuditEvents(nil)
+	require.Error(t, err)
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	require.Len(t, gitlabMock.GetAuditEvents(), 3)
+}
@@ -0,0 +1,184 @@
+package gitlabapimock_test
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xanzy/go-gitlab"
+
+	gitlabapimock "github.com/arkadiusjonczek/go-gitlab-api-mock"
+)
+
+func runGit(t *testing.T, dir string, args ...string) (string, error) {
+	t.Helper()
+
+	cmd := exec.Command("git", append([]string{"-c", "user.name=Peter Pan", "-c", "user.email=peter.pan@telekom.de"}, args...)...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+
+	output, err := cmd.CombinedOutput()
+
+	return string(output), err
+}
+
+func Test_Git_CloneProject_ReturnsCommittedFiles(t *testing.T) {
+	gitlabMock := gitlabapimock.NewGitlabMock()
+	defer gitlabMock.Close()
+
+	group1 := gitlabMock.AddGroup("group1")
+	project1 := gitlabMock.AddProject("project1", group1)
+
+	_, err := gitlabMock.CommitFiles(project1, "main", "Initial commit", map[string]string{"README.md": "# project1\n"}, nil)
+
+	require.NoError(t, err)
+
+	server := initGitlabApiMockServer(gitlabMock)
+
+	go server.ListenAndServe()
+	defer server.Close()
+
+	time.Sleep(1 * time.Second)
+
+	dir := t.TempDir()
+
+	output, err := runGit(t, dir, "clone", fmt.Sprintf("http://%s/group1/project1.git", GitlabHost), "project1")
+
+	require.NoError(t, err, output)
+
+	readme, err := os.ReadFile(filepath.Join(dir, "project1", "README.md"))
+
+	require.NoError(t, err)
+	require.Equal(t, "# project1\n", string(readme))
+}
+
+func Test_Git_PushBranch_UpdatesProject(t *testing.T) {
+	gitlabMock := gitlabapimock.NewGitlabMock()
+	defer gitlabMock.Close()
+
+	group1 := gitlabMock.AddGroup("group1")
+	gitlabMock.AddProject("project1", group1)
+
+	server := initGitlabApiMockServer(gitlabMock)
+
+	go server.ListenAndServe()
+	defer server.Close()
+
+	time.Sleep(1 * time.Second)
+
+	gitlabClient, err := initGitlabClient()
+
+	require.NoError(t, err)
+
+	project, response, err := gitlabClient.Projects.GetProject(1, nil)
+
+	require.NoError(t, err)
+	require.Equal(t, 200, response.StatusCode)
+	require.True(t, project.EmptyRepo)
+
+	dir := t.TempDir()
+
+	output, err := runGit(t, dir, "init", "--initial-branch=develop")
+	require.NoError(t, err, output)
+
+	err = os.WriteFile(filepath.Join(dir, "README.md"), []byte("# project1\n"), 0o644)
+	require.NoError(t, err)
+
+	output, err = runGit(t, dir, "add", "README.md")
+	require.NoError(t, err, output)
+
+	output, err = runGit(t, dir, "commit", "-m", "Initial commit")
+	require.NoError(t, err, output)
+
+	output, err = runGit(t, dir, "push", project.HTTPURLToRepo, "develop", "develop:feature/login")
+	require.NoError(t, err, output)
+
+	project, response, err = gitlabClient.Projects.GetProject("group1/project1", nil)
+
+	require.NoError(t, err)
+	require.Equal(t, 200, response.StatusCode)
+	require.False(t, project.EmptyRepo)
+	require.Equal(t, "develop", project.DefaultBranch)
+
+	branches, response, err := gitlabClient.Branches.ListBranches(1, &gitlab.ListBranchesOptions{})
+
+	require.NoError(t, err)
+	require.Equal(t, 200, response.StatusCode)
+	require.Len(t, branches, 2)
+
+	branch, response, err := gitlabClient.Branches.GetBranch(1, "feature/login")
+
+	require.NoError(t, err)
+	require.Equal(t, 200, response.StatusCode)
+	require.Equal(t, "Initial commit", branch.Commit.Title)
+	require.Equal(t, "Peter Pan", branch.Commit.AuthorName)
+}
+
+func Test_Git_CloneProject_WithoutMembership_ReturnsError(t *testing.T) {
+	gitlabMock := gitlabapimock.NewGitlabMock()
+	defer gitlabMock.Close()
+
+	user1, _ := gitlabMock.AddUser("Peter Pan", "peter.pan", "peter.pan@telekom.de")
+	user2, _ := gitlabMock.AddUser("Petra Pan", "petra.pan", "petra.pan@telekom.de")
+	gitlabMock.AddPersonalAccessToken(user1, "token1", "token1", []string{"api"})
+	gitlabMock.AddPersonalAccessToken(user2, "token2", "token2", []string{"api"})
+
+	group1 := gitlabMock.AddGroup("group1")
+	project1 := gitlabMock.AddProject("project1", group1)
+	gitlabMock.AddProjectMember(&gitlab.ProjectMember{ID: user1.ID, AccessLevel: gitlab.DeveloperPermissions}, project1)
+
+	_, err := gitlabMock.CommitFiles(project1, "main", "Initial commit", map[string]string{"README.md": "# project1\n"}, user1)
+
+	require.NoError(t, err)
+
+	server := initGitlabApiMockServer(gitlabMock)
+
+	go server.ListenAndServe()
+	defer server.Close()
+
+	time.Sleep(1 * time.Second)
+
+	dir := t.TempDir()
+
+	output, err := runGit(t, dir, "clone", fmt.Sprintf("http://petra.pan:token2@%s/group1/project1.git", GitlabHost), "denied")
+
+	require.Error(t, err)
+	require.True(t, strings.Contains(output, "403"), output)
+
+	output, err = runGit(t, dir, "clone", fmt.Sprintf("http://peter.pan:invalid@%s/group1/project1.git", GitlabHost), "invalid")
+
+	require.Error(t, err)
+
+	output, err = runGit(t, dir, "clone", fmt.Sprintf("http://peter.pan:token1@%s/group1/project1.git", GitlabHost), "allowed")
+
+	require.NoError(t, err, output)
+
+	// The branches API needs the same membership.
+	gitlabClient2, err := initGitlabClientWithToken("token2")
+
+	require.NoError(t, err)
+
+	_, response, err := gitlabClient2.Branches.ListBranches(project1.ID, nil)
+
+	require.Error(t, err)
+	require.Equal(t, 403, response.StatusCode)
+
+	_, response, err = gitlabClient2.Branches.GetBranch(project1.ID, "main")
+
+	require.Error(t, err)
+	require.Equal(t, 403, response.StatusCode)
+
+	gitlabClient1, err := initGitlabClientWithToken("token1")
+
+	require.NoError(t, err)
+
+	branches, _, err := gitlabClient1.Branches.ListBranches(project1.ID, nil)
+
+	require.NoError(t, err)
+	require.Len(t, branches, 1)
+}
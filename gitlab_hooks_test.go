@@ -0,0 +1,360 @@
+package gitlabapimock_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xanzy/go-gitlab"
+
+	gitlabapimock "github.com/arkadiusjonczek/go-gitlab-api-mock"
+)
+
+type receivedHook struct {
+	event gitlab.EventType
+	token string
+	body  []byte
+}
+
+// hookReceiver records the webhooks it receives. The first request fails to let the mock retry it.
+type hookReceiver struct {
+	mutex    sync.Mutex
+	requests int
+	hooks    []receivedHook
+}
+
+func (receiver *hookReceiver) ServeHTTP(responseWriter http.ResponseWriter, request *http.Request) {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+
+	receiver.requests++
+	if receiver.requests == 1 {
+		responseWriter.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	body, _ := io.ReadAll(request.Body)
+
+	receiver.hooks = append(receiver.hooks, receivedHook{
+		event: gitlab.EventType(request.Header.Get("X-Gitlab-Event")),
+		token: request.Header.Get("X-Gitlab-Token"),
+		body:  body,
+	})
+}
+
+func (receiver *hookReceiver) received(event gitlab.EventType) []receivedHook {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+
+	hooks := []receivedHook{}
+	for _, hook := range receiver.hooks {
+		if hook.event == event {
+			hooks = append(hooks, hook)
+		}
+	}
+
+	return hooks
+}
+
+func Test_ProjectHooks_DeliverEventsToReceiver(t *testing.T) {
+	gitlabMock := gitlabapimock.NewGitlabMock()
+	defer gitlabMock.Close()
+
+	gitlabMock.SetWebhookRetries(3, 10*time.Millisecond)
+
+	user1, _ := gitlabMock.AddUser("Peter Pan", "peter.pan", "peter.pan@telekom.de")
+	user2, _ := gitlabMock.AddUser("Captain Hook", "captain.hook", "captain.hook@telekom.de")
+	gitlabMock.AddPersonalAccessToken(user1, "token1", "token1", []string{"api"})
+	gitlabMock.AddPersonalAccessToken(user2, "token2", "token2", []string{"api"})
+
+	group1 := gitlabMock.AddGroup("group1")
+	project1 := gitlabMock.AddProject("project1", group1)
+	gitlabMock.AddProjectMember(&gitlab.ProjectMember{ID: user1.ID, AccessLevel: gitlab.MaintainerPermissions}, project1)
+
+	receiver := &hookReceiver{}
+	receiverServer := httptest.NewServer(receiver)
+	defer receiverServer.Close()
+
+	server := initGitlabApiMockServer(gitlabMock)
+
+	go server.ListenAndServe()
+	defer server.Close()
+
+	time.Sleep(1 * time.Second)
+
+	gitlabClient1, err := initGitlabClientWithToken("token1")
+	require.NoError(t, err)
+	gitlabClient2, err := initGitlabClientWithToken("token2")
+	require.NoError(t, err)
+
+	_, response, err := gitlabClient1.Projects.AddProjectHook(project1.ID, &gitlab.AddProjectHookOptions{URL: gitlab.Ptr("ftp://example.com")})
+	require.Error(t, err)
+	require.Equal(t, 422, response.StatusCode)
+
+	hook, _, err := gitlabClient1.Projects.AddProjectHook(project1.ID, &gitlab.AddProjectHookOptions{
+		URL:                 gitlab.Ptr(receiverServer.URL),
+		Token:               gitlab.Ptr("secret"),
+		TagPushEvents:       gitlab.Ptr(true),
+		IssuesEvents:        gitlab.Ptr(true),
+		MergeRequestsEvents: gitlab.Ptr(true),
+		NoteEvents:          gitlab.Ptr(true),
+		PipelineEvents:      gitlab.Ptr(true),
+		JobEvents:           gitlab.Ptr(true),
+	})
+	require.NoError(t, err)
+	require.True(t, hook.PushEvents)
+
+	// Member events are not part of the options of go-gitlab.
+	request, err := gitlabClient1.NewRequest(http.MethodPut, fmt.Sprintf("projects/%d/hooks/%d", project1.ID, hook.ID), map[string]bool{"member_events": true}, nil)
+	require.NoError(t, err)
+	_, err = gitlabClient1.Do(request, nil)
+	require.NoError(t, err)
+
+	_, response, err = gitlabClient2.Projects.ListProjectHooks(project1.ID, nil)
+	require.Error(t, err)
+	require.Equal(t, 403, response.StatusCode)
+
+	// Every change of the project is delivered to the hook.
+	_, err = gitlabMock.CommitFiles(project1, "main", "Initial commit", map[string]string{".gitlab-ci.yml": "build:\n  script: make\n"}, user1)
+	require.NoError(t, err)
+	_, err = gitlabMock.AddTag(project1, "v1.0.0", "main", "Release 1.0.0", user1)
+	require.NoError(t, err)
+	_, err = gitlabMock.CommitFiles(project1, "feature", "Add feature", map[string]string{"feature.txt": "feature"}, user1)
+	require.NoError(t, err)
+
+	mergeRequest, _, err := gitlabClient1.MergeRequests.CreateMergeRequest(project1.ID, &gitlab.CreateMergeRequestOptions{
+		Title:        gitlab.Ptr("Add feature"),
+		SourceBranch: gitlab.Ptr("feature"),
+		TargetBranch: gitlab.Ptr("main"),
+	})
+	require.NoError(t, err)
+
+	issue, _, err := gitlabClient1.Issues.CreateIssue(project1.ID, &gitlab.CreateIssueOptions{Title: gitlab.Ptr("Bug")})
+	require.NoError(t, err)
+	_, _, err = gitlabClient1.Notes.CreateIssueNote(project1.ID, issue.IID, &gitlab.CreateIssueNoteOptions{Body: gitlab.Ptr("Confirmed")})
+	require.NoError(t, err)
+	_, _, err = gitlabClient1.Issues.UpdateIssue(project1.ID, issue.IID, &gitlab.UpdateIssueOptions{StateEvent: gitlab.Ptr("close")})
+	require.NoError(t, err)
+
+	gitlabMock.AddProjectMember(&gitlab.ProjectMember{ID: user2.ID, AccessLevel: gitlab.DeveloperPermissions}, project1)
+
+	pipeline, err := gitlabMock.AddPipeline(project1, "main", nil, user1)
+	require.NoError(t, err)
+	job, err := gitlabMock.GetPipelineJob(pipeline, "build")
+	require.NoError(t, err)
+	require.NoError(t, gitlabMock.AdvanceJob(job))
+	require.NoError(t, gitlabMock.AdvanceJob(job))
+
+	gitlabMock.WaitForWebhooks()
+
+	// The first delivery failed and was retried.
+	require.Equal(t, len(receiver.hooks)+1, receiver.requests)
+	require.Equal(t, gitlab.EventTypePush, receiver.hooks[0].event)
+
+	for _, received := range receiver.hooks {
+		require.Equal(t, "secret", received.token)
+	}
+
+	pushes := receiver.received(gitlab.EventTypePush)
+	require.Len(t, pushes, 2)
+
+	event, err := gitlab.ParseWebhook(gitlab.EventTypePush, pushes[0].body)
+	require.NoError(t, err)
+	pushEvent := event.(*gitlab.PushEvent)
+	require.Equal(t, "refs/heads/main", pushEvent.Ref)
+	require.Equal(t, user1.Username, pushEvent.UserUsername)
+	require.Equal(t, 1, pushEvent.TotalCommitsCount)
+	require.Equal(t, []string{".gitlab-ci.yml"}, pushEvent.Commits[0].Added)
+
+	// The push of the new branch only contains the commit that is not on main.
+	event, err = gitlab.ParseWebhook(gitlab.EventTypePush, pushes[1].body)
+	require.NoError(t, err)
+	require.Equal(t, 1, event.(*gitlab.PushEvent).TotalCommitsCount)
+	require.Equal(t, "Add feature", event.(*gitlab.PushEvent).Commits[0].Title)
+
+	tagPushes := receiver.received(gitlab.EventTypeTagPush)
+	require.Len(t, tagPushes, 1)
+
+	event, err = gitlab.ParseWebhook(gitlab.EventTypeTagPush, tagPushes[0].body)
+	require.NoError(t, err)
+	require.Equal(t, "refs/tags/v1.0.0", event.(*gitlab.TagEvent).Ref)
+
+	mergeRequestHooks := receiver.received(gitlab.EventTypeMergeRequest)
+	require.Len(t, mergeRequestHooks, 1)
+
+	event, err = gitlab.ParseWebhook(gitlab.EventTypeMergeRequest, mergeRequestHooks[0].body)
+	require.NoError(t, err)
+	mergeEvent := event.(*gitlab.MergeEvent)
+	require.Equal(t, "open", mergeEvent.ObjectAttributes.Action)
+	require.Equal(t, mergeRequest.IID, mergeEvent.ObjectAttributes.IID)
+	require.Equal(t, "feature", mergeEvent.ObjectAttributes.SourceBranch)
+
+	issueHooks := receiver.received(gitlab.EventTypeIssue)
+	require.Len(t, issueHooks, 2)
+
+	event, err = gitlab.ParseWebhook(gitlab.EventTypeIssue, issueHooks[1].body)
+	require.NoError(t, err)
+	issueEvent := event.(*gitlab.IssueEvent)
+	require.Equal(t, "close", issueEvent.ObjectAttributes.Action)
+	require.Equal(t, gitlab.StateIDOpen, issueEvent.Changes.StateID.Previous)
+	require.Equal(t, gitlab.StateIDClosed, issueEvent.Changes.StateID.Current)
+
+	noteHooks := receiver.received(gitlab.EventTypeNote)
+	require.Len(t, noteHooks, 1)
+
+	event, err = gitlab.ParseWebhook(gitlab.EventTypeNote, noteHooks[0].body)
+	require.NoError(t, err)
+	noteEvent := event.(*gitlab.IssueCommentEvent)
+	require.Equal(t, "Confirmed", noteEvent.ObjectAttributes.Note)
+	require.Equal(t, issue.IID, noteEvent.Issue.IID)
+
+	memberHooks := receiver.received(gitlab.EventTypeMember)
+	require.Len(t, memberHooks, 1)
+
+	event, err = gitlab.ParseWebhook(gitlab.EventTypeMember, memberHooks[0].body)
+	require.NoError(t, err)
+	memberEvent := event.(*gitlab.MemberEvent)
+	require.Equal(t, "user_add_to_team", memberEvent.EventName)
+	require.Equal(t, user2.Username, memberEvent.UserUsername)
+
+	pipelineHooks := receiver.received(gitlab.EventTypePipeline)
+	require.NotEmpty(t, pipelineHooks)
+
+	event, err = gitlab.ParseWebhook(gitlab.EventTypePipeline, pipelineHooks[len(pipelineHooks)-1].body)
+	require.NoError(t, err)
+	pipelineEvent := event.(*gitlab.PipelineEvent)
+	require.Equal(t, pipeline.ID, pipelineEvent.ObjectAttributes.ID)
+	require.Equal(t, "success", pipelineEvent.ObjectAttributes.Status)
+	require.Len(t, pipelineEvent.Builds, 1)
+
+	jobHooks := receiver.received(gitlab.EventTypeJob)
+	require.NotEmpty(t, jobHooks)
+
+	event, err = gitlab.ParseWebhook(gitlab.EventTypeJob, jobHooks[len(jobHooks)-1].body)
+	require.NoError(t, err)
+	jobEvent := event.(*gitlab.JobEvent)
+	require.Equal(t, job.ID, jobEvent.BuildID)
+	require.Equal(t, "success", jobEvent.BuildStatus)
+
+	// Deleted hooks receive nothing anymore.
+	_, err = gitlabClient1.Projects.DeleteProjectHook(project1.ID, hook.ID)
+	require.NoError(t, err)
+
+	hooks, _, err := gitlabClient1.Projects.ListProjectHooks(project1.ID, nil)
+	require.NoError(t, err)
+	require.Empty(t, hooks)
+}
+
+func Test_ProjectHooks_LogDeliveriesAndSendTestEvents(t *testing.T) {
+	gitlabMock := gitlabapimock.NewGitlabMock()
+	defer gitlabMock.Close()
+
+	gitlabMock.SetWebhookRetries(3, 10*time.Millisecond)
+
+	clock := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	gitlabMock.SetClock(clock)
+
+	user1, _ := gitlabMock.AddUser("Peter Pan", "peter.pan", "peter.pan@telekom.de")
+	gitlabMock.AddPersonalAccessToken(user1, "token1", "token1", []string{"api"})
+
+	group1 := gitlabMock.AddGroup("group1")
+	project1 := gitlabMock.AddProject("project1", group1)
+	gitlabMock.AddProjectMember(&gitlab.ProjectMember{ID: user1.ID, AccessLevel: gitlab.MaintainerPermissions}, project1)
+
+	receiver := &hookReceiver{}
+	receiverServer := httptest.NewServer(receiver)
+	defer receiverServer.Close()
+
+	hook, err := gitlabMock.AddProjectHook(project1, &gitlabapimock.ProjectHookOptions{
+		AddProjectHookOptions: gitlab.AddProjectHookOptions{
+			URL:          gitlab.Ptr(receiverServer.URL),
+			Token:        gitlab.Ptr("secret"),
+			IssuesEvents: gitlab.Ptr(true),
+		},
+	})
+	require.NoError(t, err)
+
+	server := initGitlabApiMockServer(gitlabMock)
+
+	go server.ListenAndServe()
+	defer server.Close()
+
+	time.Sleep(1 * time.Second)
+
+	gitlabClient1, err := initGitlabClientWithToken("token1")
+	require.NoError(t, err)
+
+	_, _, err = gitlabClient1.Issues.CreateIssue(project1.ID, &gitlab.CreateIssueOptions{Title: gitlab.Ptr("Bug")})
+	require.NoError(t, err)
+
+	gitlabMock.WaitForWebhooks()
+
+	// Every attempt is logged, the token is redacted.
+	deliveries := gitlabMock.GetWebhookDeliveries(hook.ID)
+	require.Len(t, deliveries, 2)
+	require.Equal(t, "500", deliveries[0].ResponseStatus)
+	require.Equal(t, "200", deliveries[1].ResponseStatus)
+	require.Equal(t, "issue_hooks", deliveries[1].Trigger)
+	require.Equal(t, "Issue Hook", deliveries[1].RequestHeaders["X-Gitlab-Event"])
+	require.Equal(t, "[REDACTED]", deliveries[1].RequestHeaders["X-Gitlab-Token"])
+
+	// Deliveries are stamped with the clock of the mock when they are queued.
+	require.Equal(t, clock, deliveries[0].CreatedAt)
+	require.Equal(t, clock, deliveries[1].CreatedAt)
+
+	event, err := gitlab.ParseWebhook(gitlab.EventTypeIssue, deliveries[1].RequestData)
+	require.NoError(t, err)
+	require.Equal(t, "Bug", event.(*gitlab.IssueEvent).ObjectAttributes.Title)
+
+	// The log is available over the API, newest first.
+	var loggedDeliveries []*gitlabapimock.WebhookDelivery
+
+	request, err := gitlabClient1.NewRequest(http.MethodGet, fmt.Sprintf("projects/%d/hooks/%d/events", project1.ID, hook.ID), nil, nil)
+	require.NoError(t, err)
+	_, err = gitlabClient1.Do(request, &loggedDeliveries)
+	require.NoError(t, err)
+	require.Len(t, loggedDeliveries, 2)
+	require.Equal(t, deliveries[1].ID, loggedDeliveries[0].ID)
+
+	request, err = gitlabClient1.NewRequest(http.MethodGet, fmt.Sprintf("projects/%d/hooks/%d/events", project1.ID, hook.ID), &struct {
+		Status string `url:"status"`
+	}{"server_failure"}, nil)
+	require.NoError(t, err)
+	_, err = gitlabClient1.Do(request, &loggedDeliveries)
+	require.NoError(t, err)
+	require.Len(t, loggedDeliveries, 1)
+	require.Equal(t, deliveries[0].ID, loggedDeliveries[0].ID)
+
+	// Tests need data to build the sample event from.
+	response, err := gitlabClient1.Projects.TriggerTestProjectHook(project1.ID, hook.ID, gitlab.ProjectHookEventPush)
+	require.Error(t, err)
+	require.Equal(t, 422, response.StatusCode)
+
+	response, err = gitlabClient1.Projects.TriggerTestProjectHook(project1.ID, hook.ID, gitlab.ProjectHookEvent("unknown_events"))
+	require.Error(t, err)
+	require.Equal(t, 400, response.StatusCode)
+
+	_, err = gitlabMock.CommitFiles(project1, "main", "Initial commit", map[string]string{"README.md": "# project1"}, user1)
+	require.NoError(t, err)
+
+	gitlabMock.WaitForWebhooks()
+	gitlabMock.ClearWebhookDeliveries()
+
+	// Tests are sent even for events the hook did not subscribe to.
+	response, err = gitlabClient1.Projects.TriggerTestProjectHook(project1.ID, hook.ID, gitlab.ProjectHookEventPush)
+	require.NoError(t, err)
+	require.Equal(t, 201, response.StatusCode)
+
+	deliveries = gitlabMock.GetWebhookDeliveries(hook.ID)
+	require.Len(t, deliveries, 1)
+	require.Equal(t, "push_hooks", deliveries[0].Trigger)
+
+	event, err = gitlab.ParseWebhook(gitlab.EventTypePush, deliveries[0].RequestData)
+	require.NoError(t, err)
+	require.Equal(t, "Initial commit", event.(*gitlab.PushEvent).Commits[0].Title)
+}
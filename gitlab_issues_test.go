@@ -0,0 +1,252 @@
+package gitlabapimock_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xanzy/go-gitlab"
+
+	gitlabapimock "github.com/arkadiusjonczek/go-gitlab-api-mock"
+)
+
+func Test_Issues_CreateUpdateAndFilter_ReturnsIssues(t *testing.T) {
+	gitlabMock := gitlabapimock.NewGitlabMock()
+	defer gitlabMock.Close()
+
+	user1, _ := gitlabMock.AddUser("Peter Pan", "peter.pan", "peter.pan@telekom.de")
+
+	group1 := gitlabMock.AddGroup("group1")
+	gitlabMock.AddProject("project1", group1)
+
+	server := initGitlabApiMockServer(gitlabMock)
+
+	go server.ListenAndServe()
+	defer server.Close()
+
+	time.Sleep(1 * time.Second)
+
+	gitlabClient, err := initGitlabClient()
+
+	require.NoError(t, err)
+
+	issue1, response, err := gitlabClient.Issues.CreateIssue(1, &gitlab.CreateIssueOptions{
+		Title:       gitlab.Ptr("Login fails"),
+		Description: gitlab.Ptr("The login page returns a 500"),
+		Labels:      &gitlab.LabelOptions{"bug"},
+		AssigneeIDs: &[]int{user1.ID},
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, 201, response.StatusCode)
+	require.Equal(t, 1, issue1.IID)
+	require.Equal(t, "opened", issue1.State)
+	require.Equal(t, "peter.pan", issue1.Assignee.Username)
+
+	issue2, _, err := gitlabClient.Issues.CreateIssue(1, &gitlab.CreateIssueOptions{
+		Title:        gitlab.Ptr("Add dark mode"),
+		Labels:       &gitlab.LabelOptions{"feature"},
+		Confidential: gitlab.Ptr(true),
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, 2, issue2.IID)
+
+	_, response, err = gitlabClient.Issues.CreateIssue(1, &gitlab.CreateIssueOptions{})
+
+	require.Error(t, err)
+	require.Equal(t, 400, response.StatusCode)
+
+	issues, _, err := gitlabClient.Issues.ListProjectIssues(1, &gitlab.ListProjectIssuesOptions{
+		Labels: &gitlab.LabelOptions{"bug"},
+	})
+
+	require.NoError(t, err)
+	require.Len(t, issues, 1)
+	require.Equal(t, issue1.ID, issues[0].ID)
+
+	issues, _, err = gitlabClient.Issues.ListProjectIssues(1, &gitlab.ListProjectIssuesOptions{
+		Search: gitlab.Ptr("login page"),
+	})
+
+	require.NoError(t, err)
+	require.Len(t, issues, 1)
+
+	issues, _, err = gitlabClient.Issues.ListProjectIssues(1, &gitlab.ListProjectIssuesOptions{
+		Confidential: gitlab.Ptr(true),
+	})
+
+	require.NoError(t, err)
+	require.Len(t, issues, 1)
+	require.Equal(t, issue2.ID, issues[0].ID)
+
+	issues, _, err = gitlabClient.Issues.ListProjectIssues(1, &gitlab.ListProjectIssuesOptions{
+		AssigneeID: gitlab.AssigneeID(gitlab.UserIDNone),
+	})
+
+	require.NoError(t, err)
+	require.Len(t, issues, 1)
+	require.Equal(t, issue2.ID, issues[0].ID)
+
+	issues, _, err = gitlabClient.Issues.ListProjectIssues(1, &gitlab.ListProjectIssuesOptions{
+		OrderBy: gitlab.Ptr("title"),
+		Sort:    gitlab.Ptr("asc"),
+	})
+
+	require.NoError(t, err)
+	require.Len(t, issues, 2)
+	require.Equal(t, "Add dark mode", issues[0].Title)
+
+	issue1, _, err = gitlabClient.Issues.UpdateIssue(1, issue1.IID, &gitlab.UpdateIssueOptions{
+		StateEvent:  gitlab.Ptr("close"),
+		AssigneeIDs: &[]int{},
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, "closed", issue1.State)
+	require.NotNil(t, issue1.ClosedAt)
+	require.Nil(t, issue1.Assignee)
+
+	issues, _, err = gitlabClient.Issues.ListProjectIssues(1, &gitlab.ListProjectIssuesOptions{
+		State: gitlab.Ptr("opened"),
+	})
+
+	require.NoError(t, err)
+	require.Len(t, issues, 1)
+	require.Equal(t, issue2.ID, issues[0].ID)
+
+	notes, _, err := gitlabClient.Notes.ListIssueNotes(1, issue1.IID, &gitlab.ListIssueNotesOptions{
+		Sort: gitlab.Ptr("asc"),
+	})
+
+	require.NoError(t, err)
+	require.Len(t, notes, 2)
+	require.Equal(t, "closed", notes[0].Body)
+	require.Equal(t, "unassigned @peter.pan", notes[1].Body)
+
+	issue1, _, err = gitlabClient.Issues.UpdateIssue(1, issue1.IID, &gitlab.UpdateIssueOptions{
+		StateEvent: gitlab.Ptr("reopen"),
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, "opened", issue1.State)
+	require.Nil(t, issue1.ClosedAt)
+
+	response, err = gitlabClient.Issues.DeleteIssue(1, issue2.IID)
+
+	require.NoError(t, err)
+	require.Equal(t, 204, response.StatusCode)
+
+	issue3, _, err := gitlabClient.Issues.CreateIssue(1, &gitlab.CreateIssueOptions{
+		Title: gitlab.Ptr("Add light mode"),
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, 3, issue3.IID)
+
+	issues, _, err = gitlabClient.Issues.ListProjectIssues(1, &gitlab.ListProjectIssuesOptions{
+		IIDs: &[]int{1, 2},
+	})
+
+	require.NoError(t, err)
+	require.Len(t, issues, 1)
+	require.Equal(t, issue1.ID, issues[0].ID)
+}
+
+func Test_Issues_ConfidentialAndMovedIssues_RespectsPermissions(t *testing.T) {
+	gitlabMock := gitlabapimock.NewGitlabMock()
+	defer gitlabMock.Close()
+
+	user1, _ := gitlabMock.AddUser("Peter Pan", "peter.pan", "peter.pan@telekom.de")
+	user2, _ := gitlabMock.AddUser("Petra Pan", "petra.pan", "petra.pan@telekom.de")
+	gitlabMock.AddPersonalAccessToken(user1, "token1", "token1", []string{"api"})
+	gitlabMock.AddPersonalAccessToken(user2, "token2", "token2", []string{"api"})
+
+	group1 := gitlabMock.AddGroup("group1")
+	project1 := gitlabMock.AddProject("project1", group1)
+	project2 := gitlabMock.AddProject("project2", group1)
+	gitlabMock.AddGroupMember(&gitlab.GroupMember{ID: user1.ID, AccessLevel: gitlab.ReporterPermissions}, group1)
+	gitlabMock.AddProjectMember(&gitlab.ProjectMember{ID: user2.ID, AccessLevel: gitlab.GuestPermissions}, project1)
+
+	_, err := gitlabMock.AddIssue(project2, &gitlab.CreateIssueOptions{Title: gitlab.Ptr("Existing issue")}, user1)
+	require.NoError(t, err)
+
+	server := initGitlabApiMockServer(gitlabMock)
+
+	go server.ListenAndServe()
+	defer server.Close()
+
+	time.Sleep(1 * time.Second)
+
+	gitlabClient1, err := initGitlabClientWithToken("token1")
+	require.NoError(t, err)
+	gitlabClient2, err := initGitlabClientWithToken("token2")
+	require.NoError(t, err)
+
+	issue, _, err := gitlabClient1.Issues.CreateIssue(project1.ID, &gitlab.CreateIssueOptions{
+		Title:        gitlab.Ptr("Security issue"),
+		Confidential: gitlab.Ptr(true),
+	})
+
+	require.NoError(t, err)
+
+	issues, _, err := gitlabClient2.Issues.ListProjectIssues(project1.ID, nil)
+
+	require.NoError(t, err)
+	require.Len(t, issues, 0)
+
+	_, response, err := gitlabClient2.Issues.GetIssue(project1.ID, issue.IID)
+
+	require.Error(t, err)
+	require.Equal(t, 404, response.StatusCode)
+
+	// Nor the notes of it.
+	_, response, err = gitlabClient2.Notes.ListIssueNotes(project1.ID, issue.IID, nil)
+
+	require.Error(t, err)
+	require.Equal(t, 404, response.StatusCode)
+
+	issues, _, err = gitlabClient1.Issues.ListIssues(nil)
+
+	require.NoError(t, err)
+	require.Len(t, issues, 2)
+
+	_, response, err = gitlabClient2.Issues.MoveIssue(project1.ID, issue.IID, &gitlab.MoveIssueOptions{
+		ToProjectID: gitlab.Ptr(project2.ID),
+	})
+
+	require.Error(t, err)
+	require.Equal(t, 404, response.StatusCode)
+
+	movedIssue, _, err := gitlabClient1.Issues.MoveIssue(project1.ID, issue.IID, &gitlab.MoveIssueOptions{
+		ToProjectID: gitlab.Ptr(project2.ID),
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, project2.ID, movedIssue.ProjectID)
+	require.Equal(t, 2, movedIssue.IID)
+	require.Equal(t, "group1/project2#2", movedIssue.References.Full)
+
+	issue, _, err = gitlabClient1.Issues.GetIssue(project1.ID, issue.IID)
+
+	require.NoError(t, err)
+	require.Equal(t, "closed", issue.State)
+	require.Equal(t, movedIssue.ID, issue.MovedToID)
+
+	issue, _, err = gitlabClient1.Issues.GetIssueByID(movedIssue.ID)
+
+	require.NoError(t, err)
+	require.Equal(t, "Security issue", issue.Title)
+
+	issues, _, err = gitlabClient1.Issues.ListGroupIssues(group1.ID, &gitlab.ListGroupIssuesOptions{
+		State: gitlab.Ptr("opened"),
+	})
+
+	require.NoError(t, err)
+	require.Len(t, issues, 2)
+
+	response, err = gitlabClient1.Issues.DeleteIssue(project2.ID, movedIssue.IID)
+
+	require.Error(t, err)
+	require.Equal(t, 403, response.StatusCode)
+}
@@ -0,0 +1,211 @@
+package gitlabapimock_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xanzy/go-gitlab"
+
+	gitlabapimock "github.com/arkadiusjonczek/go-gitlab-api-mock"
+)
+
+const (
+	sshKey1 = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIF5fx3UdkJvJ6535XA7lzJS3dBmreXZyZQghBz4AQUon peter.pan@telekom.de"
+	sshKey2 = "ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAABAQDefGJERIrw/22dkb5DdBZIm17B+I/5x5vdO8tHqr5DD52EAqj8D4FMDNf+yLhwPhCCVOx4bahIVDgK/myfyHKjxPNefZS9YmuoSUWIZJPgDYZOlgj9wq+lJbeFYrdCvk0mxHjpYkp00AuqWp/CtW9n1GAzLi1+ioPEfx5Qhnle7+RBdNM/oLMNHiELXd5z/XNiF8khOxfcl3DcSa3rseDzFE8rzee9EHqWgbWEUGVPUvAjBjoTvIVEOlsPP86RBz/wFZPWND0wsApGMu0qgKIsMk7fJNpf5/78qu8P4yxwjJ8/w9+GHZ7suJZS/I4NJNudehULKcZ61Ejtkp3b9SGV deploy"
+
+	gpgKey1 = `-----BEGIN PGP PUBLIC KEY BLOCK-----
+
+mDMEatWG5xYJKwYBBAHaRw8BAQdAGHooF8+Md9956tSeq/aFStJGM+PUl/j5KpnB
+lUBbjka0IFBldGVyIFBhbiA8cGV0ZXIucGFuQHRlbGVrb20uZGU+iJAEExYIADgW
+IQQ5wi87NrKq98fbHIG6O8g+ad7HiwUCatWG5wIbAwULCQgHAgYVCgkICwIEFgID
+AQIeAQIXgAAKCRC6O8g+ad7HiwZ0AP9tsY+648tLSwEyL6cYk/uhMDSUoWi1y8CL
+Qwan8ZkJfgEAi/TlMXB5c4/P8Hrx619tiaSRjEVe/+UEyrMVl7OIzQo=
+=C9RW
+-----END PGP PUBLIC KEY BLOCK-----`
+)
+
+func Test_Keys_RegisterAndLookUpByFingerprint(t *testing.T) {
+	gitlabMock := gitlabapimock.NewGitlabMock()
+	defer gitlabMock.Close()
+
+	admin, _ := gitlabMock.AddUser("Administrator", "root", "admin@telekom.de")
+	admin.IsAdmin = true
+	user1, _ := gitlabMock.AddUser("Peter Pan", "peter.pan", "peter.pan@telekom.de")
+	gitlabMock.AddPersonalAccessToken(admin, "admin", "admin-token", []string{"api"})
+	gitlabMock.AddPersonalAccessToken(user1, "token1", "token1", []string{"api"})
+
+	server := initGitlabApiMockServer(gitlabMock)
+
+	go server.ListenAndServe()
+	defer server.Close()
+
+	time.Sleep(1 * time.Second)
+
+	adminClient, err := initGitlabClientWithToken("admin-token")
+	require.NoError(t, err)
+
+	gitlabClient1, err := initGitlabClientWithToken("token1")
+	require.NoError(t, err)
+
+	_, resp, err := gitlabClient1.Users.AddSSHKey(&gitlab.AddSSHKeyOptions{Title: gitlab.Ptr("laptop"), Key: gitlab.Ptr("ssh-ed25519 AAAAinvalid")})
+	require.Error(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	_, resp, err = gitlabClient1.Users.AddSSHKey(&gitlab.AddSSHKeyOptions{Title: gitlab.Ptr("laptop"), Key: gitlab.Ptr("ssh-foo AAAAC3NzaC1lZDI1NTE5")})
+	require.Error(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	key, _, err := gitlabClient1.Users.AddSSHKey(&gitlab.AddSSHKeyOptions{Title: gitlab.Ptr("laptop"), Key: gitlab.Ptr(sshKey1)})
+	require.NoError(t, err)
+	require.Equal(t, "laptop", key.Title)
+
+	// Keys are unique across all users.
+	_, resp, err = adminClient.Users.AddSSHKeyForUser(admin.ID, &gitlab.AddSSHKeyOptions{Title: gitlab.Ptr("copy"), Key: gitlab.Ptr(sshKey1)})
+	require.Error(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	keys, _, err := adminClient.Users.ListSSHKeysForUser("peter.pan", nil)
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	require.Equal(t, key.ID, keys[0].ID)
+
+	// Only admins look up keys, by their MD5 or SHA256 fingerprint.
+	_, resp, err = gitlabClient1.Keys.GetKeyByFingerprint(&gitlab.GetKeyByFingerprintOptions{Fingerprint: "SHA256:PYUp4P/QCBdXiPPAWn5WkQgZbLs/UqTvlD3P2okKPOo"})
+	require.Error(t, err)
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	keyWithUser, _, err := adminClient.Keys.GetKeyByFingerprint(&gitlab.GetKeyByFingerprintOptions{Fingerprint: "SHA256:PYUp4P/QCBdXiPPAWn5WkQgZbLs/UqTvlD3P2okKPOo"})
+	require.NoError(t, err)
+	require.Equal(t, key.ID, keyWithUser.ID)
+	require.Equal(t, "peter.pan", keyWithUser.User.Username)
+
+	keyWithUser, _, err = adminClient.Keys.GetKeyByFingerprint(&gitlab.GetKeyByFingerprintOptions{Fingerprint: "92:2d:14:42:57:ca:7b:de:35:50:57:ba:8e:e5:98:08"})
+	require.NoError(t, err)
+	require.Equal(t, key.ID, keyWithUser.ID)
+
+	_, err = gitlabClient1.Users.DeleteSSHKey(key.ID)
+	require.NoError(t, err)
+
+	_, resp, err = adminClient.Keys.GetKeyWithUser(key.ID)
+	require.Error(t, err)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	_, resp, err = gitlabClient1.Users.AddGPGKey(&gitlab.AddGPGKeyOptions{Key: gitlab.Ptr("-----BEGIN PGP PUBLIC KEY BLOCK-----\n\nnot a key\n-----END PGP PUBLIC KEY BLOCK-----")})
+	require.Error(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	gpgKey, _, err := gitlabClient1.Users.AddGPGKey(&gitlab.AddGPGKeyOptions{Key: gitlab.Ptr(gpgKey1)})
+	require.NoError(t, err)
+	require.Equal(t, gpgKey1, gpgKey.Key)
+
+	_, resp, err = adminClient.Users.AddGPGKeyForUser(admin.ID, &gitlab.AddGPGKeyOptions{Key: gitlab.Ptr(gpgKey1)})
+	require.Error(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	gpgKeys, _, err := gitlabClient1.Users.ListGPGKeysForUser(user1.ID)
+	require.NoError(t, err)
+	require.Len(t, gpgKeys, 1)
+
+	_, err = gitlabClient1.Users.DeleteGPGKey(gpgKey.ID)
+	require.NoError(t, err)
+
+	gpgKeys, _, err = gitlabClient1.Users.ListGPGKeys()
+	require.NoError(t, err)
+	require.Empty(t, gpgKeys)
+}
+
+func Test_DeployKeys_EnableAcrossProjects(t *testing.T) {
+	gitlabMock := gitlabapimock.NewGitlabMock()
+	defer gitlabMock.Close()
+
+	user1, _ := gitlabMock.AddUser("Peter Pan", "peter.pan", "peter.pan@telekom.de")
+	user2, _ := gitlabMock.AddUser("Captain Hook", "captain.hook", "captain.hook@telekom.de")
+	gitlabMock.AddPersonalAccessToken(user1, "token1", "token1", []string{"api"})
+	gitlabMock.AddPersonalAccessToken(user2, "token2", "token2", []string{"api"})
+
+	group1 := gitlabMock.AddGroup("group1")
+	project1 := gitlabMock.AddProject("project1", group1)
+	project2 := gitlabMock.AddProject("project2", group1)
+	project3 := gitlabMock.AddProject("project3", group1)
+	gitlabMock.AddProjectMember(&gitlab.ProjectMember{ID: user1.ID, AccessLevel: gitlab.MaintainerPermissions}, project1)
+	gitlabMock.AddProjectMember(&gitlab.ProjectMember{ID: user1.ID, AccessLevel: gitlab.MaintainerPermissions}, project2)
+	gitlabMock.AddProjectMember(&gitlab.ProjectMember{ID: user2.ID, AccessLevel: gitlab.MaintainerPermissions}, project3)
+
+	server := initGitlabApiMockServer(gitlabMock)
+
+	go server.ListenAndServe()
+	defer server.Close()
+
+	time.Sleep(1 * time.Second)
+
+	gitlabClient1, err := initGitlabClientWithToken("token1")
+	require.NoError(t, err)
+
+	gitlabClient2, err := initGitlabClientWithToken("token2")
+	require.NoError(t, err)
+
+	deployKey, _, err := gitlabClient1.DeployKeys.AddDeployKey(project1.ID, &gitlab.AddDeployKeyOptions{
+		Title:   gitlab.Ptr("deploy"),
+		Key:     gitlab.Ptr(sshKey2),
+		CanPush: gitlab.Ptr(true),
+	})
+	require.NoError(t, err)
+	require.True(t, deployKey.CanPush)
+
+	_, resp, err := gitlabClient1.DeployKeys.AddDeployKey(project1.ID, &gitlab.AddDeployKeyOptions{Title: gitlab.Ptr("deploy"), Key: gitlab.Ptr(sshKey2)})
+	require.Error(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	// Enabled keys are shared by the projects, pushing is allowed per project.
+	enabledKey, _, err := gitlabClient1.DeployKeys.EnableDeployKey(project2.ID, deployKey.ID)
+	require.NoError(t, err)
+	require.Equal(t, deployKey.ID, enabledKey.ID)
+	require.False(t, enabledKey.CanPush)
+
+	// Maintainers of other projects may not use the key.
+	_, resp, err = gitlabClient2.DeployKeys.EnableDeployKey(project3.ID, deployKey.ID)
+	require.Error(t, err)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	updatedKey, _, err := gitlabClient1.DeployKeys.UpdateDeployKey(project2.ID, deployKey.ID, &gitlab.UpdateDeployKeyOptions{
+		Title:   gitlab.Ptr("deploy key"),
+		CanPush: gitlab.Ptr(true),
+	})
+	require.NoError(t, err)
+	require.Equal(t, "deploy key", updatedKey.Title)
+	require.True(t, updatedKey.CanPush)
+
+	projectKeys, _, err := gitlabClient1.DeployKeys.ListProjectDeployKeys(project1.ID, nil)
+	require.NoError(t, err)
+	require.Len(t, projectKeys, 1)
+	require.Equal(t, "deploy key", projectKeys[0].Title)
+
+	instanceKeys, _, err := gitlabClient1.DeployKeys.ListAllDeployKeys(nil)
+	require.Error(t, err)
+	require.Nil(t, instanceKeys)
+
+	_, err = gitlabClient1.DeployKeys.DeleteDeployKey(project1.ID, deployKey.ID)
+	require.NoError(t, err)
+
+	_, resp, err = gitlabClient1.DeployKeys.GetDeployKey(project1.ID, deployKey.ID)
+	require.Error(t, err)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	key, _, err := gitlabClient1.DeployKeys.GetDeployKey(project2.ID, deployKey.ID)
+	require.NoError(t, err)
+	require.True(t, key.CanPush)
+
+	// Keys used as deploy keys can not be added to users.
+	_, resp, err = gitlabClient2.Users.AddSSHKey(&gitlab.AddSSHKeyOptions{Title: gitlab.Ptr("laptop"), Key: gitlab.Ptr(sshKey2)})
+	require.Error(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	// The key is deleted with the last project it is enabled in.
+	_, err = gitlabClient1.DeployKeys.DeleteDeployKey(project2.ID, deployKey.ID)
+	require.NoError(t, err)
+
+	_, _, err = gitlabClient2.Users.AddSSHKey(&gitlab.AddSSHKeyOptions{Title: gitlab.Ptr("laptop"), Key: gitlab.Ptr(sshKey2)})
+	require.NoError(t, err)
+}
@@ -0,0 +1,218 @@
+package gitlabapimock_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xanzy/go-gitlab"
+
+	gitlabapimock "github.com/arkadiusjonczek/go-gitlab-api-mock"
+)
+
+func Test_Labels_ProjectLabels_InheritGroupLabelsAndValidateIssues(t *testing.T) {
+	gitlabMock := gitlabapimock.NewGitlabMock()
+	defer gitlabMock.Close()
+
+	group1 := gitlabMock.AddGroup("group1")
+	subgroup1 := gitlabMock.AddSubgroup("subgroup1", group1)
+	project1 := gitlabMock.AddProject("project1", subgroup1)
+
+	_, err := gitlabMock.AddGroupLabel(group1, &gitlab.CreateLabelOptions{Name: gitlab.Ptr("bug"), Color: gitlab.Ptr("#d9534f")})
+	require.NoError(t, err)
+
+	server := initGitlabApiMockServer(gitlabMock)
+
+	go server.ListenAndServe()
+	defer server.Close()
+
+	time.Sleep(1 * time.Second)
+
+	gitlabClient, err := initGitlabClient()
+
+	require.NoError(t, err)
+
+	for _, name := range []string{"priority::high", "priority::low"} {
+		_, response, err := gitlabClient.Labels.CreateLabel(project1.ID, &gitlab.CreateLabelOptions{
+			Name:  gitlab.Ptr(name),
+			Color: gitlab.Ptr("#eeeeee"),
+		})
+
+		require.NoError(t, err)
+		require.Equal(t, 201, response.StatusCode)
+	}
+
+	_, response, err := gitlabClient.Labels.CreateLabel(project1.ID, &gitlab.CreateLabelOptions{
+		Name:  gitlab.Ptr("bug"),
+		Color: gitlab.Ptr("#eeeeee"),
+	})
+
+	require.Error(t, err)
+	require.Equal(t, 409, response.StatusCode)
+
+	_, response, err = gitlabClient.Labels.CreateLabel(project1.ID, &gitlab.CreateLabelOptions{
+		Name:  gitlab.Ptr("feature"),
+		Color: gitlab.Ptr("green-ish"),
+	})
+
+	require.Error(t, err)
+	require.Equal(t, 400, response.StatusCode)
+
+	labels, _, err := gitlabClient.Labels.ListLabels(project1.ID, nil)
+
+	require.NoError(t, err)
+	require.Len(t, labels, 3)
+	require.Equal(t, "bug", labels[0].Name)
+	require.False(t, labels[0].IsProjectLabel)
+	require.Equal(t, "#FFFFFF", labels[0].TextColor)
+	require.True(t, labels[1].IsProjectLabel)
+	require.Equal(t, "#333333", labels[1].TextColor)
+
+	labels, _, err = gitlabClient.Labels.ListLabels(project1.ID, &gitlab.ListLabelsOptions{
+		IncludeAncestorGroups: gitlab.Ptr(false),
+	})
+
+	require.NoError(t, err)
+	require.Len(t, labels, 2)
+
+	_, response, err = gitlabClient.Issues.CreateIssue(project1.ID, &gitlab.CreateIssueOptions{
+		Title:  gitlab.Ptr("Login fails"),
+		Labels: &gitlab.LabelOptions{"bug", "unknown"},
+	})
+
+	require.Error(t, err)
+	require.Equal(t, 400, response.StatusCode)
+
+	issue, _, err := gitlabClient.Issues.CreateIssue(project1.ID, &gitlab.CreateIssueOptions{
+		Title:  gitlab.Ptr("Login fails"),
+		Labels: &gitlab.LabelOptions{"bug", "priority::low"},
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, gitlab.Labels{"bug", "priority::low"}, issue.Labels)
+
+	issue, _, err = gitlabClient.Issues.UpdateIssue(project1.ID, issue.IID, &gitlab.UpdateIssueOptions{
+		AddLabels: &gitlab.LabelOptions{"priority::high"},
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, gitlab.Labels{"bug", "priority::high"}, issue.Labels)
+
+	labels, _, err = gitlabClient.Labels.ListLabels(project1.ID, &gitlab.ListLabelsOptions{
+		WithCounts: gitlab.Ptr(true),
+		Search:     gitlab.Ptr("priority"),
+	})
+
+	require.NoError(t, err)
+	require.Len(t, labels, 2)
+	require.Equal(t, "priority::high", labels[0].Name)
+	require.Equal(t, 1, labels[0].OpenIssuesCount)
+	require.Equal(t, 0, labels[1].OpenIssuesCount)
+}
+
+func Test_Labels_UpdateAndSubscribe_UpdatesIssuesAndMergeRequests(t *testing.T) {
+	gitlabMock := gitlabapimock.NewGitlabMock()
+	defer gitlabMock.Close()
+
+	user1, _ := gitlabMock.AddUser("Peter Pan", "peter.pan", "peter.pan@telekom.de")
+	user2, _ := gitlabMock.AddUser("Petra Pan", "petra.pan", "petra.pan@telekom.de")
+	gitlabMock.AddPersonalAccessToken(user1, "token1", "token1", []string{"api"})
+	gitlabMock.AddPersonalAccessToken(user2, "token2", "token2", []string{"api"})
+
+	group1 := gitlabMock.AddGroup("group1")
+	project1 := gitlabMock.AddProject("project1", group1)
+	gitlabMock.AddGroupMember(&gitlab.GroupMember{ID: user1.ID, AccessLevel: gitlab.MaintainerPermissions}, group1)
+	gitlabMock.AddProjectMember(&gitlab.ProjectMember{ID: user2.ID, AccessLevel: gitlab.DeveloperPermissions}, project1)
+
+	_, err := gitlabMock.CommitFiles(project1, "main", "Initial commit", map[string]string{"README.md": "# project1\n"}, nil)
+	require.NoError(t, err)
+	_, err = gitlabMock.CommitFiles(project1, "feature1", "Add feature1", map[string]string{"feature1.txt": "feature1\n"}, nil)
+	require.NoError(t, err)
+
+	server := initGitlabApiMockServer(gitlabMock)
+
+	go server.ListenAndServe()
+	defer server.Close()
+
+	time.Sleep(1 * time.Second)
+
+	gitlabClient1, err := initGitlabClientWithToken("token1")
+	require.NoError(t, err)
+	gitlabClient2, err := initGitlabClientWithToken("token2")
+	require.NoError(t, err)
+
+	_, response, err := gitlabClient2.GroupLabels.CreateGroupLabel(group1.ID, &gitlab.CreateGroupLabelOptions{
+		Name:  gitlab.Ptr("feature"),
+		Color: gitlab.Ptr("#428bca"),
+	})
+
+	require.Error(t, err)
+	require.Equal(t, 403, response.StatusCode)
+
+	groupLabel, _, err := gitlabClient1.GroupLabels.CreateGroupLabel(group1.ID, &gitlab.CreateGroupLabelOptions{
+		Name:  gitlab.Ptr("feature"),
+		Color: gitlab.Ptr("#428bca"),
+	})
+
+	require.NoError(t, err)
+
+	mergeRequest, _, err := gitlabClient2.MergeRequests.CreateMergeRequest(project1.ID, &gitlab.CreateMergeRequestOptions{
+		Title:        gitlab.Ptr("Add feature1"),
+		SourceBranch: gitlab.Ptr("feature1"),
+		TargetBranch: gitlab.Ptr("main"),
+		Labels:       &gitlab.LabelOptions{"feature"},
+	})
+
+	require.NoError(t, err)
+
+	groupLabel, _, err = gitlabClient1.GroupLabels.UpdateGroupLabel(group1.ID, &gitlab.UpdateGroupLabelOptions{
+		Name:     gitlab.Ptr("feature"),
+		NewName:  gitlab.Ptr("enhancement"),
+		Priority: gitlab.Ptr(1),
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, "enhancement", groupLabel.Name)
+	require.Equal(t, 1, groupLabel.Priority)
+
+	mergeRequest, _, err = gitlabClient2.MergeRequests.GetMergeRequest(project1.ID, mergeRequest.IID, nil)
+
+	require.NoError(t, err)
+	require.Equal(t, gitlab.Labels{"enhancement"}, mergeRequest.Labels)
+
+	label, response, err := gitlabClient2.Labels.SubscribeToLabel(project1.ID, groupLabel.ID)
+
+	require.NoError(t, err)
+	require.Equal(t, 201, response.StatusCode)
+	require.True(t, label.Subscribed)
+
+	_, response, _ = gitlabClient2.Labels.SubscribeToLabel(project1.ID, groupLabel.ID)
+
+	require.Equal(t, 304, response.StatusCode)
+
+	label, _, err = gitlabClient1.Labels.GetLabel(project1.ID, "enhancement")
+
+	require.NoError(t, err)
+	require.False(t, label.Subscribed)
+	require.Equal(t, 1, label.OpenMergeRequestsCount)
+
+	response, err = gitlabClient2.Labels.UnsubscribeFromLabel(project1.ID, groupLabel.ID)
+
+	require.NoError(t, err)
+	require.Equal(t, 201, response.StatusCode)
+
+	response, err = gitlabClient1.Labels.DeleteLabel(project1.ID, groupLabel.ID, nil)
+
+	require.Error(t, err)
+	require.Equal(t, 404, response.StatusCode)
+
+	response, err = gitlabClient1.GroupLabels.DeleteGroupLabel(group1.ID, groupLabel.ID, nil)
+
+	require.NoError(t, err)
+	require.Equal(t, 204, response.StatusCode)
+
+	mergeRequest, _, err = gitlabClient2.MergeRequests.GetMergeRequest(project1.ID, mergeRequest.IID, nil)
+
+	require.NoError(t, err)
+	require.Empty(t, mergeRequest.Labels)
+}
@@ -0,0 +1,325 @@
+package gitlabapimock_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xanzy/go-gitlab"
+
+	gitlabapimock "github.com/arkadiusjonczek/go-gitlab-api-mock"
+)
+
+func Test_MergeRequests_CreateAndFilterMergeRequests_ReturnsMergeRequests(t *testing.T) {
+	gitlabMock := gitlabapimock.NewGitlabMock()
+	defer gitlabMock.Close()
+
+	group1 := gitlabMock.AddGroup("group1")
+	project1 := gitlabMock.AddProject("project1", group1)
+
+	_, err := gitlabMock.CommitFiles(project1, "main", "Initial commit", map[string]string{"README.md": "# project1\n"}, nil)
+	require.NoError(t, err)
+	_, err = gitlabMock.CommitFiles(project1, "feature1", "Add feature1", map[string]string{"feature1.txt": "feature1\n"}, nil)
+	require.NoError(t, err)
+	_, err = gitlabMock.CommitFiles(project1, "feature2", "Add feature2", map[string]string{"feature2.txt": "feature2\n"}, nil)
+	require.NoError(t, err)
+
+	server := initGitlabApiMockServer(gitlabMock)
+
+	go server.ListenAndServe()
+	defer server.Close()
+
+	time.Sleep(1 * time.Second)
+
+	gitlabClient, err := initGitlabClient()
+
+	require.NoError(t, err)
+
+	mergeRequest1, response, err := gitlabClient.MergeRequests.CreateMergeRequest(1, &gitlab.CreateMergeRequestOptions{
+		Title:        gitlab.Ptr("Add feature1"),
+		SourceBranch: gitlab.Ptr("feature1"),
+		TargetBranch: gitlab.Ptr("main"),
+		Labels:       &gitlab.LabelOptions{"feature"},
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, 201, response.StatusCode)
+	require.Equal(t, 1, mergeRequest1.IID)
+	require.Equal(t, "opened", mergeRequest1.State)
+	require.Equal(t, "mergeable", mergeRequest1.DetailedMergeStatus)
+	require.Equal(t, "1", mergeRequest1.ChangesCount)
+
+	_, response, err = gitlabClient.MergeRequests.CreateMergeRequest(1, &gitlab.CreateMergeRequestOptions{
+		Title:        gitlab.Ptr("Add feature1 again"),
+		SourceBranch: gitlab.Ptr("feature1"),
+		TargetBranch: gitlab.Ptr("main"),
+	})
+
+	require.Error(t, err)
+	require.Equal(t, 409, response.StatusCode)
+
+	mergeRequest2, _, err := gitlabClient.MergeRequests.CreateMergeRequest(1, &gitlab.CreateMergeRequestOptions{
+		Title:        gitlab.Ptr("Draft: Add feature2"),
+		SourceBranch: gitlab.Ptr("feature2"),
+		TargetBranch: gitlab.Ptr("main"),
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, 2, mergeRequest2.IID)
+	require.True(t, mergeRequest2.Draft)
+	require.Equal(t, "draft_status", mergeRequest2.DetailedMergeStatus)
+
+	mergeRequests, _, err := gitlabClient.MergeRequests.ListProjectMergeRequests(1, &gitlab.ListProjectMergeRequestsOptions{
+		Labels: &gitlab.LabelOptions{"feature"},
+	})
+
+	require.NoError(t, err)
+	require.Len(t, mergeRequests, 1)
+	require.Equal(t, 1, mergeRequests[0].IID)
+
+	mergeRequest2, _, err = gitlabClient.MergeRequests.UpdateMergeRequest(1, 2, &gitlab.UpdateMergeRequestOptions{
+		StateEvent: gitlab.Ptr("close"),
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, "closed", mergeRequest2.State)
+
+	mergeRequests, _, err = gitlabClient.MergeRequests.ListProjectMergeRequests(1, &gitlab.ListProjectMergeRequestsOptions{
+		State: gitlab.Ptr("opened"),
+	})
+
+	require.NoError(t, err)
+	require.Len(t, mergeRequests, 1)
+	require.Equal(t, 1, mergeRequests[0].IID)
+
+	mergeRequest2, _, err = gitlabClient.MergeRequests.UpdateMergeRequest(1, 2, &gitlab.UpdateMergeRequestOptions{
+		StateEvent: gitlab.Ptr("reopen"),
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, "opened", mergeRequest2.State)
+
+	changes, _, err := gitlabClient.MergeRequests.ListMergeRequestDiffs(1, 1, nil)
+
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	require.Equal(t, "feature1.txt", changes[0].NewPath)
+	require.True(t, changes[0].NewFile)
+}
+
+func Test_MergeRequests_UpdateMergeRequestWithRejectedStateEvent_KeepsMergeRequest(t *testing.T) {
+	gitlabMock := gitlabapimock.NewGitlabMock()
+	defer gitlabMock.Close()
+
+	group1 := gitlabMock.AddGroup("group1")
+	project1 := gitlabMock.AddProject("project1", group1)
+
+	_, err := gitlabMock.CommitFiles(project1, "main", "Initial commit", map[string]string{"README.md": "# project1\n"}, nil)
+	require.NoError(t, err)
+	_, err = gitlabMock.CommitFiles(project1, "feature1", "Add feature1", map[string]string{"feature1.txt": "feature1\n"}, nil)
+	require.NoError(t, err)
+
+	server := initGitlabApiMockServer(gitlabMock)
+
+	go server.ListenAndServe()
+	defer server.Close()
+
+	time.Sleep(1 * time.Second)
+
+	gitlabClient, err := initGitlabClient()
+
+	require.NoError(t, err)
+
+	_, _, err = gitlabClient.MergeRequests.CreateMergeRequest(1, &gitlab.CreateMergeRequestOptions{
+		Title:        gitlab.Ptr("Add feature1"),
+		SourceBranch: gitlab.Ptr("feature1"),
+		TargetBranch: gitlab.Ptr("main"),
+	})
+
+	require.NoError(t, err)
+
+	_, response, err := gitlabClient.MergeRequests.UpdateMergeRequest(1, 1, &gitlab.UpdateMergeRequestOptions{
+		Title:      gitlab.Ptr("Add feature1 and more"),
+		StateEvent: gitlab.Ptr("merge"),
+	})
+
+	require.Error(t, err)
+	require.Equal(t, 400, response.StatusCode)
+
+	mergeRequest1, _, err := gitlabClient.MergeRequests.GetMergeRequest(1, 1, nil)
+
+	require.NoError(t, err)
+	require.Equal(t, "Add feature1", mergeRequest1.Title)
+
+	_, _, err = gitlabClient.MergeRequests.UpdateMergeRequest(1, 1, &gitlab.UpdateMergeRequestOptions{
+		StateEvent: gitlab.Ptr("close"),
+	})
+
+	require.NoError(t, err)
+
+	_, _, err = gitlabClient.MergeRequests.CreateMergeRequest(1, &gitlab.CreateMergeRequestOptions{
+		Title:        gitlab.Ptr("Add feature1 again"),
+		SourceBranch: gitlab.Ptr("feature1"),
+		TargetBranch: gitlab.Ptr("main"),
+	})
+
+	require.NoError(t, err)
+
+	_, response, err = gitlabClient.MergeRequests.UpdateMergeRequest(1, 1, &gitlab.UpdateMergeRequestOptions{
+		Title:      gitlab.Ptr("Add feature1 and more"),
+		StateEvent: gitlab.Ptr("reopen"),
+	})
+
+	require.Error(t, err)
+	require.Equal(t, 409, response.StatusCode)
+
+	mergeRequest1, _, err = gitlabClient.MergeRequests.GetMergeRequest(1, 1, nil)
+
+	require.NoError(t, err)
+	require.Equal(t, "Add feature1", mergeRequest1.Title)
+	require.Equal(t, "closed", mergeRequest1.State)
+}
+
+func Test_MergeRequests_AcceptMergeRequest_MergesSourceBranch(t *testing.T) {
+	gitlabMock := gitlabapimock.NewGitlabMock()
+	defer gitlabMock.Close()
+
+	user1, _ := gitlabMock.AddUser("Peter Pan", "peter.pan", "peter.pan@telekom.de")
+	user2, _ := gitlabMock.AddUser("Petra Pan", "petra.pan", "petra.pan@telekom.de")
+	gitlabMock.AddPersonalAccessToken(user1, "token1", "token1", []string{"api"})
+	gitlabMock.AddPersonalAccessToken(user2, "token2", "token2", []string{"api"})
+
+	group1 := gitlabMock.AddGroup("group1")
+	project1 := gitlabMock.AddProject("project1", group1)
+	project1.ApprovalsBeforeMerge = 1
+	gitlabMock.AddProjectMember(&gitlab.ProjectMember{ID: user1.ID, AccessLevel: gitlab.DeveloperPermissions}, project1)
+	gitlabMock.AddProjectMember(&gitlab.ProjectMember{ID: user2.ID, AccessLevel: gitlab.MaintainerPermissions}, project1)
+
+	_, err := gitlabMock.CommitFiles(project1, "main", "Initial commit", map[string]string{"README.md": "# project1\n"}, nil)
+	require.NoError(t, err)
+	_, err = gitlabMock.CommitFiles(project1, "feature1", "Change readme", map[string]string{"README.md": "# feature1\n"}, user1)
+	require.NoError(t, err)
+	_, err = gitlabMock.CommitFiles(project1, "main", "Change readme on main", map[string]string{"README.md": "# main\n"}, nil)
+	require.NoError(t, err)
+	_, err = gitlabMock.CommitFiles(project1, "feature2", "Add feature2", map[string]string{"feature2.txt": "feature2\n"}, user1)
+	require.NoError(t, err)
+
+	_, err = gitlabMock.AddMergeRequest(project1, &gitlab.CreateMergeRequestOptions{
+		Title:        gitlab.Ptr("Change readme"),
+		SourceBranch: gitlab.Ptr("feature1"),
+		TargetBranch: gitlab.Ptr("main"),
+	}, user1)
+	require.NoError(t, err)
+
+	server := initGitlabApiMockServer(gitlabMock)
+
+	go server.ListenAndServe()
+	defer server.Close()
+
+	time.Sleep(1 * time.Second)
+
+	gitlabClient1, err := initGitlabClientWithToken("token1")
+	require.NoError(t, err)
+	gitlabClient2, err := initGitlabClientWithToken("token2")
+	require.NoError(t, err)
+
+	mergeRequest1, _, err := gitlabClient1.MergeRequests.GetMergeRequest(1, 1, nil)
+
+	require.NoError(t, err)
+	require.True(t, mergeRequest1.HasConflicts)
+	require.Equal(t, "conflict", mergeRequest1.DetailedMergeStatus)
+
+	_, response, err := gitlabClient2.MergeRequests.AcceptMergeRequest(1, 1, nil)
+
+	require.Error(t, err)
+	require.Equal(t, 406, response.StatusCode)
+
+	mergeRequest2, _, err := gitlabClient1.MergeRequests.CreateMergeRequest(1, &gitlab.CreateMergeRequestOptions{
+		Title:              gitlab.Ptr("Add feature2"),
+		SourceBranch:       gitlab.Ptr("feature2"),
+		TargetBranch:       gitlab.Ptr("main"),
+		RemoveSourceBranch: gitlab.Ptr(true),
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, "not_approved", mergeRequest2.DetailedMergeStatus)
+
+	approvals, response, err := gitlabClient2.MergeRequestApprovals.ApproveMergeRequest(1, 2, nil)
+
+	require.NoError(t, err)
+	require.Equal(t, 201, response.StatusCode)
+	require.Len(t, approvals.ApprovedBy, 1)
+	require.Equal(t, "petra.pan", approvals.ApprovedBy[0].User.Username)
+
+	mergeRequest2, response, err = gitlabClient2.MergeRequests.AcceptMergeRequest(1, 2, nil)
+
+	require.NoError(t, err)
+	require.Equal(t, 200, response.StatusCode)
+	require.Equal(t, "merged", mergeRequest2.State)
+	require.Equal(t, "petra.pan", mergeRequest2.MergedBy.Username)
+
+	branch, _, err := gitlabClient2.Branches.GetBranch(1, "main")
+
+	require.NoError(t, err)
+	require.Equal(t, mergeRequest2.MergeCommitSHA, branch.Commit.ID)
+
+	_, response, err = gitlabClient2.Branches.GetBranch(1, "feature2")
+
+	require.Error(t, err)
+	require.Equal(t, 404, response.StatusCode)
+}
+
+func Test_MergeRequests_ApproveMergeRequest_ResetsApprovalsOnPush(t *testing.T) {
+	gitlabMock := gitlabapimock.NewGitlabMock()
+	defer gitlabMock.Close()
+
+	group1 := gitlabMock.AddGroup("group1")
+	project1 := gitlabMock.AddProject("project1", group1)
+
+	_, err := gitlabMock.CommitFiles(project1, "main", "Initial commit", map[string]string{"README.md": "# project1\n"}, nil)
+	require.NoError(t, err)
+	_, err = gitlabMock.CommitFiles(project1, "feature1", "Add feature1", map[string]string{"feature1.txt": "feature1\n"}, nil)
+	require.NoError(t, err)
+
+	_, err = gitlabMock.AddMergeRequest(project1, &gitlab.CreateMergeRequestOptions{
+		Title:        gitlab.Ptr("Add feature1"),
+		SourceBranch: gitlab.Ptr("feature1"),
+		TargetBranch: gitlab.Ptr("main"),
+	}, nil)
+	require.NoError(t, err)
+
+	server := initGitlabApiMockServer(gitlabMock)
+
+	go server.ListenAndServe()
+	defer server.Close()
+
+	time.Sleep(1 * time.Second)
+
+	gitlabClient, err := initGitlabClient()
+	require.NoError(t, err)
+
+	// Without authentication anonymous approvals are allowed.
+	approvals, response, err := gitlabClient.MergeRequestApprovals.ApproveMergeRequest(1, 1, nil)
+
+	require.NoError(t, err)
+	require.Equal(t, 201, response.StatusCode)
+	require.Len(t, approvals.ApprovedBy, 1)
+
+	_, err = gitlabMock.CommitFiles(project1, "feature1", "Change feature1", map[string]string{"feature1.txt": "changed\n"}, nil)
+	require.NoError(t, err)
+
+	approvals, _, err = gitlabClient.MergeRequestApprovals.GetConfiguration(1, 1)
+
+	require.NoError(t, err)
+	require.Empty(t, approvals.ApprovedBy)
+
+	_, _, err = gitlabClient.MergeRequests.UpdateMergeRequest(1, 1, &gitlab.UpdateMergeRequestOptions{
+		StateEvent: gitlab.Ptr("close"),
+	})
+	require.NoError(t, err)
+
+	_, response, err = gitlabClient.MergeRequestApprovals.ApproveMergeRequest(1, 1, nil)
+
+	require.Error(t, err)
+	require.Equal(t, 405, response.StatusCode)
+}
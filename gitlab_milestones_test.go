@@ -0,0 +1,276 @@
+package gitlabapimock_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xanzy/go-gitlab"
+
+	gitlabapimock "github.com/arkadiusjonczek/go-gitlab-api-mock"
+)
+
+func Test_Milestones_MoveIssuesBetweenMilestones_ReturnsLinkedIssues(t *testing.T) {
+	gitlabMock := gitlabapimock.NewGitlabMock()
+	defer gitlabMock.Close()
+
+	group1 := gitlabMock.AddGroup("group1")
+	project1 := gitlabMock.AddProject("project1", group1)
+
+	groupMilestone, err := gitlabMock.AddGroupMilestone(group1, &gitlab.CreateMilestoneOptions{Title: gitlab.Ptr("Q1")})
+	require.NoError(t, err)
+
+	server := initGitlabApiMockServer(gitlabMock)
+
+	go server.ListenAndServe()
+	defer server.Close()
+
+	time.Sleep(1 * time.Second)
+
+	gitlabClient, err := initGitlabClient()
+
+	require.NoError(t, err)
+
+	dueDate, _ := gitlab.ParseISOTime("2024-01-31")
+	startDate, _ := gitlab.ParseISOTime("2024-02-01")
+
+	_, response, err := gitlabClient.Milestones.CreateMilestone(project1.ID, &gitlab.CreateMilestoneOptions{
+		Title:     gitlab.Ptr("1.0"),
+		StartDate: &startDate,
+		DueDate:   &dueDate,
+	})
+
+	require.Error(t, err)
+	require.Equal(t, 400, response.StatusCode)
+
+	milestone1, response, err := gitlabClient.Milestones.CreateMilestone(project1.ID, &gitlab.CreateMilestoneOptions{
+		Title:   gitlab.Ptr("1.0"),
+		DueDate: &dueDate,
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, 201, response.StatusCode)
+	require.Equal(t, 1, milestone1.IID)
+	require.Equal(t, "active", milestone1.State)
+	require.True(t, *milestone1.Expired)
+
+	milestone2, _, err := gitlabClient.Milestones.CreateMilestone(project1.ID, &gitlab.CreateMilestoneOptions{
+		Title: gitlab.Ptr("2.0"),
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, 2, milestone2.IID)
+
+	_, response, err = gitlabClient.Milestones.CreateMilestone(project1.ID, &gitlab.CreateMilestoneOptions{
+		Title: gitlab.Ptr("Q1"),
+	})
+
+	require.Error(t, err)
+	require.Equal(t, 400, response.StatusCode)
+
+	issue, _, err := gitlabClient.Issues.CreateIssue(project1.ID, &gitlab.CreateIssueOptions{
+		Title:       gitlab.Ptr("Login fails"),
+		MilestoneID: gitlab.Ptr(milestone1.ID),
+		Weight:      gitlab.Ptr(3),
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, "1.0", issue.Milestone.Title)
+
+	issue, _, err = gitlabClient.Issues.UpdateIssue(project1.ID, issue.IID, &gitlab.UpdateIssueOptions{
+		MilestoneID: gitlab.Ptr(milestone2.ID),
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, "2.0", issue.Milestone.Title)
+
+	issues, _, err := gitlabClient.Milestones.GetMilestoneIssues(project1.ID, milestone1.ID, nil)
+
+	require.NoError(t, err)
+	require.Len(t, issues, 0)
+
+	issues, _, err = gitlabClient.Milestones.GetMilestoneIssues(project1.ID, milestone2.ID, nil)
+
+	require.NoError(t, err)
+	require.Len(t, issues, 1)
+
+	issues, _, err = gitlabClient.Issues.ListProjectIssues(project1.ID, &gitlab.ListProjectIssuesOptions{
+		Milestone: gitlab.Ptr("2.0"),
+	})
+
+	require.NoError(t, err)
+	require.Len(t, issues, 1)
+
+	events, _, err := gitlabClient.ResourceMilestoneEvents.ListIssueMilestoneEvents(project1.ID, issue.IID, nil)
+
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	require.Equal(t, "add", events[0].Action)
+	require.Equal(t, "remove", events[1].Action)
+	require.Equal(t, milestone1.ID, events[1].Milestone.ID)
+	require.Equal(t, "add", events[2].Action)
+
+	issue, _, err = gitlabClient.Issues.UpdateIssue(project1.ID, issue.IID, &gitlab.UpdateIssueOptions{
+		MilestoneID: gitlab.Ptr(groupMilestone.ID),
+		StateEvent:  gitlab.Ptr("close"),
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, "Q1", issue.Milestone.Title)
+
+	burndownEvents, _, err := gitlabClient.GroupMilestones.GetGroupMilestoneBurndownChartEvents(group1.ID, groupMilestone.ID, nil)
+
+	require.NoError(t, err)
+	require.Len(t, burndownEvents, 2)
+	require.Equal(t, "add", *burndownEvents[0].Action)
+	require.Equal(t, 3, *burndownEvents[0].Weight)
+	require.Equal(t, "closed", *burndownEvents[1].Action)
+
+	groupIssues, _, err := gitlabClient.GroupMilestones.GetGroupMilestoneIssues(group1.ID, groupMilestone.ID, nil)
+
+	require.NoError(t, err)
+	require.Len(t, groupIssues, 1)
+
+	milestone2, _, err = gitlabClient.Milestones.UpdateMilestone(project1.ID, milestone2.ID, &gitlab.UpdateMilestoneOptions{
+		StateEvent: gitlab.Ptr("close"),
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, "closed", milestone2.State)
+
+	milestones, _, err := gitlabClient.Milestones.ListMilestones(project1.ID, &gitlab.ListMilestonesOptions{
+		State: gitlab.Ptr("active"),
+	})
+
+	require.NoError(t, err)
+	require.Len(t, milestones, 1)
+	require.Equal(t, "1.0", milestones[0].Title)
+
+	milestones, _, err = gitlabClient.Milestones.ListMilestones(project1.ID, &gitlab.ListMilestonesOptions{
+		IncludeParentMilestones: gitlab.Ptr(true),
+	})
+
+	require.NoError(t, err)
+	require.Len(t, milestones, 3)
+
+	response, err = gitlabClient.GroupMilestones.DeleteGroupMilestone(group1.ID, groupMilestone.ID)
+
+	require.NoError(t, err)
+	require.Equal(t, 204, response.StatusCode)
+
+	issue, _, err = gitlabClient.Issues.GetIssue(project1.ID, issue.IID)
+
+	require.NoError(t, err)
+	require.Nil(t, issue.Milestone)
+}
+
+func Test_Milestones_ExpiredMilestone_FollowsMockClock(t *testing.T) {
+	gitlabMock := gitlabapimock.NewGitlabMock()
+	defer gitlabMock.Close()
+
+	gitlabMock.SetClock(time.Date(2024, time.January, 15, 9, 0, 0, 0, time.UTC))
+
+	group1 := gitlabMock.AddGroup("group1")
+	project1 := gitlabMock.AddProject("project1", group1)
+
+	server := initGitlabApiMockServer(gitlabMock)
+
+	go server.ListenAndServe()
+	defer server.Close()
+
+	time.Sleep(1 * time.Second)
+
+	gitlabClient, err := initGitlabClient()
+
+	require.NoError(t, err)
+
+	dueDate, _ := gitlab.ParseISOTime("2024-01-31")
+
+	milestone1, _, err := gitlabClient.Milestones.CreateMilestone(project1.ID, &gitlab.CreateMilestoneOptions{
+		Title:   gitlab.Ptr("1.0"),
+		DueDate: &dueDate,
+	})
+
+	require.NoError(t, err)
+	require.False(t, *milestone1.Expired)
+	require.Equal(t, time.Date(2024, time.January, 15, 9, 0, 0, 0, time.UTC), milestone1.CreatedAt.UTC())
+
+	gitlabMock.AdvanceClock(30 * 24 * time.Hour)
+
+	milestones, _, err := gitlabClient.Milestones.ListMilestones(project1.ID, nil)
+
+	require.NoError(t, err)
+	require.Len(t, milestones, 1)
+	require.True(t, *milestones[0].Expired)
+}
+
+func Test_Milestones_GroupIterationCadence_SchedulesIterations(t *testing.T) {
+	gitlabMock := gitlabapimock.NewGitlabMock()
+	defer gitlabMock.Close()
+
+	group1 := gitlabMock.AddGroup("group1")
+	subgroup1 := gitlabMock.AddSubgroup("subgroup1", group1)
+	project1 := gitlabMock.AddProject("project1", subgroup1)
+
+	_, err := gitlabMock.AddIterationCadence(group1, "Sprints", time.Now().AddDate(0, 0, -21), 2, 2)
+	require.NoError(t, err)
+
+	manualCadence, err := gitlabMock.AddIterationCadence(subgroup1, "Releases", time.Now(), 0, 0)
+	require.NoError(t, err)
+
+	_, err = gitlabMock.AddIteration(manualCadence, "Release 1", time.Now(), time.Now().AddDate(0, 0, 10))
+	require.NoError(t, err)
+
+	_, err = gitlabMock.AddIteration(manualCadence, "Release 2", time.Now().AddDate(0, 0, 5), time.Now().AddDate(0, 0, 20))
+	require.Error(t, err)
+
+	issue, err := gitlabMock.AddIssue(project1, &gitlab.CreateIssueOptions{Title: gitlab.Ptr("Login fails")}, nil)
+	require.NoError(t, err)
+
+	server := initGitlabApiMockServer(gitlabMock)
+
+	go server.ListenAndServe()
+	defer server.Close()
+
+	time.Sleep(1 * time.Second)
+
+	gitlabClient, err := initGitlabClient()
+
+	require.NoError(t, err)
+
+	iterations, _, err := gitlabClient.GroupIterations.ListGroupIterations(group1.ID, nil)
+
+	require.NoError(t, err)
+	require.Len(t, iterations, 4)
+	require.Equal(t, 3, iterations[0].State)
+	require.Equal(t, 2, iterations[1].State)
+	require.Equal(t, 1, iterations[2].State)
+	require.Equal(t, 4, iterations[3].Sequence)
+
+	iterations, _, err = gitlabClient.GroupIterations.ListGroupIterations(subgroup1.ID, &gitlab.ListGroupIterationsOptions{
+		State:            gitlab.Ptr("current"),
+		IncludeAncestors: gitlab.Ptr(false),
+	})
+
+	require.NoError(t, err)
+	require.Len(t, iterations, 1)
+	require.Equal(t, "Release 1", iterations[0].Title)
+
+	projectIterations, _, err := gitlabClient.ProjectIterations.ListProjectIterations(project1.ID, &gitlab.ListProjectIterationsOptions{
+		State: gitlab.Ptr("opened"),
+	})
+
+	require.NoError(t, err)
+	require.Len(t, projectIterations, 4)
+
+	err = gitlabMock.SetIssueIteration(issue, iterations[0])
+	require.NoError(t, err)
+
+	issues, _, err := gitlabClient.Issues.ListProjectIssues(project1.ID, &gitlab.ListProjectIssuesOptions{
+		IterationID: gitlab.Ptr(iterations[0].ID),
+	})
+
+	require.NoError(t, err)
+	require.Len(t, issues, 1)
+	require.Equal(t, "Release 1", issues[0].Iteration.Title)
+}
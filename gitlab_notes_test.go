@@ -0,0 +1,265 @@
+package gitlabapimock_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xanzy/go-gitlab"
+
+	gitlabapimock "github.com/arkadiusjonczek/go-gitlab-api-mock"
+)
+
+func Test_Notes_MergeRequestDiscussions_ResolveThreads(t *testing.T) {
+	gitlabMock := gitlabapimock.NewGitlabMock()
+	defer gitlabMock.Close()
+
+	group1 := gitlabMock.AddGroup("group1")
+	project1 := gitlabMock.AddProject("project1", group1)
+	project1.OnlyAllowMergeIfAllDiscussionsAreResolved = true
+
+	_, err := gitlabMock.CommitFiles(project1, "main", "Initial commit", map[string]string{"README.md": "# project1\n"}, nil)
+	require.NoError(t, err)
+	_, err = gitlabMock.CommitFiles(project1, "feature1", "Add feature1", map[string]string{"feature1.txt": "line1\nline2\n"}, nil)
+	require.NoError(t, err)
+
+	mergeRequest, err := gitlabMock.AddMergeRequest(project1, &gitlab.CreateMergeRequestOptions{
+		Title:        gitlab.Ptr("Add feature1"),
+		SourceBranch: gitlab.Ptr("feature1"),
+		TargetBranch: gitlab.Ptr("main"),
+	}, nil)
+	require.NoError(t, err)
+
+	server := initGitlabApiMockServer(gitlabMock)
+
+	go server.ListenAndServe()
+	defer server.Close()
+
+	time.Sleep(1 * time.Second)
+
+	gitlabClient, err := initGitlabClient()
+
+	require.NoError(t, err)
+
+	note, response, err := gitlabClient.Notes.CreateMergeRequestNote(1, mergeRequest.IID, &gitlab.CreateMergeRequestNoteOptions{
+		Body: gitlab.Ptr("Looks good"),
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, 201, response.StatusCode)
+	require.False(t, note.Resolvable)
+
+	_, _, err = gitlabClient.MergeRequests.UpdateMergeRequest(1, mergeRequest.IID, &gitlab.UpdateMergeRequestOptions{
+		AddLabels: &gitlab.LabelOptions{"bug"},
+	})
+
+	require.NoError(t, err)
+
+	notes, _, err := gitlabClient.Notes.ListMergeRequestNotes(1, mergeRequest.IID, &gitlab.ListMergeRequestNotesOptions{
+		Sort: gitlab.Ptr("asc"),
+	})
+
+	require.NoError(t, err)
+	require.Len(t, notes, 2)
+	require.Equal(t, "Looks good", notes[0].Body)
+	require.True(t, notes[1].System)
+	require.Equal(t, "added ~bug label", notes[1].Body)
+
+	position := &gitlab.PositionOptions{
+		BaseSHA:      gitlab.Ptr(mergeRequest.DiffRefs.BaseSha),
+		StartSHA:     gitlab.Ptr(mergeRequest.DiffRefs.StartSha),
+		HeadSHA:      gitlab.Ptr(mergeRequest.DiffRefs.HeadSha),
+		PositionType: gitlab.Ptr("text"),
+		NewPath:      gitlab.Ptr("feature1.txt"),
+		NewLine:      gitlab.Ptr(3),
+	}
+
+	_, response, err = gitlabClient.Discussions.CreateMergeRequestDiscussion(1, mergeRequest.IID, &gitlab.CreateMergeRequestDiscussionOptions{
+		Body:     gitlab.Ptr("Typo"),
+		Position: position,
+	})
+
+	require.Error(t, err)
+	require.Equal(t, 400, response.StatusCode)
+
+	position.NewLine = gitlab.Ptr(2)
+
+	discussion, response, err := gitlabClient.Discussions.CreateMergeRequestDiscussion(1, mergeRequest.IID, &gitlab.CreateMergeRequestDiscussionOptions{
+		Body:     gitlab.Ptr("Typo"),
+		Position: position,
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, 201, response.StatusCode)
+	require.False(t, discussion.IndividualNote)
+	require.Equal(t, gitlab.DiffNote, discussion.Notes[0].Type)
+	require.True(t, discussion.Notes[0].Resolvable)
+	require.Equal(t, 2, discussion.Notes[0].Position.NewLine)
+
+	reply, _, err := gitlabClient.Discussions.AddMergeRequestDiscussionNote(1, mergeRequest.IID, discussion.ID, &gitlab.AddMergeRequestDiscussionNoteOptions{
+		Body: gitlab.Ptr("Fixed"),
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, gitlab.DiffNote, reply.Type)
+
+	mergeRequest, _, err = gitlabClient.MergeRequests.GetMergeRequest(1, mergeRequest.IID, nil)
+
+	require.NoError(t, err)
+	require.False(t, mergeRequest.BlockingDiscussionsResolved)
+	require.Equal(t, "discussions_not_resolved", mergeRequest.DetailedMergeStatus)
+
+	discussion, _, err = gitlabClient.Discussions.ResolveMergeRequestDiscussion(1, mergeRequest.IID, discussion.ID, &gitlab.ResolveMergeRequestDiscussionOptions{
+		Resolved: gitlab.Ptr(true),
+	})
+
+	require.NoError(t, err)
+	require.Len(t, discussion.Notes, 2)
+	require.True(t, discussion.Notes[0].Resolved)
+	require.True(t, discussion.Notes[1].Resolved)
+
+	mergeRequest, _, err = gitlabClient.MergeRequests.GetMergeRequest(1, mergeRequest.IID, nil)
+
+	require.NoError(t, err)
+	require.True(t, mergeRequest.BlockingDiscussionsResolved)
+	require.Equal(t, "mergeable", mergeRequest.DetailedMergeStatus)
+	require.Equal(t, 3, mergeRequest.UserNotesCount)
+
+	_, err = gitlabMock.CommitFiles(project1, "feature1", "Fix typo", map[string]string{"feature1.txt": "line1\nline2 fixed\n"}, nil)
+	require.NoError(t, err)
+
+	discussions, _, err := gitlabClient.Discussions.ListMergeRequestDiscussions(1, mergeRequest.IID, nil)
+
+	require.NoError(t, err)
+	require.Len(t, discussions, 4)
+	require.True(t, discussions[3].Notes[0].System)
+	require.Contains(t, discussions[3].Notes[0].Body, "added 1 commit")
+	require.Contains(t, discussions[3].Notes[0].Body, "Fix typo")
+}
+
+func Test_Notes_IssueSnippetAndCommitNotes_ReturnsNotes(t *testing.T) {
+	gitlabMock := gitlabapimock.NewGitlabMock()
+	defer gitlabMock.Close()
+
+	user1, _ := gitlabMock.AddUser("Peter Pan", "peter.pan", "peter.pan@telekom.de")
+	user2, _ := gitlabMock.AddUser("Petra Pan", "petra.pan", "petra.pan@telekom.de")
+	user3, _ := gitlabMock.AddUser("Paul Pan", "paul.pan", "paul.pan@telekom.de")
+	gitlabMock.AddPersonalAccessToken(user1, "token1", "token1", []string{"api"})
+	gitlabMock.AddPersonalAccessToken(user2, "token2", "token2", []string{"api"})
+	gitlabMock.AddPersonalAccessToken(user3, "token3", "token3", []string{"api"})
+
+	group1 := gitlabMock.AddGroup("group1")
+	project1 := gitlabMock.AddProject("project1", group1)
+	gitlabMock.AddProjectMember(&gitlab.ProjectMember{ID: user1.ID, AccessLevel: gitlab.ReporterPermissions}, project1)
+	gitlabMock.AddProjectMember(&gitlab.ProjectMember{ID: user2.ID, AccessLevel: gitlab.ReporterPermissions}, project1)
+
+	commit, err := gitlabMock.CommitFiles(project1, "main", "Initial commit", map[string]string{"README.md": "# project1\n"}, nil)
+	require.NoError(t, err)
+
+	issue, err := gitlabMock.AddIssue(project1, &gitlab.CreateIssueOptions{Title: gitlab.Ptr("Bug")}, user1)
+	require.NoError(t, err)
+
+	snippet, err := gitlabMock.AddProjectSnippet(project1, &gitlab.CreateProjectSnippetOptions{Title: gitlab.Ptr("Example"), FileName: gitlab.Ptr("example.go")}, user1)
+	require.NoError(t, err)
+
+	server := initGitlabApiMockServer(gitlabMock)
+
+	go server.ListenAndServe()
+	defer server.Close()
+
+	time.Sleep(1 * time.Second)
+
+	gitlabClient1, err := initGitlabClientWithToken("token1")
+	require.NoError(t, err)
+	gitlabClient2, err := initGitlabClientWithToken("token2")
+	require.NoError(t, err)
+	gitlabClient3, err := initGitlabClientWithToken("token3")
+	require.NoError(t, err)
+
+	note, response, err := gitlabClient1.Notes.CreateIssueNote(1, issue.IID, &gitlab.CreateIssueNoteOptions{
+		Body: gitlab.Ptr("Can reproduce"),
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, 201, response.StatusCode)
+	require.Equal(t, "Issue", note.NoteableType)
+	require.Equal(t, issue.IID, note.NoteableIID)
+	require.Equal(t, "peter.pan", note.Author.Username)
+
+	_, response, err = gitlabClient2.Notes.UpdateIssueNote(1, issue.IID, note.ID, &gitlab.UpdateIssueNoteOptions{
+		Body: gitlab.Ptr("Cannot reproduce"),
+	})
+
+	require.Error(t, err)
+	require.Equal(t, 403, response.StatusCode)
+
+	note, _, err = gitlabClient1.Notes.UpdateIssueNote(1, issue.IID, note.ID, &gitlab.UpdateIssueNoteOptions{
+		Body: gitlab.Ptr("Can reproduce on main"),
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, "Can reproduce on main", note.Body)
+
+	response, err = gitlabClient1.Notes.DeleteIssueNote(1, issue.IID, note.ID)
+
+	require.NoError(t, err)
+	require.Equal(t, 204, response.StatusCode)
+
+	notes, _, err := gitlabClient1.Notes.ListIssueNotes(1, issue.IID, nil)
+
+	require.NoError(t, err)
+	require.Len(t, notes, 0)
+
+	_, _, err = gitlabClient2.Notes.CreateSnippetNote(1, snippet.ID, &gitlab.CreateSnippetNoteOptions{
+		Body: gitlab.Ptr("Nice snippet"),
+	})
+
+	require.NoError(t, err)
+
+	notes, _, err = gitlabClient1.Notes.ListSnippetNotes(1, snippet.ID, nil)
+
+	require.NoError(t, err)
+	require.Len(t, notes, 1)
+	require.Equal(t, "Snippet", notes[0].NoteableType)
+
+	discussion, _, err := gitlabClient2.Discussions.CreateCommitDiscussion(1, commit.ID, &gitlab.CreateCommitDiscussionOptions{
+		Body: gitlab.Ptr("Why this commit?"),
+	})
+
+	require.NoError(t, err)
+	require.False(t, discussion.Notes[0].Resolvable)
+	require.Equal(t, commit.ID, discussion.Notes[0].CommitID)
+
+	_, _, err = gitlabClient1.Discussions.AddCommitDiscussionNote(1, commit.ID, discussion.ID, &gitlab.AddCommitDiscussionNoteOptions{
+		Body: gitlab.Ptr("Initial setup"),
+	})
+
+	require.NoError(t, err)
+
+	discussions, _, err := gitlabClient1.Discussions.ListCommitDiscussions(1, commit.ShortID, nil)
+
+	require.NoError(t, err)
+	require.Len(t, discussions, 1)
+	require.Len(t, discussions[0].Notes, 2)
+
+	// Notes and discussions need at least guest access to the project.
+	_, response, err = gitlabClient3.Notes.ListSnippetNotes(1, snippet.ID, nil)
+
+	require.Error(t, err)
+	require.Equal(t, 403, response.StatusCode)
+
+	_, response, err = gitlabClient3.Notes.GetSnippetNote(1, snippet.ID, notes[0].ID)
+
+	require.Error(t, err)
+	require.Equal(t, 403, response.StatusCode)
+
+	_, response, err = gitlabClient3.Discussions.ListCommitDiscussions(1, commit.ShortID, nil)
+
+	require.Error(t, err)
+	require.Equal(t, 403, response.StatusCode)
+
+	_, response, err = gitlabClient3.Discussions.GetCommitDiscussion(1, commit.ShortID, discussion.ID)
+
+	require.Error(t, err)
+	require.Equal(t, 403, response.StatusCode)
+}
@@ -0,0 +1,321 @@
+package gitlabapimock_test
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xanzy/go-gitlab"
+
+	gitlabapimock "github.com/arkadiusjonczek/go-gitlab-api-mock"
+)
+
+const oauthRedirectURI = "http://localhost:8080/callback"
+
+// oauthAuthorize sends the authorization request and returns the query of the redirect.
+func oauthAuthorize(t *testing.T, params url.Values) (string, url.Values) {
+	t.Helper()
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+
+	response, err := client.Get(fmt.Sprintf("http://%s/oauth/authorize?%s", GitlabHost, params.Encode()))
+	require.NoError(t, err)
+	defer response.Body.Close()
+
+	require.Equal(t, http.StatusFound, response.StatusCode)
+
+	location, err := url.Parse(response.Header.Get("Location"))
+	require.NoError(t, err)
+
+	return location.Scheme + "://" + location.Host + location.Path, location.Query()
+}
+
+// oauthPost posts the form to the OAuth endpoint and decodes the JSON response.
+func oauthPost(t *testing.T, path string, form url.Values) (int, map[string]interface{}) {
+	t.Helper()
+
+	response, err := http.PostForm(fmt.Sprintf("http://%s%s", GitlabHost, path), form)
+	require.NoError(t, err)
+	defer response.Body.Close()
+
+	var body map[string]interface{}
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&body))
+
+	return response.StatusCode, body
+}
+
+// oauthGet gets the OAuth endpoint with the access token and decodes the JSON response.
+func oauthGet(t *testing.T, path string, accessToken string) (int, map[string]interface{}) {
+	t.Helper()
+
+	request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("http://%s%s", GitlabHost, path), nil)
+	require.NoError(t, err)
+
+	request.Header.Set("Authorization", "Bearer "+accessToken)
+
+	response, err := http.DefaultClient.Do(request)
+	require.NoError(t, err)
+	defer response.Body.Close()
+
+	var body map[string]interface{}
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&body))
+
+	return response.StatusCode, body
+}
+
+func Test_OAuth_AuthorizationCodeFlowWithPKCE(t *testing.T) {
+	gitlabMock := gitlabapimock.NewGitlabMock()
+	defer gitlabMock.Close()
+
+	admin, _ := gitlabMock.AddUser("Administrator", "root", "admin@telekom.de")
+	admin.IsAdmin = true
+	user1, _ := gitlabMock.AddUser("Peter Pan", "peter.pan", "peter.pan@telekom.de")
+	gitlabMock.AddPersonalAccessToken(admin, "admin", "admin-token", []string{"api"})
+	gitlabMock.AddPersonalAccessToken(user1, "token1", "token1", []string{"api"})
+
+	group1 := gitlabMock.AddGroup("group1")
+	gitlabMock.AddGroupMember(&gitlab.GroupMember{ID: user1.ID, AccessLevel: gitlab.DeveloperPermissions}, group1)
+
+	server := initGitlabApiMockServer(gitlabMock)
+
+	go server.ListenAndServe()
+	defer server.Close()
+
+	time.Sleep(1 * time.Second)
+
+	adminClient, err := initGitlabClientWithToken("admin-token")
+	require.NoError(t, err)
+
+	gitlabClient1, err := initGitlabClientWithToken("token1")
+	require.NoError(t, err)
+
+	// Applications are managed by admins.
+	_, resp, err := gitlabClient1.Applications.CreateApplication(&gitlab.CreateApplicationOptions{
+		Name:        gitlab.Ptr("webapp"),
+		RedirectURI: gitlab.Ptr(oauthRedirectURI),
+		Scopes:      gitlab.Ptr("openid read_user"),
+	})
+	require.Error(t, err)
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	application, _, err := adminClient.Applications.CreateApplication(&gitlab.CreateApplicationOptions{
+		Name:        gitlab.Ptr("webapp"),
+		RedirectURI: gitlab.Ptr(oauthRedirectURI),
+		Scopes:      gitlab.Ptr("openid read_user"),
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, application.Secret)
+	require.True(t, application.Confidential)
+
+	codeVerifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	codeChallenge := sha256.Sum256([]byte(codeVerifier))
+
+	params := url.Values{
+		"client_id":             {application.ApplicationID},
+		"redirect_uri":          {oauthRedirectURI},
+		"response_type":         {"code"},
+		"state":                 {"xyz"},
+		"scope":                 {"openid read_user"},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(codeChallenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+
+	// Authorizing needs a signed in user who consents.
+	location, _ := oauthAuthorize(t, params)
+	require.Equal(t, fmt.Sprintf("http://%s/users/sign_in", GitlabHost), location)
+
+	gitlabMock.SignIn(user1)
+	gitlabMock.SetOAuthConsent(func(application *gitlab.Application, user *gitlab.User, scopes []string) bool {
+		return false
+	})
+
+	location, query := oauthAuthorize(t, params)
+	require.Equal(t, oauthRedirectURI, location)
+	require.Equal(t, "access_denied", query.Get("error"))
+	require.Equal(t, "xyz", query.Get("state"))
+
+	gitlabMock.SetOAuthConsent(func(consentApplication *gitlab.Application, user *gitlab.User, scopes []string) bool {
+		return consentApplication.ID == application.ID && user.ID == user1.ID
+	})
+
+	_, query = oauthAuthorize(t, params)
+	code := query.Get("code")
+	require.NotEmpty(t, code)
+	require.Equal(t, "xyz", query.Get("state"))
+
+	tokenForm := url.Values{
+		"grant_type":    {"authorization_code"},
+		"client_id":     {application.ApplicationID},
+		"client_secret": {application.Secret},
+		"code":          {code},
+		"redirect_uri":  {oauthRedirectURI},
+	}
+
+	statusCode, body := oauthPost(t, "/oauth/token", tokenForm)
+	require.Equal(t, http.StatusBadRequest, statusCode)
+	require.Equal(t, "invalid_grant", body["error"])
+
+	tokenForm.Set("code_verifier", codeVerifier)
+
+	statusCode, body = oauthPost(t, "/oauth/token", tokenForm)
+	require.Equal(t, http.StatusOK, statusCode)
+	require.Equal(t, "Bearer", body["token_type"])
+	require.Equal(t, "openid read_user", body["scope"])
+
+	accessToken := body["access_token"].(string)
+	refreshToken := body["refresh_token"].(string)
+
+	// Codes are only exchanged once.
+	statusCode, _ = oauthPost(t, "/oauth/token", tokenForm)
+	require.Equal(t, http.StatusBadRequest, statusCode)
+
+	oauthClient, err := gitlab.NewOAuthClient(accessToken, gitlab.WithBaseURL(fmt.Sprintf("http://%s", GitlabHost)))
+	require.NoError(t, err)
+
+	currentUser, _, err := oauthClient.Users.CurrentUser()
+	require.NoError(t, err)
+	require.Equal(t, user1.ID, currentUser.ID)
+
+	_, resp, err = oauthClient.Projects.ListProjects(nil)
+	require.Error(t, err)
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	statusCode, body = oauthGet(t, "/oauth/token/info", accessToken)
+	require.Equal(t, http.StatusOK, statusCode)
+	require.EqualValues(t, user1.ID, body["resource_owner_id"])
+	require.Equal(t, map[string]interface{}{"uid": application.ApplicationID}, body["application"])
+
+	statusCode, body = oauthGet(t, "/oauth/userinfo", accessToken)
+	require.Equal(t, http.StatusOK, statusCode)
+	require.Equal(t, fmt.Sprint(user1.ID), body["sub"])
+	require.Equal(t, "peter.pan", body["preferred_username"])
+	require.Equal(t, []interface{}{"group1"}, body["groups"])
+
+	// Refreshing revokes the previous access token.
+	statusCode, body = oauthPost(t, "/oauth/token", url.Values{
+		"grant_type":    {"refresh_token"},
+		"client_id":     {application.ApplicationID},
+		"client_secret": {application.Secret},
+		"refresh_token": {refreshToken},
+	})
+	require.Equal(t, http.StatusOK, statusCode)
+
+	refreshedToken := body["access_token"].(string)
+	require.NotEqual(t, accessToken, refreshedToken)
+
+	_, resp, err = oauthClient.Users.CurrentUser()
+	require.Error(t, err)
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	statusCode, _ = oauthGet(t, "/oauth/token/info", refreshedToken)
+	require.Equal(t, http.StatusOK, statusCode)
+
+	statusCode, _ = oauthPost(t, "/oauth/revoke", url.Values{
+		"client_id":     {application.ApplicationID},
+		"client_secret": {application.Secret},
+		"token":         {refreshedToken},
+	})
+	require.Equal(t, http.StatusOK, statusCode)
+
+	statusCode, body = oauthGet(t, "/oauth/token/info", refreshedToken)
+	require.Equal(t, http.StatusUnauthorized, statusCode)
+	require.Equal(t, "invalid_token", body["error"])
+
+	applications, _, err := adminClient.Applications.ListApplications(nil)
+	require.NoError(t, err)
+	require.Len(t, applications, 1)
+	require.Empty(t, applications[0].Secret)
+
+	_, err = adminClient.Applications.DeleteApplication(application.ID)
+	require.NoError(t, err)
+
+	response, err := http.Get(fmt.Sprintf("http://%s/oauth/authorize?%s", GitlabHost, params.Encode()))
+	require.NoError(t, err)
+	response.Body.Close()
+	require.Equal(t, http.StatusUnauthorized, response.StatusCode)
+}
+
+func Test_OAuth_PasswordGrantAndPublicClients(t *testing.T) {
+	gitlabMock := gitlabapimock.NewGitlabMock()
+	defer gitlabMock.Close()
+
+	user1, _ := gitlabMock.AddUser("Peter Pan", "peter.pan", "peter.pan@telekom.de")
+	gitlabMock.SetUserPassword(user1, "neverland")
+	gitlabMock.SignIn(user1)
+
+	application := gitlabMock.AddApplication("cli", oauthRedirectURI, []string{"api", "read_user"}, false)
+
+	server := initGitlabApiMockServer(gitlabMock)
+
+	go server.ListenAndServe()
+	defer server.Close()
+
+	time.Sleep(1 * time.Second)
+
+	statusCode, body := oauthPost(t, "/oauth/token", url.Values{
+		"grant_type": {"password"},
+		"username":   {"peter.pan"},
+		"password":   {"captain.hook"},
+	})
+	require.Equal(t, http.StatusBadRequest, statusCode)
+	require.Equal(t, "invalid_grant", body["error"])
+
+	statusCode, body = oauthPost(t, "/oauth/token", url.Values{
+		"grant_type": {"password"},
+		"username":   {"peter.pan@telekom.de"},
+		"password":   {"neverland"},
+	})
+	require.Equal(t, http.StatusOK, statusCode)
+	require.Equal(t, "api", body["scope"])
+
+	// Issued tokens require authentication, anonymous requests are rejected from then on.
+	anonymousClient, err := initGitlabClient()
+	require.NoError(t, err)
+
+	_, resp, err := anonymousClient.Users.CurrentUser()
+	require.Error(t, err)
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	oauthClient, err := gitlab.NewOAuthClient(body["access_token"].(string), gitlab.WithBaseURL(fmt.Sprintf("http://%s", GitlabHost)))
+	require.NoError(t, err)
+
+	currentUser, _, err := oauthClient.Users.CurrentUser()
+	require.NoError(t, err)
+	require.Equal(t, "peter.pan", currentUser.Username)
+
+	statusCode, _ = oauthGet(t, "/oauth/userinfo", body["access_token"].(string))
+	require.Equal(t, http.StatusForbidden, statusCode)
+
+	// Public clients exchange codes without their secret.
+	_, query := oauthAuthorize(t, url.Values{
+		"client_id":      {application.ApplicationID},
+		"response_type":  {"code"},
+		"scope":          {"read_user"},
+		"code_challenge": {"plain-verifier-with-at-least-43-characters-abcdef"},
+	})
+
+	statusCode, body = oauthPost(t, "/oauth/token", url.Values{
+		"grant_type":    {"authorization_code"},
+		"client_id":     {application.ApplicationID},
+		"code":          {query.Get("code")},
+		"redirect_uri":  {oauthRedirectURI},
+		"code_verifier": {"plain-verifier-with-at-least-43-characters-abcdef"},
+	})
+	require.Equal(t, http.StatusOK, statusCode)
+	require.Equal(t, "read_user", body["scope"])
+	require.NotEmpty(t, body["refresh_token"])
+
+	_, query = oauthAuthorize(t, url.Values{
+		"client_id":     {application.ApplicationID},
+		"response_type": {"code"},
+		"scope":         {"sudo"},
+	})
+	require.Equal(t, "invalid_scope", query.Get("error"))
+
+}
@@ -0,0 +1,273 @@
+package gitlabapimock_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xanzy/go-gitlab"
+
+	gitlabapimock "github.com/arkadiusjonczek/go-gitlab-api-mock"
+)
+
+func Test_Pipelines_CreateAndAdvanceJobs_UpdatesPipelineStatus(t *testing.T) {
+	gitlabMock := gitlabapimock.NewGitlabMock()
+	defer gitlabMock.Close()
+
+	group1 := gitlabMock.AddGroup("group1")
+	project1 := gitlabMock.AddProject("project1", group1)
+
+	_, err := gitlabMock.CommitFiles(project1, "main", "Initial commit", map[string]string{"README.md": "# project1\n"}, nil)
+	require.NoError(t, err)
+
+	gitlabMock.SetPipelineJobs(project1, []gitlabapimock.PipelineJob{
+		{Name: "compile", Stage: "build"},
+		{Name: "unit", Stage: "test"},
+		{Name: "lint", Stage: "test", AllowFailure: true},
+		{Name: "deploy", Stage: "deploy"},
+	})
+
+	server := initGitlabApiMockServer(gitlabMock)
+
+	go server.ListenAndServe()
+	defer server.Close()
+
+	time.Sleep(1 * time.Second)
+
+	gitlabClient, err := initGitlabClient()
+
+	require.NoError(t, err)
+
+	_, response, err := gitlabClient.Pipelines.CreatePipeline(project1.ID, &gitlab.CreatePipelineOptions{Ref: gitlab.Ptr("unknown")})
+
+	require.Error(t, err)
+	require.Equal(t, 400, response.StatusCode)
+
+	pipeline, response, err := gitlabClient.Pipelines.CreatePipeline(project1.ID, &gitlab.CreatePipelineOptions{
+		Ref: gitlab.Ptr("main"),
+		Variables: &[]*gitlab.PipelineVariableOptions{
+			{Key: gitlab.Ptr("ENVIRONMENT"), Value: gitlab.Ptr("staging")},
+		},
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, 201, response.StatusCode)
+	require.Equal(t, "pending", pipeline.Status)
+	require.Equal(t, "api", pipeline.Source)
+	require.Equal(t, 1, pipeline.IID)
+
+	variables, _, err := gitlabClient.Pipelines.GetPipelineVariables(project1.ID, pipeline.ID)
+
+	require.NoError(t, err)
+	require.Len(t, variables, 1)
+	require.Equal(t, gitlab.EnvVariableType, variables[0].VariableType)
+
+	jobs, _, err := gitlabClient.Jobs.ListPipelineJobs(project1.ID, pipeline.ID, nil)
+
+	require.NoError(t, err)
+	require.Len(t, jobs, 4)
+	require.Equal(t, "pending", jobs[0].Status)
+	require.Equal(t, "created", jobs[1].Status)
+
+	mockPipeline := gitlabMock.GetPipelines(project1)[0]
+
+	compile, err := gitlabMock.GetPipelineJob(mockPipeline, "compile")
+	require.NoError(t, err)
+
+	require.Error(t, gitlabMock.SetJobStatus(compile, gitlab.Success))
+	require.NoError(t, gitlabMock.AdvanceJob(compile))
+
+	pipeline, _, err = gitlabClient.Pipelines.GetPipeline(project1.ID, pipeline.ID)
+
+	require.NoError(t, err)
+	require.Equal(t, "running", pipeline.Status)
+	require.NotNil(t, pipeline.StartedAt)
+
+	require.NoError(t, gitlabMock.AdvanceJob(compile))
+
+	jobs, _, err = gitlabClient.Jobs.ListPipelineJobs(project1.ID, pipeline.ID, &gitlab.ListJobsOptions{
+		Scope: &[]gitlab.BuildStateValue{gitlab.Pending},
+	})
+
+	require.NoError(t, err)
+	require.Len(t, jobs, 2)
+
+	unit, _ := gitlabMock.GetPipelineJob(mockPipeline, "unit")
+	lint, _ := gitlabMock.GetPipelineJob(mockPipeline, "lint")
+
+	require.NoError(t, gitlabMock.SetJobStatus(lint, gitlab.Running))
+	require.NoError(t, gitlabMock.SetJobStatus(lint, gitlab.Failed))
+	require.NoError(t, gitlabMock.SetJobStatus(unit, gitlab.Running))
+	require.NoError(t, gitlabMock.SetJobStatus(unit, gitlab.Failed))
+
+	pipeline, _, err = gitlabClient.Pipelines.GetPipeline(project1.ID, pipeline.ID)
+
+	require.NoError(t, err)
+	require.Equal(t, "failed", pipeline.Status)
+	require.NotNil(t, pipeline.FinishedAt)
+
+	deploy, _ := gitlabMock.GetPipelineJob(mockPipeline, "deploy")
+	require.Equal(t, "skipped", deploy.Status)
+
+	pipeline, response, err = gitlabClient.Pipelines.RetryPipelineBuild(project1.ID, pipeline.ID)
+
+	require.NoError(t, err)
+	require.Equal(t, 201, response.StatusCode)
+	require.Equal(t, "running", pipeline.Status)
+	require.Equal(t, "created", deploy.Status)
+
+	jobs, _, err = gitlabClient.Jobs.ListPipelineJobs(project1.ID, pipeline.ID, &gitlab.ListJobsOptions{IncludeRetried: gitlab.Ptr(true)})
+
+	require.NoError(t, err)
+	require.Len(t, jobs, 6)
+
+	unit, _ = gitlabMock.GetPipelineJob(mockPipeline, "unit")
+	require.Equal(t, "pending", unit.Status)
+
+	pipeline, _, err = gitlabClient.Pipelines.CancelPipelineBuild(project1.ID, pipeline.ID)
+
+	require.NoError(t, err)
+	require.Equal(t, "canceled", pipeline.Status)
+	require.Equal(t, "canceled", deploy.Status)
+
+	pipeline2, err := gitlabMock.AddPipeline(project1, "main", []gitlabapimock.PipelineJob{{Name: "smoke"}}, nil)
+	require.NoError(t, err)
+
+	pipelines, _, err := gitlabClient.Pipelines.ListProjectPipelines(project1.ID, &gitlab.ListProjectPipelinesOptions{Status: gitlab.Ptr(gitlab.Canceled)})
+
+	require.NoError(t, err)
+	require.Len(t, pipelines, 1)
+	require.Equal(t, pipeline.ID, pipelines[0].ID)
+
+	latest, _, err := gitlabClient.Pipelines.GetLatestPipeline(project1.ID, &gitlab.GetLatestPipelineOptions{Ref: gitlab.Ptr("main")})
+
+	require.NoError(t, err)
+	require.Equal(t, pipeline2.ID, latest.ID)
+	require.Equal(t, "push", latest.Source)
+
+	response, err = gitlabClient.Pipelines.DeletePipeline(project1.ID, pipeline.ID)
+
+	require.NoError(t, err)
+	require.Equal(t, 204, response.StatusCode)
+
+	_, response, err = gitlabClient.Pipelines.GetPipeline(project1.ID, pipeline.ID)
+
+	require.Error(t, err)
+	require.Equal(t, 404, response.StatusCode)
+}
+
+func Test_Jobs_PlayRetryCancelAndErase_FollowStateMachine(t *testing.T) {
+	gitlabMock := gitlabapimock.NewGitlabMock()
+	defer gitlabMock.Close()
+
+	user1, _ := gitlabMock.AddUser("Peter Pan", "peter.pan", "peter.pan@telekom.de")
+	user2, _ := gitlabMock.AddUser("Captain Hook", "captain.hook", "captain.hook@telekom.de")
+
+	gitlabMock.AddPersonalAccessToken(user1, "token1", "token1", []string{"api"})
+	gitlabMock.AddPersonalAccessToken(user2, "token2", "token2", []string{"api"})
+
+	group1 := gitlabMock.AddGroup("group1")
+	project1 := gitlabMock.AddProject("project1", group1)
+
+	gitlabMock.AddProjectMember(&gitlab.ProjectMember{ID: user1.ID, Username: user1.Username, AccessLevel: gitlab.DeveloperPermissions}, project1)
+	gitlabMock.AddProjectMember(&gitlab.ProjectMember{ID: user2.ID, Username: user2.Username, AccessLevel: gitlab.ReporterPermissions}, project1)
+
+	_, err := gitlabMock.CommitFiles(project1, "main", "Initial commit", map[string]string{"README.md": "# project1\n"}, user1)
+	require.NoError(t, err)
+
+	pipeline, err := gitlabMock.AddPipeline(project1, "main", []gitlabapimock.PipelineJob{
+		{Name: "build", Stage: "build"},
+		{Name: "approve", Stage: "approve", When: "manual"},
+		{Name: "deploy", Stage: "deploy"},
+		{Name: "cleanup", Stage: "deploy", When: "always"},
+	}, user1)
+	require.NoError(t, err)
+
+	build, _ := gitlabMock.GetPipelineJob(pipeline, "build")
+	require.NoError(t, gitlabMock.AdvanceJob(build))
+	require.NoError(t, gitlabMock.AdvanceJob(build))
+	require.Error(t, gitlabMock.AdvanceJob(build))
+
+	require.Equal(t, "manual", pipeline.Status)
+
+	approve, _ := gitlabMock.GetPipelineJob(pipeline, "approve")
+	deploy, _ := gitlabMock.GetPipelineJob(pipeline, "deploy")
+
+	require.Equal(t, "manual", approve.Status)
+	require.Equal(t, "created", deploy.Status)
+
+	server := initGitlabApiMockServer(gitlabMock)
+
+	go server.ListenAndServe()
+	defer server.Close()
+
+	time.Sleep(1 * time.Second)
+
+	gitlabClient1, err := initGitlabClientWithToken("token1")
+	require.NoError(t, err)
+
+	gitlabClient2, err := initGitlabClientWithToken("token2")
+	require.NoError(t, err)
+
+	_, response, err := gitlabClient1.Jobs.PlayJob(project1.ID, build.ID, nil)
+
+	require.Error(t, err)
+	require.Equal(t, 400, response.StatusCode)
+
+	_, response, err = gitlabClient2.Jobs.PlayJob(project1.ID, approve.ID, nil)
+
+	require.Error(t, err)
+	require.Equal(t, 403, response.StatusCode)
+
+	job, response, err := gitlabClient1.Jobs.PlayJob(project1.ID, approve.ID, nil)
+
+	require.NoError(t, err)
+	require.Equal(t, 200, response.StatusCode)
+	require.Equal(t, "pending", job.Status)
+	require.Equal(t, "running", job.Pipeline.Status)
+
+	require.NoError(t, gitlabMock.SetJobStatus(approve, gitlab.Running))
+	require.NoError(t, gitlabMock.SetJobStatus(approve, gitlab.Success))
+
+	job, _, err = gitlabClient2.Jobs.GetJob(project1.ID, deploy.ID)
+
+	require.NoError(t, err)
+	require.Equal(t, "pending", job.Status)
+
+	job, response, err = gitlabClient1.Jobs.CancelJob(project1.ID, deploy.ID)
+
+	require.NoError(t, err)
+	require.Equal(t, 201, response.StatusCode)
+	require.Equal(t, "canceled", job.Status)
+
+	job, response, err = gitlabClient1.Jobs.RetryJob(project1.ID, deploy.ID)
+
+	require.NoError(t, err)
+	require.Equal(t, 201, response.StatusCode)
+	require.Equal(t, "pending", job.Status)
+	require.NotEqual(t, deploy.ID, job.ID)
+
+	_, response, err = gitlabClient1.Jobs.RetryJob(project1.ID, deploy.ID)
+
+	require.Error(t, err)
+	require.Equal(t, 403, response.StatusCode)
+
+	_, response, err = gitlabClient1.Jobs.EraseJob(project1.ID, job.ID)
+
+	require.Error(t, err)
+	require.Equal(t, 403, response.StatusCode)
+
+	jobs, _, err := gitlabClient1.Jobs.ListProjectJobs(project1.ID, &gitlab.ListJobsOptions{
+		Scope: &[]gitlab.BuildStateValue{gitlab.Success},
+	})
+
+	require.NoError(t, err)
+	require.Len(t, jobs, 2)
+	require.Equal(t, approve.ID, jobs[0].ID)
+
+	job, response, err = gitlabClient1.Jobs.EraseJob(project1.ID, build.ID)
+
+	require.NoError(t, err)
+	require.Equal(t, 201, response.StatusCode)
+	require.NotNil(t, job.ErasedAt)
+}
@@ -148,6 +148,11 @@ func Test_Projects_AddProjectMember_ReturnsProjectMember(t *testing.T) {
 
 	require.NoError(t, err)
 
+	_, response, err := gitlabClient.ProjectMembers.AddProjectMember(1, &gitlab.AddProjectMemberOptions{UserID: 1})
+
+	require.Error(t, err)
+	require.Equal(t, 400, response.StatusCode)
+
 	addProjectMemberOptions := &gitlab.AddProjectMemberOptions{
 		UserID:      1,
 		AccessLevel: gitlab.Ptr(gitlab.DeveloperPermissions),
@@ -0,0 +1,217 @@
+package gitlabapimock_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xanzy/go-gitlab"
+
+	gitlabapimock "github.com/arkadiusjonczek/go-gitlab-api-mock"
+)
+
+func Test_Releases_CreateRelease_ReturnsReleaseWithLinks(t *testing.T) {
+	gitlabMock := gitlabapimock.NewGitlabMock()
+	defer gitlabMock.Close()
+
+	group1 := gitlabMock.AddGroup("group1")
+	project1 := gitlabMock.AddProject("project1", group1)
+
+	_, err := gitlabMock.CommitFiles(project1, "main", "Initial commit", map[string]string{"README.md": "# project1\n"}, nil)
+
+	require.NoError(t, err)
+
+	server := initGitlabApiMockServer(gitlabMock)
+
+	go server.ListenAndServe()
+	defer server.Close()
+
+	time.Sleep(1 * time.Second)
+
+	gitlabClient, err := initGitlabClient()
+
+	require.NoError(t, err)
+
+	createReleaseOptions := &gitlab.CreateReleaseOptions{
+		Name:        gitlab.Ptr("Release 1.0.0"),
+		TagName:     gitlab.Ptr("v1.0.0"),
+		TagMessage:  gitlab.Ptr("Version 1.0.0"),
+		Ref:         gitlab.Ptr("main"),
+		Description: gitlab.Ptr("First release"),
+		Milestones:  &[]string{"1.0"},
+		Assets: &gitlab.ReleaseAssetsOptions{
+			Links: []*gitlab.ReleaseAssetLinkOptions{
+				{
+					Name:     gitlab.Ptr("binary"),
+					URL:      gitlab.Ptr("https://example.com/binary"),
+					FilePath: gitlab.Ptr("/bin/project1"),
+					LinkType: gitlab.Ptr(gitlab.PackageLinkType),
+				},
+			},
+		},
+	}
+	release, response, err := gitlabClient.Releases.CreateRelease(1, createReleaseOptions)
+
+	require.NoError(t, err)
+	require.Equal(t, 201, response.StatusCode)
+	require.Equal(t, "Release 1.0.0", release.Name)
+	require.Equal(t, "First release", release.Description)
+	require.False(t, release.UpcomingRelease)
+	require.Len(t, release.Assets.Links, 1)
+	require.Equal(t, gitlab.PackageLinkType, release.Assets.Links[0].LinkType)
+	require.Equal(t, "/group1/project1/-/tags/v1.0.0/downloads/bin/project1", release.Assets.Links[0].DirectAssetURL)
+
+	_, response, err = gitlabClient.Releases.CreateRelease(1, createReleaseOptions)
+
+	require.Error(t, err)
+	require.Equal(t, 409, response.StatusCode)
+
+	tag, response, err := gitlabClient.Tags.GetTag(1, "v1.0.0")
+
+	require.NoError(t, err)
+	require.Equal(t, 200, response.StatusCode)
+	require.Equal(t, "Version 1.0.0", tag.Message)
+	require.Equal(t, "First release", tag.Release.Description)
+
+	link, response, err := gitlabClient.ReleaseLinks.CreateReleaseLink(1, "v1.0.0", &gitlab.CreateReleaseLinkOptions{
+		Name: gitlab.Ptr("checksums"),
+		URL:  gitlab.Ptr("https://example.com/checksums"),
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, 201, response.StatusCode)
+	require.Equal(t, gitlab.OtherLinkType, link.LinkType)
+
+	links, response, err := gitlabClient.ReleaseLinks.ListReleaseLinks(1, "v1.0.0", nil)
+
+	require.NoError(t, err)
+	require.Equal(t, 200, response.StatusCode)
+	require.Len(t, links, 2)
+}
+
+func Test_Releases_ListReleases_ReturnsUpcomingRelease(t *testing.T) {
+	gitlabMock := gitlabapimock.NewGitlabMock()
+	defer gitlabMock.Close()
+
+	group1 := gitlabMock.AddGroup("group1")
+	project1 := gitlabMock.AddProject("project1", group1)
+
+	_, err := gitlabMock.CommitFiles(project1, "main", "Initial commit", map[string]string{"README.md": "# project1\n"}, nil)
+
+	require.NoError(t, err)
+
+	_, err = gitlabMock.AddRelease(project1, &gitlab.CreateReleaseOptions{
+		TagName:    gitlab.Ptr("v1.0.0"),
+		Ref:        gitlab.Ptr("main"),
+		ReleasedAt: gitlab.Ptr(time.Now().Add(-1 * time.Hour)),
+	}, nil)
+
+	require.NoError(t, err)
+
+	_, err = gitlabMock.AddRelease(project1, &gitlab.CreateReleaseOptions{
+		TagName:    gitlab.Ptr("v2.0.0"),
+		Ref:        gitlab.Ptr("main"),
+		ReleasedAt: gitlab.Ptr(time.Now().Add(24 * time.Hour)),
+	}, nil)
+
+	require.NoError(t, err)
+
+	server := initGitlabApiMockServer(gitlabMock)
+
+	go server.ListenAndServe()
+	defer server.Close()
+
+	time.Sleep(1 * time.Second)
+
+	gitlabClient, err := initGitlabClient()
+
+	require.NoError(t, err)
+
+	releases, response, err := gitlabClient.Releases.ListReleases(1, &gitlab.ListReleasesOptions{})
+
+	require.NoError(t, err)
+	require.Equal(t, 200, response.StatusCode)
+	require.Len(t, releases, 2)
+	require.Equal(t, "v2.0.0", releases[0].TagName)
+	require.True(t, releases[0].UpcomingRelease)
+	require.Equal(t, "v1.0.0", releases[1].TagName)
+	require.False(t, releases[1].UpcomingRelease)
+
+	latestRelease, response, err := gitlabClient.Releases.GetLatestRelease(1)
+
+	require.NoError(t, err)
+	require.Equal(t, 200, response.StatusCode)
+	require.Equal(t, "v1.0.0", latestRelease.TagName)
+}
+
+func Test_Releases_RequireProjectAccess(t *testing.T) {
+	gitlabMock := gitlabapimock.NewGitlabMock()
+	defer gitlabMock.Close()
+
+	user1, _ := gitlabMock.AddUser("Peter Pan", "peter.pan", "peter.pan@telekom.de")
+	user2, _ := gitlabMock.AddUser("Petra Pan", "petra.pan", "petra.pan@telekom.de")
+	user3, _ := gitlabMock.AddUser("Paul Pan", "paul.pan", "paul.pan@telekom.de")
+	gitlabMock.AddPersonalAccessToken(user1, "token1", "token1", []string{"api"})
+	gitlabMock.AddPersonalAccessToken(user2, "token2", "token2", []string{"api"})
+
+	group1 := gitlabMock.AddGroup("group1")
+	project1 := gitlabMock.AddProject("project1", group1)
+	gitlabMock.AddProjectMember(&gitlab.ProjectMember{ID: user1.ID, AccessLevel: gitlab.ReporterPermissions}, project1)
+	gitlabMock.AddProjectMember(&gitlab.ProjectMember{ID: user2.ID, AccessLevel: gitlab.GuestPermissions}, project1)
+	gitlabMock.AddProjectMember(&gitlab.ProjectMember{ID: user3.ID, AccessLevel: gitlab.MaintainerPermissions}, project1)
+
+	_, err := gitlabMock.CommitFiles(project1, "main", "Initial commit", map[string]string{"README.md": "# project1\n"}, user3)
+
+	require.NoError(t, err)
+
+	_, err = gitlabMock.AddRelease(project1, &gitlab.CreateReleaseOptions{
+		TagName: gitlab.Ptr("v1.0.0"),
+		Ref:     gitlab.Ptr("main"),
+	}, user3)
+
+	require.NoError(t, err)
+
+	server := initGitlabApiMockServer(gitlabMock)
+
+	go server.ListenAndServe()
+	defer server.Close()
+
+	time.Sleep(1 * time.Second)
+
+	gitlabClient1, err := initGitlabClientWithToken("token1")
+
+	require.NoError(t, err)
+
+	gitlabClient2, err := initGitlabClientWithToken("token2")
+
+	require.NoError(t, err)
+
+	// Guests may not read releases.
+	_, response, err := gitlabClient2.Releases.ListReleases(project1.ID, nil)
+
+	require.Error(t, err)
+	require.Equal(t, 403, response.StatusCode)
+
+	_, response, err = gitlabClient2.ReleaseLinks.ListReleaseLinks(project1.ID, "v1.0.0", nil)
+
+	require.Error(t, err)
+	require.Equal(t, 403, response.StatusCode)
+
+	releases, response, err := gitlabClient1.Releases.ListReleases(project1.ID, nil)
+
+	require.NoError(t, err)
+	require.Equal(t, 200, response.StatusCode)
+	require.Len(t, releases, 1)
+
+	// Collecting evidence needs developer access.
+	request, err := gitlabClient1.NewRequest(http.MethodPost, fmt.Sprintf("projects/%d/releases/v1.0.0/evidence", project1.ID), nil, nil)
+
+	require.NoError(t, err)
+
+	response, err = gitlabClient1.Do(request, nil)
+
+	require.Error(t, err)
+	require.Equal(t, 403, response.StatusCode)
+}
@@ -0,0 +1,260 @@
+package gitlabapimock_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xanzy/go-gitlab"
+
+	gitlabapimock "github.com/arkadiusjonczek/go-gitlab-api-mock"
+)
+
+func Test_Runners_RegisterAndRunJobs_ReportsResults(t *testing.T) {
+	gitlabMock := gitlabapimock.NewGitlabMock()
+	defer gitlabMock.Close()
+
+	admin, _ := gitlabMock.AddUser("Administrator", "root", "root@telekom.de")
+	admin.IsAdmin = true
+	user1, _ := gitlabMock.AddUser("Peter Pan", "peter.pan", "peter.pan@telekom.de")
+	gitlabMock.AddPersonalAccessToken(admin, "token0", "token0", []string{"api"})
+	gitlabMock.AddPersonalAccessToken(user1, "token1", "token1", []string{"api"})
+
+	group1 := gitlabMock.AddGroup("group1")
+	project1 := gitlabMock.AddProject("project1", group1)
+	project2 := gitlabMock.AddProject("project2", group1)
+	gitlabMock.AddProjectMember(&gitlab.ProjectMember{ID: user1.ID, AccessLevel: gitlab.MaintainerPermissions}, project1)
+	gitlabMock.AddProjectMember(&gitlab.ProjectMember{ID: user1.ID, AccessLevel: gitlab.MaintainerPermissions}, project2)
+
+	_, err := gitlabMock.CommitFiles(project1, "main", "Initial commit", map[string]string{"README.md": "# project1\n"}, user1)
+	require.NoError(t, err)
+
+	_, err = gitlabMock.AddProjectVariable(project1, &gitlab.CreateProjectVariableOptions{Key: gitlab.Ptr("DEPLOY_TOKEN"), Value: gitlab.Ptr("s3cr3t-token"), Protected: gitlab.Ptr(true)})
+	require.NoError(t, err)
+
+	server := initGitlabApiMockServer(gitlabMock)
+
+	go server.ListenAndServe()
+	defer server.Close()
+
+	time.Sleep(1 * time.Second)
+
+	adminClient, err := initGitlabClientWithToken("token0")
+	require.NoError(t, err)
+	gitlabClient1, err := initGitlabClientWithToken("token1")
+	require.NoError(t, err)
+
+	// The registration token decides the type of the runner.
+	_, response, err := adminClient.Runners.RegisterNewRunner(&gitlab.RegisterNewRunnerOptions{Token: gitlab.Ptr("invalid")})
+
+	require.Error(t, err)
+	require.Equal(t, 403, response.StatusCode)
+
+	instanceRunner, _, err := adminClient.Runners.RegisterNewRunner(&gitlab.RegisterNewRunnerOptions{
+		Token:       gitlab.Ptr(gitlabMock.GetRunnersRegistrationToken()),
+		Description: gitlab.Ptr("shared docker runner"),
+		TagList:     gitlab.Ptr([]string{"docker"}),
+	})
+
+	require.NoError(t, err)
+	require.NotEmpty(t, instanceRunner.Token)
+
+	projectRunner, _, err := gitlabClient1.Runners.RegisterNewRunner(&gitlab.RegisterNewRunnerOptions{
+		Token:       gitlab.Ptr(project1.RunnersToken),
+		Description: gitlab.Ptr("deploy runner"),
+		TagList:     gitlab.Ptr([]string{"deploy"}),
+		RunUntagged: gitlab.Ptr(false),
+		AccessLevel: gitlab.Ptr("ref_protected"),
+	})
+
+	require.NoError(t, err)
+
+	_, err = adminClient.Runners.VerifyRegisteredRunner(&gitlab.VerifyRegisteredRunnerOptions{Token: gitlab.Ptr(projectRunner.Token)})
+	require.NoError(t, err)
+
+	gitlabMock.SetPipelineJobs(project1, []gitlabapimock.PipelineJob{
+		{Name: "build", Stage: "build", Tags: []string{"docker"}, Script: []string{"make"}, Variables: map[string]string{"GOFLAGS": "-mod=vendor"}},
+		{Name: "deploy", Stage: "deploy", Tags: []string{"deploy"}, Script: []string{"make deploy"}, AfterScript: []string{"make clean"}},
+	})
+
+	pipeline, err := gitlabMock.AddPipeline(project1, "main", nil, user1)
+	require.NoError(t, err)
+
+	// The instance runner picks up the build job, the deploy job waits for it and needs the deploy tag anyway.
+	runnerResponse, payload := requestRunnerJob(t, instanceRunner.Token)
+
+	require.Equal(t, 201, runnerResponse.StatusCode)
+	require.Equal(t, "build", payload.JobInfo.Name)
+	require.Equal(t, "main", payload.GitInfo.Ref)
+	require.Equal(t, fmt.Sprintf("http://gitlab-ci-token:%s@%s/group1/project1.git", payload.Token, GitlabHost), payload.GitInfo.RepoURL)
+	require.Equal(t, []string{"make"}, payload.Steps[0].Script)
+	require.Equal(t, "build", payload.variable("CI_JOB_NAME"))
+	require.Equal(t, "-mod=vendor", payload.variable("GOFLAGS"))
+	require.Equal(t, "s3cr3t-token", payload.variable("DEPLOY_TOKEN"))
+
+	runnerResponse, _ = requestRunnerJob(t, instanceRunner.Token)
+	require.Equal(t, 204, runnerResponse.StatusCode)
+
+	build, _ := gitlabMock.GetPipelineJob(pipeline, "build")
+	require.Equal(t, "running", build.Status)
+	require.Equal(t, instanceRunner.ID, build.Runner.ID)
+
+	// Trace chunks have to continue the trace.
+	runnerResponse = runnerRequest(t, http.MethodPatch, fmt.Sprintf("/jobs/%d/trace", payload.ID), payload.Token, "0-5", []byte("$ make\n"))
+
+	require.Equal(t, 202, runnerResponse.StatusCode)
+	require.Equal(t, "0-7", runnerResponse.Header.Get("Range"))
+
+	runnerResponse = runnerRequest(t, http.MethodPatch, fmt.Sprintf("/jobs/%d/trace", payload.ID), payload.Token, "3-10", []byte("ok\n"))
+
+	require.Equal(t, 416, runnerResponse.StatusCode)
+	require.Equal(t, "0-7", runnerResponse.Header.Get("Range"))
+
+	update, _ := json.Marshal(map[string]string{"token": payload.Token, "state": "success"})
+	runnerResponse = runnerRequest(t, http.MethodPut, fmt.Sprintf("/jobs/%d", payload.ID), "", "", update)
+
+	require.Equal(t, 200, runnerResponse.StatusCode)
+	require.Equal(t, "success", build.Status)
+	require.Equal(t, "$ make\n", gitlabMock.GetJobTrace(build))
+
+	// Only the protected project runner with the deploy tag runs the deploy job.
+	runnerResponse, _ = requestRunnerJob(t, instanceRunner.Token)
+	require.Equal(t, 204, runnerResponse.StatusCode)
+
+	runnerResponse, payload = requestRunnerJob(t, projectRunner.Token)
+
+	require.Equal(t, 201, runnerResponse.StatusCode)
+	require.Equal(t, "deploy", payload.JobInfo.Name)
+	require.Len(t, payload.Steps, 2)
+	require.Len(t, payload.Dependencies, 1)
+
+	update, _ = json.Marshal(map[string]string{"token": payload.Token, "state": "failed", "failure_reason": "script_failure"})
+	runnerResponse = runnerRequest(t, http.MethodPut, fmt.Sprintf("/jobs/%d", payload.ID), "", "", update)
+
+	require.Equal(t, 200, runnerResponse.StatusCode)
+
+	pipeline, _, err = gitlabClient1.Pipelines.GetPipeline(project1.ID, pipeline.ID)
+
+	require.NoError(t, err)
+	require.Equal(t, "failed", pipeline.Status)
+
+	// Runners are managed by admins and the maintainers of their projects.
+	runners, _, err := adminClient.Runners.ListAllRunners(nil)
+
+	require.NoError(t, err)
+	require.Len(t, runners, 2)
+
+	runners, _, err = gitlabClient1.Runners.ListRunners(nil)
+
+	require.NoError(t, err)
+	require.Len(t, runners, 1)
+	require.Equal(t, "project_type", runners[0].RunnerType)
+	require.Equal(t, "online", runners[0].Status)
+
+	_, response, err = gitlabClient1.Runners.GetRunnerDetails(instanceRunner.ID)
+
+	require.Error(t, err)
+	require.Equal(t, 403, response.StatusCode)
+
+	runnerDetails, _, err := adminClient.Runners.UpdateRunnerDetails(instanceRunner.ID, &gitlab.UpdateRunnerDetailsOptions{Paused: gitlab.Ptr(true)})
+
+	require.NoError(t, err)
+	require.True(t, runnerDetails.Paused)
+
+	jobs, _, err := adminClient.Runners.ListRunnerJobs(instanceRunner.ID, nil)
+
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+
+	response, err = gitlabClient1.Runners.DisableProjectRunner(project1.ID, projectRunner.ID)
+
+	require.Error(t, err)
+	require.Equal(t, 403, response.StatusCode)
+
+	_, _, err = gitlabClient1.Runners.EnableProjectRunner(project2.ID, &gitlab.EnableProjectRunnerOptions{RunnerID: projectRunner.ID})
+	require.NoError(t, err)
+
+	_, response, err = gitlabClient1.Runners.EnableProjectRunner(project2.ID, &gitlab.EnableProjectRunnerOptions{RunnerID: projectRunner.ID})
+
+	require.Error(t, err)
+	require.Equal(t, 409, response.StatusCode)
+
+	response, err = gitlabClient1.Runners.DisableProjectRunner(project1.ID, projectRunner.ID)
+
+	require.NoError(t, err)
+	require.Equal(t, 204, response.StatusCode)
+}
+
+type runnerJobPayload struct {
+	ID      int    `json:"id"`
+	Token   string `json:"token"`
+	JobInfo struct {
+		Name string `json:"name"`
+	} `json:"job_info"`
+	GitInfo struct {
+		RepoURL string `json:"repo_url"`
+		Ref     string `json:"ref"`
+	} `json:"git_info"`
+	Variables []struct {
+		Key   string `json:"key"`
+		Value string `json:"value"`
+	} `json:"variables"`
+	Steps []struct {
+		Script []string `json:"script"`
+	} `json:"steps"`
+	Dependencies []struct {
+		ID int `json:"id"`
+	} `json:"dependencies"`
+}
+
+func (payload *runnerJobPayload) variable(key string) string {
+	value := ""
+
+	for _, variable := range payload.Variables {
+		if variable.Key == key {
+			value = variable.Value
+		}
+	}
+
+	return value
+}
+
+func requestRunnerJob(t *testing.T, token string) (*http.Response, *runnerJobPayload) {
+	body, _ := json.Marshal(map[string]interface{}{"token": token, "info": map[string]string{"name": "gitlab-runner", "version": "17.0.0"}})
+
+	response := runnerRequest(t, http.MethodPost, "/jobs/request", "", "", body)
+
+	payload := &runnerJobPayload{}
+	if response.StatusCode == http.StatusCreated {
+		require.NoError(t, json.NewDecoder(response.Body).Decode(payload))
+	}
+
+	return response, payload
+}
+
+func runnerRequest(t *testing.T, method string, path string, jobToken string, contentRange string, body []byte) *http.Response {
+	request, err := http.NewRequest(method, fmt.Sprintf("http://%s/api/v4%s", GitlabHost, path), bytes.NewReader(body))
+	require.NoError(t, err)
+
+	if jobToken != "" {
+		request.Header.Set("JOB-TOKEN", jobToken)
+	}
+
+	if contentRange != "" {
+		request.Header.Set("Content-Range", contentRange)
+	}
+
+	response, err := http.DefaultClient.Do(request)
+	require.NoError(t, err)
+
+	data, _ := io.ReadAll(response.Body)
+	response.Body.Close()
+	response.Body = io.NopCloser(bytes.NewReader(data))
+
+	return response
+}
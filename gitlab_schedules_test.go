@@ -0,0 +1,151 @@
+package gitlabapimock_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xanzy/go-gitlab"
+
+	gitlabapimock "github.com/arkadiusjonczek/go-gitlab-api-mock"
+)
+
+func Test_PipelineTriggersAndSchedules_RunFromTokenAndClock(t *testing.T) {
+	gitlabMock := gitlabapimock.NewGitlabMock()
+	defer gitlabMock.Close()
+
+	user1, _ := gitlabMock.AddUser("Peter Pan", "peter.pan", "peter.pan@telekom.de")
+	user2, _ := gitlabMock.AddUser("Captain Hook", "captain.hook", "captain.hook@telekom.de")
+	user3, _ := gitlabMock.AddUser("Tinker Bell", "tinker.bell", "tinker.bell@telekom.de")
+	gitlabMock.AddPersonalAccessToken(user1, "token1", "token1", []string{"api"})
+	gitlabMock.AddPersonalAccessToken(user2, "token2", "token2", []string{"api"})
+	gitlabMock.AddPersonalAccessToken(user3, "token3", "token3", []string{"api"})
+
+	group1 := gitlabMock.AddGroup("group1")
+	project1 := gitlabMock.AddProject("project1", group1)
+	gitlabMock.AddProjectMember(&gitlab.ProjectMember{ID: user1.ID, AccessLevel: gitlab.MaintainerPermissions}, project1)
+	gitlabMock.AddProjectMember(&gitlab.ProjectMember{ID: user2.ID, AccessLevel: gitlab.MaintainerPermissions}, project1)
+	gitlabMock.AddProjectMember(&gitlab.ProjectMember{ID: user3.ID, AccessLevel: gitlab.DeveloperPermissions}, project1)
+
+	_, err := gitlabMock.CommitFiles(project1, "main", "Initial commit", map[string]string{".gitlab-ci.yml": "build:\n  script: make\n"}, user1)
+	require.NoError(t, err)
+
+	gitlabMock.SetClock(time.Date(2024, time.March, 1, 9, 30, 0, 0, time.UTC))
+
+	server := initGitlabApiMockServer(gitlabMock)
+
+	go server.ListenAndServe()
+	defer server.Close()
+
+	time.Sleep(1 * time.Second)
+
+	gitlabClient1, err := initGitlabClientWithToken("token1")
+	require.NoError(t, err)
+	gitlabClient2, err := initGitlabClientWithToken("token2")
+	require.NoError(t, err)
+	gitlabClient3, err := initGitlabClientWithToken("token3")
+	require.NoError(t, err)
+
+	// Trigger tokens create pipelines on behalf of their owner.
+	trigger, _, err := gitlabClient1.PipelineTriggers.AddPipelineTrigger(project1.ID, &gitlab.AddPipelineTriggerOptions{Description: gitlab.Ptr("deploy")})
+	require.NoError(t, err)
+
+	_, response, err := gitlabClient3.PipelineTriggers.ListPipelineTriggers(project1.ID, nil)
+	require.Error(t, err)
+	require.Equal(t, 403, response.StatusCode)
+
+	triggers, _, err := gitlabClient2.PipelineTriggers.ListPipelineTriggers(project1.ID, nil)
+	require.NoError(t, err)
+	require.Len(t, triggers, 1)
+	require.Equal(t, trigger.Token[:4], triggers[0].Token)
+
+	_, response, err = gitlabClient3.PipelineTriggers.RunPipelineTrigger(project1.ID, &gitlab.RunPipelineTriggerOptions{Ref: gitlab.Ptr("main"), Token: gitlab.Ptr("invalid")})
+	require.Error(t, err)
+	require.Equal(t, 404, response.StatusCode)
+
+	triggeredPipeline, _, err := gitlabClient3.PipelineTriggers.RunPipelineTrigger(project1.ID, &gitlab.RunPipelineTriggerOptions{
+		Ref:       gitlab.Ptr("main"),
+		Token:     gitlab.Ptr(trigger.Token),
+		Variables: map[string]string{"TARGET": "staging"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "trigger", triggeredPipeline.Source)
+	require.Equal(t, user1.ID, triggeredPipeline.User.ID)
+
+	variables, _, err := gitlabClient1.Pipelines.GetPipelineVariables(project1.ID, triggeredPipeline.ID)
+	require.NoError(t, err)
+	require.Len(t, variables, 1)
+	require.Equal(t, "staging", variables[0].Value)
+
+	// Schedules run when the clock of the mock passes their next run.
+	_, response, err = gitlabClient1.PipelineSchedules.CreatePipelineSchedule(project1.ID, &gitlab.CreatePipelineScheduleOptions{
+		Description: gitlab.Ptr("nightly"),
+		Ref:         gitlab.Ptr("main"),
+		Cron:        gitlab.Ptr("61 * * * *"),
+	})
+	require.Error(t, err)
+	require.Equal(t, 400, response.StatusCode)
+
+	schedule, _, err := gitlabClient1.PipelineSchedules.CreatePipelineSchedule(project1.ID, &gitlab.CreatePipelineScheduleOptions{
+		Description: gitlab.Ptr("hourly"),
+		Ref:         gitlab.Ptr("main"),
+		Cron:        gitlab.Ptr("0 * * * *"),
+	})
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2024, time.March, 1, 10, 0, 0, 0, time.UTC), *schedule.NextRunAt)
+
+	_, _, err = gitlabClient1.PipelineSchedules.CreatePipelineScheduleVariable(project1.ID, schedule.ID, &gitlab.CreatePipelineScheduleVariableOptions{
+		Key:   gitlab.Ptr("MODE"),
+		Value: gitlab.Ptr("hourly"),
+	})
+	require.NoError(t, err)
+
+	gitlabMock.AdvanceClock(time.Hour)
+
+	schedule, _, err = gitlabClient1.PipelineSchedules.GetPipelineSchedule(project1.ID, schedule.ID)
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2024, time.March, 1, 11, 0, 0, 0, time.UTC), *schedule.NextRunAt)
+	require.NotNil(t, schedule.LastPipeline)
+
+	scheduledPipeline, _, err := gitlabClient1.Pipelines.GetPipeline(project1.ID, schedule.LastPipeline.ID)
+	require.NoError(t, err)
+	require.Equal(t, "schedule", scheduledPipeline.Source)
+
+	variables, _, err = gitlabClient1.Pipelines.GetPipelineVariables(project1.ID, scheduledPipeline.ID)
+	require.NoError(t, err)
+	require.Len(t, variables, 1)
+	require.Equal(t, "MODE", variables[0].Key)
+
+	// Only the owner may edit a schedule, other maintainers have to take ownership first.
+	_, response, err = gitlabClient2.PipelineSchedules.EditPipelineSchedule(project1.ID, schedule.ID, &gitlab.EditPipelineScheduleOptions{Active: gitlab.Ptr(false)})
+	require.Error(t, err)
+	require.Equal(t, 403, response.StatusCode)
+
+	_, response, err = gitlabClient3.PipelineSchedules.TakeOwnershipOfPipelineSchedule(project1.ID, schedule.ID)
+	require.Error(t, err)
+	require.Equal(t, 403, response.StatusCode)
+
+	schedule, _, err = gitlabClient2.PipelineSchedules.TakeOwnershipOfPipelineSchedule(project1.ID, schedule.ID)
+	require.NoError(t, err)
+	require.Equal(t, user2.ID, schedule.Owner.ID)
+
+	schedule, _, err = gitlabClient2.PipelineSchedules.EditPipelineSchedule(project1.ID, schedule.ID, &gitlab.EditPipelineScheduleOptions{Active: gitlab.Ptr(false)})
+	require.NoError(t, err)
+	require.Nil(t, schedule.NextRunAt)
+
+	gitlabMock.AdvanceClock(2 * time.Hour)
+
+	// Inactive schedules can still be played manually.
+	_, err = gitlabClient3.PipelineSchedules.RunPipelineSchedule(project1.ID, schedule.ID)
+	require.NoError(t, err)
+
+	pipelines, _, err := gitlabClient1.PipelineSchedules.ListPipelinesTriggeredBySchedule(project1.ID, schedule.ID, nil)
+	require.NoError(t, err)
+	require.Len(t, pipelines, 2)
+	require.Equal(t, user2.ID, pipelines[1].User.ID)
+
+	schedules, _, err := gitlabClient1.PipelineSchedules.ListPipelineSchedules(project1.ID, nil)
+	require.NoError(t, err)
+	require.Len(t, schedules, 1)
+	require.False(t, schedules[0].Active)
+}
@@ -0,0 +1,197 @@
+package gitlabapimock_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xanzy/go-gitlab"
+
+	gitlabapimock "github.com/arkadiusjonczek/go-gitlab-api-mock"
+)
+
+func Test_Sudo_ActAsOtherUsers(t *testing.T) {
+	gitlabMock := gitlabapimock.NewGitlabMock()
+	defer gitlabMock.Close()
+
+	admin, _ := gitlabMock.AddUser("Administrator", "root", "admin@telekom.de")
+	admin.IsAdmin = true
+	user1, _ := gitlabMock.AddUser("Peter Pan", "peter.pan", "peter.pan@telekom.de")
+	user2, _ := gitlabMock.AddUser("Captain Hook", "captain.hook", "captain.hook@telekom.de")
+	gitlabMock.AddPersonalAccessToken(admin, "sudo", "sudo-token", []string{"api", "sudo"})
+	gitlabMock.AddPersonalAccessToken(admin, "admin", "admin-token", []string{"api"})
+	gitlabMock.AddPersonalAccessToken(user1, "token1", "token1", []string{"api", "sudo"})
+
+	group1 := gitlabMock.AddGroup("group1")
+	project1 := gitlabMock.AddProject("project1", group1)
+	gitlabMock.AddProjectMember(&gitlab.ProjectMember{ID: user2.ID, AccessLevel: gitlab.DeveloperPermissions}, project1)
+
+	server := initGitlabApiMockServer(gitlabMock)
+
+	go server.ListenAndServe()
+	defer server.Close()
+
+	time.Sleep(1 * time.Second)
+
+	sudoClient, err := initGitlabClientWithToken("sudo-token")
+	require.NoError(t, err)
+
+	adminClient, err := initGitlabClientWithToken("admin-token")
+	require.NoError(t, err)
+
+	gitlabClient1, err := initGitlabClientWithToken("token1")
+	require.NoError(t, err)
+
+	currentUser, _, err := sudoClient.Users.CurrentUser(gitlab.WithSudo("captain.hook"))
+	require.NoError(t, err)
+	require.Equal(t, user2.ID, currentUser.ID)
+
+	// Handlers act as the impersonated user.
+	issue, _, err := sudoClient.Issues.CreateIssue(project1.ID, &gitlab.CreateIssueOptions{Title: gitlab.Ptr("issue1")}, gitlab.WithSudo(user2.ID))
+	require.NoError(t, err)
+	require.Equal(t, user2.ID, issue.Author.ID)
+
+	_, resp, err := sudoClient.Users.CreateImpersonationToken(user1.ID, &gitlab.CreateImpersonationTokenOptions{
+		Name:   gitlab.Ptr("impersonation"),
+		Scopes: &[]string{"api"},
+	}, gitlab.WithSudo(user2.ID))
+	require.Error(t, err)
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	response, err := http.Get(fmt.Sprintf("http://%s/api/v4/user?private_token=sudo-token&sudo=%d", GitlabHost, user1.ID))
+	require.NoError(t, err)
+	defer response.Body.Close()
+
+	var sudoUser gitlab.User
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&sudoUser))
+	require.Equal(t, "peter.pan", sudoUser.Username)
+
+	// The request keeps the token of the admin.
+	sudoToken, _, err := sudoClient.PersonalAccessTokens.GetSinglePersonalAccessToken(gitlab.WithSudo(user2.ID))
+	require.NoError(t, err)
+	require.Equal(t, "sudo", sudoToken.Name)
+	require.Equal(t, admin.ID, sudoToken.UserID)
+
+	_, resp, err = sudoClient.Users.CurrentUser(gitlab.WithSudo("wendy"))
+	require.Error(t, err)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// Sudo needs an admin with a token of the sudo scope.
+	_, resp, err = adminClient.Users.CurrentUser(gitlab.WithSudo(user2.ID))
+	require.Error(t, err)
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	_, resp, err = gitlabClient1.Users.CurrentUser(gitlab.WithSudo(user2.ID))
+	require.Error(t, err)
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
+
+func Test_ImpersonationTokens_AuthenticateAsUser(t *testing.T) {
+	gitlabMock := gitlabapimock.NewGitlabMock()
+	defer gitlabMock.Close()
+
+	gitlabMock.SetClock(time.Date(2024, time.March, 1, 9, 30, 0, 0, time.UTC))
+
+	admin, _ := gitlabMock.AddUser("Administrator", "root", "admin@telekom.de")
+	admin.IsAdmin = true
+	user1, _ := gitlabMock.AddUser("Peter Pan", "peter.pan", "peter.pan@telekom.de")
+	gitlabMock.AddPersonalAccessToken(admin, "admin", "admin-token", []string{"api"})
+	gitlabMock.AddPersonalAccessToken(user1, "token1", "token1", []string{"api"})
+
+	server := initGitlabApiMockServer(gitlabMock)
+
+	go server.ListenAndServe()
+	defer server.Close()
+
+	time.Sleep(1 * time.Second)
+
+	adminClient, err := initGitlabClientWithToken("admin-token")
+	require.NoError(t, err)
+
+	gitlabClient1, err := initGitlabClientWithToken("token1")
+	require.NoError(t, err)
+
+	_, resp, err := gitlabClient1.Users.CreateImpersonationToken(user1.ID, &gitlab.CreateImpersonationTokenOptions{
+		Name:   gitlab.Ptr("impersonation"),
+		Scopes: &[]string{"api"},
+	})
+	require.Error(t, err)
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	_, resp, err = adminClient.Users.CreateImpersonationToken(user1.ID, &gitlab.CreateImpersonationTokenOptions{
+		Name: gitlab.Ptr("impersonation"),
+	})
+	require.Error(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	expiresAt := time.Date(2024, time.March, 8, 12, 0, 0, 0, time.UTC)
+	impersonationToken, _, err := adminClient.Users.CreateImpersonationToken(user1.ID, &gitlab.CreateImpersonationTokenOptions{
+		Name:      gitlab.Ptr("impersonation"),
+		Scopes:    &[]string{"read_api"},
+		ExpiresAt: &expiresAt,
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, impersonationToken.Token)
+	require.True(t, impersonationToken.Active)
+	require.Equal(t, "2024-03-08", impersonationToken.ExpiresAt.String())
+
+	impersonationClient, err := initGitlabClientWithToken(impersonationToken.Token)
+	require.NoError(t, err)
+
+	currentUser, _, err := impersonationClient.Users.CurrentUser()
+	require.NoError(t, err)
+	require.Equal(t, user1.ID, currentUser.ID)
+
+	tokens, _, err := adminClient.Users.GetAllImpersonationTokens(user1.ID, &gitlab.GetAllImpersonationTokensOptions{State: gitlab.Ptr("active")})
+	require.NoError(t, err)
+	require.Len(t, tokens, 1)
+	require.Empty(t, tokens[0].Token)
+	require.NotNil(t, tokens[0].LastUsedAt)
+
+	_, err = adminClient.Users.RevokeImpersonationToken(user1.ID, impersonationToken.ID)
+	require.NoError(t, err)
+
+	_, resp, err = impersonationClient.Users.CurrentUser()
+	require.Error(t, err)
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	token, _, err := adminClient.Users.GetImpersonationToken(user1.ID, impersonationToken.ID)
+	require.NoError(t, err)
+	require.True(t, token.Revoked)
+	require.False(t, token.Active)
+
+	tokens, _, err = adminClient.Users.GetAllImpersonationTokens(user1.ID, &gitlab.GetAllImpersonationTokensOptions{State: gitlab.Ptr("active")})
+	require.NoError(t, err)
+	require.Empty(t, tokens)
+
+	// Personal access tokens are no impersonation tokens.
+	_, resp, err = adminClient.Users.GetImpersonationToken(user1.ID, 2)
+	require.Error(t, err)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// Rotated impersonation tokens stay impersonation tokens.
+	impersonationToken, _, err = adminClient.Users.CreateImpersonationToken(user1.ID, &gitlab.CreateImpersonationTokenOptions{
+		Name:   gitlab.Ptr("rotated"),
+		Scopes: &[]string{"read_api"},
+	})
+	require.NoError(t, err)
+
+	rotatedToken, _, err := adminClient.PersonalAccessTokens.RotatePersonalAccessToken(impersonationToken.ID, nil)
+	require.NoError(t, err)
+
+	tokens, _, err = adminClient.Users.GetAllImpersonationTokens(user1.ID, &gitlab.GetAllImpersonationTokensOptions{State: gitlab.Ptr("active")})
+	require.NoError(t, err)
+	require.Len(t, tokens, 1)
+	require.Equal(t, rotatedToken.ID, tokens[0].ID)
+	require.Equal(t, "rotated", tokens[0].Name)
+
+	rotatedClient, err := initGitlabClientWithToken(rotatedToken.Token)
+	require.NoError(t, err)
+
+	currentUser, _, err = rotatedClient.Users.CurrentUser()
+	require.NoError(t, err)
+	require.Equal(t, user1.ID, currentUser.ID)
+}
@@ -0,0 +1,151 @@
+package gitlabapimock_test
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xanzy/go-gitlab"
+
+	gitlabapimock "github.com/arkadiusjonczek/go-gitlab-api-mock"
+)
+
+func Test_GroupAndSystemHooks_DeliverSystemEvents(t *testing.T) {
+	gitlabMock := gitlabapimock.NewGitlabMock()
+	defer gitlabMock.Close()
+
+	gitlabMock.SetWebhookRetries(3, 10*time.Millisecond)
+
+	admin, _ := gitlabMock.AddUser("Administrator", "root", "root@telekom.de")
+	admin.IsAdmin = true
+	user1, _ := gitlabMock.AddUser("Peter Pan", "peter.pan", "peter.pan@telekom.de")
+	gitlabMock.AddPersonalAccessToken(admin, "token0", "token0", []string{"api"})
+	gitlabMock.AddPersonalAccessToken(user1, "token1", "token1", []string{"api"})
+
+	group1 := gitlabMock.AddGroup("group1")
+	gitlabMock.AddGroupMember(&gitlab.GroupMember{ID: user1.ID, AccessLevel: gitlab.OwnerPermissions}, group1)
+
+	systemReceiver := &hookReceiver{}
+	systemReceiverServer := httptest.NewServer(systemReceiver)
+	defer systemReceiverServer.Close()
+
+	groupReceiver := &hookReceiver{}
+	groupReceiverServer := httptest.NewServer(groupReceiver)
+	defer groupReceiverServer.Close()
+
+	server := initGitlabApiMockServer(gitlabMock)
+
+	go server.ListenAndServe()
+	defer server.Close()
+
+	time.Sleep(1 * time.Second)
+
+	adminClient, err := initGitlabClientWithToken("token0")
+	require.NoError(t, err)
+	gitlabClient1, err := initGitlabClientWithToken("token1")
+	require.NoError(t, err)
+
+	// System hooks are only available to admins.
+	_, response, err := gitlabClient1.SystemHooks.ListHooks()
+	require.Error(t, err)
+	require.Equal(t, 403, response.StatusCode)
+
+	systemHook, _, err := adminClient.SystemHooks.AddHook(&gitlab.AddHookOptions{
+		URL:   gitlab.Ptr(systemReceiverServer.URL),
+		Token: gitlab.Ptr("system-secret"),
+	})
+	require.NoError(t, err)
+	require.True(t, systemHook.RepositoryUpdateEvents)
+	require.False(t, systemHook.PushEvents)
+
+	groupHook, _, err := gitlabClient1.Groups.AddGroupHook(group1.ID, &gitlab.AddGroupHookOptions{
+		URL:            gitlab.Ptr(groupReceiverServer.URL),
+		SubGroupEvents: gitlab.Ptr(true),
+		MemberEvents:   gitlab.Ptr(true),
+	})
+	require.NoError(t, err)
+	require.True(t, groupHook.PushEvents)
+
+	user2, err := gitlabMock.AddUser("Captain Hook", "captain.hook", "captain.hook@telekom.de")
+	require.NoError(t, err)
+
+	subgroup1 := gitlabMock.AddSubgroup("subgroup1", group1)
+	project1 := gitlabMock.AddProject("project1", subgroup1)
+	gitlabMock.AddProjectMember(&gitlab.ProjectMember{ID: user2.ID, AccessLevel: gitlab.DeveloperPermissions}, project1)
+	gitlabMock.AddGroupMember(&gitlab.GroupMember{ID: user2.ID, AccessLevel: gitlab.ReporterPermissions}, subgroup1)
+
+	_, err = gitlabMock.CommitFiles(project1, "main", "Initial commit", map[string]string{"README.md": "# project1"}, user1)
+	require.NoError(t, err)
+
+	gitlabMock.WaitForWebhooks()
+
+	// The system hook receives the system events of the whole instance.
+	events := map[string]interface{}{}
+	for _, received := range systemReceiver.received(gitlab.EventTypeSystemHook) {
+		require.Equal(t, "system-secret", received.token)
+
+		event, err := gitlab.ParseSystemhook(received.body)
+		require.NoError(t, err)
+
+		switch event := event.(type) {
+		case *gitlab.UserSystemEvent:
+			events[event.EventName] = event
+		case *gitlab.GroupSystemEvent:
+			events[event.EventName] = event
+		case *gitlab.ProjectSystemEvent:
+			events[event.EventName] = event
+		case *gitlab.UserTeamSystemEvent:
+			events[event.EventName] = event
+		case *gitlab.UserGroupSystemEvent:
+			events[event.EventName] = event
+		case *gitlab.RepositoryUpdateSystemEvent:
+			events[event.EventName] = event
+		}
+	}
+
+	require.Equal(t, user2.Username, events["user_create"].(*gitlab.UserSystemEvent).Username)
+	require.Equal(t, "group1/subgroup1", events["group_create"].(*gitlab.GroupSystemEvent).PathWithNamespace)
+	require.Equal(t, "group1/subgroup1/project1", events["project_create"].(*gitlab.ProjectSystemEvent).PathWithNamespace)
+	require.Equal(t, project1.ID, events["user_add_to_team"].(*gitlab.UserTeamSystemEvent).ProjectID)
+	require.Equal(t, "Reporter", events["user_add_to_group"].(*gitlab.UserGroupSystemEvent).GroupAccess)
+	require.Equal(t, "refs/heads/main", events["repository_update"].(*gitlab.RepositoryUpdateSystemEvent).Refs[0])
+
+	// The group hook receives the events of its subgroups and their projects.
+	subgroupHooks := groupReceiver.received(gitlab.EventTypeSubGroup)
+	require.Len(t, subgroupHooks, 1)
+
+	event, err := gitlab.ParseWebhook(gitlab.EventTypeSubGroup, subgroupHooks[0].body)
+	require.NoError(t, err)
+	require.Equal(t, subgroup1.ID, event.(*gitlab.SubGroupEvent).GroupID)
+	require.Equal(t, group1.ID, event.(*gitlab.SubGroupEvent).ParentGroupID)
+
+	memberHooks := groupReceiver.received(gitlab.EventTypeMember)
+	require.Len(t, memberHooks, 1)
+
+	event, err = gitlab.ParseWebhook(gitlab.EventTypeMember, memberHooks[0].body)
+	require.NoError(t, err)
+	require.Equal(t, "user_add_to_group", event.(*gitlab.MemberEvent).EventName)
+	require.Equal(t, subgroup1.ID, event.(*gitlab.MemberEvent).GroupID)
+
+	pushes := groupReceiver.received(gitlab.EventTypePush)
+	require.Len(t, pushes, 1)
+
+	event, err = gitlab.ParseWebhook(gitlab.EventTypePush, pushes[0].body)
+	require.NoError(t, err)
+	require.Equal(t, project1.ID, event.(*gitlab.PushEvent).ProjectID)
+
+	// Deleted hooks are gone.
+	_, err = adminClient.SystemHooks.DeleteHook(systemHook.ID)
+	require.NoError(t, err)
+	_, err = gitlabClient1.Groups.DeleteGroupHook(group1.ID, groupHook.ID)
+	require.NoError(t, err)
+
+	systemHooks, _, err := adminClient.SystemHooks.ListHooks()
+	require.NoError(t, err)
+	require.Empty(t, systemHooks)
+
+	groupHooks, _, err := gitlabClient1.Groups.ListGroupHooks(group1.ID, nil)
+	require.NoError(t, err)
+	require.Empty(t, groupHooks)
+}
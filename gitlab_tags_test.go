@@ -0,0 +1,148 @@
+package gitlabapimock_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xanzy/go-gitlab"
+
+	gitlabapimock "github.com/arkadiusjonczek/go-gitlab-api-mock"
+)
+
+func Test_Tags_CreateTag_ReturnsAnnotatedTag(t *testing.T) {
+	gitlabMock := gitlabapimock.NewGitlabMock()
+	defer gitlabMock.Close()
+
+	group1 := gitlabMock.AddGroup("group1")
+	project1 := gitlabMock.AddProject("project1", group1)
+
+	commit, err := gitlabMock.CommitFiles(project1, "main", "Initial commit", map[string]string{"README.md": "# project1\n"}, nil)
+
+	require.NoError(t, err)
+
+	_, err = gitlabMock.AddTag(project1, "v1.2.0", "main", "", nil)
+
+	require.NoError(t, err)
+
+	server := initGitlabApiMockServer(gitlabMock)
+
+	go server.ListenAndServe()
+	defer server.Close()
+
+	time.Sleep(1 * time.Second)
+
+	gitlabClient, err := initGitlabClient()
+
+	require.NoError(t, err)
+
+	createTagOptions := &gitlab.CreateTagOptions{
+		TagName: gitlab.Ptr("v1.10.0"),
+		Ref:     gitlab.Ptr("main"),
+		Message: gitlab.Ptr("Release 1.10.0"),
+	}
+	tag, response, err := gitlabClient.Tags.CreateTag(1, createTagOptions)
+
+	require.NoError(t, err)
+	require.Equal(t, 201, response.StatusCode)
+	require.Equal(t, "v1.10.0", tag.Name)
+	require.Equal(t, "Release 1.10.0", tag.Message)
+	require.Equal(t, commit.ID, tag.Commit.ID)
+	require.NotEqual(t, commit.ID, tag.Target)
+
+	_, response, err = gitlabClient.Tags.CreateTag(1, createTagOptions)
+
+	require.Error(t, err)
+	require.Equal(t, 400, response.StatusCode)
+
+	listTagsOptions := &gitlab.ListTagsOptions{
+		OrderBy: gitlab.Ptr("version"),
+	}
+	tags, response, err := gitlabClient.Tags.ListTags(1, listTagsOptions)
+
+	require.NoError(t, err)
+	require.Equal(t, 200, response.StatusCode)
+	require.Len(t, tags, 2)
+	require.Equal(t, "v1.10.0", tags[0].Name)
+	require.Equal(t, "v1.2.0", tags[1].Name)
+	require.Equal(t, "", tags[1].Message)
+
+	response, err = gitlabClient.Tags.DeleteTag(1, "v1.2.0")
+
+	require.NoError(t, err)
+	require.Equal(t, 204, response.StatusCode)
+
+	_, response, err = gitlabClient.Tags.GetTag(1, "v1.2.0")
+
+	require.Error(t, err)
+	require.Equal(t, 404, response.StatusCode)
+}
+
+func Test_Tags_CreateProtectedTag_RequiresAccessLevel(t *testing.T) {
+	gitlabMock := gitlabapimock.NewGitlabMock()
+	defer gitlabMock.Close()
+
+	developer, _ := gitlabMock.AddUser("Peter Pan", "peter.pan", "peter.pan@telekom.de")
+	gitlabMock.AddPersonalAccessToken(developer, "developer", "developer-token", []string{"api"})
+
+	maintainer, _ := gitlabMock.AddUser("Petra Pan", "petra.pan", "petra.pan@telekom.de")
+	gitlabMock.AddPersonalAccessToken(maintainer, "maintainer", "maintainer-token", []string{"api"})
+
+	group1 := gitlabMock.AddGroup("group1")
+	project1 := gitlabMock.AddProject("project1", group1)
+	gitlabMock.AddProjectMember(&gitlab.ProjectMember{ID: developer.ID, AccessLevel: gitlab.DeveloperPermissions}, project1)
+	gitlabMock.AddProjectMember(&gitlab.ProjectMember{ID: maintainer.ID, AccessLevel: gitlab.MaintainerPermissions}, project1)
+
+	_, err := gitlabMock.CommitFiles(project1, "main", "Initial commit", map[string]string{"README.md": "# project1\n"}, nil)
+
+	require.NoError(t, err)
+
+	server := initGitlabApiMockServer(gitlabMock)
+
+	go server.ListenAndServe()
+	defer server.Close()
+
+	time.Sleep(1 * time.Second)
+
+	maintainerClient, err := initGitlabClientWithToken("maintainer-token")
+
+	require.NoError(t, err)
+
+	protectRepositoryTagsOptions := &gitlab.ProtectRepositoryTagsOptions{
+		Name:              gitlab.Ptr("v*"),
+		CreateAccessLevel: gitlab.Ptr(gitlab.MaintainerPermissions),
+	}
+	protectedTag, response, err := maintainerClient.ProtectedTags.ProtectRepositoryTags(1, protectRepositoryTagsOptions)
+
+	require.NoError(t, err)
+	require.Equal(t, 201, response.StatusCode)
+	require.Equal(t, "v*", protectedTag.Name)
+	require.Equal(t, gitlab.MaintainerPermissions, protectedTag.CreateAccessLevels[0].AccessLevel)
+
+	developerClient, err := initGitlabClientWithToken("developer-token")
+
+	require.NoError(t, err)
+
+	createTagOptions := &gitlab.CreateTagOptions{
+		TagName: gitlab.Ptr("v1.0.0"),
+		Ref:     gitlab.Ptr("main"),
+	}
+	_, response, err = developerClient.Tags.CreateTag(1, createTagOptions)
+
+	require.Error(t, err)
+	require.Equal(t, 403, response.StatusCode)
+
+	tag, response, err := maintainerClient.Tags.CreateTag(1, createTagOptions)
+
+	require.NoError(t, err)
+	require.Equal(t, 201, response.StatusCode)
+	require.True(t, tag.Protected)
+
+	_, response, err = developerClient.Tags.CreateTag(1, &gitlab.CreateTagOptions{
+		TagName: gitlab.Ptr("nightly"),
+		Ref:     gitlab.Ptr("main"),
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, 201, response.StatusCode)
+}
@@ -21,3 +21,7 @@ func initGitlabApiMockServer(gitlabMock *gitlabapimock.GitlabMock) *http.Server
 func initGitlabClient() (*gitlab.Client, error) {
 	return gitlab.NewClient("foobar", gitlab.WithBaseURL(fmt.Sprintf("http://%s", GitlabHost)))
 }
+
+func initGitlabClientWithToken(token string) (*gitlab.Client, error) {
+	return gitlab.NewClient(token, gitlab.WithBaseURL(fmt.Sprintf("http://%s", GitlabHost)))
+}
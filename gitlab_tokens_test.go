@@ -0,0 +1,347 @@
+package gitlabapimock_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xanzy/go-gitlab"
+
+	gitlabapimock "github.com/arkadiusjonczek/go-gitlab-api-mock"
+)
+
+func Test_PersonalAccessTokens_EnforceScopesExpiryAndRotation(t *testing.T) {
+	gitlabMock := gitlabapimock.NewGitlabMock()
+	defer gitlabMock.Close()
+
+	gitlabMock.SetClock(time.Date(2024, time.March, 1, 9, 30, 0, 0, time.UTC))
+
+	admin, _ := gitlabMock.AddUser("Administrator", "root", "admin@telekom.de")
+	admin.IsAdmin = true
+	user, _ := gitlabMock.AddUser("Peter Pan", "peter.pan", "peter.pan@telekom.de")
+	gitlabMock.AddPersonalAccessToken(admin, "admin", "admin-token", []string{"api"})
+	gitlabMock.AddPersonalAccessToken(user, "token1", "token1", []string{"api"})
+
+	group1 := gitlabMock.AddGroup("group1")
+	project1 := gitlabMock.AddProject("project1", group1)
+	gitlabMock.AddProjectMember(&gitlab.ProjectMember{ID: user.ID, AccessLevel: gitlab.MaintainerPermissions}, project1)
+
+	server := initGitlabApiMockServer(gitlabMock)
+
+	go server.ListenAndServe()
+	defer server.Close()
+
+	time.Sleep(1 * time.Second)
+
+	adminClient, err := initGitlabClientWithToken("admin-token")
+	require.NoError(t, err)
+
+	gitlabClient1, err := initGitlabClientWithToken("token1")
+	require.NoError(t, err)
+
+	// Only admins create tokens for other users.
+	_, resp, err := gitlabClient1.Users.CreatePersonalAccessToken(user.ID, &gitlab.CreatePersonalAccessTokenOptions{
+		Name:   gitlab.Ptr("readonly"),
+		Scopes: &[]string{"read_api"},
+	})
+	require.Error(t, err)
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	_, resp, err = adminClient.Users.CreatePersonalAccessToken(user.ID, &gitlab.CreatePersonalAccessTokenOptions{
+		Name:   gitlab.Ptr("readonly"),
+		Scopes: &[]string{"read_everything"},
+	})
+	require.Error(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	expiresAt := gitlab.ISOTime(time.Date(2024, time.March, 4, 0, 0, 0, 0, time.UTC))
+	readOnlyToken, _, err := adminClient.Users.CreatePersonalAccessToken(user.ID, &gitlab.CreatePersonalAccessTokenOptions{
+		Name:      gitlab.Ptr("readonly"),
+		Scopes:    &[]string{"read_api"},
+		ExpiresAt: &expiresAt,
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, readOnlyToken.Token)
+	require.Equal(t, user.ID, readOnlyToken.UserID)
+
+	readOnlyClient, err := initGitlabClientWithToken(readOnlyToken.Token)
+	require.NoError(t, err)
+
+	_, _, err = readOnlyClient.Projects.GetProject(project1.ID, nil)
+	require.NoError(t, err)
+
+	_, resp, err = readOnlyClient.Issues.CreateIssue(project1.ID, &gitlab.CreateIssueOptions{Title: gitlab.Ptr("issue1")})
+	require.Error(t, err)
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	self, _, err := readOnlyClient.PersonalAccessTokens.GetSinglePersonalAccessToken()
+	require.NoError(t, err)
+	require.Equal(t, "readonly", self.Name)
+	require.Empty(t, self.Token)
+
+	// Rotating a token needs the api or self_rotate scope.
+	_, resp, err = readOnlyClient.PersonalAccessTokens.RotatePersonalAccessTokenSelf(nil)
+	require.Error(t, err)
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	rotatedToken, _, err := gitlabClient1.PersonalAccessTokens.RotatePersonalAccessTokenByID(readOnlyToken.ID, nil)
+	require.NoError(t, err)
+	require.NotEqual(t, readOnlyToken.Token, rotatedToken.Token)
+	require.Equal(t, []string{"read_api"}, rotatedToken.Scopes)
+	require.Equal(t, "2024-03-08", rotatedToken.ExpiresAt.String())
+
+	_, resp, err = readOnlyClient.Projects.GetProject(project1.ID, nil)
+	require.Error(t, err)
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	rotatedClient, err := initGitlabClientWithToken(rotatedToken.Token)
+	require.NoError(t, err)
+
+	_, _, err = rotatedClient.Projects.GetProject(project1.ID, nil)
+	require.NoError(t, err)
+
+	// Users only list their own tokens.
+	tokens, _, err := gitlabClient1.PersonalAccessTokens.ListPersonalAccessTokens(&gitlab.ListPersonalAccessTokensOptions{UserID: gitlab.Ptr(admin.ID)})
+	require.NoError(t, err)
+	require.Len(t, tokens, 3)
+	require.True(t, tokens[1].Revoked)
+	require.False(t, tokens[1].Active)
+
+	// Tokens expire at midnight UTC of their expiry date.
+	gitlabMock.AdvanceClock(7 * 24 * time.Hour)
+
+	_, resp, err = rotatedClient.Projects.GetProject(project1.ID, nil)
+	require.Error(t, err)
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	_, err = gitlabClient1.PersonalAccessTokens.RevokePersonalAccessTokenSelf()
+	require.NoError(t, err)
+
+	_, resp, err = gitlabClient1.Projects.GetProject(project1.ID, nil)
+	require.Error(t, err)
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	tokens, _, err = adminClient.PersonalAccessTokens.ListPersonalAccessTokens(&gitlab.ListPersonalAccessTokensOptions{UserID: gitlab.Ptr(user.ID)})
+	require.NoError(t, err)
+	require.Len(t, tokens, 3)
+
+	for _, token := range tokens {
+		require.False(t, token.Active)
+	}
+}
+
+func Test_PersonalAccessTokens_RequireTokenAndMembership(t *testing.T) {
+	gitlabMock := gitlabapimock.NewGitlabMock()
+	defer gitlabMock.Close()
+
+	user1, _ := gitlabMock.AddUser("Peter Pan", "peter.pan", "peter.pan@telekom.de")
+	user2, _ := gitlabMock.AddUser("Captain Hook", "captain.hook", "captain.hook@telekom.de")
+	user3, _ := gitlabMock.AddUser("Wendy Darling", "wendy.darling", "wendy.darling@telekom.de")
+	gitlabMock.AddPersonalAccessToken(user1, "token1", "token1", []string{"api"})
+	gitlabMock.AddPersonalAccessToken(user2, "token2", "token2", []string{"api"})
+	gitlabMock.AddPersonalAccessToken(user3, "token3", "token3", []string{"api"})
+
+	group1 := gitlabMock.AddGroup("group1")
+	project1 := gitlabMock.AddProject("project1", group1)
+	gitlabMock.AddProjectMember(&gitlab.ProjectMember{ID: user1.ID, AccessLevel: gitlab.MaintainerPermissions}, project1)
+	gitlabMock.AddProjectMember(&gitlab.ProjectMember{ID: user2.ID, AccessLevel: gitlab.DeveloperPermissions}, project1)
+
+	server := initGitlabApiMockServer(gitlabMock)
+
+	go server.ListenAndServe()
+	defer server.Close()
+
+	time.Sleep(1 * time.Second)
+
+	anonymousClient, err := initGitlabClient()
+	require.NoError(t, err)
+
+	gitlabClient1, err := initGitlabClientWithToken("token1")
+	require.NoError(t, err)
+
+	gitlabClient2, err := initGitlabClientWithToken("token2")
+	require.NoError(t, err)
+
+	gitlabClient3, err := initGitlabClientWithToken("token3")
+	require.NoError(t, err)
+
+	// Requests without a token are rejected once tokens are registered.
+	_, resp, err := anonymousClient.Projects.GetProject(project1.ID, nil)
+	require.Error(t, err)
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	_, resp, err = anonymousClient.Issues.ListProjectIssues(project1.ID, nil)
+	require.Error(t, err)
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	_, resp, err = anonymousClient.MergeRequests.ListProjectMergeRequests(project1.ID, nil)
+	require.Error(t, err)
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	_, resp, err = anonymousClient.ProjectMembers.AddProjectMember(project1.ID, &gitlab.AddProjectMemberOptions{UserID: user3.ID, AccessLevel: gitlab.Ptr(gitlab.OwnerPermissions)})
+	require.Error(t, err)
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	_, resp, err = anonymousClient.ProjectMembers.EditProjectMember(project1.ID, user1.ID, &gitlab.EditProjectMemberOptions{AccessLevel: gitlab.Ptr(gitlab.GuestPermissions)})
+	require.Error(t, err)
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	resp, err = anonymousClient.ProjectMembers.DeleteProjectMember(project1.ID, user1.ID)
+	require.Error(t, err)
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// Reads need reporters, member changes need maintainers.
+	_, resp, err = gitlabClient3.Projects.GetProject(project1.ID, nil)
+	require.Error(t, err)
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	_, resp, err = gitlabClient3.Issues.ListProjectIssues(project1.ID, nil)
+	require.Error(t, err)
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	_, resp, err = gitlabClient3.MergeRequests.ListProjectMergeRequests(project1.ID, nil)
+	require.Error(t, err)
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	_, resp, err = gitlabClient3.ProjectMembers.ListProjectMembers(project1.ID, nil)
+	require.Error(t, err)
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	_, _, err = gitlabClient2.Projects.GetProject(project1.ID, nil)
+	require.NoError(t, err)
+
+	_, resp, err = gitlabClient2.ProjectMembers.AddProjectMember(project1.ID, &gitlab.AddProjectMemberOptions{UserID: user3.ID, AccessLevel: gitlab.Ptr(gitlab.OwnerPermissions)})
+	require.Error(t, err)
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	_, resp, err = gitlabClient2.ProjectMembers.EditProjectMember(project1.ID, user1.ID, &gitlab.EditProjectMemberOptions{AccessLevel: gitlab.Ptr(gitlab.GuestPermissions)})
+	require.Error(t, err)
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp, err = gitlabClient2.ProjectMembers.DeleteProjectMember(project1.ID, user1.ID)
+	require.Error(t, err)
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	_, _, err = gitlabClient1.ProjectMembers.AddProjectMember(project1.ID, &gitlab.AddProjectMemberOptions{UserID: user3.ID, AccessLevel: gitlab.Ptr(gitlab.ReporterPermissions)})
+	require.NoError(t, err)
+
+	_, _, err = gitlabClient3.Issues.ListProjectIssues(project1.ID, nil)
+	require.NoError(t, err)
+
+	members, _, err := gitlabClient1.ProjectMembers.ListProjectMembers(project1.ID, nil)
+	require.NoError(t, err)
+	require.Len(t, members, 3)
+	require.Equal(t, gitlab.MaintainerPermissions, members[0].AccessLevel)
+
+	members, _, err = gitlabClient3.ProjectMembers.ListProjectMembers(project1.ID, nil)
+	require.NoError(t, err)
+	require.Len(t, members, 3)
+}
+
+func Test_ResourceAccessTokens_CreateBotMembers(t *testing.T) {
+	gitlabMock := gitlabapimock.NewGitlabMock()
+	defer gitlabMock.Close()
+
+	user1, _ := gitlabMock.AddUser("Peter Pan", "peter.pan", "peter.pan@telekom.de")
+	gitlabMock.AddPersonalAccessToken(user1, "token1", "token1", []string{"api"})
+
+	group1 := gitlabMock.AddGroup("group1")
+	gitlabMock.AddGroupMember(&gitlab.GroupMember{ID: user1.ID, AccessLevel: gitlab.OwnerPermissions}, group1)
+	project1 := gitlabMock.AddProject("project1", group1)
+	project2 := gitlabMock.AddProject("project2", gitlabMock.AddGroup("group2"))
+	gitlabMock.AddProjectMember(&gitlab.ProjectMember{ID: user1.ID, AccessLevel: gitlab.MaintainerPermissions}, project2)
+
+	server := initGitlabApiMockServer(gitlabMock)
+
+	go server.ListenAndServe()
+	defer server.Close()
+
+	time.Sleep(1 * time.Second)
+
+	gitlabClient1, err := initGitlabClientWithToken("token1")
+	require.NoError(t, err)
+
+	// Maintainers may not create tokens with a higher access level than their own.
+	_, resp, err := gitlabClient1.ProjectAccessTokens.CreateProjectAccessToken(project2.ID, &gitlab.CreateProjectAccessTokenOptions{
+		Name:        gitlab.Ptr("owner"),
+		Scopes:      &[]string{"api"},
+		AccessLevel: gitlab.Ptr(gitlab.OwnerPermissions),
+	})
+	require.Error(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	projectToken, _, err := gitlabClient1.ProjectAccessTokens.CreateProjectAccessToken(project2.ID, &gitlab.CreateProjectAccessTokenOptions{
+		Name:        gitlab.Ptr("ci"),
+		Scopes:      &[]string{"api"},
+		AccessLevel: gitlab.Ptr(gitlab.DeveloperPermissions),
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, projectToken.Token)
+	require.Equal(t, gitlab.DeveloperPermissions, projectToken.AccessLevel)
+
+	members, _, err := gitlabClient1.ProjectMembers.ListProjectMembers(project2.ID, nil)
+	require.NoError(t, err)
+	require.Len(t, members, 2)
+	require.Equal(t, projectToken.UserID, members[1].ID)
+
+	projectClient, err := initGitlabClientWithToken(projectToken.Token)
+	require.NoError(t, err)
+
+	_, _, err = projectClient.Issues.CreateIssue(project2.ID, &gitlab.CreateIssueOptions{Title: gitlab.Ptr("issue1")})
+	require.NoError(t, err)
+
+	// The bot is no member of other projects.
+	_, resp, err = projectClient.ProjectAccessTokens.ListProjectAccessTokens(project1.ID, nil)
+	require.Error(t, err)
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	rotatedToken, _, err := gitlabClient1.ProjectAccessTokens.RotateProjectAccessToken(project2.ID, projectToken.ID, nil)
+	require.NoError(t, err)
+	require.Equal(t, projectToken.UserID, rotatedToken.UserID)
+	require.Equal(t, gitlab.DeveloperPermissions, rotatedToken.AccessLevel)
+
+	projectTokens, _, err := gitlabClient1.ProjectAccessTokens.ListProjectAccessTokens(project2.ID, nil)
+	require.NoError(t, err)
+	require.Len(t, projectTokens, 2)
+	require.True(t, projectTokens[0].Revoked)
+	require.Empty(t, projectTokens[1].Token)
+
+	_, err = gitlabClient1.ProjectAccessTokens.RevokeProjectAccessToken(project2.ID, rotatedToken.ID)
+	require.NoError(t, err)
+
+	members, _, err = gitlabClient1.ProjectMembers.ListProjectMembers(project2.ID, nil)
+	require.NoError(t, err)
+	require.Len(t, members, 1)
+
+	_, resp, err = gitlabClient1.ProjectAccessTokens.GetProjectAccessToken(project2.ID, rotatedToken.ID)
+	require.Error(t, err)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// Group access tokens give access to the projects of the group.
+	groupToken, _, err := gitlabClient1.GroupAccessTokens.CreateGroupAccessToken(group1.ID, &gitlab.CreateGroupAccessTokenOptions{
+		Name:   gitlab.Ptr("deploy"),
+		Scopes: &[]string{"read_api"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, gitlab.MaintainerPermissions, groupToken.AccessLevel)
+
+	groupClient, err := initGitlabClientWithToken(groupToken.Token)
+	require.NoError(t, err)
+
+	_, _, err = groupClient.Projects.GetProject(project1.ID, nil)
+	require.NoError(t, err)
+
+	groupTokens, _, err := gitlabClient1.GroupAccessTokens.ListGroupAccessTokens(group1.ID, nil)
+	require.NoError(t, err)
+	require.Len(t, groupTokens, 1)
+	require.Equal(t, "deploy", groupTokens[0].Name)
+	require.True(t, groupTokens[0].Active)
+
+	_, err = gitlabClient1.GroupAccessTokens.RevokeGroupAccessToken(group1.ID, groupToken.ID)
+	require.NoError(t, err)
+
+	_, resp, err = groupClient.Projects.GetProject(project1.ID, nil)
+	require.Error(t, err)
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
@@ -0,0 +1,136 @@
+package gitlabapimock_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xanzy/go-gitlab"
+
+	gitlabapimock "github.com/arkadiusjonczek/go-gitlab-api-mock"
+)
+
+func Test_Variables_ManageAllLevels_ValidatesKeysAndMasking(t *testing.T) {
+	gitlabMock := gitlabapimock.NewGitlabMock()
+	defer gitlabMock.Close()
+
+	admin, _ := gitlabMock.AddUser("Administrator", "root", "root@telekom.de")
+	admin.IsAdmin = true
+	user1, _ := gitlabMock.AddUser("Peter Pan", "peter.pan", "peter.pan@telekom.de")
+	user2, _ := gitlabMock.AddUser("Petra Pan", "petra.pan", "petra.pan@telekom.de")
+	gitlabMock.AddPersonalAccessToken(admin, "token0", "token0", []string{"api"})
+	gitlabMock.AddPersonalAccessToken(user1, "token1", "token1", []string{"api"})
+	gitlabMock.AddPersonalAccessToken(user2, "token2", "token2", []string{"api"})
+
+	group1 := gitlabMock.AddGroup("group1")
+	gitlabMock.AddGroupMember(&gitlab.GroupMember{ID: user1.ID, AccessLevel: gitlab.MaintainerPermissions}, group1)
+
+	project1 := gitlabMock.AddProject("project1", group1)
+	gitlabMock.AddProjectMember(&gitlab.ProjectMember{ID: user2.ID, AccessLevel: gitlab.DeveloperPermissions}, project1)
+
+	server := initGitlabApiMockServer(gitlabMock)
+
+	go server.ListenAndServe()
+	defer server.Close()
+
+	time.Sleep(1 * time.Second)
+
+	adminClient, err := initGitlabClientWithToken("token0")
+	require.NoError(t, err)
+	gitlabClient1, err := initGitlabClientWithToken("token1")
+	require.NoError(t, err)
+	gitlabClient2, err := initGitlabClientWithToken("token2")
+	require.NoError(t, err)
+
+	// Instance variables are restricted to admins.
+	_, response, err := gitlabClient1.InstanceVariables.CreateVariable(&gitlab.CreateInstanceVariableOptions{Key: gitlab.Ptr("REGISTRY"), Value: gitlab.Ptr("registry.example.com")})
+
+	require.Error(t, err)
+	require.Equal(t, 403, response.StatusCode)
+
+	instanceVariable, response, err := adminClient.InstanceVariables.CreateVariable(&gitlab.CreateInstanceVariableOptions{Key: gitlab.Ptr("REGISTRY"), Value: gitlab.Ptr("registry.example.com")})
+
+	require.NoError(t, err)
+	require.Equal(t, 201, response.StatusCode)
+	require.Equal(t, gitlab.EnvVariableType, instanceVariable.VariableType)
+
+	_, response, err = adminClient.InstanceVariables.CreateVariable(&gitlab.CreateInstanceVariableOptions{Key: gitlab.Ptr("REGISTRY"), Value: gitlab.Ptr("other")})
+
+	require.Error(t, err)
+	require.Equal(t, 400, response.StatusCode)
+	require.ErrorContains(t, err, "(REGISTRY) has already been taken")
+
+	// Group variables with the same key are allowed in different environment scopes.
+	_, _, err = gitlabClient1.GroupVariables.CreateVariable(group1.ID, &gitlab.CreateGroupVariableOptions{Key: gitlab.Ptr("DEPLOY_TOKEN"), Value: gitlab.Ptr("staging-token"), EnvironmentScope: gitlab.Ptr("staging")})
+	require.NoError(t, err)
+
+	groupVariable, _, err := gitlabClient1.GroupVariables.CreateVariable(group1.ID, &gitlab.CreateGroupVariableOptions{Key: gitlab.Ptr("DEPLOY_TOKEN"), Value: gitlab.Ptr("production-token"), EnvironmentScope: gitlab.Ptr("production"), Protected: gitlab.Ptr(true), Masked: gitlab.Ptr(true)})
+
+	require.NoError(t, err)
+	require.True(t, groupVariable.Masked)
+	require.Equal(t, "production", groupVariable.EnvironmentScope)
+
+	groupVariables, _, err := gitlabClient1.GroupVariables.ListVariables(group1.ID, nil)
+
+	require.NoError(t, err)
+	require.Len(t, groupVariables, 2)
+
+	// Project variables need the maintainer role.
+	_, response, err = gitlabClient2.ProjectVariables.ListVariables(project1.ID, nil)
+
+	require.Error(t, err)
+	require.Equal(t, 403, response.StatusCode)
+
+	_, response, err = gitlabClient1.ProjectVariables.CreateVariable(project1.ID, &gitlab.CreateProjectVariableOptions{Key: gitlab.Ptr("API KEY"), Value: gitlab.Ptr("secret")})
+
+	require.Error(t, err)
+	require.Equal(t, 400, response.StatusCode)
+	require.ErrorContains(t, err, "can contain only letters, digits and '_'.")
+
+	_, response, err = gitlabClient1.ProjectVariables.CreateVariable(project1.ID, &gitlab.CreateProjectVariableOptions{Key: gitlab.Ptr("API_KEY"), Value: gitlab.Ptr("short"), Masked: gitlab.Ptr(true)})
+
+	require.Error(t, err)
+	require.Equal(t, 400, response.StatusCode)
+	require.ErrorContains(t, err, "is invalid")
+
+	_, _, err = gitlabClient1.ProjectVariables.CreateVariable(project1.ID, &gitlab.CreateProjectVariableOptions{Key: gitlab.Ptr("API_KEY"), Value: gitlab.Ptr("secret with spaces!"), Raw: gitlab.Ptr(true), EnvironmentScope: gitlab.Ptr("review/*")})
+	require.NoError(t, err)
+
+	_, _, err = gitlabClient1.ProjectVariables.CreateVariable(project1.ID, &gitlab.CreateProjectVariableOptions{Key: gitlab.Ptr("API_KEY"), Value: gitlab.Ptr("s3cr3t$t0ken#1"), Raw: gitlab.Ptr(true), Masked: gitlab.Ptr(true), VariableType: gitlab.Ptr(gitlab.FileVariableType)})
+	require.NoError(t, err)
+
+	// Keys that exist in several environment scopes have to be filtered.
+	_, response, err = gitlabClient1.ProjectVariables.GetVariable(project1.ID, "API_KEY", nil)
+
+	require.Error(t, err)
+	require.Equal(t, 409, response.StatusCode)
+
+	projectVariable, _, err := gitlabClient1.ProjectVariables.UpdateVariable(project1.ID, "API_KEY", &gitlab.UpdateProjectVariableOptions{
+		Value:  gitlab.Ptr("review-secret"),
+		Raw:    gitlab.Ptr(false),
+		Filter: &gitlab.VariableFilter{EnvironmentScope: "review/*"},
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, "review-secret", projectVariable.Value)
+	require.False(t, projectVariable.Raw)
+
+	_, response, err = gitlabClient1.ProjectVariables.UpdateVariable(project1.ID, "API_KEY", &gitlab.UpdateProjectVariableOptions{
+		EnvironmentScope: gitlab.Ptr("*"),
+		Filter:           &gitlab.VariableFilter{EnvironmentScope: "review/*"},
+	})
+
+	require.Error(t, err)
+	require.Equal(t, 400, response.StatusCode)
+	require.ErrorContains(t, err, "(API_KEY) has already been taken")
+
+	response, err = gitlabClient1.ProjectVariables.RemoveVariable(project1.ID, "API_KEY", &gitlab.RemoveProjectVariableOptions{Filter: &gitlab.VariableFilter{EnvironmentScope: "*"}})
+
+	require.NoError(t, err)
+	require.Equal(t, 204, response.StatusCode)
+
+	projectVariable, _, err = gitlabClient1.ProjectVariables.GetVariable(project1.ID, "API_KEY", nil)
+
+	require.NoError(t, err)
+	require.Equal(t, "review/*", projectVariable.EnvironmentScope)
+}
@@ -1,10 +1,17 @@
 package gitlabapimock
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"net/url"
+	"slices"
 	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
 	"github.com/gorilla/schema"
@@ -15,10 +22,18 @@ const (
 	GitlabApiPrefix = "/api/v4/"
 )
 
+type contextKey string
+
+const (
+	currentUserContextKey  contextKey = "currentUser"
+	currentTokenContextKey contextKey = "currentToken"
+)
+
 // GitlabApiMock
 // TODO: separate handler from business logic
 type GitlabApiMock struct {
 	gitlabMock *GitlabMock
+	baseURL    string
 }
 
 func NewGitlabApiMock(gitlabMock *GitlabMock) *GitlabApiMock {
@@ -28,24 +43,646 @@ func NewGitlabApiMock(gitlabMock *GitlabMock) *GitlabApiMock {
 }
 
 func (mock *GitlabApiMock) CreateServer(addr string) *http.Server {
-	r := mux.NewRouter().PathPrefix(GitlabApiPrefix).Subrouter()
+	mock.baseURL = fmt.Sprintf("http://%s", addr)
+
+	router := mux.NewRouter().UseEncodedPath()
+
+	r := router.PathPrefix(GitlabApiPrefix).Subrouter()
+	r.Use(mock.authenticationMiddleware)
 
+	r.HandleFunc("/user", mock.CurrentUserHandler).Methods(http.MethodGet)
 	r.HandleFunc("/users", mock.ListUsersHandler).Methods(http.MethodGet)
 	r.HandleFunc("/groups", mock.ListGroupsHandler).Methods(http.MethodGet)
 	r.HandleFunc("/projects", mock.ListProjectsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}", mock.GetSingleProjectHandler).Methods(http.MethodGet)
 	r.HandleFunc("/projects/{id}/members", mock.ListAllMembersOfAProjectsHandler).Methods(http.MethodGet)
 	r.HandleFunc("/projects/{id}/members", mock.AddMemberToAProjectsHandler).Methods(http.MethodPost)
 	r.HandleFunc("/projects/{id}/members/{user_id}", mock.EdifMemberOfAProjectHandler).Methods(http.MethodPut)
 	r.HandleFunc("/projects/{id}/members/{user_id}", mock.DeleteMemberFromAProjectHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/projects/{id}/repository/branches", mock.ListRepositoryBranchesHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/repository/branches/{branch}", mock.GetSingleRepositoryBranchHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/repository/tags", mock.ListProjectRepositoryTagsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/repository/tags", mock.CreateNewTagHandler).Methods(http.MethodPost)
+	r.HandleFunc("/projects/{id}/repository/tags/{tag_name}", mock.GetSingleRepositoryTagHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/repository/tags/{tag_name}", mock.DeleteTagHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/projects/{id}/protected_tags", mock.ListProtectedTagsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/protected_tags", mock.ProtectRepositoryTagsHandler).Methods(http.MethodPost)
+	r.HandleFunc("/projects/{id}/protected_tags/{name}", mock.GetProtectedTagHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/protected_tags/{name}", mock.UnprotectRepositoryTagsHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/projects/{id}/releases", mock.ListReleasesHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/releases", mock.CreateReleaseHandler).Methods(http.MethodPost)
+	r.HandleFunc("/projects/{id}/releases/permalink/latest", mock.GetLatestReleaseHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/releases/{tag_name}", mock.GetReleaseByTagNameHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/releases/{tag_name}", mock.UpdateReleaseHandler).Methods(http.MethodPut)
+	r.HandleFunc("/projects/{id}/releases/{tag_name}", mock.DeleteReleaseHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/projects/{id}/releases/{tag_name}/evidence", mock.CollectReleaseEvidenceHandler).Methods(http.MethodPost)
+	r.HandleFunc("/projects/{id}/releases/{tag_name}/assets/links", mock.ListReleaseLinksHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/releases/{tag_name}/assets/links", mock.CreateReleaseLinkHandler).Methods(http.MethodPost)
+	r.HandleFunc("/projects/{id}/releases/{tag_name}/assets/links/{link_id}", mock.GetReleaseLinkHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/releases/{tag_name}/assets/links/{link_id}", mock.UpdateReleaseLinkHandler).Methods(http.MethodPut)
+	r.HandleFunc("/projects/{id}/releases/{tag_name}/assets/links/{link_id}", mock.DeleteReleaseLinkHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/projects/{id}/merge_requests", mock.ListProjectMergeRequestsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/merge_requests", mock.CreateMergeRequestHandler).Methods(http.MethodPost)
+	r.HandleFunc("/projects/{id}/merge_requests/{merge_request_iid}", mock.GetSingleMergeRequestHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/merge_requests/{merge_request_iid}", mock.UpdateMergeRequestHandler).Methods(http.MethodPut)
+	r.HandleFunc("/projects/{id}/merge_requests/{merge_request_iid}", mock.DeleteMergeRequestHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/projects/{id}/merge_requests/{merge_request_iid}/merge", mock.MergeMergeRequestHandler).Methods(http.MethodPut)
+	r.HandleFunc("/projects/{id}/merge_requests/{merge_request_iid}/rebase", mock.RebaseMergeRequestHandler).Methods(http.MethodPut)
+	r.HandleFunc("/projects/{id}/merge_requests/{merge_request_iid}/changes", mock.GetMergeRequestChangesHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/merge_requests/{merge_request_iid}/diffs", mock.ListMergeRequestDiffsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/merge_requests/{merge_request_iid}/commits", mock.GetMergeRequestCommitsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/merge_requests/{merge_request_iid}/approvals", mock.GetMergeRequestApprovalsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/merge_requests/{merge_request_iid}/approve", mock.ApproveMergeRequestHandler).Methods(http.MethodPost)
+	r.HandleFunc("/projects/{id}/merge_requests/{merge_request_iid}/unapprove", mock.UnapproveMergeRequestHandler).Methods(http.MethodPost)
+	r.HandleFunc("/projects/{id}/merge_requests/{merge_request_iid}/approval_state", mock.GetMergeRequestApprovalStateHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/merge_requests/{merge_request_iid}/approval_rules", mock.ListMergeRequestApprovalRulesHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/merge_requests/{merge_request_iid}/approval_rules", mock.CreateMergeRequestApprovalRuleHandler).Methods(http.MethodPost)
+	r.HandleFunc("/projects/{id}/merge_requests/{merge_request_iid}/approval_rules/{approval_rule_id}", mock.UpdateMergeRequestApprovalRuleHandler).Methods(http.MethodPut)
+	r.HandleFunc("/projects/{id}/merge_requests/{merge_request_iid}/approval_rules/{approval_rule_id}", mock.DeleteMergeRequestApprovalRuleHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/issues", mock.ListIssuesHandler).Methods(http.MethodGet)
+	r.HandleFunc("/issues/{issue_id}", mock.GetIssueHandler).Methods(http.MethodGet)
+	r.HandleFunc("/groups/{id}/issues", mock.ListGroupIssuesHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/issues", mock.ListProjectIssuesHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/issues", mock.CreateIssueHandler).Methods(http.MethodPost)
+	r.HandleFunc("/projects/{id}/issues/{issue_iid}", mock.GetProjectIssueHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/issues/{issue_iid}", mock.UpdateIssueHandler).Methods(http.MethodPut)
+	r.HandleFunc("/projects/{id}/issues/{issue_iid}", mock.DeleteIssueHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/projects/{id}/issues/{issue_iid}/move", mock.MoveIssueHandler).Methods(http.MethodPost)
+	r.HandleFunc("/projects/{id}/labels", mock.ListLabelsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/labels", mock.CreateLabelHandler).Methods(http.MethodPost)
+	r.HandleFunc("/projects/{id}/labels", mock.UpdateLabelHandler).Methods(http.MethodPut)
+	r.HandleFunc("/projects/{id}/labels", mock.DeleteLabelHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/projects/{id}/labels/{label_id}", mock.GetLabelHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/labels/{label_id}", mock.UpdateLabelHandler).Methods(http.MethodPut)
+	r.HandleFunc("/projects/{id}/labels/{label_id}", mock.DeleteLabelHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/projects/{id}/labels/{label_id}/subscribe", mock.SubscribeToLabelHandler).Methods(http.MethodPost)
+	r.HandleFunc("/projects/{id}/labels/{label_id}/unsubscribe", mock.UnsubscribeFromLabelHandler).Methods(http.MethodPost)
+	r.HandleFunc("/groups/{id}/labels", mock.ListGroupLabelsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/groups/{id}/labels", mock.CreateGroupLabelHandler).Methods(http.MethodPost)
+	r.HandleFunc("/groups/{id}/labels", mock.UpdateGroupLabelHandler).Methods(http.MethodPut)
+	r.HandleFunc("/groups/{id}/labels", mock.DeleteGroupLabelHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/groups/{id}/labels/{label_id}", mock.GetGroupLabelHandler).Methods(http.MethodGet)
+	r.HandleFunc("/groups/{id}/labels/{label_id}", mock.UpdateGroupLabelHandler).Methods(http.MethodPut)
+	r.HandleFunc("/groups/{id}/labels/{label_id}", mock.DeleteGroupLabelHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/groups/{id}/labels/{label_id}/subscribe", mock.SubscribeToGroupLabelHandler).Methods(http.MethodPost)
+	r.HandleFunc("/groups/{id}/labels/{label_id}/unsubscribe", mock.UnsubscribeFromGroupLabelHandler).Methods(http.MethodPost)
+	r.HandleFunc("/projects/{id}/milestones", mock.ListMilestonesHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/milestones", mock.CreateMilestoneHandler).Methods(http.MethodPost)
+	r.HandleFunc("/projects/{id}/milestones/{milestone_id}", mock.GetMilestoneHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/milestones/{milestone_id}", mock.UpdateMilestoneHandler).Methods(http.MethodPut)
+	r.HandleFunc("/projects/{id}/milestones/{milestone_id}", mock.DeleteMilestoneHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/projects/{id}/milestones/{milestone_id}/issues", mock.GetMilestoneIssuesHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/milestones/{milestone_id}/merge_requests", mock.GetMilestoneMergeRequestsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/milestones/{milestone_id}/burndown_events", mock.GetMilestoneBurndownEventsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/groups/{id}/milestones", mock.ListGroupMilestonesHandler).Methods(http.MethodGet)
+	r.HandleFunc("/groups/{id}/milestones", mock.CreateGroupMilestoneHandler).Methods(http.MethodPost)
+	r.HandleFunc("/groups/{id}/milestones/{milestone_id}", mock.GetGroupMilestoneHandler).Methods(http.MethodGet)
+	r.HandleFunc("/groups/{id}/milestones/{milestone_id}", mock.UpdateGroupMilestoneHandler).Methods(http.MethodPut)
+	r.HandleFunc("/groups/{id}/milestones/{milestone_id}", mock.DeleteGroupMilestoneHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/groups/{id}/milestones/{milestone_id}/issues", mock.GetGroupMilestoneIssuesHandler).Methods(http.MethodGet)
+	r.HandleFunc("/groups/{id}/milestones/{milestone_id}/merge_requests", mock.GetGroupMilestoneMergeRequestsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/groups/{id}/milestones/{milestone_id}/burndown_events", mock.GetGroupMilestoneBurndownEventsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/groups/{id}/iterations", mock.ListGroupIterationsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/iterations", mock.ListProjectIterationsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/{noteable_type:issues|merge_requests}/{noteable_id}/resource_milestone_events", mock.ListMilestoneEventsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/{noteable_type:issues|merge_requests}/{noteable_id}/resource_milestone_events/{event_id}", mock.GetMilestoneEventHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/pipeline", mock.CreatePipelineHandler).Methods(http.MethodPost)
+	r.HandleFunc("/projects/{id}/pipelines", mock.ListProjectPipelinesHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/pipelines/latest", mock.GetLatestPipelineHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/pipelines/{pipeline_id}", mock.GetPipelineHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/pipelines/{pipeline_id}", mock.DeletePipelineHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/projects/{id}/pipelines/{pipeline_id}/variables", mock.GetPipelineVariablesHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/pipelines/{pipeline_id}/retry", mock.RetryPipelineHandler).Methods(http.MethodPost)
+	r.HandleFunc("/projects/{id}/pipelines/{pipeline_id}/cancel", mock.CancelPipelineHandler).Methods(http.MethodPost)
+	r.HandleFunc("/projects/{id}/pipelines/{pipeline_id}/jobs", mock.ListPipelineJobsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/jobs", mock.ListProjectJobsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/jobs/{job_id}", mock.GetJobHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/jobs/{job_id}/cancel", mock.CancelJobHandler).Methods(http.MethodPost)
+	r.HandleFunc("/projects/{id}/jobs/{job_id}/retry", mock.RetryJobHandler).Methods(http.MethodPost)
+	r.HandleFunc("/projects/{id}/jobs/{job_id}/erase", mock.EraseJobHandler).Methods(http.MethodPost)
+	r.HandleFunc("/projects/{id}/jobs/{job_id}/play", mock.PlayJobHandler).Methods(http.MethodPost)
+	r.HandleFunc("/projects/{id}/jobs/{job_id}/trace", mock.GetTraceFileHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/jobs/artifacts/{ref_name}/raw/{artifact_path:.+}", mock.DownloadSingleArtifactsFileByRefHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/jobs/artifacts/{ref_name:.+}/download", mock.DownloadArtifactsFileHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/jobs/{job_id}/artifacts", mock.GetJobArtifactsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/jobs/{job_id}/artifacts", mock.DeleteArtifactsHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/projects/{id}/jobs/{job_id}/artifacts/keep", mock.KeepArtifactsHandler).Methods(http.MethodPost)
+	r.HandleFunc("/projects/{id}/jobs/{job_id}/artifacts/{artifact_path:.+}", mock.DownloadSingleArtifactsFileHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/artifacts", mock.DeleteProjectArtifactsHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/jobs/{job_id}/artifacts", mock.UploadJobArtifactsHandler).Methods(http.MethodPost)
+	r.HandleFunc("/jobs/{job_id}/artifacts", mock.DownloadRunnerJobArtifactsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/jobs/request", mock.RequestJobHandler).Methods(http.MethodPost)
+	r.HandleFunc("/jobs/{job_id}", mock.UpdateRunnerJobHandler).Methods(http.MethodPut)
+	r.HandleFunc("/jobs/{job_id}/trace", mock.AppendRunnerJobTraceHandler).Methods(http.MethodPatch)
+	r.HandleFunc("/projects/{id}/hooks", mock.ListProjectHooksHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/hooks", mock.AddProjectHookHandler).Methods(http.MethodPost)
+	r.HandleFunc("/projects/{id}/hooks/{hook_id}", mock.GetProjectHookHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/hooks/{hook_id}", mock.EditProjectHookHandler).Methods(http.MethodPut)
+	r.HandleFunc("/projects/{id}/hooks/{hook_id}", mock.DeleteProjectHookHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/projects/{id}/hooks/{hook_id}/events", mock.ListProjectHookEventsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/hooks/{hook_id}/test/{trigger}", mock.TestProjectHookHandler).Methods(http.MethodPost)
+	r.HandleFunc("/groups/{id}/hooks", mock.ListGroupHooksHandler).Methods(http.MethodGet)
+	r.HandleFunc("/groups/{id}/hooks", mock.AddGroupHookHandler).Methods(http.MethodPost)
+	r.HandleFunc("/groups/{id}/hooks/{hook_id}", mock.GetGroupHookHandler).Methods(http.MethodGet)
+	r.HandleFunc("/groups/{id}/hooks/{hook_id}", mock.EditGroupHookHandler).Methods(http.MethodPut)
+	r.HandleFunc("/groups/{id}/hooks/{hook_id}", mock.DeleteGroupHookHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/groups/{id}/hooks/{hook_id}/events", mock.ListGroupHookEventsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/hooks", mock.ListSystemHooksHandler).Methods(http.MethodGet)
+	r.HandleFunc("/hooks", mock.AddSystemHookHandler).Methods(http.MethodPost)
+	r.HandleFunc("/hooks/{hook_id}", mock.GetSystemHookHandler).Methods(http.MethodGet)
+	r.HandleFunc("/hooks/{hook_id}", mock.EditSystemHookHandler).Methods(http.MethodPut)
+	r.HandleFunc("/hooks/{hook_id}", mock.TestSystemHookHandler).Methods(http.MethodPost)
+	r.HandleFunc("/hooks/{hook_id}", mock.DeleteSystemHookHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/events", mock.ListCurrentUserEventsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/users/{id}/events", mock.ListUserEventsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/events", mock.ListProjectEventsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/audit_events", mock.ListInstanceAuditEventsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/audit_events/{event_id}", mock.GetInstanceAuditEventHandler).Methods(http.MethodGet)
+	r.HandleFunc("/groups/{id}/audit_events", mock.ListGroupAuditEventsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/groups/{id}/audit_events/{event_id}", mock.GetGroupAuditEventHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/audit_events", mock.ListProjectAuditEventsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/audit_events/{event_id}", mock.GetProjectAuditEventHandler).Methods(http.MethodGet)
+	r.HandleFunc("/personal_access_tokens", mock.ListPersonalAccessTokensHandler).Methods(http.MethodGet)
+	r.HandleFunc("/personal_access_tokens/self", mock.GetPersonalAccessTokenHandler).Methods(http.MethodGet)
+	r.HandleFunc("/personal_access_tokens/self", mock.RevokePersonalAccessTokenHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/personal_access_tokens/self/rotate", mock.RotatePersonalAccessTokenHandler).Methods(http.MethodPost)
+	r.HandleFunc("/personal_access_tokens/{token_id}", mock.GetPersonalAccessTokenHandler).Methods(http.MethodGet)
+	r.HandleFunc("/personal_access_tokens/{token_id}", mock.RevokePersonalAccessTokenHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/personal_access_tokens/{token_id}/rotate", mock.RotatePersonalAccessTokenHandler).Methods(http.MethodPost)
+	r.HandleFunc("/users/{id}/personal_access_tokens", mock.CreatePersonalAccessTokenHandler).Methods(http.MethodPost)
+	r.HandleFunc("/users/{id}/impersonation_tokens", mock.ListImpersonationTokensHandler).Methods(http.MethodGet)
+	r.HandleFunc("/users/{id}/impersonation_tokens", mock.CreateImpersonationTokenHandler).Methods(http.MethodPost)
+	r.HandleFunc("/users/{id}/impersonation_tokens/{token_id}", mock.GetImpersonationTokenHandler).Methods(http.MethodGet)
+	r.HandleFunc("/users/{id}/impersonation_tokens/{token_id}", mock.RevokeImpersonationTokenHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/projects/{id}/access_tokens", mock.ListProjectAccessTokensHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/access_tokens", mock.CreateProjectAccessTokenHandler).Methods(http.MethodPost)
+	r.HandleFunc("/projects/{id}/access_tokens/{token_id}", mock.GetProjectAccessTokenHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/access_tokens/{token_id}", mock.RevokeProjectAccessTokenHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/projects/{id}/access_tokens/{token_id}/rotate", mock.RotateProjectAccessTokenHandler).Methods(http.MethodPost)
+	r.HandleFunc("/groups/{id}/access_tokens", mock.ListGroupAccessTokensHandler).Methods(http.MethodGet)
+	r.HandleFunc("/groups/{id}/access_tokens", mock.CreateGroupAccessTokenHandler).Methods(http.MethodPost)
+	r.HandleFunc("/groups/{id}/access_tokens/{token_id}", mock.GetGroupAccessTokenHandler).Methods(http.MethodGet)
+	r.HandleFunc("/groups/{id}/access_tokens/{token_id}", mock.RevokeGroupAccessTokenHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/groups/{id}/access_tokens/{token_id}/rotate", mock.RotateGroupAccessTokenHandler).Methods(http.MethodPost)
+	r.HandleFunc("/user/keys", mock.ListSSHKeysHandler).Methods(http.MethodGet)
+	r.HandleFunc("/user/keys", mock.AddSSHKeyHandler).Methods(http.MethodPost)
+	r.HandleFunc("/user/keys/{key_id}", mock.GetSSHKeyHandler).Methods(http.MethodGet)
+	r.HandleFunc("/user/keys/{key_id}", mock.DeleteSSHKeyHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/users/{id}/keys", mock.ListSSHKeysHandler).Methods(http.MethodGet)
+	r.HandleFunc("/users/{id}/keys", mock.AddSSHKeyHandler).Methods(http.MethodPost)
+	r.HandleFunc("/users/{id}/keys/{key_id}", mock.GetSSHKeyHandler).Methods(http.MethodGet)
+	r.HandleFunc("/users/{id}/keys/{key_id}", mock.DeleteSSHKeyHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/keys", mock.GetKeyByFingerprintHandler).Methods(http.MethodGet)
+	r.HandleFunc("/keys/{key_id}", mock.GetKeyWithUserHandler).Methods(http.MethodGet)
+	r.HandleFunc("/deploy_keys", mock.ListAllDeployKeysHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/deploy_keys", mock.ListProjectDeployKeysHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/deploy_keys", mock.AddProjectDeployKeyHandler).Methods(http.MethodPost)
+	r.HandleFunc("/projects/{id}/deploy_keys/{key_id}", mock.GetProjectDeployKeyHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/deploy_keys/{key_id}", mock.UpdateProjectDeployKeyHandler).Methods(http.MethodPut)
+	r.HandleFunc("/projects/{id}/deploy_keys/{key_id}", mock.DeleteProjectDeployKeyHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/projects/{id}/deploy_keys/{key_id}/enable", mock.EnableProjectDeployKeyHandler).Methods(http.MethodPost)
+	r.HandleFunc("/user/gpg_keys", mock.ListGPGKeysHandler).Methods(http.MethodGet)
+	r.HandleFunc("/user/gpg_keys", mock.AddGPGKeyHandler).Methods(http.MethodPost)
+	r.HandleFunc("/user/gpg_keys/{key_id}", mock.GetGPGKeyHandler).Methods(http.MethodGet)
+	r.HandleFunc("/user/gpg_keys/{key_id}", mock.DeleteGPGKeyHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/users/{id}/gpg_keys", mock.ListGPGKeysHandler).Methods(http.MethodGet)
+	r.HandleFunc("/users/{id}/gpg_keys", mock.AddGPGKeyHandler).Methods(http.MethodPost)
+	r.HandleFunc("/users/{id}/gpg_keys/{key_id}", mock.GetGPGKeyHandler).Methods(http.MethodGet)
+	r.HandleFunc("/users/{id}/gpg_keys/{key_id}", mock.DeleteGPGKeyHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/deploy_tokens", mock.ListAllDeployTokensHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/deploy_tokens", mock.ListProjectDeployTokensHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/deploy_tokens", mock.CreateProjectDeployTokenHandler).Methods(http.MethodPost)
+	r.HandleFunc("/projects/{id}/deploy_tokens/{token_id}", mock.GetProjectDeployTokenHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/deploy_tokens/{token_id}", mock.DeleteProjectDeployTokenHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/groups/{id}/deploy_tokens", mock.ListGroupDeployTokensHandler).Methods(http.MethodGet)
+	r.HandleFunc("/groups/{id}/deploy_tokens", mock.CreateGroupDeployTokenHandler).Methods(http.MethodPost)
+	r.HandleFunc("/groups/{id}/deploy_tokens/{token_id}", mock.GetGroupDeployTokenHandler).Methods(http.MethodGet)
+	r.HandleFunc("/groups/{id}/deploy_tokens/{token_id}", mock.DeleteGroupDeployTokenHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/applications", mock.ListApplicationsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/applications", mock.CreateApplicationHandler).Methods(http.MethodPost)
+	r.HandleFunc("/applications/{id}", mock.DeleteApplicationHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/applications/{id}/renew-secret", mock.RenewApplicationSecretHandler).Methods(http.MethodPost)
+	r.HandleFunc("/projects/{id}/triggers", mock.ListPipelineTriggersHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/triggers", mock.AddPipelineTriggerHandler).Methods(http.MethodPost)
+	r.HandleFunc("/projects/{id}/triggers/{trigger_id}", mock.GetPipelineTriggerHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/triggers/{trigger_id}", mock.EditPipelineTriggerHandler).Methods(http.MethodPut)
+	r.HandleFunc("/projects/{id}/triggers/{trigger_id}", mock.DeletePipelineTriggerHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/projects/{id}/triggers/{trigger_id}/take_ownership", mock.TakeOwnershipOfPipelineTriggerHandler).Methods(http.MethodPost)
+	r.HandleFunc("/projects/{id}/trigger/pipeline", mock.RunPipelineTriggerHandler).Methods(http.MethodPost)
+	r.HandleFunc("/projects/{id}/ref/{ref:.+}/trigger/pipeline", mock.RunPipelineTriggerHandler).Methods(http.MethodPost)
+	r.HandleFunc("/projects/{id}/pipeline_schedules", mock.ListPipelineSchedulesHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/pipeline_schedules", mock.CreatePipelineScheduleHandler).Methods(http.MethodPost)
+	r.HandleFunc("/projects/{id}/pipeline_schedules/{schedule_id}", mock.GetPipelineScheduleHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/pipeline_schedules/{schedule_id}", mock.EditPipelineScheduleHandler).Methods(http.MethodPut)
+	r.HandleFunc("/projects/{id}/pipeline_schedules/{schedule_id}", mock.DeletePipelineScheduleHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/projects/{id}/pipeline_schedules/{schedule_id}/pipelines", mock.ListPipelinesTriggeredByScheduleHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/pipeline_schedules/{schedule_id}/take_ownership", mock.TakeOwnershipOfPipelineScheduleHandler).Methods(http.MethodPost)
+	r.HandleFunc("/projects/{id}/pipeline_schedules/{schedule_id}/play", mock.RunPipelineScheduleHandler).Methods(http.MethodPost)
+	r.HandleFunc("/projects/{id}/pipeline_schedules/{schedule_id}/variables", mock.CreatePipelineScheduleVariableHandler).Methods(http.MethodPost)
+	r.HandleFunc("/projects/{id}/pipeline_schedules/{schedule_id}/variables/{key}", mock.EditPipelineScheduleVariableHandler).Methods(http.MethodPut)
+	r.HandleFunc("/projects/{id}/pipeline_schedules/{schedule_id}/variables/{key}", mock.DeletePipelineScheduleVariableHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/runners", mock.ListRunnersHandler).Methods(http.MethodGet)
+	r.HandleFunc("/runners", mock.RegisterRunnerHandler).Methods(http.MethodPost)
+	r.HandleFunc("/runners", mock.DeleteRegisteredRunnerHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/runners/all", mock.ListAllRunnersHandler).Methods(http.MethodGet)
+	r.HandleFunc("/runners/verify", mock.VerifyRunnerHandler).Methods(http.MethodPost)
+	r.HandleFunc("/runners/reset_registration_token", mock.ResetInstanceRunnersTokenHandler).Methods(http.MethodPost)
+	r.HandleFunc("/runners/{runner_id:[0-9]+}", mock.GetRunnerDetailsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/runners/{runner_id:[0-9]+}", mock.UpdateRunnerDetailsHandler).Methods(http.MethodPut)
+	r.HandleFunc("/runners/{runner_id:[0-9]+}", mock.RemoveRunnerHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/runners/{runner_id:[0-9]+}/jobs", mock.ListRunnerJobsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/runners/{runner_id:[0-9]+}/reset_authentication_token", mock.ResetRunnerAuthenticationTokenHandler).Methods(http.MethodPost)
+	r.HandleFunc("/projects/{id}/runners", mock.ListProjectRunnersHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/runners", mock.EnableProjectRunnerHandler).Methods(http.MethodPost)
+	r.HandleFunc("/projects/{id}/runners/reset_registration_token", mock.ResetProjectRunnersTokenHandler).Methods(http.MethodPost)
+	r.HandleFunc("/projects/{id}/runners/{runner_id:[0-9]+}", mock.DisableProjectRunnerHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/groups/{id}/runners", mock.ListGroupRunnersHandler).Methods(http.MethodGet)
+	r.HandleFunc("/groups/{id}/runners/reset_registration_token", mock.ResetGroupRunnersTokenHandler).Methods(http.MethodPost)
+	r.HandleFunc("/ci/lint", mock.LintHandler).Methods(http.MethodPost)
+	r.HandleFunc("/admin/ci/variables", mock.ListInstanceVariablesHandler).Methods(http.MethodGet)
+	r.HandleFunc("/admin/ci/variables", mock.CreateInstanceVariableHandler).Methods(http.MethodPost)
+	r.HandleFunc("/admin/ci/variables/{key}", mock.GetInstanceVariableHandler).Methods(http.MethodGet)
+	r.HandleFunc("/admin/ci/variables/{key}", mock.UpdateInstanceVariableHandler).Methods(http.MethodPut)
+	r.HandleFunc("/admin/ci/variables/{key}", mock.DeleteInstanceVariableHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/groups/{id}/variables", mock.ListGroupVariablesHandler).Methods(http.MethodGet)
+	r.HandleFunc("/groups/{id}/variables", mock.CreateGroupVariableHandler).Methods(http.MethodPost)
+	r.HandleFunc("/groups/{id}/variables/{key}", mock.GetGroupVariableHandler).Methods(http.MethodGet)
+	r.HandleFunc("/groups/{id}/variables/{key}", mock.UpdateGroupVariableHandler).Methods(http.MethodPut)
+	r.HandleFunc("/groups/{id}/variables/{key}", mock.DeleteGroupVariableHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/projects/{id}/variables", mock.ListProjectVariablesHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/variables", mock.CreateProjectVariableHandler).Methods(http.MethodPost)
+	r.HandleFunc("/projects/{id}/variables/{key}", mock.GetProjectVariableHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/variables/{key}", mock.UpdateProjectVariableHandler).Methods(http.MethodPut)
+	r.HandleFunc("/projects/{id}/variables/{key}", mock.DeleteProjectVariableHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/projects/{id}/ci/lint", mock.ProjectLintHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/ci/lint", mock.ProjectNamespaceLintHandler).Methods(http.MethodPost)
+	r.HandleFunc("/projects/{id}/snippets", mock.ListProjectSnippetsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/snippets/{snippet_id}", mock.GetProjectSnippetHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/{noteable_type:issues|merge_requests|snippets}/{noteable_id}/notes", mock.ListNotesHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/{noteable_type:issues|merge_requests|snippets}/{noteable_id}/notes", mock.CreateNoteHandler).Methods(http.MethodPost)
+	r.HandleFunc("/projects/{id}/{noteable_type:issues|merge_requests|snippets}/{noteable_id}/notes/{note_id}", mock.GetNoteHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/{noteable_type:issues|merge_requests|snippets}/{noteable_id}/notes/{note_id}", mock.UpdateNoteHandler).Methods(http.MethodPut)
+	r.HandleFunc("/projects/{id}/{noteable_type:issues|merge_requests|snippets}/{noteable_id}/notes/{note_id}", mock.DeleteNoteHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/projects/{id}/{noteable_type:issues|merge_requests|snippets|repository/commits}/{noteable_id}/discussions", mock.ListDiscussionsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/{noteable_type:issues|merge_requests|snippets|repository/commits}/{noteable_id}/discussions", mock.CreateDiscussionHandler).Methods(http.MethodPost)
+	r.HandleFunc("/projects/{id}/{noteable_type:issues|merge_requests|snippets|repository/commits}/{noteable_id}/discussions/{discussion_id}", mock.GetDiscussionHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/{noteable_type:merge_requests}/{noteable_id}/discussions/{discussion_id}", mock.ResolveDiscussionHandler).Methods(http.MethodPut)
+	r.HandleFunc("/projects/{id}/{noteable_type:issues|merge_requests|snippets|repository/commits}/{noteable_id}/discussions/{discussion_id}/notes", mock.AddDiscussionNoteHandler).Methods(http.MethodPost)
+	r.HandleFunc("/projects/{id}/{noteable_type:issues|merge_requests|snippets|repository/commits}/{noteable_id}/discussions/{discussion_id}/notes/{note_id}", mock.UpdateDiscussionNoteHandler).Methods(http.MethodPut)
+	r.HandleFunc("/projects/{id}/{noteable_type:issues|merge_requests|snippets|repository/commits}/{noteable_id}/discussions/{discussion_id}/notes/{note_id}", mock.DeleteDiscussionNoteHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/projects/{id}/approval_rules", mock.ListProjectApprovalRulesHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/approval_rules", mock.CreateProjectApprovalRuleHandler).Methods(http.MethodPost)
+	r.HandleFunc("/projects/{id}/approval_rules/{approval_rule_id}", mock.GetProjectApprovalRuleHandler).Methods(http.MethodGet)
+	r.HandleFunc("/projects/{id}/approval_rules/{approval_rule_id}", mock.UpdateProjectApprovalRuleHandler).Methods(http.MethodPut)
+	r.HandleFunc("/projects/{id}/approval_rules/{approval_rule_id}", mock.DeleteProjectApprovalRuleHandler).Methods(http.MethodDelete)
+
+	router.HandleFunc("/oauth/authorize", mock.OAuthAuthorizeHandler).Methods(http.MethodGet)
+	router.HandleFunc("/oauth/token", mock.OAuthTokenHandler).Methods(http.MethodPost)
+	router.HandleFunc("/oauth/revoke", mock.OAuthRevokeHandler).Methods(http.MethodPost)
+	router.HandleFunc("/oauth/token/info", mock.OAuthTokenInfoHandler).Methods(http.MethodGet)
+	router.HandleFunc("/oauth/userinfo", mock.OAuthUserInfoHandler).Methods(http.MethodGet, http.MethodPost)
+	router.HandleFunc("/jwt/auth", mock.RegistryAuthHandler).Methods(http.MethodGet)
+	router.HandleFunc("/{namespace:.+}/{project}.git/info/refs", mock.GitInfoRefsHandler).Methods(http.MethodGet)
+	router.HandleFunc("/{namespace:.+}/{project}.git/git-upload-pack", mock.GitUploadPackHandler).Methods(http.MethodPost)
+	router.HandleFunc("/{namespace:.+}/{project}.git/git-receive-pack", mock.GitReceivePackHandler).Methods(http.MethodPost)
 
 	server := &http.Server{
 		Addr:    addr,
-		Handler: r,
+		Handler: router,
 	}
 
 	return server
 }
 
+// writeInsufficientScope writes the error of a token lacking the scope the request requires.
+func writeInsufficientScope(responseWriter http.ResponseWriter, scope string) {
+	writeJSON(responseWriter, http.StatusForbidden, map[string]string{
+		"error":             "insufficient_scope",
+		"error_description": "The request requires higher privileges than provided by the access token.",
+		"scope":             scope,
+	})
+}
+
+// authenticationMiddleware resolves the user of the token the request was sent with. Once tokens are registered,
+// requests without one are rejected unless they carry credentials of runners or triggers. CI_JOB_TOKENs are only
+// accepted by the job and artifact routes. Admins act as another user with the Sudo header or sudo parameter, the
+// handlers only see the impersonated user.
+func (mock *GitlabApiMock) authenticationMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		rawToken := tokenFromRequest(request)
+
+		if rawToken == "" && mock.gitlabMock.authenticationRequired() && !routeHasOwnCredentials(request) {
+			writeErrorMessage(responseWriter, http.StatusUnauthorized, "401 Unauthorized")
+			return
+		}
+
+		user, token, err := mock.gitlabMock.authenticate(rawToken)
+		if err != nil && !routeHasOwnCredentials(request) {
+			writeError(responseWriter, err)
+			return
+		}
+
+		_, isJobToken := mock.gitlabMock.jobByToken(rawToken)
+		if isJobToken && mock.gitlabMock.authenticationRequired() && !routeAcceptsJobToken(request) {
+			writeErrorMessage(responseWriter, http.StatusUnauthorized, "401 Unauthorized")
+			return
+		}
+
+		scopes, scoped := mock.gitlabMock.tokenScopes(rawToken, token)
+		if scoped && !tokenAllowsAPIRequest(scopes, request.Method, strings.TrimPrefix(request.URL.Path, GitlabApiPrefix)) {
+			writeInsufficientScope(responseWriter, "api")
+			return
+		}
+
+		if sudo := sudoFromRequest(request); sudo != "" {
+			if scoped && !slices.Contains(scopes, "sudo") {
+				writeInsufficientScope(responseWriter, "sudo")
+				return
+			}
+
+			user, err = mock.gitlabMock.sudo(user, scoped, sudo)
+			if err != nil {
+				writeError(responseWriter, err)
+				return
+			}
+		}
+
+		ctx := context.WithValue(request.Context(), currentUserContextKey, user)
+		ctx = context.WithValue(ctx, currentTokenContextKey, token)
+		next.ServeHTTP(responseWriter, request.WithContext(ctx))
+	})
+}
+
+// routesWithOwnCredentials are the routes runners and triggers call, they authenticate with the runner, job or
+// trigger token in the body of the request instead of the token of a user.
+var routesWithOwnCredentials = map[string]bool{
+	http.MethodPost + " runners":                                     true,
+	http.MethodDelete + " runners":                                   true,
+	http.MethodPost + " runners/verify":                              true,
+	http.MethodPost + " jobs/request":                                true,
+	http.MethodPut + " jobs/{job_id}":                                true,
+	http.MethodPatch + " jobs/{job_id}/trace":                        true,
+	http.MethodPost + " jobs/{job_id}/artifacts":                     true,
+	http.MethodGet + " jobs/{job_id}/artifacts":                      true,
+	http.MethodPost + " projects/{id}/trigger/pipeline":              true,
+	http.MethodPost + " projects/{id}/ref/{ref:.+}/trigger/pipeline": true,
+}
+
+// routesForJobTokens are the job and artifact routes a CI_JOB_TOKEN may be used for besides the
+// routesWithOwnCredentials.
+var routesForJobTokens = map[string]bool{
+	http.MethodGet + " projects/{id}/jobs/{job_id}":                                    true,
+	http.MethodGet + " projects/{id}/jobs/{job_id}/artifacts":                          true,
+	http.MethodGet + " projects/{id}/jobs/{job_id}/artifacts/{artifact_path:.+}":       true,
+	http.MethodGet + " projects/{id}/jobs/artifacts/{ref_name}/raw/{artifact_path:.+}": true,
+	http.MethodGet + " projects/{id}/jobs/artifacts/{ref_name:.+}/download":            true,
+}
+
+// routeHasOwnCredentials reports whether the request is sent to one of the routesWithOwnCredentials.
+func routeHasOwnCredentials(request *http.Request) bool {
+	return routesWithOwnCredentials[routeKey(request)]
+}
+
+// routeAcceptsJobToken reports whether a CI_JOB_TOKEN may be used for the request.
+func routeAcceptsJobToken(request *http.Request) bool {
+	key := routeKey(request)
+
+	return routesWithOwnCredentials[key] || routesForJobTokens[key]
+}
+
+// routeKey returns the method and the path template of the route of the request relative to the API prefix,
+// e.g. "GET projects/{id}". Requests without a route result in an empty key.
+func routeKey(request *http.Request) string {
+	route := mux.CurrentRoute(request)
+	if route == nil {
+		return ""
+	}
+
+	pathTemplate, err := route.GetPathTemplate()
+	if err != nil {
+		return ""
+	}
+
+	return request.Method + " " + strings.TrimPrefix(pathTemplate, GitlabApiPrefix)
+}
+
+// tokenFromRequest extracts the token from the headers, query parameters or basic auth credentials.
+func tokenFromRequest(request *http.Request) string {
+	if token := request.Header.Get("PRIVATE-TOKEN"); token != "" {
+		return token
+	}
+
+	if token, isBearer := strings.CutPrefix(request.Header.Get("Authorization"), "Bearer "); isBearer {
+		return token
+	}
+
+	if token := request.Header.Get("JOB-TOKEN"); token != "" {
+		return token
+	}
+
+	if token := request.URL.Query().Get("private_token"); token != "" {
+		return token
+	}
+
+	if token := request.URL.Query().Get("access_token"); token != "" {
+		return token
+	}
+
+	if token := request.URL.Query().Get("job_token"); token != "" {
+		return token
+	}
+
+	if _, password, ok := request.BasicAuth(); ok {
+		return password
+	}
+
+	return ""
+}
+
+// sudoFromRequest returns the ID or username of the user to act as from the Sudo header or the sudo parameter.
+func sudoFromRequest(request *http.Request) string {
+	if sudo := request.Header.Get("Sudo"); sudo != "" {
+		return sudo
+	}
+
+	return request.URL.Query().Get("sudo")
+}
+
+// jobTokenFromRequest extracts the CI_JOB_TOKEN from the JOB-TOKEN header or the job_token and token parameters.
+func jobTokenFromRequest(request *http.Request) string {
+	if token := request.Header.Get("JOB-TOKEN"); token != "" {
+		return token
+	}
+
+	if token := request.URL.Query().Get("job_token"); token != "" {
+		return token
+	}
+
+	return request.URL.Query().Get("token")
+}
+
+// currentUser returns the authenticated user of the request or nil for anonymous requests.
+func currentUser(request *http.Request) *gitlab.User {
+	user, _ := request.Context().Value(currentUserContextKey).(*gitlab.User)
+	return user
+}
+
+// currentToken returns the personal access token the request was authenticated with or nil for other requests.
+// Requests using sudo keep the token of the admin, like GitLab its scopes apply and self refers to it.
+func currentToken(request *http.Request) *gitlab.PersonalAccessToken {
+	token, _ := request.Context().Value(currentTokenContextKey).(*gitlab.PersonalAccessToken)
+	return token
+}
+
+// pathVar returns the unescaped path variable of the request.
+func pathVar(request *http.Request, name string) string {
+	value := mux.Vars(request)[name]
+
+	unescaped, err := url.PathUnescape(value)
+	if err != nil {
+		return value
+	}
+
+	return unescaped
+}
+
+// getProject resolves the project referenced by the id path variable, either by ID or by its
+// URL-encoded path.
+func (mock *GitlabApiMock) getProject(request *http.Request) (*gitlab.Project, bool) {
+	id := pathVar(request, "id")
+
+	idInteger, err := strconv.Atoi(id)
+	if err == nil {
+		project, projectExists := mock.gitlabMock.projects[idInteger]
+		return project, projectExists
+	}
+
+	project, err := mock.gitlabMock.GetProjectByPath(id)
+	if err != nil {
+		return nil, false
+	}
+
+	return project, true
+}
+
+// getProjectWithAccess resolves the project referenced by the id path variable and checks that the user of the
+// request has at least the access level in it.
+func (mock *GitlabApiMock) getProjectWithAccess(request *http.Request, accessLevel gitlab.AccessLevelValue) (*gitlab.Project, error) {
+	project, projectExists := mock.getProject(request)
+	if !projectExists {
+		return nil, newAPIError(http.StatusNotFound, "404 Project Not Found")
+	}
+
+	if !mock.gitlabMock.hasAccess(project, currentUser(request), accessLevel) {
+		return nil, newAPIError(http.StatusForbidden, "403 Forbidden")
+	}
+
+	return project, nil
+}
+
+// getGroup resolves the group referenced by the id path variable, either by ID or by its URL-encoded
+// full path.
+func (mock *GitlabApiMock) getGroup(request *http.Request) (*gitlab.Group, bool) {
+	id := pathVar(request, "id")
+
+	idInteger, err := strconv.Atoi(id)
+
+	for _, group := range mock.gitlabMock.groups {
+		if (err == nil && group.ID == idInteger) || group.FullPath == id {
+			return group, true
+		}
+	}
+
+	return nil, false
+}
+
+// getUserFromRequest resolves the user referenced by the id path variable, either by ID or by username.
+func (mock *GitlabApiMock) getUserFromRequest(request *http.Request) (*gitlab.User, bool) {
+	id := pathVar(request, "id")
+
+	idInteger, err := strconv.Atoi(id)
+
+	for _, user := range mock.gitlabMock.users {
+		if (err == nil && user.ID == idInteger) || user.Username == id {
+			return user, true
+		}
+	}
+
+	return nil, false
+}
+
+// withURLs fills in the URLs of the project, which depend on the address the server listens on.
+func (mock *GitlabApiMock) withURLs(project *gitlab.Project) *gitlab.Project {
+	project.WebURL = fmt.Sprintf("%s/%s", mock.baseURL, project.PathWithNamespace)
+	project.HTTPURLToRepo = fmt.Sprintf("%s/%s.git", mock.baseURL, project.PathWithNamespace)
+
+	return project
+}
+
+func writeJSON(responseWriter http.ResponseWriter, statusCode int, value interface{}) {
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.WriteHeader(statusCode)
+
+	err := json.NewEncoder(responseWriter).Encode(value)
+	if err != nil {
+		log.Printf("failed to encode response: %v", err)
+	}
+}
+
+// writeFile writes the content as download with the filename. Its content type is detected from the content.
+func writeFile(responseWriter http.ResponseWriter, filename string, content []byte) {
+	responseWriter.Header().Set("Content-Type", http.DetectContentType(content))
+	responseWriter.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	responseWriter.WriteHeader(http.StatusOK)
+	responseWriter.Write(content)
+}
+
+// writeErrorMessage writes an error in the format of the GitLab API, e.g. {"message": "404 Project Not Found"}.
+func writeErrorMessage(responseWriter http.ResponseWriter, statusCode int, message interface{}) {
+	writeJSON(responseWriter, statusCode, map[string]interface{}{"message": message})
+}
+
+// writeError writes an error returned by the GitlabMock. Errors without a status code result in a 500.
+func writeError(responseWriter http.ResponseWriter, err error) {
+	var apiErr *apiError
+	if errors.As(err, &apiErr) {
+		writeErrorMessage(responseWriter, apiErr.statusCode, apiErr.message)
+		return
+	}
+
+	writeErrorMessage(responseWriter, http.StatusInternalServerError, err.Error())
+}
+
+// decodeQuery decodes the query parameters of the request into the options, using their url tags.
+func decodeQuery(request *http.Request, options interface{}) error {
+	decoder := schema.NewDecoder()
+	decoder.SetAliasTag("url")
+	decoder.IgnoreUnknownKeys(true)
+
+	return decoder.Decode(options, request.URL.Query())
+}
+
+// decodeBody decodes the JSON body of the request into the options. An empty body leaves the options untouched.
+func decodeBody(request *http.Request, options interface{}) error {
+	err := json.NewDecoder(request.Body).Decode(options)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return newAPIError(http.StatusBadRequest, err.Error())
+	}
+
+	return nil
+}
+
+// CurrentUserHandler implements https://docs.gitlab.com/ee/api/users.html#list-current-user
+func (mock *GitlabApiMock) CurrentUserHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	user := currentUser(request)
+	if user == nil {
+		writeErrorMessage(responseWriter, http.StatusUnauthorized, "401 Unauthorized")
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, user)
+}
+
 // ListUsersHandler implements https://docs.gitlab.com/ee/api/users.html#list-users
 func (mock *GitlabApiMock) ListUsersHandler(responseWriter http.ResponseWriter, request *http.Request) {
 	var listUsersOptions gitlab.ListUsersOptions
@@ -98,7 +735,9 @@ func (mock *GitlabApiMock) ListProjectsHandler(responseWriter http.ResponseWrite
 	var projects []*gitlab.Project
 
 	for _, group := range mock.gitlabMock.groups {
-		projects = append(projects, group.Projects...)
+		for _, project := range group.Projects {
+			projects = append(projects, mock.withURLs(project))
+		}
 	}
 
 	err := json.NewEncoder(responseWriter).Encode(projects)
@@ -111,21 +750,32 @@ func (mock *GitlabApiMock) ListProjectsHandler(responseWriter http.ResponseWrite
 	return
 }
 
-// ListAllMembersOfAProjectsHandler implements https://docs.gitlab.com/ee/api/members.html#list-all-members-of-a-group-or-project
-func (mock *GitlabApiMock) ListAllMembersOfAProjectsHandler(responseWriter http.ResponseWriter, request *http.Request) {
-	vars := mux.Vars(request)
+// GetSingleProjectHandler implements https://docs.gitlab.com/ee/api/projects.html#get-single-project
+func (mock *GitlabApiMock) GetSingleProjectHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, err := mock.getProjectWithAccess(request, gitlab.ReporterPermissions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
 
-	id := vars["id"]
-	idInteger, _ := strconv.Atoi(id)
+	writeJSON(responseWriter, http.StatusOK, mock.withURLs(project))
+}
 
-	_, projectExists := mock.gitlabMock.projects[idInteger]
+// ListAllMembersOfAProjectsHandler implements https://docs.gitlab.com/ee/api/members.html#list-all-members-of-a-group-or-project
+func (mock *GitlabApiMock) ListAllMembersOfAProjectsHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, projectExists := mock.getProject(request)
 	if !projectExists {
 		responseWriter.WriteHeader(http.StatusNotFound)
 		responseWriter.Write([]byte(http.StatusText(http.StatusNotFound)))
 		return
 	}
 
-	projectMembers := mock.gitlabMock.projectMembers[idInteger]
+	if !mock.gitlabMock.hasAccess(project, currentUser(request), gitlab.GuestPermissions) {
+		writeErrorMessage(responseWriter, http.StatusForbidden, "403 Forbidden")
+		return
+	}
+
+	projectMembers := mock.gitlabMock.projectMembers[project.ID]
 
 	err := json.NewEncoder(responseWriter).Encode(projectMembers)
 	if err != nil {
@@ -139,18 +789,18 @@ func (mock *GitlabApiMock) ListAllMembersOfAProjectsHandler(responseWriter http.
 
 // AddMemberToAProjectsHandler implements https://docs.gitlab.com/ee/api/members.html#add-a-member-to-a-group-or-project
 func (mock *GitlabApiMock) AddMemberToAProjectsHandler(responseWriter http.ResponseWriter, request *http.Request) {
-	vars := mux.Vars(request)
-
-	id := vars["id"]
-	idInteger, _ := strconv.Atoi(id)
-
-	_, projectExists := mock.gitlabMock.projects[idInteger]
+	project, projectExists := mock.getProject(request)
 	if !projectExists {
 		responseWriter.WriteHeader(http.StatusNotFound)
 		responseWriter.Write([]byte(http.StatusText(http.StatusNotFound)))
 		return
 	}
 
+	if !mock.gitlabMock.hasAccess(project, currentUser(request), gitlab.MaintainerPermissions) {
+		writeErrorMessage(responseWriter, http.StatusForbidden, "403 Forbidden")
+		return
+	}
+
 	var addProjectMemberOptions gitlab.AddProjectMemberOptions
 	err := json.NewDecoder(request.Body).Decode(&addProjectMemberOptions)
 	if err != nil {
@@ -159,27 +809,35 @@ func (mock *GitlabApiMock) AddMemberToAProjectsHandler(responseWriter http.Respo
 		return
 	}
 
-	projectMembers := mock.gitlabMock.projectMembers[idInteger]
+	if addProjectMemberOptions.AccessLevel == nil {
+		writeErrorMessage(responseWriter, http.StatusBadRequest, "access_level is missing")
+		return
+	}
+
+	var userIdInteger int
+
+	switch userId := addProjectMemberOptions.UserID.(type) {
+	case float64:
+		userIdInteger = int(userId)
+	case string:
+		userIdInteger, _ = strconv.Atoi(userId)
+	}
+
+	projectMembers := mock.gitlabMock.projectMembers[project.ID]
 
-	found := false
 	for _, member := range projectMembers {
-		if member.ID == addProjectMemberOptions.UserID {
-			found = true
-			break
+		if member.ID == userIdInteger {
+			writeErrorMessage(responseWriter, http.StatusConflict, "Member already exists")
+			return
 		}
 	}
 
-	userId := addProjectMemberOptions.UserID.(float64)
-	userIdInteger := int(userId)
-
 	projectMember := &gitlab.ProjectMember{
 		ID:          userIdInteger,
 		AccessLevel: *addProjectMemberOptions.AccessLevel,
 	}
 
-	if !found {
-		mock.gitlabMock.projectMembers[idInteger] = append(projectMembers, projectMember)
-	}
+	mock.gitlabMock.addProjectMember(projectMember, project, currentUser(request))
 
 	err = json.NewEncoder(responseWriter).Encode(projectMember)
 	if err != nil {
@@ -195,16 +853,18 @@ func (mock *GitlabApiMock) AddMemberToAProjectsHandler(responseWriter http.Respo
 func (mock *GitlabApiMock) EdifMemberOfAProjectHandler(responseWriter http.ResponseWriter, request *http.Request) {
 	vars := mux.Vars(request)
 
-	id := vars["id"]
-	idInteger, _ := strconv.Atoi(id)
-
-	_, projectExists := mock.gitlabMock.projects[idInteger]
+	project, projectExists := mock.getProject(request)
 	if !projectExists {
 		responseWriter.WriteHeader(http.StatusNotFound)
 		responseWriter.Write([]byte(http.StatusText(http.StatusNotFound)))
 		return
 	}
 
+	if !mock.gitlabMock.hasAccess(project, currentUser(request), gitlab.MaintainerPermissions) {
+		writeErrorMessage(responseWriter, http.StatusForbidden, "403 Forbidden")
+		return
+	}
+
 	userId := vars["user_id"]
 	userIdInteger, _ := strconv.Atoi(userId)
 
@@ -218,7 +878,7 @@ func (mock *GitlabApiMock) EdifMemberOfAProjectHandler(responseWriter http.Respo
 
 	var projectMember *gitlab.ProjectMember
 
-	projectMembers := mock.gitlabMock.projectMembers[idInteger]
+	projectMembers := mock.gitlabMock.projectMembers[project.ID]
 
 	found := false
 	for idx, member := range projectMembers {
@@ -235,10 +895,7 @@ func (mock *GitlabApiMock) EdifMemberOfAProjectHandler(responseWriter http.Respo
 		return
 	}
 
-	if editProjectMemberOptions.AccessLevel != nil {
-		log.Printf("UserID = %d, Old Access Level = %d, New Access Level = %d", userIdInteger, projectMember.AccessLevel, *editProjectMemberOptions.AccessLevel)
-		projectMember.AccessLevel = *editProjectMemberOptions.AccessLevel
-	}
+	mock.gitlabMock.editProjectMember(project, projectMember, editProjectMemberOptions.AccessLevel, currentUser(request))
 
 	err = json.NewEncoder(responseWriter).Encode(projectMember)
 	if err != nil {
@@ -254,36 +911,23 @@ func (mock *GitlabApiMock) EdifMemberOfAProjectHandler(responseWriter http.Respo
 func (mock *GitlabApiMock) DeleteMemberFromAProjectHandler(responseWriter http.ResponseWriter, request *http.Request) {
 	vars := mux.Vars(request)
 
-	id := vars["id"]
-	idInteger, _ := strconv.Atoi(id)
-
-	_, projectExists := mock.gitlabMock.projects[idInteger]
+	project, projectExists := mock.getProject(request)
 	if !projectExists {
 		responseWriter.WriteHeader(http.StatusNotFound)
 		responseWriter.Write([]byte(http.StatusText(http.StatusNotFound)))
 		return
 	}
 
+	if !mock.gitlabMock.hasAccess(project, currentUser(request), gitlab.MaintainerPermissions) {
+		writeErrorMessage(responseWriter, http.StatusForbidden, "403 Forbidden")
+		return
+	}
+
 	userId := vars["user_id"]
 	userIdInteger, _ := strconv.Atoi(userId)
 
-	projectMembers := mock.gitlabMock.projectMembers[idInteger]
-
-	found := false
-	for idx, member := range projectMembers {
-		if member.ID == userIdInteger {
-			found = true
-
-			copy(projectMembers[idx:], projectMembers[idx+1:])
-			projectMembers[len(projectMembers)-1] = nil
-			projectMembers = projectMembers[:len(projectMembers)-1]
-
-			mock.gitlabMock.projectMembers[idInteger] = projectMembers
-			break
-		}
-	}
-
-	if !found {
+	err := mock.gitlabMock.removeProjectMember(userIdInteger, project, currentUser(request))
+	if err != nil {
 		responseWriter.WriteHeader(http.StatusNotFound)
 		return
 	}
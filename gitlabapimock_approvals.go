@@ -0,0 +1,198 @@
+package gitlabapimock
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// ListProjectApprovalRulesHandler implements https://docs.gitlab.com/ee/api/merge_request_approvals.html#get-project-level-rules
+func (mock *GitlabApiMock) ListProjectApprovalRulesHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, projectExists := mock.getProject(request)
+	if !projectExists {
+		writeErrorMessage(responseWriter, http.StatusNotFound, "404 Project Not Found")
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, mock.gitlabMock.getProjectApprovalRules(project))
+}
+
+// GetProjectApprovalRuleHandler implements https://docs.gitlab.com/ee/api/merge_request_approvals.html#get-a-single-project-level-rule
+func (mock *GitlabApiMock) GetProjectApprovalRuleHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, projectExists := mock.getProject(request)
+	if !projectExists {
+		writeErrorMessage(responseWriter, http.StatusNotFound, "404 Project Not Found")
+		return
+	}
+
+	ruleID, _ := strconv.Atoi(pathVar(request, "approval_rule_id"))
+
+	rule, err := mock.gitlabMock.getProjectApprovalRule(project, ruleID)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, rule)
+}
+
+// CreateProjectApprovalRuleHandler implements https://docs.gitlab.com/ee/api/merge_request_approvals.html#create-project-level-rule
+func (mock *GitlabApiMock) CreateProjectApprovalRuleHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, err := mock.getProjectWithAccess(request, gitlab.MaintainerPermissions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	var createProjectLevelRuleOptions gitlab.CreateProjectLevelRuleOptions
+	err = decodeBody(request, &createProjectLevelRuleOptions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	rule, err := mock.gitlabMock.AddProjectApprovalRule(project, &createProjectLevelRuleOptions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusCreated, rule)
+}
+
+// UpdateProjectApprovalRuleHandler implements https://docs.gitlab.com/ee/api/merge_request_approvals.html#update-project-level-rule
+func (mock *GitlabApiMock) UpdateProjectApprovalRuleHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, err := mock.getProjectWithAccess(request, gitlab.MaintainerPermissions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	var updateProjectLevelRuleOptions gitlab.UpdateProjectLevelRuleOptions
+	err = decodeBody(request, &updateProjectLevelRuleOptions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	ruleID, _ := strconv.Atoi(pathVar(request, "approval_rule_id"))
+
+	rule, err := mock.gitlabMock.updateProjectApprovalRule(project, ruleID, &updateProjectLevelRuleOptions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, rule)
+}
+
+// DeleteProjectApprovalRuleHandler implements https://docs.gitlab.com/ee/api/merge_request_approvals.html#delete-project-level-rule
+func (mock *GitlabApiMock) DeleteProjectApprovalRuleHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, err := mock.getProjectWithAccess(request, gitlab.MaintainerPermissions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	ruleID, _ := strconv.Atoi(pathVar(request, "approval_rule_id"))
+
+	err = mock.gitlabMock.deleteProjectApprovalRule(project, ruleID)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	responseWriter.WriteHeader(http.StatusNoContent)
+}
+
+// ListMergeRequestApprovalRulesHandler implements https://docs.gitlab.com/ee/api/merge_request_approvals.html#get-merge-request-level-rules
+func (mock *GitlabApiMock) ListMergeRequestApprovalRulesHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, mergeRequest, err := mock.getMergeRequestFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, mock.gitlabMock.getMergeRequestApprovalRules(project, mergeRequest))
+}
+
+// CreateMergeRequestApprovalRuleHandler implements https://docs.gitlab.com/ee/api/merge_request_approvals.html#create-merge-request-level-rule
+func (mock *GitlabApiMock) CreateMergeRequestApprovalRuleHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, mergeRequest, err := mock.getMergeRequestFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	var createMergeRequestApprovalRuleOptions gitlab.CreateMergeRequestApprovalRuleOptions
+	err = decodeBody(request, &createMergeRequestApprovalRuleOptions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	rule, err := mock.gitlabMock.createMergeRequestApprovalRule(project, mergeRequest, &createMergeRequestApprovalRuleOptions, currentUser(request))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusCreated, rule)
+}
+
+// UpdateMergeRequestApprovalRuleHandler implements https://docs.gitlab.com/ee/api/merge_request_approvals.html#update-merge-request-level-rule
+func (mock *GitlabApiMock) UpdateMergeRequestApprovalRuleHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, mergeRequest, err := mock.getMergeRequestFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	var updateMergeRequestApprovalRuleOptions gitlab.UpdateMergeRequestApprovalRuleOptions
+	err = decodeBody(request, &updateMergeRequestApprovalRuleOptions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	ruleID, _ := strconv.Atoi(pathVar(request, "approval_rule_id"))
+
+	rule, err := mock.gitlabMock.updateMergeRequestApprovalRule(project, mergeRequest, ruleID, &updateMergeRequestApprovalRuleOptions, currentUser(request))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, rule)
+}
+
+// DeleteMergeRequestApprovalRuleHandler implements https://docs.gitlab.com/ee/api/merge_request_approvals.html#delete-merge-request-level-rule
+func (mock *GitlabApiMock) DeleteMergeRequestApprovalRuleHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, mergeRequest, err := mock.getMergeRequestFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	ruleID, _ := strconv.Atoi(pathVar(request, "approval_rule_id"))
+
+	err = mock.gitlabMock.deleteMergeRequestApprovalRule(project, mergeRequest, ruleID, currentUser(request))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	responseWriter.WriteHeader(http.StatusNoContent)
+}
+
+// GetMergeRequestApprovalStateHandler implements https://docs.gitlab.com/ee/api/merge_request_approvals.html#get-the-approval-state-of-merge-requests
+func (mock *GitlabApiMock) GetMergeRequestApprovalStateHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, mergeRequest, err := mock.getMergeRequestFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, mock.gitlabMock.getMergeRequestApprovalState(project, mergeRequest))
+}
@@ -0,0 +1,219 @@
+package gitlabapimock
+
+import (
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+const maxArtifactsUploadMemory = 32 << 20
+
+// GetJobArtifactsHandler implements https://docs.gitlab.com/ee/api/job_artifacts.html#get-job-artifacts
+func (mock *GitlabApiMock) GetJobArtifactsHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	_, job, err := mock.getJobFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	archive, err := mock.gitlabMock.artifactsArchive(job)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeFile(responseWriter, archive.filename, archive.data)
+}
+
+// DownloadArtifactsFileHandler implements https://docs.gitlab.com/ee/api/job_artifacts.html#download-the-artifacts-archive
+func (mock *GitlabApiMock) DownloadArtifactsFileHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, err := mock.getProjectWithAccess(request, gitlab.ReporterPermissions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	job, err := mock.gitlabMock.latestSuccessfulJob(project, pathVar(request, "ref_name"), request.URL.Query().Get("job"))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	archive, err := mock.gitlabMock.artifactsArchive(job)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeFile(responseWriter, archive.filename, archive.data)
+}
+
+// DownloadSingleArtifactsFileHandler implements https://docs.gitlab.com/ee/api/job_artifacts.html#download-a-single-artifact-file-by-job-id
+func (mock *GitlabApiMock) DownloadSingleArtifactsFileHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	_, job, err := mock.getJobFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	artifactPath := pathVar(request, "artifact_path")
+
+	content, err := mock.gitlabMock.artifactsFile(job, artifactPath)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeFile(responseWriter, path.Base(artifactPath), content)
+}
+
+// DownloadSingleArtifactsFileByRefHandler implements https://docs.gitlab.com/ee/api/job_artifacts.html#download-a-single-artifact-file-from-specific-tag-or-branch
+func (mock *GitlabApiMock) DownloadSingleArtifactsFileByRefHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, err := mock.getProjectWithAccess(request, gitlab.ReporterPermissions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	job, err := mock.gitlabMock.latestSuccessfulJob(project, pathVar(request, "ref_name"), request.URL.Query().Get("job"))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	artifactPath := pathVar(request, "artifact_path")
+
+	content, err := mock.gitlabMock.artifactsFile(job, artifactPath)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeFile(responseWriter, path.Base(artifactPath), content)
+}
+
+// KeepArtifactsHandler implements https://docs.gitlab.com/ee/api/job_artifacts.html#keep-artifacts
+func (mock *GitlabApiMock) KeepArtifactsHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, job, err := mock.getJobFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	job, err = mock.gitlabMock.keepArtifacts(project, job, currentUser(request))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, job)
+}
+
+// DeleteArtifactsHandler implements https://docs.gitlab.com/ee/api/job_artifacts.html#delete-job-artifacts
+func (mock *GitlabApiMock) DeleteArtifactsHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, job, err := mock.getJobFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	err = mock.gitlabMock.deleteArtifacts(project, job, currentUser(request))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	responseWriter.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteProjectArtifactsHandler implements https://docs.gitlab.com/ee/api/job_artifacts.html#delete-project-artifacts
+func (mock *GitlabApiMock) DeleteProjectArtifactsHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, err := mock.getProjectWithAccess(request, gitlab.ReporterPermissions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	err = mock.gitlabMock.deleteProjectArtifacts(project, currentUser(request))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	responseWriter.WriteHeader(http.StatusAccepted)
+}
+
+// GetTraceFileHandler implements https://docs.gitlab.com/ee/api/jobs.html#get-a-log-file
+func (mock *GitlabApiMock) GetTraceFileHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	_, job, err := mock.getJobFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	responseWriter.Header().Set("Content-Type", "text/plain")
+	responseWriter.WriteHeader(http.StatusOK)
+	responseWriter.Write(mock.gitlabMock.jobTraces[job.ID])
+}
+
+// getRunnerJob resolves the job of the job_id path variable, which has to match the CI_JOB_TOKEN of the request.
+func (mock *GitlabApiMock) getRunnerJob(request *http.Request) (*gitlab.Job, error) {
+	jobID, _ := strconv.Atoi(pathVar(request, "job_id"))
+
+	token := jobTokenFromRequest(request)
+	if token == "" {
+		token = request.FormValue("token")
+	}
+
+	job, isJobToken := mock.gitlabMock.jobByToken(token)
+	if !isJobToken || job.ID != jobID {
+		return nil, newAPIError(http.StatusForbidden, "403 Forbidden")
+	}
+
+	return job, nil
+}
+
+// UploadJobArtifactsHandler implements the endpoint runners upload the artifacts of a job to, see
+// https://gitlab.com/gitlab-org/gitlab/-/blob/master/lib/api/ci/runner.rb
+func (mock *GitlabApiMock) UploadJobArtifactsHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	err := request.ParseMultipartForm(maxArtifactsUploadMemory)
+	if err != nil {
+		writeErrorMessage(responseWriter, http.StatusBadRequest, "400 Bad request - "+err.Error())
+		return
+	}
+
+	job, err := mock.getRunnerJob(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	if job.Status != string(gitlab.Running) {
+		writeErrorMessage(responseWriter, http.StatusForbidden, "403 Forbidden - Job is not running")
+		return
+	}
+
+	file, _, err := request.FormFile("file")
+	if err != nil {
+		writeErrorMessage(responseWriter, http.StatusBadRequest, "400 Bad request - Missing file")
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	err = mock.gitlabMock.uploadJobArtifact(job, request.FormValue("artifact_type"), request.FormValue("artifact_format"), data, request.FormValue("expire_in"))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusCreated, job)
+}
@@ -0,0 +1,170 @@
+package gitlabapimock
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// parseAuditEventTime parses the created_after and created_before parameters, which are either timestamps or
+// dates.
+func parseAuditEventTime(name string, value string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return parsed, nil
+		}
+	}
+
+	return time.Time{}, newAPIError(http.StatusBadRequest, name+" is invalid")
+}
+
+// writeAuditEvents writes the events created in the range of the created_after and created_before parameters,
+// newest first.
+func writeAuditEvents(responseWriter http.ResponseWriter, request *http.Request, events []*gitlab.AuditEvent) {
+	query := request.URL.Query()
+
+	var createdAfter, createdBefore time.Time
+
+	for name, date := range map[string]*time.Time{"created_after": &createdAfter, "created_before": &createdBefore} {
+		if value := query.Get(name); value != "" {
+			parsed, err := parseAuditEventTime(name, value)
+			if err != nil {
+				writeError(responseWriter, err)
+				return
+			}
+
+			*date = parsed
+		}
+	}
+
+	filtered := []*gitlab.AuditEvent{}
+
+	for _, event := range events {
+		if (!createdAfter.IsZero() && event.CreatedAt.Before(createdAfter)) || (!createdBefore.IsZero() && event.CreatedAt.After(createdBefore)) {
+			continue
+		}
+
+		filtered = append(filtered, event)
+	}
+
+	slices.Reverse(filtered)
+
+	writeJSON(responseWriter, http.StatusOK, filtered)
+}
+
+// writeAuditEvent writes the event with the event_id of the request out of the events.
+func writeAuditEvent(responseWriter http.ResponseWriter, request *http.Request, events []*gitlab.AuditEvent) {
+	eventID, _ := strconv.Atoi(pathVar(request, "event_id"))
+
+	event, err := getAuditEvent(events, eventID)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, event)
+}
+
+// ListInstanceAuditEventsHandler implements https://docs.gitlab.com/ee/api/audit_events.html#retrieve-all-instance-audit-events
+func (mock *GitlabApiMock) ListInstanceAuditEventsHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	if err := mock.checkAdmin(request); err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	query := request.URL.Query()
+	entityType := query.Get("entity_type")
+	entityID, _ := strconv.Atoi(query.Get("entity_id"))
+
+	events := []*gitlab.AuditEvent{}
+
+	for _, event := range mock.gitlabMock.auditEvents {
+		if (entityType == "" || event.EntityType == entityType) && (entityID == 0 || event.EntityID == entityID) {
+			events = append(events, event)
+		}
+	}
+
+	writeAuditEvents(responseWriter, request, events)
+}
+
+// GetInstanceAuditEventHandler implements https://docs.gitlab.com/ee/api/audit_events.html#retrieve-single-instance-audit-event
+func (mock *GitlabApiMock) GetInstanceAuditEventHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	if err := mock.checkAdmin(request); err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeAuditEvent(responseWriter, request, mock.gitlabMock.auditEvents)
+}
+
+// getGroupAuditEvents resolves the group of the request and returns its audit events, only owners may read them.
+func (mock *GitlabApiMock) getGroupAuditEvents(request *http.Request) ([]*gitlab.AuditEvent, error) {
+	group, groupExists := mock.getGroup(request)
+	if !groupExists {
+		return nil, newAPIError(http.StatusNotFound, "404 Group Not Found")
+	}
+
+	if !mock.gitlabMock.hasGroupAccess(group, currentUser(request), gitlab.OwnerPermissions) {
+		return nil, newAPIError(http.StatusForbidden, "403 Forbidden")
+	}
+
+	return mock.gitlabMock.entityAuditEvents("Group", group.ID), nil
+}
+
+// ListGroupAuditEventsHandler implements https://docs.gitlab.com/ee/api/audit_events.html#retrieve-all-group-audit-events
+func (mock *GitlabApiMock) ListGroupAuditEventsHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	events, err := mock.getGroupAuditEvents(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeAuditEvents(responseWriter, request, events)
+}
+
+// GetGroupAuditEventHandler implements https://docs.gitlab.com/ee/api/audit_events.html#retrieve-a-specific-group-audit-event
+func (mock *GitlabApiMock) GetGroupAuditEventHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	events, err := mock.getGroupAuditEvents(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeAuditEvent(responseWriter, request, events)
+}
+
+// getProjectAuditEvents resolves the project of the request and returns its audit events, only maintainers may
+// read them.
+func (mock *GitlabApiMock) getProjectAuditEvents(request *http.Request) ([]*gitlab.AuditEvent, error) {
+	project, err := mock.getProjectWithAccess(request, gitlab.MaintainerPermissions)
+	if err != nil {
+		return nil, err
+	}
+
+	return mock.gitlabMock.entityAuditEvents("Project", project.ID), nil
+}
+
+// ListProjectAuditEventsHandler implements https://docs.gitlab.com/ee/api/audit_events.html#retrieve-all-project-audit-events
+func (mock *GitlabApiMock) ListProjectAuditEventsHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	events, err := mock.getProjectAuditEvents(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeAuditEvents(responseWriter, request, events)
+}
+
+// GetProjectAuditEventHandler implements https://docs.gitlab.com/ee/api/audit_events.html#retrieve-a-specific-project-audit-event
+func (mock *GitlabApiMock) GetProjectAuditEventHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	events, err := mock.getProjectAuditEvents(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeAuditEvent(responseWriter, request, events)
+}
@@ -0,0 +1,135 @@
+package gitlabapimock
+
+import (
+	"net/http"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// LintHandler implements https://docs.gitlab.com/ee/api/lint.html#validate-the-ci-yaml-configuration-deprecated
+func (mock *GitlabApiMock) LintHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	var lintOptions gitlab.LintOptions
+	err := decodeBody(request, &lintOptions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	if lintOptions.Content == "" {
+		writeErrorMessage(responseWriter, http.StatusBadRequest, "content is missing")
+		return
+	}
+
+	result, err := mock.gitlabMock.lintCIConfig(nil, lintOptions.Content, "", false, lintOptions.IncludeJobs)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	result.Status = "invalid"
+	if result.Valid {
+		result.Status = "valid"
+	}
+
+	if !lintOptions.IncludeMergedYAML {
+		result.MergedYaml = ""
+	}
+
+	writeJSON(responseWriter, http.StatusOK, result)
+}
+
+// ProjectNamespaceLintHandler implements https://docs.gitlab.com/ee/api/lint.html#validate-sample-cicd-configuration
+func (mock *GitlabApiMock) ProjectNamespaceLintHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, err := mock.getProjectWithAccess(request, gitlab.ReporterPermissions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	var lintOptions gitlab.ProjectNamespaceLintOptions
+	err = decodeBody(request, &lintOptions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	if lintOptions.Content == nil {
+		writeErrorMessage(responseWriter, http.StatusBadRequest, "content is missing")
+		return
+	}
+
+	dryRun := valueOf(lintOptions.DryRun)
+	if dryRun && !mock.gitlabMock.hasAccess(project, currentUser(request), gitlab.DeveloperPermissions) {
+		writeErrorMessage(responseWriter, http.StatusForbidden, "403 Forbidden")
+		return
+	}
+
+	result, err := mock.gitlabMock.lintCIConfig(project, *lintOptions.Content, valueOf(lintOptions.Ref), dryRun, valueOf(lintOptions.IncludeJobs))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, result)
+}
+
+// ProjectLintHandler implements https://docs.gitlab.com/ee/api/lint.html#validate-a-projects-cicd-configuration
+func (mock *GitlabApiMock) ProjectLintHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, err := mock.getProjectWithAccess(request, gitlab.ReporterPermissions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	var lintOptions gitlab.ProjectLintOptions
+	err = decodeQuery(request, &lintOptions)
+	if err != nil {
+		writeErrorMessage(responseWriter, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	dryRun := valueOf(lintOptions.DryRun)
+	if dryRun && !mock.gitlabMock.hasAccess(project, currentUser(request), gitlab.DeveloperPermissions) {
+		writeErrorMessage(responseWriter, http.StatusForbidden, "403 Forbidden")
+		return
+	}
+
+	contentRef := valueOf(lintOptions.ContentRef)
+	if contentRef == "" {
+		contentRef = valueOf(lintOptions.Ref)
+	}
+	if contentRef == "" {
+		contentRef = project.DefaultBranch
+	}
+
+	dryRunRef := valueOf(lintOptions.DryRunRef)
+	if dryRunRef == "" {
+		dryRunRef = contentRef
+	}
+
+	repo, err := mock.gitlabMock.getRepository(project)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	sha, err := repo.resolve(contentRef)
+	if err != nil {
+		writeErrorMessage(responseWriter, http.StatusNotFound, "404 Commit Not Found")
+		return
+	}
+
+	content, err := repo.readFile(sha, ciConfigPath)
+	if err != nil {
+		writeErrorMessage(responseWriter, http.StatusNotFound, "404 File Not Found")
+		return
+	}
+
+	result, err := mock.gitlabMock.lintCIConfig(project, string(content), dryRunRef, dryRun, valueOf(lintOptions.IncludeJobs))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, result)
+}
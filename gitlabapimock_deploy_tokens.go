@@ -0,0 +1,194 @@
+package gitlabapimock
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// deployTokensActiveFilter returns the value of the active query parameter or nil if it is not set.
+func deployTokensActiveFilter(request *http.Request) *bool {
+	active, err := strconv.ParseBool(request.URL.Query().Get("active"))
+	if err != nil {
+		return nil
+	}
+
+	return &active
+}
+
+// ListAllDeployTokensHandler implements https://docs.gitlab.com/ee/api/deploy_tokens.html#list-all-deploy-tokens
+func (mock *GitlabApiMock) ListAllDeployTokensHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	if err := mock.checkAdmin(request); err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, mock.gitlabMock.filterDeployTokens(deployTokensActiveFilter(request), func(*deployToken) bool {
+		return true
+	}))
+}
+
+// getProjectDeployTokenFromRequest resolves the deploy token of the id and token_id path variables.
+func (mock *GitlabApiMock) getProjectDeployTokenFromRequest(request *http.Request) (*deployToken, error) {
+	project, err := mock.getProjectWithAccess(request, gitlab.MaintainerPermissions)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenID, _ := strconv.Atoi(pathVar(request, "token_id"))
+
+	return mock.gitlabMock.getDeployToken(project.ID, 0, tokenID)
+}
+
+// ListProjectDeployTokensHandler implements https://docs.gitlab.com/ee/api/deploy_tokens.html#list-project-deploy-tokens
+func (mock *GitlabApiMock) ListProjectDeployTokensHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, err := mock.getProjectWithAccess(request, gitlab.MaintainerPermissions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, mock.gitlabMock.filterDeployTokens(deployTokensActiveFilter(request), func(token *deployToken) bool {
+		return token.projectID == project.ID
+	}))
+}
+
+// GetProjectDeployTokenHandler implements https://docs.gitlab.com/ee/api/deploy_tokens.html#get-a-project-deploy-token
+func (mock *GitlabApiMock) GetProjectDeployTokenHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	token, err := mock.getProjectDeployTokenFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, mock.gitlabMock.visibleDeployToken(token))
+}
+
+// CreateProjectDeployTokenHandler implements https://docs.gitlab.com/ee/api/deploy_tokens.html#create-a-project-deploy-token
+func (mock *GitlabApiMock) CreateProjectDeployTokenHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, err := mock.getProjectWithAccess(request, gitlab.MaintainerPermissions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	var options gitlab.CreateProjectDeployTokenOptions
+
+	err = decodeBody(request, &options)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	token, err := mock.gitlabMock.createDeployToken(project.ID, 0, options.Name, options.Username, options.ExpiresAt, options.Scopes)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusCreated, token.DeployToken)
+}
+
+// DeleteProjectDeployTokenHandler implements https://docs.gitlab.com/ee/api/deploy_tokens.html#delete-a-project-deploy-token,
+// the token is revoked.
+func (mock *GitlabApiMock) DeleteProjectDeployTokenHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	token, err := mock.getProjectDeployTokenFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	token.Revoked = true
+
+	responseWriter.WriteHeader(http.StatusNoContent)
+}
+
+// getGroupForDeployTokens resolves the group of the request, its deploy tokens are managed by maintainers.
+func (mock *GitlabApiMock) getGroupForDeployTokens(request *http.Request) (*gitlab.Group, error) {
+	group, groupExists := mock.getGroup(request)
+	if !groupExists {
+		return nil, newAPIError(http.StatusNotFound, "404 Group Not Found")
+	}
+
+	if !mock.gitlabMock.hasGroupAccess(group, currentUser(request), gitlab.MaintainerPermissions) {
+		return nil, newAPIError(http.StatusForbidden, "403 Forbidden")
+	}
+
+	return group, nil
+}
+
+// getGroupDeployTokenFromRequest resolves the deploy token of the id and token_id path variables.
+func (mock *GitlabApiMock) getGroupDeployTokenFromRequest(request *http.Request) (*deployToken, error) {
+	group, err := mock.getGroupForDeployTokens(request)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenID, _ := strconv.Atoi(pathVar(request, "token_id"))
+
+	return mock.gitlabMock.getDeployToken(0, group.ID, tokenID)
+}
+
+// ListGroupDeployTokensHandler implements https://docs.gitlab.com/ee/api/deploy_tokens.html#list-group-deploy-tokens
+func (mock *GitlabApiMock) ListGroupDeployTokensHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	group, err := mock.getGroupForDeployTokens(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, mock.gitlabMock.filterDeployTokens(deployTokensActiveFilter(request), func(token *deployToken) bool {
+		return token.groupID == group.ID
+	}))
+}
+
+// GetGroupDeployTokenHandler implements https://docs.gitlab.com/ee/api/deploy_tokens.html#get-a-group-deploy-token
+func (mock *GitlabApiMock) GetGroupDeployTokenHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	token, err := mock.getGroupDeployTokenFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, mock.gitlabMock.visibleDeployToken(token))
+}
+
+// CreateGroupDeployTokenHandler implements https://docs.gitlab.com/ee/api/deploy_tokens.html#create-a-group-deploy-token
+func (mock *GitlabApiMock) CreateGroupDeployTokenHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	group, err := mock.getGroupForDeployTokens(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	var options gitlab.CreateGroupDeployTokenOptions
+
+	err = decodeBody(request, &options)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	token, err := mock.gitlabMock.createDeployToken(0, group.ID, options.Name, options.Username, options.ExpiresAt, options.Scopes)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusCreated, token.DeployToken)
+}
+
+// DeleteGroupDeployTokenHandler implements https://docs.gitlab.com/ee/api/deploy_tokens.html#delete-a-group-deploy-token,
+// the token is revoked.
+func (mock *GitlabApiMock) DeleteGroupDeployTokenHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	token, err := mock.getGroupDeployTokenFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	token.Revoked = true
+
+	responseWriter.WriteHeader(http.StatusNoContent)
+}
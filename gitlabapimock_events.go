@@ -0,0 +1,110 @@
+package gitlabapimock
+
+import (
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// filterActivityEvents returns the events matching the action, target_type, before and after parameters of the
+// request, newest first unless sort is asc. Only events of projects the user may read are returned.
+func (mock *GitlabApiMock) filterActivityEvents(request *http.Request, match func(event *activityEvent) bool) ([]*gitlab.ContributionEvent, error) {
+	query := request.URL.Query()
+
+	var before, after time.Time
+
+	for name, date := range map[string]*time.Time{"before": &before, "after": &after} {
+		if value := query.Get(name); value != "" {
+			parsed, err := time.Parse(time.DateOnly, value)
+			if err != nil {
+				return nil, newAPIError(http.StatusBadRequest, name+" is invalid")
+			}
+
+			*date = parsed
+		}
+	}
+
+	user := currentUser(request)
+	events := []*gitlab.ContributionEvent{}
+
+	for _, event := range mock.gitlabMock.activityEvents {
+		project, projectExists := mock.gitlabMock.projects[event.ProjectID]
+		if !projectExists || !mock.gitlabMock.hasAccess(project, user, gitlab.GuestPermissions) || !match(event) {
+			continue
+		}
+
+		if action := query.Get("action"); action != "" && string(event.action) != action {
+			continue
+		}
+
+		if targetType := query.Get("target_type"); targetType != "" && string(event.targetType()) != targetType {
+			continue
+		}
+
+		// Both dates are exclusive, events of the before date and of the after date are left out.
+		createdOn := event.CreatedAt.UTC().Truncate(24 * time.Hour)
+		if (!before.IsZero() && !createdOn.Before(before)) || (!after.IsZero() && !createdOn.After(after)) {
+			continue
+		}
+
+		events = append(events, event.ContributionEvent)
+	}
+
+	if query.Get("sort") != "asc" {
+		slices.Reverse(events)
+	}
+
+	return events, nil
+}
+
+// writeActivityEvents writes the filtered events or the error of invalid filters.
+func (mock *GitlabApiMock) writeActivityEvents(responseWriter http.ResponseWriter, request *http.Request, match func(event *activityEvent) bool) {
+	events, err := mock.filterActivityEvents(request, match)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, events)
+}
+
+// ListCurrentUserEventsHandler implements https://docs.gitlab.com/ee/api/events.html#list-currently-authenticated-users-events
+func (mock *GitlabApiMock) ListCurrentUserEventsHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	user := currentUser(request)
+	if user == nil {
+		writeErrorMessage(responseWriter, http.StatusUnauthorized, "401 Unauthorized")
+		return
+	}
+
+	mock.writeActivityEvents(responseWriter, request, func(event *activityEvent) bool {
+		return event.AuthorID == user.ID
+	})
+}
+
+// ListUserEventsHandler implements https://docs.gitlab.com/ee/api/events.html#get-user-contribution-events
+func (mock *GitlabApiMock) ListUserEventsHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	user, userExists := mock.getUserFromRequest(request)
+	if !userExists {
+		writeErrorMessage(responseWriter, http.StatusNotFound, "404 User Not Found")
+		return
+	}
+
+	mock.writeActivityEvents(responseWriter, request, func(event *activityEvent) bool {
+		return event.AuthorID == user.ID
+	})
+}
+
+// ListProjectEventsHandler implements https://docs.gitlab.com/ee/api/events.html#list-a-projects-visible-events
+func (mock *GitlabApiMock) ListProjectEventsHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, projectExists := mock.getProject(request)
+	if !projectExists || !mock.gitlabMock.hasAccess(project, currentUser(request), gitlab.GuestPermissions) {
+		writeErrorMessage(responseWriter, http.StatusNotFound, "404 Project Not Found")
+		return
+	}
+
+	mock.writeActivityEvents(responseWriter, request, func(event *activityEvent) bool {
+		return event.ProjectID == project.ID
+	})
+}
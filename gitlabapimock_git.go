@@ -0,0 +1,183 @@
+package gitlabapimock
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+const (
+	gitUploadPack  = "git-upload-pack"
+	gitReceivePack = "git-receive-pack"
+)
+
+// getGitProject resolves the project of a Git smart HTTP request and checks that the credentials
+// of the request allow the service to be used. It writes the error response itself.
+func (mock *GitlabApiMock) getGitProject(responseWriter http.ResponseWriter, request *http.Request, service string) (*gitlab.Project, *gitlab.User, bool) {
+	pathWithNamespace := fmt.Sprintf("%s/%s", pathVar(request, "namespace"), pathVar(request, "project"))
+
+	project, err := mock.gitlabMock.GetProjectByPath(pathWithNamespace)
+	if err != nil {
+		http.Error(responseWriter, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return nil, nil, false
+	}
+
+	if username, password, ok := request.BasicAuth(); ok {
+		deployToken, err := mock.gitlabMock.authenticateDeployToken(username, password)
+		if err != nil {
+			responseWriter.Header().Set("WWW-Authenticate", `Basic realm="GitLab"`)
+			http.Error(responseWriter, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return nil, nil, false
+		}
+
+		// Deploy tokens only pull the repositories of their project or group.
+		if deployToken != nil {
+			if service != gitUploadPack || !slices.Contains(deployToken.Scopes, "read_repository") || !mock.gitlabMock.deployTokenAllowsProject(deployToken, project) {
+				http.Error(responseWriter, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return nil, nil, false
+			}
+
+			return project, nil, true
+		}
+	}
+
+	rawToken := tokenFromRequest(request)
+
+	user, token, err := mock.gitlabMock.authenticate(rawToken)
+	if err != nil {
+		responseWriter.Header().Set("WWW-Authenticate", `Basic realm="GitLab"`)
+		http.Error(responseWriter, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return nil, nil, false
+	}
+
+	if scopes, scoped := mock.gitlabMock.tokenScopes(rawToken, token); scoped && !tokenAllowsGit(scopes, service) {
+		http.Error(responseWriter, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return nil, nil, false
+	}
+
+	if !mock.gitlabMock.authenticationRequired() {
+		return project, user, true
+	}
+
+	requiredAccessLevel := gitlab.DeveloperPermissions
+	if service == gitUploadPack {
+		if project.Visibility == gitlab.PublicVisibility {
+			return project, user, true
+		}
+
+		requiredAccessLevel = gitlab.ReporterPermissions
+	}
+
+	if user == nil {
+		responseWriter.Header().Set("WWW-Authenticate", `Basic realm="GitLab"`)
+		http.Error(responseWriter, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return nil, nil, false
+	}
+
+	if mock.gitlabMock.projectAccessLevel(project, user) < requiredAccessLevel {
+		http.Error(responseWriter, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return nil, nil, false
+	}
+
+	return project, user, true
+}
+
+// GitInfoRefsHandler implements the ref advertisement of the Git smart HTTP protocol,
+// see https://git-scm.com/docs/http-protocol#_smart_clients
+func (mock *GitlabApiMock) GitInfoRefsHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	service := request.URL.Query().Get("service")
+	if service != gitUploadPack && service != gitReceivePack {
+		http.Error(responseWriter, "Only smart HTTP is supported", http.StatusForbidden)
+		return
+	}
+
+	project, _, ok := mock.getGitProject(responseWriter, request, service)
+	if !ok {
+		return
+	}
+
+	repo, err := mock.gitlabMock.getRepository(project)
+	if err != nil {
+		http.Error(responseWriter, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	responseWriter.Header().Set("Content-Type", fmt.Sprintf("application/x-%s-advertisement", service))
+	responseWriter.Header().Set("Cache-Control", "no-cache")
+
+	header := fmt.Sprintf("# service=%s\n", service)
+	fmt.Fprintf(responseWriter, "%04x%s0000", len(header)+4, header)
+
+	err = repo.serviceRPC(service, true, nil, responseWriter)
+	if err != nil {
+		log.Printf("%s: %v", project.PathWithNamespace, err)
+	}
+}
+
+// GitUploadPackHandler implements the fetch and clone part of the Git smart HTTP protocol.
+func (mock *GitlabApiMock) GitUploadPackHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	mock.serveGitService(responseWriter, request, gitUploadPack)
+}
+
+// GitReceivePackHandler implements the push part of the Git smart HTTP protocol. The pushed ref
+// updates are applied to the project afterwards.
+func (mock *GitlabApiMock) GitReceivePackHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	mock.serveGitService(responseWriter, request, gitReceivePack)
+}
+
+func (mock *GitlabApiMock) serveGitService(responseWriter http.ResponseWriter, request *http.Request, service string) {
+	project, user, ok := mock.getGitProject(responseWriter, request, service)
+	if !ok {
+		return
+	}
+
+	repo, err := mock.gitlabMock.getRepository(project)
+	if err != nil {
+		http.Error(responseWriter, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var body io.Reader = request.Body
+	if request.Header.Get("Content-Encoding") == "gzip" {
+		gzipReader, err := gzip.NewReader(request.Body)
+		if err != nil {
+			http.Error(responseWriter, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer gzipReader.Close()
+
+		body = gzipReader
+	}
+
+	refsBefore, err := repo.refs()
+	if err != nil {
+		http.Error(responseWriter, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	responseWriter.Header().Set("Content-Type", fmt.Sprintf("application/x-%s-result", service))
+	responseWriter.Header().Set("Cache-Control", "no-cache")
+
+	err = repo.serviceRPC(service, false, body, responseWriter)
+	if err != nil {
+		log.Printf("%s: %v", project.PathWithNamespace, err)
+		return
+	}
+
+	if service != gitReceivePack {
+		return
+	}
+
+	refsAfter, err := repo.refs()
+	if err != nil {
+		log.Printf("%s: %v", project.PathWithNamespace, err)
+		return
+	}
+
+	mock.gitlabMock.applyRefUpdates(project, user, diffRefs(refsBefore, refsAfter))
+}
@@ -0,0 +1,402 @@
+package gitlabapimock
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// getProjectHookFromRequest resolves the project and hook of the id and hook_id path variables.
+func (mock *GitlabApiMock) getProjectHookFromRequest(request *http.Request) (*gitlab.Project, *projectHook, error) {
+	project, err := mock.getProjectWithAccess(request, gitlab.MaintainerPermissions)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hookID, _ := strconv.Atoi(pathVar(request, "hook_id"))
+
+	hook, err := mock.gitlabMock.getProjectHook(project, hookID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return project, hook, nil
+}
+
+// ListProjectHooksHandler implements https://docs.gitlab.com/ee/api/projects.html#list-project-hooks
+func (mock *GitlabApiMock) ListProjectHooksHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, err := mock.getProjectWithAccess(request, gitlab.MaintainerPermissions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	hooks := append([]*projectHook{}, mock.gitlabMock.projectHooks[project.ID]...)
+
+	writeJSON(responseWriter, http.StatusOK, hooks)
+}
+
+// GetProjectHookHandler implements https://docs.gitlab.com/ee/api/projects.html#get-project-hook
+func (mock *GitlabApiMock) GetProjectHookHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	_, hook, err := mock.getProjectHookFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, hook)
+}
+
+// AddProjectHookHandler implements https://docs.gitlab.com/ee/api/projects.html#add-project-hook
+func (mock *GitlabApiMock) AddProjectHookHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, err := mock.getProjectWithAccess(request, gitlab.MaintainerPermissions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	var options ProjectHookOptions
+
+	err = decodeBody(request, &options)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	hook, err := mock.gitlabMock.addProjectHook(project, &options)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusCreated, hook)
+}
+
+// EditProjectHookHandler implements https://docs.gitlab.com/ee/api/projects.html#edit-project-hook
+func (mock *GitlabApiMock) EditProjectHookHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	_, hook, err := mock.getProjectHookFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	var options ProjectHookOptions
+
+	err = decodeBody(request, &options)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	err = mock.gitlabMock.applyProjectHookOptions(hook, &options)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, hook)
+}
+
+// DeleteProjectHookHandler implements https://docs.gitlab.com/ee/api/projects.html#delete-project-hook
+func (mock *GitlabApiMock) DeleteProjectHookHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, hook, err := mock.getProjectHookFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	mock.gitlabMock.removeProjectHook(project, hook)
+
+	responseWriter.WriteHeader(http.StatusNoContent)
+}
+
+// ListProjectHookEventsHandler implements https://docs.gitlab.com/ee/api/project_webhooks.html#get-a-list-of-project-webhook-events
+func (mock *GitlabApiMock) ListProjectHookEventsHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	_, hook, err := mock.getProjectHookFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeWebhookDeliveries(responseWriter, request, mock.gitlabMock.GetWebhookDeliveries(hook.ID))
+}
+
+// TestProjectHookHandler implements https://docs.gitlab.com/ee/api/project_webhooks.html#trigger-a-test-project-webhook
+func (mock *GitlabApiMock) TestProjectHookHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, hook, err := mock.getProjectHookFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	delivery, err := mock.gitlabMock.TestProjectHook(project, hook.ID, gitlab.ProjectHookEvent(pathVar(request, "trigger")), currentUser(request))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	if !delivery.successful() {
+		message := "Hook execution failed: " + delivery.ResponseStatus
+		if delivery.InternalErrorMessage != "" {
+			message = "Hook execution failed: " + delivery.InternalErrorMessage
+		}
+
+		writeErrorMessage(responseWriter, http.StatusUnprocessableEntity, message)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusCreated, map[string]string{"message": "201 Created"})
+}
+
+// writeWebhookDeliveries writes the deliveries of a hook, newest first and filtered by the status parameter,
+// which takes response codes or one of successful, client_failure and server_failure.
+func writeWebhookDeliveries(responseWriter http.ResponseWriter, request *http.Request, deliveries []*WebhookDelivery) {
+	statuses := append(request.URL.Query()["status"], request.URL.Query()["status[]"]...)
+
+	filtered := []*WebhookDelivery{}
+
+	for i := len(deliveries) - 1; i >= 0; i-- {
+		if len(statuses) == 0 || slices.ContainsFunc(statuses, deliveries[i].hasStatus) {
+			filtered = append(filtered, deliveries[i])
+		}
+	}
+
+	writeJSON(responseWriter, http.StatusOK, filtered)
+}
+
+// getGroupForHooks resolves the group of the request and checks that the user may manage its webhooks.
+func (mock *GitlabApiMock) getGroupForHooks(request *http.Request) (*gitlab.Group, error) {
+	group, groupExists := mock.getGroup(request)
+	if !groupExists {
+		return nil, newAPIError(http.StatusNotFound, "404 Group Not Found")
+	}
+
+	if !mock.gitlabMock.hasGroupAccess(group, currentUser(request), gitlab.OwnerPermissions) {
+		return nil, newAPIError(http.StatusForbidden, "403 Forbidden")
+	}
+
+	return group, nil
+}
+
+// getGroupHookFromRequest resolves the group and hook of the id and hook_id path variables.
+func (mock *GitlabApiMock) getGroupHookFromRequest(request *http.Request) (*gitlab.Group, *gitlab.GroupHook, error) {
+	group, err := mock.getGroupForHooks(request)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hookID, _ := strconv.Atoi(pathVar(request, "hook_id"))
+
+	hook, err := mock.gitlabMock.getGroupHook(group, hookID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return group, hook, nil
+}
+
+// ListGroupHooksHandler implements https://docs.gitlab.com/ee/api/groups.html#list-group-hooks
+func (mock *GitlabApiMock) ListGroupHooksHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	group, err := mock.getGroupForHooks(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, mock.gitlabMock.GetGroupHooks(group))
+}
+
+// GetGroupHookHandler implements https://docs.gitlab.com/ee/api/groups.html#get-group-hook
+func (mock *GitlabApiMock) GetGroupHookHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	_, hook, err := mock.getGroupHookFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, hook)
+}
+
+// AddGroupHookHandler implements https://docs.gitlab.com/ee/api/groups.html#add-group-hook
+func (mock *GitlabApiMock) AddGroupHookHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	group, err := mock.getGroupForHooks(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	var options gitlab.AddGroupHookOptions
+
+	err = decodeBody(request, &options)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	hook, err := mock.gitlabMock.AddGroupHook(group, &options)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusCreated, hook)
+}
+
+// EditGroupHookHandler implements https://docs.gitlab.com/ee/api/groups.html#edit-group-hook
+func (mock *GitlabApiMock) EditGroupHookHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	_, hook, err := mock.getGroupHookFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	var options gitlab.AddGroupHookOptions
+
+	err = decodeBody(request, &options)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	err = mock.gitlabMock.applyGroupHookOptions(hook, &options)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, hook)
+}
+
+// DeleteGroupHookHandler implements https://docs.gitlab.com/ee/api/groups.html#delete-group-hook
+func (mock *GitlabApiMock) DeleteGroupHookHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	group, hook, err := mock.getGroupHookFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	mock.gitlabMock.removeGroupHook(group, hook)
+
+	responseWriter.WriteHeader(http.StatusNoContent)
+}
+
+// ListGroupHookEventsHandler implements https://docs.gitlab.com/ee/api/group_webhooks.html#get-group-webhook-events
+func (mock *GitlabApiMock) ListGroupHookEventsHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	_, hook, err := mock.getGroupHookFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeWebhookDeliveries(responseWriter, request, mock.gitlabMock.GetWebhookDeliveries(hook.ID))
+}
+
+// getSystemHookFromRequest resolves the system hook of the hook_id path variable for admins.
+func (mock *GitlabApiMock) getSystemHookFromRequest(request *http.Request) (*gitlab.Hook, error) {
+	err := mock.checkAdmin(request)
+	if err != nil {
+		return nil, err
+	}
+
+	hookID, _ := strconv.Atoi(pathVar(request, "hook_id"))
+
+	return mock.gitlabMock.getSystemHook(hookID)
+}
+
+// ListSystemHooksHandler implements https://docs.gitlab.com/ee/api/system_hooks.html#list-system-hooks
+func (mock *GitlabApiMock) ListSystemHooksHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	err := mock.checkAdmin(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, mock.gitlabMock.GetSystemHooks())
+}
+
+// GetSystemHookHandler implements https://docs.gitlab.com/ee/api/system_hooks.html#get-system-hook
+func (mock *GitlabApiMock) GetSystemHookHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	hook, err := mock.getSystemHookFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, hook)
+}
+
+// AddSystemHookHandler implements https://docs.gitlab.com/ee/api/system_hooks.html#add-new-system-hook
+func (mock *GitlabApiMock) AddSystemHookHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	err := mock.checkAdmin(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	var options gitlab.AddHookOptions
+
+	err = decodeBody(request, &options)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	hook, err := mock.gitlabMock.AddSystemHook(&options)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusCreated, hook)
+}
+
+// EditSystemHookHandler implements https://docs.gitlab.com/ee/api/system_hooks.html#update-system-hook
+func (mock *GitlabApiMock) EditSystemHookHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	hook, err := mock.getSystemHookFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	var options gitlab.AddHookOptions
+
+	err = decodeBody(request, &options)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	err = mock.gitlabMock.applySystemHookOptions(hook, &options)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, hook)
+}
+
+// TestSystemHookHandler implements https://docs.gitlab.com/ee/api/system_hooks.html#test-system-hook
+func (mock *GitlabApiMock) TestSystemHookHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	hook, err := mock.getSystemHookFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusCreated, mock.gitlabMock.testSystemHook(hook))
+}
+
+// DeleteSystemHookHandler implements https://docs.gitlab.com/ee/api/system_hooks.html#delete-system-hook
+func (mock *GitlabApiMock) DeleteSystemHookHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	hook, err := mock.getSystemHookFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	mock.gitlabMock.removeSystemHook(hook)
+
+	responseWriter.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,399 @@
+package gitlabapimock
+
+import (
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// matchesMilestoneFilter implements the milestone parameter of list endpoints: a milestone title,
+// "None" or "Any".
+func matchesMilestoneFilter(milestone *gitlab.Milestone, filter string) bool {
+	switch filter {
+	case "":
+		return true
+	case "None":
+		return milestone == nil
+	case "Any":
+		return milestone != nil
+	}
+
+	return milestone != nil && milestone.Title == filter
+}
+
+// matchesIterationFilter implements the iteration_id parameter of the issue list endpoints: an iteration
+// ID, "None" or "Any".
+func matchesIterationFilter(iteration *gitlab.GroupIteration, filter string) bool {
+	switch strings.ToLower(filter) {
+	case "":
+		return true
+	case "none":
+		return iteration == nil
+	case "any":
+		return iteration != nil
+	}
+
+	iterationID, _ := strconv.Atoi(filter)
+
+	return iteration != nil && iteration.ID == iterationID
+}
+
+// matchesSearchFilter implements the search and in parameters of the issue list endpoints.
+func matchesSearchFilter(issue *gitlab.Issue, query url.Values) bool {
+	search := query.Get("search")
+	if search == "" {
+		return true
+	}
+
+	in := query.Get("in")
+	if in == "" {
+		in = "title,description"
+	}
+
+	search = strings.ToLower(search)
+
+	for _, field := range strings.Split(in, ",") {
+		switch strings.TrimSpace(field) {
+		case "title":
+			if strings.Contains(strings.ToLower(issue.Title), search) {
+				return true
+			}
+		case "description":
+			if strings.Contains(strings.ToLower(issue.Description), search) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// filterIssues applies the filters of https://docs.gitlab.com/ee/api/issues.html#list-issues
+func filterIssues(issues []*gitlab.Issue, query url.Values, user *gitlab.User) []*gitlab.Issue {
+	filteredIssues := []*gitlab.Issue{}
+
+	for _, issue := range issues {
+		if state := query.Get("state"); state != "" && state != "all" && state != issue.State {
+			continue
+		}
+
+		if user != nil {
+			switch query.Get("scope") {
+			case "created_by_me", "created-by-me":
+				if issue.Author == nil || issue.Author.ID != user.ID {
+					continue
+				}
+			case "assigned_to_me", "assigned-to-me":
+				if !matchesUserFilter(issueAssignees(issue), strconv.Itoa(user.ID)) {
+					continue
+				}
+			}
+		}
+
+		if !matchesIIDsFilter(issue.IID, query) {
+			continue
+		}
+
+		if !matchesLabelsFilter(issue.Labels, query.Get("labels")) {
+			continue
+		}
+
+		if notLabels := query.Get("not[labels]"); notLabels != "" && matchesLabelsFilter(issue.Labels, notLabels) {
+			continue
+		}
+
+		if !matchesMilestoneFilter(issue.Milestone, query.Get("milestone")) {
+			continue
+		}
+
+		if !matchesIterationFilter(issue.Iteration, query.Get("iteration_id")) {
+			continue
+		}
+
+		if !matchesUserFilter(userList(issueAuthor(issue)), query.Get("author_id")) {
+			continue
+		}
+
+		if !matchesUsernameFilter(userList(issueAuthor(issue)), query.Get("author_username")) {
+			continue
+		}
+
+		if !matchesUserFilter(issueAssignees(issue), query.Get("assignee_id")) {
+			continue
+		}
+
+		if !matchesUsernameFilter(issueAssignees(issue), query.Get("assignee_username")) {
+			continue
+		}
+
+		if !matchesSearchFilter(issue, query) {
+			continue
+		}
+
+		if confidential, err := strconv.ParseBool(query.Get("confidential")); err == nil && confidential != issue.Confidential {
+			continue
+		}
+
+		if issueType := query.Get("issue_type"); issueType != "" && (issue.IssueType == nil || *issue.IssueType != issueType) {
+			continue
+		}
+
+		if !matchesTimeFilter(issue.CreatedAt, query, "created_after", "created_before") {
+			continue
+		}
+
+		if !matchesTimeFilter(issue.UpdatedAt, query, "updated_after", "updated_before") {
+			continue
+		}
+
+		filteredIssues = append(filteredIssues, issue)
+	}
+
+	orderBy := query.Get("order_by")
+	ascending := query.Get("sort") == "asc"
+
+	sort.SliceStable(filteredIssues, func(i, j int) bool {
+		left, right := filteredIssues[i], filteredIssues[j]
+
+		var comparison int
+
+		switch orderBy {
+		case "title":
+			comparison = strings.Compare(left.Title, right.Title)
+		case "updated_at":
+			comparison = left.UpdatedAt.Compare(*right.UpdatedAt)
+		case "due_date":
+			// Issues without due date are always listed last.
+			switch {
+			case left.DueDate == nil && right.DueDate == nil:
+				comparison = 0
+			case left.DueDate == nil:
+				return false
+			case right.DueDate == nil:
+				return true
+			default:
+				comparison = time.Time(*left.DueDate).Compare(time.Time(*right.DueDate))
+			}
+		default:
+			comparison = left.CreatedAt.Compare(*right.CreatedAt)
+			if comparison == 0 {
+				comparison = left.ID - right.ID
+			}
+		}
+
+		if ascending {
+			return comparison < 0
+		}
+
+		return comparison > 0
+	})
+
+	return filteredIssues
+}
+
+// getIssueFromRequest resolves the project and issue referenced by the id and issue_iid path variables.
+// Issues the user may not see are reported as not found.
+func (mock *GitlabApiMock) getIssueFromRequest(request *http.Request) (*gitlab.Project, *gitlab.Issue, error) {
+	project, projectExists := mock.getProject(request)
+	if !projectExists {
+		return nil, nil, newAPIError(http.StatusNotFound, "404 Project Not Found")
+	}
+
+	iid, _ := strconv.Atoi(pathVar(request, "issue_iid"))
+
+	issue, err := mock.gitlabMock.getIssue(project, iid)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !mock.gitlabMock.canReadIssue(project, issue, currentUser(request)) {
+		return nil, nil, newAPIError(http.StatusNotFound, "404 Not found")
+	}
+
+	return project, issue, nil
+}
+
+// ListIssuesHandler implements https://docs.gitlab.com/ee/api/issues.html#list-issues
+func (mock *GitlabApiMock) ListIssuesHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	user := currentUser(request)
+
+	issues := []*gitlab.Issue{}
+	for _, project := range mock.gitlabMock.projects {
+		issues = append(issues, mock.gitlabMock.getIssues(project, user)...)
+	}
+
+	// The scope of the global issue list defaults to the issues created by the user.
+	query := request.URL.Query()
+	if query.Get("scope") == "" {
+		query.Set("scope", "created_by_me")
+	}
+
+	writeJSON(responseWriter, http.StatusOK, filterIssues(issues, query, user))
+}
+
+// ListGroupIssuesHandler implements https://docs.gitlab.com/ee/api/issues.html#list-group-issues
+func (mock *GitlabApiMock) ListGroupIssuesHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	group, groupExists := mock.getGroup(request)
+	if !groupExists {
+		writeErrorMessage(responseWriter, http.StatusNotFound, "404 Group Not Found")
+		return
+	}
+
+	user := currentUser(request)
+
+	issues := []*gitlab.Issue{}
+	for _, project := range mock.gitlabMock.projects {
+		if project.Namespace != nil && project.Namespace.ID == group.ID {
+			issues = append(issues, mock.gitlabMock.getIssues(project, user)...)
+		}
+	}
+
+	writeJSON(responseWriter, http.StatusOK, filterIssues(issues, request.URL.Query(), user))
+}
+
+// ListProjectIssuesHandler implements https://docs.gitlab.com/ee/api/issues.html#list-project-issues
+func (mock *GitlabApiMock) ListProjectIssuesHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, projectExists := mock.getProject(request)
+	if !projectExists {
+		writeErrorMessage(responseWriter, http.StatusNotFound, "404 Project Not Found")
+		return
+	}
+
+	user := currentUser(request)
+
+	// Guests list the issues they may read, like canReadIssue.
+	if !mock.gitlabMock.hasAccess(project, user, gitlab.GuestPermissions) {
+		writeErrorMessage(responseWriter, http.StatusForbidden, "403 Forbidden")
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, filterIssues(mock.gitlabMock.getIssues(project, user), request.URL.Query(), user))
+}
+
+// GetIssueHandler implements https://docs.gitlab.com/ee/api/issues.html#single-issue
+func (mock *GitlabApiMock) GetIssueHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	issueID, _ := strconv.Atoi(pathVar(request, "issue_id"))
+
+	project, issue, err := mock.gitlabMock.getIssueByID(issueID)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	if !mock.gitlabMock.canReadIssue(project, issue, currentUser(request)) {
+		writeErrorMessage(responseWriter, http.StatusNotFound, "404 Not found")
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, issue)
+}
+
+// GetProjectIssueHandler implements https://docs.gitlab.com/ee/api/issues.html#single-project-issue
+func (mock *GitlabApiMock) GetProjectIssueHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	_, issue, err := mock.getIssueFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, issue)
+}
+
+// CreateIssueHandler implements https://docs.gitlab.com/ee/api/issues.html#new-issue
+func (mock *GitlabApiMock) CreateIssueHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, projectExists := mock.getProject(request)
+	if !projectExists {
+		writeErrorMessage(responseWriter, http.StatusNotFound, "404 Project Not Found")
+		return
+	}
+
+	var createIssueOptions gitlab.CreateIssueOptions
+	err := decodeBody(request, &createIssueOptions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	issue, err := mock.gitlabMock.AddIssue(project, &createIssueOptions, currentUser(request))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusCreated, issue)
+}
+
+// UpdateIssueHandler implements https://docs.gitlab.com/ee/api/issues.html#edit-an-issue
+func (mock *GitlabApiMock) UpdateIssueHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, issue, err := mock.getIssueFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	var updateIssueOptions gitlab.UpdateIssueOptions
+	err = decodeBody(request, &updateIssueOptions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	issue, err = mock.gitlabMock.updateIssue(project, issue.IID, &updateIssueOptions, currentUser(request))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, issue)
+}
+
+// DeleteIssueHandler implements https://docs.gitlab.com/ee/api/issues.html#delete-an-issue
+func (mock *GitlabApiMock) DeleteIssueHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, issue, err := mock.getIssueFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	err = mock.gitlabMock.deleteIssue(project, issue.IID, currentUser(request))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	responseWriter.WriteHeader(http.StatusNoContent)
+}
+
+// MoveIssueHandler implements https://docs.gitlab.com/ee/api/issues.html#move-an-issue
+func (mock *GitlabApiMock) MoveIssueHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, issue, err := mock.getIssueFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	var moveIssueOptions gitlab.MoveIssueOptions
+	err = decodeBody(request, &moveIssueOptions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	if moveIssueOptions.ToProjectID == nil {
+		writeErrorMessage(responseWriter, http.StatusBadRequest, "to_project_id is missing")
+		return
+	}
+
+	issue, err = mock.gitlabMock.moveIssue(project, issue.IID, *moveIssueOptions.ToProjectID, currentUser(request))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusCreated, issue)
+}
@@ -0,0 +1,84 @@
+package gitlabapimock
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// filterIterations applies the filters of https://docs.gitlab.com/ee/api/group_iterations.html#list-group-iterations
+func filterIterations(iterations []*gitlab.GroupIteration, query url.Values) []*gitlab.GroupIteration {
+	search := strings.ToLower(query.Get("search"))
+
+	states := map[string][]int{
+		"":         {iterationUpcoming, iterationCurrent, iterationClosed},
+		"all":      {iterationUpcoming, iterationCurrent, iterationClosed},
+		"opened":   {iterationUpcoming, iterationCurrent},
+		"upcoming": {iterationUpcoming},
+		"current":  {iterationCurrent},
+		"closed":   {iterationClosed},
+	}[query.Get("state")]
+
+	filteredIterations := []*gitlab.GroupIteration{}
+
+	for _, iteration := range iterations {
+		if !slices.Contains(states, iteration.State) {
+			continue
+		}
+
+		if search != "" && !strings.Contains(strings.ToLower(iteration.Title), search) {
+			continue
+		}
+
+		filteredIterations = append(filteredIterations, iteration)
+	}
+
+	return filteredIterations
+}
+
+// ListGroupIterationsHandler implements https://docs.gitlab.com/ee/api/group_iterations.html#list-group-iterations
+func (mock *GitlabApiMock) ListGroupIterationsHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	group, groupExists := mock.getGroup(request)
+	if !groupExists {
+		writeErrorMessage(responseWriter, http.StatusNotFound, "404 Group Not Found")
+		return
+	}
+
+	includeAncestors, err := strconv.ParseBool(request.URL.Query().Get("include_ancestors"))
+	if err != nil {
+		includeAncestors = true
+	}
+
+	iterations := mock.gitlabMock.groupIterations(group, includeAncestors)
+
+	writeJSON(responseWriter, http.StatusOK, filterIterations(iterations, request.URL.Query()))
+}
+
+// ListProjectIterationsHandler implements https://docs.gitlab.com/ee/api/iterations.html#list-project-iterations
+func (mock *GitlabApiMock) ListProjectIterationsHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, projectExists := mock.getProject(request)
+	if !projectExists {
+		writeErrorMessage(responseWriter, http.StatusNotFound, "404 Project Not Found")
+		return
+	}
+
+	iterations := []*gitlab.GroupIteration{}
+
+	if project.Namespace != nil {
+		group, err := mock.gitlabMock.getGroup(project.Namespace.ID)
+		if err == nil {
+			includeAncestors, err := strconv.ParseBool(request.URL.Query().Get("include_ancestors"))
+			if err != nil {
+				includeAncestors = true
+			}
+
+			iterations = mock.gitlabMock.groupIterations(group, includeAncestors)
+		}
+	}
+
+	writeJSON(responseWriter, http.StatusOK, filterIterations(iterations, request.URL.Query()))
+}
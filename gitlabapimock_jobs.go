@@ -0,0 +1,150 @@
+package gitlabapimock
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// filterJobs applies the scope parameter of https://docs.gitlab.com/ee/api/jobs.html#list-project-jobs, which
+// may be given once or as array.
+func filterJobs(jobs []*gitlab.Job, query url.Values) []*gitlab.Job {
+	scopes := slices.Concat(query["scope[]"], query["scope"])
+
+	filteredJobs := []*gitlab.Job{}
+
+	for _, job := range jobs {
+		if len(scopes) > 0 && !slices.Contains(scopes, job.Status) {
+			continue
+		}
+
+		filteredJobs = append(filteredJobs, job)
+	}
+
+	return filteredJobs
+}
+
+// getJobFromRequest resolves the project and job referenced by the id and job_id path variables.
+func (mock *GitlabApiMock) getJobFromRequest(request *http.Request) (*gitlab.Project, *gitlab.Job, error) {
+	project, err := mock.getProjectWithAccess(request, gitlab.ReporterPermissions)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	jobID, _ := strconv.Atoi(pathVar(request, "job_id"))
+
+	job, err := mock.gitlabMock.getJob(project, jobID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return project, job, nil
+}
+
+// ListProjectJobsHandler implements https://docs.gitlab.com/ee/api/jobs.html#list-project-jobs
+func (mock *GitlabApiMock) ListProjectJobsHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, err := mock.getProjectWithAccess(request, gitlab.ReporterPermissions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	includeRetried, _ := strconv.ParseBool(request.URL.Query().Get("include_retried"))
+
+	writeJSON(responseWriter, http.StatusOK, filterJobs(mock.gitlabMock.getProjectJobs(project, includeRetried), request.URL.Query()))
+}
+
+// ListPipelineJobsHandler implements https://docs.gitlab.com/ee/api/jobs.html#list-pipeline-jobs
+func (mock *GitlabApiMock) ListPipelineJobsHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	_, pipeline, err := mock.getPipelineFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	includeRetried, _ := strconv.ParseBool(request.URL.Query().Get("include_retried"))
+
+	writeJSON(responseWriter, http.StatusOK, filterJobs(mock.gitlabMock.pipelineJobs(pipeline, includeRetried), request.URL.Query()))
+}
+
+// GetJobHandler implements https://docs.gitlab.com/ee/api/jobs.html#get-a-single-job
+func (mock *GitlabApiMock) GetJobHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	_, job, err := mock.getJobFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, job)
+}
+
+// CancelJobHandler implements https://docs.gitlab.com/ee/api/jobs.html#cancel-a-job
+func (mock *GitlabApiMock) CancelJobHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, job, err := mock.getJobFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	job, err = mock.gitlabMock.cancelJob(project, job, currentUser(request))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusCreated, job)
+}
+
+// RetryJobHandler implements https://docs.gitlab.com/ee/api/jobs.html#retry-a-job
+func (mock *GitlabApiMock) RetryJobHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, job, err := mock.getJobFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	job, err = mock.gitlabMock.retryJob(project, job, currentUser(request))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusCreated, job)
+}
+
+// EraseJobHandler implements https://docs.gitlab.com/ee/api/jobs.html#erase-a-job
+func (mock *GitlabApiMock) EraseJobHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, job, err := mock.getJobFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	job, err = mock.gitlabMock.eraseJob(project, job, currentUser(request))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusCreated, job)
+}
+
+// PlayJobHandler implements https://docs.gitlab.com/ee/api/jobs.html#run-a-job
+func (mock *GitlabApiMock) PlayJobHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, job, err := mock.getJobFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	job, err = mock.gitlabMock.playJob(project, job, currentUser(request))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, job)
+}
@@ -0,0 +1,368 @@
+package gitlabapimock
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// getKeyUser resolves the user whose keys the request manages, the current user for the /user endpoints or the
+// user of the id path variable. Only admins manage the keys of other users, everyone may read them.
+func (mock *GitlabApiMock) getKeyUser(request *http.Request, manage bool) (*gitlab.User, error) {
+	if pathVar(request, "id") == "" {
+		user := currentUser(request)
+		if user == nil {
+			return nil, errUnauthorized
+		}
+
+		return user, nil
+	}
+
+	if manage {
+		if err := mock.checkAdmin(request); err != nil {
+			return nil, err
+		}
+	}
+
+	user, userExists := mock.getUserFromRequest(request)
+	if !userExists {
+		return nil, newAPIError(http.StatusNotFound, "404 User Not Found")
+	}
+
+	return user, nil
+}
+
+// ListSSHKeysHandler implements https://docs.gitlab.com/ee/api/user_keys.html#list-your-ssh-keys and
+// https://docs.gitlab.com/ee/api/user_keys.html#list-ssh-keys-for-a-user
+func (mock *GitlabApiMock) ListSSHKeysHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	user, err := mock.getKeyUser(request, false)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, mock.gitlabMock.userSSHKeys(user.ID))
+}
+
+// GetSSHKeyHandler implements https://docs.gitlab.com/ee/api/user_keys.html#get-a-single-ssh-key
+func (mock *GitlabApiMock) GetSSHKeyHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	user, err := mock.getKeyUser(request, false)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	keyID, _ := strconv.Atoi(pathVar(request, "key_id"))
+
+	key, err := mock.gitlabMock.getUserSSHKey(user.ID, keyID)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, key)
+}
+
+// AddSSHKeyHandler implements https://docs.gitlab.com/ee/api/user_keys.html#add-an-ssh-key-to-your-account and
+// https://docs.gitlab.com/ee/api/user_keys.html#add-an-ssh-key-to-a-users-account
+func (mock *GitlabApiMock) AddSSHKeyHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	user, err := mock.getKeyUser(request, true)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	var options gitlab.AddSSHKeyOptions
+
+	err = decodeBody(request, &options)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	key, err := mock.gitlabMock.addSSHKey(user, &options)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusCreated, key)
+}
+
+// DeleteSSHKeyHandler implements https://docs.gitlab.com/ee/api/user_keys.html#delete-an-ssh-key-from-your-account
+// and https://docs.gitlab.com/ee/api/user_keys.html#delete-an-ssh-key-from-a-users-account
+func (mock *GitlabApiMock) DeleteSSHKeyHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	user, err := mock.getKeyUser(request, true)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	keyID, _ := strconv.Atoi(pathVar(request, "key_id"))
+
+	key, err := mock.gitlabMock.getUserSSHKey(user.ID, keyID)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	mock.gitlabMock.removeSSHKey(key)
+
+	responseWriter.WriteHeader(http.StatusNoContent)
+}
+
+// GetKeyByFingerprintHandler implements https://docs.gitlab.com/ee/api/keys.html#get-user-by-fingerprint-of-ssh-key
+func (mock *GitlabApiMock) GetKeyByFingerprintHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	if err := mock.checkAdmin(request); err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	fingerprint := request.URL.Query().Get("fingerprint")
+	if fingerprint == "" {
+		writeErrorMessage(responseWriter, http.StatusBadRequest, "fingerprint is missing")
+		return
+	}
+
+	key := mock.gitlabMock.sshKeyByFingerprint(fingerprint)
+	if key == nil {
+		writeErrorMessage(responseWriter, http.StatusNotFound, "404 Key Not Found")
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, mock.gitlabMock.sshKeyWithUser(key))
+}
+
+// GetKeyWithUserHandler implements https://docs.gitlab.com/ee/api/keys.html#get-ssh-key-with-user-by-id-of-an-ssh-key
+func (mock *GitlabApiMock) GetKeyWithUserHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	if err := mock.checkAdmin(request); err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	keyID, _ := strconv.Atoi(pathVar(request, "key_id"))
+
+	key, err := mock.gitlabMock.getSSHKey(keyID)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, mock.gitlabMock.sshKeyWithUser(key))
+}
+
+// ListAllDeployKeysHandler implements https://docs.gitlab.com/ee/api/deploy_keys.html#list-all-deploy-keys
+func (mock *GitlabApiMock) ListAllDeployKeysHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	if err := mock.checkAdmin(request); err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, mock.gitlabMock.instanceDeployKeys())
+}
+
+// getProjectDeployKeyFromRequest resolves the project and deploy key of the id and key_id path variables.
+func (mock *GitlabApiMock) getProjectDeployKeyFromRequest(request *http.Request) (*gitlab.Project, *deployKeyProject, error) {
+	project, err := mock.getProjectWithAccess(request, gitlab.MaintainerPermissions)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keyID, _ := strconv.Atoi(pathVar(request, "key_id"))
+
+	deployKey, err := mock.gitlabMock.getProjectDeployKey(project, keyID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return project, deployKey, nil
+}
+
+// ListProjectDeployKeysHandler implements https://docs.gitlab.com/ee/api/deploy_keys.html#list-deploy-keys-for-project
+func (mock *GitlabApiMock) ListProjectDeployKeysHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, err := mock.getProjectWithAccess(request, gitlab.MaintainerPermissions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	deployKeys := []*projectDeployKey{}
+	for _, deployKey := range mock.gitlabMock.projectDeployKeys[project.ID] {
+		deployKeys = append(deployKeys, mock.gitlabMock.projectDeployKey(deployKey))
+	}
+
+	writeJSON(responseWriter, http.StatusOK, deployKeys)
+}
+
+// GetProjectDeployKeyHandler implements https://docs.gitlab.com/ee/api/deploy_keys.html#get-a-single-deploy-key
+func (mock *GitlabApiMock) GetProjectDeployKeyHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	_, deployKey, err := mock.getProjectDeployKeyFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, mock.gitlabMock.projectDeployKey(deployKey))
+}
+
+// AddProjectDeployKeyHandler implements https://docs.gitlab.com/ee/api/deploy_keys.html#add-deploy-key-for-a-project
+func (mock *GitlabApiMock) AddProjectDeployKeyHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, err := mock.getProjectWithAccess(request, gitlab.MaintainerPermissions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	var options gitlab.AddDeployKeyOptions
+
+	err = decodeBody(request, &options)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	deployKey, err := mock.gitlabMock.addDeployKey(project, &options, currentUser(request))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusCreated, deployKey)
+}
+
+// UpdateProjectDeployKeyHandler implements https://docs.gitlab.com/ee/api/deploy_keys.html#update-deploy-key
+func (mock *GitlabApiMock) UpdateProjectDeployKeyHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	_, deployKey, err := mock.getProjectDeployKeyFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	var options gitlab.UpdateDeployKeyOptions
+
+	err = decodeBody(request, &options)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	updatedKey, err := mock.gitlabMock.updateDeployKey(deployKey, &options)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, updatedKey)
+}
+
+// DeleteProjectDeployKeyHandler implements https://docs.gitlab.com/ee/api/deploy_keys.html#delete-deploy-key
+func (mock *GitlabApiMock) DeleteProjectDeployKeyHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, deployKey, err := mock.getProjectDeployKeyFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	mock.gitlabMock.removeDeployKey(project, deployKey)
+
+	responseWriter.WriteHeader(http.StatusNoContent)
+}
+
+// EnableProjectDeployKeyHandler implements https://docs.gitlab.com/ee/api/deploy_keys.html#enable-a-deploy-key
+func (mock *GitlabApiMock) EnableProjectDeployKeyHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, err := mock.getProjectWithAccess(request, gitlab.MaintainerPermissions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	keyID, _ := strconv.Atoi(pathVar(request, "key_id"))
+
+	deployKey, err := mock.gitlabMock.enableDeployKey(project, keyID, currentUser(request))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusCreated, deployKey)
+}
+
+// ListGPGKeysHandler implements https://docs.gitlab.com/ee/api/user_keys.html#list-your-gpg-keys and
+// https://docs.gitlab.com/ee/api/user_keys.html#list-all-gpg-keys-for-a-user
+func (mock *GitlabApiMock) ListGPGKeysHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	user, err := mock.getKeyUser(request, false)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, mock.gitlabMock.userGPGKeys(user.ID))
+}
+
+// GetGPGKeyHandler implements https://docs.gitlab.com/ee/api/user_keys.html#get-a-gpg-key
+func (mock *GitlabApiMock) GetGPGKeyHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	user, err := mock.getKeyUser(request, false)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	keyID, _ := strconv.Atoi(pathVar(request, "key_id"))
+
+	key, err := mock.gitlabMock.getUserGPGKey(user.ID, keyID)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, key)
+}
+
+// AddGPGKeyHandler implements https://docs.gitlab.com/ee/api/user_keys.html#add-a-gpg-key-to-your-account and
+// https://docs.gitlab.com/ee/api/user_keys.html#add-a-gpg-key-to-a-users-account
+func (mock *GitlabApiMock) AddGPGKeyHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	user, err := mock.getKeyUser(request, true)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	var options gitlab.AddGPGKeyOptions
+
+	err = decodeBody(request, &options)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	key, err := mock.gitlabMock.addGPGKey(user, &options)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusCreated, key)
+}
+
+// DeleteGPGKeyHandler implements https://docs.gitlab.com/ee/api/user_keys.html#delete-a-gpg-key-from-your-account
+// and https://docs.gitlab.com/ee/api/user_keys.html#delete-a-gpg-key-from-a-users-account
+func (mock *GitlabApiMock) DeleteGPGKeyHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	user, err := mock.getKeyUser(request, true)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	keyID, _ := strconv.Atoi(pathVar(request, "key_id"))
+
+	key, err := mock.gitlabMock.getUserGPGKey(user.ID, keyID)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	mock.gitlabMock.removeGPGKey(key)
+
+	responseWriter.WriteHeader(http.StatusNoContent)
+}
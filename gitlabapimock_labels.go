@@ -0,0 +1,375 @@
+package gitlabapimock
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// filterLabels applies the search parameter of the label list endpoints and sorts the labels by name.
+func filterLabels(labels []*gitlab.Label, query url.Values) []*gitlab.Label {
+	search := strings.ToLower(query.Get("search"))
+
+	filteredLabels := []*gitlab.Label{}
+	for _, label := range labels {
+		if search == "" || strings.Contains(strings.ToLower(label.Name), search) || strings.Contains(strings.ToLower(label.Description), search) {
+			filteredLabels = append(filteredLabels, label)
+		}
+	}
+
+	sort.SliceStable(filteredLabels, func(i, j int) bool {
+		return strings.ToLower(filteredLabels[i].Name) < strings.ToLower(filteredLabels[j].Name)
+	})
+
+	return filteredLabels
+}
+
+// writeLabels writes the labels, including the counts of the projects if requested by with_counts.
+func (mock *GitlabApiMock) writeLabels(responseWriter http.ResponseWriter, request *http.Request, labels []*gitlab.Label, projects []*gitlab.Project) {
+	withCounts, _ := strconv.ParseBool(request.URL.Query().Get("with_counts"))
+	if !withCounts {
+		projects = nil
+	}
+
+	result := []*gitlab.Label{}
+	for _, label := range filterLabels(labels, request.URL.Query()) {
+		result = append(result, mock.gitlabMock.labelWithCounts(label, projects, currentUser(request)))
+	}
+
+	writeJSON(responseWriter, http.StatusOK, result)
+}
+
+// labelIDOrName returns the label referenced by the label_id path variable or, as fallback, by the
+// name parameter.
+func labelIDOrName(request *http.Request, name *string) string {
+	if labelID := pathVar(request, "label_id"); labelID != "" {
+		return labelID
+	}
+
+	if name != nil {
+		return *name
+	}
+
+	return request.URL.Query().Get("name")
+}
+
+// ListLabelsHandler implements https://docs.gitlab.com/ee/api/labels.html#list-labels
+func (mock *GitlabApiMock) ListLabelsHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, projectExists := mock.getProject(request)
+	if !projectExists {
+		writeErrorMessage(responseWriter, http.StatusNotFound, "404 Project Not Found")
+		return
+	}
+
+	labels := mock.gitlabMock.projectLabels[project.ID]
+	if includeAncestorGroups, err := strconv.ParseBool(request.URL.Query().Get("include_ancestor_groups")); err != nil || includeAncestorGroups {
+		labels = mock.gitlabMock.availableLabels(project)
+	}
+
+	mock.writeLabels(responseWriter, request, labels, []*gitlab.Project{project})
+}
+
+// GetLabelHandler implements https://docs.gitlab.com/ee/api/labels.html#get-a-single-project-label
+func (mock *GitlabApiMock) GetLabelHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, projectExists := mock.getProject(request)
+	if !projectExists {
+		writeErrorMessage(responseWriter, http.StatusNotFound, "404 Project Not Found")
+		return
+	}
+
+	label, err := mock.gitlabMock.getProjectLabel(project, pathVar(request, "label_id"))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, mock.gitlabMock.labelWithCounts(label, []*gitlab.Project{project}, currentUser(request)))
+}
+
+// CreateLabelHandler implements https://docs.gitlab.com/ee/api/labels.html#create-a-new-label
+func (mock *GitlabApiMock) CreateLabelHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, err := mock.getProjectWithAccess(request, gitlab.ReporterPermissions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	var createLabelOptions gitlab.CreateLabelOptions
+	err = decodeBody(request, &createLabelOptions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	label, err := mock.gitlabMock.AddProjectLabel(project, &createLabelOptions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusCreated, label)
+}
+
+// UpdateLabelHandler implements https://docs.gitlab.com/ee/api/labels.html#edit-an-existing-label
+func (mock *GitlabApiMock) UpdateLabelHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, err := mock.getProjectWithAccess(request, gitlab.ReporterPermissions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	var updateLabelOptions gitlab.UpdateLabelOptions
+	err = decodeBody(request, &updateLabelOptions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	// Labels inherited from groups can only be edited in the group.
+	label := findLabel(mock.gitlabMock.projectLabels[project.ID], labelIDOrName(request, updateLabelOptions.Name))
+	if label == nil {
+		writeErrorMessage(responseWriter, http.StatusNotFound, "404 Label Not Found")
+		return
+	}
+
+	label, err = mock.gitlabMock.updateLabel(project, nil, label, &updateLabelOptions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, label)
+}
+
+// DeleteLabelHandler implements https://docs.gitlab.com/ee/api/labels.html#delete-a-label
+func (mock *GitlabApiMock) DeleteLabelHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, err := mock.getProjectWithAccess(request, gitlab.ReporterPermissions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	label, err := mock.gitlabMock.getProjectLabel(project, labelIDOrName(request, nil))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	err = mock.gitlabMock.deleteLabel(project, nil, label)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	responseWriter.WriteHeader(http.StatusNoContent)
+}
+
+// writeLabelSubscription subscribes the user to the label or unsubscribes the user. Like GitLab, it
+// responds with 304 Not Modified if the subscription doesn't change.
+func (mock *GitlabApiMock) writeLabelSubscription(responseWriter http.ResponseWriter, request *http.Request, label *gitlab.Label, projects []*gitlab.Project, subscribed bool) {
+	user := currentUser(request)
+	if user == nil {
+		writeErrorMessage(responseWriter, http.StatusUnauthorized, "401 Unauthorized")
+		return
+	}
+
+	if !mock.gitlabMock.subscribeToLabel(label, user, subscribed) {
+		responseWriter.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusCreated, mock.gitlabMock.labelWithCounts(label, projects, user))
+}
+
+// SubscribeToLabelHandler implements https://docs.gitlab.com/ee/api/labels.html#subscribe-to-a-label
+func (mock *GitlabApiMock) SubscribeToLabelHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	mock.projectLabelSubscription(responseWriter, request, true)
+}
+
+// UnsubscribeFromLabelHandler implements https://docs.gitlab.com/ee/api/labels.html#unsubscribe-from-a-label
+func (mock *GitlabApiMock) UnsubscribeFromLabelHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	mock.projectLabelSubscription(responseWriter, request, false)
+}
+
+func (mock *GitlabApiMock) projectLabelSubscription(responseWriter http.ResponseWriter, request *http.Request, subscribed bool) {
+	project, projectExists := mock.getProject(request)
+	if !projectExists {
+		writeErrorMessage(responseWriter, http.StatusNotFound, "404 Project Not Found")
+		return
+	}
+
+	label, err := mock.gitlabMock.getProjectLabel(project, pathVar(request, "label_id"))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	mock.writeLabelSubscription(responseWriter, request, label, []*gitlab.Project{project}, subscribed)
+}
+
+// ListGroupLabelsHandler implements https://docs.gitlab.com/ee/api/group_labels.html#list-group-labels
+func (mock *GitlabApiMock) ListGroupLabelsHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	group, groupExists := mock.getGroup(request)
+	if !groupExists {
+		writeErrorMessage(responseWriter, http.StatusNotFound, "404 Group Not Found")
+		return
+	}
+
+	query := request.URL.Query()
+
+	labels := mock.gitlabMock.groupLabels[group.ID]
+	if includeAncestorGroups, err := strconv.ParseBool(query.Get("include_ancestor_groups")); err != nil || includeAncestorGroups {
+		labels = mock.gitlabMock.availableGroupLabels(group)
+	}
+
+	if includeDescendantGroups, _ := strconv.ParseBool(query.Get("include_descendant_groups")); includeDescendantGroups {
+		for _, descendant := range mock.gitlabMock.groups {
+			if descendant != group && slices.Contains(mock.gitlabMock.groupAncestors(descendant), group) {
+				labels = append(slices.Clone(labels), mock.gitlabMock.groupLabels[descendant.ID]...)
+			}
+		}
+	}
+
+	mock.writeLabels(responseWriter, request, labels, mock.gitlabMock.groupProjects(group))
+}
+
+// GetGroupLabelHandler implements https://docs.gitlab.com/ee/api/group_labels.html#get-a-single-group-label
+func (mock *GitlabApiMock) GetGroupLabelHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	group, groupExists := mock.getGroup(request)
+	if !groupExists {
+		writeErrorMessage(responseWriter, http.StatusNotFound, "404 Group Not Found")
+		return
+	}
+
+	label, err := mock.gitlabMock.getGroupLabel(group, pathVar(request, "label_id"))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, mock.gitlabMock.labelWithCounts(label, mock.gitlabMock.groupProjects(group), currentUser(request)))
+}
+
+// CreateGroupLabelHandler implements https://docs.gitlab.com/ee/api/group_labels.html#create-a-new-group-label
+func (mock *GitlabApiMock) CreateGroupLabelHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	group, groupExists := mock.getGroup(request)
+	if !groupExists {
+		writeErrorMessage(responseWriter, http.StatusNotFound, "404 Group Not Found")
+		return
+	}
+
+	if !mock.gitlabMock.hasGroupAccess(group, currentUser(request), gitlab.ReporterPermissions) {
+		writeErrorMessage(responseWriter, http.StatusForbidden, "403 Forbidden")
+		return
+	}
+
+	var createLabelOptions gitlab.CreateLabelOptions
+	err := decodeBody(request, &createLabelOptions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	label, err := mock.gitlabMock.AddGroupLabel(group, &createLabelOptions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusCreated, label)
+}
+
+// UpdateGroupLabelHandler implements https://docs.gitlab.com/ee/api/group_labels.html#update-a-group-label
+func (mock *GitlabApiMock) UpdateGroupLabelHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	group, groupExists := mock.getGroup(request)
+	if !groupExists {
+		writeErrorMessage(responseWriter, http.StatusNotFound, "404 Group Not Found")
+		return
+	}
+
+	if !mock.gitlabMock.hasGroupAccess(group, currentUser(request), gitlab.ReporterPermissions) {
+		writeErrorMessage(responseWriter, http.StatusForbidden, "403 Forbidden")
+		return
+	}
+
+	var updateLabelOptions gitlab.UpdateLabelOptions
+	err := decodeBody(request, &updateLabelOptions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	// Labels inherited from parent groups can only be edited in the parent group.
+	label := findLabel(mock.gitlabMock.groupLabels[group.ID], labelIDOrName(request, updateLabelOptions.Name))
+	if label == nil {
+		writeErrorMessage(responseWriter, http.StatusNotFound, "404 Label Not Found")
+		return
+	}
+
+	label, err = mock.gitlabMock.updateLabel(nil, group, label, &updateLabelOptions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, label)
+}
+
+// DeleteGroupLabelHandler implements https://docs.gitlab.com/ee/api/group_labels.html#delete-a-group-label
+func (mock *GitlabApiMock) DeleteGroupLabelHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	group, groupExists := mock.getGroup(request)
+	if !groupExists {
+		writeErrorMessage(responseWriter, http.StatusNotFound, "404 Group Not Found")
+		return
+	}
+
+	if !mock.gitlabMock.hasGroupAccess(group, currentUser(request), gitlab.ReporterPermissions) {
+		writeErrorMessage(responseWriter, http.StatusForbidden, "403 Forbidden")
+		return
+	}
+
+	label, err := mock.gitlabMock.getGroupLabel(group, labelIDOrName(request, nil))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	err = mock.gitlabMock.deleteLabel(nil, group, label)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	responseWriter.WriteHeader(http.StatusNoContent)
+}
+
+// SubscribeToGroupLabelHandler implements https://docs.gitlab.com/ee/api/group_labels.html#subscribe-to-a-group-label
+func (mock *GitlabApiMock) SubscribeToGroupLabelHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	mock.groupLabelSubscription(responseWriter, request, true)
+}
+
+// UnsubscribeFromGroupLabelHandler implements https://docs.gitlab.com/ee/api/group_labels.html#unsubscribe-from-a-group-label
+func (mock *GitlabApiMock) UnsubscribeFromGroupLabelHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	mock.groupLabelSubscription(responseWriter, request, false)
+}
+
+func (mock *GitlabApiMock) groupLabelSubscription(responseWriter http.ResponseWriter, request *http.Request, subscribed bool) {
+	group, groupExists := mock.getGroup(request)
+	if !groupExists {
+		writeErrorMessage(responseWriter, http.StatusNotFound, "404 Group Not Found")
+		return
+	}
+
+	label, err := mock.gitlabMock.getGroupLabel(group, pathVar(request, "label_id"))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	mock.writeLabelSubscription(responseWriter, request, label, mock.gitlabMock.groupProjects(group), subscribed)
+}
@@ -0,0 +1,515 @@
+package gitlabapimock
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// matchesLabelsFilter implements the labels parameter of list endpoints: a comma separated list of
+// labels that all have to be present, "None" or "Any".
+func matchesLabelsFilter(labels gitlab.Labels, filter string) bool {
+	switch filter {
+	case "":
+		return true
+	case "None":
+		return len(labels) == 0
+	case "Any":
+		return len(labels) > 0
+	}
+
+	for _, label := range strings.Split(filter, ",") {
+		if !slices.Contains(labels, strings.TrimSpace(label)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchesUserFilter implements user ID parameters like assignee_id: a user ID, "None" or "Any".
+func matchesUserFilter(users []*gitlab.BasicUser, filter string) bool {
+	switch strings.ToLower(filter) {
+	case "":
+		return true
+	case "none":
+		return len(users) == 0
+	case "any":
+		return len(users) > 0
+	}
+
+	userID, _ := strconv.Atoi(filter)
+
+	return slices.ContainsFunc(users, func(user *gitlab.BasicUser) bool {
+		return user.ID == userID
+	})
+}
+
+// matchesUsernameFilter implements username parameters like author_username.
+func matchesUsernameFilter(users []*gitlab.BasicUser, filter string) bool {
+	if filter == "" {
+		return true
+	}
+
+	return slices.ContainsFunc(users, func(user *gitlab.BasicUser) bool {
+		return user.Username == filter
+	})
+}
+
+// matchesTimeFilter implements the *_after and *_before parameters of list endpoints.
+func matchesTimeFilter(value *time.Time, query url.Values, afterParameter string, beforeParameter string) bool {
+	if after, err := time.Parse(time.RFC3339, query.Get(afterParameter)); err == nil {
+		if value == nil || value.Before(after) {
+			return false
+		}
+	}
+
+	if before, err := time.Parse(time.RFC3339, query.Get(beforeParameter)); err == nil {
+		if value == nil || value.After(before) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchesIIDsFilter implements the iids[] parameter of list endpoints.
+func matchesIIDsFilter(iid int, query url.Values) bool {
+	iids := query["iids[]"]
+	if len(iids) == 0 {
+		return true
+	}
+
+	return slices.Contains(iids, strconv.Itoa(iid))
+}
+
+func userList(user *gitlab.BasicUser) []*gitlab.BasicUser {
+	if user == nil {
+		return nil
+	}
+
+	return []*gitlab.BasicUser{user}
+}
+
+// filterMergeRequests applies the filters of https://docs.gitlab.com/ee/api/merge_requests.html#list-project-merge-requests
+func filterMergeRequests(mergeRequests []*gitlab.MergeRequest, query url.Values) []*gitlab.MergeRequest {
+	filteredMergeRequests := []*gitlab.MergeRequest{}
+
+	for _, mergeRequest := range mergeRequests {
+		if state := query.Get("state"); state != "" && state != "all" && state != mergeRequest.State {
+			continue
+		}
+
+		if !matchesIIDsFilter(mergeRequest.IID, query) {
+			continue
+		}
+
+		if !matchesLabelsFilter(mergeRequest.Labels, query.Get("labels")) {
+			continue
+		}
+
+		if notLabels := query.Get("not[labels]"); notLabels != "" && matchesLabelsFilter(mergeRequest.Labels, notLabels) {
+			continue
+		}
+
+		if !matchesUserFilter(userList(mergeRequest.Author), query.Get("author_id")) {
+			continue
+		}
+
+		if !matchesUsernameFilter(userList(mergeRequest.Author), query.Get("author_username")) {
+			continue
+		}
+
+		if !matchesUserFilter(mergeRequest.Assignees, query.Get("assignee_id")) {
+			continue
+		}
+
+		if !matchesUserFilter(mergeRequest.Reviewers, query.Get("reviewer_id")) {
+			continue
+		}
+
+		if !matchesUsernameFilter(mergeRequest.Reviewers, query.Get("reviewer_username")) {
+			continue
+		}
+
+		if sourceBranch := query.Get("source_branch"); sourceBranch != "" && sourceBranch != mergeRequest.SourceBranch {
+			continue
+		}
+
+		if targetBranch := query.Get("target_branch"); targetBranch != "" && targetBranch != mergeRequest.TargetBranch {
+			continue
+		}
+
+		if search := query.Get("search"); search != "" && !strings.Contains(mergeRequest.Title, search) && !strings.Contains(mergeRequest.Description, search) {
+			continue
+		}
+
+		if draft, err := strconv.ParseBool(query.Get("draft")); err == nil && draft != mergeRequest.Draft {
+			continue
+		}
+
+		if !matchesTimeFilter(mergeRequest.CreatedAt, query, "created_after", "created_before") {
+			continue
+		}
+
+		if !matchesTimeFilter(mergeRequest.UpdatedAt, query, "updated_after", "updated_before") {
+			continue
+		}
+
+		filteredMergeRequests = append(filteredMergeRequests, mergeRequest)
+	}
+
+	orderBy := query.Get("order_by")
+	ascending := query.Get("sort") == "asc"
+
+	sort.SliceStable(filteredMergeRequests, func(i, j int) bool {
+		left, right := filteredMergeRequests[i], filteredMergeRequests[j]
+
+		var comparison int
+
+		switch orderBy {
+		case "title":
+			comparison = strings.Compare(left.Title, right.Title)
+		case "updated_at":
+			comparison = left.UpdatedAt.Compare(*right.UpdatedAt)
+		default:
+			comparison = left.CreatedAt.Compare(*right.CreatedAt)
+			if comparison == 0 {
+				comparison = left.ID - right.ID
+			}
+		}
+
+		if ascending {
+			return comparison < 0
+		}
+
+		return comparison > 0
+	})
+
+	return filteredMergeRequests
+}
+
+// getMergeRequestFromRequest resolves the project and merge request referenced by the id and
+// merge_request_iid path variables.
+func (mock *GitlabApiMock) getMergeRequestFromRequest(request *http.Request) (*gitlab.Project, *gitlab.MergeRequest, error) {
+	project, projectExists := mock.getProject(request)
+	if !projectExists {
+		return nil, nil, newAPIError(http.StatusNotFound, "404 Project Not Found")
+	}
+
+	iid, _ := strconv.Atoi(pathVar(request, "merge_request_iid"))
+
+	mergeRequest, err := mock.gitlabMock.getMergeRequest(project, iid)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return project, mergeRequest, nil
+}
+
+// ListProjectMergeRequestsHandler implements https://docs.gitlab.com/ee/api/merge_requests.html#list-project-merge-requests
+func (mock *GitlabApiMock) ListProjectMergeRequestsHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, err := mock.getProjectWithAccess(request, gitlab.ReporterPermissions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	mergeRequests, err := mock.gitlabMock.getMergeRequests(project)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, filterMergeRequests(mergeRequests, request.URL.Query()))
+}
+
+// GetSingleMergeRequestHandler implements https://docs.gitlab.com/ee/api/merge_requests.html#get-single-mr
+func (mock *GitlabApiMock) GetSingleMergeRequestHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	_, mergeRequest, err := mock.getMergeRequestFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, mergeRequest)
+}
+
+// CreateMergeRequestHandler implements https://docs.gitlab.com/ee/api/merge_requests.html#create-mr
+func (mock *GitlabApiMock) CreateMergeRequestHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, projectExists := mock.getProject(request)
+	if !projectExists {
+		writeErrorMessage(responseWriter, http.StatusNotFound, "404 Project Not Found")
+		return
+	}
+
+	var createMergeRequestOptions gitlab.CreateMergeRequestOptions
+	err := decodeBody(request, &createMergeRequestOptions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	mergeRequest, err := mock.gitlabMock.AddMergeRequest(project, &createMergeRequestOptions, currentUser(request))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusCreated, mergeRequest)
+}
+
+// UpdateMergeRequestHandler implements https://docs.gitlab.com/ee/api/merge_requests.html#update-mr
+func (mock *GitlabApiMock) UpdateMergeRequestHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, mergeRequest, err := mock.getMergeRequestFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	var updateMergeRequestOptions gitlab.UpdateMergeRequestOptions
+	err = decodeBody(request, &updateMergeRequestOptions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	mergeRequest, err = mock.gitlabMock.updateMergeRequest(project, mergeRequest.IID, &updateMergeRequestOptions, currentUser(request))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, mergeRequest)
+}
+
+// DeleteMergeRequestHandler implements https://docs.gitlab.com/ee/api/merge_requests.html#delete-a-merge-request
+func (mock *GitlabApiMock) DeleteMergeRequestHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, mergeRequest, err := mock.getMergeRequestFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	err = mock.gitlabMock.deleteMergeRequest(project, mergeRequest.IID, currentUser(request))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	responseWriter.WriteHeader(http.StatusNoContent)
+}
+
+// MergeMergeRequestHandler implements https://docs.gitlab.com/ee/api/merge_requests.html#merge-a-merge-request
+func (mock *GitlabApiMock) MergeMergeRequestHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, mergeRequest, err := mock.getMergeRequestFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	var acceptMergeRequestOptions gitlab.AcceptMergeRequestOptions
+	err = decodeBody(request, &acceptMergeRequestOptions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	mergeRequest, err = mock.gitlabMock.acceptMergeRequest(project, mergeRequest.IID, &acceptMergeRequestOptions, currentUser(request))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, mergeRequest)
+}
+
+// RebaseMergeRequestHandler implements https://docs.gitlab.com/ee/api/merge_requests.html#rebase-a-merge-request
+// The rebase is done synchronously, so rebase_in_progress is false once the response arrives.
+func (mock *GitlabApiMock) RebaseMergeRequestHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, mergeRequest, err := mock.getMergeRequestFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	_, err = mock.gitlabMock.rebaseMergeRequest(project, mergeRequest.IID, currentUser(request))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusAccepted, map[string]bool{"rebase_in_progress": false})
+}
+
+// GetMergeRequestChangesHandler implements https://docs.gitlab.com/ee/api/merge_requests.html#get-single-merge-request-changes
+func (mock *GitlabApiMock) GetMergeRequestChangesHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, mergeRequest, err := mock.getMergeRequestFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	changes, err := mock.gitlabMock.mergeRequestChanges(project, mergeRequest)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	mergeRequestWithChanges := *mergeRequest
+	mergeRequestWithChanges.Changes = changes
+
+	writeJSON(responseWriter, http.StatusOK, &mergeRequestWithChanges)
+}
+
+// ListMergeRequestDiffsHandler implements https://docs.gitlab.com/ee/api/merge_requests.html#list-merge-request-diffs
+func (mock *GitlabApiMock) ListMergeRequestDiffsHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, mergeRequest, err := mock.getMergeRequestFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	diffs, err := mock.gitlabMock.mergeRequestChanges(project, mergeRequest)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, diffs)
+}
+
+// GetMergeRequestCommitsHandler implements https://docs.gitlab.com/ee/api/merge_requests.html#get-single-merge-request-commits
+func (mock *GitlabApiMock) GetMergeRequestCommitsHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, mergeRequest, err := mock.getMergeRequestFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	commits, err := mock.gitlabMock.mergeRequestCommits(project, mergeRequest)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, commits)
+}
+
+// mergeRequestApprovals is the response of the approvals endpoint. The go-gitlab type cannot be used
+// for encoding, because its approver type lacks the json tag of the user field.
+type mergeRequestApprovals struct {
+	ID                int                                `json:"id"`
+	IID               int                                `json:"iid"`
+	ProjectID         int                                `json:"project_id"`
+	Title             string                             `json:"title"`
+	Description       string                             `json:"description"`
+	State             string                             `json:"state"`
+	CreatedAt         *time.Time                         `json:"created_at"`
+	UpdatedAt         *time.Time                         `json:"updated_at"`
+	MergeStatus       string                             `json:"merge_status"`
+	Approved          bool                               `json:"approved"`
+	ApprovalsRequired int                                `json:"approvals_required"`
+	ApprovalsLeft     int                                `json:"approvals_left"`
+	ApprovedBy        []*mergeRequestApprover            `json:"approved_by"`
+	UserHasApproved   bool                               `json:"user_has_approved"`
+	UserCanApprove    bool                               `json:"user_can_approve"`
+	ApprovalRulesLeft []*gitlab.MergeRequestApprovalRule `json:"approval_rules_left"`
+	HasApprovalRules  bool                               `json:"has_approval_rules"`
+}
+
+type mergeRequestApprover struct {
+	User *gitlab.BasicUser `json:"user"`
+}
+
+func (mock *GitlabApiMock) writeMergeRequestApprovals(responseWriter http.ResponseWriter, request *http.Request, statusCode int, project *gitlab.Project, mergeRequest *gitlab.MergeRequest) {
+	user := currentUser(request)
+
+	approvals := &mergeRequestApprovals{
+		ID:                mergeRequest.ID,
+		IID:               mergeRequest.IID,
+		ProjectID:         mergeRequest.ProjectID,
+		Title:             mergeRequest.Title,
+		Description:       mergeRequest.Description,
+		State:             mergeRequest.State,
+		CreatedAt:         mergeRequest.CreatedAt,
+		UpdatedAt:         mergeRequest.UpdatedAt,
+		MergeStatus:       mergeRequest.MergeStatus,
+		Approved:          mock.gitlabMock.mergeRequestApproved(project, mergeRequest),
+		ApprovalsRequired: mock.gitlabMock.approvalsRequired(project, mergeRequest),
+		ApprovalsLeft:     mock.gitlabMock.approvalsLeft(project, mergeRequest),
+		ApprovedBy:        []*mergeRequestApprover{},
+		ApprovalRulesLeft: mock.gitlabMock.approvalRulesLeft(project, mergeRequest),
+		HasApprovalRules:  len(mock.gitlabMock.mergeRequestApprovalRules[mergeRequest.ID]) > 0,
+		UserCanApprove:    user != nil && mock.gitlabMock.projectAccessLevel(project, user) >= gitlab.DeveloperPermissions,
+	}
+
+	for _, approver := range mock.gitlabMock.mergeRequestApprovals[mergeRequest.ID] {
+		approvals.ApprovedBy = append(approvals.ApprovedBy, &mergeRequestApprover{User: approver})
+
+		if user != nil && approver.ID == user.ID {
+			approvals.UserHasApproved = true
+			approvals.UserCanApprove = false
+		}
+	}
+
+	writeJSON(responseWriter, statusCode, approvals)
+}
+
+// GetMergeRequestApprovalsHandler implements https://docs.gitlab.com/ee/api/merge_request_approvals.html#merge-request-level-mr-approvals
+func (mock *GitlabApiMock) GetMergeRequestApprovalsHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, mergeRequest, err := mock.getMergeRequestFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	mock.writeMergeRequestApprovals(responseWriter, request, http.StatusOK, project, mergeRequest)
+}
+
+// ApproveMergeRequestHandler implements https://docs.gitlab.com/ee/api/merge_request_approvals.html#approve-merge-request
+func (mock *GitlabApiMock) ApproveMergeRequestHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, mergeRequest, err := mock.getMergeRequestFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	var approveMergeRequestOptions gitlab.ApproveMergeRequestOptions
+	err = decodeBody(request, &approveMergeRequestOptions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	mergeRequest, err = mock.gitlabMock.approveMergeRequest(project, mergeRequest.IID, approveMergeRequestOptions.SHA, currentUser(request))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	mock.writeMergeRequestApprovals(responseWriter, request, http.StatusCreated, project, mergeRequest)
+}
+
+// UnapproveMergeRequestHandler implements https://docs.gitlab.com/ee/api/merge_request_approvals.html#unapprove-merge-request
+func (mock *GitlabApiMock) UnapproveMergeRequestHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, mergeRequest, err := mock.getMergeRequestFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	_, err = mock.gitlabMock.unapproveMergeRequest(project, mergeRequest.IID, currentUser(request))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	responseWriter.WriteHeader(http.StatusCreated)
+}
@@ -0,0 +1,421 @@
+package gitlabapimock
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// filterMilestones applies the filters of https://docs.gitlab.com/ee/api/milestones.html#list-project-milestones
+func filterMilestones(milestones []*gitlab.Milestone, query url.Values, now time.Time) []*gitlab.Milestone {
+	search := strings.ToLower(query.Get("search"))
+
+	filteredMilestones := []*gitlab.Milestone{}
+
+	for _, milestone := range milestones {
+		refreshMilestone(milestone, now)
+
+		if state := query.Get("state"); state != "" && state != milestone.State {
+			continue
+		}
+
+		if title := query.Get("title"); title != "" && title != milestone.Title {
+			continue
+		}
+
+		if search != "" && !strings.Contains(strings.ToLower(milestone.Title), search) && !strings.Contains(strings.ToLower(milestone.Description), search) {
+			continue
+		}
+
+		if !matchesIIDsFilter(milestone.IID, query) {
+			continue
+		}
+
+		filteredMilestones = append(filteredMilestones, milestone)
+	}
+
+	return filteredMilestones
+}
+
+// getMilestoneFromRequest resolves the project and milestone referenced by the id and milestone_id path variables.
+func (mock *GitlabApiMock) getMilestoneFromRequest(request *http.Request) (*gitlab.Project, *gitlab.Milestone, error) {
+	project, projectExists := mock.getProject(request)
+	if !projectExists {
+		return nil, nil, newAPIError(http.StatusNotFound, "404 Project Not Found")
+	}
+
+	milestoneID, _ := strconv.Atoi(pathVar(request, "milestone_id"))
+
+	milestone, err := mock.gitlabMock.getMilestone(mock.gitlabMock.projectMilestones[project.ID], milestoneID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return project, milestone, nil
+}
+
+// getGroupMilestoneFromRequest resolves the group and milestone referenced by the id and milestone_id path variables.
+func (mock *GitlabApiMock) getGroupMilestoneFromRequest(request *http.Request) (*gitlab.Group, *gitlab.Milestone, error) {
+	group, groupExists := mock.getGroup(request)
+	if !groupExists {
+		return nil, nil, newAPIError(http.StatusNotFound, "404 Group Not Found")
+	}
+
+	milestoneID, _ := strconv.Atoi(pathVar(request, "milestone_id"))
+
+	milestone, err := mock.gitlabMock.getMilestone(mock.gitlabMock.groupMilestones[group.ID], milestoneID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return group, milestone, nil
+}
+
+// visibleIssues returns the issues the user of the request may see.
+func (mock *GitlabApiMock) visibleIssues(request *http.Request, issues []*gitlab.Issue) []*gitlab.Issue {
+	visibleIssues := []*gitlab.Issue{}
+
+	for _, issue := range issues {
+		if mock.gitlabMock.canReadIssue(mock.gitlabMock.projects[issue.ProjectID], issue, currentUser(request)) {
+			visibleIssues = append(visibleIssues, issue)
+		}
+	}
+
+	return visibleIssues
+}
+
+// ListMilestonesHandler implements https://docs.gitlab.com/ee/api/milestones.html#list-project-milestones
+func (mock *GitlabApiMock) ListMilestonesHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, projectExists := mock.getProject(request)
+	if !projectExists {
+		writeErrorMessage(responseWriter, http.StatusNotFound, "404 Project Not Found")
+		return
+	}
+
+	milestones := mock.gitlabMock.projectMilestones[project.ID]
+	if includeParentMilestones, _ := strconv.ParseBool(request.URL.Query().Get("include_parent_milestones")); includeParentMilestones {
+		milestones = mock.gitlabMock.availableMilestones(project)
+	}
+
+	writeJSON(responseWriter, http.StatusOK, filterMilestones(milestones, request.URL.Query(), mock.gitlabMock.now()))
+}
+
+// GetMilestoneHandler implements https://docs.gitlab.com/ee/api/milestones.html#get-single-milestone
+func (mock *GitlabApiMock) GetMilestoneHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	_, milestone, err := mock.getMilestoneFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, milestone)
+}
+
+// CreateMilestoneHandler implements https://docs.gitlab.com/ee/api/milestones.html#create-new-milestone
+func (mock *GitlabApiMock) CreateMilestoneHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, err := mock.getProjectWithAccess(request, gitlab.ReporterPermissions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	var createMilestoneOptions gitlab.CreateMilestoneOptions
+	err = decodeBody(request, &createMilestoneOptions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	milestone, err := mock.gitlabMock.AddMilestone(project, &createMilestoneOptions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusCreated, milestone)
+}
+
+// UpdateMilestoneHandler implements https://docs.gitlab.com/ee/api/milestones.html#edit-milestone
+func (mock *GitlabApiMock) UpdateMilestoneHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, milestone, err := mock.getMilestoneFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	if !mock.gitlabMock.hasAccess(project, currentUser(request), gitlab.ReporterPermissions) {
+		writeErrorMessage(responseWriter, http.StatusForbidden, "403 Forbidden")
+		return
+	}
+
+	var updateMilestoneOptions gitlab.UpdateMilestoneOptions
+	err = decodeBody(request, &updateMilestoneOptions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	milestone, err = mock.gitlabMock.updateMilestone(milestone, mock.gitlabMock.availableMilestones(project), &updateMilestoneOptions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, milestone)
+}
+
+// DeleteMilestoneHandler implements https://docs.gitlab.com/ee/api/milestones.html#delete-project-milestone
+func (mock *GitlabApiMock) DeleteMilestoneHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, milestone, err := mock.getMilestoneFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	if !mock.gitlabMock.hasAccess(project, currentUser(request), gitlab.ReporterPermissions) {
+		writeErrorMessage(responseWriter, http.StatusForbidden, "403 Forbidden")
+		return
+	}
+
+	err = mock.gitlabMock.deleteMilestone(project, nil, milestone)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	responseWriter.WriteHeader(http.StatusNoContent)
+}
+
+// GetMilestoneIssuesHandler implements https://docs.gitlab.com/ee/api/milestones.html#get-all-issues-assigned-to-a-single-milestone
+func (mock *GitlabApiMock) GetMilestoneIssuesHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, milestone, err := mock.getMilestoneFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	issues := mock.gitlabMock.milestoneIssues([]*gitlab.Project{project}, milestone)
+
+	writeJSON(responseWriter, http.StatusOK, mock.visibleIssues(request, issues))
+}
+
+// GetMilestoneMergeRequestsHandler implements https://docs.gitlab.com/ee/api/milestones.html#get-all-merge-requests-assigned-to-a-single-milestone
+func (mock *GitlabApiMock) GetMilestoneMergeRequestsHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, milestone, err := mock.getMilestoneFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	mergeRequests, err := mock.gitlabMock.milestoneMergeRequests([]*gitlab.Project{project}, milestone)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, mergeRequests)
+}
+
+// GetMilestoneBurndownEventsHandler implements https://docs.gitlab.com/ee/api/milestones.html#get-all-burndown-chart-events-for-a-single-milestone
+func (mock *GitlabApiMock) GetMilestoneBurndownEventsHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	_, milestone, err := mock.getMilestoneFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, append([]*gitlab.BurndownChartEvent{}, mock.gitlabMock.burndownEvents[milestone.ID]...))
+}
+
+// ListGroupMilestonesHandler implements https://docs.gitlab.com/ee/api/group_milestones.html#list-group-milestones
+func (mock *GitlabApiMock) ListGroupMilestonesHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	group, groupExists := mock.getGroup(request)
+	if !groupExists {
+		writeErrorMessage(responseWriter, http.StatusNotFound, "404 Group Not Found")
+		return
+	}
+
+	milestones := mock.gitlabMock.groupMilestones[group.ID]
+	if includeParentMilestones, _ := strconv.ParseBool(request.URL.Query().Get("include_parent_milestones")); includeParentMilestones {
+		milestones = mock.gitlabMock.availableGroupMilestones(group)
+	}
+
+	writeJSON(responseWriter, http.StatusOK, filterMilestones(milestones, request.URL.Query(), mock.gitlabMock.now()))
+}
+
+// GetGroupMilestoneHandler implements https://docs.gitlab.com/ee/api/group_milestones.html#get-single-milestone
+func (mock *GitlabApiMock) GetGroupMilestoneHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	_, milestone, err := mock.getGroupMilestoneFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, milestone)
+}
+
+// CreateGroupMilestoneHandler implements https://docs.gitlab.com/ee/api/group_milestones.html#create-new-milestone
+func (mock *GitlabApiMock) CreateGroupMilestoneHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	group, groupExists := mock.getGroup(request)
+	if !groupExists {
+		writeErrorMessage(responseWriter, http.StatusNotFound, "404 Group Not Found")
+		return
+	}
+
+	if !mock.gitlabMock.hasGroupAccess(group, currentUser(request), gitlab.ReporterPermissions) {
+		writeErrorMessage(responseWriter, http.StatusForbidden, "403 Forbidden")
+		return
+	}
+
+	var createMilestoneOptions gitlab.CreateMilestoneOptions
+	err := decodeBody(request, &createMilestoneOptions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	milestone, err := mock.gitlabMock.AddGroupMilestone(group, &createMilestoneOptions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusCreated, milestone)
+}
+
+// UpdateGroupMilestoneHandler implements https://docs.gitlab.com/ee/api/group_milestones.html#edit-milestone
+func (mock *GitlabApiMock) UpdateGroupMilestoneHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	group, milestone, err := mock.getGroupMilestoneFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	if !mock.gitlabMock.hasGroupAccess(group, currentUser(request), gitlab.ReporterPermissions) {
+		writeErrorMessage(responseWriter, http.StatusForbidden, "403 Forbidden")
+		return
+	}
+
+	var updateMilestoneOptions gitlab.UpdateMilestoneOptions
+	err = decodeBody(request, &updateMilestoneOptions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	milestone, err = mock.gitlabMock.updateMilestone(milestone, mock.gitlabMock.availableGroupMilestones(group), &updateMilestoneOptions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, milestone)
+}
+
+// DeleteGroupMilestoneHandler implements https://docs.gitlab.com/ee/api/group_milestones.html#delete-group-milestone
+func (mock *GitlabApiMock) DeleteGroupMilestoneHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	group, milestone, err := mock.getGroupMilestoneFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	if !mock.gitlabMock.hasGroupAccess(group, currentUser(request), gitlab.ReporterPermissions) {
+		writeErrorMessage(responseWriter, http.StatusForbidden, "403 Forbidden")
+		return
+	}
+
+	err = mock.gitlabMock.deleteMilestone(nil, group, milestone)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	responseWriter.WriteHeader(http.StatusNoContent)
+}
+
+// GetGroupMilestoneIssuesHandler implements https://docs.gitlab.com/ee/api/group_milestones.html#get-all-issues-assigned-to-a-single-milestone
+func (mock *GitlabApiMock) GetGroupMilestoneIssuesHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	group, milestone, err := mock.getGroupMilestoneFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	issues := mock.gitlabMock.milestoneIssues(mock.gitlabMock.groupProjects(group), milestone)
+
+	writeJSON(responseWriter, http.StatusOK, mock.visibleIssues(request, issues))
+}
+
+// GetGroupMilestoneMergeRequestsHandler implements https://docs.gitlab.com/ee/api/group_milestones.html#get-all-merge-requests-assigned-to-a-single-milestone
+func (mock *GitlabApiMock) GetGroupMilestoneMergeRequestsHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	group, milestone, err := mock.getGroupMilestoneFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	mergeRequests, err := mock.gitlabMock.milestoneMergeRequests(mock.gitlabMock.groupProjects(group), milestone)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, mergeRequests)
+}
+
+// GetGroupMilestoneBurndownEventsHandler implements https://docs.gitlab.com/ee/api/group_milestones.html#get-all-burndown-chart-events-for-a-single-milestone
+func (mock *GitlabApiMock) GetGroupMilestoneBurndownEventsHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	_, milestone, err := mock.getGroupMilestoneFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, append([]*gitlab.BurndownChartEvent{}, mock.gitlabMock.burndownEvents[milestone.ID]...))
+}
+
+// ListMilestoneEventsHandler implements https://docs.gitlab.com/ee/api/resource_milestone_events.html
+func (mock *GitlabApiMock) ListMilestoneEventsHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, resourceType, iid, err := mock.getMilestoneEventResource(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, mock.gitlabMock.getMilestoneEvents(resourceType, project.ID, iid))
+}
+
+// GetMilestoneEventHandler implements https://docs.gitlab.com/ee/api/resource_milestone_events.html#get-single-issue-milestone-event
+func (mock *GitlabApiMock) GetMilestoneEventHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, resourceType, iid, err := mock.getMilestoneEventResource(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	eventID, _ := strconv.Atoi(pathVar(request, "event_id"))
+
+	for _, event := range mock.gitlabMock.getMilestoneEvents(resourceType, project.ID, iid) {
+		if event.ID == eventID {
+			writeJSON(responseWriter, http.StatusOK, event)
+			return
+		}
+	}
+
+	writeErrorMessage(responseWriter, http.StatusNotFound, "404 Not found")
+}
+
+// getMilestoneEventResource resolves the issue or merge request of the resource milestone event endpoints.
+func (mock *GitlabApiMock) getMilestoneEventResource(request *http.Request) (*gitlab.Project, string, int, error) {
+	project, noteable, _, err := mock.getNoteableFromRequest(request)
+	if err != nil {
+		return nil, "", 0, err
+	}
+
+	return project, noteable.Type, noteable.IID, nil
+}
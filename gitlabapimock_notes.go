@@ -0,0 +1,383 @@
+package gitlabapimock
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// getNoteableFromRequest resolves the project and the issue, merge request, snippet or commit referenced by
+// the id, noteable_type and noteable_id path variables. It also returns the ID of the author of the noteable.
+// The user of the request has to be at least a guest of the project.
+func (mock *GitlabApiMock) getNoteableFromRequest(request *http.Request) (*gitlab.Project, noteable, int, error) {
+	project, err := mock.getProjectWithAccess(request, gitlab.GuestPermissions)
+	if err != nil {
+		return nil, noteable{}, 0, err
+	}
+
+	noteableID := pathVar(request, "noteable_id")
+	id, _ := strconv.Atoi(noteableID)
+
+	switch pathVar(request, "noteable_type") {
+	case "merge_requests":
+		mergeRequest, err := mock.gitlabMock.getMergeRequest(project, id)
+		if err != nil {
+			return nil, noteable{}, 0, err
+		}
+
+		authorID := 0
+		if mergeRequest.Author != nil {
+			authorID = mergeRequest.Author.ID
+		}
+
+		return project, mergeRequestNoteable(mergeRequest), authorID, nil
+	case "issues":
+		issue, err := mock.gitlabMock.getIssue(project, id)
+		if err != nil {
+			return nil, noteable{}, 0, err
+		}
+
+		if !mock.gitlabMock.canReadIssue(project, issue, currentUser(request)) {
+			return nil, noteable{}, 0, newAPIError(http.StatusNotFound, "404 Not found")
+		}
+
+		authorID := 0
+		if issue.Author != nil {
+			authorID = issue.Author.ID
+		}
+
+		return project, issueNoteable(issue), authorID, nil
+	case "snippets":
+		snippet, err := mock.gitlabMock.getProjectSnippet(project, id)
+		if err != nil {
+			return nil, noteable{}, 0, err
+		}
+
+		return project, snippetNoteable(snippet), snippet.Author.ID, nil
+	default:
+		repo, err := mock.gitlabMock.getRepository(project)
+		if err != nil {
+			return nil, noteable{}, 0, err
+		}
+
+		sha, err := repo.resolve(noteableID)
+		if err != nil {
+			return nil, noteable{}, 0, newAPIError(http.StatusNotFound, "404 Commit Not Found")
+		}
+
+		return project, commitNoteable(project, sha), 0, nil
+	}
+}
+
+// ListNotesHandler implements https://docs.gitlab.com/ee/api/notes.html#list-project-issue-notes and the
+// corresponding endpoints for merge requests and snippets.
+func (mock *GitlabApiMock) ListNotesHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	_, noteable, _, err := mock.getNoteableFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	notes := mock.gitlabMock.getNotes(noteable)
+
+	query := request.URL.Query()
+	ascending := query.Get("sort") == "asc"
+
+	sort.SliceStable(notes, func(i, j int) bool {
+		left, right := notes[i].CreatedAt, notes[j].CreatedAt
+		if query.Get("order_by") == "updated_at" {
+			left, right = notes[i].UpdatedAt, notes[j].UpdatedAt
+		}
+
+		comparison := left.Compare(*right)
+		if comparison == 0 {
+			comparison = notes[i].ID - notes[j].ID
+		}
+
+		if ascending {
+			return comparison < 0
+		}
+
+		return comparison > 0
+	})
+
+	writeJSON(responseWriter, http.StatusOK, notes)
+}
+
+// GetNoteHandler implements https://docs.gitlab.com/ee/api/notes.html#get-single-issue-note and the
+// corresponding endpoints for merge requests and snippets.
+func (mock *GitlabApiMock) GetNoteHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	_, noteable, _, err := mock.getNoteableFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	noteID, _ := strconv.Atoi(pathVar(request, "note_id"))
+
+	_, note, err := mock.gitlabMock.getNote(noteable, noteID)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, note)
+}
+
+// CreateNoteHandler implements https://docs.gitlab.com/ee/api/notes.html#create-new-issue-note and the
+// corresponding endpoints for merge requests and snippets.
+func (mock *GitlabApiMock) CreateNoteHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, noteable, _, err := mock.getNoteableFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	var createIssueNoteOptions gitlab.CreateIssueNoteOptions
+	err = decodeBody(request, &createIssueNoteOptions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	discussion, err := mock.gitlabMock.createDiscussion(project, noteable, createIssueNoteOptions.Body, nil, true, currentUser(request))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusCreated, discussion.Notes[0])
+}
+
+// UpdateNoteHandler implements https://docs.gitlab.com/ee/api/notes.html#modify-existing-issue-note and the
+// corresponding endpoints for merge requests and snippets.
+func (mock *GitlabApiMock) UpdateNoteHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, noteable, _, err := mock.getNoteableFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	var updateIssueNoteOptions gitlab.UpdateIssueNoteOptions
+	err = decodeBody(request, &updateIssueNoteOptions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	noteID, _ := strconv.Atoi(pathVar(request, "note_id"))
+
+	note, err := mock.gitlabMock.updateNote(project, noteable, noteID, updateIssueNoteOptions.Body, currentUser(request))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, note)
+}
+
+// DeleteNoteHandler implements https://docs.gitlab.com/ee/api/notes.html#delete-an-issue-note and the
+// corresponding endpoints for merge requests and snippets.
+func (mock *GitlabApiMock) DeleteNoteHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, noteable, _, err := mock.getNoteableFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	noteID, _ := strconv.Atoi(pathVar(request, "note_id"))
+
+	err = mock.gitlabMock.deleteNote(project, noteable, noteID, currentUser(request))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	responseWriter.WriteHeader(http.StatusNoContent)
+}
+
+// ListDiscussionsHandler implements https://docs.gitlab.com/ee/api/discussions.html#list-project-issue-discussion-items
+// and the corresponding endpoints for merge requests, snippets and commits.
+func (mock *GitlabApiMock) ListDiscussionsHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	_, noteable, _, err := mock.getNoteableFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, mock.gitlabMock.getDiscussions(noteable))
+}
+
+// GetDiscussionHandler implements https://docs.gitlab.com/ee/api/discussions.html#get-single-issue-discussion-item
+// and the corresponding endpoints for merge requests, snippets and commits.
+func (mock *GitlabApiMock) GetDiscussionHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	_, noteable, _, err := mock.getNoteableFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	discussion, err := mock.gitlabMock.getDiscussion(noteable, pathVar(request, "discussion_id"))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, discussion)
+}
+
+// CreateDiscussionHandler implements https://docs.gitlab.com/ee/api/discussions.html#create-new-issue-thread
+// and the corresponding endpoints for merge requests, snippets and commits. Merge request and commit
+// threads can be positioned on a line of the diff.
+func (mock *GitlabApiMock) CreateDiscussionHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, noteable, _, err := mock.getNoteableFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	var createMergeRequestDiscussionOptions gitlab.CreateMergeRequestDiscussionOptions
+	err = decodeBody(request, &createMergeRequestDiscussionOptions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	position := createMergeRequestDiscussionOptions.Position
+	if noteable.Type != noteableMergeRequest && noteable.Type != noteableCommit {
+		position = nil
+	}
+
+	discussion, err := mock.gitlabMock.createDiscussion(project, noteable, createMergeRequestDiscussionOptions.Body, position, false, currentUser(request))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusCreated, discussion)
+}
+
+// ResolveDiscussionHandler implements https://docs.gitlab.com/ee/api/discussions.html#resolve-a-merge-request-thread
+func (mock *GitlabApiMock) ResolveDiscussionHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, noteable, authorID, err := mock.getNoteableFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	var resolveMergeRequestDiscussionOptions gitlab.ResolveMergeRequestDiscussionOptions
+	err = decodeBody(request, &resolveMergeRequestDiscussionOptions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	if resolveMergeRequestDiscussionOptions.Resolved == nil {
+		writeErrorMessage(responseWriter, http.StatusBadRequest, "resolved is missing")
+		return
+	}
+
+	discussion, err := mock.gitlabMock.resolveDiscussion(project, noteable, authorID, pathVar(request, "discussion_id"), *resolveMergeRequestDiscussionOptions.Resolved, currentUser(request))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, discussion)
+}
+
+// AddDiscussionNoteHandler implements https://docs.gitlab.com/ee/api/discussions.html#add-note-to-existing-issue-thread
+// and the corresponding endpoints for merge requests, snippets and commits.
+func (mock *GitlabApiMock) AddDiscussionNoteHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, noteable, _, err := mock.getNoteableFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	var addMergeRequestDiscussionNoteOptions gitlab.AddMergeRequestDiscussionNoteOptions
+	err = decodeBody(request, &addMergeRequestDiscussionNoteOptions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	note, err := mock.gitlabMock.addDiscussionNote(project, noteable, pathVar(request, "discussion_id"), addMergeRequestDiscussionNoteOptions.Body, currentUser(request))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusCreated, note)
+}
+
+// UpdateDiscussionNoteHandler implements https://docs.gitlab.com/ee/api/discussions.html#modify-an-existing-merge-request-thread-note
+// and the corresponding endpoints for issues, snippets and commits. Either the body is changed or the thread is resolved.
+func (mock *GitlabApiMock) UpdateDiscussionNoteHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, noteable, authorID, err := mock.getNoteableFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	var updateMergeRequestDiscussionNoteOptions gitlab.UpdateMergeRequestDiscussionNoteOptions
+	err = decodeBody(request, &updateMergeRequestDiscussionNoteOptions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	if (updateMergeRequestDiscussionNoteOptions.Body == nil) == (updateMergeRequestDiscussionNoteOptions.Resolved == nil) {
+		writeErrorMessage(responseWriter, http.StatusBadRequest, "body, resolved are mutually exclusive")
+		return
+	}
+
+	noteID, _ := strconv.Atoi(pathVar(request, "note_id"))
+
+	discussion, note, err := mock.gitlabMock.getNote(noteable, noteID)
+	if err != nil || discussion.ID != pathVar(request, "discussion_id") {
+		writeErrorMessage(responseWriter, http.StatusNotFound, "404 Note Not Found")
+		return
+	}
+
+	if updateMergeRequestDiscussionNoteOptions.Resolved != nil {
+		_, err = mock.gitlabMock.resolveDiscussion(project, noteable, authorID, discussion.ID, *updateMergeRequestDiscussionNoteOptions.Resolved, currentUser(request))
+	} else {
+		_, err = mock.gitlabMock.updateNote(project, noteable, note.ID, updateMergeRequestDiscussionNoteOptions.Body, currentUser(request))
+	}
+
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, note)
+}
+
+// DeleteDiscussionNoteHandler implements https://docs.gitlab.com/ee/api/discussions.html#delete-an-issue-thread-note
+// and the corresponding endpoints for merge requests, snippets and commits.
+func (mock *GitlabApiMock) DeleteDiscussionNoteHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, noteable, _, err := mock.getNoteableFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	noteID, _ := strconv.Atoi(pathVar(request, "note_id"))
+
+	discussion, _, err := mock.gitlabMock.getNote(noteable, noteID)
+	if err != nil || discussion.ID != pathVar(request, "discussion_id") {
+		writeErrorMessage(responseWriter, http.StatusNotFound, "404 Note Not Found")
+		return
+	}
+
+	err = mock.gitlabMock.deleteNote(project, noteable, noteID, currentUser(request))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	responseWriter.WriteHeader(http.StatusNoContent)
+}
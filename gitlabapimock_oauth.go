@@ -0,0 +1,358 @@
+package gitlabapimock
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+var (
+	errInvalidToken      = newOAuthError(http.StatusUnauthorized, "invalid_token", "The access token is invalid")
+	errInsufficientScope = newOAuthError(http.StatusForbidden, "insufficient_scope",
+		"The request requires higher privileges than provided by the access token.")
+)
+
+// writeOAuthError writes an error of the OAuth endpoints, other errors are written like API errors.
+func writeOAuthError(responseWriter http.ResponseWriter, err error) {
+	var oauthErr *oauthError
+	if errors.As(err, &oauthErr) {
+		writeJSON(responseWriter, oauthErr.statusCode, map[string]string{
+			"error":             oauthErr.code,
+			"error_description": oauthErr.description,
+		})
+		return
+	}
+
+	writeError(responseWriter, err)
+}
+
+// oauthClientCredentials returns the client credentials of the request, sent either as basic auth credentials or
+// as client_id and client_secret parameters.
+func oauthClientCredentials(request *http.Request) (string, string) {
+	if clientID, clientSecret, ok := request.BasicAuth(); ok {
+		return clientID, clientSecret
+	}
+
+	return request.Form.Get("client_id"), request.Form.Get("client_secret")
+}
+
+// activeOAuthToken resolves the OAuth token the request was sent with.
+func (mock *GitlabApiMock) activeOAuthToken(request *http.Request) (*oauthToken, error) {
+	token, tokenExists := mock.gitlabMock.oauthAccessToken(tokenFromRequest(request))
+	if !tokenExists || !mock.gitlabMock.oauthTokenActive(token) {
+		return nil, errInvalidToken
+	}
+
+	return token, nil
+}
+
+// OAuthAuthorizeHandler implements the authorization code flow of https://docs.gitlab.com/ee/api/oauth2.html#authorization-code-with-proof-key-for-code-exchange-pkce
+// and https://docs.gitlab.com/ee/api/oauth2.html#authorization-code-flow. The user signed in to the mock
+// authorizes the application if the consent approves it, see SignIn and SetOAuthConsent.
+func (mock *GitlabApiMock) OAuthAuthorizeHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	query := request.URL.Query()
+
+	application, applicationExists := mock.gitlabMock.applicationByClientID(query.Get("client_id"))
+	if !applicationExists {
+		writeOAuthError(responseWriter, errInvalidClient)
+		return
+	}
+
+	redirectURIs := application.redirectURIs()
+
+	redirectURI := query.Get("redirect_uri")
+	if redirectURI == "" && len(redirectURIs) == 1 {
+		redirectURI = redirectURIs[0]
+	}
+
+	if !slices.Contains(redirectURIs, redirectURI) {
+		writeOAuthError(responseWriter, newOAuthError(http.StatusBadRequest, "invalid_redirect_uri",
+			"The requested redirect uri is malformed or doesn't match client redirect URI."))
+		return
+	}
+
+	// Errors of valid clients are reported to their redirect URI.
+	redirect := func(params map[string]string) {
+		target, _ := url.Parse(redirectURI)
+
+		targetQuery := target.Query()
+		for key, value := range params {
+			targetQuery.Set(key, value)
+		}
+
+		if state := query.Get("state"); state != "" {
+			targetQuery.Set("state", state)
+		}
+
+		target.RawQuery = targetQuery.Encode()
+
+		http.Redirect(responseWriter, request, target.String(), http.StatusFound)
+	}
+
+	redirectError := func(err error) {
+		var oauthErr *oauthError
+		if errors.As(err, &oauthErr) {
+			redirect(map[string]string{"error": oauthErr.code, "error_description": oauthErr.description})
+		}
+	}
+
+	if query.Get("response_type") != "code" {
+		redirectError(newOAuthError(http.StatusBadRequest, "unsupported_response_type", "The authorization server does not support this response type."))
+		return
+	}
+
+	scopes, err := parseOAuthScopes(query.Get("scope"), application.scopes)
+	if err != nil {
+		redirectError(err)
+		return
+	}
+
+	if len(scopes) == 0 {
+		scopes = application.scopes
+	}
+
+	codeChallenge := query.Get("code_challenge")
+	codeChallengeMethod := query.Get("code_challenge_method")
+
+	if codeChallenge != "" && codeChallengeMethod == "" {
+		codeChallengeMethod = pkceMethodPlain
+	}
+
+	if codeChallenge != "" && codeChallengeMethod != pkceMethodPlain && codeChallengeMethod != pkceMethodS256 {
+		redirectError(newOAuthError(http.StatusBadRequest, "invalid_request", "The code challenge method must be plain or S256."))
+		return
+	}
+
+	user := mock.gitlabMock.sessionUser
+	if user == nil {
+		http.Redirect(responseWriter, request, mock.baseURL+"/users/sign_in", http.StatusFound)
+		return
+	}
+
+	if consent := mock.gitlabMock.oauthConsent; consent != nil && !consent(visibleApplication(application), user, scopes) {
+		redirectError(newOAuthError(http.StatusForbidden, "access_denied", "The resource owner or authorization server denied the request."))
+		return
+	}
+
+	grant := mock.gitlabMock.createOAuthGrant(application, user, redirectURI, scopes, codeChallenge, codeChallengeMethod)
+
+	redirect(map[string]string{"code": grant.code})
+}
+
+// OAuthTokenHandler implements https://docs.gitlab.com/ee/api/oauth2.html, it issues tokens for authorization
+// codes, refresh tokens and the credentials of users. The password grant works without client credentials.
+func (mock *GitlabApiMock) OAuthTokenHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	if err := request.ParseForm(); err != nil {
+		writeOAuthError(responseWriter, newOAuthError(http.StatusBadRequest, "invalid_request", err.Error()))
+		return
+	}
+
+	clientID, clientSecret := oauthClientCredentials(request)
+
+	var application *oauthApplication
+	var err error
+
+	if clientID != "" || request.Form.Get("grant_type") != "password" {
+		application, err = mock.gitlabMock.authenticateClient(clientID, clientSecret)
+		if err != nil {
+			writeOAuthError(responseWriter, err)
+			return
+		}
+	}
+
+	var token *oauthToken
+
+	switch request.Form.Get("grant_type") {
+	case "authorization_code":
+		token, err = mock.gitlabMock.exchangeOAuthGrant(application, request.Form.Get("code"), request.Form.Get("redirect_uri"), request.Form.Get("code_verifier"))
+	case "refresh_token":
+		token, err = mock.gitlabMock.refreshOAuthToken(application, request.Form.Get("refresh_token"))
+	case "password":
+		token, err = mock.gitlabMock.passwordOAuthToken(application, request.Form.Get("username"), request.Form.Get("password"), request.Form.Get("scope"))
+	default:
+		err = newOAuthError(http.StatusBadRequest, "unsupported_grant_type", "The authorization grant type is not supported by the authorization server.")
+	}
+
+	if err != nil {
+		writeOAuthError(responseWriter, err)
+		return
+	}
+
+	responseWriter.Header().Set("Cache-Control", "no-store")
+	writeJSON(responseWriter, http.StatusOK, mock.gitlabMock.oauthTokenResponse(token))
+}
+
+// OAuthRevokeHandler implements https://docs.gitlab.com/ee/api/oauth2.html#revoke-a-token, the access token or
+// refresh token is revoked together with its counterpart.
+func (mock *GitlabApiMock) OAuthRevokeHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	if err := request.ParseForm(); err != nil {
+		writeOAuthError(responseWriter, newOAuthError(http.StatusBadRequest, "invalid_request", err.Error()))
+		return
+	}
+
+	var application *oauthApplication
+
+	if clientID, clientSecret := oauthClientCredentials(request); clientID != "" {
+		var err error
+
+		application, err = mock.gitlabMock.authenticateClient(clientID, clientSecret)
+		if err != nil {
+			writeOAuthError(responseWriter, err)
+			return
+		}
+	}
+
+	if err := mock.gitlabMock.revokeOAuthToken(application, request.Form.Get("token")); err != nil {
+		writeOAuthError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, map[string]string{})
+}
+
+// OAuthTokenInfoHandler implements https://docs.gitlab.com/ee/api/oauth2.html#retrieve-the-token-information
+func (mock *GitlabApiMock) OAuthTokenInfoHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	token, err := mock.activeOAuthToken(request)
+	if err != nil {
+		writeOAuthError(responseWriter, err)
+		return
+	}
+
+	var application map[string]string
+	if tokenApplication, err := mock.gitlabMock.getApplication(token.applicationID); err == nil {
+		application = map[string]string{"uid": tokenApplication.ApplicationID}
+	}
+
+	expiresIn := int(mock.gitlabMock.oauthTokenExpiresIn(token) / time.Second)
+
+	writeJSON(responseWriter, http.StatusOK, map[string]interface{}{
+		"resource_owner_id":  token.userID,
+		"scope":              token.scopes,
+		"scopes":             token.scopes,
+		"expires_in":         expiresIn,
+		"expires_in_seconds": expiresIn,
+		"application":        application,
+		"created_at":         token.createdAt.Unix(),
+	})
+}
+
+// OAuthUserInfoHandler implements https://docs.gitlab.com/ee/integration/openid_connect_provider.html#shared-information,
+// the token needs the openid scope.
+func (mock *GitlabApiMock) OAuthUserInfoHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	token, err := mock.activeOAuthToken(request)
+	if err != nil {
+		writeOAuthError(responseWriter, err)
+		return
+	}
+
+	if !slices.Contains(token.scopes, "openid") {
+		writeOAuthError(responseWriter, errInsufficientScope)
+		return
+	}
+
+	user, err := mock.gitlabMock.getUser(token.userID)
+	if err != nil {
+		writeOAuthError(responseWriter, errInvalidToken)
+		return
+	}
+
+	groups := []string{}
+	for _, group := range mock.gitlabMock.groups {
+		if slices.ContainsFunc(mock.gitlabMock.groupMembers[group.ID], func(member *gitlab.GroupMember) bool { return member.ID == user.ID }) {
+			groups = append(groups, group.FullPath)
+		}
+	}
+
+	writeJSON(responseWriter, http.StatusOK, map[string]interface{}{
+		"sub":                strconv.Itoa(user.ID),
+		"name":               user.Name,
+		"nickname":           user.Username,
+		"preferred_username": user.Username,
+		"email":              user.Email,
+		"email_verified":     true,
+		"website":            user.WebsiteURL,
+		"profile":            fmt.Sprintf("%s/%s", mock.baseURL, user.Username),
+		"picture":            user.AvatarURL,
+		"groups":             groups,
+	})
+}
+
+// ListApplicationsHandler implements https://docs.gitlab.com/ee/api/applications.html#list-all-applications
+func (mock *GitlabApiMock) ListApplicationsHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	if err := mock.checkAdmin(request); err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	applications := []*gitlab.Application{}
+	for _, application := range mock.gitlabMock.applications {
+		applications = append(applications, visibleApplication(application))
+	}
+
+	writeJSON(responseWriter, http.StatusOK, applications)
+}
+
+// CreateApplicationHandler implements https://docs.gitlab.com/ee/api/applications.html#create-an-application
+func (mock *GitlabApiMock) CreateApplicationHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	if err := mock.checkAdmin(request); err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	var options gitlab.CreateApplicationOptions
+
+	err := decodeBody(request, &options)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	application, err := mock.gitlabMock.createApplication(&options)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusCreated, application)
+}
+
+// getApplicationFromRequest resolves the application of the id path variable, applications are managed by admins.
+func (mock *GitlabApiMock) getApplicationFromRequest(request *http.Request) (*oauthApplication, error) {
+	if err := mock.checkAdmin(request); err != nil {
+		return nil, err
+	}
+
+	id, _ := strconv.Atoi(pathVar(request, "id"))
+
+	return mock.gitlabMock.getApplication(id)
+}
+
+// DeleteApplicationHandler implements https://docs.gitlab.com/ee/api/applications.html#delete-an-application
+func (mock *GitlabApiMock) DeleteApplicationHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	application, err := mock.getApplicationFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	mock.gitlabMock.deleteApplication(application)
+
+	responseWriter.WriteHeader(http.StatusNoContent)
+}
+
+// RenewApplicationSecretHandler implements https://docs.gitlab.com/ee/api/applications.html#renew-an-application-secret
+func (mock *GitlabApiMock) RenewApplicationSecretHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	application, err := mock.getApplicationFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusCreated, mock.gitlabMock.renewApplicationSecret(application))
+}
@@ -0,0 +1,268 @@
+package gitlabapimock
+
+import (
+	"cmp"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// matchesPipelineScope implements the scope parameter of https://docs.gitlab.com/ee/api/pipelines.html#list-project-pipelines
+func matchesPipelineScope(pipeline *gitlab.Pipeline, scope string) bool {
+	switch scope {
+	case "running", "pending":
+		return pipeline.Status == scope
+	case "finished":
+		return pipelineFinished(pipeline.Status)
+	case "branches":
+		return !pipeline.Tag
+	case "tags":
+		return pipeline.Tag
+	}
+
+	return true
+}
+
+// filterPipelines applies the filters of https://docs.gitlab.com/ee/api/pipelines.html#list-project-pipelines
+func filterPipelines(pipelines []*gitlab.Pipeline, query url.Values) []*gitlab.PipelineInfo {
+	filteredPipelines := []*gitlab.Pipeline{}
+
+	for _, pipeline := range pipelines {
+		if !matchesPipelineScope(pipeline, query.Get("scope")) {
+			continue
+		}
+
+		if status := query.Get("status"); status != "" && status != pipeline.Status {
+			continue
+		}
+
+		if source := query.Get("source"); source != "" && source != pipeline.Source {
+			continue
+		}
+
+		if ref := query.Get("ref"); ref != "" && ref != pipeline.Ref {
+			continue
+		}
+
+		if sha := query.Get("sha"); sha != "" && sha != pipeline.SHA {
+			continue
+		}
+
+		if username := query.Get("username"); username != "" && (pipeline.User == nil || pipeline.User.Username != username) {
+			continue
+		}
+
+		if !matchesTimeFilter(pipeline.UpdatedAt, query, "updated_after", "updated_before") {
+			continue
+		}
+
+		filteredPipelines = append(filteredPipelines, pipeline)
+	}
+
+	orderBy := query.Get("order_by")
+	ascending := query.Get("sort") == "asc"
+
+	slices.SortStableFunc(filteredPipelines, func(left *gitlab.Pipeline, right *gitlab.Pipeline) int {
+		var comparison int
+
+		switch orderBy {
+		case "status":
+			comparison = strings.Compare(left.Status, right.Status)
+		case "ref":
+			comparison = strings.Compare(left.Ref, right.Ref)
+		case "updated_at":
+			comparison = left.UpdatedAt.Compare(*right.UpdatedAt)
+		case "user_id":
+			comparison = cmp.Compare(pipelineUserID(left), pipelineUserID(right))
+		}
+
+		if comparison == 0 {
+			comparison = cmp.Compare(left.ID, right.ID)
+		}
+
+		if !ascending {
+			comparison = -comparison
+		}
+
+		return comparison
+	})
+
+	pipelineInfos := []*gitlab.PipelineInfo{}
+	for _, pipeline := range filteredPipelines {
+		pipelineInfos = append(pipelineInfos, &gitlab.PipelineInfo{
+			ID:        pipeline.ID,
+			IID:       pipeline.IID,
+			ProjectID: pipeline.ProjectID,
+			Status:    pipeline.Status,
+			Source:    pipeline.Source,
+			Ref:       pipeline.Ref,
+			SHA:       pipeline.SHA,
+			WebURL:    pipeline.WebURL,
+			UpdatedAt: pipeline.UpdatedAt,
+			CreatedAt: pipeline.CreatedAt,
+		})
+	}
+
+	return pipelineInfos
+}
+
+func pipelineUserID(pipeline *gitlab.Pipeline) int {
+	if pipeline.User == nil {
+		return 0
+	}
+
+	return pipeline.User.ID
+}
+
+// getPipelineFromRequest resolves the project and pipeline referenced by the id and pipeline_id path variables.
+func (mock *GitlabApiMock) getPipelineFromRequest(request *http.Request) (*gitlab.Project, *gitlab.Pipeline, error) {
+	project, err := mock.getProjectWithAccess(request, gitlab.ReporterPermissions)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pipelineID, _ := strconv.Atoi(pathVar(request, "pipeline_id"))
+
+	pipeline, err := mock.gitlabMock.getPipeline(project, pipelineID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return project, pipeline, nil
+}
+
+// ListProjectPipelinesHandler implements https://docs.gitlab.com/ee/api/pipelines.html#list-project-pipelines
+func (mock *GitlabApiMock) ListProjectPipelinesHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, err := mock.getProjectWithAccess(request, gitlab.ReporterPermissions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, filterPipelines(mock.gitlabMock.pipelines[project.ID], request.URL.Query()))
+}
+
+// GetPipelineHandler implements https://docs.gitlab.com/ee/api/pipelines.html#get-a-single-pipeline
+func (mock *GitlabApiMock) GetPipelineHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	_, pipeline, err := mock.getPipelineFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, pipeline)
+}
+
+// GetLatestPipelineHandler implements https://docs.gitlab.com/ee/api/pipelines.html#get-the-latest-pipeline
+func (mock *GitlabApiMock) GetLatestPipelineHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, err := mock.getProjectWithAccess(request, gitlab.ReporterPermissions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	pipeline, err := mock.gitlabMock.latestPipeline(project, request.URL.Query().Get("ref"))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, pipeline)
+}
+
+// GetPipelineVariablesHandler implements https://docs.gitlab.com/ee/api/pipelines.html#get-variables-of-a-pipeline
+func (mock *GitlabApiMock) GetPipelineVariablesHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	_, pipeline, err := mock.getPipelineFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, mock.gitlabMock.pipelineVariables[pipeline.ID])
+}
+
+// CreatePipelineHandler implements https://docs.gitlab.com/ee/api/pipelines.html#create-a-new-pipeline
+func (mock *GitlabApiMock) CreatePipelineHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, projectExists := mock.getProject(request)
+	if !projectExists {
+		writeErrorMessage(responseWriter, http.StatusNotFound, "404 Project Not Found")
+		return
+	}
+
+	user := currentUser(request)
+
+	if !mock.gitlabMock.hasAccess(project, user, gitlab.DeveloperPermissions) {
+		writeErrorMessage(responseWriter, http.StatusForbidden, "403 Forbidden")
+		return
+	}
+
+	var createPipelineOptions gitlab.CreatePipelineOptions
+	err := decodeBody(request, &createPipelineOptions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	pipeline, err := mock.gitlabMock.createPipeline(project, valueOf(createPipelineOptions.Ref), "api", toPipelineVariables(createPipelineOptions.Variables), nil, user)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusCreated, pipeline)
+}
+
+// RetryPipelineHandler implements https://docs.gitlab.com/ee/api/pipelines.html#retry-jobs-in-a-pipeline
+func (mock *GitlabApiMock) RetryPipelineHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, pipeline, err := mock.getPipelineFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	pipeline, err = mock.gitlabMock.retryPipeline(project, pipeline, currentUser(request))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusCreated, pipeline)
+}
+
+// CancelPipelineHandler implements https://docs.gitlab.com/ee/api/pipelines.html#cancel-a-pipelines-jobs
+func (mock *GitlabApiMock) CancelPipelineHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, pipeline, err := mock.getPipelineFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	pipeline, err = mock.gitlabMock.cancelPipeline(project, pipeline, currentUser(request))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusCreated, pipeline)
+}
+
+// DeletePipelineHandler implements https://docs.gitlab.com/ee/api/pipelines.html#delete-a-pipeline
+func (mock *GitlabApiMock) DeletePipelineHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, pipeline, err := mock.getPipelineFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	err = mock.gitlabMock.deletePipeline(project, pipeline, currentUser(request))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	responseWriter.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,89 @@
+package gitlabapimock
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// writeRegistryError writes an error in the format of the container registry.
+func writeRegistryError(responseWriter http.ResponseWriter, statusCode int, code string, message string) {
+	writeJSON(responseWriter, statusCode, map[string]interface{}{
+		"errors": []map[string]string{{"code": code, "message": message}},
+	})
+}
+
+// registryCredentialsFromRequest resolves the basic auth credentials of a container registry token request,
+// which are either a deploy token or a token of a user.
+func (mock *GitlabApiMock) registryCredentialsFromRequest(request *http.Request) (*registryCredentials, error) {
+	username, password, ok := request.BasicAuth()
+	if !ok {
+		return &registryCredentials{}, nil
+	}
+
+	deployToken, err := mock.gitlabMock.authenticateDeployToken(username, password)
+	if err != nil {
+		return nil, err
+	}
+
+	if deployToken != nil {
+		return &registryCredentials{deployToken: deployToken}, nil
+	}
+
+	user, token, err := mock.gitlabMock.authenticate(password)
+	if err != nil {
+		return nil, err
+	}
+
+	scopes, scoped := mock.gitlabMock.tokenScopes(password, token)
+
+	return &registryCredentials{user: user, scopes: scopes, scoped: scoped}, nil
+}
+
+// RegistryAuthHandler implements the token authentication GitLab provides for the container registry, see
+// https://docs.gitlab.com/ee/administration/packages/container_registry.html#use-an-external-container-registry-with-gitlab-as-an-auth-endpoint
+// and https://distribution.github.io/distribution/spec/auth/token/. Scopes of the form
+// repository:<path>:<actions> are granted the actions the credentials are allowed to, the request is denied if
+// none of them is granted.
+func (mock *GitlabApiMock) RegistryAuthHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	credentials, err := mock.registryCredentialsFromRequest(request)
+	if err != nil {
+		responseWriter.Header().Set("WWW-Authenticate", `Basic realm="GitLab"`)
+		writeRegistryError(responseWriter, http.StatusUnauthorized, "UNAUTHORIZED", "HTTP Basic: Access denied")
+		return
+	}
+
+	query := request.URL.Query()
+
+	access := []*registryAccess{}
+
+	for _, scope := range query["scope"] {
+		parts := strings.Split(scope, ":")
+		if len(parts) != 3 || parts[0] != "repository" {
+			continue
+		}
+
+		project, projectExists := mock.gitlabMock.registryProject(parts[1])
+		if !projectExists {
+			continue
+		}
+
+		actions := mock.gitlabMock.registryActions(credentials, project, strings.Split(parts[2], ","))
+		if len(actions) > 0 {
+			access = append(access, &registryAccess{Type: parts[0], Name: parts[1], Actions: actions})
+		}
+	}
+
+	if len(query["scope"]) > 0 && len(access) == 0 {
+		writeRegistryError(responseWriter, http.StatusForbidden, "DENIED", "access forbidden")
+		return
+	}
+
+	token, issuedAt := mock.gitlabMock.signRegistryToken(credentials.username(), query.Get("service"), access)
+
+	writeJSON(responseWriter, http.StatusOK, map[string]interface{}{
+		"token":      token,
+		"expires_in": int(registryTokenLifetime / time.Second),
+		"issued_at":  issuedAt.Format(time.RFC3339),
+	})
+}
@@ -0,0 +1,243 @@
+package gitlabapimock
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// getReleaseFromRequest resolves the project and release referenced by the id and tag_name path variables and
+// checks that the user of the request has at least the access level in the project.
+func (mock *GitlabApiMock) getReleaseFromRequest(request *http.Request, accessLevel gitlab.AccessLevelValue) (*gitlab.Project, *release, error) {
+	project, err := mock.getProjectWithAccess(request, accessLevel)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	release, err := mock.gitlabMock.getRelease(project, pathVar(request, "tag_name"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return project, release, nil
+}
+
+// ListReleasesHandler implements https://docs.gitlab.com/ee/api/releases/#list-releases
+func (mock *GitlabApiMock) ListReleasesHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, err := mock.getProjectWithAccess(request, gitlab.ReporterPermissions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	var listReleasesOptions gitlab.ListReleasesOptions
+	decodeQuery(request, &listReleasesOptions)
+
+	releases := mock.gitlabMock.getReleases(project, valueOf(listReleasesOptions.OrderBy), valueOf(listReleasesOptions.Sort))
+
+	writeJSON(responseWriter, http.StatusOK, releases)
+}
+
+// GetReleaseByTagNameHandler implements https://docs.gitlab.com/ee/api/releases/#get-a-release-by-a-tag-name
+func (mock *GitlabApiMock) GetReleaseByTagNameHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	_, release, err := mock.getReleaseFromRequest(request, gitlab.ReporterPermissions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, release)
+}
+
+// GetLatestReleaseHandler implements https://docs.gitlab.com/ee/api/releases/#get-the-latest-release
+func (mock *GitlabApiMock) GetLatestReleaseHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, err := mock.getProjectWithAccess(request, gitlab.ReporterPermissions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	release, err := mock.gitlabMock.getLatestRelease(project)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, release)
+}
+
+// CreateReleaseHandler implements https://docs.gitlab.com/ee/api/releases/#create-a-release
+func (mock *GitlabApiMock) CreateReleaseHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, projectExists := mock.getProject(request)
+	if !projectExists {
+		writeErrorMessage(responseWriter, http.StatusNotFound, "404 Project Not Found")
+		return
+	}
+
+	var createReleaseOptions gitlab.CreateReleaseOptions
+	err := decodeBody(request, &createReleaseOptions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	release, err := mock.gitlabMock.createRelease(project, &createReleaseOptions, currentUser(request))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusCreated, release)
+}
+
+// UpdateReleaseHandler implements https://docs.gitlab.com/ee/api/releases/#update-a-release
+func (mock *GitlabApiMock) UpdateReleaseHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, projectExists := mock.getProject(request)
+	if !projectExists {
+		writeErrorMessage(responseWriter, http.StatusNotFound, "404 Project Not Found")
+		return
+	}
+
+	var updateReleaseOptions gitlab.UpdateReleaseOptions
+	err := decodeBody(request, &updateReleaseOptions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	release, err := mock.gitlabMock.updateRelease(project, pathVar(request, "tag_name"), &updateReleaseOptions, currentUser(request))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, release)
+}
+
+// DeleteReleaseHandler implements https://docs.gitlab.com/ee/api/releases/#delete-a-release
+func (mock *GitlabApiMock) DeleteReleaseHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, _, err := mock.getReleaseFromRequest(request, gitlab.MaintainerPermissions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	release, err := mock.gitlabMock.removeRelease(project, pathVar(request, "tag_name"))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, release)
+}
+
+// CollectReleaseEvidenceHandler implements https://docs.gitlab.com/ee/api/releases/#collect-release-evidence
+func (mock *GitlabApiMock) CollectReleaseEvidenceHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, release, err := mock.getReleaseFromRequest(request, gitlab.DeveloperPermissions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusCreated, release.collectEvidence(project, mock.gitlabMock.now()))
+}
+
+// ListReleaseLinksHandler implements https://docs.gitlab.com/ee/api/releases/links.html#list-links-of-a-release
+func (mock *GitlabApiMock) ListReleaseLinksHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	_, release, err := mock.getReleaseFromRequest(request, gitlab.ReporterPermissions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, release.Assets.Links)
+}
+
+// GetReleaseLinkHandler implements https://docs.gitlab.com/ee/api/releases/links.html#get-a-release-link
+func (mock *GitlabApiMock) GetReleaseLinkHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	_, release, err := mock.getReleaseFromRequest(request, gitlab.ReporterPermissions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	linkID, _ := strconv.Atoi(pathVar(request, "link_id"))
+
+	link, err := getReleaseLink(release, linkID)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, link)
+}
+
+// CreateReleaseLinkHandler implements https://docs.gitlab.com/ee/api/releases/links.html#create-a-release-link
+func (mock *GitlabApiMock) CreateReleaseLinkHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	_, release, err := mock.getReleaseFromRequest(request, gitlab.DeveloperPermissions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	var createReleaseLinkOptions gitlab.CreateReleaseLinkOptions
+	err = decodeBody(request, &createReleaseLinkOptions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	link, err := mock.gitlabMock.createReleaseLink(release, &createReleaseLinkOptions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusCreated, link)
+}
+
+// UpdateReleaseLinkHandler implements https://docs.gitlab.com/ee/api/releases/links.html#update-a-release-link
+func (mock *GitlabApiMock) UpdateReleaseLinkHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	_, release, err := mock.getReleaseFromRequest(request, gitlab.DeveloperPermissions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	var updateReleaseLinkOptions gitlab.UpdateReleaseLinkOptions
+	err = decodeBody(request, &updateReleaseLinkOptions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	linkID, _ := strconv.Atoi(pathVar(request, "link_id"))
+
+	link, err := updateReleaseLink(release, linkID, &updateReleaseLinkOptions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, link)
+}
+
+// DeleteReleaseLinkHandler implements https://docs.gitlab.com/ee/api/releases/links.html#delete-a-release-link
+func (mock *GitlabApiMock) DeleteReleaseLinkHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	_, release, err := mock.getReleaseFromRequest(request, gitlab.DeveloperPermissions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	linkID, _ := strconv.Atoi(pathVar(request, "link_id"))
+
+	link, err := deleteReleaseLink(release, linkID)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, link)
+}
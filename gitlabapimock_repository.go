@@ -0,0 +1,50 @@
+package gitlabapimock
+
+import (
+	"net/http"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// ListRepositoryBranchesHandler implements https://docs.gitlab.com/ee/api/branches.html#list-repository-branches
+func (mock *GitlabApiMock) ListRepositoryBranchesHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, err := mock.getProjectWithAccess(request, gitlab.ReporterPermissions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	branches, err := mock.gitlabMock.branches(project)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, branches)
+}
+
+// GetSingleRepositoryBranchHandler implements https://docs.gitlab.com/ee/api/branches.html#get-single-repository-branch
+func (mock *GitlabApiMock) GetSingleRepositoryBranchHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, err := mock.getProjectWithAccess(request, gitlab.ReporterPermissions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	branches, err := mock.gitlabMock.branches(project)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	name := pathVar(request, "branch")
+
+	for _, branch := range branches {
+		if branch.Name == name {
+			writeJSON(responseWriter, http.StatusOK, branch)
+			return
+		}
+	}
+
+	writeErrorMessage(responseWriter, http.StatusNotFound, "404 Branch Not Found")
+}
@@ -0,0 +1,510 @@
+package gitlabapimock
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// runnerScopes maps the deprecated scope parameter of the runner lists to the runner types and statuses.
+var runnerScopes = map[string]func(runner *gitlab.RunnerDetails) bool{
+	"active":          func(runner *gitlab.RunnerDetails) bool { return !runner.Paused },
+	"paused":          func(runner *gitlab.RunnerDetails) bool { return runner.Paused },
+	"online":          func(runner *gitlab.RunnerDetails) bool { return runner.Status == runnerStatusOnline },
+	"offline":         func(runner *gitlab.RunnerDetails) bool { return runner.Status == runnerStatusOffline },
+	"specific":        func(runner *gitlab.RunnerDetails) bool { return !runner.IsShared },
+	"shared":          func(runner *gitlab.RunnerDetails) bool { return runner.IsShared },
+	"instance_type":   func(runner *gitlab.RunnerDetails) bool { return runner.RunnerType == runnerTypeInstance },
+	"group_type":      func(runner *gitlab.RunnerDetails) bool { return runner.RunnerType == runnerTypeGroup },
+	"project_type":    func(runner *gitlab.RunnerDetails) bool { return runner.RunnerType == runnerTypeProject },
+	"stale":           func(runner *gitlab.RunnerDetails) bool { return runner.Status == runnerStatusStale },
+	"never_contacted": func(runner *gitlab.RunnerDetails) bool { return runner.Status == runnerStatusNeverContacted },
+}
+
+// filterRunners applies the type, status, paused, tag_list and scope parameters of
+// https://docs.gitlab.com/ee/api/runners.html#list-owned-runners and returns the runners in their short form.
+func filterRunners(runners []*gitlab.RunnerDetails, query url.Values) ([]*gitlab.Runner, error) {
+	scope := query.Get("scope")
+	if _, validScope := runnerScopes[scope]; scope != "" && !validScope {
+		return nil, newAPIError(http.StatusBadRequest, "Scope contains invalid value")
+	}
+
+	filteredRunners := []*gitlab.Runner{}
+
+	for _, runner := range runners {
+		if runnerType := query.Get("type"); runnerType != "" && runner.RunnerType != runnerType {
+			continue
+		}
+
+		if status := query.Get("status"); status != "" {
+			if matchesStatus, validStatus := runnerScopes[status]; !validStatus || !matchesStatus(runner) {
+				continue
+			}
+		}
+
+		if paused, err := strconv.ParseBool(query.Get("paused")); err == nil && runner.Paused != paused {
+			continue
+		}
+
+		if tagList := query.Get("tag_list"); tagList != "" && slices.ContainsFunc(strings.Split(tagList, ","), func(tag string) bool {
+			return !slices.Contains(runner.TagList, strings.TrimSpace(tag))
+		}) {
+			continue
+		}
+
+		if scope != "" && !runnerScopes[scope](runner) {
+			continue
+		}
+
+		filteredRunners = append(filteredRunners, toRunner(runner))
+	}
+
+	return filteredRunners, nil
+}
+
+// getRunnerFromRequest resolves the runner of the id path variable and checks that the user manages it.
+func (mock *GitlabApiMock) getRunnerFromRequest(request *http.Request) (*gitlab.RunnerDetails, error) {
+	runnerID, _ := strconv.Atoi(pathVar(request, "runner_id"))
+
+	return mock.gitlabMock.getManagedRunner(runnerID, currentUser(request))
+}
+
+// getGroupForRunners resolves the group of the request and checks that the user may manage its runners.
+func (mock *GitlabApiMock) getGroupForRunners(request *http.Request) (*gitlab.Group, error) {
+	group, groupExists := mock.getGroup(request)
+	if !groupExists {
+		return nil, newAPIError(http.StatusNotFound, "404 Group Not Found")
+	}
+
+	if !mock.gitlabMock.hasGroupAccess(group, currentUser(request), gitlab.OwnerPermissions) {
+		return nil, newAPIError(http.StatusForbidden, "403 Forbidden")
+	}
+
+	return group, nil
+}
+
+// writeRunners writes the filtered runners of a runner list.
+func writeRunners(responseWriter http.ResponseWriter, request *http.Request, runners []*gitlab.RunnerDetails) {
+	filteredRunners, err := filterRunners(runners, request.URL.Query())
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, filteredRunners)
+}
+
+// ListRunnersHandler implements https://docs.gitlab.com/ee/api/runners.html#list-owned-runners
+func (mock *GitlabApiMock) ListRunnersHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	writeRunners(responseWriter, request, mock.gitlabMock.ownedRunners(currentUser(request)))
+}
+
+// ListAllRunnersHandler implements https://docs.gitlab.com/ee/api/runners.html#list-all-runners
+func (mock *GitlabApiMock) ListAllRunnersHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	if err := mock.checkAdmin(request); err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeRunners(responseWriter, request, mock.gitlabMock.GetRunners())
+}
+
+// GetRunnerDetailsHandler implements https://docs.gitlab.com/ee/api/runners.html#get-runners-details
+func (mock *GitlabApiMock) GetRunnerDetailsHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	runner, err := mock.getRunnerFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, runner)
+}
+
+// UpdateRunnerDetailsHandler implements https://docs.gitlab.com/ee/api/runners.html#update-runners-details
+func (mock *GitlabApiMock) UpdateRunnerDetailsHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	runner, err := mock.getRunnerFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	var options gitlab.UpdateRunnerDetailsOptions
+
+	err = decodeBody(request, &options)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	err = mock.gitlabMock.updateRunner(runner, &options)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, runner)
+}
+
+// RemoveRunnerHandler implements https://docs.gitlab.com/ee/api/runners.html#delete-a-runner-by-id
+func (mock *GitlabApiMock) RemoveRunnerHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	runner, err := mock.getRunnerFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	mock.gitlabMock.removeRunner(runner)
+
+	responseWriter.WriteHeader(http.StatusNoContent)
+}
+
+// ListRunnerJobsHandler implements https://docs.gitlab.com/ee/api/runners.html#list-jobs-processed-by-a-runner
+func (mock *GitlabApiMock) ListRunnerJobsHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	runner, err := mock.getRunnerFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	jobs := mock.gitlabMock.runnerJobs(runner)
+
+	if status := request.URL.Query().Get("status"); status != "" {
+		jobs = slices.DeleteFunc(jobs, func(job *gitlab.Job) bool { return job.Status != status })
+	}
+
+	if request.URL.Query().Get("sort") == "asc" {
+		slices.Reverse(jobs)
+	}
+
+	writeJSON(responseWriter, http.StatusOK, jobs)
+}
+
+// ResetRunnerAuthenticationTokenHandler implements https://docs.gitlab.com/ee/api/runners.html#reset-runners-authentication-token-by-using-the-runner-id
+func (mock *GitlabApiMock) ResetRunnerAuthenticationTokenHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	runner, err := mock.getRunnerFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	token := mock.gitlabMock.resetRunnerToken(runner)
+
+	writeJSON(responseWriter, http.StatusCreated, &gitlab.RunnerAuthenticationToken{Token: &token})
+}
+
+// ListProjectRunnersHandler implements https://docs.gitlab.com/ee/api/runners.html#list-projects-runners
+func (mock *GitlabApiMock) ListProjectRunnersHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, err := mock.getProjectWithAccess(request, gitlab.MaintainerPermissions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeRunners(responseWriter, request, mock.gitlabMock.projectRunners(project))
+}
+
+// EnableProjectRunnerHandler implements https://docs.gitlab.com/ee/api/runners.html#assign-a-runner-to-project
+func (mock *GitlabApiMock) EnableProjectRunnerHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, err := mock.getProjectWithAccess(request, gitlab.MaintainerPermissions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	var options gitlab.EnableProjectRunnerOptions
+
+	err = decodeBody(request, &options)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	runner, err := mock.gitlabMock.getRunner(options.RunnerID)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	err = mock.gitlabMock.enableProjectRunner(project, runner, currentUser(request))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusCreated, toRunner(runner))
+}
+
+// DisableProjectRunnerHandler implements https://docs.gitlab.com/ee/api/runners.html#unassign-a-runner-from-project
+func (mock *GitlabApiMock) DisableProjectRunnerHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, err := mock.getProjectWithAccess(request, gitlab.MaintainerPermissions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	runnerID, _ := strconv.Atoi(pathVar(request, "runner_id"))
+
+	runner, err := mock.gitlabMock.getRunner(runnerID)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	err = mock.gitlabMock.disableProjectRunner(project, runner)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	responseWriter.WriteHeader(http.StatusNoContent)
+}
+
+// ListGroupRunnersHandler implements https://docs.gitlab.com/ee/api/runners.html#list-groups-runners
+func (mock *GitlabApiMock) ListGroupRunnersHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	group, err := mock.getGroupForRunners(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeRunners(responseWriter, request, mock.gitlabMock.groupRunners(group))
+}
+
+// ResetInstanceRunnersTokenHandler implements https://docs.gitlab.com/ee/api/runners.html#reset-instances-runner-registration-token
+func (mock *GitlabApiMock) ResetInstanceRunnersTokenHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	if err := mock.checkAdmin(request); err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	token := mock.gitlabMock.resetRunnersRegistrationToken()
+
+	writeJSON(responseWriter, http.StatusCreated, &gitlab.RunnerRegistrationToken{Token: &token})
+}
+
+// ResetGroupRunnersTokenHandler implements https://docs.gitlab.com/ee/api/runners.html#reset-groups-runner-registration-token
+func (mock *GitlabApiMock) ResetGroupRunnersTokenHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	group, err := mock.getGroupForRunners(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	token := mock.gitlabMock.resetGroupRunnersToken(group)
+
+	writeJSON(responseWriter, http.StatusCreated, &gitlab.RunnerRegistrationToken{Token: &token})
+}
+
+// ResetProjectRunnersTokenHandler implements https://docs.gitlab.com/ee/api/runners.html#reset-projects-runner-registration-token
+func (mock *GitlabApiMock) ResetProjectRunnersTokenHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, err := mock.getProjectWithAccess(request, gitlab.MaintainerPermissions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	token := mock.gitlabMock.resetProjectRunnersToken(project)
+
+	writeJSON(responseWriter, http.StatusCreated, &gitlab.RunnerRegistrationToken{Token: &token})
+}
+
+// RegisterRunnerHandler implements https://docs.gitlab.com/ee/api/runners.html#create-a-runner
+func (mock *GitlabApiMock) RegisterRunnerHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	var options gitlab.RegisterNewRunnerOptions
+
+	err := decodeBody(request, &options)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	runner, err := mock.gitlabMock.RegisterRunner(&options)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusCreated, &registeredRunner{ID: runner.ID, Token: mock.gitlabMock.GetRunnerToken(runner)})
+}
+
+// runnerTokenRequest is the body runners authenticate with on the endpoints they use.
+type runnerTokenRequest struct {
+	Token      string      `json:"token"`
+	Info       *runnerInfo `json:"info"`
+	LastUpdate string      `json:"last_update"`
+}
+
+// getRequestingRunner resolves the runner by the authentication token in the body of the request.
+func (mock *GitlabApiMock) getRequestingRunner(request *http.Request) (*gitlab.RunnerDetails, *runnerTokenRequest, error) {
+	var body runnerTokenRequest
+
+	err := decodeBody(request, &body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	runner, isRunnerToken := mock.gitlabMock.runnerByToken(body.Token)
+	if !isRunnerToken {
+		return nil, nil, newAPIError(http.StatusForbidden, "403 Forbidden")
+	}
+
+	return runner, &body, nil
+}
+
+// VerifyRunnerHandler implements https://docs.gitlab.com/ee/api/runners.html#verify-authentication-for-a-registered-runner
+func (mock *GitlabApiMock) VerifyRunnerHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	runner, _, err := mock.getRequestingRunner(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, &registeredRunner{ID: runner.ID, Token: mock.gitlabMock.GetRunnerToken(runner)})
+}
+
+// DeleteRegisteredRunnerHandler implements https://docs.gitlab.com/ee/api/runners.html#delete-a-runner-by-authentication-token
+func (mock *GitlabApiMock) DeleteRegisteredRunnerHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	runner, _, err := mock.getRequestingRunner(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	mock.gitlabMock.removeRunner(runner)
+
+	responseWriter.WriteHeader(http.StatusNoContent)
+}
+
+// RequestJobHandler implements the endpoint runners poll for jobs, see
+// https://gitlab.com/gitlab-org/gitlab/-/blob/master/lib/api/ci/runner.rb
+// Runners without a job to run get 204.
+func (mock *GitlabApiMock) RequestJobHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	runner, body, err := mock.getRequestingRunner(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	ipAddress, _, _ := net.SplitHostPort(request.RemoteAddr)
+	mock.gitlabMock.contactRunner(runner, body.Info, ipAddress)
+
+	job, err := mock.gitlabMock.pickJob(runner)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	if job == nil {
+		responseWriter.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	payload, err := mock.gitlabMock.runnerJobPayload(runner, job, mock.baseURL)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusCreated, payload)
+}
+
+// UpdateRunnerJobHandler implements the endpoint runners report the state of a job with, see
+// https://gitlab.com/gitlab-org/gitlab/-/blob/master/lib/api/ci/runner.rb
+func (mock *GitlabApiMock) UpdateRunnerJobHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	var body struct {
+		Token         string `json:"token"`
+		State         string `json:"state"`
+		FailureReason string `json:"failure_reason"`
+		ExitCode      *int   `json:"exit_code"`
+	}
+
+	err := decodeBody(request, &body)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	job, isJobToken := mock.gitlabMock.jobByToken(body.Token)
+	if jobID, _ := strconv.Atoi(pathVar(request, "job_id")); !isJobToken || job.ID != jobID {
+		writeErrorMessage(responseWriter, http.StatusForbidden, "403 Forbidden")
+		return
+	}
+
+	err = mock.gitlabMock.updateRunnerJob(job, body.State, body.FailureReason)
+
+	responseWriter.Header().Set("Job-Status", job.Status)
+
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	responseWriter.WriteHeader(http.StatusOK)
+}
+
+// AppendRunnerJobTraceHandler implements the endpoint runners send the log of a running job to in chunks, see
+// https://gitlab.com/gitlab-org/gitlab/-/blob/master/lib/api/ci/runner.rb
+func (mock *GitlabApiMock) AppendRunnerJobTraceHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	job, err := mock.getRunnerJob(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	data, err := io.ReadAll(request.Body)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	length, err := mock.gitlabMock.appendRunnerTrace(job, request.Header.Get("Content-Range"), data)
+
+	responseWriter.Header().Set("Job-Status", job.Status)
+	responseWriter.Header().Set("Range", fmt.Sprintf("0-%d", length))
+
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	responseWriter.WriteHeader(http.StatusAccepted)
+}
+
+// DownloadRunnerJobArtifactsHandler implements the endpoint runners download the artifacts of the dependencies
+// of a job from. The CI_JOB_TOKEN of a running job of the same project grants access.
+func (mock *GitlabApiMock) DownloadRunnerJobArtifactsHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	runningJob, isJobToken := mock.gitlabMock.jobByToken(jobTokenFromRequest(request))
+	if !isJobToken || runningJob.Status != string(gitlab.Running) {
+		writeErrorMessage(responseWriter, http.StatusForbidden, "403 Forbidden")
+		return
+	}
+
+	project, projectExists := mock.gitlabMock.projects[runningJob.Pipeline.ProjectID]
+	if !projectExists {
+		writeErrorMessage(responseWriter, http.StatusNotFound, "404 Project Not Found")
+		return
+	}
+
+	jobID, _ := strconv.Atoi(pathVar(request, "job_id"))
+
+	job, err := mock.gitlabMock.getJob(project, jobID)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	archive, err := mock.gitlabMock.artifactsArchive(job)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeFile(responseWriter, archive.filename, archive.data)
+}
@@ -0,0 +1,267 @@
+package gitlabapimock
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// getPipelineScheduleFromRequest resolves the project and schedule of the id and schedule_id path variables.
+func (mock *GitlabApiMock) getPipelineScheduleFromRequest(request *http.Request) (*gitlab.Project, *gitlab.PipelineSchedule, error) {
+	project, err := mock.getProjectWithAccess(request, gitlab.DeveloperPermissions)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	scheduleID, _ := strconv.Atoi(pathVar(request, "schedule_id"))
+
+	schedule, err := mock.gitlabMock.getPipelineSchedule(project, scheduleID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return project, schedule, nil
+}
+
+// getOwnedPipelineScheduleFromRequest resolves the schedule like getPipelineScheduleFromRequest and checks that
+// the user owns it.
+func (mock *GitlabApiMock) getOwnedPipelineScheduleFromRequest(request *http.Request) (*gitlab.Project, *gitlab.PipelineSchedule, error) {
+	project, schedule, err := mock.getPipelineScheduleFromRequest(request)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !mock.gitlabMock.ownsPipelineSchedule(schedule, currentUser(request)) {
+		return nil, nil, newAPIError(http.StatusForbidden, "403 Forbidden")
+	}
+
+	return project, schedule, nil
+}
+
+// ListPipelineSchedulesHandler implements https://docs.gitlab.com/ee/api/pipeline_schedules.html#get-all-pipeline-schedules
+func (mock *GitlabApiMock) ListPipelineSchedulesHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, err := mock.getProjectWithAccess(request, gitlab.DeveloperPermissions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	scope := request.URL.Query().Get("scope")
+
+	schedules := []*gitlab.PipelineSchedule{}
+
+	for _, schedule := range mock.gitlabMock.GetPipelineSchedules(project) {
+		if (scope == "active" && !schedule.Active) || (scope == "inactive" && schedule.Active) {
+			continue
+		}
+
+		// The list leaves out the last pipeline and the variables of the schedules.
+		listedSchedule := *schedule
+		listedSchedule.LastPipeline = nil
+		listedSchedule.Variables = nil
+
+		schedules = append(schedules, &listedSchedule)
+	}
+
+	writeJSON(responseWriter, http.StatusOK, schedules)
+}
+
+// GetPipelineScheduleHandler implements https://docs.gitlab.com/ee/api/pipeline_schedules.html#get-a-single-pipeline-schedule
+func (mock *GitlabApiMock) GetPipelineScheduleHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	_, schedule, err := mock.getPipelineScheduleFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, schedule)
+}
+
+// ListPipelinesTriggeredByScheduleHandler implements https://docs.gitlab.com/ee/api/pipeline_schedules.html#get-all-pipelines-triggered-by-a-pipeline-schedule
+func (mock *GitlabApiMock) ListPipelinesTriggeredByScheduleHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	_, schedule, err := mock.getPipelineScheduleFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	pipelines := append([]*gitlab.Pipeline{}, mock.gitlabMock.GetSchedulePipelines(schedule)...)
+
+	writeJSON(responseWriter, http.StatusOK, pipelines)
+}
+
+// CreatePipelineScheduleHandler implements https://docs.gitlab.com/ee/api/pipeline_schedules.html#create-a-new-pipeline-schedule
+func (mock *GitlabApiMock) CreatePipelineScheduleHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, err := mock.getProjectWithAccess(request, gitlab.DeveloperPermissions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	var options gitlab.CreatePipelineScheduleOptions
+
+	err = decodeBody(request, &options)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	schedule, err := mock.gitlabMock.AddPipelineSchedule(project, &options, currentUser(request))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusCreated, schedule)
+}
+
+// EditPipelineScheduleHandler implements https://docs.gitlab.com/ee/api/pipeline_schedules.html#edit-a-pipeline-schedule
+func (mock *GitlabApiMock) EditPipelineScheduleHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	_, schedule, err := mock.getOwnedPipelineScheduleFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	var options gitlab.EditPipelineScheduleOptions
+
+	err = decodeBody(request, &options)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	err = mock.gitlabMock.editPipelineSchedule(schedule, &options)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, schedule)
+}
+
+// TakeOwnershipOfPipelineScheduleHandler implements https://docs.gitlab.com/ee/api/pipeline_schedules.html#take-ownership-of-a-pipeline-schedule
+func (mock *GitlabApiMock) TakeOwnershipOfPipelineScheduleHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, schedule, err := mock.getPipelineScheduleFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	user := currentUser(request)
+
+	if !mock.gitlabMock.hasAccess(project, user, gitlab.MaintainerPermissions) {
+		writeErrorMessage(responseWriter, http.StatusForbidden, "403 Forbidden")
+		return
+	}
+
+	mock.gitlabMock.takeOwnershipOfPipelineSchedule(schedule, user)
+
+	writeJSON(responseWriter, http.StatusCreated, schedule)
+}
+
+// DeletePipelineScheduleHandler implements https://docs.gitlab.com/ee/api/pipeline_schedules.html#delete-a-pipeline-schedule
+func (mock *GitlabApiMock) DeletePipelineScheduleHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, schedule, err := mock.getPipelineScheduleFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	user := currentUser(request)
+
+	if !mock.gitlabMock.ownsPipelineSchedule(schedule, user) && !mock.gitlabMock.hasAccess(project, user, gitlab.MaintainerPermissions) {
+		writeErrorMessage(responseWriter, http.StatusForbidden, "403 Forbidden")
+		return
+	}
+
+	mock.gitlabMock.removePipelineSchedule(project, schedule)
+
+	responseWriter.WriteHeader(http.StatusNoContent)
+}
+
+// RunPipelineScheduleHandler implements https://docs.gitlab.com/ee/api/pipeline_schedules.html#run-a-scheduled-pipeline-immediately
+// The pipeline is created right away instead of being enqueued.
+func (mock *GitlabApiMock) RunPipelineScheduleHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, schedule, err := mock.getPipelineScheduleFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	_, err = mock.gitlabMock.runPipelineSchedule(project, schedule)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusCreated, map[string]string{"message": "201 Created"})
+}
+
+// CreatePipelineScheduleVariableHandler implements https://docs.gitlab.com/ee/api/pipeline_schedules.html#create-a-new-pipeline-schedule-variable
+func (mock *GitlabApiMock) CreatePipelineScheduleVariableHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	_, schedule, err := mock.getOwnedPipelineScheduleFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	var options gitlab.CreatePipelineScheduleVariableOptions
+
+	err = decodeBody(request, &options)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	variable, err := mock.gitlabMock.addScheduleVariable(schedule, &options)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusCreated, variable)
+}
+
+// EditPipelineScheduleVariableHandler implements https://docs.gitlab.com/ee/api/pipeline_schedules.html#edit-a-pipeline-schedule-variable
+func (mock *GitlabApiMock) EditPipelineScheduleVariableHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	_, schedule, err := mock.getOwnedPipelineScheduleFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	var options gitlab.EditPipelineScheduleVariableOptions
+
+	err = decodeBody(request, &options)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	variable, err := mock.gitlabMock.editScheduleVariable(schedule, pathVar(request, "key"), &options)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, variable)
+}
+
+// DeletePipelineScheduleVariableHandler implements https://docs.gitlab.com/ee/api/pipeline_schedules.html#delete-a-pipeline-schedule-variable
+func (mock *GitlabApiMock) DeletePipelineScheduleVariableHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	_, schedule, err := mock.getOwnedPipelineScheduleFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	variable, err := mock.gitlabMock.removeScheduleVariable(schedule, pathVar(request, "key"))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusAccepted, variable)
+}
@@ -0,0 +1,36 @@
+package gitlabapimock
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// ListProjectSnippetsHandler implements https://docs.gitlab.com/ee/api/project_snippets.html#list-snippets
+func (mock *GitlabApiMock) ListProjectSnippetsHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, projectExists := mock.getProject(request)
+	if !projectExists {
+		writeErrorMessage(responseWriter, http.StatusNotFound, "404 Project Not Found")
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, mock.gitlabMock.getProjectSnippets(project))
+}
+
+// GetProjectSnippetHandler implements https://docs.gitlab.com/ee/api/project_snippets.html#single-snippet
+func (mock *GitlabApiMock) GetProjectSnippetHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, projectExists := mock.getProject(request)
+	if !projectExists {
+		writeErrorMessage(responseWriter, http.StatusNotFound, "404 Project Not Found")
+		return
+	}
+
+	snippetID, _ := strconv.Atoi(pathVar(request, "snippet_id"))
+
+	snippet, err := mock.gitlabMock.getProjectSnippet(project, snippetID)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, snippet)
+}
@@ -0,0 +1,247 @@
+package gitlabapimock
+
+import (
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+var versionSegmentPattern = regexp.MustCompile(`\d+|\D+`)
+
+// compareVersions compares tag names segment by segment, comparing numeric segments as numbers.
+func compareVersions(left string, right string) int {
+	leftSegments := versionSegmentPattern.FindAllString(left, -1)
+	rightSegments := versionSegmentPattern.FindAllString(right, -1)
+
+	for idx := 0; idx < len(leftSegments) && idx < len(rightSegments); idx++ {
+		leftNumber, leftErr := strconv.Atoi(leftSegments[idx])
+		rightNumber, rightErr := strconv.Atoi(rightSegments[idx])
+
+		if leftErr == nil && rightErr == nil {
+			if leftNumber != rightNumber {
+				return leftNumber - rightNumber
+			}
+			continue
+		}
+
+		if comparison := strings.Compare(leftSegments[idx], rightSegments[idx]); comparison != 0 {
+			return comparison
+		}
+	}
+
+	return len(leftSegments) - len(rightSegments)
+}
+
+// matchesSearch implements the search parameter of the tags and branches API: "^prefix", "suffix$" or a substring.
+func matchesSearch(name string, search string) bool {
+	switch {
+	case strings.HasPrefix(search, "^"):
+		return strings.HasPrefix(name, search[1:])
+	case strings.HasSuffix(search, "$"):
+		return strings.HasSuffix(name, search[:len(search)-1])
+	default:
+		return strings.Contains(name, search)
+	}
+}
+
+// ListProjectRepositoryTagsHandler implements https://docs.gitlab.com/ee/api/tags.html#list-project-repository-tags
+func (mock *GitlabApiMock) ListProjectRepositoryTagsHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, projectExists := mock.getProject(request)
+	if !projectExists {
+		writeErrorMessage(responseWriter, http.StatusNotFound, "404 Project Not Found")
+		return
+	}
+
+	var listTagsOptions gitlab.ListTagsOptions
+	decodeQuery(request, &listTagsOptions)
+
+	tags, err := mock.gitlabMock.GetTags(project)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	if listTagsOptions.Search != nil {
+		filteredTags := []*gitlab.Tag{}
+		for _, tag := range tags {
+			if matchesSearch(tag.Name, *listTagsOptions.Search) {
+				filteredTags = append(filteredTags, tag)
+			}
+		}
+		tags = filteredTags
+	}
+
+	orderBy := "updated"
+	if listTagsOptions.OrderBy != nil {
+		orderBy = *listTagsOptions.OrderBy
+	}
+
+	ascending := listTagsOptions.Sort != nil && *listTagsOptions.Sort == "asc"
+
+	sort.SliceStable(tags, func(i, j int) bool {
+		var comparison int
+
+		switch orderBy {
+		case "name":
+			comparison = strings.Compare(tags[i].Name, tags[j].Name)
+		case "version":
+			comparison = compareVersions(tags[i].Name, tags[j].Name)
+		default:
+			comparison = tags[i].Commit.CommittedDate.Compare(*tags[j].Commit.CommittedDate)
+		}
+
+		if ascending {
+			return comparison < 0
+		}
+
+		return comparison > 0
+	})
+
+	writeJSON(responseWriter, http.StatusOK, tags)
+}
+
+// GetSingleRepositoryTagHandler implements https://docs.gitlab.com/ee/api/tags.html#get-a-single-repository-tag
+func (mock *GitlabApiMock) GetSingleRepositoryTagHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, projectExists := mock.getProject(request)
+	if !projectExists {
+		writeErrorMessage(responseWriter, http.StatusNotFound, "404 Project Not Found")
+		return
+	}
+
+	tag, err := mock.gitlabMock.GetTag(project, pathVar(request, "tag_name"))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, tag)
+}
+
+// CreateNewTagHandler implements https://docs.gitlab.com/ee/api/tags.html#create-a-new-tag
+func (mock *GitlabApiMock) CreateNewTagHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, projectExists := mock.getProject(request)
+	if !projectExists {
+		writeErrorMessage(responseWriter, http.StatusNotFound, "404 Project Not Found")
+		return
+	}
+
+	var createTagOptions gitlab.CreateTagOptions
+	err := decodeBody(request, &createTagOptions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	tag, err := mock.gitlabMock.AddTag(project, valueOf(createTagOptions.TagName), valueOf(createTagOptions.Ref), valueOf(createTagOptions.Message), currentUser(request))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusCreated, tag)
+}
+
+// DeleteTagHandler implements https://docs.gitlab.com/ee/api/tags.html#delete-a-tag
+func (mock *GitlabApiMock) DeleteTagHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, projectExists := mock.getProject(request)
+	if !projectExists {
+		writeErrorMessage(responseWriter, http.StatusNotFound, "404 Project Not Found")
+		return
+	}
+
+	err := mock.gitlabMock.deleteTag(project, pathVar(request, "tag_name"), currentUser(request))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	responseWriter.WriteHeader(http.StatusNoContent)
+}
+
+// ListProtectedTagsHandler implements https://docs.gitlab.com/ee/api/protected_tags.html#list-protected-tags
+func (mock *GitlabApiMock) ListProtectedTagsHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, projectExists := mock.getProject(request)
+	if !projectExists {
+		writeErrorMessage(responseWriter, http.StatusNotFound, "404 Project Not Found")
+		return
+	}
+
+	protectedTags := mock.gitlabMock.protectedTags[project.ID]
+	if protectedTags == nil {
+		protectedTags = []*gitlab.ProtectedTag{}
+	}
+
+	writeJSON(responseWriter, http.StatusOK, protectedTags)
+}
+
+// GetProtectedTagHandler implements https://docs.gitlab.com/ee/api/protected_tags.html#get-a-single-protected-tag-or-wildcard-protected-tag
+func (mock *GitlabApiMock) GetProtectedTagHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, projectExists := mock.getProject(request)
+	if !projectExists {
+		writeErrorMessage(responseWriter, http.StatusNotFound, "404 Project Not Found")
+		return
+	}
+
+	protectedTag, err := mock.gitlabMock.getProtectedTag(project, pathVar(request, "name"))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, protectedTag)
+}
+
+// ProtectRepositoryTagsHandler implements https://docs.gitlab.com/ee/api/protected_tags.html#protect-repository-tags
+func (mock *GitlabApiMock) ProtectRepositoryTagsHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, err := mock.getProjectWithAccess(request, gitlab.MaintainerPermissions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	var protectRepositoryTagsOptions gitlab.ProtectRepositoryTagsOptions
+	err = decodeBody(request, &protectRepositoryTagsOptions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	if protectRepositoryTagsOptions.Name == nil {
+		writeErrorMessage(responseWriter, http.StatusBadRequest, "name is missing")
+		return
+	}
+
+	createAccessLevel := gitlab.MaintainerPermissions
+	if protectRepositoryTagsOptions.CreateAccessLevel != nil {
+		createAccessLevel = *protectRepositoryTagsOptions.CreateAccessLevel
+	}
+
+	protectedTag, err := mock.gitlabMock.AddProtectedTag(project, *protectRepositoryTagsOptions.Name, createAccessLevel)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusCreated, protectedTag)
+}
+
+// UnprotectRepositoryTagsHandler implements https://docs.gitlab.com/ee/api/protected_tags.html#unprotect-repository-tags
+func (mock *GitlabApiMock) UnprotectRepositoryTagsHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, err := mock.getProjectWithAccess(request, gitlab.MaintainerPermissions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	err = mock.gitlabMock.removeProtectedTag(project, pathVar(request, "name"))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	responseWriter.WriteHeader(http.StatusNoContent)
+}
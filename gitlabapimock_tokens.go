@@ -0,0 +1,495 @@
+package gitlabapimock
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// getPersonalAccessTokenFromRequest resolves the token of the token_id path variable or the token the request was
+// sent with for self. Users manage their own tokens, admins the ones of all users.
+func (mock *GitlabApiMock) getPersonalAccessTokenFromRequest(request *http.Request) (*gitlab.PersonalAccessToken, error) {
+	tokenID := pathVar(request, "token_id")
+	if tokenID == "" {
+		token := currentToken(request)
+		if token == nil {
+			return nil, errUnauthorized
+		}
+
+		return token, nil
+	}
+
+	tokenIDInteger, _ := strconv.Atoi(tokenID)
+
+	token, err := mock.gitlabMock.getPersonalAccessToken(tokenIDInteger)
+	if err != nil {
+		return nil, err
+	}
+
+	user := currentUser(request)
+	if !mock.gitlabMock.isAdmin(user) && (user == nil || user.ID != token.UserID) {
+		return nil, newAPIError(http.StatusNotFound, "404 Not Found")
+	}
+
+	return token, nil
+}
+
+// ListPersonalAccessTokensHandler implements https://docs.gitlab.com/ee/api/personal_access_tokens.html#list-personal-access-tokens
+func (mock *GitlabApiMock) ListPersonalAccessTokensHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	user := currentUser(request)
+	if user == nil && mock.gitlabMock.authenticationRequired() {
+		writeErrorMessage(responseWriter, http.StatusUnauthorized, "401 Unauthorized")
+		return
+	}
+
+	query := request.URL.Query()
+
+	userID, _ := strconv.Atoi(query.Get("user_id"))
+	if !mock.gitlabMock.isAdmin(user) {
+		userID = user.ID
+	}
+
+	search := strings.ToLower(query.Get("search"))
+	revoked, revokedErr := strconv.ParseBool(query.Get("revoked"))
+
+	tokens := []*gitlab.PersonalAccessToken{}
+
+	for _, token := range mock.gitlabMock.personalAccessTokens {
+		visibleToken := mock.gitlabMock.visiblePersonalAccessToken(token)
+
+		if (userID != 0 && token.UserID != userID) || (revokedErr == nil && token.Revoked != revoked) {
+			continue
+		}
+
+		if (query.Get("state") == "active" && !visibleToken.Active) || (query.Get("state") == "inactive" && visibleToken.Active) {
+			continue
+		}
+
+		if search != "" && !strings.Contains(strings.ToLower(token.Name), search) {
+			continue
+		}
+
+		tokens = append(tokens, visibleToken)
+	}
+
+	writeJSON(responseWriter, http.StatusOK, tokens)
+}
+
+// GetPersonalAccessTokenHandler implements https://docs.gitlab.com/ee/api/personal_access_tokens.html#get-single-personal-access-token
+func (mock *GitlabApiMock) GetPersonalAccessTokenHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	token, err := mock.getPersonalAccessTokenFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, mock.gitlabMock.visiblePersonalAccessToken(token))
+}
+
+// RotatePersonalAccessTokenHandler implements https://docs.gitlab.com/ee/api/personal_access_tokens.html#rotate-a-personal-access-token
+func (mock *GitlabApiMock) RotatePersonalAccessTokenHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	token, err := mock.getPersonalAccessTokenFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	var options gitlab.RotatePersonalAccessTokenOptions
+
+	err = decodeBody(request, &options)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	rotatedToken, err := mock.gitlabMock.rotatePersonalAccessToken(token, options.ExpiresAt)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, rotatedToken)
+}
+
+// RevokePersonalAccessTokenHandler implements https://docs.gitlab.com/ee/api/personal_access_tokens.html#revoke-a-personal-access-token
+func (mock *GitlabApiMock) RevokePersonalAccessTokenHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	token, err := mock.getPersonalAccessTokenFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	mock.gitlabMock.revokePersonalAccessToken(token)
+
+	responseWriter.WriteHeader(http.StatusNoContent)
+}
+
+// CreatePersonalAccessTokenHandler implements https://docs.gitlab.com/ee/api/users.html#create-a-personal-access-token
+func (mock *GitlabApiMock) CreatePersonalAccessTokenHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	if err := mock.checkAdmin(request); err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	user, userExists := mock.getUserFromRequest(request)
+	if !userExists {
+		writeErrorMessage(responseWriter, http.StatusNotFound, "404 User Not Found")
+		return
+	}
+
+	var options gitlab.CreatePersonalAccessTokenOptions
+
+	err := decodeBody(request, &options)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	if options.Name == nil || *options.Name == "" {
+		writeErrorMessage(responseWriter, http.StatusBadRequest, "name is missing")
+		return
+	}
+
+	scopes, err := validateTokenScopes(options.Scopes, personalAccessTokenScopes)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	if err := mock.gitlabMock.validateTokenExpiry(options.ExpiresAt); err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	token := mock.gitlabMock.createPersonalAccessToken(user, *options.Name, scopes, options.ExpiresAt)
+
+	writeJSON(responseWriter, http.StatusCreated, token)
+}
+
+// getProjectAccessTokenFromRequest resolves the project and token of the id and token_id path variables.
+func (mock *GitlabApiMock) getProjectAccessTokenFromRequest(request *http.Request) (*gitlab.Project, *gitlab.PersonalAccessToken, error) {
+	project, err := mock.getProjectWithAccess(request, gitlab.MaintainerPermissions)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tokenID, _ := strconv.Atoi(pathVar(request, "token_id"))
+
+	token, err := mock.gitlabMock.getProjectAccessToken(project, tokenID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return project, token, nil
+}
+
+// ListProjectAccessTokensHandler implements https://docs.gitlab.com/ee/api/project_access_tokens.html#list-project-access-tokens
+func (mock *GitlabApiMock) ListProjectAccessTokensHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, err := mock.getProjectWithAccess(request, gitlab.MaintainerPermissions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, mock.gitlabMock.projectAccessTokens(project))
+}
+
+// GetProjectAccessTokenHandler implements https://docs.gitlab.com/ee/api/project_access_tokens.html#get-a-project-access-token
+func (mock *GitlabApiMock) GetProjectAccessTokenHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, token, err := mock.getProjectAccessTokenFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, mock.gitlabMock.projectAccessToken(project, token))
+}
+
+// CreateProjectAccessTokenHandler implements https://docs.gitlab.com/ee/api/project_access_tokens.html#create-a-project-access-token
+func (mock *GitlabApiMock) CreateProjectAccessTokenHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, err := mock.getProjectWithAccess(request, gitlab.MaintainerPermissions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	var options gitlab.CreateProjectAccessTokenOptions
+
+	err = decodeBody(request, &options)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	token, err := mock.gitlabMock.CreateProjectAccessToken(project, &options, currentUser(request))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusCreated, token)
+}
+
+// RotateProjectAccessTokenHandler implements https://docs.gitlab.com/ee/api/project_access_tokens.html#rotate-a-project-access-token
+func (mock *GitlabApiMock) RotateProjectAccessTokenHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, token, err := mock.getProjectAccessTokenFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	var options gitlab.RotateProjectAccessTokenOptions
+
+	err = decodeBody(request, &options)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	rotatedToken, err := mock.gitlabMock.rotateProjectAccessToken(project, token, options.ExpiresAt)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, rotatedToken)
+}
+
+// RevokeProjectAccessTokenHandler implements https://docs.gitlab.com/ee/api/project_access_tokens.html#revoke-a-project-access-token
+func (mock *GitlabApiMock) RevokeProjectAccessTokenHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, token, err := mock.getProjectAccessTokenFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	err = mock.gitlabMock.revokeProjectAccessToken(project, token, currentUser(request))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	responseWriter.WriteHeader(http.StatusNoContent)
+}
+
+// getGroupForAccessTokens resolves the group of the request, its access tokens are managed by owners.
+func (mock *GitlabApiMock) getGroupForAccessTokens(request *http.Request) (*gitlab.Group, error) {
+	group, groupExists := mock.getGroup(request)
+	if !groupExists {
+		return nil, newAPIError(http.StatusNotFound, "404 Group Not Found")
+	}
+
+	if !mock.gitlabMock.hasGroupAccess(group, currentUser(request), gitlab.OwnerPermissions) {
+		return nil, newAPIError(http.StatusForbidden, "403 Forbidden")
+	}
+
+	return group, nil
+}
+
+// getGroupAccessTokenFromRequest resolves the group and token of the id and token_id path variables.
+func (mock *GitlabApiMock) getGroupAccessTokenFromRequest(request *http.Request) (*gitlab.Group, *gitlab.PersonalAccessToken, error) {
+	group, err := mock.getGroupForAccessTokens(request)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tokenID, _ := strconv.Atoi(pathVar(request, "token_id"))
+
+	token, err := mock.gitlabMock.getGroupAccessToken(group, tokenID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return group, token, nil
+}
+
+// ListGroupAccessTokensHandler implements https://docs.gitlab.com/ee/api/group_access_tokens.html#list-group-access-tokens
+func (mock *GitlabApiMock) ListGroupAccessTokensHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	group, err := mock.getGroupForAccessTokens(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, mock.gitlabMock.groupAccessTokens(group))
+}
+
+// GetGroupAccessTokenHandler implements https://docs.gitlab.com/ee/api/group_access_tokens.html#get-a-group-access-token
+func (mock *GitlabApiMock) GetGroupAccessTokenHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	group, token, err := mock.getGroupAccessTokenFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, mock.gitlabMock.groupAccessToken(group, token))
+}
+
+// CreateGroupAccessTokenHandler implements https://docs.gitlab.com/ee/api/group_access_tokens.html#create-a-group-access-token
+func (mock *GitlabApiMock) CreateGroupAccessTokenHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	group, err := mock.getGroupForAccessTokens(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	var options gitlab.CreateGroupAccessTokenOptions
+
+	err = decodeBody(request, &options)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	token, err := mock.gitlabMock.CreateGroupAccessToken(group, &options, currentUser(request))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusCreated, token)
+}
+
+// RotateGroupAccessTokenHandler implements https://docs.gitlab.com/ee/api/group_access_tokens.html#rotate-a-group-access-token
+func (mock *GitlabApiMock) RotateGroupAccessTokenHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	group, token, err := mock.getGroupAccessTokenFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	var options gitlab.RotateGroupAccessTokenOptions
+
+	err = decodeBody(request, &options)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	rotatedToken, err := mock.gitlabMock.rotateGroupAccessToken(group, token, options.ExpiresAt)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, rotatedToken)
+}
+
+// RevokeGroupAccessTokenHandler implements https://docs.gitlab.com/ee/api/group_access_tokens.html#revoke-a-group-access-token
+func (mock *GitlabApiMock) RevokeGroupAccessTokenHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	group, token, err := mock.getGroupAccessTokenFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	err = mock.gitlabMock.revokeGroupAccessToken(group, token)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	responseWriter.WriteHeader(http.StatusNoContent)
+}
+
+// getImpersonationTokenUser resolves the user of the id path variable, impersonation tokens are managed by admins.
+func (mock *GitlabApiMock) getImpersonationTokenUser(request *http.Request) (*gitlab.User, error) {
+	if err := mock.checkAdmin(request); err != nil {
+		return nil, err
+	}
+
+	user, userExists := mock.getUserFromRequest(request)
+	if !userExists {
+		return nil, newAPIError(http.StatusNotFound, "404 User Not Found")
+	}
+
+	return user, nil
+}
+
+// getImpersonationTokenFromRequest resolves the impersonation token of the id and token_id path variables.
+func (mock *GitlabApiMock) getImpersonationTokenFromRequest(request *http.Request) (*gitlab.PersonalAccessToken, error) {
+	user, err := mock.getImpersonationTokenUser(request)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenID, _ := strconv.Atoi(pathVar(request, "token_id"))
+
+	return mock.gitlabMock.getImpersonationToken(user, tokenID)
+}
+
+// ListImpersonationTokensHandler implements https://docs.gitlab.com/ee/api/user_tokens.html#list-all-impersonation-tokens-for-a-user
+func (mock *GitlabApiMock) ListImpersonationTokensHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	user, err := mock.getImpersonationTokenUser(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	state := request.URL.Query().Get("state")
+
+	tokens := []*gitlab.ImpersonationToken{}
+
+	for _, token := range mock.gitlabMock.userImpersonationTokens(user) {
+		impersonationToken := mock.gitlabMock.impersonationToken(token)
+
+		if (state == "active" && !impersonationToken.Active) || (state == "inactive" && impersonationToken.Active) {
+			continue
+		}
+
+		tokens = append(tokens, impersonationToken)
+	}
+
+	writeJSON(responseWriter, http.StatusOK, tokens)
+}
+
+// GetImpersonationTokenHandler implements https://docs.gitlab.com/ee/api/user_tokens.html#get-an-impersonation-token-for-a-user
+func (mock *GitlabApiMock) GetImpersonationTokenHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	token, err := mock.getImpersonationTokenFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, mock.gitlabMock.impersonationToken(token))
+}
+
+// CreateImpersonationTokenHandler implements https://docs.gitlab.com/ee/api/user_tokens.html#create-an-impersonation-token
+func (mock *GitlabApiMock) CreateImpersonationTokenHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	user, err := mock.getImpersonationTokenUser(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	var options gitlab.CreateImpersonationTokenOptions
+
+	err = decodeBody(request, &options)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	token, err := mock.gitlabMock.createImpersonationToken(user, &options)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusCreated, token)
+}
+
+// RevokeImpersonationTokenHandler implements https://docs.gitlab.com/ee/api/user_tokens.html#revoke-an-impersonation-token
+func (mock *GitlabApiMock) RevokeImpersonationTokenHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	token, err := mock.getImpersonationTokenFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	mock.gitlabMock.revokePersonalAccessToken(token)
+
+	responseWriter.WriteHeader(http.StatusNoContent)
+}
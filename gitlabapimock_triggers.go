@@ -0,0 +1,192 @@
+package gitlabapimock
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// getPipelineTriggerFromRequest resolves the project and trigger of the id and trigger_id path variables. Triggers
+// are managed by maintainers.
+func (mock *GitlabApiMock) getPipelineTriggerFromRequest(request *http.Request) (*gitlab.Project, *gitlab.PipelineTrigger, error) {
+	project, err := mock.getProjectWithAccess(request, gitlab.MaintainerPermissions)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	triggerID, _ := strconv.Atoi(pathVar(request, "trigger_id"))
+
+	trigger, err := mock.gitlabMock.getPipelineTrigger(project, triggerID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return project, trigger, nil
+}
+
+// ListPipelineTriggersHandler implements https://docs.gitlab.com/ee/api/pipeline_triggers.html#list-project-trigger-tokens
+func (mock *GitlabApiMock) ListPipelineTriggersHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, err := mock.getProjectWithAccess(request, gitlab.MaintainerPermissions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	triggers := []*gitlab.PipelineTrigger{}
+	for _, trigger := range mock.gitlabMock.pipelineTriggers[project.ID] {
+		triggers = append(triggers, mock.gitlabMock.visiblePipelineTrigger(trigger, currentUser(request)))
+	}
+
+	writeJSON(responseWriter, http.StatusOK, triggers)
+}
+
+// GetPipelineTriggerHandler implements https://docs.gitlab.com/ee/api/pipeline_triggers.html#get-trigger-token-details
+func (mock *GitlabApiMock) GetPipelineTriggerHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	_, trigger, err := mock.getPipelineTriggerFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, mock.gitlabMock.visiblePipelineTrigger(trigger, currentUser(request)))
+}
+
+// AddPipelineTriggerHandler implements https://docs.gitlab.com/ee/api/pipeline_triggers.html#create-a-trigger-token
+func (mock *GitlabApiMock) AddPipelineTriggerHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, err := mock.getProjectWithAccess(request, gitlab.MaintainerPermissions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	var options gitlab.AddPipelineTriggerOptions
+
+	err = decodeBody(request, &options)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	if options.Description == nil {
+		writeErrorMessage(responseWriter, http.StatusBadRequest, "description is missing")
+		return
+	}
+
+	trigger := mock.gitlabMock.AddPipelineTrigger(project, *options.Description, currentUser(request))
+
+	writeJSON(responseWriter, http.StatusCreated, trigger)
+}
+
+// EditPipelineTriggerHandler implements https://docs.gitlab.com/ee/api/pipeline_triggers.html#update-a-project-trigger-token
+func (mock *GitlabApiMock) EditPipelineTriggerHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	_, trigger, err := mock.getPipelineTriggerFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	var options gitlab.EditPipelineTriggerOptions
+
+	err = decodeBody(request, &options)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	mock.gitlabMock.editPipelineTrigger(trigger, &options)
+
+	writeJSON(responseWriter, http.StatusOK, mock.gitlabMock.visiblePipelineTrigger(trigger, currentUser(request)))
+}
+
+// TakeOwnershipOfPipelineTriggerHandler implements the take_ownership endpoint of trigger tokens, which GitLab
+// removed in 16.0 but clients still call.
+func (mock *GitlabApiMock) TakeOwnershipOfPipelineTriggerHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	_, trigger, err := mock.getPipelineTriggerFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	mock.gitlabMock.takeOwnershipOfPipelineTrigger(trigger, currentUser(request))
+
+	writeJSON(responseWriter, http.StatusOK, trigger)
+}
+
+// DeletePipelineTriggerHandler implements https://docs.gitlab.com/ee/api/pipeline_triggers.html#remove-a-project-trigger-token
+func (mock *GitlabApiMock) DeletePipelineTriggerHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, trigger, err := mock.getPipelineTriggerFromRequest(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	mock.gitlabMock.removePipelineTrigger(project, trigger)
+
+	responseWriter.WriteHeader(http.StatusNoContent)
+}
+
+// RunPipelineTriggerHandler implements https://docs.gitlab.com/ee/api/pipeline_triggers.html#trigger-a-pipeline-with-a-token
+// The parameters are accepted as JSON or as form, with variables given as variables[KEY]=value. The ref may
+// also be part of the path, as for webhooks.
+func (mock *GitlabApiMock) RunPipelineTriggerHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, projectExists := mock.getProject(request)
+	if !projectExists {
+		writeErrorMessage(responseWriter, http.StatusNotFound, "404 Project Not Found")
+		return
+	}
+
+	options := gitlab.RunPipelineTriggerOptions{Variables: map[string]string{}}
+
+	if strings.HasPrefix(request.Header.Get("Content-Type"), "application/json") {
+		err := decodeBody(request, &options)
+		if err != nil {
+			writeError(responseWriter, err)
+			return
+		}
+	} else {
+		err := request.ParseMultipartForm(maxArtifactsUploadMemory)
+		if err != nil && !errors.Is(err, http.ErrNotMultipart) {
+			writeErrorMessage(responseWriter, http.StatusBadRequest, "400 Bad request - "+err.Error())
+			return
+		}
+
+		for key, values := range request.Form {
+			if name, isVariable := strings.CutPrefix(key, "variables["); isVariable && strings.HasSuffix(name, "]") {
+				options.Variables[strings.TrimSuffix(name, "]")] = values[0]
+			}
+		}
+
+		if token := request.FormValue("token"); token != "" {
+			options.Token = &token
+		}
+
+		if ref := request.FormValue("ref"); ref != "" {
+			options.Ref = &ref
+		}
+	}
+
+	if ref := pathVar(request, "ref"); ref != "" {
+		options.Ref = &ref
+	}
+
+	if options.Token == nil {
+		writeErrorMessage(responseWriter, http.StatusBadRequest, "token is missing")
+		return
+	}
+
+	if options.Ref == nil {
+		writeErrorMessage(responseWriter, http.StatusBadRequest, "ref is missing")
+		return
+	}
+
+	pipeline, err := mock.gitlabMock.triggerPipeline(project, *options.Token, *options.Ref, options.Variables)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusCreated, pipeline)
+}
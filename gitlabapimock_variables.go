@@ -0,0 +1,393 @@
+package gitlabapimock
+
+import (
+	"net/http"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// environmentScopeFilter returns the filter[environment_scope] parameter of the request.
+func environmentScopeFilter(request *http.Request, filter *gitlab.VariableFilter) string {
+	if filter != nil && filter.EnvironmentScope != "" {
+		return filter.EnvironmentScope
+	}
+
+	return request.URL.Query().Get("filter[environment_scope]")
+}
+
+// checkAdmin rejects requests of users that may not use the admin API.
+func (mock *GitlabApiMock) checkAdmin(request *http.Request) error {
+	if !mock.gitlabMock.isAdmin(currentUser(request)) {
+		return newAPIError(http.StatusForbidden, "403 Forbidden")
+	}
+
+	return nil
+}
+
+// getGroupForVariables resolves the group of the request and checks that the user may manage its variables.
+func (mock *GitlabApiMock) getGroupForVariables(request *http.Request) (*gitlab.Group, error) {
+	group, groupExists := mock.getGroup(request)
+	if !groupExists {
+		return nil, newAPIError(http.StatusNotFound, "404 Group Not Found")
+	}
+
+	if !mock.gitlabMock.hasGroupAccess(group, currentUser(request), gitlab.MaintainerPermissions) {
+		return nil, newAPIError(http.StatusForbidden, "403 Forbidden")
+	}
+
+	return group, nil
+}
+
+// ListInstanceVariablesHandler implements https://docs.gitlab.com/ee/api/instance_level_ci_variables.html#list-all-instance-variables
+func (mock *GitlabApiMock) ListInstanceVariablesHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	err := mock.checkAdmin(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	variables := []*gitlab.InstanceVariable{}
+	for _, variable := range mock.gitlabMock.instanceVariables {
+		variables = append(variables, toInstanceVariable(variable))
+	}
+
+	writeJSON(responseWriter, http.StatusOK, variables)
+}
+
+// GetInstanceVariableHandler implements https://docs.gitlab.com/ee/api/instance_level_ci_variables.html#show-instance-variable-details
+func (mock *GitlabApiMock) GetInstanceVariableHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	err := mock.checkAdmin(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	variable, err := findVariable(mock.gitlabMock.instanceVariables, pathVar(request, "key"), "")
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, toInstanceVariable(variable))
+}
+
+// CreateInstanceVariableHandler implements https://docs.gitlab.com/ee/api/instance_level_ci_variables.html#create-instance-variable
+func (mock *GitlabApiMock) CreateInstanceVariableHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	err := mock.checkAdmin(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	var createInstanceVariableOptions gitlab.CreateInstanceVariableOptions
+	err = decodeBody(request, &createInstanceVariableOptions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	variable, err := mock.gitlabMock.AddInstanceVariable(&createInstanceVariableOptions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusCreated, variable)
+}
+
+// UpdateInstanceVariableHandler implements https://docs.gitlab.com/ee/api/instance_level_ci_variables.html#update-instance-variable
+func (mock *GitlabApiMock) UpdateInstanceVariableHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	err := mock.checkAdmin(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	var updateInstanceVariableOptions gitlab.UpdateInstanceVariableOptions
+	err = decodeBody(request, &updateInstanceVariableOptions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	variable, err := findVariable(mock.gitlabMock.instanceVariables, pathVar(request, "key"), "")
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	variable, err = updateVariable(mock.gitlabMock.instanceVariables, variable, &gitlab.CreateProjectVariableOptions{
+		Value:        updateInstanceVariableOptions.Value,
+		Description:  updateInstanceVariableOptions.Description,
+		Masked:       updateInstanceVariableOptions.Masked,
+		Protected:    updateInstanceVariableOptions.Protected,
+		Raw:          updateInstanceVariableOptions.Raw,
+		VariableType: updateInstanceVariableOptions.VariableType,
+	})
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, toInstanceVariable(variable))
+}
+
+// DeleteInstanceVariableHandler implements https://docs.gitlab.com/ee/api/instance_level_ci_variables.html#remove-instance-variable
+func (mock *GitlabApiMock) DeleteInstanceVariableHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	err := mock.checkAdmin(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	variable, err := findVariable(mock.gitlabMock.instanceVariables, pathVar(request, "key"), "")
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	removeVariable(&mock.gitlabMock.instanceVariables, variable)
+
+	responseWriter.WriteHeader(http.StatusNoContent)
+}
+
+// ListGroupVariablesHandler implements https://docs.gitlab.com/ee/api/group_level_variables.html#list-group-variables
+func (mock *GitlabApiMock) ListGroupVariablesHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	group, err := mock.getGroupForVariables(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	variables := []*gitlab.GroupVariable{}
+	for _, variable := range mock.gitlabMock.groupVariables[group.ID] {
+		variables = append(variables, toGroupVariable(variable))
+	}
+
+	writeJSON(responseWriter, http.StatusOK, variables)
+}
+
+// GetGroupVariableHandler implements https://docs.gitlab.com/ee/api/group_level_variables.html#show-variable-details
+func (mock *GitlabApiMock) GetGroupVariableHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	group, err := mock.getGroupForVariables(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	variable, err := findVariable(mock.gitlabMock.groupVariables[group.ID], pathVar(request, "key"), environmentScopeFilter(request, nil))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, toGroupVariable(variable))
+}
+
+// CreateGroupVariableHandler implements https://docs.gitlab.com/ee/api/group_level_variables.html#create-variable
+func (mock *GitlabApiMock) CreateGroupVariableHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	group, err := mock.getGroupForVariables(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	var createGroupVariableOptions gitlab.CreateGroupVariableOptions
+	err = decodeBody(request, &createGroupVariableOptions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	variable, err := mock.gitlabMock.AddGroupVariable(group, &createGroupVariableOptions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusCreated, variable)
+}
+
+// UpdateGroupVariableHandler implements https://docs.gitlab.com/ee/api/group_level_variables.html#update-variable
+func (mock *GitlabApiMock) UpdateGroupVariableHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	group, err := mock.getGroupForVariables(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	var updateGroupVariableOptions struct {
+		gitlab.UpdateGroupVariableOptions
+		Filter *gitlab.VariableFilter `json:"filter,omitempty"`
+	}
+	err = decodeBody(request, &updateGroupVariableOptions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	variables := mock.gitlabMock.groupVariables[group.ID]
+
+	variable, err := findVariable(variables, pathVar(request, "key"), environmentScopeFilter(request, updateGroupVariableOptions.Filter))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	variable, err = updateVariable(variables, variable, &gitlab.CreateProjectVariableOptions{
+		Value:            updateGroupVariableOptions.Value,
+		Description:      updateGroupVariableOptions.Description,
+		EnvironmentScope: updateGroupVariableOptions.EnvironmentScope,
+		Masked:           updateGroupVariableOptions.Masked,
+		Protected:        updateGroupVariableOptions.Protected,
+		Raw:              updateGroupVariableOptions.Raw,
+		VariableType:     updateGroupVariableOptions.VariableType,
+	})
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, toGroupVariable(variable))
+}
+
+// DeleteGroupVariableHandler implements https://docs.gitlab.com/ee/api/group_level_variables.html#remove-variable
+func (mock *GitlabApiMock) DeleteGroupVariableHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	group, err := mock.getGroupForVariables(request)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	variables := mock.gitlabMock.groupVariables[group.ID]
+
+	variable, err := findVariable(variables, pathVar(request, "key"), environmentScopeFilter(request, nil))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	removeVariable(&variables, variable)
+	mock.gitlabMock.groupVariables[group.ID] = variables
+
+	responseWriter.WriteHeader(http.StatusNoContent)
+}
+
+// ListProjectVariablesHandler implements https://docs.gitlab.com/ee/api/project_level_variables.html#list-project-variables
+func (mock *GitlabApiMock) ListProjectVariablesHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, err := mock.getProjectWithAccess(request, gitlab.MaintainerPermissions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	variables := mock.gitlabMock.projectVariables[project.ID]
+	if variables == nil {
+		variables = []*gitlab.ProjectVariable{}
+	}
+
+	writeJSON(responseWriter, http.StatusOK, variables)
+}
+
+// GetProjectVariableHandler implements https://docs.gitlab.com/ee/api/project_level_variables.html#get-a-single-variable
+func (mock *GitlabApiMock) GetProjectVariableHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, err := mock.getProjectWithAccess(request, gitlab.MaintainerPermissions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	variable, err := findVariable(mock.gitlabMock.projectVariables[project.ID], pathVar(request, "key"), environmentScopeFilter(request, nil))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, variable)
+}
+
+// CreateProjectVariableHandler implements https://docs.gitlab.com/ee/api/project_level_variables.html#create-a-variable
+func (mock *GitlabApiMock) CreateProjectVariableHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, err := mock.getProjectWithAccess(request, gitlab.MaintainerPermissions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	var createProjectVariableOptions gitlab.CreateProjectVariableOptions
+	err = decodeBody(request, &createProjectVariableOptions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	variable, err := mock.gitlabMock.AddProjectVariable(project, &createProjectVariableOptions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusCreated, variable)
+}
+
+// UpdateProjectVariableHandler implements https://docs.gitlab.com/ee/api/project_level_variables.html#update-a-variable
+func (mock *GitlabApiMock) UpdateProjectVariableHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, err := mock.getProjectWithAccess(request, gitlab.MaintainerPermissions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	var updateProjectVariableOptions gitlab.UpdateProjectVariableOptions
+	err = decodeBody(request, &updateProjectVariableOptions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	variables := mock.gitlabMock.projectVariables[project.ID]
+
+	variable, err := findVariable(variables, pathVar(request, "key"), environmentScopeFilter(request, updateProjectVariableOptions.Filter))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	variable, err = updateVariable(variables, variable, &gitlab.CreateProjectVariableOptions{
+		Value:            updateProjectVariableOptions.Value,
+		Description:      updateProjectVariableOptions.Description,
+		EnvironmentScope: updateProjectVariableOptions.EnvironmentScope,
+		Masked:           updateProjectVariableOptions.Masked,
+		Protected:        updateProjectVariableOptions.Protected,
+		Raw:              updateProjectVariableOptions.Raw,
+		VariableType:     updateProjectVariableOptions.VariableType,
+	})
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	writeJSON(responseWriter, http.StatusOK, variable)
+}
+
+// DeleteProjectVariableHandler implements https://docs.gitlab.com/ee/api/project_level_variables.html#delete-a-variable
+func (mock *GitlabApiMock) DeleteProjectVariableHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	project, err := mock.getProjectWithAccess(request, gitlab.MaintainerPermissions)
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	variables := mock.gitlabMock.projectVariables[project.ID]
+
+	variable, err := findVariable(variables, pathVar(request, "key"), environmentScopeFilter(request, nil))
+	if err != nil {
+		writeError(responseWriter, err)
+		return
+	}
+
+	removeVariable(&variables, variable)
+	mock.gitlabMock.projectVariables[project.ID] = variables
+
+	responseWriter.WriteHeader(http.StatusNoContent)
+}
@@ -3,11 +3,44 @@ package gitlabapimock
 import (
 	"errors"
 	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/xanzy/go-gitlab"
 )
 
+// apiError is returned by the GitlabMock for errors the API has to report with a specific status code and
+// message, e.g. a 404 for unknown resources or a 400 for invalid options.
+type apiError struct {
+	statusCode int
+	message    interface{}
+}
+
+func newAPIError(statusCode int, message interface{}) error {
+	return &apiError{
+		statusCode: statusCode,
+		message:    message,
+	}
+}
+
+func (err *apiError) Error() string {
+	return fmt.Sprint(err.message)
+}
+
+// valueOf dereferences an optional option value, returning the zero value for nil.
+func valueOf[T any](value *T) T {
+	if value == nil {
+		var zero T
+		return zero
+	}
+
+	return *value
+}
+
 // GitlabMock
 // TODO: Add error handling to Add methods
 type GitlabMock struct {
@@ -16,10 +49,155 @@ type GitlabMock struct {
 	projectIds       atomic.Int32
 	projectMemberIds atomic.Int32
 
+	personalAccessTokenIds atomic.Int32
+	protectedTagAccessIds  atomic.Int32
+	releaseLinkIds         atomic.Int32
+	mergeRequestIds        atomic.Int32
+	approvalRuleIds        atomic.Int32
+	issueIds               atomic.Int32
+	snippetIds             atomic.Int32
+	noteIds                atomic.Int32
+	labelIds               atomic.Int32
+	milestoneIds           atomic.Int32
+	milestoneEventIds      atomic.Int32
+	iterationCadenceIds    atomic.Int32
+	iterationIds           atomic.Int32
+	pipelineIds            atomic.Int32
+	jobIds                 atomic.Int32
+	runnerIds              atomic.Int32
+	pipelineTriggerIds     atomic.Int32
+	pipelineScheduleIds    atomic.Int32
+	hookIds                atomic.Int32
+
+	// iids holds the last internal ID per kind of resource and project, see nextIID.
+	iids map[string]int
+
 	users          []*gitlab.User
 	groups         []*gitlab.Group
 	projects       map[int]*gitlab.Project
 	projectMembers map[int][]*gitlab.ProjectMember
+	groupMembers   map[int][]*gitlab.GroupMember
+
+	personalAccessTokens []*gitlab.PersonalAccessToken
+	protectedTags        map[int][]*gitlab.ProtectedTag
+	releases             map[int][]*release
+
+	// impersonationTokens flags the personal access tokens admins created to act as their user, by token ID.
+	impersonationTokens map[int]bool
+
+	mergeRequests         map[int][]*gitlab.MergeRequest
+	mergeRequestApprovals map[int][]*gitlab.BasicUser
+
+	// The approval rules of merge requests are keyed by merge request ID. They are copied from the
+	// project rules when the merge request is opened.
+	projectApprovalRules                 map[int][]*gitlab.ProjectApprovalRule
+	mergeRequestApprovalRules            map[int][]*gitlab.MergeRequestApprovalRule
+	mergeRequestApprovalRulesOverwritten map[int]bool
+
+	issues   map[int][]*gitlab.Issue
+	snippets map[int][]*gitlab.Snippet
+
+	// Labels are keyed by project or group ID, subscriptions by label ID.
+	projectLabels      map[int][]*gitlab.Label
+	groupLabels        map[int][]*gitlab.Label
+	labelSubscriptions map[int][]int
+
+	// Milestones are keyed by project or group ID, burndown events by milestone ID.
+	projectMilestones map[int][]*gitlab.Milestone
+	groupMilestones   map[int][]*gitlab.Milestone
+	milestoneEvents   []*milestoneEvent
+	burndownEvents    map[int][]*gitlab.BurndownChartEvent
+	iterationCadences map[int][]*IterationCadence
+
+	// Pipelines are keyed by project ID, their variables and jobs by pipeline ID. Retried jobs stay in the
+	// pipeline and are flagged by job ID.
+	pipelines         map[int][]*gitlab.Pipeline
+	pipelineVariables map[int][]*gitlab.PipelineVariable
+	pipelineJobSpecs  map[int][]PipelineJob
+	jobs              map[int][]*gitlab.Job
+	jobSpecs          map[int]PipelineJob
+	retriedJobs       map[int]bool
+
+	// Artifacts, traces and tokens of jobs are keyed by job ID.
+	jobArtifacts map[int][]*jobArtifact
+	jobTraces    map[int][]byte
+	jobTokens    map[int]string
+
+	// Triggers and schedules are keyed by project ID, the pipelines a schedule ran by schedule ID. Schedules
+	// run when the clock of the mock passes their next run, see SetClock.
+	pipelineTriggers  map[int][]*gitlab.PipelineTrigger
+	pipelineSchedules map[int][]*gitlab.PipelineSchedule
+	schedulePipelines map[int][]*gitlab.Pipeline
+	clock             time.Time
+
+	// Runners are assigned to projects and groups by their details, their authentication tokens are keyed by
+	// runner ID. Group and project registration tokens are kept in RunnersToken.
+	runners                  []*gitlab.RunnerDetails
+	runnerTokens             map[int]string
+	runnersRegistrationToken string
+
+	// CI/CD variables are keyed by group or project ID. All levels share the ProjectVariable type, instance
+	// variables always have the environment scope *.
+	instanceVariables []*gitlab.ProjectVariable
+	groupVariables    map[int][]*gitlab.ProjectVariable
+	projectVariables  map[int][]*gitlab.ProjectVariable
+
+	// discussions holds the discussions per noteable, see noteable.key.
+	discussions map[string][]*gitlab.Discussion
+
+	// Webhooks are keyed by project or group ID, their secret tokens by hook ID. Deliveries are sent in the
+	// background, hookQueues holds the last delivery of every hook to keep them in order.
+	projectHooks   map[int][]*projectHook
+	groupHooks     map[int][]*gitlab.GroupHook
+	systemHooks    []*gitlab.Hook
+	hookTokens     map[int]string
+	hookClient     *http.Client
+	hookRetries    int
+	hookRetryDelay time.Duration
+	hooksMutex     sync.Mutex
+	hookQueues     map[int]chan struct{}
+	pendingHooks   sync.WaitGroup
+
+	// hookDeliveries logs the deliveries per hook ID, it is written by the delivery goroutines under hooksMutex.
+	hookDeliveryIds atomic.Int32
+	hookDeliveries  map[int][]*WebhookDelivery
+
+	activityEventIds atomic.Int32
+	activityEvents   []*activityEvent
+	auditEventIds    atomic.Int32
+	auditEvents      []*gitlab.AuditEvent
+
+	// SSH keys of users and deploy keys share their fingerprints, the projects deploy keys are enabled in are
+	// keyed by project ID.
+	sshKeyIds         atomic.Int32
+	sshKeys           []*sshKey
+	projectDeployKeys map[int][]*deployKeyProject
+	gpgKeyIds         atomic.Int32
+	gpgKeys           []*gpgKey
+
+	// Deploy tokens belong to a project or a group, revoked tokens are kept. registryTokenKey signs the tokens
+	// of the container registry, see RegistryAuthHandler.
+	deployTokenIds   atomic.Int32
+	deployTokens     []*deployToken
+	registryTokenKey []byte
+
+	// OAuth applications with their authorization codes and tokens. The signed in user authorizes applications if
+	// the consent approves it, see SignIn and SetOAuthConsent.
+	applicationIds atomic.Int32
+	applications   []*oauthApplication
+	oauthGrants    []*oauthGrant
+	oauthTokens    []*oauthToken
+	userPasswords  map[int]string
+	sessionUser    *gitlab.User
+	oauthConsent   OAuthConsent
+
+	// eventSubscribers receive the published events, see Subscribe.
+	eventsMutex        sync.Mutex
+	eventSubscriberIds int
+	eventSubscribers   []*eventSubscriber
+
+	repositoriesDir string
+	repositories    map[int]*repository
 }
 
 func NewGitlabMock() *GitlabMock {
@@ -27,9 +205,103 @@ func NewGitlabMock() *GitlabMock {
 		groups:         make([]*gitlab.Group, 0),
 		projects:       make(map[int]*gitlab.Project),
 		projectMembers: make(map[int][]*gitlab.ProjectMember),
+		groupMembers:   make(map[int][]*gitlab.GroupMember),
+		protectedTags:  make(map[int][]*gitlab.ProtectedTag),
+		releases:       make(map[int][]*release),
+		iids:           make(map[string]int),
+
+		impersonationTokens: make(map[int]bool),
+
+		mergeRequests:         make(map[int][]*gitlab.MergeRequest),
+		mergeRequestApprovals: make(map[int][]*gitlab.BasicUser),
+
+		projectApprovalRules:                 make(map[int][]*gitlab.ProjectApprovalRule),
+		mergeRequestApprovalRules:            make(map[int][]*gitlab.MergeRequestApprovalRule),
+		mergeRequestApprovalRulesOverwritten: make(map[int]bool),
+
+		issues:      make(map[int][]*gitlab.Issue),
+		snippets:    make(map[int][]*gitlab.Snippet),
+		discussions: make(map[string][]*gitlab.Discussion),
+
+		projectLabels:      make(map[int][]*gitlab.Label),
+		groupLabels:        make(map[int][]*gitlab.Label),
+		labelSubscriptions: make(map[int][]int),
+
+		projectMilestones: make(map[int][]*gitlab.Milestone),
+		groupMilestones:   make(map[int][]*gitlab.Milestone),
+		burndownEvents:    make(map[int][]*gitlab.BurndownChartEvent),
+		iterationCadences: make(map[int][]*IterationCadence),
+
+		pipelines:         make(map[int][]*gitlab.Pipeline),
+		pipelineVariables: make(map[int][]*gitlab.PipelineVariable),
+		pipelineJobSpecs:  make(map[int][]PipelineJob),
+		jobs:              make(map[int][]*gitlab.Job),
+		jobSpecs:          make(map[int]PipelineJob),
+		retriedJobs:       make(map[int]bool),
+
+		jobArtifacts: make(map[int][]*jobArtifact),
+		jobTraces:    make(map[int][]byte),
+		jobTokens:    make(map[int]string),
+
+		pipelineTriggers:  make(map[int][]*gitlab.PipelineTrigger),
+		pipelineSchedules: make(map[int][]*gitlab.PipelineSchedule),
+		schedulePipelines: make(map[int][]*gitlab.Pipeline),
+
+		runnerTokens:             make(map[int]string),
+		runnersRegistrationToken: newToken(runnerRegistrationTokenPrefix),
+
+		groupVariables:   make(map[int][]*gitlab.ProjectVariable),
+		projectVariables: make(map[int][]*gitlab.ProjectVariable),
+
+		projectHooks:   make(map[int][]*projectHook),
+		groupHooks:     make(map[int][]*gitlab.GroupHook),
+		hookTokens:     make(map[int]string),
+		hookClient:     &http.Client{Timeout: hookTimeout},
+		hookRetries:    defaultHookRetries,
+		hookRetryDelay: defaultHookRetryDelay,
+		hookQueues:     make(map[int]chan struct{}),
+		hookDeliveries: make(map[int][]*WebhookDelivery),
+
+		projectDeployKeys: make(map[int][]*deployKeyProject),
+		registryTokenKey:  []byte(newToken("")),
+		userPasswords:     make(map[int]string),
+
+		repositories: make(map[int]*repository),
 	}
 }
 
+// Close waits for pending webhook deliveries and removes the Git repositories created for the mock projects.
+func (mock *GitlabMock) Close() error {
+	mock.WaitForWebhooks()
+
+	if mock.repositoriesDir == "" {
+		return nil
+	}
+
+	return os.RemoveAll(mock.repositoriesDir)
+}
+
+// now returns the time of the clock of the mock, which follows the real time until it is set.
+func (mock *GitlabMock) now() time.Time {
+	if mock.clock.IsZero() {
+		return time.Now()
+	}
+
+	return mock.clock
+}
+
+// SetClock sets the clock of the mock. Pipeline schedules only run from the clock, the schedules that are
+// due at the new time run right away.
+func (mock *GitlabMock) SetClock(now time.Time) {
+	mock.clock = now
+	mock.runDueSchedules()
+}
+
+// AdvanceClock moves the clock of the mock forward, starting from the real time if the clock is not set yet.
+func (mock *GitlabMock) AdvanceClock(duration time.Duration) {
+	mock.SetClock(mock.now().Add(duration))
+}
+
 func (mock *GitlabMock) AddUser(name string, username string, email string) (*gitlab.User, error) {
 	for _, user := range mock.users {
 		if user.Username == username {
@@ -49,18 +321,30 @@ func (mock *GitlabMock) AddUser(name string, username string, email string) (*gi
 	}
 
 	mock.users = append(mock.users, user)
+	mock.fireUserSystemHooks(user, "user_create")
+	mock.publish(UserCreated{User: user})
 
 	return user, nil
 }
 
 func (mock *GitlabMock) AddGroup(name string) *gitlab.Group {
+	group := mock.newGroup(name)
+	mock.fireGroupCreateHooks(group)
+	mock.publish(GroupCreated{Group: group})
+
+	return group
+}
+
+func (mock *GitlabMock) newGroup(name string) *gitlab.Group {
 	id := int(mock.groupIds.Add(1))
 
 	group := &gitlab.Group{
-		ID:       id,
-		Name:     name,
-		FullName: name,
-		Path:     name,
+		ID:           id,
+		Name:         name,
+		FullName:     name,
+		Path:         name,
+		FullPath:     name,
+		RunnersToken: newToken(runnerRegistrationTokenPrefix),
 	}
 
 	mock.groups = append(mock.groups, group)
@@ -68,6 +352,46 @@ func (mock *GitlabMock) AddGroup(name string) *gitlab.Group {
 	return group
 }
 
+// AddSubgroup adds a group below the parent group. Members and labels of the parent group are inherited.
+func (mock *GitlabMock) AddSubgroup(name string, parent *gitlab.Group) *gitlab.Group {
+	group := mock.newGroup(name)
+	group.ParentID = parent.ID
+	group.FullName = fmt.Sprintf("%s / %s", parent.FullName, name)
+	group.FullPath = fmt.Sprintf("%s/%s", parent.FullPath, name)
+
+	mock.fireGroupCreateHooks(group)
+	mock.publish(GroupCreated{Group: group})
+
+	return group
+}
+
+func (mock *GitlabMock) getGroup(groupID int) (*gitlab.Group, error) {
+	for _, group := range mock.groups {
+		if group.ID == groupID {
+			return group, nil
+		}
+	}
+
+	return nil, fmt.Errorf("group %d not found", groupID)
+}
+
+// groupAncestors returns the group followed by its parent groups up to the top-level group.
+func (mock *GitlabMock) groupAncestors(group *gitlab.Group) []*gitlab.Group {
+	ancestors := []*gitlab.Group{group}
+
+	for group.ParentID != 0 {
+		parent, err := mock.getGroup(group.ParentID)
+		if err != nil {
+			break
+		}
+
+		ancestors = append(ancestors, parent)
+		group = parent
+	}
+
+	return ancestors
+}
+
 func (mock *GitlabMock) GetGroups() []*gitlab.Group {
 	return mock.groups
 }
@@ -75,18 +399,49 @@ func (mock *GitlabMock) GetGroups() []*gitlab.Group {
 func (mock *GitlabMock) AddProject(name string, group *gitlab.Group) *gitlab.Project {
 	id := int(mock.projectIds.Add(1))
 
+	createdAt := mock.now()
+
 	project := &gitlab.Project{
-		ID:   id,
-		Name: name,
-		Path: name,
+		ID:                id,
+		Name:              name,
+		NameWithNamespace: fmt.Sprintf("%s / %s", group.FullName, name),
+		Path:              name,
+		PathWithNamespace: fmt.Sprintf("%s/%s", group.FullPath, name),
+		Namespace: &gitlab.ProjectNamespace{
+			ID:       group.ID,
+			Name:     group.Name,
+			Path:     group.Path,
+			Kind:     "group",
+			FullPath: group.FullPath,
+		},
+		Visibility:           gitlab.PrivateVisibility,
+		MergeMethod:          gitlab.NoFastForwardMerge,
+		MergeRequestsEnabled: true,
+		EmptyRepo:            true,
+		CreatedAt:            &createdAt,
+		LastActivityAt:       &createdAt,
+		RunnersToken:         newToken(runnerRegistrationTokenPrefix),
 	}
 
 	group.Projects = append(group.Projects, project)
 	mock.projects[id] = project
+	mock.fireProjectSystemHooks(project, "project_create")
+	mock.publish(ProjectCreated{Project: project})
 
 	return project
 }
 
+// GetProjectByPath returns the project with the given full path, e.g. "group1/project1".
+func (mock *GitlabMock) GetProjectByPath(pathWithNamespace string) (*gitlab.Project, error) {
+	for _, project := range mock.projects {
+		if project.PathWithNamespace == pathWithNamespace {
+			return project, nil
+		}
+	}
+
+	return nil, fmt.Errorf("project %s not found", pathWithNamespace)
+}
+
 func (mock *GitlabMock) GetProjects() []*gitlab.Project {
 	var projects []*gitlab.Project
 
@@ -98,16 +453,215 @@ func (mock *GitlabMock) GetProjects() []*gitlab.Project {
 }
 
 func (mock *GitlabMock) AddProjectMember(projectMember *gitlab.ProjectMember, project *gitlab.Project) *gitlab.Project {
-	mock.projectMembers[project.ID] = append(mock.projectMembers[project.ID], projectMember)
+	mock.addProjectMember(projectMember, project, nil)
 
 	return project
 }
 
+// addProjectMember adds the member to the project on behalf of the author, which is nil for changes made through
+// the Go API.
+func (mock *GitlabMock) addProjectMember(projectMember *gitlab.ProjectMember, project *gitlab.Project, author *gitlab.User) {
+	mock.projectMembers[project.ID] = append(mock.projectMembers[project.ID], projectMember)
+	mock.fireMemberHooks(project, projectMember, "user_add_to_team")
+	mock.addMemberEvent(project, projectMember.ID, gitlab.JoinedEventType)
+	mock.addProjectAuditEvent(project, projectMember.ID, author, "member_created", gitlab.AuditEventDetails{
+		Add: "user_access",
+		As:  accessLevelNames[projectMember.AccessLevel],
+	})
+	member := *projectMember
+	mock.publish(ProjectMemberAdded{Project: project, Member: &member})
+}
+
+// editProjectMember changes the access level of the member on behalf of the author.
+func (mock *GitlabMock) editProjectMember(project *gitlab.Project, projectMember *gitlab.ProjectMember, accessLevel *gitlab.AccessLevelValue, author *gitlab.User) {
+	oldAccessLevel := projectMember.AccessLevel
+
+	if accessLevel != nil {
+		projectMember.AccessLevel = *accessLevel
+	}
+
+	mock.fireMemberHooks(project, projectMember, "user_update_for_team")
+
+	if projectMember.AccessLevel == oldAccessLevel {
+		return
+	}
+
+	mock.addProjectAuditEvent(project, projectMember.ID, author, "member_updated", gitlab.AuditEventDetails{
+		Change: "access_level",
+		From:   accessLevelNames[oldAccessLevel],
+		To:     accessLevelNames[projectMember.AccessLevel],
+	})
+	member := *projectMember
+	mock.publish(ProjectMemberAccessChanged{
+		Project:        project,
+		Member:         &member,
+		OldAccessLevel: oldAccessLevel,
+		NewAccessLevel: projectMember.AccessLevel,
+	})
+}
+
 func (mock *GitlabMock) GetProjectMembers(projectID int) ([]*gitlab.ProjectMember, error) {
-	projectMembers, projectExists := mock.projectMembers[projectID]
+	_, projectExists := mock.projects[projectID]
 	if !projectExists {
-		fmt.Errorf("project %d not found", projectID)
+		return nil, fmt.Errorf("project %d not found", projectID)
+	}
+
+	return mock.projectMembers[projectID], nil
+}
+
+func (mock *GitlabMock) AddGroupMember(groupMember *gitlab.GroupMember, group *gitlab.Group) *gitlab.Group {
+	mock.groupMembers[group.ID] = append(mock.groupMembers[group.ID], groupMember)
+	mock.fireGroupMemberHooks(group, groupMember, "user_add_to_group")
+	mock.addGroupAuditEvent(group, groupMember.ID, nil, "member_created", gitlab.AuditEventDetails{
+		Add: "user_access",
+		As:  accessLevelNames[groupMember.AccessLevel],
+	})
+	member := *groupMember
+	mock.publish(GroupMemberAdded{Group: group, Member: &member})
+
+	return group
+}
+
+func (mock *GitlabMock) GetGroupMembers(groupID int) ([]*gitlab.GroupMember, error) {
+	for _, group := range mock.groups {
+		if group.ID == groupID {
+			return mock.groupMembers[groupID], nil
+		}
+	}
+
+	return nil, fmt.Errorf("group %d not found", groupID)
+}
+
+// RemoveProjectMember removes the user from the members of the project.
+func (mock *GitlabMock) RemoveProjectMember(userID int, project *gitlab.Project) error {
+	return mock.removeProjectMember(userID, project, nil)
+}
+
+// removeProjectMember removes the user from the members of the project on behalf of the author.
+func (mock *GitlabMock) removeProjectMember(userID int, project *gitlab.Project, author *gitlab.User) error {
+	index := slices.IndexFunc(mock.projectMembers[project.ID], func(member *gitlab.ProjectMember) bool {
+		return member.ID == userID
+	})
+	if index < 0 {
+		return newAPIError(http.StatusNotFound, "404 Not found")
+	}
+
+	mock.fireMemberHooks(project, mock.projectMembers[project.ID][index], "user_remove_from_team")
+
+	mock.projectMembers[project.ID] = slices.Delete(mock.projectMembers[project.ID], index, index+1)
+	mock.addMemberEvent(project, userID, gitlab.LeftEventType)
+	mock.addProjectAuditEvent(project, userID, author, "member_destroyed", gitlab.AuditEventDetails{Remove: "user_access"})
+	mock.publish(MemberRemoved{Project: project, UserID: userID})
+
+	return nil
+}
+
+// RemoveGroupMember removes the user from the members of the group.
+func (mock *GitlabMock) RemoveGroupMember(userID int, group *gitlab.Group) error {
+	index := slices.IndexFunc(mock.groupMembers[group.ID], func(member *gitlab.GroupMember) bool {
+		return member.ID == userID
+	})
+	if index < 0 {
+		return newAPIError(http.StatusNotFound, "404 Not found")
+	}
+
+	mock.fireGroupMemberHooks(group, mock.groupMembers[group.ID][index], "user_remove_from_group")
+
+	mock.groupMembers[group.ID] = slices.Delete(mock.groupMembers[group.ID], index, index+1)
+	mock.addGroupAuditEvent(group, userID, nil, "member_destroyed", gitlab.AuditEventDetails{Remove: "user_access"})
+	mock.publish(MemberRemoved{Group: group, UserID: userID})
+
+	return nil
+}
+
+func (mock *GitlabMock) getUser(userID int) (*gitlab.User, error) {
+	for _, user := range mock.users {
+		if user.ID == userID {
+			return user, nil
+		}
+	}
+
+	return nil, fmt.Errorf("user %d not found", userID)
+}
+
+// projectAccessLevel returns the access level the user has in the project, either as project member or
+// as member of the group of the project, whichever is higher. Admins are treated as owners.
+func (mock *GitlabMock) projectAccessLevel(project *gitlab.Project, user *gitlab.User) gitlab.AccessLevelValue {
+	if user == nil {
+		return gitlab.NoPermissions
+	}
+
+	if user.IsAdmin {
+		return gitlab.OwnerPermissions
+	}
+
+	accessLevel := gitlab.NoPermissions
+
+	for _, member := range mock.projectMembers[project.ID] {
+		if member.ID == user.ID {
+			accessLevel = max(accessLevel, member.AccessLevel)
+		}
+	}
+
+	if project.Namespace != nil {
+		group, err := mock.getGroup(project.Namespace.ID)
+		if err == nil {
+			accessLevel = max(accessLevel, mock.groupAccessLevel(group, user))
+		}
+	}
+
+	return accessLevel
+}
+
+// groupAccessLevel returns the access level the user has in the group, either as direct member or as
+// member of a parent group, whichever is higher. Admins are treated as owners.
+func (mock *GitlabMock) groupAccessLevel(group *gitlab.Group, user *gitlab.User) gitlab.AccessLevelValue {
+	if user == nil {
+		return gitlab.NoPermissions
+	}
+
+	if user.IsAdmin {
+		return gitlab.OwnerPermissions
+	}
+
+	accessLevel := gitlab.NoPermissions
+
+	for _, ancestor := range mock.groupAncestors(group) {
+		for _, member := range mock.groupMembers[ancestor.ID] {
+			if member.ID == user.ID {
+				accessLevel = max(accessLevel, member.AccessLevel)
+			}
+		}
+	}
+
+	return accessLevel
+}
+
+// hasAccess reports whether the user has at least the access level in the project. Without authentication
+// every request is allowed.
+func (mock *GitlabMock) hasAccess(project *gitlab.Project, user *gitlab.User, accessLevel gitlab.AccessLevelValue) bool {
+	if !mock.authenticationRequired() {
+		return true
+	}
+
+	return mock.projectAccessLevel(project, user) >= accessLevel
+}
+
+// isAdmin reports whether the user may use the admin API. Without authentication every request is allowed.
+func (mock *GitlabMock) isAdmin(user *gitlab.User) bool {
+	if !mock.authenticationRequired() {
+		return true
+	}
+
+	return user != nil && user.IsAdmin
+}
+
+// hasGroupAccess reports whether the user has at least the access level in the group. Without
+// authentication every request is allowed.
+func (mock *GitlabMock) hasGroupAccess(group *gitlab.Group, user *gitlab.User, accessLevel gitlab.AccessLevelValue) bool {
+	if !mock.authenticationRequired() {
+		return true
 	}
 
-	return projectMembers, nil
+	return mock.groupAccessLevel(group, user) >= accessLevel
 }
@@ -0,0 +1,446 @@
+package gitlabapimock
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+const (
+	approvalRuleRegular     = "regular"
+	approvalRuleAnyApprover = "any_approver"
+)
+
+// approvalRuleGroups resolves the group IDs of an approval rule. Unknown groups are skipped.
+func (mock *GitlabMock) approvalRuleGroups(groupIDs []int) []*gitlab.Group {
+	groups := []*gitlab.Group{}
+
+	for _, group := range mock.groups {
+		if slices.Contains(groupIDs, group.ID) {
+			groups = append(groups, group)
+		}
+	}
+
+	return groups
+}
+
+// eligibleApprovers returns the users that may approve for a rule: the users of the rule and the members
+// of its groups, or all project members for rules without approvers. Only users with at least the
+// developer role in the project are eligible.
+func (mock *GitlabMock) eligibleApprovers(project *gitlab.Project, users []*gitlab.BasicUser, groups []*gitlab.Group) []*gitlab.BasicUser {
+	candidateIDs := []int{}
+
+	if len(users) == 0 && len(groups) == 0 {
+		for _, member := range mock.projectMembers[project.ID] {
+			candidateIDs = append(candidateIDs, member.ID)
+		}
+
+		if project.Namespace != nil {
+			for _, member := range mock.groupMembers[project.Namespace.ID] {
+				candidateIDs = append(candidateIDs, member.ID)
+			}
+		}
+	}
+
+	for _, user := range users {
+		candidateIDs = append(candidateIDs, user.ID)
+	}
+
+	for _, group := range groups {
+		for _, member := range mock.groupMembers[group.ID] {
+			candidateIDs = append(candidateIDs, member.ID)
+		}
+	}
+
+	eligibleApprovers := []*gitlab.BasicUser{}
+	seen := map[int]bool{}
+
+	for _, candidateID := range candidateIDs {
+		if seen[candidateID] {
+			continue
+		}
+		seen[candidateID] = true
+
+		user, err := mock.getUser(candidateID)
+		if err != nil {
+			continue
+		}
+
+		if mock.projectAccessLevel(project, user) >= gitlab.DeveloperPermissions {
+			eligibleApprovers = append(eligibleApprovers, toBasicUser(user))
+		}
+	}
+
+	return eligibleApprovers
+}
+
+func validateApprovalRule(rules []string, name *string, approvalsRequired *int) error {
+	if name != nil && slices.Contains(rules, *name) {
+		return newAPIError(http.StatusBadRequest, map[string][]string{"name": {"has already been taken"}})
+	}
+
+	if approvalsRequired != nil && *approvalsRequired < 0 {
+		return newAPIError(http.StatusBadRequest, map[string][]string{"approvals_required": {"must be greater than or equal to 0"}})
+	}
+
+	return nil
+}
+
+// AddProjectApprovalRule adds a project-level approval rule. Merge requests opened afterwards copy the rule.
+func (mock *GitlabMock) AddProjectApprovalRule(project *gitlab.Project, options *gitlab.CreateProjectLevelRuleOptions) (*gitlab.ProjectApprovalRule, error) {
+	if options.Name == nil {
+		return nil, newAPIError(http.StatusBadRequest, "name is missing")
+	}
+
+	if options.ApprovalsRequired == nil {
+		return nil, newAPIError(http.StatusBadRequest, "approvals_required is missing")
+	}
+
+	names := []string{}
+	for _, rule := range mock.projectApprovalRules[project.ID] {
+		names = append(names, rule.Name)
+	}
+
+	err := validateApprovalRule(names, options.Name, options.ApprovalsRequired)
+	if err != nil {
+		return nil, err
+	}
+
+	rule := &gitlab.ProjectApprovalRule{
+		ID:                            int(mock.approvalRuleIds.Add(1)),
+		Name:                          *options.Name,
+		RuleType:                      approvalRuleRegular,
+		ApprovalsRequired:             *options.ApprovalsRequired,
+		Users:                         mock.toBasicUsers(valueOf(options.UserIDs)),
+		Groups:                        mock.approvalRuleGroups(valueOf(options.GroupIDs)),
+		ProtectedBranches:             []*gitlab.ProtectedBranch{},
+		AppliesToAllProtectedBranches: valueOf(options.AppliesToAllProtectedBranches),
+	}
+
+	if options.RuleType != nil {
+		rule.RuleType = *options.RuleType
+	}
+
+	if rule.RuleType == approvalRuleAnyApprover {
+		rule.Users = []*gitlab.BasicUser{}
+		rule.Groups = []*gitlab.Group{}
+	}
+
+	mock.projectApprovalRules[project.ID] = append(mock.projectApprovalRules[project.ID], rule)
+
+	return mock.refreshProjectApprovalRule(project, rule), nil
+}
+
+func (mock *GitlabMock) refreshProjectApprovalRule(project *gitlab.Project, rule *gitlab.ProjectApprovalRule) *gitlab.ProjectApprovalRule {
+	rule.EligibleApprovers = mock.eligibleApprovers(project, rule.Users, rule.Groups)
+
+	return rule
+}
+
+func (mock *GitlabMock) getProjectApprovalRules(project *gitlab.Project) []*gitlab.ProjectApprovalRule {
+	rules := []*gitlab.ProjectApprovalRule{}
+
+	for _, rule := range mock.projectApprovalRules[project.ID] {
+		rules = append(rules, mock.refreshProjectApprovalRule(project, rule))
+	}
+
+	return rules
+}
+
+func (mock *GitlabMock) getProjectApprovalRule(project *gitlab.Project, ruleID int) (*gitlab.ProjectApprovalRule, error) {
+	for _, rule := range mock.projectApprovalRules[project.ID] {
+		if rule.ID == ruleID {
+			return mock.refreshProjectApprovalRule(project, rule), nil
+		}
+	}
+
+	return nil, newAPIError(http.StatusNotFound, "404 Not found")
+}
+
+func (mock *GitlabMock) updateProjectApprovalRule(project *gitlab.Project, ruleID int, options *gitlab.UpdateProjectLevelRuleOptions) (*gitlab.ProjectApprovalRule, error) {
+	rule, err := mock.getProjectApprovalRule(project, ruleID)
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, other := range mock.projectApprovalRules[project.ID] {
+		if other != rule {
+			names = append(names, other.Name)
+		}
+	}
+
+	err = validateApprovalRule(names, options.Name, options.ApprovalsRequired)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.Name != nil {
+		rule.Name = *options.Name
+	}
+
+	if options.ApprovalsRequired != nil {
+		rule.ApprovalsRequired = *options.ApprovalsRequired
+	}
+
+	if options.UserIDs != nil {
+		rule.Users = mock.toBasicUsers(*options.UserIDs)
+	}
+
+	if options.GroupIDs != nil {
+		rule.Groups = mock.approvalRuleGroups(*options.GroupIDs)
+	}
+
+	if options.AppliesToAllProtectedBranches != nil {
+		rule.AppliesToAllProtectedBranches = *options.AppliesToAllProtectedBranches
+	}
+
+	return mock.refreshProjectApprovalRule(project, rule), nil
+}
+
+func (mock *GitlabMock) deleteProjectApprovalRule(project *gitlab.Project, ruleID int) error {
+	rules := mock.projectApprovalRules[project.ID]
+
+	for idx, rule := range rules {
+		if rule.ID == ruleID {
+			mock.projectApprovalRules[project.ID] = append(rules[:idx:idx], rules[idx+1:]...)
+			return nil
+		}
+	}
+
+	return newAPIError(http.StatusNotFound, "404 Not found")
+}
+
+// copyProjectApprovalRules copies the project rules to a newly opened merge request.
+func (mock *GitlabMock) copyProjectApprovalRules(project *gitlab.Project, mergeRequest *gitlab.MergeRequest) {
+	rules := []*gitlab.MergeRequestApprovalRule{}
+
+	for _, projectRule := range mock.projectApprovalRules[project.ID] {
+		rules = append(rules, &gitlab.MergeRequestApprovalRule{
+			ID:                int(mock.approvalRuleIds.Add(1)),
+			Name:              projectRule.Name,
+			RuleType:          projectRule.RuleType,
+			ApprovalsRequired: projectRule.ApprovalsRequired,
+			SourceRule:        projectRule,
+			Users:             slices.Clone(projectRule.Users),
+			Groups:            slices.Clone(projectRule.Groups),
+		})
+	}
+
+	mock.mergeRequestApprovalRules[mergeRequest.ID] = rules
+}
+
+// getMergeRequestApprovalRules returns the rules of the merge request with their current approvals.
+func (mock *GitlabMock) getMergeRequestApprovalRules(project *gitlab.Project, mergeRequest *gitlab.MergeRequest) []*gitlab.MergeRequestApprovalRule {
+	rules := []*gitlab.MergeRequestApprovalRule{}
+
+	for _, rule := range mock.mergeRequestApprovalRules[mergeRequest.ID] {
+		rule.EligibleApprovers = mock.eligibleApprovers(project, rule.Users, rule.Groups)
+		rule.ApprovedBy = []*gitlab.BasicUser{}
+
+		for _, approver := range mock.mergeRequestApprovals[mergeRequest.ID] {
+			isEligible := slices.ContainsFunc(rule.EligibleApprovers, func(user *gitlab.BasicUser) bool {
+				return user.ID == approver.ID
+			})
+
+			if isEligible {
+				rule.ApprovedBy = append(rule.ApprovedBy, approver)
+			}
+		}
+
+		rule.Approved = len(rule.ApprovedBy) >= rule.ApprovalsRequired
+
+		rules = append(rules, rule)
+	}
+
+	return rules
+}
+
+func (mock *GitlabMock) getMergeRequestApprovalRule(project *gitlab.Project, mergeRequest *gitlab.MergeRequest, ruleID int) (*gitlab.MergeRequestApprovalRule, error) {
+	for _, rule := range mock.getMergeRequestApprovalRules(project, mergeRequest) {
+		if rule.ID == ruleID {
+			return rule, nil
+		}
+	}
+
+	return nil, newAPIError(http.StatusNotFound, "404 Not found")
+}
+
+func (mock *GitlabMock) createMergeRequestApprovalRule(project *gitlab.Project, mergeRequest *gitlab.MergeRequest, options *gitlab.CreateMergeRequestApprovalRuleOptions, user *gitlab.User) (*gitlab.MergeRequestApprovalRule, error) {
+	if !mock.hasAccess(project, user, gitlab.DeveloperPermissions) {
+		return nil, newAPIError(http.StatusForbidden, "403 Forbidden")
+	}
+
+	if options.Name == nil {
+		return nil, newAPIError(http.StatusBadRequest, "name is missing")
+	}
+
+	if options.ApprovalsRequired == nil {
+		return nil, newAPIError(http.StatusBadRequest, "approvals_required is missing")
+	}
+
+	names := []string{}
+	for _, rule := range mock.mergeRequestApprovalRules[mergeRequest.ID] {
+		names = append(names, rule.Name)
+	}
+
+	err := validateApprovalRule(names, options.Name, options.ApprovalsRequired)
+	if err != nil {
+		return nil, err
+	}
+
+	rule := &gitlab.MergeRequestApprovalRule{
+		ID:                int(mock.approvalRuleIds.Add(1)),
+		Name:              *options.Name,
+		RuleType:          approvalRuleRegular,
+		ApprovalsRequired: *options.ApprovalsRequired,
+		Users:             mock.toBasicUsers(valueOf(options.UserIDs)),
+		Groups:            mock.approvalRuleGroups(valueOf(options.GroupIDs)),
+	}
+
+	if options.ApprovalProjectRuleID != nil {
+		sourceRule, err := mock.getProjectApprovalRule(project, *options.ApprovalProjectRuleID)
+		if err != nil {
+			return nil, err
+		}
+
+		rule.SourceRule = sourceRule
+		rule.RuleType = sourceRule.RuleType
+
+		if options.UserIDs == nil && options.GroupIDs == nil {
+			rule.Users = slices.Clone(sourceRule.Users)
+			rule.Groups = slices.Clone(sourceRule.Groups)
+		}
+	}
+
+	mock.mergeRequestApprovalRules[mergeRequest.ID] = append(mock.mergeRequestApprovalRules[mergeRequest.ID], rule)
+	mock.mergeRequestApprovalRulesOverwritten[mergeRequest.ID] = true
+
+	return mock.getMergeRequestApprovalRule(project, mergeRequest, rule.ID)
+}
+
+func (mock *GitlabMock) updateMergeRequestApprovalRule(project *gitlab.Project, mergeRequest *gitlab.MergeRequest, ruleID int, options *gitlab.UpdateMergeRequestApprovalRuleOptions, user *gitlab.User) (*gitlab.MergeRequestApprovalRule, error) {
+	rule, err := mock.getMergeRequestApprovalRule(project, mergeRequest, ruleID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !mock.hasAccess(project, user, gitlab.DeveloperPermissions) {
+		return nil, newAPIError(http.StatusForbidden, "403 Forbidden")
+	}
+
+	names := []string{}
+	for _, other := range mock.mergeRequestApprovalRules[mergeRequest.ID] {
+		if other != rule {
+			names = append(names, other.Name)
+		}
+	}
+
+	err = validateApprovalRule(names, options.Name, options.ApprovalsRequired)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.Name != nil {
+		rule.Name = *options.Name
+	}
+
+	if options.ApprovalsRequired != nil {
+		rule.ApprovalsRequired = *options.ApprovalsRequired
+	}
+
+	if options.UserIDs != nil {
+		rule.Users = mock.toBasicUsers(*options.UserIDs)
+	}
+
+	if options.GroupIDs != nil {
+		rule.Groups = mock.approvalRuleGroups(*options.GroupIDs)
+	}
+
+	mock.mergeRequestApprovalRulesOverwritten[mergeRequest.ID] = true
+
+	return mock.getMergeRequestApprovalRule(project, mergeRequest, rule.ID)
+}
+
+func (mock *GitlabMock) deleteMergeRequestApprovalRule(project *gitlab.Project, mergeRequest *gitlab.MergeRequest, ruleID int, user *gitlab.User) error {
+	if !mock.hasAccess(project, user, gitlab.DeveloperPermissions) {
+		return newAPIError(http.StatusForbidden, "403 Forbidden")
+	}
+
+	rules := mock.mergeRequestApprovalRules[mergeRequest.ID]
+
+	for idx, rule := range rules {
+		if rule.ID == ruleID {
+			mock.mergeRequestApprovalRules[mergeRequest.ID] = append(rules[:idx:idx], rules[idx+1:]...)
+			mock.mergeRequestApprovalRulesOverwritten[mergeRequest.ID] = true
+			return nil
+		}
+	}
+
+	return newAPIError(http.StatusNotFound, "404 Not found")
+}
+
+// getMergeRequestApprovalState implements the approval state of https://docs.gitlab.com/ee/api/merge_request_approvals.html#get-the-approval-state-of-merge-requests
+func (mock *GitlabMock) getMergeRequestApprovalState(project *gitlab.Project, mergeRequest *gitlab.MergeRequest) *gitlab.MergeRequestApprovalState {
+	return &gitlab.MergeRequestApprovalState{
+		ApprovalRulesOverwritten: mock.mergeRequestApprovalRulesOverwritten[mergeRequest.ID],
+		Rules:                    mock.getMergeRequestApprovalRules(project, mergeRequest),
+	}
+}
+
+// approvalsRequired returns the number of approvals the merge request needs: the sum of its approval
+// rules, or approvals_before_merge if it has no rules.
+func (mock *GitlabMock) approvalsRequired(project *gitlab.Project, mergeRequest *gitlab.MergeRequest) int {
+	rules := mock.mergeRequestApprovalRules[mergeRequest.ID]
+	if len(rules) > 0 {
+		approvalsRequired := 0
+		for _, rule := range rules {
+			approvalsRequired += rule.ApprovalsRequired
+		}
+
+		return approvalsRequired
+	}
+
+	if mergeRequest.ApprovalsBeforeMerge > 0 {
+		return mergeRequest.ApprovalsBeforeMerge
+	}
+
+	return project.ApprovalsBeforeMerge
+}
+
+// approvalRulesLeft returns the rules of the merge request that still lack approvals.
+func (mock *GitlabMock) approvalRulesLeft(project *gitlab.Project, mergeRequest *gitlab.MergeRequest) []*gitlab.MergeRequestApprovalRule {
+	rulesLeft := []*gitlab.MergeRequestApprovalRule{}
+
+	for _, rule := range mock.getMergeRequestApprovalRules(project, mergeRequest) {
+		if !rule.Approved {
+			rulesLeft = append(rulesLeft, rule)
+		}
+	}
+
+	return rulesLeft
+}
+
+// mergeRequestApproved reports whether all approval rules of the merge request are satisfied.
+func (mock *GitlabMock) mergeRequestApproved(project *gitlab.Project, mergeRequest *gitlab.MergeRequest) bool {
+	if len(mock.mergeRequestApprovalRules[mergeRequest.ID]) > 0 {
+		return len(mock.approvalRulesLeft(project, mergeRequest)) == 0
+	}
+
+	return len(mock.mergeRequestApprovals[mergeRequest.ID]) >= mock.approvalsRequired(project, mergeRequest)
+}
+
+// approvalsLeft returns the number of approvals still needed, counting only eligible approvers per rule.
+func (mock *GitlabMock) approvalsLeft(project *gitlab.Project, mergeRequest *gitlab.MergeRequest) int {
+	if len(mock.mergeRequestApprovalRules[mergeRequest.ID]) > 0 {
+		approvalsLeft := 0
+		for _, rule := range mock.approvalRulesLeft(project, mergeRequest) {
+			approvalsLeft += rule.ApprovalsRequired - len(rule.ApprovedBy)
+		}
+
+		return approvalsLeft
+	}
+
+	return max(mock.approvalsRequired(project, mergeRequest)-len(mock.mergeRequestApprovals[mergeRequest.ID]), 0)
+}
@@ -0,0 +1,391 @@
+package gitlabapimock
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+const (
+	artifactTypeArchive = "archive"
+	artifactFormatZip   = "zip"
+	artifactFormatGzip  = "gzip"
+	artifactFormatRaw   = "raw"
+
+	defaultArtifactsExpireIn = 30 * 24 * time.Hour
+)
+
+// artifactFilenames are the names GitLab gives the uploaded files of each artifact format.
+var artifactFilenames = map[string]string{
+	artifactFormatZip:  "artifacts.zip",
+	artifactFormatGzip: "%s.gz",
+	artifactFormatRaw:  "%s",
+}
+
+var durationPartRegexp = regexp.MustCompile(`(\d+(?:\.\d+)?)\s*([a-z]+)`)
+
+// durationUnits maps the units accepted by expire_in, which GitLab parses with ChronicDuration, to their length.
+var durationUnits = map[string]time.Duration{
+	"s": time.Second, "sec": time.Second, "secs": time.Second, "second": time.Second, "seconds": time.Second,
+	"m": time.Minute, "min": time.Minute, "mins": time.Minute, "minute": time.Minute, "minutes": time.Minute,
+	"h": time.Hour, "hr": time.Hour, "hrs": time.Hour, "hour": time.Hour, "hours": time.Hour,
+	"d": 24 * time.Hour, "day": 24 * time.Hour, "days": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour, "wk": 7 * 24 * time.Hour, "wks": 7 * 24 * time.Hour, "week": 7 * 24 * time.Hour, "weeks": 7 * 24 * time.Hour,
+	"mo": 30 * 24 * time.Hour, "mos": 30 * 24 * time.Hour, "month": 30 * 24 * time.Hour, "months": 30 * 24 * time.Hour,
+	"y": 365 * 24 * time.Hour, "yr": 365 * 24 * time.Hour, "yrs": 365 * 24 * time.Hour, "year": 365 * 24 * time.Hour, "years": 365 * 24 * time.Hour,
+}
+
+// jobArtifact is a file uploaded for a job, either the archive or a report.
+type jobArtifact struct {
+	fileType string
+	format   string
+	filename string
+	data     []byte
+	expireAt *time.Time
+}
+
+// parseExpireIn parses an expire_in value like "1 week" or "3 hrs 4 mins". A plain number is taken as seconds.
+// Artifacts that should never expire result in a nil time.
+func (mock *GitlabMock) parseExpireIn(expireIn string) (*time.Time, error) {
+	expireIn = strings.ToLower(strings.TrimSpace(expireIn))
+
+	if expireIn == "" {
+		expireAt := mock.now().Add(defaultArtifactsExpireIn)
+		return &expireAt, nil
+	}
+
+	if expireIn == "never" {
+		return nil, nil
+	}
+
+	if seconds, err := strconv.Atoi(expireIn); err == nil {
+		expireAt := mock.now().Add(time.Duration(seconds) * time.Second)
+		return &expireAt, nil
+	}
+
+	parts := durationPartRegexp.FindAllStringSubmatch(expireIn, -1)
+
+	remainder := strings.NewReplacer(",", "", "and", "", " ", "").Replace(durationPartRegexp.ReplaceAllString(expireIn, ""))
+	if len(parts) == 0 || remainder != "" {
+		return nil, fmt.Errorf("invalid expire_in: %s", expireIn)
+	}
+
+	var duration time.Duration
+
+	for _, part := range parts {
+		unit, knownUnit := durationUnits[part[2]]
+		if !knownUnit {
+			return nil, fmt.Errorf("invalid expire_in: %s", expireIn)
+		}
+
+		value, _ := strconv.ParseFloat(part[1], 64)
+		duration += time.Duration(value * float64(unit))
+	}
+
+	expireAt := mock.now().Add(duration)
+
+	return &expireAt, nil
+}
+
+// zipFiles creates a zip archive with the files, sorted by their path.
+func zipFiles(files map[string]string) ([]byte, error) {
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+
+	sort.Strings(paths)
+
+	var buffer bytes.Buffer
+
+	writer := zip.NewWriter(&buffer)
+
+	for _, path := range paths {
+		fileWriter, err := writer.Create(path)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = fileWriter.Write([]byte(files[path]))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := writer.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// AddJobArtifacts uploads the files as artifacts archive of the job, as the artifacts keyword of a job
+// would. The expiry accepts the values of artifacts:expire_in, an empty value applies the default of 30 days.
+func (mock *GitlabMock) AddJobArtifacts(job *gitlab.Job, files map[string]string, expireIn string) error {
+	archive, err := zipFiles(files)
+	if err != nil {
+		return err
+	}
+
+	return mock.uploadJobArtifact(job, artifactTypeArchive, artifactFormatZip, archive, expireIn)
+}
+
+// uploadJobArtifact stores the artifact of the job, replacing an artifact of the same type.
+func (mock *GitlabMock) uploadJobArtifact(job *gitlab.Job, fileType string, format string, data []byte, expireIn string) error {
+	if fileType == "" {
+		fileType = artifactTypeArchive
+	}
+
+	if format == "" {
+		format = artifactFormatZip
+		if fileType != artifactTypeArchive {
+			format = artifactFormatGzip
+		}
+	}
+
+	filenameFormat, knownFormat := artifactFilenames[format]
+	if !knownFormat || (fileType == artifactTypeArchive) != (format == artifactFormatZip) {
+		return newAPIError(http.StatusBadRequest, fmt.Sprintf("400 Bad request - Invalid artifact format: %s", format))
+	}
+
+	if fileType == artifactTypeArchive {
+		_, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return newAPIError(http.StatusBadRequest, "400 Bad request - Invalid archive")
+		}
+	}
+
+	expireAt, err := mock.parseExpireIn(expireIn)
+	if err != nil {
+		return newAPIError(http.StatusBadRequest, fmt.Sprintf("400 Bad request - %s", err))
+	}
+
+	filename := filenameFormat
+	if strings.Contains(filenameFormat, "%s") {
+		filename = fmt.Sprintf(filenameFormat, fileType)
+	}
+
+	artifacts := []*jobArtifact{}
+	for _, artifact := range mock.jobArtifacts[job.ID] {
+		if artifact.fileType != fileType {
+			artifacts = append(artifacts, artifact)
+		}
+	}
+
+	mock.jobArtifacts[job.ID] = append(artifacts, &jobArtifact{
+		fileType: fileType,
+		format:   format,
+		filename: filename,
+		data:     data,
+		expireAt: expireAt,
+	})
+
+	mock.updateArtifactFields(job)
+
+	return nil
+}
+
+// updateArtifactFields sets the artifact fields of the job from its stored artifacts.
+func (mock *GitlabMock) updateArtifactFields(job *gitlab.Job) {
+	job.Artifacts = nil
+	job.ArtifactsFile.Filename = ""
+	job.ArtifactsFile.Size = 0
+	job.ArtifactsExpireAt = nil
+
+	for _, artifact := range mock.jobArtifacts[job.ID] {
+		job.Artifacts = append(job.Artifacts, struct {
+			FileType   string `json:"file_type"`
+			Filename   string `json:"filename"`
+			Size       int    `json:"size"`
+			FileFormat string `json:"file_format"`
+		}{
+			FileType:   artifact.fileType,
+			Filename:   artifact.filename,
+			Size:       len(artifact.data),
+			FileFormat: artifact.format,
+		})
+
+		if artifact.fileType == artifactTypeArchive {
+			job.ArtifactsFile.Filename = artifact.filename
+			job.ArtifactsFile.Size = len(artifact.data)
+			job.ArtifactsExpireAt = artifact.expireAt
+		}
+	}
+}
+
+// removeExpiredArtifacts deletes the artifacts of the job whose expiry date has passed, as the cleanup
+// worker of GitLab would.
+func (mock *GitlabMock) removeExpiredArtifacts(job *gitlab.Job) {
+	artifacts := []*jobArtifact{}
+	for _, artifact := range mock.jobArtifacts[job.ID] {
+		if artifact.expireAt == nil || artifact.expireAt.After(mock.now()) {
+			artifacts = append(artifacts, artifact)
+		}
+	}
+
+	if len(artifacts) == len(mock.jobArtifacts[job.ID]) {
+		return
+	}
+
+	mock.jobArtifacts[job.ID] = artifacts
+	mock.updateArtifactFields(job)
+}
+
+// artifactsArchive returns the artifacts archive of the job, unless it has expired.
+func (mock *GitlabMock) artifactsArchive(job *gitlab.Job) (*jobArtifact, error) {
+	mock.removeExpiredArtifacts(job)
+
+	for _, artifact := range mock.jobArtifacts[job.ID] {
+		if artifact.fileType == artifactTypeArchive {
+			return artifact, nil
+		}
+	}
+
+	return nil, newAPIError(http.StatusNotFound, "404 Not Found")
+}
+
+// artifactsFile extracts a single file from the artifacts archive of the job.
+func (mock *GitlabMock) artifactsFile(job *gitlab.Job, path string) ([]byte, error) {
+	archive, err := mock.artifactsArchive(job)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := zip.NewReader(bytes.NewReader(archive.data), int64(len(archive.data)))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range reader.File {
+		if file.Name != path || file.FileInfo().IsDir() {
+			continue
+		}
+
+		fileReader, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer fileReader.Close()
+
+		return io.ReadAll(fileReader)
+	}
+
+	return nil, newAPIError(http.StatusNotFound, "404 Not Found")
+}
+
+// GetJobArtifactsFile returns a file of the artifacts archive of the job.
+func (mock *GitlabMock) GetJobArtifactsFile(job *gitlab.Job, path string) ([]byte, error) {
+	return mock.artifactsFile(job, path)
+}
+
+// GetJobReport returns the uncompressed content of a report artifact of the job, e.g. junit.
+func (mock *GitlabMock) GetJobReport(job *gitlab.Job, fileType string) ([]byte, error) {
+	mock.removeExpiredArtifacts(job)
+
+	for _, artifact := range mock.jobArtifacts[job.ID] {
+		if artifact.fileType != fileType {
+			continue
+		}
+
+		if artifact.format != artifactFormatGzip {
+			return artifact.data, nil
+		}
+
+		reader, err := gzip.NewReader(bytes.NewReader(artifact.data))
+		if err != nil {
+			return nil, err
+		}
+
+		return io.ReadAll(reader)
+	}
+
+	return nil, fmt.Errorf("job %d has no %s report", job.ID, fileType)
+}
+
+// latestSuccessfulJob returns the job with the name of the latest successful pipeline for the ref.
+func (mock *GitlabMock) latestSuccessfulJob(project *gitlab.Project, ref string, name string) (*gitlab.Job, error) {
+	var latest *gitlab.Pipeline
+
+	for _, pipeline := range mock.pipelines[project.ID] {
+		if pipeline.Ref == ref && pipeline.Status == string(gitlab.Success) && (latest == nil || pipeline.ID > latest.ID) {
+			latest = pipeline
+		}
+	}
+
+	if latest != nil {
+		for _, job := range mock.pipelineJobs(latest, false) {
+			if job.Name == name && job.Status == string(gitlab.Success) {
+				return job, nil
+			}
+		}
+	}
+
+	return nil, newAPIError(http.StatusNotFound, "404 Not Found")
+}
+
+// keepArtifacts prevents the artifacts of the job from expiring.
+func (mock *GitlabMock) keepArtifacts(project *gitlab.Project, job *gitlab.Job, user *gitlab.User) (*gitlab.Job, error) {
+	if !mock.hasAccess(project, user, gitlab.DeveloperPermissions) {
+		return nil, newAPIError(http.StatusForbidden, "403 Forbidden")
+	}
+
+	_, err := mock.artifactsArchive(job)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, artifact := range mock.jobArtifacts[job.ID] {
+		artifact.expireAt = nil
+	}
+
+	mock.updateArtifactFields(job)
+
+	return job, nil
+}
+
+// deleteArtifacts removes all artifacts of the job.
+func (mock *GitlabMock) deleteArtifacts(project *gitlab.Project, job *gitlab.Job, user *gitlab.User) error {
+	if !mock.hasAccess(project, user, gitlab.MaintainerPermissions) {
+		return newAPIError(http.StatusForbidden, "403 Forbidden")
+	}
+
+	delete(mock.jobArtifacts, job.ID)
+	mock.updateArtifactFields(job)
+
+	return nil
+}
+
+// deleteProjectArtifacts removes the artifacts of all jobs of the project.
+func (mock *GitlabMock) deleteProjectArtifacts(project *gitlab.Project, user *gitlab.User) error {
+	if !mock.hasAccess(project, user, gitlab.MaintainerPermissions) {
+		return newAPIError(http.StatusForbidden, "403 Forbidden")
+	}
+
+	for _, job := range mock.getProjectJobs(project, true) {
+		delete(mock.jobArtifacts, job.ID)
+		mock.updateArtifactFields(job)
+	}
+
+	return nil
+}
+
+// AppendJobTrace appends the text to the log of the job, as a runner does while the job is running.
+func (mock *GitlabMock) AppendJobTrace(job *gitlab.Job, text string) {
+	mock.jobTraces[job.ID] = append(mock.jobTraces[job.ID], text...)
+}
+
+// GetJobTrace returns the log of the job.
+func (mock *GitlabMock) GetJobTrace(job *gitlab.Job) string {
+	return string(mock.jobTraces[job.ID])
+}
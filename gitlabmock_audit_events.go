@@ -0,0 +1,77 @@
+package gitlabapimock
+
+import (
+	"net/http"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// addAuditEvent records an audit event of a change the author made to a user of the entity, see
+// https://docs.gitlab.com/ee/administration/audit_event_reports.html. Changes made through the Go API have no
+// author.
+func (mock *GitlabMock) addAuditEvent(entityType string, entityID int, entityPath string, userID int, author *gitlab.User, eventType string, details gitlab.AuditEventDetails) {
+	createdAt := mock.now()
+
+	details.TargetID = userID
+	details.TargetType = "User"
+	details.EntityPath = entityPath
+
+	if user, err := mock.getUser(userID); err == nil {
+		details.TargetDetails = user.Name
+	}
+
+	event := &gitlab.AuditEvent{
+		ID:         int(mock.auditEventIds.Add(1)),
+		EntityID:   entityID,
+		EntityType: entityType,
+		EventType:  eventType,
+		CreatedAt:  &createdAt,
+	}
+
+	if author != nil {
+		event.AuthorID = author.ID
+		details.AuthorName = author.Name
+		details.AuthorEmail = author.Email
+		details.AuthorClass = "User"
+	}
+
+	event.Details = details
+
+	mock.auditEvents = append(mock.auditEvents, event)
+}
+
+func (mock *GitlabMock) addProjectAuditEvent(project *gitlab.Project, userID int, author *gitlab.User, eventType string, details gitlab.AuditEventDetails) {
+	mock.addAuditEvent("Project", project.ID, project.PathWithNamespace, userID, author, eventType, details)
+}
+
+func (mock *GitlabMock) addGroupAuditEvent(group *gitlab.Group, userID int, author *gitlab.User, eventType string, details gitlab.AuditEventDetails) {
+	mock.addAuditEvent("Group", group.ID, group.FullPath, userID, author, eventType, details)
+}
+
+// GetAuditEvents returns the audit events of the instance, oldest first.
+func (mock *GitlabMock) GetAuditEvents() []*gitlab.AuditEvent {
+	return append([]*gitlab.AuditEvent{}, mock.auditEvents...)
+}
+
+// entityAuditEvents returns the audit events of the entity, e.g. of the project with the ID for Project.
+func (mock *GitlabMock) entityAuditEvents(entityType string, entityID int) []*gitlab.AuditEvent {
+	events := []*gitlab.AuditEvent{}
+
+	for _, event := range mock.auditEvents {
+		if event.EntityType == entityType && event.EntityID == entityID {
+			events = append(events, event)
+		}
+	}
+
+	return events
+}
+
+func getAuditEvent(events []*gitlab.AuditEvent, eventID int) (*gitlab.AuditEvent, error) {
+	for _, event := range events {
+		if event.ID == eventID {
+			return event, nil
+		}
+	}
+
+	return nil, newAPIError(http.StatusNotFound, "404 Not found")
+}
@@ -0,0 +1,995 @@
+package gitlabapimock
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/xanzy/go-gitlab"
+	"gopkg.in/yaml.v3"
+)
+
+const ciConfigPath = ".gitlab-ci.yml"
+
+var (
+	defaultCIStages = []string{"build", "test", "deploy"}
+
+	// ciReservedKeywords are the top-level keys of a CI configuration that do not define jobs.
+	ciReservedKeywords = []string{"image", "services", "stages", "types", "before_script", "after_script", "variables", "cache", "include", "workflow", "default", "spec"}
+
+	ciJobWhens  = []string{whenOnSuccess, whenOnFailure, whenAlways, whenManual, "delayed"}
+	ciRuleWhens = []string{whenOnSuccess, whenOnFailure, whenAlways, whenManual, "delayed", "never"}
+
+	// ciRefKeywords maps the special keywords of only:refs and except:refs to the pipeline sources they match.
+	ciRefKeywords = map[string][]string{
+		"api":                    {"api"},
+		"chat":                   {"chat"},
+		"external":               {"external"},
+		"external_pull_requests": {"external_pull_request_event"},
+		"merge_requests":         {"merge_request_event"},
+		"pipelines":              {"pipeline", "parent_pipeline"},
+		"pushes":                 {"push"},
+		"schedules":              {"schedule"},
+		"triggers":               {"trigger"},
+		"web":                    {"web"},
+	}
+)
+
+// ciConfig is a parsed .gitlab-ci.yml, see https://docs.gitlab.com/ee/ci/yaml/
+type ciConfig struct {
+	stages        []string
+	variables     map[string]string
+	defaults      map[string]interface{}
+	workflowRules []*ciRule
+	jobs          []*ciJob
+	warnings      []string
+}
+
+type ciJob struct {
+	name         string
+	definition   map[string]interface{}
+	stage        string
+	when         string
+	allowFailure *bool
+	variables    map[string]string
+	rules        []*ciRule
+	only         *ciRefPolicy
+	except       *ciRefPolicy
+	// needs is nil for jobs that run stage by stage, needs: [] lets a job start right away.
+	needs        []*ciNeed
+	dependencies []string
+}
+
+type ciRule struct {
+	ifExpression string
+	changes      []string
+	compareTo    string
+	exists       []string
+	when         string
+	allowFailure *bool
+	variables    map[string]string
+}
+
+type ciRefPolicy struct {
+	refs      []string
+	variables []string
+	changes   []string
+}
+
+type ciNeed struct {
+	job      string
+	optional bool
+}
+
+// ciPipelineContext holds what the rules of a CI configuration are evaluated against.
+type ciPipelineContext struct {
+	project   *gitlab.Project
+	repo      *repository
+	ref       string
+	sha       string
+	beforeSHA string
+	tag       bool
+	source    string
+	commit    *gitlab.Commit
+}
+
+func toStringList(value interface{}) ([]string, bool) {
+	switch value := value.(type) {
+	case string:
+		return []string{value}, true
+	case []interface{}:
+		list := []string{}
+		for _, item := range value {
+			text, isString := item.(string)
+			if !isString {
+				return nil, false
+			}
+			list = append(list, text)
+		}
+
+		return list, true
+	}
+
+	return nil, false
+}
+
+func toCIVariables(value interface{}) (map[string]string, bool) {
+	if value == nil {
+		return map[string]string{}, true
+	}
+
+	values, isMap := value.(map[string]interface{})
+	if !isMap {
+		return nil, false
+	}
+
+	variables := map[string]string{}
+	for key, value := range values {
+		if options, isMap := value.(map[string]interface{}); isMap {
+			value = options["value"]
+		}
+
+		if value != nil {
+			variables[key] = fmt.Sprint(value)
+		}
+	}
+
+	return variables, true
+}
+
+// deepMerge merges the override into the base like GitLab merges extends: hashes are merged recursively, all
+// other values are replaced.
+func deepMerge(base map[string]interface{}, override map[string]interface{}) map[string]interface{} {
+	merged := map[string]interface{}{}
+
+	for key, value := range base {
+		merged[key] = value
+	}
+
+	for key, value := range override {
+		baseValue, baseIsMap := merged[key].(map[string]interface{})
+		overrideValue, overrideIsMap := value.(map[string]interface{})
+
+		if baseIsMap && overrideIsMap {
+			merged[key] = deepMerge(baseValue, overrideValue)
+		} else {
+			merged[key] = value
+		}
+	}
+
+	return merged
+}
+
+// decodeCIYAML decodes the configuration into a map and returns its keys in the order of the file.
+func decodeCIYAML(content []byte) (map[string]interface{}, []string, error) {
+	var document yaml.Node
+
+	err := yaml.Unmarshal(content, &document)
+	if err != nil {
+		return nil, nil, fmt.Errorf("(<unknown>): %s", strings.TrimPrefix(err.Error(), "yaml: "))
+	}
+
+	if len(document.Content) == 0 || document.Content[0].Kind != yaml.MappingNode {
+		return nil, nil, fmt.Errorf("Invalid configuration format")
+	}
+
+	root := document.Content[0]
+	values := map[string]interface{}{}
+	keys := []string{}
+
+	for index := 0; index+1 < len(root.Content); index += 2 {
+		key := root.Content[index].Value
+
+		var value interface{}
+		err = root.Content[index+1].Decode(&value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("(<unknown>): %s", strings.TrimPrefix(err.Error(), "yaml: "))
+		}
+
+		if !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+
+		values[key] = value
+	}
+
+	return values, keys, nil
+}
+
+// resolveExtends merges the jobs and hidden jobs the job extends into it.
+func resolveExtends(name string, definitions map[string]interface{}, visiting []string) (map[string]interface{}, error) {
+	definition, _ := definitions[name].(map[string]interface{})
+
+	extends, hasExtends := definition["extends"]
+	if !hasExtends {
+		return definition, nil
+	}
+
+	if slices.Contains(visiting, name) {
+		return nil, fmt.Errorf("%s: circular dependency detected in `extends`", visiting[0])
+	}
+
+	parents, isList := toStringList(extends)
+	if !isList {
+		return nil, fmt.Errorf("jobs:%s:extends should be an array of strings or a string", name)
+	}
+
+	merged := map[string]interface{}{}
+
+	for _, parent := range parents {
+		if _, isMap := definitions[parent].(map[string]interface{}); !isMap {
+			return nil, fmt.Errorf("%s: unknown keys in `extends` (%s)", name, parent)
+		}
+
+		resolved, err := resolveExtends(parent, definitions, append(visiting, name))
+		if err != nil {
+			return nil, err
+		}
+
+		merged = deepMerge(merged, resolved)
+	}
+
+	merged = deepMerge(merged, definition)
+	delete(merged, "extends")
+
+	return merged, nil
+}
+
+// parseCIConfig parses a .gitlab-ci.yml that has been decoded and merged with its includes. All errors are
+// collected, like GitLab reports them.
+func parseCIConfig(values map[string]interface{}, keys []string) (*ciConfig, []string) {
+	config := &ciConfig{
+		stages:    append(append([]string{".pre"}, defaultCIStages...), ".post"),
+		variables: map[string]string{},
+		defaults:  map[string]interface{}{},
+	}
+
+	errs := []string{}
+
+	// The global keywords are deprecated in favor of the default section, which takes precedence.
+	for _, keyword := range ciDefaultKeywords {
+		if value, hasKeyword := values[keyword]; hasKeyword && slices.Contains(ciReservedKeywords, keyword) {
+			config.defaults[keyword] = value
+		}
+	}
+
+	if defaultValue, hasDefault := values["default"]; hasDefault {
+		defaults, isMap := defaultValue.(map[string]interface{})
+		if !isMap {
+			errs = append(errs, "default config should be a hash")
+		} else if err := checkCIKeys("default", defaults, ciDefaultKeywords); err != nil {
+			errs = append(errs, err.Error())
+		} else {
+			for keyword, value := range defaults {
+				config.defaults[keyword] = value
+			}
+		}
+	}
+
+	if stagesValue, hasStages := values["stages"]; hasStages {
+		stages, isList := toStringList(stagesValue)
+		if !isList {
+			errs = append(errs, "stages config should be an array of strings")
+		} else {
+			config.stages = append(append([]string{".pre"}, stages...), ".post")
+		}
+	}
+
+	variables, isMap := toCIVariables(values["variables"])
+	if !isMap {
+		errs = append(errs, "variables config should be a hash of key value pairs")
+	} else {
+		config.variables = variables
+	}
+
+	if workflow, hasWorkflow := values["workflow"]; hasWorkflow {
+		workflowValues, isMap := workflow.(map[string]interface{})
+		if !isMap {
+			errs = append(errs, "workflow config should be a hash")
+		} else if err := checkCIKeys("workflow", workflowValues, ciWorkflowKeywords); err != nil {
+			errs = append(errs, err.Error())
+		} else if rulesValue, hasRules := workflowValues["rules"]; hasRules {
+			rules, err := parseCIRules("workflow", rulesValue)
+			if err != nil {
+				errs = append(errs, err.Error())
+			}
+
+			config.workflowRules = rules
+		}
+	}
+
+	for _, name := range keys {
+		if slices.Contains(ciReservedKeywords, name) || strings.HasPrefix(name, ".") {
+			continue
+		}
+
+		if _, isMap := values[name].(map[string]interface{}); !isMap {
+			errs = append(errs, fmt.Sprintf("jobs:%s config should be a hash", name))
+			continue
+		}
+
+		definition, err := resolveExtends(name, values, nil)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+
+		job, jobErrs := parseCIJob(name, definition, config.stages)
+		errs = append(errs, jobErrs...)
+
+		if job != nil {
+			config.jobs = append(config.jobs, job)
+		}
+	}
+
+	if len(config.jobs) == 0 && len(errs) == 0 {
+		errs = append(errs, "jobs config should contain at least one visible job")
+	}
+
+	if len(errs) == 0 {
+		errs = append(errs, config.validateDependencies()...)
+	}
+
+	if config.workflowRules == nil {
+		for _, job := range config.jobs {
+			if slices.ContainsFunc(job.rules, func(rule *ciRule) bool {
+				return rule.ifExpression == "" && rule.changes == nil && rule.exists == nil && rule.when != "" && rule.when != "never"
+			}) {
+				config.warnings = append(config.warnings, fmt.Sprintf("jobs:%s may allow multiple pipelines to run for a single action due to `rules:when` clause with no `workflow:rules` - read more: https://docs.gitlab.com/ee/ci/troubleshooting.html#pipeline-warnings", job.name))
+			}
+		}
+	}
+
+	return config, errs
+}
+
+func parseCIJob(name string, definition map[string]interface{}, stages []string) (*ciJob, []string) {
+	errs := []string{}
+
+	job := &ciJob{
+		name:       name,
+		definition: definition,
+		stage:      defaultStage,
+	}
+
+	if err := checkCIKeys("jobs:"+name, definition, ciJobKeywords); err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	_, hasScript := definition["script"]
+	_, hasTrigger := definition["trigger"]
+	_, hasRun := definition["run"]
+	if !hasScript && !hasTrigger && !hasRun {
+		errs = append(errs, fmt.Sprintf("jobs:%s config should implement the script:, run:, or trigger: keyword", name))
+	}
+
+	if stage, hasStage := definition["stage"]; hasStage {
+		job.stage = fmt.Sprint(stage)
+	}
+
+	if !slices.Contains(stages, job.stage) {
+		errs = append(errs, fmt.Sprintf("jobs:%s chosen stage %s does not exist; available stages are %s", name, job.stage, strings.Join(stages, ", ")))
+	}
+
+	if when, hasWhen := definition["when"]; hasWhen {
+		job.when = fmt.Sprint(when)
+
+		if !slices.Contains(ciJobWhens, job.when) {
+			errs = append(errs, fmt.Sprintf("jobs:%s when unknown value: %s", name, job.when))
+		}
+	}
+
+	if allowFailure, isBool := definition["allow_failure"].(bool); isBool {
+		job.allowFailure = &allowFailure
+	}
+
+	variables, isMap := toCIVariables(definition["variables"])
+	if !isMap {
+		errs = append(errs, fmt.Sprintf("jobs:%s:variables config should be a hash of key value pairs", name))
+	}
+
+	job.variables = variables
+
+	if rulesValue, hasRules := definition["rules"]; hasRules {
+		for _, keyword := range []string{"only", "except"} {
+			if _, hasKeyword := definition[keyword]; hasKeyword {
+				errs = append(errs, fmt.Sprintf("jobs:%s may not be used with `rules`: %s", name, keyword))
+			}
+		}
+
+		rules, err := parseCIRules("jobs:"+name, rulesValue)
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+
+		job.rules = rules
+	}
+
+	for keyword, policy := range map[string]**ciRefPolicy{"only": &job.only, "except": &job.except} {
+		if value, hasKeyword := definition[keyword]; hasKeyword {
+			refPolicy, err := parseCIRefPolicy(fmt.Sprintf("jobs:%s:%s", name, keyword), value)
+			if err != nil {
+				errs = append(errs, err.Error())
+			}
+
+			*policy = refPolicy
+		}
+	}
+
+	if needsValue, hasNeeds := definition["needs"]; hasNeeds {
+		needs, err := parseCINeeds(name, needsValue)
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+
+		job.needs = needs
+	}
+
+	if dependenciesValue, hasDependencies := definition["dependencies"]; hasDependencies {
+		dependencies, isList := toStringList(dependenciesValue)
+		if !isList {
+			errs = append(errs, fmt.Sprintf("jobs:%s dependencies should be an array of strings", name))
+		}
+
+		job.dependencies = dependencies
+	}
+
+	return job, errs
+}
+
+func parseCIRules(location string, value interface{}) ([]*ciRule, error) {
+	items, isList := value.([]interface{})
+	if !isList {
+		return nil, fmt.Errorf("%s:rules config should be an array of hashes", location)
+	}
+
+	rules := []*ciRule{}
+
+	for _, item := range items {
+		values, isMap := item.(map[string]interface{})
+		if !isMap {
+			return nil, fmt.Errorf("%s:rules:rule config should be a hash", location)
+		}
+
+		if err := checkCIKeys(location+":rules:rule", values, ciRuleKeywords); err != nil {
+			return nil, err
+		}
+
+		rule := &ciRule{}
+
+		if ifExpression, hasIf := values["if"]; hasIf {
+			rule.ifExpression = fmt.Sprint(ifExpression)
+
+			_, err := tokenizeCIExpression(rule.ifExpression)
+			if err != nil {
+				return nil, fmt.Errorf("%s:rules:rule if %s", location, err)
+			}
+		}
+
+		switch changes := values["changes"].(type) {
+		case map[string]interface{}:
+			rule.changes, _ = toStringList(changes["paths"])
+			if compareTo, hasCompareTo := changes["compare_to"]; hasCompareTo {
+				rule.compareTo = fmt.Sprint(compareTo)
+			}
+		case nil:
+		default:
+			rule.changes, _ = toStringList(changes)
+		}
+
+		switch exists := values["exists"].(type) {
+		case map[string]interface{}:
+			rule.exists, _ = toStringList(exists["paths"])
+		case nil:
+		default:
+			rule.exists, _ = toStringList(exists)
+		}
+
+		if when, hasWhen := values["when"]; hasWhen {
+			rule.when = fmt.Sprint(when)
+
+			if !slices.Contains(ciRuleWhens, rule.when) {
+				return nil, fmt.Errorf("%s:rules:rule when unknown value: %s", location, rule.when)
+			}
+		}
+
+		if allowFailure, isBool := values["allow_failure"].(bool); isBool {
+			rule.allowFailure = &allowFailure
+		}
+
+		variables, isMap := toCIVariables(values["variables"])
+		if !isMap {
+			return nil, fmt.Errorf("%s:rules:rule:variables config should be a hash of key value pairs", location)
+		}
+
+		rule.variables = variables
+
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+func parseCIRefPolicy(location string, value interface{}) (*ciRefPolicy, error) {
+	if refs, isList := toStringList(value); isList {
+		return &ciRefPolicy{refs: refs}, nil
+	}
+
+	values, isMap := value.(map[string]interface{})
+	if !isMap {
+		return nil, fmt.Errorf("%s config should be an array of strings or a hash", location)
+	}
+
+	policy := &ciRefPolicy{}
+	policy.refs, _ = toStringList(values["refs"])
+	policy.variables, _ = toStringList(values["variables"])
+
+	switch changes := values["changes"].(type) {
+	case map[string]interface{}:
+		policy.changes, _ = toStringList(changes["paths"])
+	default:
+		policy.changes, _ = toStringList(changes)
+	}
+
+	return policy, nil
+}
+
+func parseCINeeds(name string, value interface{}) ([]*ciNeed, error) {
+	items, isList := value.([]interface{})
+	if !isList {
+		return nil, fmt.Errorf("jobs:%s:needs config can only be a hash or an array", name)
+	}
+
+	needs := []*ciNeed{}
+
+	for _, item := range items {
+		switch item := item.(type) {
+		case string:
+			needs = append(needs, &ciNeed{job: item})
+		case map[string]interface{}:
+			job, isString := item["job"].(string)
+			if !isString {
+				// Needs of other pipelines or projects do not affect the jobs of this pipeline.
+				continue
+			}
+
+			optional, _ := item["optional"].(bool)
+			needs = append(needs, &ciNeed{job: job, optional: optional})
+		default:
+			return nil, fmt.Errorf("jobs:%s:needs need can only be a hash or a string", name)
+		}
+	}
+
+	return needs, nil
+}
+
+// ciGlob converts a glob of rules:changes or rules:exists to a regular expression. ** matches any number of
+// directories and {a,b} matches either alternative.
+func ciGlob(glob string) (*regexp.Regexp, error) {
+	var pattern strings.Builder
+
+	pattern.WriteString("^")
+
+	for index := 0; index < len(glob); index++ {
+		switch character := glob[index]; {
+		case strings.HasPrefix(glob[index:], "**/"):
+			pattern.WriteString("(?:.*/)?")
+			index += 2
+		case strings.HasPrefix(glob[index:], "**"):
+			pattern.WriteString(".*")
+			index++
+		case character == '*':
+			pattern.WriteString("[^/]*")
+		case character == '?':
+			pattern.WriteString("[^/]")
+		case character == '{':
+			pattern.WriteString("(?:")
+		case character == '}':
+			pattern.WriteString(")")
+		case character == ',' && strings.Contains(glob[:index], "{"):
+			pattern.WriteString("|")
+		default:
+			pattern.WriteString(regexp.QuoteMeta(string(character)))
+		}
+	}
+
+	pattern.WriteString("$")
+
+	return regexp.Compile(pattern.String())
+}
+
+func matchesAnyGlob(paths []string, globs []string) bool {
+	for _, glob := range globs {
+		pattern, err := ciGlob(glob)
+		if err != nil {
+			continue
+		}
+
+		if slices.ContainsFunc(paths, pattern.MatchString) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// predefinedVariables returns the predefined CI/CD variables that are known when the pipeline is created, see
+// https://docs.gitlab.com/ee/ci/variables/predefined_variables.html
+func (context *ciPipelineContext) predefinedVariables() map[string]string {
+	variables := map[string]string{
+		"CI":                   "true",
+		"GITLAB_CI":            "true",
+		"CI_COMMIT_REF_NAME":   context.ref,
+		"CI_COMMIT_REF_SLUG":   refSlug(context.ref),
+		"CI_COMMIT_SHA":        context.sha,
+		"CI_COMMIT_SHORT_SHA":  context.sha[:min(8, len(context.sha))],
+		"CI_COMMIT_BEFORE_SHA": context.beforeSHA,
+		"CI_DEFAULT_BRANCH":    context.project.DefaultBranch,
+		"CI_PIPELINE_SOURCE":   context.source,
+		"CI_PROJECT_ID":        fmt.Sprint(context.project.ID),
+		"CI_PROJECT_NAME":      context.project.Path,
+		"CI_PROJECT_PATH":      context.project.PathWithNamespace,
+	}
+
+	if context.project.Namespace != nil {
+		variables["CI_PROJECT_NAMESPACE"] = context.project.Namespace.FullPath
+	}
+
+	if context.tag {
+		variables["CI_COMMIT_TAG"] = context.ref
+	} else {
+		variables["CI_COMMIT_BRANCH"] = context.ref
+	}
+
+	if context.commit != nil {
+		variables["CI_COMMIT_MESSAGE"] = context.commit.Message
+		variables["CI_COMMIT_TITLE"] = context.commit.Title
+	}
+
+	return variables
+}
+
+var refSlugPattern = regexp.MustCompile(`[^a-z0-9]+`)
+
+// refSlug implements CI_COMMIT_REF_SLUG: lowercased, shortened to 63 bytes and with everything except 0-9
+// and a-z replaced by -.
+func refSlug(ref string) string {
+	slug := refSlugPattern.ReplaceAllString(strings.ToLower(ref), "-")
+	slug = slug[:min(63, len(slug))]
+
+	return strings.Trim(slug, "-")
+}
+
+// changes reports whether one of the files matching the globs changed in the pipeline. Like in GitLab, the
+// check is always true for pipelines that are not created by a push to an existing branch.
+func (context *ciPipelineContext) changes(globs []string, compareTo string) bool {
+	base := context.beforeSHA
+
+	if compareTo != "" {
+		sha, err := context.repo.resolve(compareTo)
+		if err != nil {
+			return false
+		}
+
+		base = sha
+	} else if context.source != "push" || base == "" || base == zeroSHA {
+		return true
+	}
+
+	changedFiles, err := context.repo.changedFiles(base, context.sha)
+	if err != nil {
+		return true
+	}
+
+	return matchesAnyGlob(changedFiles, globs)
+}
+
+func (context *ciPipelineContext) exists(globs []string) bool {
+	files, err := context.repo.files(context.sha)
+	if err != nil {
+		return false
+	}
+
+	return matchesAnyGlob(files, globs)
+}
+
+// matchRules returns the first rule whose clauses all match, or nil if there is none.
+func (context *ciPipelineContext) matchRules(rules []*ciRule, variables map[string]string) (*ciRule, error) {
+	for _, rule := range rules {
+		if rule.ifExpression != "" {
+			matches, err := evaluateCIExpression(rule.ifExpression, variables)
+			if err != nil {
+				return nil, err
+			}
+
+			if !matches {
+				continue
+			}
+		}
+
+		if rule.changes != nil && !context.changes(rule.changes, rule.compareTo) {
+			continue
+		}
+
+		if rule.exists != nil && !context.exists(rule.exists) {
+			continue
+		}
+
+		return rule, nil
+	}
+
+	return nil, nil
+}
+
+// matchesRefPolicy implements only and except: all given keys need at least one matching condition.
+func (context *ciPipelineContext) matchesRefPolicy(policy *ciRefPolicy, variables map[string]string) bool {
+	if policy.refs != nil && !slices.ContainsFunc(policy.refs, context.matchesRef) {
+		return false
+	}
+
+	if policy.variables != nil && !slices.ContainsFunc(policy.variables, func(expression string) bool {
+		matches, _ := evaluateCIExpression(expression, variables)
+		return matches
+	}) {
+		return false
+	}
+
+	if policy.changes != nil && !context.changes(policy.changes, "") {
+		return false
+	}
+
+	return true
+}
+
+func (context *ciPipelineContext) matchesRef(ref string) bool {
+	ref, projectPath, hasProjectPath := strings.Cut(ref, "@")
+	if hasProjectPath && projectPath != context.project.PathWithNamespace {
+		return false
+	}
+
+	switch {
+	case ref == "branches":
+		return !context.tag && context.source != "merge_request_event"
+	case ref == "tags":
+		return context.tag
+	case ciRefKeywords[ref] != nil:
+		return slices.Contains(ciRefKeywords[ref], context.source)
+	case strings.HasPrefix(ref, "/"):
+		pattern, err := ciRegexp(ref)
+		return err == nil && pattern.MatchString(context.ref)
+	}
+
+	return ref == context.ref
+}
+
+// pipelineJobs evaluates workflow:rules and the rules, only and except of the jobs and returns the jobs of the
+// pipeline ordered by stage.
+func (config *ciConfig) pipelineJobs(context *ciPipelineContext, pipelineVariables []*gitlab.PipelineVariable) ([]PipelineJob, error) {
+	variables := context.predefinedVariables()
+	for key, value := range config.variables {
+		variables[key] = value
+	}
+
+	overrides := map[string]string{}
+	for _, variable := range pipelineVariables {
+		overrides[variable.Key] = variable.Value
+	}
+
+	withOverrides := func(variables map[string]string) map[string]string {
+		merged := map[string]string{}
+		for _, values := range []map[string]string{variables, overrides} {
+			for key, value := range values {
+				merged[key] = value
+			}
+		}
+
+		return merged
+	}
+
+	if config.workflowRules != nil {
+		rule, err := context.matchRules(config.workflowRules, withOverrides(variables))
+		if err != nil {
+			return nil, newAPIError(http.StatusBadRequest, map[string][]string{"base": {err.Error()}})
+		}
+
+		if rule == nil || rule.when == "never" {
+			return nil, newAPIError(http.StatusBadRequest, map[string][]string{"base": {"Pipeline filtered out by workflow rules."}})
+		}
+
+		for key, value := range rule.variables {
+			variables[key] = value
+		}
+	}
+
+	jobs := []PipelineJob{}
+
+	for _, job := range config.jobs {
+		jobVariables := map[string]string{}
+		for _, values := range []map[string]string{variables, job.variables} {
+			for key, value := range values {
+				jobVariables[key] = value
+			}
+		}
+
+		jobVariables = withOverrides(jobVariables)
+
+		pipelineJob, included, err := context.evaluateJob(job, jobVariables)
+		if err != nil {
+			return nil, newAPIError(http.StatusBadRequest, map[string][]string{"base": {err.Error()}})
+		}
+
+		if included {
+			config.applyJobDefinition(job, &pipelineJob)
+			jobs = append(jobs, pipelineJob)
+		}
+	}
+
+	if len(jobs) == 0 {
+		return nil, newAPIError(http.StatusBadRequest, map[string][]string{"base": {"Pipeline will not run for the selected trigger. The rules configuration prevented any jobs from being added to the pipeline."}})
+	}
+
+	err := resolveNeeds(jobs, config.jobs)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortStableFunc(jobs, func(left PipelineJob, right PipelineJob) int {
+		return slices.Index(config.stages, left.Stage) - slices.Index(config.stages, right.Stage)
+	})
+
+	return jobs, nil
+}
+
+// evaluateJob decides whether the job is part of the pipeline and when it runs.
+// applyJobDefinition copies what runners need to run the job from its definition, falling back to the
+// default section for keywords the job does not set.
+func (config *ciConfig) applyJobDefinition(job *ciJob, pipelineJob *PipelineJob) {
+	keyword := func(name string) interface{} {
+		if value, hasValue := job.definition[name]; hasValue {
+			return value
+		}
+
+		return config.defaults[name]
+	}
+
+	beforeScript, _ := toStringList(keyword("before_script"))
+	script, _ := toStringList(job.definition["script"])
+
+	pipelineJob.Script = append(beforeScript, script...)
+	pipelineJob.AfterScript, _ = toStringList(keyword("after_script"))
+	pipelineJob.Tags, _ = toStringList(keyword("tags"))
+
+	pipelineJob.Variables = map[string]string{}
+	for _, variables := range []map[string]string{config.variables, job.variables} {
+		for key, value := range variables {
+			pipelineJob.Variables[key] = value
+		}
+	}
+}
+
+func (context *ciPipelineContext) evaluateJob(job *ciJob, variables map[string]string) (PipelineJob, bool, error) {
+	pipelineJob := PipelineJob{
+		Name:  job.name,
+		Stage: job.stage,
+		When:  job.when,
+	}
+
+	if job.needs != nil {
+		pipelineJob.Needs = []string{}
+		for _, need := range job.needs {
+			pipelineJob.Needs = append(pipelineJob.Needs, need.job)
+		}
+	}
+
+	if pipelineJob.When == "" || pipelineJob.When == "delayed" {
+		pipelineJob.When = whenOnSuccess
+	}
+
+	if job.rules != nil {
+		rule, err := context.matchRules(job.rules, variables)
+		if err != nil {
+			return pipelineJob, false, fmt.Errorf("jobs:%s:rules:rule if %s", job.name, err)
+		}
+
+		if rule == nil || rule.when == "never" {
+			return pipelineJob, false, nil
+		}
+
+		if rule.when != "" && rule.when != "delayed" {
+			pipelineJob.When = rule.when
+		}
+
+		// With rules, manual jobs do not allow failure by default.
+		pipelineJob.AllowFailure = valueOf(job.allowFailure)
+		if rule.allowFailure != nil {
+			pipelineJob.AllowFailure = *rule.allowFailure
+		}
+
+		return pipelineJob, true, nil
+	}
+
+	only := job.only
+	if only == nil {
+		only = &ciRefPolicy{refs: []string{"branches", "tags"}}
+	}
+
+	if !context.matchesRefPolicy(only, variables) {
+		return pipelineJob, false, nil
+	}
+
+	if job.except != nil && context.matchesRefPolicy(job.except, variables) {
+		return pipelineJob, false, nil
+	}
+
+	pipelineJob.AllowFailure = pipelineJob.When == whenManual
+	if job.allowFailure != nil {
+		pipelineJob.AllowFailure = *job.allowFailure
+	}
+
+	return pipelineJob, true, nil
+}
+
+// resolveNeeds drops optional needs of jobs that are not part of the pipeline and rejects the other ones.
+func resolveNeeds(jobs []PipelineJob, ciJobs []*ciJob) error {
+	names := []string{}
+	for _, job := range jobs {
+		names = append(names, job.Name)
+	}
+
+	for index, job := range jobs {
+		if job.Needs == nil {
+			continue
+		}
+
+		ciJobIndex := slices.IndexFunc(ciJobs, func(ciJob *ciJob) bool {
+			return ciJob.name == job.Name
+		})
+
+		needs := []string{}
+
+		for _, need := range ciJobs[ciJobIndex].needs {
+			if slices.Contains(names, need.job) {
+				needs = append(needs, need.job)
+				continue
+			}
+
+			if !need.optional {
+				return newAPIError(http.StatusBadRequest, map[string][]string{"base": {fmt.Sprintf("'%s' job needs '%s' job, but '%s' does not exist in the pipeline. This might be because of the only, except, or rules keywords. To need a job that sometimes does not exist in the pipeline, use needs:optional.", job.Name, need.job, need.job)}})
+			}
+		}
+
+		jobs[index].Needs = needs
+	}
+
+	return nil
+}
+
+// ciPipelineJobs creates the jobs of a pipeline from the .gitlab-ci.yml of the project at the commit. Projects
+// without a configuration file fall back to the jobs set with SetPipelineJobs.
+func (mock *GitlabMock) ciPipelineJobs(context *ciPipelineContext, pipelineVariables []*gitlab.PipelineVariable) ([]PipelineJob, error) {
+	content, err := context.repo.readFile(context.sha, ciConfigPath)
+	if err != nil {
+		jobs, hasJobs := mock.pipelineJobSpecs[context.project.ID]
+		if !hasJobs {
+			return nil, newAPIError(http.StatusBadRequest, map[string][]string{"base": {"Missing CI config file"}})
+		}
+
+		return jobs, nil
+	}
+
+	loader := &ciConfigLoader{project: context.project, repo: context.repo, sha: context.sha}
+
+	values, keys, err := loader.load(content)
+	if err != nil {
+		return nil, newAPIError(http.StatusBadRequest, map[string][]string{"base": {err.Error()}})
+	}
+
+	config, errs := parseCIConfig(values, keys)
+	if len(errs) > 0 {
+		return nil, newAPIError(http.StatusBadRequest, map[string][]string{"base": errs})
+	}
+
+	return config.pipelineJobs(context, pipelineVariables)
+}
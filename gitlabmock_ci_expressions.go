@@ -0,0 +1,252 @@
+package gitlabapimock
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// ciExpression evaluates the CI/CD variable expressions of rules:if, only:variables and except:variables, see
+// https://docs.gitlab.com/ee/ci/jobs/job_rules.html#cicd-variable-expressions
+type ciExpression struct {
+	tokens    []string
+	position  int
+	variables map[string]string
+}
+
+// ciValue is the value of an operand. Undefined variables and null are represented by nil.
+type ciValue *string
+
+var ciVariablePattern = regexp.MustCompile(`^\$(\{[A-Za-z_][A-Za-z0-9_]*\}|[A-Za-z_][A-Za-z0-9_]*)`)
+
+// evaluateCIExpression reports whether the expression is true for the variables.
+func evaluateCIExpression(expression string, variables map[string]string) (bool, error) {
+	tokens, err := tokenizeCIExpression(expression)
+	if err != nil {
+		return false, err
+	}
+
+	parser := &ciExpression{tokens: tokens, variables: variables}
+
+	result, err := parser.or()
+	if err != nil {
+		return false, err
+	}
+
+	if parser.position < len(parser.tokens) {
+		return false, fmt.Errorf("invalid expression syntax: unexpected %s", parser.tokens[parser.position])
+	}
+
+	return result, nil
+}
+
+func tokenizeCIExpression(expression string) ([]string, error) {
+	tokens := []string{}
+
+	for index := 0; index < len(expression); {
+		rest := expression[index:]
+
+		switch {
+		case unicode.IsSpace(rune(rest[0])):
+			index++
+		case strings.HasPrefix(rest, "&&"), strings.HasPrefix(rest, "||"), strings.HasPrefix(rest, "=="),
+			strings.HasPrefix(rest, "!="), strings.HasPrefix(rest, "=~"), strings.HasPrefix(rest, "!~"):
+			tokens = append(tokens, rest[:2])
+			index += 2
+		case rest[0] == '(' || rest[0] == ')':
+			tokens = append(tokens, rest[:1])
+			index++
+		case rest[0] == '"' || rest[0] == '\'' || rest[0] == '/':
+			end := strings.IndexByte(rest[1:], rest[0])
+			for end >= 0 && rest[0] == '/' && rest[end] == '\\' {
+				next := strings.IndexByte(rest[end+2:], '/')
+				if next < 0 {
+					end = -1
+					break
+				}
+				end += next + 1
+			}
+
+			if end < 0 {
+				return nil, fmt.Errorf("invalid expression syntax: unterminated %c", rest[0])
+			}
+
+			length := end + 2
+			if rest[0] == '/' {
+				for length < len(rest) && strings.ContainsRune("im", rune(rest[length])) {
+					length++
+				}
+			}
+
+			tokens = append(tokens, rest[:length])
+			index += length
+		case ciVariablePattern.MatchString(rest):
+			variable := ciVariablePattern.FindString(rest)
+			tokens = append(tokens, variable)
+			index += len(variable)
+		case strings.HasPrefix(rest, "null"):
+			tokens = append(tokens, "null")
+			index += 4
+		default:
+			return nil, fmt.Errorf("invalid expression syntax: unexpected %q", rest)
+		}
+	}
+
+	return tokens, nil
+}
+
+func (parser *ciExpression) peek() string {
+	if parser.position < len(parser.tokens) {
+		return parser.tokens[parser.position]
+	}
+
+	return ""
+}
+
+func (parser *ciExpression) next() string {
+	token := parser.peek()
+	parser.position++
+
+	return token
+}
+
+func (parser *ciExpression) or() (bool, error) {
+	result, err := parser.and()
+	if err != nil {
+		return false, err
+	}
+
+	for parser.peek() == "||" {
+		parser.next()
+
+		right, err := parser.and()
+		if err != nil {
+			return false, err
+		}
+
+		result = result || right
+	}
+
+	return result, nil
+}
+
+func (parser *ciExpression) and() (bool, error) {
+	result, err := parser.primary()
+	if err != nil {
+		return false, err
+	}
+
+	for parser.peek() == "&&" {
+		parser.next()
+
+		right, err := parser.primary()
+		if err != nil {
+			return false, err
+		}
+
+		result = result && right
+	}
+
+	return result, nil
+}
+
+func (parser *ciExpression) primary() (bool, error) {
+	if parser.peek() == "(" {
+		parser.next()
+
+		result, err := parser.or()
+		if err != nil {
+			return false, err
+		}
+
+		if parser.next() != ")" {
+			return false, fmt.Errorf("invalid expression syntax: missing )")
+		}
+
+		return result, nil
+	}
+
+	leftToken := parser.next()
+	left, err := parser.operand(leftToken)
+	if err != nil {
+		return false, err
+	}
+
+	switch operator := parser.peek(); operator {
+	case "==", "!=":
+		parser.next()
+
+		right, err := parser.operand(parser.next())
+		if err != nil {
+			return false, err
+		}
+
+		equal := (left == nil && right == nil) || (left != nil && right != nil && *left == *right)
+
+		return equal == (operator == "=="), nil
+	case "=~", "!~":
+		parser.next()
+
+		rightToken := parser.next()
+		if strings.HasPrefix(rightToken, "$") {
+			value, err := parser.operand(rightToken)
+			if err != nil {
+				return false, err
+			}
+
+			if value != nil {
+				rightToken = *value
+			}
+		}
+
+		pattern, err := ciRegexp(rightToken)
+		if err != nil {
+			return false, err
+		}
+
+		matches := left != nil && pattern.MatchString(*left)
+
+		return matches == (operator == "=~"), nil
+	}
+
+	// A variable on its own is true if it is defined and not empty.
+	return strings.HasPrefix(leftToken, "$") && left != nil && *left != "", nil
+}
+
+func (parser *ciExpression) operand(token string) (ciValue, error) {
+	switch {
+	case token == "null":
+		return nil, nil
+	case strings.HasPrefix(token, "$"):
+		name := strings.Trim(strings.TrimPrefix(token, "$"), "{}")
+
+		value, defined := parser.variables[name]
+		if !defined {
+			return nil, nil
+		}
+
+		return &value, nil
+	case strings.HasPrefix(token, `"`) || strings.HasPrefix(token, "'"):
+		value := token[1 : len(token)-1]
+		return &value, nil
+	}
+
+	return nil, fmt.Errorf("invalid expression syntax: unexpected %s", token)
+}
+
+// ciRegexp converts a /pattern/flags literal to a regular expression.
+func ciRegexp(literal string) (*regexp.Regexp, error) {
+	end := strings.LastIndexByte(literal, '/')
+	if !strings.HasPrefix(literal, "/") || end <= 0 {
+		return nil, fmt.Errorf("invalid expression syntax: %s is not a regular expression", literal)
+	}
+
+	pattern := literal[1:end]
+	// Ruby's multiline flag lets the dot match newlines, which is the s flag in Go.
+	if flags := strings.ReplaceAll(literal[end+1:], "m", "s"); flags != "" {
+		pattern = fmt.Sprintf("(?%s)%s", flags, pattern)
+	}
+
+	return regexp.Compile(pattern)
+}
@@ -0,0 +1,487 @@
+package gitlabapimock
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/xanzy/go-gitlab"
+	"gopkg.in/yaml.v3"
+)
+
+const maxCIIncludes = 150
+
+var (
+	ciJobKeywords = []string{"after_script", "allow_failure", "artifacts", "before_script", "cache", "coverage", "dast_configuration",
+		"dependencies", "environment", "except", "extends", "hooks", "id_tokens", "identity", "image", "inherit", "interruptible",
+		"manual_confirmation", "needs", "only", "pages", "parallel", "release", "resource_group", "retry", "rules", "run", "script",
+		"secrets", "services", "stage", "start_in", "tags", "timeout", "trigger", "variables", "when"}
+	ciDefaultKeywords  = []string{"after_script", "artifacts", "before_script", "cache", "hooks", "id_tokens", "image", "interruptible", "retry", "services", "tags", "timeout"}
+	ciWorkflowKeywords = []string{"auto_cancel", "name", "rules"}
+	ciRuleKeywords     = []string{"allow_failure", "changes", "exists", "if", "interruptible", "needs", "start_in", "variables", "when"}
+)
+
+// ciInclude describes a file that has been included into a CI configuration, as listed by the lint API.
+type ciInclude struct {
+	Type           string                 `json:"type"`
+	Location       string                 `json:"location"`
+	Blob           string                 `json:"blob"`
+	Raw            string                 `json:"raw"`
+	Extra          map[string]interface{} `json:"extra"`
+	ContextProject string                 `json:"context_project"`
+	ContextSHA     string                 `json:"context_sha"`
+}
+
+// ciLintJob is a job as listed by the lint API with include_jobs.
+type ciLintJob struct {
+	Name         string        `json:"name"`
+	Stage        string        `json:"stage"`
+	BeforeScript []string      `json:"before_script"`
+	Script       []string      `json:"script"`
+	AfterScript  []string      `json:"after_script"`
+	TagList      []string      `json:"tag_list"`
+	Only         interface{}   `json:"only,omitempty"`
+	Except       interface{}   `json:"except,omitempty"`
+	Environment  interface{}   `json:"environment,omitempty"`
+	When         string        `json:"when"`
+	AllowFailure bool          `json:"allow_failure"`
+	Needs        []*ciLintNeed `json:"needs,omitempty"`
+}
+
+type ciLintNeed struct {
+	Name string `json:"name"`
+}
+
+// ciLintResult implements the response of https://docs.gitlab.com/ee/api/lint.html. Status is only set by the
+// instance endpoint, which GitLab kept for backwards compatibility.
+type ciLintResult struct {
+	Status     string       `json:"status,omitempty"`
+	Valid      bool         `json:"valid"`
+	Errors     []string     `json:"errors"`
+	Warnings   []string     `json:"warnings"`
+	MergedYaml string       `json:"merged_yaml"`
+	Includes   []*ciInclude `json:"includes"`
+	Jobs       []*ciLintJob `json:"jobs,omitempty"`
+}
+
+// checkCIKeys rejects keys that are not known keywords at the location.
+func checkCIKeys(location string, values map[string]interface{}, keywords []string) error {
+	unknownKeys := []string{}
+
+	for key := range values {
+		if !slices.Contains(keywords, key) {
+			unknownKeys = append(unknownKeys, key)
+		}
+	}
+
+	if len(unknownKeys) == 0 {
+		return nil
+	}
+
+	sort.Strings(unknownKeys)
+
+	return fmt.Errorf("%s config contains unknown keys: %s", location, strings.Join(unknownKeys, ", "))
+}
+
+// validateDependencies checks that needs and dependencies reference jobs of the current or prior stages and that
+// needs do not form a cycle.
+func (config *ciConfig) validateDependencies() []string {
+	errs := []string{}
+
+	stageIndex := func(name string) int {
+		for _, job := range config.jobs {
+			if job.name == name {
+				return slices.Index(config.stages, job.stage)
+			}
+		}
+
+		return -1
+	}
+
+	for _, job := range config.jobs {
+		jobStageIndex := slices.Index(config.stages, job.stage)
+
+		for _, need := range job.needs {
+			needStageIndex := stageIndex(need.job)
+
+			switch {
+			case needStageIndex < 0 && !need.optional:
+				errs = append(errs, fmt.Sprintf("%s job: undefined need: %s", job.name, need.job))
+			case needStageIndex > jobStageIndex:
+				errs = append(errs, fmt.Sprintf("%s job: need %s is not defined in current or prior stages", job.name, need.job))
+			}
+		}
+
+		for _, dependency := range job.dependencies {
+			dependencyStageIndex := stageIndex(dependency)
+
+			switch {
+			case dependencyStageIndex < 0:
+				errs = append(errs, fmt.Sprintf("%s job: undefined dependency: %s", job.name, dependency))
+			case dependencyStageIndex > jobStageIndex:
+				errs = append(errs, fmt.Sprintf("%s job: dependency %s is not defined in current or prior stages", job.name, dependency))
+			}
+		}
+	}
+
+	if len(errs) == 0 && config.hasNeedsCycle() {
+		errs = append(errs, "The pipeline has circular dependencies")
+	}
+
+	return errs
+}
+
+func (config *ciConfig) hasNeedsCycle() bool {
+	needs := map[string][]string{}
+	for _, job := range config.jobs {
+		for _, need := range job.needs {
+			needs[job.name] = append(needs[job.name], need.job)
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	states := map[string]int{}
+
+	var visit func(name string) bool
+	visit = func(name string) bool {
+		switch states[name] {
+		case visiting:
+			return true
+		case visited:
+			return false
+		}
+
+		states[name] = visiting
+
+		for _, need := range needs[name] {
+			if visit(need) {
+				return true
+			}
+		}
+
+		states[name] = visited
+
+		return false
+	}
+
+	for _, job := range config.jobs {
+		if states[job.name] == unvisited && visit(job.name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ciConfigLoader reads a CI configuration together with the files it includes. Only include:local is resolved,
+// against the repository of the project at the commit.
+type ciConfigLoader struct {
+	project  *gitlab.Project
+	repo     *repository
+	sha      string
+	includes []*ciInclude
+	warnings []string
+}
+
+// load decodes the configuration and merges the included files into it. The configuration itself takes
+// precedence over the included files.
+func (loader *ciConfigLoader) load(content []byte) (map[string]interface{}, []string, error) {
+	values, keys, err := decodeCIYAML(content)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	includeValue, hasInclude := values["include"]
+	if !hasInclude {
+		return values, keys, nil
+	}
+
+	locations, err := loader.localIncludes(includeValue)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	merged := map[string]interface{}{}
+	mergedKeys := []string{}
+
+	for _, location := range locations {
+		if slices.ContainsFunc(loader.includes, func(include *ciInclude) bool {
+			return include.Location == location
+		}) {
+			continue
+		}
+
+		if len(loader.includes) >= maxCIIncludes {
+			return nil, nil, fmt.Errorf("Maximum of %d nested includes are allowed!", maxCIIncludes)
+		}
+
+		if loader.project == nil {
+			return nil, nil, fmt.Errorf("Local file `%s` does not have project!", location)
+		}
+
+		includedContent, err := loader.repo.readFile(loader.sha, strings.TrimPrefix(location, "/"))
+		if err != nil {
+			return nil, nil, fmt.Errorf("Local file `%s` does not exist!", location)
+		}
+
+		loader.includes = append(loader.includes, &ciInclude{
+			Type:           "local",
+			Location:       location,
+			Blob:           fmt.Sprintf("/%s/-/blob/%s/%s", loader.project.PathWithNamespace, loader.sha, strings.TrimPrefix(location, "/")),
+			Raw:            fmt.Sprintf("/%s/-/raw/%s/%s", loader.project.PathWithNamespace, loader.sha, strings.TrimPrefix(location, "/")),
+			Extra:          map[string]interface{}{},
+			ContextProject: loader.project.PathWithNamespace,
+			ContextSHA:     loader.sha,
+		})
+
+		includedValues, includedKeys, err := loader.load(includedContent)
+		if err != nil {
+			if strings.HasPrefix(err.Error(), "(<unknown>)") || err.Error() == "Invalid configuration format" {
+				return nil, nil, fmt.Errorf("Included file `%s` does not have valid YAML syntax!", location)
+			}
+
+			return nil, nil, err
+		}
+
+		merged = deepMerge(merged, includedValues)
+		mergedKeys = append(mergedKeys, includedKeys...)
+	}
+
+	delete(values, "include")
+	merged = deepMerge(merged, values)
+
+	orderedKeys := []string{}
+	for _, key := range append(mergedKeys, keys...) {
+		if key != "include" && !slices.Contains(orderedKeys, key) {
+			orderedKeys = append(orderedKeys, key)
+		}
+	}
+
+	return merged, orderedKeys, nil
+}
+
+// localIncludes returns the paths of the local files the include keyword references. Paths with wildcards
+// are expanded against the repository.
+func (loader *ciConfigLoader) localIncludes(value interface{}) ([]string, error) {
+	items, isList := value.([]interface{})
+	if !isList {
+		items = []interface{}{value}
+	}
+
+	locations := []string{}
+
+	for _, item := range items {
+		var location string
+
+		switch item := item.(type) {
+		case string:
+			if strings.Contains(item, "://") {
+				loader.warnings = append(loader.warnings, fmt.Sprintf("include:remote `%s` has not been resolved", item))
+				continue
+			}
+
+			location = item
+		case map[string]interface{}:
+			local, isLocal := item["local"].(string)
+			if !isLocal {
+				for _, includeType := range []string{"remote", "project", "template", "component"} {
+					if _, hasType := item[includeType]; hasType {
+						loader.warnings = append(loader.warnings, fmt.Sprintf("include:%s has not been resolved", includeType))
+					}
+				}
+				continue
+			}
+
+			location = local
+		default:
+			return nil, fmt.Errorf("include config should be a hash, a string or an array containing strings and/or hashes")
+		}
+
+		if !strings.Contains(location, "*") {
+			locations = append(locations, location)
+			continue
+		}
+
+		if loader.repo == nil {
+			return nil, fmt.Errorf("Local file `%s` does not have project!", location)
+		}
+
+		files, err := loader.repo.files(loader.sha)
+		if err != nil {
+			return nil, err
+		}
+
+		pattern, err := ciGlob(strings.TrimPrefix(location, "/"))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, file := range files {
+			if pattern.MatchString(file) {
+				locations = append(locations, "/"+file)
+			}
+		}
+	}
+
+	return locations, nil
+}
+
+// mergedYAML renders the merged configuration with the top-level keys in the order of the files.
+func mergedYAML(values map[string]interface{}, keys []string) string {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+
+	for _, key := range keys {
+		value := &yaml.Node{}
+		if err := value.Encode(values[key]); err != nil {
+			continue
+		}
+
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, value)
+	}
+
+	output, err := yaml.Marshal(root)
+	if err != nil {
+		return ""
+	}
+
+	return string(output)
+}
+
+func toLintJob(job *ciJob, when string, allowFailure bool, needs []string) *ciLintJob {
+	lintJob := &ciLintJob{
+		Name:         job.name,
+		Stage:        job.stage,
+		Only:         job.definition["only"],
+		Except:       job.definition["except"],
+		Environment:  job.definition["environment"],
+		When:         when,
+		AllowFailure: allowFailure,
+	}
+
+	lintJob.BeforeScript, _ = toStringList(job.definition["before_script"])
+	lintJob.Script, _ = toStringList(job.definition["script"])
+	lintJob.AfterScript, _ = toStringList(job.definition["after_script"])
+	lintJob.TagList, _ = toStringList(job.definition["tags"])
+
+	for _, need := range needs {
+		lintJob.Needs = append(lintJob.Needs, &ciLintNeed{Name: need})
+	}
+
+	return lintJob
+}
+
+// lintCIConfig validates the content like https://docs.gitlab.com/ee/api/lint.html. Without a project, includes
+// cannot be resolved. A dry run simulates the creation of a pipeline for the ref, which also evaluates rules.
+func (mock *GitlabMock) lintCIConfig(project *gitlab.Project, content string, ref string, dryRun bool, includeJobs bool) (*ciLintResult, error) {
+	result := &ciLintResult{
+		Errors:   []string{},
+		Warnings: []string{},
+		Includes: []*ciInclude{},
+	}
+
+	loader := &ciConfigLoader{project: project}
+
+	var context *ciPipelineContext
+
+	if project != nil {
+		repo, err := mock.getRepository(project)
+		if err != nil {
+			return nil, err
+		}
+
+		if ref == "" {
+			ref = project.DefaultBranch
+		}
+
+		sha, err := repo.resolve(ref)
+		if err != nil && dryRun {
+			return nil, newAPIError(http.StatusBadRequest, map[string][]string{"base": {"Reference not found"}})
+		}
+
+		loader.repo = repo
+		loader.sha = sha
+
+		if sha != "" {
+			refs, _ := repo.refs()
+			_, isTag := refs["refs/tags/"+ref]
+			commit, _ := repo.commit(sha)
+
+			context = &ciPipelineContext{project: project, repo: repo, ref: ref, sha: sha, beforeSHA: zeroSHA, tag: isTag, source: "push", commit: commit}
+		}
+	}
+
+	values, keys, err := loader.load([]byte(content))
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
+		return result, nil
+	}
+
+	result.Includes = append(result.Includes, loader.includes...)
+	result.Warnings = append(result.Warnings, loader.warnings...)
+
+	config, errs := parseCIConfig(values, keys)
+	result.Errors = append(result.Errors, errs...)
+
+	if len(result.Errors) > 0 {
+		return result, nil
+	}
+
+	result.Valid = true
+	result.MergedYaml = mergedYAML(values, keys)
+	result.Warnings = append(result.Warnings, config.warnings...)
+
+	if !dryRun || context == nil {
+		if includeJobs {
+			for _, job := range config.jobs {
+				when := job.when
+				if when == "" {
+					when = whenOnSuccess
+				}
+
+				needs := []string{}
+				for _, need := range job.needs {
+					needs = append(needs, need.job)
+				}
+
+				result.Jobs = append(result.Jobs, toLintJob(job, when, valueOf(job.allowFailure) || when == whenManual, needs))
+			}
+		}
+
+		return result, nil
+	}
+
+	jobs, err := config.pipelineJobs(context, nil)
+	if err != nil {
+		result.Valid = false
+
+		if apiErr, isAPIError := err.(*apiError); isAPIError {
+			if messages, isMap := apiErr.message.(map[string][]string); isMap {
+				result.Errors = append(result.Errors, messages["base"]...)
+				return result, nil
+			}
+		}
+
+		result.Errors = append(result.Errors, err.Error())
+
+		return result, nil
+	}
+
+	if includeJobs {
+		for _, job := range jobs {
+			ciJobIndex := slices.IndexFunc(config.jobs, func(ciJob *ciJob) bool {
+				return ciJob.name == job.Name
+			})
+
+			result.Jobs = append(result.Jobs, toLintJob(config.jobs[ciJobIndex], job.When, job.AllowFailure, job.Needs))
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,191 @@
+package gitlabapimock
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronField describes the values a field of a cron expression may have.
+type cronField struct {
+	min   int
+	max   int
+	names []string
+}
+
+var cronFields = []cronField{
+	{min: 0, max: 59},
+	{min: 0, max: 23},
+	{min: 1, max: 31},
+	{min: 1, max: 12, names: []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}},
+	{min: 0, max: 7, names: []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}},
+}
+
+// cronMacros are the shortcuts Fugit, which GitLab parses cron expressions with, accepts.
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// cronSchedule is a parsed cron expression: the allowed minutes, hours, days, months and weekdays in the
+// time zone of the schedule.
+type cronSchedule struct {
+	fields   [5]map[int]bool
+	location *time.Location
+
+	// Like in cron, a day matches either field when both the day of month and the weekday are restricted.
+	daysRestricted     bool
+	weekdaysRestricted bool
+}
+
+// parseCron parses a cron expression with five fields, which may contain lists, ranges, steps and names.
+func parseCron(expression string, timezone string) (*cronSchedule, error) {
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cron timezone %q", timezone)
+	}
+
+	if macro, isMacro := cronMacros[strings.ToLower(strings.TrimSpace(expression))]; isMacro {
+		expression = macro
+	}
+
+	parts := strings.Fields(expression)
+	if len(parts) != len(cronFields) {
+		return nil, fmt.Errorf("invalid cron expression %q", expression)
+	}
+
+	schedule := &cronSchedule{location: location}
+
+	for i, part := range parts {
+		values, err := parseCronField(strings.ToLower(part), cronFields[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron expression %q: %w", expression, err)
+		}
+
+		schedule.fields[i] = values
+	}
+
+	// Sunday may be given as 0 or 7.
+	if schedule.fields[4][7] {
+		schedule.fields[4][0] = true
+	}
+
+	schedule.daysRestricted = !strings.HasPrefix(parts[2], "*")
+	schedule.weekdaysRestricted = !strings.HasPrefix(parts[4], "*")
+
+	return schedule, nil
+}
+
+func parseCronField(part string, field cronField) (map[int]bool, error) {
+	values := map[int]bool{}
+
+	for _, item := range strings.Split(part, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(item, "/")
+
+		step := 1
+		if hasStep {
+			var err error
+
+			step, err = strconv.Atoi(stepPart)
+			if err != nil || step <= 0 {
+				return nil, fmt.Errorf("invalid step %q", item)
+			}
+		}
+
+		start, end := field.min, field.max
+
+		if rangePart != "*" {
+			startPart, endPart, isRange := strings.Cut(rangePart, "-")
+
+			var err error
+
+			start, err = parseCronValue(startPart, field)
+			if err != nil {
+				return nil, err
+			}
+
+			end = start
+			if isRange {
+				end, err = parseCronValue(endPart, field)
+				if err != nil {
+					return nil, err
+				}
+			} else if hasStep {
+				end = field.max
+			}
+		}
+
+		if start > end {
+			return nil, fmt.Errorf("invalid range %q", item)
+		}
+
+		for value := start; value <= end; value += step {
+			values[value] = true
+		}
+	}
+
+	return values, nil
+}
+
+func parseCronValue(value string, field cronField) (int, error) {
+	for i, name := range field.names {
+		if value == name {
+			return i + field.min, nil
+		}
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil || number < field.min || number > field.max {
+		return 0, fmt.Errorf("invalid value %q", value)
+	}
+
+	return number, nil
+}
+
+func (schedule *cronSchedule) matchesDay(t time.Time) bool {
+	dayMatches := schedule.fields[2][t.Day()]
+	weekdayMatches := schedule.fields[4][int(t.Weekday())]
+
+	switch {
+	case schedule.daysRestricted && schedule.weekdaysRestricted:
+		return dayMatches || weekdayMatches
+	case schedule.daysRestricted:
+		return dayMatches
+	case schedule.weekdaysRestricted:
+		return weekdayMatches
+	}
+
+	return true
+}
+
+// next returns the first time after the given time the schedule matches, or the zero time if it never does,
+// e.g. for the 31st of February.
+func (schedule *cronSchedule) next(after time.Time) time.Time {
+	t := after.In(schedule.location).Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		year, month, day := t.Date()
+
+		switch {
+		case !schedule.fields[3][int(month)]:
+			t = time.Date(year, month+1, 1, 0, 0, 0, 0, schedule.location)
+		case !schedule.matchesDay(t):
+			t = time.Date(year, month, day+1, 0, 0, 0, 0, schedule.location)
+		case !schedule.fields[1][t.Hour()]:
+			t = time.Date(year, month, day, t.Hour()+1, 0, 0, 0, schedule.location)
+		case !schedule.fields[0][t.Minute()]:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+
+	return time.Time{}
+}
@@ -0,0 +1,195 @@
+package gitlabapimock
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+const (
+	deployTokenPrefix = "gldt-"
+
+	// gitlabDeployTokenName is the name of the deploy token GitLab exposes to CI/CD jobs as CI_DEPLOY_USER and
+	// CI_DEPLOY_PASSWORD, see https://docs.gitlab.com/ee/user/project/deploy_tokens/#gitlab-deploy-token.
+	gitlabDeployTokenName = "gitlab-deploy-token"
+)
+
+// deployTokenScopes are the scopes of deploy tokens, see
+// https://docs.gitlab.com/ee/user/project/deploy_tokens/#scope.
+var deployTokenScopes = []string{
+	"read_repository", "read_registry", "write_registry", "read_package_registry", "write_package_registry",
+}
+
+// deployToken is a deploy token of a project or a group, group deploy tokens give access to all projects of the
+// group and its subgroups.
+type deployToken struct {
+	gitlab.DeployToken
+
+	projectID int
+	groupID   int
+}
+
+// createDeployToken validates the options and creates a deploy token for the project or group. Without a
+// username the token gets the username gitlab+deploy-token-<id>.
+func (mock *GitlabMock) createDeployToken(projectID int, groupID int, name *string, username *string, expiresAt *time.Time, scopes *[]string) (*deployToken, error) {
+	if name == nil || *name == "" {
+		return nil, newAPIError(http.StatusBadRequest, "name is missing")
+	}
+
+	validScopes, err := validateTokenScopes(scopes, deployTokenScopes)
+	if err != nil {
+		return nil, err
+	}
+
+	if expiresAt != nil && !mock.now().Before(*expiresAt) {
+		return nil, newAPIError(http.StatusBadRequest, map[string][]string{"expires_at": {"must be in the future"}})
+	}
+
+	id := int(mock.deployTokenIds.Add(1))
+
+	token := &deployToken{
+		DeployToken: gitlab.DeployToken{
+			ID:        id,
+			Name:      *name,
+			Username:  fmt.Sprintf("gitlab+deploy-token-%d", id),
+			ExpiresAt: expiresAt,
+			Token:     newToken(deployTokenPrefix),
+			Scopes:    validScopes,
+		},
+		projectID: projectID,
+		groupID:   groupID,
+	}
+
+	if username != nil && *username != "" {
+		token.Username = *username
+	}
+
+	mock.deployTokens = append(mock.deployTokens, token)
+
+	return token, nil
+}
+
+// AddProjectDeployToken registers a deploy token with the given value for the project.
+func (mock *GitlabMock) AddProjectDeployToken(project *gitlab.Project, name string, username string, token string, scopes []string) (*gitlab.DeployToken, error) {
+	deployToken, err := mock.createDeployToken(project.ID, 0, &name, &username, nil, &scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	deployToken.Token = token
+
+	return &deployToken.DeployToken, nil
+}
+
+// AddGroupDeployToken registers a deploy token with the given value for the group.
+func (mock *GitlabMock) AddGroupDeployToken(group *gitlab.Group, name string, username string, token string, scopes []string) (*gitlab.DeployToken, error) {
+	deployToken, err := mock.createDeployToken(0, group.ID, &name, &username, nil, &scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	deployToken.Token = token
+
+	return &deployToken.DeployToken, nil
+}
+
+// deployTokenExpired reports whether the expiry date of the deploy token has been reached on the clock of the mock.
+func (mock *GitlabMock) deployTokenExpired(token *deployToken) bool {
+	return token.ExpiresAt != nil && !mock.now().Before(*token.ExpiresAt)
+}
+
+// deployTokenActive reports whether the deploy token is neither revoked nor expired.
+func (mock *GitlabMock) deployTokenActive(token *deployToken) bool {
+	return !token.Revoked && !mock.deployTokenExpired(token)
+}
+
+// visibleDeployToken returns the deploy token without its value, which GitLab only shows when the token is created.
+func (mock *GitlabMock) visibleDeployToken(token *deployToken) *gitlab.DeployToken {
+	visibleToken := token.DeployToken
+	visibleToken.Token = ""
+	visibleToken.Expired = mock.deployTokenExpired(token)
+
+	return &visibleToken
+}
+
+// filterDeployTokens returns the visible deploy tokens matching the filter, only active ones if active is set.
+func (mock *GitlabMock) filterDeployTokens(active *bool, filter func(*deployToken) bool) []*gitlab.DeployToken {
+	deployTokens := []*gitlab.DeployToken{}
+
+	for _, token := range mock.deployTokens {
+		if !filter(token) {
+			continue
+		}
+
+		if active != nil && mock.deployTokenActive(token) != *active {
+			continue
+		}
+
+		deployTokens = append(deployTokens, mock.visibleDeployToken(token))
+	}
+
+	return deployTokens
+}
+
+// getDeployToken returns the deploy token of the project or group.
+func (mock *GitlabMock) getDeployToken(projectID int, groupID int, tokenID int) (*deployToken, error) {
+	for _, token := range mock.deployTokens {
+		if token.ID == tokenID && token.projectID == projectID && token.groupID == groupID {
+			return token, nil
+		}
+	}
+
+	return nil, newAPIError(http.StatusNotFound, "404 Not Found")
+}
+
+// authenticateDeployToken resolves the deploy token of basic auth credentials. It returns nil if the password is
+// no deploy token, the username has to match the one of the token.
+func (mock *GitlabMock) authenticateDeployToken(username string, password string) (*deployToken, error) {
+	for _, token := range mock.deployTokens {
+		if token.Token != password {
+			continue
+		}
+
+		if token.Username != username || !mock.deployTokenActive(token) {
+			return nil, errUnauthorized
+		}
+
+		return token, nil
+	}
+
+	return nil, nil
+}
+
+// deployTokenAllowsProject reports whether the deploy token belongs to the project or one of its groups.
+func (mock *GitlabMock) deployTokenAllowsProject(token *deployToken, project *gitlab.Project) bool {
+	if token.projectID != 0 {
+		return token.projectID == project.ID
+	}
+
+	if project.Namespace == nil {
+		return false
+	}
+
+	group, err := mock.getGroup(project.Namespace.ID)
+	if err != nil {
+		return false
+	}
+
+	return slices.ContainsFunc(mock.groupAncestors(group), func(ancestor *gitlab.Group) bool {
+		return ancestor.ID == token.groupID
+	})
+}
+
+// gitlabDeployToken returns the active deploy token named gitlab-deploy-token of the project or its groups.
+func (mock *GitlabMock) gitlabDeployToken(project *gitlab.Project) (*deployToken, bool) {
+	for _, token := range mock.deployTokens {
+		if token.Name == gitlabDeployTokenName && mock.deployTokenActive(token) && mock.deployTokenAllowsProject(token, project) {
+			return token, true
+		}
+	}
+
+	return nil, false
+}
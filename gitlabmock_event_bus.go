@@ -0,0 +1,175 @@
+package gitlabapimock
+
+import (
+	"sync"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// Event is a change of the state of the mock, published to the subscribers of the mock. Use a type switch to
+// handle the events, e.g. UserCreated or ProjectMemberAdded. Members, pipelines and jobs are copies of their state
+// when the event was published, later changes of the mock do not show in them.
+type Event interface {
+	event()
+}
+
+// UserCreated is published when a user is added.
+type UserCreated struct {
+	User *gitlab.User
+}
+
+// GroupCreated is published when a group or subgroup is added.
+type GroupCreated struct {
+	Group *gitlab.Group
+}
+
+// ProjectCreated is published when a project is added.
+type ProjectCreated struct {
+	Project *gitlab.Project
+}
+
+// ProjectMemberAdded is published when a user becomes a member of a project.
+type ProjectMemberAdded struct {
+	Project *gitlab.Project
+	Member  *gitlab.ProjectMember
+}
+
+// ProjectMemberAccessChanged is published when the access level of a project member is edited.
+type ProjectMemberAccessChanged struct {
+	Project        *gitlab.Project
+	Member         *gitlab.ProjectMember
+	OldAccessLevel gitlab.AccessLevelValue
+	NewAccessLevel gitlab.AccessLevelValue
+}
+
+// GroupMemberAdded is published when a user becomes a member of a group.
+type GroupMemberAdded struct {
+	Group  *gitlab.Group
+	Member *gitlab.GroupMember
+}
+
+// MemberRemoved is published when a user is removed from a project or a group, only one of both is set.
+type MemberRemoved struct {
+	Project *gitlab.Project
+	Group   *gitlab.Group
+	UserID  int
+}
+
+// PipelineStatusChanged is published when the status of a pipeline changes.
+type PipelineStatusChanged struct {
+	Pipeline  *gitlab.Pipeline
+	OldStatus string
+}
+
+// JobStatusChanged is published when the status of a job changes.
+type JobStatusChanged struct {
+	Job       *gitlab.Job
+	OldStatus string
+}
+
+func (UserCreated) event()                {}
+func (GroupCreated) event()               {}
+func (ProjectCreated) event()             {}
+func (ProjectMemberAdded) event()         {}
+func (ProjectMemberAccessChanged) event() {}
+func (GroupMemberAdded) event()           {}
+func (MemberRemoved) event()              {}
+func (PipelineStatusChanged) event()      {}
+func (JobStatusChanged) event()           {}
+
+type eventSubscriber struct {
+	id       int
+	callback func(Event)
+}
+
+// Subscribe calls the callback with every event published from now on until the returned function is called.
+// Callbacks are called synchronously by the change that published the event, which may be an API request, so
+// they must not block and must not change the mock.
+func (mock *GitlabMock) Subscribe(callback func(Event)) (unsubscribe func()) {
+	mock.eventsMutex.Lock()
+	defer mock.eventsMutex.Unlock()
+
+	mock.eventSubscriberIds++
+	subscriber := &eventSubscriber{id: mock.eventSubscriberIds, callback: callback}
+	mock.eventSubscribers = append(mock.eventSubscribers, subscriber)
+
+	return func() {
+		mock.eventsMutex.Lock()
+		defer mock.eventsMutex.Unlock()
+
+		for i, other := range mock.eventSubscribers {
+			if other.id == subscriber.id {
+				mock.eventSubscribers = append(mock.eventSubscribers[:i:i], mock.eventSubscribers[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// Events returns a channel receiving every event published from now on, in order. Events are queued for slow
+// receivers instead of blocking the mock. The returned function ends the subscription and closes the channel,
+// events that were not received yet are dropped.
+func (mock *GitlabMock) Events() (<-chan Event, func()) {
+	events := make(chan Event)
+	done := make(chan struct{})
+	queued := make(chan struct{}, 1)
+
+	var queueMutex sync.Mutex
+	var queue []Event
+
+	unsubscribe := mock.Subscribe(func(event Event) {
+		queueMutex.Lock()
+		queue = append(queue, event)
+		queueMutex.Unlock()
+
+		select {
+		case queued <- struct{}{}:
+		default:
+		}
+	})
+
+	go func() {
+		defer close(events)
+
+		for {
+			queueMutex.Lock()
+			pending := queue
+			queue = nil
+			queueMutex.Unlock()
+
+			for _, event := range pending {
+				select {
+				case events <- event:
+				case <-done:
+					return
+				}
+			}
+
+			select {
+			case <-queued:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+
+	return events, func() {
+		once.Do(func() {
+			unsubscribe()
+			close(done)
+		})
+	}
+}
+
+// publish passes the event to the subscribers in the order they subscribed.
+func (mock *GitlabMock) publish(event Event) {
+	mock.eventsMutex.Lock()
+	subscribers := append([]*eventSubscriber{}, mock.eventSubscribers...)
+	mock.eventsMutex.Unlock()
+
+	for _, subscriber := range subscribers {
+		subscriber.callback(event)
+	}
+}
@@ -0,0 +1,202 @@
+package gitlabapimock
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// approvedEventType is the action of merge request approvals, which go-gitlab has no constant for.
+const approvedEventType gitlab.EventTypeValue = "approved"
+
+// activityEvent is an event of the activity feeds of users and projects, see
+// https://docs.gitlab.com/ee/api/events.html. The action is the value the action filter matches, the action name
+// describes it in more detail, e.g. the action pushed has the action names "pushed to", "pushed new" and
+// "deleted".
+type activityEvent struct {
+	*gitlab.ContributionEvent
+	action gitlab.EventTypeValue
+}
+
+// targetType returns the target type in the format of the target_type filter, e.g. merge_request for
+// MergeRequest. All kinds of notes are notes.
+func (event *activityEvent) targetType() gitlab.EventTargetTypeValue {
+	if strings.HasSuffix(event.TargetType, "Note") {
+		return gitlab.NoteEventTargetType
+	}
+
+	var targetType strings.Builder
+
+	for i, char := range event.TargetType {
+		if i > 0 && char >= 'A' && char <= 'Z' {
+			targetType.WriteByte('_')
+		}
+
+		targetType.WriteRune(char)
+	}
+
+	return gitlab.EventTargetTypeValue(strings.ToLower(targetType.String()))
+}
+
+// addActivityEvent records an event of the author in the project. Changes without author, e.g. the ones made
+// through the Go API without a user, are not recorded.
+func (mock *GitlabMock) addActivityEvent(project *gitlab.Project, author *gitlab.User, action gitlab.EventTypeValue, event *gitlab.ContributionEvent) {
+	if author == nil {
+		return
+	}
+
+	createdAt := mock.now()
+
+	event.ID = int(mock.activityEventIds.Add(1))
+	event.ProjectID = project.ID
+	event.AuthorID = author.ID
+	event.AuthorUsername = author.Username
+	event.Author.ID = author.ID
+	event.Author.Name = author.Name
+	event.Author.Username = author.Username
+	event.Author.State = "active"
+	event.Author.AvatarURL = author.AvatarURL
+	event.Author.WebURL = author.WebURL
+	event.CreatedAt = &createdAt
+
+	mock.activityEvents = append(mock.activityEvents, &activityEvent{ContributionEvent: event, action: action})
+}
+
+// addPushEvents records the pushed, created and deleted branches and tags of a push.
+func (mock *GitlabMock) addPushEvents(project *gitlab.Project, repo *repository, user *gitlab.User, updates []refUpdate) {
+	if user == nil {
+		return
+	}
+
+	for _, update := range updates {
+		ref, refType := strings.TrimPrefix(update.Ref, "refs/heads/"), "branch"
+		if tag, isTag := strings.CutPrefix(update.Ref, "refs/tags/"); isTag {
+			ref, refType = tag, "tag"
+		} else if ref == update.Ref {
+			continue
+		}
+
+		event := &gitlab.ContributionEvent{ActionName: "pushed to"}
+		event.PushData.Action = "pushed"
+		event.PushData.RefType = refType
+		event.PushData.Ref = ref
+		event.PushData.CommitFrom = update.Before
+		event.PushData.CommitTo = update.After
+
+		switch {
+		case update.After == zeroSHA:
+			event.ActionName = "deleted"
+			event.PushData.Action = "removed"
+			event.PushData.CommitTo = ""
+		case update.Before == zeroSHA:
+			event.ActionName = "pushed new"
+			event.PushData.Action = "created"
+			event.PushData.CommitFrom = ""
+		}
+
+		if update.After != zeroSHA {
+			output, err := repo.git(nil, nil, append([]string{"rev-list", "--count"}, pushedRevisions(update)...)...)
+			if err == nil {
+				event.PushData.CommitCount, _ = strconv.Atoi(strings.TrimSpace(string(output)))
+			}
+
+			if commit, err := repo.commit(update.After); err == nil {
+				event.PushData.CommitTitle = commit.Title
+			}
+		}
+
+		mock.addActivityEvent(project, user, gitlab.PushedEventType, event)
+	}
+}
+
+// stateEventActions are the actions and action names of the issue and merge request actions of webhooks that
+// are shown in the activity feeds.
+var stateEventActions = map[string]struct {
+	action     gitlab.EventTypeValue
+	actionName string
+}{
+	"open":     {gitlab.CreatedEventType, "opened"},
+	"close":    {gitlab.ClosedEventType, "closed"},
+	"reopen":   {gitlab.ReopenedEventType, "reopened"},
+	"merge":    {gitlab.MergedEventType, "accepted"},
+	"approved": {approvedEventType, "approved"},
+}
+
+// addIssueEvent records the action of webhooks on the issue, e.g. open or close.
+func (mock *GitlabMock) addIssueEvent(project *gitlab.Project, issue *gitlab.Issue, action string, user *gitlab.User) {
+	stateAction, exists := stateEventActions[action]
+	if !exists {
+		return
+	}
+
+	mock.addActivityEvent(project, user, stateAction.action, &gitlab.ContributionEvent{
+		ActionName:  stateAction.actionName,
+		TargetID:    issue.ID,
+		TargetIID:   issue.IID,
+		TargetType:  "Issue",
+		TargetTitle: issue.Title,
+	})
+}
+
+// addMergeRequestEvent records the action of webhooks on the merge request, e.g. open, merge or approved.
+func (mock *GitlabMock) addMergeRequestEvent(project *gitlab.Project, mergeRequest *gitlab.MergeRequest, action string, user *gitlab.User) {
+	stateAction, exists := stateEventActions[action]
+	if !exists {
+		return
+	}
+
+	mock.addActivityEvent(project, user, stateAction.action, &gitlab.ContributionEvent{
+		ActionName:  stateAction.actionName,
+		TargetID:    mergeRequest.ID,
+		TargetIID:   mergeRequest.IID,
+		TargetType:  "MergeRequest",
+		TargetTitle: mergeRequest.Title,
+	})
+}
+
+// addNoteEvent records a new comment, system notes are no contributions.
+func (mock *GitlabMock) addNoteEvent(project *gitlab.Project, noteable noteable, note *gitlab.Note, user *gitlab.User) {
+	if note.System {
+		return
+	}
+
+	targetType := "Note"
+	switch note.Type {
+	case gitlab.DiscussionNote:
+		targetType = "DiscussionNote"
+	case gitlab.DiffNote:
+		targetType = "DiffNote"
+	}
+
+	event := &gitlab.ContributionEvent{
+		ActionName: "commented on",
+		TargetID:   note.ID,
+		TargetIID:  note.ID,
+		TargetType: targetType,
+		Note:       note,
+	}
+
+	switch noteable.Type {
+	case noteableIssue:
+		if issue, err := mock.getIssue(project, noteable.IID); err == nil {
+			event.TargetTitle = issue.Title
+		}
+	case noteableMergeRequest:
+		if mergeRequest, err := mock.getMergeRequest(project, noteable.IID); err == nil {
+			event.TargetTitle = mergeRequest.Title
+		}
+	}
+
+	mock.addActivityEvent(project, user, gitlab.CommentedEventType, event)
+}
+
+// addMemberEvent records a user joining or leaving the project as an event of the member.
+func (mock *GitlabMock) addMemberEvent(project *gitlab.Project, userID int, action gitlab.EventTypeValue) {
+	user, err := mock.getUser(userID)
+	if err != nil {
+		return
+	}
+
+	mock.addActivityEvent(project, user, action, &gitlab.ContributionEvent{ActionName: string(action)})
+}
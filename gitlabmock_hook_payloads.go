@@ -0,0 +1,1177 @@
+package gitlabapimock
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// The payload types follow https://docs.gitlab.com/ee/user/project/integrations/webhook_events.html and decode
+// into the event types of go-gitlab, see gitlab.ParseWebhook.
+
+const maxPushHookCommits = 20
+
+var (
+	visibilityLevels = map[gitlab.VisibilityValue]int{
+		gitlab.PrivateVisibility:  0,
+		gitlab.InternalVisibility: 10,
+		gitlab.PublicVisibility:   20,
+	}
+
+	stateIDs = map[string]gitlab.StateID{
+		issueOpened:        gitlab.StateIDOpen,
+		issueClosed:        gitlab.StateIDClosed,
+		mergeRequestMerged: gitlab.StateIDMerged,
+		"locked":           gitlab.StateIDLocked,
+	}
+
+	accessLevelNames = map[gitlab.AccessLevelValue]string{
+		gitlab.MinimalAccessPermissions: "Minimal Access",
+		gitlab.GuestPermissions:         "Guest",
+		gitlab.ReporterPermissions:      "Reporter",
+		gitlab.DeveloperPermissions:     "Developer",
+		gitlab.MaintainerPermissions:    "Maintainer",
+		gitlab.OwnerPermissions:         "Owner",
+	}
+)
+
+type hookProject struct {
+	ID                int    `json:"id"`
+	Name              string `json:"name"`
+	Description       string `json:"description"`
+	WebURL            string `json:"web_url"`
+	AvatarURL         string `json:"avatar_url"`
+	GitSSHURL         string `json:"git_ssh_url"`
+	GitHTTPURL        string `json:"git_http_url"`
+	Namespace         string `json:"namespace"`
+	VisibilityLevel   int    `json:"visibility_level"`
+	PathWithNamespace string `json:"path_with_namespace"`
+	DefaultBranch     string `json:"default_branch"`
+	CIConfigPath      string `json:"ci_config_path"`
+	Homepage          string `json:"homepage"`
+	URL               string `json:"url"`
+	SSHURL            string `json:"ssh_url"`
+	HTTPURL           string `json:"http_url"`
+}
+
+type hookRepository struct {
+	Name            string `json:"name"`
+	URL             string `json:"url"`
+	Description     string `json:"description"`
+	Homepage        string `json:"homepage"`
+	GitHTTPURL      string `json:"git_http_url"`
+	GitSSHURL       string `json:"git_ssh_url"`
+	VisibilityLevel int    `json:"visibility_level"`
+}
+
+type hookUser struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	Username  string `json:"username"`
+	AvatarURL string `json:"avatar_url"`
+	Email     string `json:"email"`
+}
+
+type hookLabel struct {
+	ID          int     `json:"id"`
+	Title       string  `json:"title"`
+	Color       string  `json:"color"`
+	ProjectID   *int    `json:"project_id"`
+	CreatedAt   *string `json:"created_at"`
+	UpdatedAt   *string `json:"updated_at"`
+	Template    bool    `json:"template"`
+	Description string  `json:"description"`
+	Type        string  `json:"type"`
+	GroupID     *int    `json:"group_id"`
+}
+
+type hookCommitAuthor struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+type hookCommit struct {
+	ID        string           `json:"id"`
+	Message   string           `json:"message"`
+	Title     string           `json:"title"`
+	Timestamp *time.Time       `json:"timestamp"`
+	URL       string           `json:"url"`
+	Author    hookCommitAuthor `json:"author"`
+}
+
+type hookPushCommit struct {
+	hookCommit
+	Added    []string `json:"added"`
+	Modified []string `json:"modified"`
+	Removed  []string `json:"removed"`
+}
+
+type hookChange struct {
+	Previous interface{} `json:"previous"`
+	Current  interface{} `json:"current"`
+}
+
+type pushHookPayload struct {
+	ObjectKind        string            `json:"object_kind"`
+	EventName         string            `json:"event_name"`
+	Before            string            `json:"before"`
+	After             string            `json:"after"`
+	Ref               string            `json:"ref"`
+	RefProtected      bool              `json:"ref_protected"`
+	CheckoutSHA       *string           `json:"checkout_sha"`
+	Message           *string           `json:"message"`
+	UserID            int               `json:"user_id"`
+	UserName          string            `json:"user_name"`
+	UserUsername      string            `json:"user_username"`
+	UserEmail         string            `json:"user_email"`
+	UserAvatar        string            `json:"user_avatar"`
+	ProjectID         int               `json:"project_id"`
+	Project           hookProject       `json:"project"`
+	Commits           []*hookPushCommit `json:"commits"`
+	TotalCommitsCount int               `json:"total_commits_count"`
+	Repository        hookRepository    `json:"repository"`
+}
+
+type issueHookAttributes struct {
+	ID               int             `json:"id"`
+	IID              int             `json:"iid"`
+	Title            string          `json:"title"`
+	Description      string          `json:"description"`
+	State            string          `json:"state"`
+	StateID          gitlab.StateID  `json:"state_id"`
+	Action           string          `json:"action,omitempty"`
+	AuthorID         int             `json:"author_id"`
+	ProjectID        int             `json:"project_id"`
+	AssigneeIDs      []int           `json:"assignee_ids"`
+	AssigneeID       *int            `json:"assignee_id"`
+	MilestoneID      *int            `json:"milestone_id"`
+	Confidential     bool            `json:"confidential"`
+	DiscussionLocked bool            `json:"discussion_locked"`
+	DueDate          *gitlab.ISOTime `json:"due_date"`
+	MovedToID        *int            `json:"moved_to_id"`
+	Weight           int             `json:"weight"`
+	CreatedAt        *string         `json:"created_at"`
+	UpdatedAt        *string         `json:"updated_at"`
+	ClosedAt         *string         `json:"closed_at"`
+	URL              string          `json:"url"`
+	Labels           []*hookLabel    `json:"labels"`
+}
+
+type issueHookPayload struct {
+	ObjectKind       string                `json:"object_kind"`
+	EventType        string                `json:"event_type"`
+	User             *hookUser             `json:"user"`
+	Project          hookProject           `json:"project"`
+	ObjectAttributes issueHookAttributes   `json:"object_attributes"`
+	Repository       hookRepository        `json:"repository"`
+	Assignees        []*hookUser           `json:"assignees"`
+	Labels           []*hookLabel          `json:"labels"`
+	Changes          map[string]hookChange `json:"changes"`
+}
+
+type mergeRequestHookAttributes struct {
+	ID                  int            `json:"id"`
+	IID                 int            `json:"iid"`
+	Title               string         `json:"title"`
+	Description         string         `json:"description"`
+	State               string         `json:"state"`
+	StateID             gitlab.StateID `json:"state_id"`
+	Action              string         `json:"action,omitempty"`
+	OldRev              string         `json:"oldrev,omitempty"`
+	SourceBranch        string         `json:"source_branch"`
+	SourceProjectID     int            `json:"source_project_id"`
+	TargetBranch        string         `json:"target_branch"`
+	TargetProjectID     int            `json:"target_project_id"`
+	AuthorID            int            `json:"author_id"`
+	AssigneeIDs         []int          `json:"assignee_ids"`
+	AssigneeID          *int           `json:"assignee_id"`
+	ReviewerIDs         []int          `json:"reviewer_ids"`
+	MilestoneID         *int           `json:"milestone_id"`
+	MergeStatus         string         `json:"merge_status"`
+	DetailedMergeStatus string         `json:"detailed_merge_status"`
+	MergeCommitSHA      *string        `json:"merge_commit_sha"`
+	MergeUserID         *int           `json:"merge_user_id"`
+	Draft               bool           `json:"draft"`
+	WorkInProgress      bool           `json:"work_in_progress"`
+	HeadPipelineID      *int           `json:"head_pipeline_id"`
+	CreatedAt           *string        `json:"created_at"`
+	UpdatedAt           *string        `json:"updated_at"`
+	URL                 string         `json:"url"`
+	Source              hookProject    `json:"source"`
+	Target              hookProject    `json:"target"`
+	LastCommit          *hookCommit    `json:"last_commit"`
+	Labels              []*hookLabel   `json:"labels"`
+}
+
+type mergeRequestHookPayload struct {
+	ObjectKind       string                     `json:"object_kind"`
+	EventType        string                     `json:"event_type"`
+	User             *hookUser                  `json:"user"`
+	Project          hookProject                `json:"project"`
+	ObjectAttributes mergeRequestHookAttributes `json:"object_attributes"`
+	Repository       hookRepository             `json:"repository"`
+	Assignees        []*hookUser                `json:"assignees"`
+	Reviewers        []*hookUser                `json:"reviewers"`
+	Labels           []*hookLabel               `json:"labels"`
+	Changes          map[string]hookChange      `json:"changes"`
+}
+
+type noteHookAttributes struct {
+	ID           int                  `json:"id"`
+	Note         string               `json:"note"`
+	NoteableType string               `json:"noteable_type"`
+	NoteableID   *int                 `json:"noteable_id"`
+	AuthorID     int                  `json:"author_id"`
+	ProjectID    int                  `json:"project_id"`
+	CommitID     *string              `json:"commit_id"`
+	DiscussionID string               `json:"discussion_id"`
+	Type         *string              `json:"type"`
+	System       bool                 `json:"system"`
+	Position     *gitlab.NotePosition `json:"position"`
+	Action       string               `json:"action"`
+	CreatedAt    *string              `json:"created_at"`
+	UpdatedAt    *string              `json:"updated_at"`
+	URL          string               `json:"url"`
+}
+
+type noteHookSnippet struct {
+	ID          int    `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	FileName    string `json:"file_name"`
+	AuthorID    int    `json:"author_id"`
+	ProjectID   int    `json:"project_id"`
+}
+
+type noteHookPayload struct {
+	ObjectKind       string                      `json:"object_kind"`
+	EventType        string                      `json:"event_type"`
+	User             *hookUser                   `json:"user"`
+	ProjectID        int                         `json:"project_id"`
+	Project          hookProject                 `json:"project"`
+	Repository       hookRepository              `json:"repository"`
+	ObjectAttributes noteHookAttributes          `json:"object_attributes"`
+	Issue            *issueHookAttributes        `json:"issue,omitempty"`
+	MergeRequest     *mergeRequestHookAttributes `json:"merge_request,omitempty"`
+	Commit           *hookCommit                 `json:"commit,omitempty"`
+	Snippet          *noteHookSnippet            `json:"snippet,omitempty"`
+}
+
+type hookVariable struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+type pipelineHookAttributes struct {
+	ID             int             `json:"id"`
+	IID            int             `json:"iid"`
+	Ref            string          `json:"ref"`
+	Tag            bool            `json:"tag"`
+	SHA            string          `json:"sha"`
+	BeforeSHA      string          `json:"before_sha"`
+	Source         string          `json:"source"`
+	Status         string          `json:"status"`
+	DetailedStatus string          `json:"detailed_status"`
+	Stages         []string        `json:"stages"`
+	CreatedAt      *string         `json:"created_at"`
+	FinishedAt     *string         `json:"finished_at"`
+	Duration       int             `json:"duration"`
+	QueuedDuration int             `json:"queued_duration"`
+	URL            string          `json:"url"`
+	Variables      []*hookVariable `json:"variables"`
+}
+
+type hookRunner struct {
+	ID          int      `json:"id"`
+	Description string   `json:"description"`
+	Active      bool     `json:"active"`
+	RunnerType  string   `json:"runner_type"`
+	IsShared    bool     `json:"is_shared"`
+	Tags        []string `json:"tags"`
+}
+
+type hookArtifactsFile struct {
+	Filename *string `json:"filename"`
+	Size     *int    `json:"size"`
+}
+
+type pipelineHookBuild struct {
+	ID             int               `json:"id"`
+	Stage          string            `json:"stage"`
+	Name           string            `json:"name"`
+	Status         string            `json:"status"`
+	CreatedAt      *string           `json:"created_at"`
+	StartedAt      *string           `json:"started_at"`
+	FinishedAt     *string           `json:"finished_at"`
+	Duration       float64           `json:"duration"`
+	QueuedDuration float64           `json:"queued_duration"`
+	FailureReason  *string           `json:"failure_reason"`
+	When           string            `json:"when"`
+	Manual         bool              `json:"manual"`
+	AllowFailure   bool              `json:"allow_failure"`
+	User           *hookUser         `json:"user"`
+	Runner         *hookRunner       `json:"runner"`
+	ArtifactsFile  hookArtifactsFile `json:"artifacts_file"`
+}
+
+type pipelineHookPayload struct {
+	ObjectKind       string                 `json:"object_kind"`
+	ObjectAttributes pipelineHookAttributes `json:"object_attributes"`
+	User             *hookUser              `json:"user"`
+	Project          hookProject            `json:"project"`
+	Commit           *hookCommit            `json:"commit"`
+	Builds           []*pipelineHookBuild   `json:"builds"`
+}
+
+type jobHookCommit struct {
+	ID          int     `json:"id"`
+	Name        *string `json:"name"`
+	SHA         string  `json:"sha"`
+	Message     string  `json:"message"`
+	AuthorName  string  `json:"author_name"`
+	AuthorEmail string  `json:"author_email"`
+	AuthorURL   string  `json:"author_url"`
+	Status      string  `json:"status"`
+	Duration    int     `json:"duration"`
+	StartedAt   *string `json:"started_at"`
+	FinishedAt  *string `json:"finished_at"`
+}
+
+type jobHookPayload struct {
+	ObjectKind          string         `json:"object_kind"`
+	Ref                 string         `json:"ref"`
+	Tag                 bool           `json:"tag"`
+	BeforeSHA           string         `json:"before_sha"`
+	SHA                 string         `json:"sha"`
+	BuildID             int            `json:"build_id"`
+	BuildName           string         `json:"build_name"`
+	BuildStage          string         `json:"build_stage"`
+	BuildStatus         string         `json:"build_status"`
+	BuildCreatedAt      *string        `json:"build_created_at"`
+	BuildStartedAt      *string        `json:"build_started_at"`
+	BuildFinishedAt     *string        `json:"build_finished_at"`
+	BuildDuration       float64        `json:"build_duration"`
+	BuildQueuedDuration float64        `json:"build_queued_duration"`
+	BuildAllowFailure   bool           `json:"build_allow_failure"`
+	BuildFailureReason  string         `json:"build_failure_reason"`
+	RetriesCount        int            `json:"retries_count"`
+	PipelineID          int            `json:"pipeline_id"`
+	ProjectID           int            `json:"project_id"`
+	ProjectName         string         `json:"project_name"`
+	User                *hookUser      `json:"user"`
+	Commit              jobHookCommit  `json:"commit"`
+	Repository          hookRepository `json:"repository"`
+	Runner              *hookRunner    `json:"runner"`
+}
+
+// memberHookPayload is the payload of project member events, in the format of the project member events of
+// system hooks.
+type memberHookPayload struct {
+	CreatedAt                *time.Time `json:"created_at"`
+	UpdatedAt                *time.Time `json:"updated_at"`
+	EventName                string     `json:"event_name"`
+	AccessLevel              string     `json:"access_level"`
+	ProjectID                int        `json:"project_id"`
+	ProjectName              string     `json:"project_name"`
+	ProjectPath              string     `json:"project_path"`
+	ProjectPathWithNamespace string     `json:"project_path_with_namespace"`
+	ProjectVisibility        string     `json:"project_visibility"`
+	UserID                   int        `json:"user_id"`
+	UserName                 string     `json:"user_name"`
+	UserUsername             string     `json:"user_username"`
+	UserEmail                string     `json:"user_email"`
+	ExpiresAt                *time.Time `json:"expires_at"`
+}
+
+// hookTime formats the time like GitLab does in webhook payloads, nil stays nil.
+func hookTime(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+
+	formatted := t.UTC().Format("2006-01-02 15:04:05 UTC")
+
+	return &formatted
+}
+
+// nonZero returns a pointer to the value, or nil for the zero value, for IDs that are null in payloads.
+func nonZero[T comparable](value T) *T {
+	var zero T
+	if value == zero {
+		return nil
+	}
+
+	return &value
+}
+
+func toHookProject(project *gitlab.Project) hookProject {
+	namespace := ""
+	if project.Namespace != nil {
+		namespace = project.Namespace.Name
+	}
+
+	return hookProject{
+		ID:                project.ID,
+		Name:              project.Name,
+		Description:       project.Description,
+		WebURL:            project.WebURL,
+		AvatarURL:         project.AvatarURL,
+		GitSSHURL:         project.SSHURLToRepo,
+		GitHTTPURL:        project.HTTPURLToRepo,
+		Namespace:         namespace,
+		VisibilityLevel:   visibilityLevels[project.Visibility],
+		PathWithNamespace: project.PathWithNamespace,
+		DefaultBranch:     project.DefaultBranch,
+		CIConfigPath:      project.CIConfigPath,
+		Homepage:          project.WebURL,
+		URL:               project.HTTPURLToRepo,
+		SSHURL:            project.SSHURLToRepo,
+		HTTPURL:           project.HTTPURLToRepo,
+	}
+}
+
+func toHookRepository(project *gitlab.Project) hookRepository {
+	return hookRepository{
+		Name:            project.Name,
+		URL:             project.HTTPURLToRepo,
+		Description:     project.Description,
+		Homepage:        project.WebURL,
+		GitHTTPURL:      project.HTTPURLToRepo,
+		GitSSHURL:       project.SSHURLToRepo,
+		VisibilityLevel: visibilityLevels[project.Visibility],
+	}
+}
+
+func toHookUser(user *gitlab.User) *hookUser {
+	if user == nil {
+		return nil
+	}
+
+	return &hookUser{ID: user.ID, Name: user.Name, Username: user.Username, AvatarURL: user.AvatarURL, Email: user.Email}
+}
+
+// toHookUsers returns the users of the basic users, with their email addresses.
+func (mock *GitlabMock) toHookUsers(basicUsers []*gitlab.BasicUser) []*hookUser {
+	users := []*hookUser{}
+
+	for _, basicUser := range basicUsers {
+		if user, err := mock.getUser(basicUser.ID); err == nil {
+			users = append(users, toHookUser(user))
+		}
+	}
+
+	return users
+}
+
+func toHookCommit(project *gitlab.Project, commit *gitlab.Commit) *hookCommit {
+	if commit == nil {
+		return nil
+	}
+
+	return &hookCommit{
+		ID:        commit.ID,
+		Message:   commit.Message,
+		Title:     commit.Title,
+		Timestamp: commit.AuthoredDate,
+		URL:       project.WebURL + "/-/commit/" + commit.ID,
+		Author:    hookCommitAuthor{Name: commit.AuthorName, Email: commit.AuthorEmail},
+	}
+}
+
+// toHookLabels resolves the label names to the labels available in the project.
+func (mock *GitlabMock) toHookLabels(project *gitlab.Project, names gitlab.Labels) []*hookLabel {
+	labels := []*hookLabel{}
+
+	for _, name := range names {
+		for _, label := range mock.availableLabels(project) {
+			if label.Name != name {
+				continue
+			}
+
+			hookLabel := &hookLabel{ID: label.ID, Title: label.Name, Color: label.Color, Description: label.Description, Type: "GroupLabel"}
+			if label.IsProjectLabel {
+				hookLabel.ProjectID = &project.ID
+				hookLabel.Type = "ProjectLabel"
+			}
+
+			labels = append(labels, hookLabel)
+
+			break
+		}
+	}
+
+	return labels
+}
+
+func basicUserIDs(users []*gitlab.BasicUser) []int {
+	ids := []int{}
+	for _, user := range users {
+		ids = append(ids, user.ID)
+	}
+
+	return ids
+}
+
+// firePushHooks sends push or tag push events for the ref updates of a push.
+func (mock *GitlabMock) firePushHooks(project *gitlab.Project, repo *repository, user *gitlab.User, updates []refUpdate) {
+	mock.addPushEvents(project, repo, user, updates)
+	mock.fireRepositoryUpdateHooks(project, user, updates)
+
+	if !mock.hasProjectHooks(project) {
+		return
+	}
+
+	for _, update := range updates {
+		event, branch, payload := mock.newPushHookPayload(project, repo, user, update)
+		if payload == nil {
+			continue
+		}
+
+		mock.fireProjectHooks(project, event, branch, payload)
+	}
+}
+
+// newPushHookPayload returns the push or tag push event of a ref update together with the pushed branch. Updates
+// of other refs have no event.
+func (mock *GitlabMock) newPushHookPayload(project *gitlab.Project, repo *repository, user *gitlab.User, update refUpdate) (gitlab.EventType, string, *pushHookPayload) {
+	branch, isBranch := strings.CutPrefix(update.Ref, "refs/heads/")
+	tag, isTag := strings.CutPrefix(update.Ref, "refs/tags/")
+
+	if !isBranch && !isTag {
+		return "", "", nil
+	}
+
+	payload := &pushHookPayload{
+		ObjectKind: "push",
+		EventName:  "push",
+		Before:     update.Before,
+		After:      update.After,
+		Ref:        update.Ref,
+		ProjectID:  project.ID,
+		Project:    toHookProject(project),
+		Commits:    []*hookPushCommit{},
+		Repository: toHookRepository(project),
+	}
+
+	if user != nil {
+		payload.UserID = user.ID
+		payload.UserName = user.Name
+		payload.UserUsername = user.Username
+		payload.UserEmail = user.Email
+		payload.UserAvatar = user.AvatarURL
+	}
+
+	if update.After != zeroSHA {
+		payload.CheckoutSHA = &update.After
+		payload.Commits, payload.TotalCommitsCount = mock.pushedCommits(project, repo, update)
+	}
+
+	event := gitlab.EventTypePush
+
+	if isTag {
+		event = gitlab.EventTypeTagPush
+		payload.ObjectKind = "tag_push"
+		payload.EventName = "tag_push"
+		payload.RefProtected = mock.matchingProtectedTag(project, tag) != nil
+
+		if message, err := repo.git(nil, nil, "tag", "-l", "--format=%(contents)", tag); err == nil && len(message) > 0 {
+			payload.Message = nonZero(strings.TrimSpace(string(message)))
+		}
+	} else {
+		payload.RefProtected = branch == project.DefaultBranch
+	}
+
+	return event, branch, payload
+}
+
+// pushedCommits returns the commits a ref update added, oldest first and limited to the last 20 like in GitLab,
+// together with their total count. The commits of new refs are the ones no other branch contains.
+func (mock *GitlabMock) pushedCommits(project *gitlab.Project, repo *repository, update refUpdate) ([]*hookPushCommit, int) {
+	output, err := repo.git(nil, nil, append([]string{"rev-list", "--reverse"}, pushedRevisions(update)...)...)
+	if err != nil {
+		return []*hookPushCommit{}, 0
+	}
+
+	shas := strings.Fields(string(output))
+	total := len(shas)
+
+	if len(shas) > maxPushHookCommits {
+		shas = shas[len(shas)-maxPushHookCommits:]
+	}
+
+	commits := []*hookPushCommit{}
+
+	for _, sha := range shas {
+		commit, err := repo.commit(sha)
+		if err != nil {
+			continue
+		}
+
+		pushCommit := &hookPushCommit{hookCommit: *toHookCommit(project, commit), Added: []string{}, Modified: []string{}, Removed: []string{}}
+
+		changes, err := repo.git(nil, nil, "diff-tree", "--root", "--no-commit-id", "--name-status", "-r", sha)
+		if err == nil {
+			for _, line := range splitLines(changes) {
+				status, path, _ := strings.Cut(line, "\t")
+
+				switch status {
+				case "A":
+					pushCommit.Added = append(pushCommit.Added, path)
+				case "D":
+					pushCommit.Removed = append(pushCommit.Removed, path)
+				default:
+					pushCommit.Modified = append(pushCommit.Modified, path)
+				}
+			}
+		}
+
+		commits = append(commits, pushCommit)
+	}
+
+	return commits, total
+}
+
+// pushedRevisions returns the rev-list arguments selecting the commits a ref update added.
+func pushedRevisions(update refUpdate) []string {
+	if update.Before != zeroSHA {
+		return []string{update.After, "^" + update.Before}
+	}
+
+	return []string{update.After, "--not", "--exclude=" + strings.TrimPrefix(update.Ref, "refs/heads/"), "--branches"}
+}
+
+func (mock *GitlabMock) issueHookAttributes(project *gitlab.Project, issue *gitlab.Issue) *issueHookAttributes {
+	attributes := &issueHookAttributes{
+		ID:               issue.ID,
+		IID:              issue.IID,
+		Title:            issue.Title,
+		Description:      issue.Description,
+		State:            issue.State,
+		StateID:          stateIDs[issue.State],
+		ProjectID:        issue.ProjectID,
+		AssigneeIDs:      []int{},
+		Confidential:     issue.Confidential,
+		DiscussionLocked: issue.DiscussionLocked,
+		DueDate:          issue.DueDate,
+		MovedToID:        nonZero(issue.MovedToID),
+		Weight:           issue.Weight,
+		CreatedAt:        hookTime(issue.CreatedAt),
+		UpdatedAt:        hookTime(issue.UpdatedAt),
+		ClosedAt:         hookTime(issue.ClosedAt),
+		URL:              project.WebURL + "/-/issues/" + strconv.Itoa(issue.IID),
+		Labels:           mock.toHookLabels(project, issue.Labels),
+	}
+
+	if issue.Author != nil {
+		attributes.AuthorID = issue.Author.ID
+	}
+
+	attributes.AssigneeIDs = basicUserIDs(issueAssignees(issue))
+	if len(attributes.AssigneeIDs) > 0 {
+		attributes.AssigneeID = &attributes.AssigneeIDs[0]
+	}
+
+	if issue.Milestone != nil {
+		attributes.MilestoneID = &issue.Milestone.ID
+	}
+
+	return attributes
+}
+
+// fireIssueHooks sends an issue event for the action, e.g. open, update, close or reopen. The issue before the
+// change is used to report the changes of updates.
+func (mock *GitlabMock) fireIssueHooks(project *gitlab.Project, issue *gitlab.Issue, before *gitlab.Issue, action string, user *gitlab.User) {
+	mock.addIssueEvent(project, issue, action, user)
+
+	if !mock.hasProjectHooks(project) {
+		return
+	}
+
+	event, payload := mock.newIssueHookPayload(project, issue, before, action, user)
+
+	mock.fireProjectHooks(project, event, "", payload)
+}
+
+// newIssueHookPayload returns the issue event of the action, which is a confidential issue event for
+// confidential issues.
+func (mock *GitlabMock) newIssueHookPayload(project *gitlab.Project, issue *gitlab.Issue, before *gitlab.Issue, action string, user *gitlab.User) (gitlab.EventType, *issueHookPayload) {
+	attributes := mock.issueHookAttributes(project, issue)
+	attributes.Action = action
+
+	payload := &issueHookPayload{
+		ObjectKind:       "issue",
+		EventType:        "issue",
+		User:             toHookUser(user),
+		Project:          toHookProject(project),
+		ObjectAttributes: *attributes,
+		Repository:       toHookRepository(project),
+		Assignees:        mock.toHookUsers(issueAssignees(issue)),
+		Labels:           attributes.Labels,
+		Changes:          map[string]hookChange{},
+	}
+
+	if before != nil {
+		previous := mock.issueHookAttributes(project, before)
+
+		addHookChange(payload.Changes, "title", previous.Title, attributes.Title)
+		addHookChange(payload.Changes, "description", previous.Description, attributes.Description)
+		addHookChange(payload.Changes, "state_id", previous.StateID, attributes.StateID)
+		addHookChange(payload.Changes, "confidential", previous.Confidential, attributes.Confidential)
+		addHookChange(payload.Changes, "closed_at", previous.ClosedAt, attributes.ClosedAt)
+		addHookChange(payload.Changes, "labels", previous.Labels, attributes.Labels)
+		addHookChange(payload.Changes, "assignees", mock.toHookUsers(issueAssignees(before)), payload.Assignees)
+		addHookChange(payload.Changes, "updated_at", previous.UpdatedAt, attributes.UpdatedAt)
+	}
+
+	event := gitlab.EventTypeIssue
+	if issue.Confidential {
+		event = gitlab.EventConfidentialIssue
+		payload.EventType = "confidential_issue"
+	}
+
+	return event, payload
+}
+
+func (mock *GitlabMock) mergeRequestHookAttributes(project *gitlab.Project, mergeRequest *gitlab.MergeRequest) *mergeRequestHookAttributes {
+	attributes := &mergeRequestHookAttributes{
+		ID:                  mergeRequest.ID,
+		IID:                 mergeRequest.IID,
+		Title:               mergeRequest.Title,
+		Description:         mergeRequest.Description,
+		State:               mergeRequest.State,
+		StateID:             stateIDs[mergeRequest.State],
+		SourceBranch:        mergeRequest.SourceBranch,
+		SourceProjectID:     mergeRequest.SourceProjectID,
+		TargetBranch:        mergeRequest.TargetBranch,
+		TargetProjectID:     mergeRequest.TargetProjectID,
+		AssigneeIDs:         basicUserIDs(mergeRequest.Assignees),
+		ReviewerIDs:         basicUserIDs(mergeRequest.Reviewers),
+		MergeStatus:         mergeRequest.MergeStatus,
+		DetailedMergeStatus: mergeRequest.DetailedMergeStatus,
+		MergeCommitSHA:      nonZero(mergeRequest.MergeCommitSHA),
+		Draft:               mergeRequest.Draft,
+		WorkInProgress:      mergeRequest.Draft,
+		CreatedAt:           hookTime(mergeRequest.CreatedAt),
+		UpdatedAt:           hookTime(mergeRequest.UpdatedAt),
+		URL:                 project.WebURL + "/-/merge_requests/" + strconv.Itoa(mergeRequest.IID),
+		Source:              toHookProject(project),
+		Target:              toHookProject(project),
+		Labels:              mock.toHookLabels(project, mergeRequest.Labels),
+	}
+
+	if mergeRequest.Author != nil {
+		attributes.AuthorID = mergeRequest.Author.ID
+	}
+
+	if len(attributes.AssigneeIDs) > 0 {
+		attributes.AssigneeID = &attributes.AssigneeIDs[0]
+	}
+
+	if mergeRequest.Milestone != nil {
+		attributes.MilestoneID = &mergeRequest.Milestone.ID
+	}
+
+	if mergeRequest.MergedBy != nil {
+		attributes.MergeUserID = &mergeRequest.MergedBy.ID
+	}
+
+	if mergeRequest.HeadPipeline != nil {
+		attributes.HeadPipelineID = &mergeRequest.HeadPipeline.ID
+	}
+
+	if repo, err := mock.getRepository(project); err == nil && mergeRequest.SHA != "" {
+		if commit, err := repo.commit(mergeRequest.SHA); err == nil {
+			attributes.LastCommit = toHookCommit(project, commit)
+		}
+	}
+
+	return attributes
+}
+
+// fireMergeRequestHooks sends a merge request event for the action, e.g. open, update, close, reopen, merge,
+// approved or unapproved. The merge request before the change is used to report the changes of updates, the old
+// revision is set for updates that pushed new commits.
+func (mock *GitlabMock) fireMergeRequestHooks(project *gitlab.Project, mergeRequest *gitlab.MergeRequest, before *gitlab.MergeRequest, action string, oldRev string, user *gitlab.User) {
+	mock.addMergeRequestEvent(project, mergeRequest, action, user)
+
+	if !mock.hasProjectHooks(project) {
+		return
+	}
+
+	payload := mock.newMergeRequestHookPayload(project, mergeRequest, before, action, oldRev, user)
+
+	mock.fireProjectHooks(project, gitlab.EventTypeMergeRequest, "", payload)
+}
+
+func (mock *GitlabMock) newMergeRequestHookPayload(project *gitlab.Project, mergeRequest *gitlab.MergeRequest, before *gitlab.MergeRequest, action string, oldRev string, user *gitlab.User) *mergeRequestHookPayload {
+	attributes := mock.mergeRequestHookAttributes(project, mergeRequest)
+	attributes.Action = action
+	attributes.OldRev = oldRev
+
+	payload := &mergeRequestHookPayload{
+		ObjectKind:       "merge_request",
+		EventType:        "merge_request",
+		User:             toHookUser(user),
+		Project:          toHookProject(project),
+		ObjectAttributes: *attributes,
+		Repository:       toHookRepository(project),
+		Assignees:        mock.toHookUsers(mergeRequest.Assignees),
+		Reviewers:        mock.toHookUsers(mergeRequest.Reviewers),
+		Labels:           attributes.Labels,
+		Changes:          map[string]hookChange{},
+	}
+
+	if before != nil {
+		addHookChange(payload.Changes, "title", before.Title, mergeRequest.Title)
+		addHookChange(payload.Changes, "description", before.Description, mergeRequest.Description)
+		addHookChange(payload.Changes, "state_id", stateIDs[before.State], attributes.StateID)
+		addHookChange(payload.Changes, "target_branch", before.TargetBranch, mergeRequest.TargetBranch)
+		addHookChange(payload.Changes, "draft", before.Draft, mergeRequest.Draft)
+		addHookChange(payload.Changes, "labels", mock.toHookLabels(project, before.Labels), attributes.Labels)
+		addHookChange(payload.Changes, "assignees", mock.toHookUsers(before.Assignees), payload.Assignees)
+		addHookChange(payload.Changes, "reviewers", mock.toHookUsers(before.Reviewers), payload.Reviewers)
+		addHookChange(payload.Changes, "updated_at", hookTime(before.UpdatedAt), attributes.UpdatedAt)
+	}
+
+	return payload
+}
+
+// addHookChange adds the change of the attribute if its value changed.
+func addHookChange(changes map[string]hookChange, attribute string, previous interface{}, current interface{}) {
+	previousJSON, _ := json.Marshal(previous)
+	currentJSON, _ := json.Marshal(current)
+
+	if !bytes.Equal(previousJSON, currentJSON) {
+		changes[attribute] = hookChange{Previous: previous, Current: current}
+	}
+}
+
+// fireNoteHooks sends a note event for a comment, which is created or updated. System notes send no events.
+func (mock *GitlabMock) fireNoteHooks(project *gitlab.Project, noteable noteable, discussionID string, note *gitlab.Note, action string, user *gitlab.User) {
+	if action == "create" {
+		mock.addNoteEvent(project, noteable, note, user)
+	}
+
+	if !mock.hasProjectHooks(project) || note.System {
+		return
+	}
+
+	event, payload, err := mock.newNoteHookPayload(project, noteable, discussionID, note, action, user)
+	if err != nil {
+		return
+	}
+
+	mock.fireProjectHooks(project, event, "", payload)
+}
+
+// newNoteHookPayload returns the note event of a comment, which is a confidential note event for comments of
+// confidential issues.
+func (mock *GitlabMock) newNoteHookPayload(project *gitlab.Project, noteable noteable, discussionID string, note *gitlab.Note, action string, user *gitlab.User) (gitlab.EventType, *noteHookPayload, error) {
+	attributes := noteHookAttributes{
+		ID:           note.ID,
+		Note:         note.Body,
+		NoteableType: note.NoteableType,
+		NoteableID:   nonZero(note.NoteableID),
+		AuthorID:     note.Author.ID,
+		ProjectID:    project.ID,
+		CommitID:     nonZero(note.CommitID),
+		DiscussionID: discussionID,
+		Type:         nonZero(string(note.Type)),
+		Position:     note.Position,
+		Action:       action,
+		CreatedAt:    hookTime(note.CreatedAt),
+		UpdatedAt:    hookTime(note.UpdatedAt),
+	}
+
+	payload := &noteHookPayload{
+		ObjectKind: "note",
+		EventType:  "note",
+		User:       toHookUser(user),
+		ProjectID:  project.ID,
+		Project:    toHookProject(project),
+		Repository: toHookRepository(project),
+	}
+
+	event := gitlab.EventTypeNote
+
+	switch noteable.Type {
+	case noteableIssue:
+		issue, err := mock.getIssue(project, noteable.IID)
+		if err != nil {
+			return "", nil, err
+		}
+
+		payload.Issue = mock.issueHookAttributes(project, issue)
+		attributes.URL = payload.Issue.URL + "#note_" + strconv.Itoa(note.ID)
+
+		if issue.Confidential {
+			event = gitlab.EventConfidentialNote
+			payload.EventType = "confidential_note"
+		}
+	case noteableMergeRequest:
+		mergeRequest, err := mock.getMergeRequest(project, noteable.IID)
+		if err != nil {
+			return "", nil, err
+		}
+
+		payload.MergeRequest = mock.mergeRequestHookAttributes(project, mergeRequest)
+		attributes.URL = payload.MergeRequest.URL + "#note_" + strconv.Itoa(note.ID)
+	case noteableCommit:
+		repo, err := mock.getRepository(project)
+		if err != nil {
+			return "", nil, err
+		}
+
+		commit, err := repo.commit(noteable.CommitID)
+		if err != nil {
+			return "", nil, err
+		}
+
+		payload.Commit = toHookCommit(project, commit)
+		attributes.URL = payload.Commit.URL + "#note_" + strconv.Itoa(note.ID)
+	case noteableSnippet:
+		snippet, err := mock.getProjectSnippet(project, noteable.ID)
+		if err != nil {
+			return "", nil, err
+		}
+
+		payload.Snippet = &noteHookSnippet{
+			ID:          snippet.ID,
+			Title:       snippet.Title,
+			Description: snippet.Description,
+			FileName:    snippet.FileName,
+			AuthorID:    snippet.Author.ID,
+			ProjectID:   project.ID,
+		}
+		attributes.URL = project.WebURL + "/-/snippets/" + strconv.Itoa(snippet.ID) + "#note_" + strconv.Itoa(note.ID)
+	}
+
+	payload.ObjectAttributes = attributes
+
+	return event, payload, nil
+}
+
+func (mock *GitlabMock) hookRunner(job *gitlab.Job) *hookRunner {
+	if job.Runner.ID == 0 {
+		return nil
+	}
+
+	runner := &hookRunner{
+		ID:          job.Runner.ID,
+		Description: job.Runner.Description,
+		Active:      job.Runner.Active,
+		IsShared:    job.Runner.IsShared,
+		Tags:        []string{},
+	}
+
+	if details, err := mock.getRunner(job.Runner.ID); err == nil {
+		runner.Active = !details.Paused
+		runner.RunnerType = details.RunnerType
+		runner.IsShared = details.IsShared
+		runner.Tags = slices.Clone(details.TagList)
+	}
+
+	return runner
+}
+
+// firePipelineHooks sends a pipeline event with the current status of the pipeline and its jobs.
+func (mock *GitlabMock) firePipelineHooks(pipeline *gitlab.Pipeline) {
+	project, projectExists := mock.projects[pipeline.ProjectID]
+	if !projectExists || !mock.hasProjectHooks(project) {
+		return
+	}
+
+	mock.fireProjectHooks(project, gitlab.EventTypePipeline, "", mock.newPipelineHookPayload(project, pipeline))
+}
+
+func (mock *GitlabMock) newPipelineHookPayload(project *gitlab.Project, pipeline *gitlab.Pipeline) *pipelineHookPayload {
+	payload := &pipelineHookPayload{
+		ObjectKind: "pipeline",
+		ObjectAttributes: pipelineHookAttributes{
+			ID:             pipeline.ID,
+			IID:            pipeline.IID,
+			Ref:            pipeline.Ref,
+			Tag:            pipeline.Tag,
+			SHA:            pipeline.SHA,
+			BeforeSHA:      pipeline.BeforeSHA,
+			Source:         pipeline.Source,
+			Status:         pipeline.Status,
+			DetailedStatus: pipeline.Status,
+			Stages:         mock.pipelineStages(pipeline),
+			CreatedAt:      hookTime(pipeline.CreatedAt),
+			FinishedAt:     hookTime(pipeline.FinishedAt),
+			Duration:       pipeline.Duration,
+			QueuedDuration: pipeline.QueuedDuration,
+			URL:            project.WebURL + "/-/pipelines/" + strconv.Itoa(pipeline.ID),
+			Variables:      []*hookVariable{},
+		},
+		Project: toHookProject(project),
+		Builds:  []*pipelineHookBuild{},
+	}
+
+	if pipeline.DetailedStatus != nil {
+		payload.ObjectAttributes.DetailedStatus = pipeline.DetailedStatus.Text
+	}
+
+	if pipeline.User != nil {
+		if user, err := mock.getUser(pipeline.User.ID); err == nil {
+			payload.User = toHookUser(user)
+		}
+	}
+
+	for _, variable := range mock.pipelineVariables[pipeline.ID] {
+		payload.ObjectAttributes.Variables = append(payload.ObjectAttributes.Variables, &hookVariable{Key: variable.Key, Value: variable.Value})
+	}
+
+	if repo, err := mock.getRepository(project); err == nil {
+		if commit, err := repo.commit(pipeline.SHA); err == nil {
+			payload.Commit = toHookCommit(project, commit)
+		}
+	}
+
+	for _, job := range mock.pipelineJobs(pipeline, false) {
+		spec := mock.jobSpecs[job.ID]
+
+		build := &pipelineHookBuild{
+			ID:             job.ID,
+			Stage:          job.Stage,
+			Name:           job.Name,
+			Status:         job.Status,
+			CreatedAt:      hookTime(job.CreatedAt),
+			StartedAt:      hookTime(job.StartedAt),
+			FinishedAt:     hookTime(job.FinishedAt),
+			Duration:       job.Duration,
+			QueuedDuration: job.QueuedDuration,
+			FailureReason:  nonZero(job.FailureReason),
+			When:           spec.When,
+			Manual:         spec.When == whenManual,
+			AllowFailure:   job.AllowFailure,
+			User:           toHookUser(job.User),
+			Runner:         mock.hookRunner(job),
+		}
+
+		if job.ArtifactsFile.Filename != "" {
+			build.ArtifactsFile = hookArtifactsFile{Filename: &job.ArtifactsFile.Filename, Size: &job.ArtifactsFile.Size}
+		}
+
+		payload.Builds = append(payload.Builds, build)
+	}
+
+	return payload
+}
+
+// fireJobHooks sends a job event with the current status of the job.
+func (mock *GitlabMock) fireJobHooks(job *gitlab.Job) {
+	project, projectExists := mock.projects[job.Pipeline.ProjectID]
+	if !projectExists || !mock.hasProjectHooks(project) {
+		return
+	}
+
+	mock.fireProjectHooks(project, gitlab.EventTypeJob, "", mock.newJobHookPayload(project, job))
+}
+
+func (mock *GitlabMock) newJobHookPayload(project *gitlab.Project, job *gitlab.Job) *jobHookPayload {
+	payload := &jobHookPayload{
+		ObjectKind:          "build",
+		Ref:                 job.Ref,
+		Tag:                 job.Tag,
+		BeforeSHA:           zeroSHA,
+		SHA:                 job.Pipeline.Sha,
+		BuildID:             job.ID,
+		BuildName:           job.Name,
+		BuildStage:          job.Stage,
+		BuildStatus:         job.Status,
+		BuildCreatedAt:      hookTime(job.CreatedAt),
+		BuildStartedAt:      hookTime(job.StartedAt),
+		BuildFinishedAt:     hookTime(job.FinishedAt),
+		BuildDuration:       job.Duration,
+		BuildQueuedDuration: job.QueuedDuration,
+		BuildAllowFailure:   job.AllowFailure,
+		BuildFailureReason:  job.FailureReason,
+		PipelineID:          job.Pipeline.ID,
+		ProjectID:           project.ID,
+		ProjectName:         project.NameWithNamespace,
+		User:                toHookUser(job.User),
+		Repository:          toHookRepository(project),
+		Runner:              mock.hookRunner(job),
+	}
+
+	if payload.BuildFailureReason == "" {
+		payload.BuildFailureReason = "unknown_failure"
+	}
+
+	for _, other := range mock.jobs[job.Pipeline.ID] {
+		if other.Name == job.Name && other.ID < job.ID {
+			payload.RetriesCount++
+		}
+	}
+
+	if pipeline, err := mock.getPipeline(project, job.Pipeline.ID); err == nil {
+		payload.BeforeSHA = pipeline.BeforeSHA
+		payload.Commit = jobHookCommit{
+			ID:         pipeline.ID,
+			SHA:        pipeline.SHA,
+			Status:     pipeline.Status,
+			Duration:   pipeline.Duration,
+			StartedAt:  hookTime(pipeline.StartedAt),
+			FinishedAt: hookTime(pipeline.FinishedAt),
+		}
+	}
+
+	if job.Commit != nil {
+		payload.Commit.Message = job.Commit.Message
+		payload.Commit.AuthorName = job.Commit.AuthorName
+		payload.Commit.AuthorEmail = job.Commit.AuthorEmail
+		payload.Commit.AuthorURL = "mailto:" + job.Commit.AuthorEmail
+	}
+
+	return payload
+}
+
+// fireMemberHooks sends a member event of the project, e.g. user_add_to_team, user_update_for_team or
+// user_remove_from_team.
+func (mock *GitlabMock) fireMemberHooks(project *gitlab.Project, member *gitlab.ProjectMember, eventName string) {
+	if !mock.hasProjectHooks(project) {
+		return
+	}
+
+	mock.fireProjectHooks(project, gitlab.EventTypeMember, "", mock.memberHookPayload(project, member, eventName))
+}
+
+func (mock *GitlabMock) memberHookPayload(project *gitlab.Project, member *gitlab.ProjectMember, eventName string) *memberHookPayload {
+	now := mock.now()
+
+	payload := &memberHookPayload{
+		CreatedAt:                member.CreatedAt,
+		UpdatedAt:                &now,
+		EventName:                eventName,
+		AccessLevel:              accessLevelNames[member.AccessLevel],
+		ProjectID:                project.ID,
+		ProjectName:              project.Name,
+		ProjectPath:              project.Path,
+		ProjectPathWithNamespace: project.PathWithNamespace,
+		ProjectVisibility:        string(project.Visibility),
+		UserID:                   member.ID,
+		UserName:                 member.Name,
+		UserUsername:             member.Username,
+		UserEmail:                member.Email,
+	}
+
+	if member.ExpiresAt != nil {
+		expiresAt := time.Time(*member.ExpiresAt)
+		payload.ExpiresAt = &expiresAt
+	}
+
+	if user, err := mock.getUser(member.ID); err == nil {
+		payload.UserName = user.Name
+		payload.UserUsername = user.Username
+		payload.UserEmail = user.Email
+	}
+
+	return payload
+}
@@ -0,0 +1,762 @@
+package gitlabapimock
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+const (
+	hookUserAgent = "GitLab/16.11.0"
+
+	defaultHookRetries    = 3
+	defaultHookRetryDelay = 100 * time.Millisecond
+	hookTimeout           = 10 * time.Second
+)
+
+// projectHook is a webhook of a project. GitLab only sends member events to group hooks, the mock sends them to
+// project hooks as well so that member changes of a project can be received.
+type projectHook struct {
+	*gitlab.ProjectHook
+	MemberEvents bool `json:"member_events"`
+}
+
+// ProjectHookOptions are the options to add or edit a project hook with. The URL is only required when adding a
+// hook.
+type ProjectHookOptions struct {
+	gitlab.AddProjectHookOptions
+	MemberEvents *bool `json:"member_events,omitempty"`
+}
+
+// hookDelivery is a webhook request waiting to be sent. createdAt is taken from the clock of the mock when the
+// delivery is queued, the delivery goroutines never read the clock.
+type hookDelivery struct {
+	hookID    int
+	url       string
+	token     string
+	headers   []*gitlab.HookCustomHeader
+	event     gitlab.EventType
+	payload   []byte
+	createdAt time.Time
+}
+
+// WebhookDelivery is a logged request of a webhook, like the webhook events GitLab keeps per hook. Every attempt
+// of a delivery is logged, the response status is "internal error" if no response was received.
+type WebhookDelivery struct {
+	ID                   int               `json:"id"`
+	HookID               int               `json:"-"`
+	URL                  string            `json:"url"`
+	Trigger              string            `json:"trigger"`
+	RequestHeaders       map[string]string `json:"request_headers"`
+	RequestData          json.RawMessage   `json:"request_data"`
+	ResponseHeaders      map[string]string `json:"response_headers"`
+	ResponseBody         string            `json:"response_body"`
+	ExecutionDuration    float64           `json:"execution_duration"`
+	ResponseStatus       string            `json:"response_status"`
+	InternalErrorMessage string            `json:"internal_error_message,omitempty"`
+	CreatedAt            time.Time         `json:"created_at"`
+}
+
+// hookTriggers are the triggers GitLab logs the deliveries of the events with.
+var hookTriggers = map[gitlab.EventType]string{
+	gitlab.EventTypePush:          "push_hooks",
+	gitlab.EventTypeTagPush:       "tag_push_hooks",
+	gitlab.EventTypeIssue:         "issue_hooks",
+	gitlab.EventConfidentialIssue: "confidential_issue_hooks",
+	gitlab.EventTypeMergeRequest:  "merge_request_hooks",
+	gitlab.EventTypeNote:          "note_hooks",
+	gitlab.EventConfidentialNote:  "confidential_note_hooks",
+	gitlab.EventTypePipeline:      "pipeline_hooks",
+	gitlab.EventTypeJob:           "job_hooks",
+	gitlab.EventTypeMember:        "member_hooks",
+	gitlab.EventTypeSubGroup:      "subgroup_hooks",
+	gitlab.EventTypeSystemHook:    "system_hooks",
+}
+
+// successful reports whether the webhook receiver accepted the delivery.
+func (delivery *WebhookDelivery) successful() bool {
+	statusCode, err := strconv.Atoi(delivery.ResponseStatus)
+
+	return err == nil && statusCode >= 200 && statusCode < 300
+}
+
+// hasStatus reports whether the delivery matches a status filter, either a response code or one of successful,
+// client_failure and server_failure. Deliveries without a response count as server failures.
+func (delivery *WebhookDelivery) hasStatus(status string) bool {
+	statusCode, err := strconv.Atoi(delivery.ResponseStatus)
+	if err != nil {
+		statusCode = http.StatusInternalServerError
+	}
+
+	switch status {
+	case "successful":
+		return statusCode >= 200 && statusCode < 300
+	case "client_failure":
+		return statusCode >= 400 && statusCode < 500
+	case "server_failure":
+		return statusCode >= 500
+	}
+
+	return status == delivery.ResponseStatus
+}
+
+// AddProjectHook adds a webhook to the project. Push events are enabled unless disabled by the options.
+func (mock *GitlabMock) AddProjectHook(project *gitlab.Project, options *ProjectHookOptions) (*gitlab.ProjectHook, error) {
+	hook, err := mock.addProjectHook(project, options)
+	if err != nil {
+		return nil, err
+	}
+
+	return hook.ProjectHook, nil
+}
+
+func (mock *GitlabMock) addProjectHook(project *gitlab.Project, options *ProjectHookOptions) (*projectHook, error) {
+	if options.URL == nil {
+		return nil, newAPIError(http.StatusBadRequest, "url is missing")
+	}
+
+	createdAt := mock.now()
+
+	hook := &projectHook{ProjectHook: &gitlab.ProjectHook{
+		ID:                    int(mock.hookIds.Add(1)),
+		ProjectID:             project.ID,
+		CreatedAt:             &createdAt,
+		PushEvents:            true,
+		EnableSSLVerification: true,
+		CustomHeaders:         []*gitlab.HookCustomHeader{},
+	}}
+
+	err := mock.applyProjectHookOptions(hook, options)
+	if err != nil {
+		return nil, err
+	}
+
+	mock.projectHooks[project.ID] = append(mock.projectHooks[project.ID], hook)
+
+	return hook, nil
+}
+
+// applyProjectHookOptions applies the options to the hook. An invalid URL leaves the hook untouched.
+func (mock *GitlabMock) applyProjectHookOptions(hook *projectHook, options *ProjectHookOptions) error {
+	if options.URL != nil {
+		err := validateHookURL(*options.URL)
+		if err != nil {
+			return err
+		}
+
+		hook.URL = *options.URL
+	}
+
+	setHookOption(&hook.PushEvents, options.PushEvents)
+	setHookOption(&hook.TagPushEvents, options.TagPushEvents)
+	setHookOption(&hook.IssuesEvents, options.IssuesEvents)
+	setHookOption(&hook.ConfidentialIssuesEvents, options.ConfidentialIssuesEvents)
+	setHookOption(&hook.MergeRequestsEvents, options.MergeRequestsEvents)
+	setHookOption(&hook.NoteEvents, options.NoteEvents)
+	setHookOption(&hook.ConfidentialNoteEvents, options.ConfidentialNoteEvents)
+	setHookOption(&hook.PipelineEvents, options.PipelineEvents)
+	setHookOption(&hook.JobEvents, options.JobEvents)
+	setHookOption(&hook.WikiPageEvents, options.WikiPageEvents)
+	setHookOption(&hook.DeploymentEvents, options.DeploymentEvents)
+	setHookOption(&hook.ReleasesEvents, options.ReleasesEvents)
+	setHookOption(&hook.ResourceAccessTokenEvents, options.ResourceAccessTokenEvents)
+	setHookOption(&hook.MemberEvents, options.MemberEvents)
+	setHookOption(&hook.EnableSSLVerification, options.EnableSSLVerification)
+	setHookOption(&hook.PushEventsBranchFilter, options.PushEventsBranchFilter)
+	setHookOption(&hook.CustomWebhookTemplate, options.CustomWebhookTemplate)
+	setHookOption(&hook.CustomHeaders, options.CustomHeaders)
+
+	if options.Token != nil {
+		mock.hookTokens[hook.ID] = *options.Token
+	}
+
+	return nil
+}
+
+// subscribes reports whether the project hook receives the event.
+func (hook *projectHook) subscribes(event gitlab.EventType, branch string) bool {
+	events := map[gitlab.EventType]bool{
+		gitlab.EventTypePush:          hook.PushEvents,
+		gitlab.EventTypeTagPush:       hook.TagPushEvents,
+		gitlab.EventTypeIssue:         hook.IssuesEvents,
+		gitlab.EventConfidentialIssue: hook.ConfidentialIssuesEvents,
+		gitlab.EventTypeMergeRequest:  hook.MergeRequestsEvents,
+		gitlab.EventTypeNote:          hook.NoteEvents,
+		gitlab.EventConfidentialNote:  hook.ConfidentialNoteEvents,
+		gitlab.EventTypePipeline:      hook.PipelineEvents,
+		gitlab.EventTypeJob:           hook.JobEvents,
+		gitlab.EventTypeMember:        hook.MemberEvents,
+	}
+
+	return hookSubscribes(events, hook.PushEventsBranchFilter, event, branch)
+}
+
+// setHookOption sets the field of a hook to the option, fields of options that are not set are kept.
+func setHookOption[T any](field *T, option *T) {
+	if option != nil {
+		*field = *option
+	}
+}
+
+// hookSubscribes reports whether a hook with the enabled events receives the event. Push events of branches are
+// filtered by the branch filter of the hook.
+func hookSubscribes(events map[gitlab.EventType]bool, branchFilter string, event gitlab.EventType, branch string) bool {
+	if !events[event] {
+		return false
+	}
+
+	return event != gitlab.EventTypePush || branchFilter == "" || matchesWildcard(branchFilter, branch)
+}
+
+func validateHookURL(hookURL string) error {
+	parsedURL, err := url.ParseRequestURI(hookURL)
+	if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") || parsedURL.Host == "" {
+		return newAPIError(http.StatusUnprocessableEntity, map[string][]string{"url": {"is blocked: Only allowed schemes are http, https"}})
+	}
+
+	return nil
+}
+
+func (mock *GitlabMock) getProjectHook(project *gitlab.Project, hookID int) (*projectHook, error) {
+	for _, hook := range mock.projectHooks[project.ID] {
+		if hook.ID == hookID {
+			return hook, nil
+		}
+	}
+
+	return nil, newAPIError(http.StatusNotFound, "404 Not found")
+}
+
+// GetProjectHooks returns the webhooks of the project.
+func (mock *GitlabMock) GetProjectHooks(project *gitlab.Project) []*gitlab.ProjectHook {
+	hooks := []*gitlab.ProjectHook{}
+	for _, hook := range mock.projectHooks[project.ID] {
+		hooks = append(hooks, hook.ProjectHook)
+	}
+
+	return hooks
+}
+
+func (mock *GitlabMock) removeProjectHook(project *gitlab.Project, hook *projectHook) {
+	mock.projectHooks[project.ID] = slices.DeleteFunc(mock.projectHooks[project.ID], func(other *projectHook) bool {
+		return other.ID == hook.ID
+	})
+
+	delete(mock.hookTokens, hook.ID)
+	mock.removeHookDeliveries(hook.ID)
+}
+
+// AddGroupHook adds a webhook to the group, which receives the events of the projects and subgroups of the group
+// as well. Push events are enabled unless disabled by the options.
+func (mock *GitlabMock) AddGroupHook(group *gitlab.Group, options *gitlab.AddGroupHookOptions) (*gitlab.GroupHook, error) {
+	if options.URL == nil {
+		return nil, newAPIError(http.StatusBadRequest, "url is missing")
+	}
+
+	createdAt := mock.now()
+
+	hook := &gitlab.GroupHook{
+		ID:                    int(mock.hookIds.Add(1)),
+		GroupID:               group.ID,
+		CreatedAt:             &createdAt,
+		PushEvents:            true,
+		EnableSSLVerification: true,
+		CustomHeaders:         []*gitlab.HookCustomHeader{},
+	}
+
+	err := mock.applyGroupHookOptions(hook, options)
+	if err != nil {
+		return nil, err
+	}
+
+	mock.groupHooks[group.ID] = append(mock.groupHooks[group.ID], hook)
+
+	return hook, nil
+}
+
+// applyGroupHookOptions applies the options to the hook. An invalid URL leaves the hook untouched.
+func (mock *GitlabMock) applyGroupHookOptions(hook *gitlab.GroupHook, options *gitlab.AddGroupHookOptions) error {
+	if options.URL != nil {
+		err := validateHookURL(*options.URL)
+		if err != nil {
+			return err
+		}
+
+		hook.URL = *options.URL
+	}
+
+	setHookOption(&hook.PushEvents, options.PushEvents)
+	setHookOption(&hook.TagPushEvents, options.TagPushEvents)
+	setHookOption(&hook.IssuesEvents, options.IssuesEvents)
+	setHookOption(&hook.ConfidentialIssuesEvents, options.ConfidentialIssuesEvents)
+	setHookOption(&hook.MergeRequestsEvents, options.MergeRequestsEvents)
+	setHookOption(&hook.NoteEvents, options.NoteEvents)
+	setHookOption(&hook.ConfidentialNoteEvents, options.ConfidentialNoteEvents)
+	setHookOption(&hook.PipelineEvents, options.PipelineEvents)
+	setHookOption(&hook.JobEvents, options.JobEvents)
+	setHookOption(&hook.WikiPageEvents, options.WikiPageEvents)
+	setHookOption(&hook.DeploymentEvents, options.DeploymentEvents)
+	setHookOption(&hook.ReleasesEvents, options.ReleasesEvents)
+	setHookOption(&hook.SubGroupEvents, options.SubGroupEvents)
+	setHookOption(&hook.MemberEvents, options.MemberEvents)
+	setHookOption(&hook.ResourceAccessTokenEvents, options.ResourceAccessTokenEvents)
+	setHookOption(&hook.EnableSSLVerification, options.EnableSSLVerification)
+	setHookOption(&hook.PushEventsBranchFilter, options.PushEventsBranchFilter)
+	setHookOption(&hook.CustomWebhookTemplate, options.CustomWebhookTemplate)
+	setHookOption(&hook.CustomHeaders, options.CustomHeaders)
+
+	if options.Token != nil {
+		mock.hookTokens[hook.ID] = *options.Token
+	}
+
+	return nil
+}
+
+// groupHookSubscribes reports whether the group hook receives the event.
+func groupHookSubscribes(hook *gitlab.GroupHook, event gitlab.EventType, branch string) bool {
+	events := map[gitlab.EventType]bool{
+		gitlab.EventTypePush:          hook.PushEvents,
+		gitlab.EventTypeTagPush:       hook.TagPushEvents,
+		gitlab.EventTypeIssue:         hook.IssuesEvents,
+		gitlab.EventConfidentialIssue: hook.ConfidentialIssuesEvents,
+		gitlab.EventTypeMergeRequest:  hook.MergeRequestsEvents,
+		gitlab.EventTypeNote:          hook.NoteEvents,
+		gitlab.EventConfidentialNote:  hook.ConfidentialNoteEvents,
+		gitlab.EventTypePipeline:      hook.PipelineEvents,
+		gitlab.EventTypeJob:           hook.JobEvents,
+		gitlab.EventTypeMember:        hook.MemberEvents,
+		gitlab.EventTypeSubGroup:      hook.SubGroupEvents,
+	}
+
+	return hookSubscribes(events, hook.PushEventsBranchFilter, event, branch)
+}
+
+func (mock *GitlabMock) getGroupHook(group *gitlab.Group, hookID int) (*gitlab.GroupHook, error) {
+	for _, hook := range mock.groupHooks[group.ID] {
+		if hook.ID == hookID {
+			return hook, nil
+		}
+	}
+
+	return nil, newAPIError(http.StatusNotFound, "404 Not found")
+}
+
+// GetGroupHooks returns the webhooks of the group.
+func (mock *GitlabMock) GetGroupHooks(group *gitlab.Group) []*gitlab.GroupHook {
+	return append([]*gitlab.GroupHook{}, mock.groupHooks[group.ID]...)
+}
+
+func (mock *GitlabMock) removeGroupHook(group *gitlab.Group, hook *gitlab.GroupHook) {
+	mock.groupHooks[group.ID] = slices.DeleteFunc(mock.groupHooks[group.ID], func(other *gitlab.GroupHook) bool {
+		return other.ID == hook.ID
+	})
+
+	delete(mock.hookTokens, hook.ID)
+	mock.removeHookDeliveries(hook.ID)
+}
+
+// projectGroups returns the group of the project followed by its parent groups.
+func (mock *GitlabMock) projectGroups(project *gitlab.Project) []*gitlab.Group {
+	if project.Namespace == nil {
+		return nil
+	}
+
+	group, err := mock.getGroup(project.Namespace.ID)
+	if err != nil {
+		return nil
+	}
+
+	return mock.groupAncestors(group)
+}
+
+// hasProjectHooks reports whether any hook may receive events of the project, which saves building payloads
+// nobody receives.
+func (mock *GitlabMock) hasProjectHooks(project *gitlab.Project) bool {
+	if len(mock.projectHooks[project.ID]) > 0 || len(mock.systemHooks) > 0 {
+		return true
+	}
+
+	return slices.ContainsFunc(mock.projectGroups(project), func(group *gitlab.Group) bool {
+		return len(mock.groupHooks[group.ID]) > 0
+	})
+}
+
+// fireProjectHooks sends the event to the hooks of the project, the hooks of its groups and the system hooks
+// that subscribed to it. The branch is only used for push events. Group hooks receive the member events of
+// their group instead of the ones of their projects.
+func (mock *GitlabMock) fireProjectHooks(project *gitlab.Project, event gitlab.EventType, branch string, payload interface{}) {
+	// The payload is encoded right away, the deliveries must not see later changes of the mock.
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+
+	for _, hook := range mock.projectHooks[project.ID] {
+		if hook.subscribes(event, branch) {
+			mock.queueHookDelivery(hook.ID, hook.URL, hook.CustomHeaders, event, body)
+		}
+	}
+
+	if event != gitlab.EventTypeMember {
+		for _, group := range mock.projectGroups(project) {
+			for _, hook := range mock.groupHooks[group.ID] {
+				if groupHookSubscribes(hook, event, branch) {
+					mock.queueHookDelivery(hook.ID, hook.URL, hook.CustomHeaders, event, body)
+				}
+			}
+		}
+	}
+
+	mock.queueSystemHookDeliveries(body, func(hook *gitlab.Hook) bool {
+		return systemHookSubscribes(hook, event)
+	})
+}
+
+// fireGroupHooks sends the event of the group to the hooks of the group and its parent groups and to the system
+// hooks that subscribed to it.
+func (mock *GitlabMock) fireGroupHooks(group *gitlab.Group, event gitlab.EventType, payload interface{}) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+
+	for _, ancestor := range mock.groupAncestors(group) {
+		for _, hook := range mock.groupHooks[ancestor.ID] {
+			if groupHookSubscribes(hook, event, "") {
+				mock.queueHookDelivery(hook.ID, hook.URL, hook.CustomHeaders, event, body)
+			}
+		}
+	}
+
+	mock.queueSystemHookDeliveries(body, func(hook *gitlab.Hook) bool {
+		return systemHookSubscribes(hook, event)
+	})
+}
+
+// SetWebhookRetries sets how often failed webhook deliveries are retried and the delay before the first retry,
+// which grows with every retry.
+func (mock *GitlabMock) SetWebhookRetries(retries int, delay time.Duration) {
+	mock.hooksMutex.Lock()
+	defer mock.hooksMutex.Unlock()
+
+	mock.hookRetries = retries
+	mock.hookRetryDelay = delay
+}
+
+// WaitForWebhooks blocks until all queued webhook deliveries have been sent, including their retries.
+func (mock *GitlabMock) WaitForWebhooks() {
+	mock.pendingHooks.Wait()
+}
+
+// queueHookDelivery sends the payload to the hook in the background. Deliveries of the same hook are sent in the
+// order they were queued.
+func (mock *GitlabMock) queueHookDelivery(hookID int, hookURL string, headers []*gitlab.HookCustomHeader, event gitlab.EventType, payload []byte) {
+	delivery := &hookDelivery{
+		hookID:    hookID,
+		url:       hookURL,
+		token:     mock.hookTokens[hookID],
+		headers:   headers,
+		event:     event,
+		payload:   payload,
+		createdAt: mock.now(),
+	}
+
+	mock.hooksMutex.Lock()
+	previous := mock.hookQueues[delivery.hookID]
+	done := make(chan struct{})
+	mock.hookQueues[delivery.hookID] = done
+	retries, retryDelay := mock.hookRetries, mock.hookRetryDelay
+	mock.hooksMutex.Unlock()
+
+	mock.pendingHooks.Add(1)
+
+	go func() {
+		defer mock.pendingHooks.Done()
+		defer close(done)
+
+		if previous != nil {
+			<-previous
+		}
+
+		for attempt := 0; attempt <= retries; attempt++ {
+			if attempt > 0 {
+				time.Sleep(retryDelay * time.Duration(attempt))
+			}
+
+			if mock.sendHook(delivery).successful() {
+				return
+			}
+		}
+	}()
+}
+
+// sendHook posts the payload of the delivery with the headers GitLab sends and logs the request and its response.
+func (mock *GitlabMock) sendHook(delivery *hookDelivery) *WebhookDelivery {
+	logged := &WebhookDelivery{
+		ID:              int(mock.hookDeliveryIds.Add(1)),
+		HookID:          delivery.hookID,
+		URL:             delivery.url,
+		Trigger:         hookTriggers[delivery.event],
+		RequestHeaders:  map[string]string{},
+		RequestData:     delivery.payload,
+		ResponseHeaders: map[string]string{},
+		ResponseStatus:  "internal error",
+		CreatedAt:       delivery.createdAt,
+	}
+
+	defer mock.logHookDelivery(logged)
+
+	request, err := http.NewRequest(http.MethodPost, delivery.url, bytes.NewReader(delivery.payload))
+	if err != nil {
+		logged.InternalErrorMessage = err.Error()
+		return logged
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", hookUserAgent)
+	request.Header.Set("X-Gitlab-Event", string(delivery.event))
+	request.Header.Set("X-Gitlab-Event-UUID", newUUID())
+	request.Header.Set("X-Gitlab-Webhook-UUID", newUUID())
+
+	if delivery.token != "" {
+		request.Header.Set("X-Gitlab-Token", delivery.token)
+	}
+
+	for _, header := range delivery.headers {
+		request.Header.Set(header.Key, header.Value)
+	}
+
+	for name := range request.Header {
+		logged.RequestHeaders[name] = request.Header.Get(name)
+	}
+
+	// Like GitLab, the secret token is not logged.
+	if delivery.token != "" {
+		logged.RequestHeaders["X-Gitlab-Token"] = "[REDACTED]"
+	}
+
+	start := time.Now()
+	response, err := mock.hookClient.Do(request)
+	logged.ExecutionDuration = time.Since(start).Seconds()
+
+	if err != nil {
+		logged.InternalErrorMessage = err.Error()
+		return logged
+	}
+	defer response.Body.Close()
+
+	body, _ := io.ReadAll(response.Body)
+
+	for name := range response.Header {
+		logged.ResponseHeaders[name] = response.Header.Get(name)
+	}
+
+	logged.ResponseBody = string(body)
+	logged.ResponseStatus = strconv.Itoa(response.StatusCode)
+
+	return logged
+}
+
+func (mock *GitlabMock) logHookDelivery(delivery *WebhookDelivery) {
+	mock.hooksMutex.Lock()
+	defer mock.hooksMutex.Unlock()
+
+	mock.hookDeliveries[delivery.HookID] = append(mock.hookDeliveries[delivery.HookID], delivery)
+}
+
+// GetWebhookDeliveries returns the logged deliveries of the project, group or system hook, oldest first. Call
+// WaitForWebhooks before to include the deliveries still being sent.
+func (mock *GitlabMock) GetWebhookDeliveries(hookID int) []*WebhookDelivery {
+	mock.hooksMutex.Lock()
+	defer mock.hooksMutex.Unlock()
+
+	return append([]*WebhookDelivery{}, mock.hookDeliveries[hookID]...)
+}
+
+// ClearWebhookDeliveries empties the delivery logs of all hooks, e.g. to only assert the deliveries of the next
+// changes.
+func (mock *GitlabMock) ClearWebhookDeliveries() {
+	mock.hooksMutex.Lock()
+	defer mock.hooksMutex.Unlock()
+
+	mock.hookDeliveries = make(map[int][]*WebhookDelivery)
+}
+
+// removeHookDeliveries drops the delivery log of a deleted hook.
+func (mock *GitlabMock) removeHookDeliveries(hookID int) {
+	mock.hooksMutex.Lock()
+	defer mock.hooksMutex.Unlock()
+
+	delete(mock.hookDeliveries, hookID)
+}
+
+// newUUID generates a random version 4 UUID.
+func newUUID() string {
+	uuid := make([]byte, 16)
+	_, _ = rand.Read(uuid)
+
+	uuid[6] = uuid[6]&0x0f | 0x40
+	uuid[8] = uuid[8]&0x3f | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:16])
+}
+
+// TestProjectHook sends a sample event of the trigger to the hook like the Test button of GitLab, regardless of
+// the events the hook subscribed to. The sample is built from the most recent data of the project, e.g. the last
+// commit of the default branch for push_events. The delivery is sent right away and without retries.
+func (mock *GitlabMock) TestProjectHook(project *gitlab.Project, hookID int, trigger gitlab.ProjectHookEvent, user *gitlab.User) (*WebhookDelivery, error) {
+	hook, err := mock.getProjectHook(project, hookID)
+	if err != nil {
+		return nil, err
+	}
+
+	event, payload, err := mock.sampleHookPayload(project, trigger, user)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return mock.sendHook(&hookDelivery{
+		hookID:    hook.ID,
+		url:       hook.URL,
+		token:     mock.hookTokens[hook.ID],
+		headers:   hook.CustomHeaders,
+		event:     event,
+		payload:   body,
+		createdAt: mock.now(),
+	}), nil
+}
+
+// sampleHookPayload returns the event the trigger of a hook test sends. Triggers without data to build the event
+// from fail like in GitLab.
+func (mock *GitlabMock) sampleHookPayload(project *gitlab.Project, trigger gitlab.ProjectHookEvent, user *gitlab.User) (gitlab.EventType, interface{}, error) {
+	switch trigger {
+	case gitlab.ProjectHookEventPush, gitlab.ProjectHookEventTagPush:
+		return mock.samplePushHookPayload(project, trigger == gitlab.ProjectHookEventTagPush, user)
+	case gitlab.ProjectHookEventIssues, gitlab.ProjectHookEventConfidentialIssues:
+		issues := mock.issues[project.ID]
+		if len(issues) == 0 {
+			return "", nil, newAPIError(http.StatusUnprocessableEntity, "Ensure the project has issues.")
+		}
+
+		_, payload := mock.newIssueHookPayload(project, issues[len(issues)-1], nil, "open", user)
+		if trigger == gitlab.ProjectHookEventConfidentialIssues {
+			return gitlab.EventConfidentialIssue, payload, nil
+		}
+
+		return gitlab.EventTypeIssue, payload, nil
+	case gitlab.ProjectHookEventMergeRequests:
+		mergeRequests := mock.mergeRequests[project.ID]
+		if len(mergeRequests) == 0 {
+			return "", nil, newAPIError(http.StatusUnprocessableEntity, "Ensure the project has merge requests.")
+		}
+
+		return gitlab.EventTypeMergeRequest, mock.newMergeRequestHookPayload(project, mergeRequests[len(mergeRequests)-1], nil, "open", "", user), nil
+	case gitlab.ProjectHookEventNote:
+		return mock.sampleNoteHookPayload(project, user)
+	case gitlab.ProjectHookEventPipeline:
+		pipelines := mock.pipelines[project.ID]
+		if len(pipelines) == 0 {
+			return "", nil, newAPIError(http.StatusUnprocessableEntity, "Ensure the project has CI pipelines.")
+		}
+
+		return gitlab.EventTypePipeline, mock.newPipelineHookPayload(project, pipelines[len(pipelines)-1]), nil
+	case gitlab.ProjectHookEventJob:
+		var lastJob *gitlab.Job
+		for _, pipeline := range mock.pipelines[project.ID] {
+			for _, job := range mock.jobs[pipeline.ID] {
+				if lastJob == nil || job.ID > lastJob.ID {
+					lastJob = job
+				}
+			}
+		}
+
+		if lastJob == nil {
+			return "", nil, newAPIError(http.StatusUnprocessableEntity, "Ensure the project has CI jobs.")
+		}
+
+		return gitlab.EventTypeJob, mock.newJobHookPayload(project, lastJob), nil
+	}
+
+	return "", nil, newAPIError(http.StatusBadRequest, "trigger does not have a valid value")
+}
+
+// samplePushHookPayload returns a push of the last commit of the default branch, or of the last tag.
+func (mock *GitlabMock) samplePushHookPayload(project *gitlab.Project, tag bool, user *gitlab.User) (gitlab.EventType, interface{}, error) {
+	repo, err := mock.getRepository(project)
+	if err != nil {
+		return "", nil, err
+	}
+
+	update := refUpdate{Ref: "refs/heads/" + project.DefaultBranch, Before: zeroSHA}
+
+	if tag {
+		tags, err := repo.tags()
+		if err != nil || len(tags) == 0 {
+			return "", nil, newAPIError(http.StatusUnprocessableEntity, "Ensure the project has tags.")
+		}
+
+		update.Ref = "refs/tags/" + tags[len(tags)-1].Name
+		update.After = tags[len(tags)-1].Target
+	} else {
+		head, err := repo.resolveRef(update.Ref)
+		if err != nil {
+			return "", nil, newAPIError(http.StatusUnprocessableEntity, "Ensure the project has at least one commit.")
+		}
+
+		update.After = head
+
+		if commit, err := repo.commit(head); err == nil && len(commit.ParentIDs) > 0 {
+			update.Before = commit.ParentIDs[0]
+		}
+	}
+
+	event, _, payload := mock.newPushHookPayload(project, repo, user, update)
+
+	return event, payload, nil
+}
+
+// sampleNoteHookPayload returns the last comment of the issues and merge requests of the project.
+func (mock *GitlabMock) sampleNoteHookPayload(project *gitlab.Project, user *gitlab.User) (gitlab.EventType, interface{}, error) {
+	noteables := []noteable{}
+	for _, issue := range mock.issues[project.ID] {
+		noteables = append(noteables, issueNoteable(issue))
+	}
+
+	for _, mergeRequest := range mock.mergeRequests[project.ID] {
+		noteables = append(noteables, mergeRequestNoteable(mergeRequest))
+	}
+
+	var lastNote *gitlab.Note
+	var lastNoteable noteable
+	var lastDiscussionID string
+
+	for _, noteable := range noteables {
+		for _, discussion := range mock.discussions[noteable.key()] {
+			for _, note := range discussion.Notes {
+				if !note.System && (lastNote == nil || note.ID > lastNote.ID) {
+					lastNote, lastNoteable, lastDiscussionID = note, noteable, discussion.ID
+				}
+			}
+		}
+	}
+
+	if lastNote == nil {
+		return "", nil, newAPIError(http.StatusUnprocessableEntity, "Ensure the project has notes.")
+	}
+
+	return mock.newNoteHookPayload(project, lastNoteable, lastDiscussionID, lastNote, "create", user)
+}
@@ -0,0 +1,488 @@
+package gitlabapimock
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+const (
+	issueOpened = "opened"
+	issueClosed = "closed"
+)
+
+func toIssueAuthor(user *gitlab.User) *gitlab.IssueAuthor {
+	if user == nil {
+		return nil
+	}
+
+	return &gitlab.IssueAuthor{
+		ID:        user.ID,
+		State:     user.State,
+		WebURL:    user.WebURL,
+		Name:      user.Name,
+		AvatarURL: user.AvatarURL,
+		Username:  user.Username,
+	}
+}
+
+// toIssueAssignees resolves the user IDs. Unknown users are skipped, as GitLab does.
+func (mock *GitlabMock) toIssueAssignees(userIDs []int) []*gitlab.IssueAssignee {
+	assignees := []*gitlab.IssueAssignee{}
+
+	for _, userID := range userIDs {
+		user, err := mock.getUser(userID)
+		if err == nil {
+			assignees = append(assignees, &gitlab.IssueAssignee{
+				ID:        user.ID,
+				State:     user.State,
+				WebURL:    user.WebURL,
+				Name:      user.Name,
+				AvatarURL: user.AvatarURL,
+				Username:  user.Username,
+			})
+		}
+	}
+
+	return assignees
+}
+
+// issueAssignees returns the assignees of the issue as basic users, so the list filters of merge
+// requests can be reused.
+func issueAssignees(issue *gitlab.Issue) []*gitlab.BasicUser {
+	users := []*gitlab.BasicUser{}
+
+	for _, assignee := range issue.Assignees {
+		users = append(users, &gitlab.BasicUser{ID: assignee.ID, Username: assignee.Username, Name: assignee.Name})
+	}
+
+	return users
+}
+
+// issueAuthor returns the author of the issue as basic user.
+func issueAuthor(issue *gitlab.Issue) *gitlab.BasicUser {
+	if issue.Author == nil {
+		return nil
+	}
+
+	return &gitlab.BasicUser{ID: issue.Author.ID, Username: issue.Author.Username, Name: issue.Author.Name}
+}
+
+func setIssueAssignees(issue *gitlab.Issue, assignees []*gitlab.IssueAssignee) {
+	issue.Assignees = assignees
+	issue.Assignee = nil
+	if len(assignees) > 0 {
+		issue.Assignee = assignees[0]
+	}
+}
+
+func setIssueReferences(project *gitlab.Project, issue *gitlab.Issue) {
+	issue.WebURL = fmt.Sprintf("/%s/-/issues/%d", project.PathWithNamespace, issue.IID)
+	issue.References = &gitlab.IssueReferences{
+		Short:    fmt.Sprintf("#%d", issue.IID),
+		Relative: fmt.Sprintf("#%d", issue.IID),
+		Full:     fmt.Sprintf("%s#%d", project.PathWithNamespace, issue.IID),
+	}
+}
+
+// AddIssue opens an issue in the project.
+func (mock *GitlabMock) AddIssue(project *gitlab.Project, options *gitlab.CreateIssueOptions, author *gitlab.User) (*gitlab.Issue, error) {
+	if options.Title == nil || *options.Title == "" {
+		return nil, newAPIError(http.StatusBadRequest, "title is missing")
+	}
+
+	if !mock.hasAccess(project, author, gitlab.GuestPermissions) {
+		return nil, newAPIError(http.StatusForbidden, "403 Forbidden")
+	}
+
+	labels, err := mock.normalizeLabels(project, applyLabelOptions(nil, options.Labels, nil, nil))
+	if err != nil {
+		return nil, err
+	}
+
+	createdAt := mock.now()
+	if options.CreatedAt != nil {
+		createdAt = *options.CreatedAt
+	}
+
+	var iid int
+
+	// Only admins and owners may set the IID, e.g. when importing issues.
+	if options.IID != nil && mock.hasAccess(project, author, gitlab.OwnerPermissions) {
+		for _, issue := range mock.issues[project.ID] {
+			if issue.IID == *options.IID {
+				return nil, newAPIError(http.StatusConflict, map[string][]string{"iid": {"has already been taken"}})
+			}
+		}
+
+		iid = *options.IID
+
+		key := fmt.Sprintf("issue/%d", project.ID)
+		mock.iids[key] = max(mock.iids[key], iid)
+	} else {
+		iid = mock.nextIID("issue", project.ID)
+	}
+
+	issueType := "issue"
+	if options.IssueType != nil {
+		issueType = *options.IssueType
+	}
+
+	issue := &gitlab.Issue{
+		ID:           int(mock.issueIds.Add(1)),
+		IID:          iid,
+		ProjectID:    project.ID,
+		Title:        *options.Title,
+		Description:  valueOf(options.Description),
+		State:        issueOpened,
+		Author:       toIssueAuthor(author),
+		Labels:       labels,
+		Confidential: valueOf(options.Confidential),
+		DueDate:      options.DueDate,
+		Weight:       valueOf(options.Weight),
+		CreatedAt:    &createdAt,
+		UpdatedAt:    &createdAt,
+		IssueType:    &issueType,
+	}
+
+	setIssueReferences(project, issue)
+	setIssueAssignees(issue, []*gitlab.IssueAssignee{})
+
+	if options.AssigneeIDs != nil {
+		setIssueAssignees(issue, mock.toIssueAssignees(*options.AssigneeIDs))
+	}
+
+	issue.Milestone = mock.resolveMilestone(project, options.MilestoneID)
+
+	mock.issues[project.ID] = append(mock.issues[project.ID], issue)
+	mock.recordMilestoneChange(noteableIssue, issue.ID, project.ID, issue.IID, issue.Weight, nil, issue.Milestone, author)
+	mock.fireIssueHooks(project, issue, nil, "open", author)
+
+	return issue, nil
+}
+
+func (mock *GitlabMock) getIssue(project *gitlab.Project, iid int) (*gitlab.Issue, error) {
+	for _, issue := range mock.issues[project.ID] {
+		if issue.IID == iid {
+			mock.refreshIssue(issue)
+			return issue, nil
+		}
+	}
+
+	return nil, newAPIError(http.StatusNotFound, "404 Not found")
+}
+
+// getIssueByID returns the issue with the global ID together with its project.
+func (mock *GitlabMock) getIssueByID(issueID int) (*gitlab.Project, *gitlab.Issue, error) {
+	for projectID, issues := range mock.issues {
+		for _, issue := range issues {
+			if issue.ID == issueID {
+				mock.refreshIssue(issue)
+				return mock.projects[projectID], issue, nil
+			}
+		}
+	}
+
+	return nil, nil, newAPIError(http.StatusNotFound, "404 Not found")
+}
+
+// getIssues returns the issues of the project, which are visible to the user.
+func (mock *GitlabMock) getIssues(project *gitlab.Project, user *gitlab.User) []*gitlab.Issue {
+	issues := []*gitlab.Issue{}
+
+	for _, issue := range mock.issues[project.ID] {
+		if mock.canReadIssue(project, issue, user) {
+			mock.refreshIssue(issue)
+			issues = append(issues, issue)
+		}
+	}
+
+	return issues
+}
+
+// canReadIssue reports whether the user may see the issue. Confidential issues are only visible to
+// reporters, their author and their assignees.
+func (mock *GitlabMock) canReadIssue(project *gitlab.Project, issue *gitlab.Issue, user *gitlab.User) bool {
+	if !mock.hasAccess(project, user, gitlab.GuestPermissions) {
+		return false
+	}
+
+	if !issue.Confidential || mock.hasAccess(project, user, gitlab.ReporterPermissions) {
+		return true
+	}
+
+	if issue.Author != nil && issue.Author.ID == user.ID {
+		return true
+	}
+
+	return slices.ContainsFunc(issue.Assignees, func(assignee *gitlab.IssueAssignee) bool {
+		return assignee.ID == user.ID
+	})
+}
+
+func (mock *GitlabMock) refreshIssue(issue *gitlab.Issue) {
+	issue.UserNotesCount = mock.userNotesCount(issueNoteable(issue))
+}
+
+// updateIssue edits the issue. Guests may only edit their own issues.
+func (mock *GitlabMock) updateIssue(project *gitlab.Project, iid int, options *gitlab.UpdateIssueOptions, user *gitlab.User) (*gitlab.Issue, error) {
+	issue, err := mock.getIssue(project, iid)
+	if err != nil {
+		return nil, err
+	}
+
+	if !mock.canReadIssue(project, issue, user) {
+		return nil, newAPIError(http.StatusNotFound, "404 Not found")
+	}
+
+	isAuthor := user != nil && issue.Author != nil && issue.Author.ID == user.ID
+	if !isAuthor && !mock.hasAccess(project, user, gitlab.ReporterPermissions) {
+		return nil, newAPIError(http.StatusForbidden, "403 Forbidden")
+	}
+
+	labels, err := mock.normalizeLabels(project, applyLabelOptions(issue.Labels, options.Labels, options.AddLabels, options.RemoveLabels))
+	if err != nil {
+		return nil, err
+	}
+
+	before := *issue
+	titleBefore := issue.Title
+	confidentialBefore := issue.Confidential
+	labelsBefore := slices.Clone(issue.Labels)
+	assigneesBefore := basicUsernames(issueAssignees(issue))
+	milestoneBefore := issue.Milestone
+
+	if options.Title != nil {
+		if *options.Title == "" {
+			return nil, newAPIError(http.StatusBadRequest, map[string][]string{"title": {"can't be blank"}})
+		}
+
+		issue.Title = *options.Title
+	}
+
+	if options.Description != nil {
+		issue.Description = *options.Description
+	}
+
+	if options.Confidential != nil {
+		issue.Confidential = *options.Confidential
+	}
+
+	if options.AssigneeIDs != nil {
+		setIssueAssignees(issue, mock.toIssueAssignees(*options.AssigneeIDs))
+	}
+
+	issue.Labels = labels
+
+	if options.MilestoneID != nil {
+		issue.Milestone = mock.resolveMilestone(project, options.MilestoneID)
+		mock.recordMilestoneChange(noteableIssue, issue.ID, project.ID, issue.IID, issue.Weight, milestoneBefore, issue.Milestone, user)
+	}
+
+	if options.DueDate != nil {
+		issue.DueDate = options.DueDate
+	}
+
+	if options.Weight != nil {
+		issue.Weight = *options.Weight
+	}
+
+	if options.DiscussionLocked != nil {
+		issue.DiscussionLocked = *options.DiscussionLocked
+	}
+
+	if options.IssueType != nil {
+		issue.IssueType = options.IssueType
+	}
+
+	if options.StateEvent != nil {
+		err = mock.changeIssueState(issue, *options.StateEvent, user)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	updatedAt := mock.now()
+	if options.UpdatedAt != nil {
+		updatedAt = *options.UpdatedAt
+	}
+	issue.UpdatedAt = &updatedAt
+
+	noteable := issueNoteable(issue)
+
+	if issue.Title != titleBefore {
+		mock.addSystemNote(noteable, fmt.Sprintf("changed title from **%s** to **%s**", titleBefore, issue.Title), user)
+	}
+
+	if issue.Confidential != confidentialBefore {
+		if issue.Confidential {
+			mock.addSystemNote(noteable, "made the issue confidential", user)
+		} else {
+			mock.addSystemNote(noteable, "made the issue visible to everyone", user)
+		}
+	}
+
+	if body := labelsSystemNote(labelsBefore, issue.Labels); body != "" {
+		mock.addSystemNote(noteable, body, user)
+	}
+
+	if body := usersSystemNote(assigneesBefore, basicUsernames(issueAssignees(issue)), "assigned to", "unassigned"); body != "" {
+		mock.addSystemNote(noteable, body, user)
+	}
+
+	if body := milestoneSystemNote(milestoneBefore, issue.Milestone); body != "" {
+		mock.addSystemNote(noteable, body, user)
+	}
+
+	mock.refreshIssue(issue)
+
+	action := "update"
+	if issue.State != before.State {
+		action = *options.StateEvent
+	}
+
+	mock.fireIssueHooks(project, issue, &before, action, user)
+
+	return issue, nil
+}
+
+// changeIssueState closes or reopens the issue.
+func (mock *GitlabMock) changeIssueState(issue *gitlab.Issue, stateEvent string, user *gitlab.User) error {
+	switch {
+	case stateEvent == "close" && issue.State == issueOpened:
+		closedAt := mock.now()
+		issue.State = issueClosed
+		issue.ClosedAt = &closedAt
+		issue.ClosedBy = nil
+		if user != nil {
+			issue.ClosedBy = &gitlab.IssueCloser{
+				ID:        user.ID,
+				State:     user.State,
+				WebURL:    user.WebURL,
+				Name:      user.Name,
+				AvatarURL: user.AvatarURL,
+				Username:  user.Username,
+			}
+		}
+		mock.addSystemNote(issueNoteable(issue), "closed", user)
+		mock.addBurndownEvent(issue.Milestone, "closed", issue.Weight)
+	case stateEvent == "reopen" && issue.State == issueClosed:
+		if issue.MovedToID != 0 {
+			return newAPIError(http.StatusBadRequest, "Cannot reopen a moved issue")
+		}
+
+		issue.State = issueOpened
+		issue.ClosedAt = nil
+		issue.ClosedBy = nil
+		mock.addSystemNote(issueNoteable(issue), "reopened", user)
+		mock.addBurndownEvent(issue.Milestone, "reopened", issue.Weight)
+	case stateEvent != "close" && stateEvent != "reopen":
+		return newAPIError(http.StatusBadRequest, "state_event does not have a valid value")
+	}
+
+	return nil
+}
+
+// moveIssue moves the issue to another project. The issue is copied with a new IID and its notes to
+// the target project and the original issue is closed, as GitLab does.
+func (mock *GitlabMock) moveIssue(project *gitlab.Project, iid int, toProjectID int, user *gitlab.User) (*gitlab.Issue, error) {
+	issue, err := mock.getIssue(project, iid)
+	if err != nil {
+		return nil, err
+	}
+
+	targetProject, targetProjectExists := mock.projects[toProjectID]
+	if !targetProjectExists {
+		return nil, newAPIError(http.StatusNotFound, "404 Project Not Found")
+	}
+
+	if !mock.hasAccess(project, user, gitlab.ReporterPermissions) || !mock.hasAccess(targetProject, user, gitlab.ReporterPermissions) {
+		return nil, newAPIError(http.StatusBadRequest, "Cannot move issue due to insufficient permissions!")
+	}
+
+	if targetProject.ID == project.ID {
+		return nil, newAPIError(http.StatusBadRequest, "Cannot move issue to project it originates from!")
+	}
+
+	if issue.MovedToID != 0 {
+		return nil, newAPIError(http.StatusBadRequest, "Cannot move issue which has already been moved!")
+	}
+
+	movedIssue := *issue
+	movedIssue.ID = int(mock.issueIds.Add(1))
+	movedIssue.IID = mock.nextIID("issue", targetProject.ID)
+	movedIssue.ProjectID = targetProject.ID
+	movedIssue.Labels = slices.Clone(issue.Labels)
+	movedIssue.Assignees = slices.Clone(issue.Assignees)
+	setIssueReferences(targetProject, &movedIssue)
+
+	// The milestone is kept only if it is available in the target project as well.
+	if !slices.Contains(mock.availableMilestones(targetProject), movedIssue.Milestone) {
+		movedIssue.Milestone = nil
+	}
+
+	updatedAt := mock.now()
+	movedIssue.UpdatedAt = &updatedAt
+
+	mock.issues[targetProject.ID] = append(mock.issues[targetProject.ID], &movedIssue)
+	mock.recordMilestoneChange(noteableIssue, movedIssue.ID, targetProject.ID, movedIssue.IID, movedIssue.Weight, nil, movedIssue.Milestone, user)
+
+	for _, discussion := range mock.getDiscussions(issueNoteable(issue)) {
+		copiedDiscussion := &gitlab.Discussion{IndividualNote: discussion.IndividualNote}
+
+		for _, note := range discussion.Notes {
+			copiedNote := *note
+			copiedNote.ID = int(mock.noteIds.Add(1))
+			copiedNote.NoteableID = movedIssue.ID
+			copiedNote.NoteableIID = movedIssue.IID
+			copiedNote.ProjectID = targetProject.ID
+			copiedDiscussion.Notes = append(copiedDiscussion.Notes, &copiedNote)
+		}
+
+		copiedDiscussion.ID = discussionID(copiedDiscussion.Notes[0].ID)
+
+		key := issueNoteable(&movedIssue).key()
+		mock.discussions[key] = append(mock.discussions[key], copiedDiscussion)
+	}
+
+	mock.addSystemNote(issueNoteable(&movedIssue), fmt.Sprintf("moved from %s", issue.References.Full), user)
+
+	before := *issue
+
+	if issue.State == issueOpened {
+		err = mock.changeIssueState(issue, "close", user)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	issue.MovedToID = movedIssue.ID
+	mock.addSystemNote(issueNoteable(issue), fmt.Sprintf("moved to %s", movedIssue.References.Full), user)
+
+	mock.refreshIssue(&movedIssue)
+	mock.fireIssueHooks(targetProject, &movedIssue, nil, "open", user)
+	mock.fireIssueHooks(project, issue, &before, "close", user)
+
+	return &movedIssue, nil
+}
+
+// deleteIssue removes the issue. Only admins and owners may do this.
+func (mock *GitlabMock) deleteIssue(project *gitlab.Project, iid int, user *gitlab.User) error {
+	if !mock.hasAccess(project, user, gitlab.OwnerPermissions) {
+		return newAPIError(http.StatusForbidden, "403 Forbidden")
+	}
+
+	issues := mock.issues[project.ID]
+
+	for idx, issue := range issues {
+		if issue.IID == iid {
+			mock.issues[project.ID] = append(issues[:idx:idx], issues[idx+1:]...)
+			delete(mock.discussions, issueNoteable(issue).key())
+			return nil
+		}
+	}
+
+	return newAPIError(http.StatusNotFound, "404 Not found")
+}
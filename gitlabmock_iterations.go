@@ -0,0 +1,231 @@
+package gitlabapimock
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// The states of iterations as returned by the iterations API.
+const (
+	iterationUpcoming = 1
+	iterationCurrent  = 2
+	iterationClosed   = 3
+)
+
+// IterationCadence groups the iterations of a group, see https://docs.gitlab.com/ee/user/group/iterations/.
+// Automatic cadences schedule iterations of the same length themselves, the iterations of manual
+// cadences are added with AddIteration. GitLab has no REST API for cadences, so they are only
+// available in Go.
+type IterationCadence struct {
+	ID                  int       `json:"id"`
+	GroupID             int       `json:"group_id"`
+	Title               string    `json:"title"`
+	StartDate           time.Time `json:"start_date"`
+	DurationInWeeks     int       `json:"duration_in_weeks"`
+	IterationsInAdvance int       `json:"iterations_in_advance"`
+	Automatic           bool      `json:"automatic"`
+
+	iterations []*gitlab.GroupIteration
+	now        func() time.Time
+}
+
+// Iterations returns the iterations of the cadence ordered by their start date.
+func (cadence *IterationCadence) Iterations() []*gitlab.GroupIteration {
+	for _, iteration := range cadence.iterations {
+		refreshIteration(iteration, cadence.now())
+	}
+
+	return cadence.iterations
+}
+
+func truncateToDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// refreshIteration updates the state of the iteration, which depends on the current date.
+func refreshIteration(iteration *gitlab.GroupIteration, now time.Time) {
+	today := truncateToDay(now)
+
+	switch {
+	case time.Time(*iteration.DueDate).Before(today):
+		iteration.State = iterationClosed
+	case time.Time(*iteration.StartDate).After(today):
+		iteration.State = iterationUpcoming
+	default:
+		iteration.State = iterationCurrent
+	}
+}
+
+// AddIterationCadence creates an iteration cadence in the group. If durationInWeeks is positive, the
+// cadence is automatic: iterations are scheduled from the start date up to the current one plus the
+// requested number of upcoming iterations.
+func (mock *GitlabMock) AddIterationCadence(group *gitlab.Group, title string, startDate time.Time, durationInWeeks int, iterationsInAdvance int) (*IterationCadence, error) {
+	if title == "" {
+		return nil, errors.New("title is missing")
+	}
+
+	if durationInWeeks < 0 || durationInWeeks > 4 {
+		return nil, errors.New("duration in weeks must be between 1 and 4")
+	}
+
+	if durationInWeeks > 0 && iterationsInAdvance < 1 {
+		return nil, errors.New("iterations in advance must be at least 1")
+	}
+
+	cadence := &IterationCadence{
+		ID:                  int(mock.iterationCadenceIds.Add(1)),
+		GroupID:             group.ID,
+		Title:               title,
+		StartDate:           truncateToDay(startDate),
+		DurationInWeeks:     durationInWeeks,
+		IterationsInAdvance: iterationsInAdvance,
+		Automatic:           durationInWeeks > 0,
+		now:                 mock.now,
+	}
+
+	mock.iterationCadences[group.ID] = append(mock.iterationCadences[group.ID], cadence)
+
+	if cadence.Automatic {
+		mock.scheduleIterations(cadence)
+	}
+
+	return cadence, nil
+}
+
+// scheduleIterations adds the iterations of the automatic cadence up to the current iteration and the
+// requested number of upcoming iterations.
+func (mock *GitlabMock) scheduleIterations(cadence *IterationCadence) {
+	today := truncateToDay(mock.now())
+
+	startDate := cadence.StartDate
+	upcoming := 0
+
+	for _, iteration := range cadence.iterations {
+		startDate = time.Time(*iteration.DueDate).AddDate(0, 0, 1)
+		if time.Time(*iteration.StartDate).After(today) {
+			upcoming++
+		}
+	}
+
+	for !startDate.After(today) || upcoming < cadence.IterationsInAdvance {
+		dueDate := startDate.AddDate(0, 0, 7*cadence.DurationInWeeks-1)
+		if startDate.After(today) {
+			upcoming++
+		}
+
+		mock.newIteration(cadence, "", startDate, dueDate)
+		startDate = dueDate.AddDate(0, 0, 1)
+	}
+}
+
+// AddIteration adds an iteration to the manual cadence. Iterations of a cadence must not overlap.
+func (mock *GitlabMock) AddIteration(cadence *IterationCadence, title string, startDate time.Time, dueDate time.Time) (*gitlab.GroupIteration, error) {
+	if cadence.Automatic {
+		return nil, newAPIError(http.StatusBadRequest, "Iterations can't be added to automatic iteration cadences")
+	}
+
+	startDate, dueDate = truncateToDay(startDate), truncateToDay(dueDate)
+
+	if dueDate.Before(startDate) {
+		return nil, newAPIError(http.StatusBadRequest, map[string][]string{"due_date": {"must be greater than start date"}})
+	}
+
+	for _, iteration := range cadence.iterations {
+		if !startDate.After(time.Time(*iteration.DueDate)) && !dueDate.Before(time.Time(*iteration.StartDate)) {
+			return nil, newAPIError(http.StatusBadRequest, "Dates cannot overlap with other existing Iterations within this iterations cadence")
+		}
+	}
+
+	return mock.newIteration(cadence, title, startDate, dueDate), nil
+}
+
+func (mock *GitlabMock) newIteration(cadence *IterationCadence, title string, startDate time.Time, dueDate time.Time) *gitlab.GroupIteration {
+	createdAt := mock.now()
+	start, due := gitlab.ISOTime(startDate), gitlab.ISOTime(dueDate)
+
+	group, _ := mock.getGroup(cadence.GroupID)
+
+	iteration := &gitlab.GroupIteration{
+		ID:        int(mock.iterationIds.Add(1)),
+		IID:       mock.nextIID("iteration", cadence.GroupID),
+		GroupID:   cadence.GroupID,
+		Title:     title,
+		StartDate: &start,
+		DueDate:   &due,
+		CreatedAt: &createdAt,
+		UpdatedAt: &createdAt,
+	}
+
+	if group != nil {
+		iteration.WebURL = fmt.Sprintf("/groups/%s/-/iterations/%d", group.FullPath, iteration.ID)
+	}
+
+	// Iterations are kept ordered by their start date to number them.
+	idx := 0
+	for idx < len(cadence.iterations) && time.Time(*cadence.iterations[idx].StartDate).Before(startDate) {
+		idx++
+	}
+
+	cadence.iterations = append(cadence.iterations[:idx], append([]*gitlab.GroupIteration{iteration}, cadence.iterations[idx:]...)...)
+
+	for sequence, other := range cadence.iterations {
+		other.Sequence = sequence + 1
+	}
+
+	refreshIteration(iteration, mock.now())
+
+	return iteration
+}
+
+// groupIterations returns the iterations of the cadences of the group and, if requested, of its ancestors.
+// Automatic cadences schedule new iterations as time passes.
+func (mock *GitlabMock) groupIterations(group *gitlab.Group, includeAncestors bool) []*gitlab.GroupIteration {
+	groups := []*gitlab.Group{group}
+	if includeAncestors {
+		groups = mock.groupAncestors(group)
+	}
+
+	iterations := []*gitlab.GroupIteration{}
+
+	for _, group := range groups {
+		for _, cadence := range mock.iterationCadences[group.ID] {
+			if cadence.Automatic {
+				mock.scheduleIterations(cadence)
+			}
+
+			iterations = append(iterations, cadence.Iterations()...)
+		}
+	}
+
+	return iterations
+}
+
+// SetIssueIteration assigns the issue to the iteration, which must belong to the group of the project of
+// the issue or one of its ancestors. A nil iteration removes the issue from its iteration.
+func (mock *GitlabMock) SetIssueIteration(issue *gitlab.Issue, iteration *gitlab.GroupIteration) error {
+	if iteration != nil {
+		project := mock.projects[issue.ProjectID]
+
+		group, err := mock.getGroup(project.Namespace.ID)
+		if err != nil {
+			return err
+		}
+
+		available := slices.ContainsFunc(mock.groupAncestors(group), func(ancestor *gitlab.Group) bool {
+			return ancestor.ID == iteration.GroupID
+		})
+
+		if !available {
+			return fmt.Errorf("iteration %d is not available in project %s", iteration.ID, project.PathWithNamespace)
+		}
+	}
+
+	issue.Iteration = iteration
+
+	return nil
+}
@@ -0,0 +1,362 @@
+package gitlabapimock
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+const (
+	whenOnSuccess = "on_success"
+	whenOnFailure = "on_failure"
+	whenAlways    = "always"
+	whenManual    = "manual"
+)
+
+// jobTransitions lists the statuses a job can change to, following the state machine of GitLab builds.
+// Finished jobs cannot change their status anymore, they are retried as new jobs instead.
+var jobTransitions = map[gitlab.BuildStateValue][]gitlab.BuildStateValue{
+	gitlab.Created: {gitlab.Pending, gitlab.Manual, gitlab.Skipped, gitlab.Canceled},
+	gitlab.Manual:  {gitlab.Pending, gitlab.Skipped, gitlab.Canceled},
+	gitlab.Pending: {gitlab.Running, gitlab.Failed, gitlab.Canceled, gitlab.Skipped},
+	gitlab.Running: {gitlab.Success, gitlab.Failed, gitlab.Canceled},
+}
+
+func jobFinished(job *gitlab.Job) bool {
+	switch gitlab.BuildStateValue(job.Status) {
+	case gitlab.Success, gitlab.Failed, gitlab.Canceled, gitlab.Skipped:
+		return true
+	}
+
+	return false
+}
+
+func jobCancelable(job *gitlab.Job) bool {
+	switch gitlab.BuildStateValue(job.Status) {
+	case gitlab.Created, gitlab.Pending, gitlab.Running:
+		return true
+	}
+
+	return false
+}
+
+func jobRetryable(job *gitlab.Job) bool {
+	switch gitlab.BuildStateValue(job.Status) {
+	case gitlab.Success, gitlab.Failed, gitlab.Canceled:
+		return true
+	}
+
+	return false
+}
+
+// getJob returns the job of the project, including retried jobs.
+func (mock *GitlabMock) getJob(project *gitlab.Project, jobID int) (*gitlab.Job, error) {
+	for _, pipeline := range mock.pipelines[project.ID] {
+		for _, job := range mock.jobs[pipeline.ID] {
+			if job.ID == jobID {
+				return job, nil
+			}
+		}
+	}
+
+	return nil, newAPIError(http.StatusNotFound, "404 Job Not Found")
+}
+
+// getProjectJobs returns the jobs of all pipelines of the project, newest first.
+func (mock *GitlabMock) getProjectJobs(project *gitlab.Project, includeRetried bool) []*gitlab.Job {
+	jobs := []*gitlab.Job{}
+
+	for _, pipeline := range mock.pipelines[project.ID] {
+		jobs = append(jobs, mock.pipelineJobs(pipeline, includeRetried)...)
+	}
+
+	slices.SortFunc(jobs, func(left *gitlab.Job, right *gitlab.Job) int {
+		return right.ID - left.ID
+	})
+
+	return jobs
+}
+
+// pipelineJobs returns the jobs of the pipeline in the order of their stages. Retried jobs are only included
+// on request.
+func (mock *GitlabMock) pipelineJobs(pipeline *gitlab.Pipeline, includeRetried bool) []*gitlab.Job {
+	jobs := []*gitlab.Job{}
+
+	for _, job := range mock.jobs[pipeline.ID] {
+		if includeRetried || !mock.retriedJobs[job.ID] {
+			jobs = append(jobs, job)
+		}
+	}
+
+	return jobs
+}
+
+// GetPipelineJobs returns the current jobs of the pipeline, without the jobs that have been retried.
+func (mock *GitlabMock) GetPipelineJobs(pipeline *gitlab.Pipeline) []*gitlab.Job {
+	return mock.pipelineJobs(pipeline, false)
+}
+
+// GetPipelineJob returns the current job with the given name of the pipeline.
+func (mock *GitlabMock) GetPipelineJob(pipeline *gitlab.Pipeline, name string) (*gitlab.Job, error) {
+	for _, job := range mock.pipelineJobs(pipeline, false) {
+		if job.Name == name {
+			return job, nil
+		}
+	}
+
+	return nil, fmt.Errorf("job %s not found in pipeline %d", name, pipeline.ID)
+}
+
+// SetJobStatus changes the status of the job as a runner would, e.g. to running, success or failed, and
+// processes the pipeline of the job afterwards. Transitions GitLab does not allow are rejected.
+func (mock *GitlabMock) SetJobStatus(job *gitlab.Job, status gitlab.BuildStateValue) error {
+	if !slices.Contains(jobTransitions[gitlab.BuildStateValue(job.Status)], status) {
+		return fmt.Errorf("job %d cannot transition from %s to %s", job.ID, job.Status, status)
+	}
+
+	mock.transitionJob(job, status)
+
+	return mock.processPipelineOfJob(job)
+}
+
+// AdvanceJob moves the job one step further on its way to success: pending jobs start running and running
+// jobs succeed. Manual jobs have to be played and jobs waiting for earlier stages cannot be advanced.
+func (mock *GitlabMock) AdvanceJob(job *gitlab.Job) error {
+	switch gitlab.BuildStateValue(job.Status) {
+	case gitlab.Pending:
+		return mock.SetJobStatus(job, gitlab.Running)
+	case gitlab.Running:
+		return mock.SetJobStatus(job, gitlab.Success)
+	}
+
+	return fmt.Errorf("job %d cannot be advanced from %s", job.ID, job.Status)
+}
+
+// transitionJob sets the status of the job and the timestamps that come with it.
+func (mock *GitlabMock) transitionJob(job *gitlab.Job, status gitlab.BuildStateValue) {
+	now := mock.now()
+	statusBefore := job.Status
+
+	switch status {
+	case gitlab.Pending:
+		job.QueuedDuration = 0
+	case gitlab.Running:
+		job.StartedAt = &now
+		if job.CreatedAt != nil {
+			job.QueuedDuration = now.Sub(*job.CreatedAt).Seconds()
+		}
+	}
+
+	job.Status = string(status)
+
+	if jobFinished(job) {
+		job.FinishedAt = &now
+		if job.StartedAt != nil {
+			job.Duration = now.Sub(*job.StartedAt).Seconds()
+		}
+	}
+
+	if job.Status != statusBefore {
+		mock.fireJobHooks(job)
+		publishedJob := *job
+		mock.publish(JobStatusChanged{Job: &publishedJob, OldStatus: statusBefore})
+	}
+}
+
+func (mock *GitlabMock) processPipelineOfJob(job *gitlab.Job) error {
+	project, projectExists := mock.projects[job.Pipeline.ProjectID]
+	if !projectExists {
+		return fmt.Errorf("project %d not found", job.Pipeline.ProjectID)
+	}
+
+	pipeline, err := mock.getPipeline(project, job.Pipeline.ID)
+	if err != nil {
+		return err
+	}
+
+	mock.processPipeline(pipeline)
+
+	return nil
+}
+
+// newJob creates a job of the pipeline in the created status, it is enqueued by processPipeline.
+func (mock *GitlabMock) newJob(pipeline *gitlab.Pipeline, spec PipelineJob, commit *gitlab.Commit, user *gitlab.User) *gitlab.Job {
+	createdAt := mock.now()
+
+	job := &gitlab.Job{
+		ID:           int(mock.jobIds.Add(1)),
+		Name:         spec.Name,
+		Stage:        spec.Stage,
+		Status:       string(gitlab.Created),
+		AllowFailure: spec.AllowFailure,
+		Ref:          pipeline.Ref,
+		Tag:          pipeline.Tag,
+		Commit:       commit,
+		CreatedAt:    &createdAt,
+		User:         user,
+		TagList:      append([]string{}, spec.Tags...),
+	}
+
+	job.Pipeline.ID = pipeline.ID
+	job.Pipeline.ProjectID = pipeline.ProjectID
+	job.Pipeline.Ref = pipeline.Ref
+	job.Pipeline.Sha = pipeline.SHA
+	job.Pipeline.Status = pipeline.Status
+
+	if project, projectExists := mock.projects[pipeline.ProjectID]; projectExists {
+		job.WebURL = fmt.Sprintf("/%s/-/jobs/%d", project.PathWithNamespace, job.ID)
+	}
+
+	mock.jobs[pipeline.ID] = append(mock.jobs[pipeline.ID], job)
+	mock.jobSpecs[job.ID] = spec
+	mock.jobTokens[job.ID] = newToken("glcbt-")
+
+	return job
+}
+
+// GetJobToken returns the CI_JOB_TOKEN of the job, runners authenticate their requests for the job with it.
+func (mock *GitlabMock) GetJobToken(job *gitlab.Job) string {
+	return mock.jobTokens[job.ID]
+}
+
+// jobByToken returns the job the CI_JOB_TOKEN belongs to.
+func (mock *GitlabMock) jobByToken(token string) (*gitlab.Job, bool) {
+	if token == "" {
+		return nil, false
+	}
+
+	for jobID, jobToken := range mock.jobTokens {
+		if jobToken != token {
+			continue
+		}
+
+		for _, jobs := range mock.jobs {
+			for _, job := range jobs {
+				if job.ID == jobID {
+					return job, true
+				}
+			}
+		}
+	}
+
+	return nil, false
+}
+
+// cancelJob cancels a created, pending or running job. Other jobs are returned unchanged, as GitLab does.
+func (mock *GitlabMock) cancelJob(project *gitlab.Project, job *gitlab.Job, user *gitlab.User) (*gitlab.Job, error) {
+	if !mock.hasAccess(project, user, gitlab.DeveloperPermissions) {
+		return nil, newAPIError(http.StatusForbidden, "403 Forbidden")
+	}
+
+	if jobCancelable(job) {
+		mock.transitionJob(job, gitlab.Canceled)
+
+		err := mock.processPipelineOfJob(job)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return job, nil
+}
+
+// retryJob replaces the finished job by a new job with the same name in its pipeline. Jobs of later stages
+// that have been skipped because of the job are created again.
+func (mock *GitlabMock) retryJob(project *gitlab.Project, job *gitlab.Job, user *gitlab.User) (*gitlab.Job, error) {
+	if !mock.hasAccess(project, user, gitlab.DeveloperPermissions) {
+		return nil, newAPIError(http.StatusForbidden, "403 Forbidden")
+	}
+
+	if !jobRetryable(job) || mock.retriedJobs[job.ID] {
+		return nil, newAPIError(http.StatusForbidden, "403 Forbidden - Job is not retryable")
+	}
+
+	pipeline, err := mock.getPipeline(project, job.Pipeline.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	retriedJob := mock.recreateJob(pipeline, job, user)
+	mock.resetSkippedJobs(pipeline, job.Stage)
+	mock.processPipeline(pipeline)
+
+	return retriedJob, nil
+}
+
+// recreateJob marks the job as retried and adds a copy of it to the pipeline.
+func (mock *GitlabMock) recreateJob(pipeline *gitlab.Pipeline, job *gitlab.Job, user *gitlab.User) *gitlab.Job {
+	mock.retriedJobs[job.ID] = true
+
+	if user == nil {
+		user = job.User
+	}
+
+	return mock.newJob(pipeline, mock.jobSpecs[job.ID], job.Commit, user)
+}
+
+// resetSkippedJobs creates the skipped jobs of the stages after the given stage again, so that they run once
+// the retried jobs are finished.
+func (mock *GitlabMock) resetSkippedJobs(pipeline *gitlab.Pipeline, stage string) {
+	stages := mock.pipelineStages(pipeline)
+	stageIndex := slices.Index(stages, stage)
+
+	for _, job := range mock.pipelineJobs(pipeline, false) {
+		if job.Status == string(gitlab.Skipped) && slices.Index(stages, job.Stage) > stageIndex {
+			job.Status = string(gitlab.Created)
+			job.FinishedAt = nil
+		}
+	}
+}
+
+// playJob starts a manual job.
+func (mock *GitlabMock) playJob(project *gitlab.Project, job *gitlab.Job, user *gitlab.User) (*gitlab.Job, error) {
+	if !mock.hasAccess(project, user, gitlab.DeveloperPermissions) {
+		return nil, newAPIError(http.StatusForbidden, "403 Forbidden")
+	}
+
+	if mock.jobSpecs[job.ID].When != whenManual || mock.retriedJobs[job.ID] {
+		return nil, newAPIError(http.StatusBadRequest, "400 Bad request - Unplayable Job")
+	}
+
+	switch gitlab.BuildStateValue(job.Status) {
+	case gitlab.Manual:
+		if user != nil {
+			job.User = user
+		}
+
+		mock.transitionJob(job, gitlab.Pending)
+
+		err := mock.processPipelineOfJob(job)
+		if err != nil {
+			return nil, err
+		}
+
+		return job, nil
+	case gitlab.Success, gitlab.Failed, gitlab.Canceled:
+		return mock.retryJob(project, job, user)
+	}
+
+	return nil, newAPIError(http.StatusBadRequest, "400 Bad request - Unplayable Job")
+}
+
+// eraseJob removes the trace and artifacts of a finished job.
+func (mock *GitlabMock) eraseJob(project *gitlab.Project, job *gitlab.Job, user *gitlab.User) (*gitlab.Job, error) {
+	if !mock.hasAccess(project, user, gitlab.DeveloperPermissions) {
+		return nil, newAPIError(http.StatusForbidden, "403 Forbidden")
+	}
+
+	if !jobFinished(job) || job.ErasedAt != nil {
+		return nil, newAPIError(http.StatusForbidden, "403 Forbidden - Job is not erasable!")
+	}
+
+	erasedAt := mock.now()
+	job.ErasedAt = &erasedAt
+
+	delete(mock.jobArtifacts, job.ID)
+	delete(mock.jobTraces, job.ID)
+	mock.updateArtifactFields(job)
+
+	return job, nil
+}
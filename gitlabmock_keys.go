@@ -0,0 +1,597 @@
+package gitlabapimock
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+const (
+	gpgPublicKeyBegin = "-----BEGIN PGP PUBLIC KEY BLOCK-----"
+	gpgPublicKeyEnd   = "-----END PGP PUBLIC KEY BLOCK-----"
+)
+
+var (
+	// sshKeyTypes are the key types GitLab accepts, see https://docs.gitlab.com/ee/user/ssh.html#supported-ssh-key-types.
+	sshKeyTypes = []string{
+		"ssh-rsa", "ssh-dss", "ssh-ed25519", "ecdsa-sha2-nistp256", "ecdsa-sha2-nistp384", "ecdsa-sha2-nistp521",
+		"sk-ecdsa-sha2-nistp256@openssh.com", "sk-ssh-ed25519@openssh.com",
+	}
+
+	errInvalidKey       = newAPIError(http.StatusBadRequest, map[string][]string{"key": {"is invalid"}})
+	errFingerprintTaken = newAPIError(http.StatusBadRequest, map[string][]string{"fingerprint_sha256": {"has already been taken"}})
+)
+
+// sshKey is an SSH key of a user or a deploy key, see https://docs.gitlab.com/ee/api/users.html#list-ssh-keys and
+// https://docs.gitlab.com/ee/api/deploy_keys.html. Deploy keys belong to the user who added them first.
+type sshKey struct {
+	ID                int        `json:"id"`
+	Title             string     `json:"title"`
+	Key               string     `json:"key"`
+	CreatedAt         *time.Time `json:"created_at"`
+	ExpiresAt         *time.Time `json:"expires_at"`
+	UsageType         string     `json:"usage_type"`
+	Fingerprint       string     `json:"fingerprint"`
+	FingerprintSHA256 string     `json:"fingerprint_sha256"`
+
+	userID    int
+	deployKey bool
+}
+
+// sshKeyWithUser is a key with its owner, see https://docs.gitlab.com/ee/api/keys.html.
+type sshKeyWithUser struct {
+	sshKey
+	User *gitlab.User `json:"user"`
+}
+
+// deployKeyProject enables a deploy key in a project, whether the key may push is set per project.
+type deployKeyProject struct {
+	keyID   int
+	canPush bool
+}
+
+// projectDeployKey is a deploy key as the project deploy key endpoints return it.
+type projectDeployKey struct {
+	sshKey
+	CanPush bool `json:"can_push"`
+}
+
+// instanceDeployKey is a deploy key as the instance deploy key list returns it.
+type instanceDeployKey struct {
+	sshKey
+	ProjectsWithWriteAccess []*gitlab.DeployKeyProject `json:"projects_with_write_access"`
+}
+
+// gpgKey is a GPG key of a user, see https://docs.gitlab.com/ee/api/user_keys.html#list-your-gpg-keys.
+type gpgKey struct {
+	ID        int        `json:"id"`
+	Key       string     `json:"key"`
+	CreatedAt *time.Time `json:"created_at"`
+
+	userID      int
+	fingerprint string
+}
+
+// readSSHString reads a length-prefixed string of the SSH wire format, which mpints share.
+func readSSHString(data []byte) ([]byte, []byte, bool) {
+	if len(data) < 4 {
+		return nil, nil, false
+	}
+
+	length := binary.BigEndian.Uint32(data)
+	if uint64(len(data)-4) < uint64(length) {
+		return nil, nil, false
+	}
+
+	return data[4 : 4+length], data[4+length:], true
+}
+
+// parseSSHKey validates a public key in the authorized_keys format, e.g. "ssh-ed25519 AAAA... comment", and returns
+// its MD5 and SHA256 fingerprints as ssh-keygen shows them.
+func parseSSHKey(key string) (string, string, error) {
+	fields := strings.Fields(key)
+	if len(fields) < 2 {
+		return "", "", errInvalidKey
+	}
+
+	if !slices.Contains(sshKeyTypes, fields[0]) {
+		return "", "", newAPIError(http.StatusBadRequest, map[string][]string{"key": {"type is forbidden. Must be DSA, ECDSA, ED25519, ECDSA_SK, ED25519_SK, or RSA"}})
+	}
+
+	blob, err := base64.StdEncoding.DecodeString(fields[1])
+	if err != nil {
+		return "", "", errInvalidKey
+	}
+
+	keyType, rest, ok := readSSHString(blob)
+	if !ok || string(keyType) != fields[0] {
+		return "", "", errInvalidKey
+	}
+
+	var keyFields [][]byte
+
+	for len(rest) > 0 {
+		var field []byte
+
+		field, rest, ok = readSSHString(rest)
+		if !ok {
+			return "", "", errInvalidKey
+		}
+
+		keyFields = append(keyFields, field)
+	}
+
+	if len(keyFields) == 0 || (fields[0] == "ssh-ed25519" && (len(keyFields) != 1 || len(keyFields[0]) != 32)) {
+		return "", "", errInvalidKey
+	}
+
+	md5Sum := md5.Sum(blob)
+	hexPairs := make([]string, len(md5Sum))
+
+	for i, b := range md5Sum {
+		hexPairs[i] = fmt.Sprintf("%02x", b)
+	}
+
+	sha256Sum := sha256.Sum256(blob)
+
+	return strings.Join(hexPairs, ":"), "SHA256:" + base64.RawStdEncoding.EncodeToString(sha256Sum[:]), nil
+}
+
+// newSSHKey validates the title, key and expiry date of a new key. Keys are unique across users and deploy keys.
+func (mock *GitlabMock) newSSHKey(title *string, key *string, expiresAt *gitlab.ISOTime) (*sshKey, error) {
+	if title == nil || *title == "" {
+		return nil, newAPIError(http.StatusBadRequest, "title is missing")
+	}
+
+	if key == nil || *key == "" {
+		return nil, newAPIError(http.StatusBadRequest, "key is missing")
+	}
+
+	fingerprint, fingerprintSHA256, err := parseSSHKey(*key)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := mock.validateTokenExpiry(expiresAt); err != nil {
+		return nil, err
+	}
+
+	createdAt := mock.now()
+
+	newKey := &sshKey{
+		Title:             *title,
+		Key:               strings.TrimSpace(*key),
+		CreatedAt:         &createdAt,
+		UsageType:         "auth_and_signing",
+		Fingerprint:       fingerprint,
+		FingerprintSHA256: fingerprintSHA256,
+	}
+
+	if expiresAt != nil {
+		expiry := time.Time(*expiresAt)
+		newKey.ExpiresAt = &expiry
+	}
+
+	return newKey, nil
+}
+
+// addSSHKey adds an SSH key to the user.
+func (mock *GitlabMock) addSSHKey(user *gitlab.User, options *gitlab.AddSSHKeyOptions) (*sshKey, error) {
+	key, err := mock.newSSHKey(options.Title, options.Key, options.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if mock.sshKeyByFingerprint(key.FingerprintSHA256) != nil {
+		return nil, errFingerprintTaken
+	}
+
+	key.ID = int(mock.sshKeyIds.Add(1))
+	key.userID = user.ID
+
+	mock.sshKeys = append(mock.sshKeys, key)
+
+	return key, nil
+}
+
+// userSSHKeys returns the SSH keys of the user, deploy keys the user added are not included.
+func (mock *GitlabMock) userSSHKeys(userID int) []*sshKey {
+	keys := []*sshKey{}
+
+	for _, key := range mock.sshKeys {
+		if key.userID == userID && !key.deployKey {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+func (mock *GitlabMock) getSSHKey(keyID int) (*sshKey, error) {
+	for _, key := range mock.sshKeys {
+		if key.ID == keyID {
+			return key, nil
+		}
+	}
+
+	return nil, newAPIError(http.StatusNotFound, "404 Key Not Found")
+}
+
+func (mock *GitlabMock) getUserSSHKey(userID int, keyID int) (*sshKey, error) {
+	key, err := mock.getSSHKey(keyID)
+	if err != nil || key.userID != userID || key.deployKey {
+		return nil, newAPIError(http.StatusNotFound, "404 Key Not Found")
+	}
+
+	return key, nil
+}
+
+// sshKeyByFingerprint returns the key with the MD5 fingerprint, e.g. ba:81:59:..., or the SHA256 fingerprint with
+// its SHA256: prefix. It returns nil if no key matches.
+func (mock *GitlabMock) sshKeyByFingerprint(fingerprint string) *sshKey {
+	fingerprint = strings.TrimPrefix(fingerprint, "MD5:")
+
+	for _, key := range mock.sshKeys {
+		if key.Fingerprint == fingerprint || key.FingerprintSHA256 == fingerprint {
+			return key
+		}
+	}
+
+	return nil
+}
+
+func (mock *GitlabMock) removeSSHKey(key *sshKey) {
+	mock.sshKeys = slices.DeleteFunc(mock.sshKeys, func(other *sshKey) bool {
+		return other.ID == key.ID
+	})
+}
+
+// sshKeyWithUser returns the key with the user who owns it.
+func (mock *GitlabMock) sshKeyWithUser(key *sshKey) *sshKeyWithUser {
+	user, _ := mock.getUser(key.userID)
+
+	return &sshKeyWithUser{sshKey: *key, User: user}
+}
+
+// addDeployKey adds a deploy key to the project. A deploy key that exists already is enabled in the project
+// instead, as long as the user may use it.
+func (mock *GitlabMock) addDeployKey(project *gitlab.Project, options *gitlab.AddDeployKeyOptions, user *gitlab.User) (*projectDeployKey, error) {
+	key, err := mock.newSSHKey(options.Title, options.Key, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if existingKey := mock.sshKeyByFingerprint(key.FingerprintSHA256); existingKey != nil {
+		if !existingKey.deployKey || !mock.canUseDeployKey(existingKey, user) {
+			return nil, errFingerprintTaken
+		}
+
+		if _, err := mock.getProjectDeployKey(project, existingKey.ID); err == nil {
+			return nil, errFingerprintTaken
+		}
+
+		key = existingKey
+	} else {
+		key.ID = int(mock.sshKeyIds.Add(1))
+		key.deployKey = true
+
+		if user != nil {
+			key.userID = user.ID
+		}
+
+		mock.sshKeys = append(mock.sshKeys, key)
+	}
+
+	deployKey := &deployKeyProject{keyID: key.ID, canPush: valueOf(options.CanPush)}
+	mock.projectDeployKeys[project.ID] = append(mock.projectDeployKeys[project.ID], deployKey)
+
+	return &projectDeployKey{sshKey: *key, CanPush: deployKey.canPush}, nil
+}
+
+// canUseDeployKey reports whether the user may enable the deploy key in other projects, which requires to have
+// added the key or to be a maintainer of a project the key is enabled in.
+func (mock *GitlabMock) canUseDeployKey(key *sshKey, user *gitlab.User) bool {
+	if mock.isAdmin(user) || (user != nil && key.userID == user.ID) {
+		return true
+	}
+
+	for projectID, deployKeys := range mock.projectDeployKeys {
+		project, projectExists := mock.projects[projectID]
+		if !projectExists || !mock.hasAccess(project, user, gitlab.MaintainerPermissions) {
+			continue
+		}
+
+		if slices.ContainsFunc(deployKeys, func(deployKey *deployKeyProject) bool { return deployKey.keyID == key.ID }) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getProjectDeployKey returns the deploy key enabled in the project.
+func (mock *GitlabMock) getProjectDeployKey(project *gitlab.Project, keyID int) (*deployKeyProject, error) {
+	for _, deployKey := range mock.projectDeployKeys[project.ID] {
+		if deployKey.keyID == keyID {
+			return deployKey, nil
+		}
+	}
+
+	return nil, newAPIError(http.StatusNotFound, "404 Deploy Key Not Found")
+}
+
+// projectDeployKey returns the deploy key of the project as the API shows it.
+func (mock *GitlabMock) projectDeployKey(deployKey *deployKeyProject) *projectDeployKey {
+	key, err := mock.getSSHKey(deployKey.keyID)
+	if err != nil {
+		return nil
+	}
+
+	return &projectDeployKey{sshKey: *key, CanPush: deployKey.canPush}
+}
+
+// enableDeployKey enables a deploy key of another project in the project, without push access.
+func (mock *GitlabMock) enableDeployKey(project *gitlab.Project, keyID int, user *gitlab.User) (*projectDeployKey, error) {
+	key, err := mock.getSSHKey(keyID)
+	if err != nil || !key.deployKey || !mock.canUseDeployKey(key, user) {
+		return nil, newAPIError(http.StatusNotFound, "404 Deploy Key Not Found")
+	}
+
+	deployKey, err := mock.getProjectDeployKey(project, keyID)
+	if err != nil {
+		deployKey = &deployKeyProject{keyID: keyID}
+		mock.projectDeployKeys[project.ID] = append(mock.projectDeployKeys[project.ID], deployKey)
+	}
+
+	return mock.projectDeployKey(deployKey), nil
+}
+
+// updateDeployKey changes the title of the key, which all projects share, and whether it may push to the project.
+func (mock *GitlabMock) updateDeployKey(deployKey *deployKeyProject, options *gitlab.UpdateDeployKeyOptions) (*projectDeployKey, error) {
+	key, err := mock.getSSHKey(deployKey.keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.Title != nil {
+		if *options.Title == "" {
+			return nil, newAPIError(http.StatusBadRequest, map[string][]string{"title": {"can't be blank"}})
+		}
+
+		key.Title = *options.Title
+	}
+
+	if options.CanPush != nil {
+		deployKey.canPush = *options.CanPush
+	}
+
+	return mock.projectDeployKey(deployKey), nil
+}
+
+// removeDeployKey disables the deploy key in the project, keys that are not enabled in any project are deleted.
+func (mock *GitlabMock) removeDeployKey(project *gitlab.Project, deployKey *deployKeyProject) {
+	mock.projectDeployKeys[project.ID] = slices.DeleteFunc(mock.projectDeployKeys[project.ID], func(other *deployKeyProject) bool {
+		return other == deployKey
+	})
+
+	for _, deployKeys := range mock.projectDeployKeys {
+		if slices.ContainsFunc(deployKeys, func(other *deployKeyProject) bool { return other.keyID == deployKey.keyID }) {
+			return
+		}
+	}
+
+	if key, err := mock.getSSHKey(deployKey.keyID); err == nil {
+		mock.removeSSHKey(key)
+	}
+}
+
+// instanceDeployKeys returns all deploy keys with the projects they may push to.
+func (mock *GitlabMock) instanceDeployKeys() []*instanceDeployKey {
+	deployKeys := []*instanceDeployKey{}
+
+	for _, key := range mock.sshKeys {
+		if !key.deployKey {
+			continue
+		}
+
+		deployKey := &instanceDeployKey{sshKey: *key, ProjectsWithWriteAccess: []*gitlab.DeployKeyProject{}}
+
+		for projectID, projectKeys := range mock.projectDeployKeys {
+			project, projectExists := mock.projects[projectID]
+			if !projectExists {
+				continue
+			}
+
+			for _, projectKey := range projectKeys {
+				if projectKey.keyID == key.ID && projectKey.canPush {
+					deployKey.ProjectsWithWriteAccess = append(deployKey.ProjectsWithWriteAccess, &gitlab.DeployKeyProject{
+						ID:                project.ID,
+						Name:              project.Name,
+						NameWithNamespace: project.NameWithNamespace,
+						Path:              project.Path,
+						PathWithNamespace: project.PathWithNamespace,
+						CreatedAt:         project.CreatedAt,
+					})
+				}
+			}
+		}
+
+		slices.SortFunc(deployKey.ProjectsWithWriteAccess, func(a, b *gitlab.DeployKeyProject) int {
+			return a.ID - b.ID
+		})
+
+		deployKeys = append(deployKeys, deployKey)
+	}
+
+	return deployKeys
+}
+
+// parseGPGKey validates an ASCII armored OpenPGP public key and returns the fingerprint of its primary key. Only
+// version 4 keys, which all current GnuPG versions create, are supported.
+func parseGPGKey(key string) (string, error) {
+	begin := strings.Index(key, gpgPublicKeyBegin)
+	end := strings.Index(key, gpgPublicKeyEnd)
+
+	if begin < 0 || end < begin {
+		return "", errInvalidKey
+	}
+
+	// The armor headers, e.g. "Version: GnuPG", end with an empty line and the checksum starts with =.
+	var body strings.Builder
+
+	lines := strings.Split(key[begin+len(gpgPublicKeyBegin):end], "\n")
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.Contains(line, ": ") || strings.HasPrefix(line, "=") {
+			continue
+		}
+
+		body.WriteString(line)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(body.String())
+	if err != nil || len(data) < 2 || data[0]&0x80 == 0 {
+		return "", errInvalidKey
+	}
+
+	var (
+		tag    byte
+		packet []byte
+	)
+
+	if data[0]&0x40 != 0 {
+		tag = data[0] & 0x3f
+		packet = newFormatPacketBody(data[1:])
+	} else {
+		tag = (data[0] >> 2) & 0x0f
+		packet = oldFormatPacketBody(data[0]&0x03, data[1:])
+	}
+
+	// Tag 6 is a public key packet, see https://www.rfc-editor.org/rfc/rfc4880#section-5.5.1.1.
+	if tag != 6 || len(packet) < 6 || packet[0] != 4 {
+		return "", errInvalidKey
+	}
+
+	var fingerprintData bytes.Buffer
+
+	fingerprintData.WriteByte(0x99)
+	_ = binary.Write(&fingerprintData, binary.BigEndian, uint16(len(packet)))
+	fingerprintData.Write(packet)
+
+	return fmt.Sprintf("%X", sha1.Sum(fingerprintData.Bytes())), nil
+}
+
+// newFormatPacketBody returns the body of an OpenPGP packet with a new format length, nil if it is truncated.
+func newFormatPacketBody(data []byte) []byte {
+	var length, offset int
+
+	switch {
+	case len(data) >= 1 && data[0] < 192:
+		length, offset = int(data[0]), 1
+	case len(data) >= 2 && data[0] < 224:
+		length, offset = (int(data[0])-192)<<8+int(data[1])+192, 2
+	case len(data) >= 5 && data[0] == 255:
+		length, offset = int(binary.BigEndian.Uint32(data[1:5])), 5
+	default:
+		return nil
+	}
+
+	if len(data)-offset < length {
+		return nil
+	}
+
+	return data[offset : offset+length]
+}
+
+// oldFormatPacketBody returns the body of an OpenPGP packet with an old format length, nil if it is truncated or
+// has an indeterminate length.
+func oldFormatPacketBody(lengthType byte, data []byte) []byte {
+	var length, offset int
+
+	switch {
+	case lengthType == 0 && len(data) >= 1:
+		length, offset = int(data[0]), 1
+	case lengthType == 1 && len(data) >= 2:
+		length, offset = int(binary.BigEndian.Uint16(data)), 2
+	case lengthType == 2 && len(data) >= 4:
+		length, offset = int(binary.BigEndian.Uint32(data)), 4
+	default:
+		return nil
+	}
+
+	if len(data)-offset < length {
+		return nil
+	}
+
+	return data[offset : offset+length]
+}
+
+// addGPGKey adds a GPG key to the user, keys are unique across users.
+func (mock *GitlabMock) addGPGKey(user *gitlab.User, options *gitlab.AddGPGKeyOptions) (*gpgKey, error) {
+	if options.Key == nil || *options.Key == "" {
+		return nil, newAPIError(http.StatusBadRequest, "key is missing")
+	}
+
+	fingerprint, err := parseGPGKey(*options.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, other := range mock.gpgKeys {
+		if other.fingerprint == fingerprint {
+			return nil, newAPIError(http.StatusBadRequest, map[string][]string{"fingerprint": {"has already been taken"}})
+		}
+	}
+
+	createdAt := mock.now()
+
+	key := &gpgKey{
+		ID:          int(mock.gpgKeyIds.Add(1)),
+		Key:         strings.TrimSpace(*options.Key),
+		CreatedAt:   &createdAt,
+		userID:      user.ID,
+		fingerprint: fingerprint,
+	}
+
+	mock.gpgKeys = append(mock.gpgKeys, key)
+
+	return key, nil
+}
+
+func (mock *GitlabMock) userGPGKeys(userID int) []*gpgKey {
+	keys := []*gpgKey{}
+
+	for _, key := range mock.gpgKeys {
+		if key.userID == userID {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+func (mock *GitlabMock) getUserGPGKey(userID int, keyID int) (*gpgKey, error) {
+	for _, key := range mock.gpgKeys {
+		if key.ID == keyID && key.userID == userID {
+			return key, nil
+		}
+	}
+
+	return nil, newAPIError(http.StatusNotFound, "404 GPG Key Not Found")
+}
+
+func (mock *GitlabMock) removeGPGKey(key *gpgKey) {
+	mock.gpgKeys = slices.DeleteFunc(mock.gpgKeys, func(other *gpgKey) bool {
+		return other.ID == key.ID
+	})
+}
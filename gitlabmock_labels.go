@@ -0,0 +1,417 @@
+package gitlabapimock
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+var labelColorPattern = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// labelScope returns the scope of a scoped label, e.g. "priority" for "priority::high", or an empty
+// string for unscoped labels. Nested scopes like "a::b::c" have the scope "a::b", as in GitLab.
+func labelScope(name string) string {
+	idx := strings.LastIndex(name, "::")
+	if idx <= 0 {
+		return ""
+	}
+
+	return name[:idx]
+}
+
+// labelTextColor returns the text color GitLab uses for labels of the background color.
+func labelTextColor(color string) string {
+	hex := strings.TrimPrefix(color, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+
+	rgb, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return "#FFFFFF"
+	}
+
+	red, green, blue := (rgb>>16)&0xff, (rgb>>8)&0xff, rgb&0xff
+	if red*299+green*587+blue*114 > 128*1000 {
+		return "#333333"
+	}
+
+	return "#FFFFFF"
+}
+
+// findLabel returns the label with the ID or, if idOrName is not numeric, with the name.
+func findLabel(labels []*gitlab.Label, idOrName string) *gitlab.Label {
+	labelID, err := strconv.Atoi(idOrName)
+
+	for _, label := range labels {
+		if (err == nil && label.ID == labelID) || (err != nil && label.Name == idOrName) {
+			return label
+		}
+	}
+
+	return nil
+}
+
+// availableLabels returns the labels of the project together with the labels of its group and the
+// ancestors of the group.
+func (mock *GitlabMock) availableLabels(project *gitlab.Project) []*gitlab.Label {
+	labels := slices.Clone(mock.projectLabels[project.ID])
+
+	if project.Namespace != nil {
+		group, err := mock.getGroup(project.Namespace.ID)
+		if err == nil {
+			labels = append(labels, mock.availableGroupLabels(group)...)
+		}
+	}
+
+	return labels
+}
+
+// availableGroupLabels returns the labels of the group and its ancestors.
+func (mock *GitlabMock) availableGroupLabels(group *gitlab.Group) []*gitlab.Label {
+	labels := []*gitlab.Label{}
+
+	for _, ancestor := range mock.groupAncestors(group) {
+		labels = append(labels, mock.groupLabels[ancestor.ID]...)
+	}
+
+	return labels
+}
+
+// groupProjects returns the projects of the group and its subgroups.
+func (mock *GitlabMock) groupProjects(group *gitlab.Group) []*gitlab.Project {
+	projects := []*gitlab.Project{}
+
+	for _, project := range mock.projects {
+		if project.Namespace == nil {
+			continue
+		}
+
+		namespace, err := mock.getGroup(project.Namespace.ID)
+		if err != nil {
+			continue
+		}
+
+		if slices.Contains(mock.groupAncestors(namespace), group) {
+			projects = append(projects, project)
+		}
+	}
+
+	sort.Slice(projects, func(i, j int) bool {
+		return projects[i].ID < projects[j].ID
+	})
+
+	return projects
+}
+
+// labelProjects returns the projects the labels defined in the project or group are available in.
+func (mock *GitlabMock) labelProjects(project *gitlab.Project, group *gitlab.Group) []*gitlab.Project {
+	if project != nil {
+		return []*gitlab.Project{project}
+	}
+
+	return mock.groupProjects(group)
+}
+
+func (mock *GitlabMock) newLabel(options *gitlab.CreateLabelOptions, existingLabels []*gitlab.Label, isProjectLabel bool) (*gitlab.Label, error) {
+	if options.Name == nil || strings.TrimSpace(*options.Name) == "" {
+		return nil, newAPIError(http.StatusBadRequest, "name is missing")
+	}
+
+	if options.Color == nil {
+		return nil, newAPIError(http.StatusBadRequest, "color is missing")
+	}
+
+	if !labelColorPattern.MatchString(*options.Color) {
+		return nil, newAPIError(http.StatusBadRequest, map[string][]string{"color": {"must be a valid color code"}})
+	}
+
+	name := strings.TrimSpace(*options.Name)
+
+	if findLabel(existingLabels, name) != nil {
+		return nil, newAPIError(http.StatusConflict, "Label already exists")
+	}
+
+	return &gitlab.Label{
+		ID:             int(mock.labelIds.Add(1)),
+		Name:           name,
+		Color:          *options.Color,
+		TextColor:      labelTextColor(*options.Color),
+		Description:    valueOf(options.Description),
+		Priority:       valueOf(options.Priority),
+		IsProjectLabel: isProjectLabel,
+	}, nil
+}
+
+// AddProjectLabel defines a label in the project. The name must not be taken by a label of the
+// project or of one of its groups.
+func (mock *GitlabMock) AddProjectLabel(project *gitlab.Project, options *gitlab.CreateLabelOptions) (*gitlab.Label, error) {
+	label, err := mock.newLabel(options, mock.availableLabels(project), true)
+	if err != nil {
+		return nil, err
+	}
+
+	mock.projectLabels[project.ID] = append(mock.projectLabels[project.ID], label)
+
+	return label, nil
+}
+
+// AddGroupLabel defines a label in the group, which is available in all its projects and subgroups.
+func (mock *GitlabMock) AddGroupLabel(group *gitlab.Group, options *gitlab.CreateLabelOptions) (*gitlab.Label, error) {
+	label, err := mock.newLabel(options, mock.availableGroupLabels(group), false)
+	if err != nil {
+		return nil, err
+	}
+
+	mock.groupLabels[group.ID] = append(mock.groupLabels[group.ID], label)
+
+	return label, nil
+}
+
+func (mock *GitlabMock) getProjectLabel(project *gitlab.Project, idOrName string) (*gitlab.Label, error) {
+	label := findLabel(mock.availableLabels(project), idOrName)
+	if label == nil {
+		return nil, newAPIError(http.StatusNotFound, "404 Label Not Found")
+	}
+
+	return label, nil
+}
+
+func (mock *GitlabMock) getGroupLabel(group *gitlab.Group, idOrName string) (*gitlab.Label, error) {
+	label := findLabel(mock.availableGroupLabels(group), idOrName)
+	if label == nil {
+		return nil, newAPIError(http.StatusNotFound, "404 Label Not Found")
+	}
+
+	return label, nil
+}
+
+// updateLabel edits the label, which is defined in the project or the group. Renamed labels are
+// renamed on the issues and merge requests they are set on.
+func (mock *GitlabMock) updateLabel(project *gitlab.Project, group *gitlab.Group, label *gitlab.Label, options *gitlab.UpdateLabelOptions) (*gitlab.Label, error) {
+	if options.NewName == nil && options.Color == nil && options.Description == nil && options.Priority == nil {
+		return nil, newAPIError(http.StatusBadRequest, "new_name, color, description, priority are missing, at least one parameter must be provided")
+	}
+
+	if options.Color != nil && !labelColorPattern.MatchString(*options.Color) {
+		return nil, newAPIError(http.StatusBadRequest, map[string][]string{"color": {"must be a valid color code"}})
+	}
+
+	if options.NewName != nil && *options.NewName != label.Name {
+		var existingLabels []*gitlab.Label
+		if project != nil {
+			existingLabels = mock.availableLabels(project)
+		} else {
+			existingLabels = mock.availableGroupLabels(group)
+		}
+
+		if findLabel(existingLabels, *options.NewName) != nil {
+			return nil, newAPIError(http.StatusConflict, "Label already exists")
+		}
+
+		mock.replaceLabel(mock.labelProjects(project, group), label.Name, *options.NewName)
+		label.Name = *options.NewName
+	}
+
+	if options.Color != nil {
+		label.Color = *options.Color
+		label.TextColor = labelTextColor(*options.Color)
+	}
+
+	if options.Description != nil {
+		label.Description = *options.Description
+	}
+
+	if options.Priority != nil {
+		label.Priority = *options.Priority
+	}
+
+	return label, nil
+}
+
+// deleteLabel removes the label, which is defined in the project or the group, and unsets it on all
+// issues and merge requests.
+func (mock *GitlabMock) deleteLabel(project *gitlab.Project, group *gitlab.Group, label *gitlab.Label) error {
+	var labels map[int][]*gitlab.Label
+	var ownerID int
+
+	if project != nil {
+		labels, ownerID = mock.projectLabels, project.ID
+	} else {
+		labels, ownerID = mock.groupLabels, group.ID
+	}
+
+	idx := slices.Index(labels[ownerID], label)
+	if idx < 0 {
+		// The label is inherited from a group, it can't be deleted in the project.
+		return newAPIError(http.StatusNotFound, "404 Label Not Found")
+	}
+
+	labels[ownerID] = slices.Delete(labels[ownerID], idx, idx+1)
+	delete(mock.labelSubscriptions, label.ID)
+
+	mock.replaceLabel(mock.labelProjects(project, group), label.Name, "")
+
+	return nil
+}
+
+// replaceLabel renames the label on the issues and merge requests of the projects or removes it if the
+// new name is empty.
+func (mock *GitlabMock) replaceLabel(projects []*gitlab.Project, name string, newName string) {
+	replace := func(labels gitlab.Labels) gitlab.Labels {
+		idx := slices.Index(labels, name)
+		if idx < 0 {
+			return labels
+		}
+
+		if newName == "" {
+			return slices.Delete(labels, idx, idx+1)
+		}
+
+		labels[idx] = newName
+		return labels
+	}
+
+	for _, project := range projects {
+		for _, issue := range mock.issues[project.ID] {
+			issue.Labels = replace(issue.Labels)
+		}
+
+		for _, mergeRequest := range mock.mergeRequests[project.ID] {
+			mergeRequest.Labels = replace(mergeRequest.Labels)
+		}
+	}
+}
+
+// subscribeToLabel subscribes the user to the label and reports whether the subscription changed.
+func (mock *GitlabMock) subscribeToLabel(label *gitlab.Label, user *gitlab.User, subscribed bool) bool {
+	subscribers := mock.labelSubscriptions[label.ID]
+
+	if slices.Contains(subscribers, user.ID) == subscribed {
+		return false
+	}
+
+	if subscribed {
+		mock.labelSubscriptions[label.ID] = append(subscribers, user.ID)
+	} else {
+		mock.labelSubscriptions[label.ID] = slices.DeleteFunc(subscribers, func(userID int) bool {
+			return userID == user.ID
+		})
+	}
+
+	return true
+}
+
+// labelWithCounts returns a copy of the label with the subscription of the user and the counts of the
+// issues and merge requests of the projects.
+func (mock *GitlabMock) labelWithCounts(label *gitlab.Label, projects []*gitlab.Project, user *gitlab.User) *gitlab.Label {
+	labelCopy := *label
+
+	if user != nil {
+		labelCopy.Subscribed = slices.Contains(mock.labelSubscriptions[label.ID], user.ID)
+	}
+
+	for _, project := range projects {
+		for _, issue := range mock.issues[project.ID] {
+			if slices.Contains(issue.Labels, label.Name) {
+				if issue.State == issueOpened {
+					labelCopy.OpenIssuesCount++
+				} else {
+					labelCopy.ClosedIssuesCount++
+				}
+			}
+		}
+
+		for _, mergeRequest := range mock.mergeRequests[project.ID] {
+			if mergeRequest.State == mergeRequestOpened && slices.Contains(mergeRequest.Labels, label.Name) {
+				labelCopy.OpenMergeRequestsCount++
+			}
+		}
+	}
+
+	return &labelCopy
+}
+
+// splitLabels splits the labels, which may be given as comma separated list.
+func splitLabels(labels *gitlab.LabelOptions) gitlab.Labels {
+	result := gitlab.Labels{}
+
+	for _, label := range *labels {
+		for _, name := range strings.Split(label, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				result = append(result, name)
+			}
+		}
+	}
+
+	return result
+}
+
+// applyLabelOptions returns the labels after replacing, adding and removing labels as requested by the
+// labels, add_labels and remove_labels parameters of issues and merge requests.
+func applyLabelOptions(current gitlab.Labels, labels *gitlab.LabelOptions, addLabels *gitlab.LabelOptions, removeLabels *gitlab.LabelOptions) gitlab.Labels {
+	result := slices.Clone(current)
+
+	if labels != nil {
+		result = splitLabels(labels)
+	}
+
+	if addLabels != nil {
+		for _, label := range splitLabels(addLabels) {
+			if !slices.Contains(result, label) {
+				result = append(result, label)
+			}
+		}
+	}
+
+	if removeLabels != nil {
+		removedLabels := splitLabels(removeLabels)
+		result = slices.DeleteFunc(result, func(label string) bool {
+			return slices.Contains(removedLabels, label)
+		})
+	}
+
+	return result
+}
+
+// normalizeLabels validates the labels of an issue or merge request in the project and keeps only the
+// last label of every scope, as scoped labels are mutually exclusive. As long as neither the project
+// nor its groups define labels, any label is accepted.
+func (mock *GitlabMock) normalizeLabels(project *gitlab.Project, labels gitlab.Labels) (gitlab.Labels, error) {
+	availableLabels := mock.availableLabels(project)
+
+	normalizedLabels := gitlab.Labels{}
+
+	for idx, name := range labels {
+		name = strings.TrimSpace(name)
+		if name == "" || slices.Contains(normalizedLabels, name) {
+			continue
+		}
+
+		labelExists := slices.ContainsFunc(availableLabels, func(label *gitlab.Label) bool {
+			return label.Name == name
+		})
+
+		if len(availableLabels) > 0 && !labelExists {
+			return nil, newAPIError(http.StatusBadRequest, map[string][]string{"labels": {fmt.Sprintf("Label %q does not exist", name)}})
+		}
+
+		scope := labelScope(name)
+
+		overridden := scope != "" && slices.ContainsFunc(labels[idx+1:], func(other string) bool {
+			return labelScope(strings.TrimSpace(other)) == scope
+		})
+
+		if !overridden {
+			normalizedLabels = append(normalizedLabels, name)
+		}
+	}
+
+	return normalizedLabels, nil
+}
@@ -0,0 +1,780 @@
+package gitlabapimock
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+const (
+	mergeRequestOpened = "opened"
+	mergeRequestClosed = "closed"
+	mergeRequestMerged = "merged"
+)
+
+var draftTitlePattern = regexp.MustCompile(`(?i)^\s*(\[draft\]|\(draft\)|draft:|draft\s-\s|draft\s|\[wip\]|wip:)`)
+
+func toBasicUser(user *gitlab.User) *gitlab.BasicUser {
+	if user == nil {
+		return nil
+	}
+
+	return &gitlab.BasicUser{
+		ID:        user.ID,
+		Username:  user.Username,
+		Name:      user.Name,
+		State:     user.State,
+		CreatedAt: user.CreatedAt,
+		AvatarURL: user.AvatarURL,
+		WebURL:    user.WebURL,
+	}
+}
+
+// toBasicUsers resolves the user IDs. Unknown users are skipped, as GitLab does.
+func (mock *GitlabMock) toBasicUsers(userIDs []int) []*gitlab.BasicUser {
+	users := []*gitlab.BasicUser{}
+
+	for _, userID := range userIDs {
+		user, err := mock.getUser(userID)
+		if err == nil {
+			users = append(users, toBasicUser(user))
+		}
+	}
+
+	return users
+}
+
+// nextIID returns the next internal ID of the kind of resource, e.g. "merge_request", in the project.
+// IIDs are sequential per project, unlike the global IDs.
+func (mock *GitlabMock) nextIID(kind string, projectID int) int {
+	key := fmt.Sprintf("%s/%d", kind, projectID)
+	mock.iids[key]++
+
+	return mock.iids[key]
+}
+
+// AddMergeRequest opens a merge request from the source branch into the target branch of the project.
+func (mock *GitlabMock) AddMergeRequest(project *gitlab.Project, options *gitlab.CreateMergeRequestOptions, author *gitlab.User) (*gitlab.MergeRequest, error) {
+	if options.Title == nil || options.SourceBranch == nil || options.TargetBranch == nil {
+		return nil, newAPIError(http.StatusBadRequest, "title, source_branch and target_branch are required")
+	}
+
+	if !mock.hasAccess(project, author, gitlab.DeveloperPermissions) {
+		return nil, newAPIError(http.StatusForbidden, "403 Forbidden")
+	}
+
+	repo, err := mock.getRepository(project)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, branch := range []string{*options.SourceBranch, *options.TargetBranch} {
+		_, err = repo.resolve("refs/heads/" + branch)
+		if err != nil {
+			return nil, newAPIError(http.StatusBadRequest, []string{fmt.Sprintf("Validate branches Branch %q does not exist", branch)})
+		}
+	}
+
+	if *options.SourceBranch == *options.TargetBranch {
+		return nil, newAPIError(http.StatusBadRequest, []string{"Validate branches You can't use same project/branch for source and target"})
+	}
+
+	for _, mergeRequest := range mock.mergeRequests[project.ID] {
+		if mergeRequest.State == mergeRequestOpened && mergeRequest.SourceBranch == *options.SourceBranch && mergeRequest.TargetBranch == *options.TargetBranch {
+			return nil, newAPIError(http.StatusConflict, []string{fmt.Sprintf("Another open merge request already exists for this source branch: !%d", mergeRequest.IID)})
+		}
+	}
+
+	labels, err := mock.normalizeLabels(project, applyLabelOptions(nil, options.Labels, nil, nil))
+	if err != nil {
+		return nil, err
+	}
+
+	createdAt := mock.now()
+	iid := mock.nextIID("merge_request", project.ID)
+
+	mergeRequest := &gitlab.MergeRequest{
+		ID:                      int(mock.mergeRequestIds.Add(1)),
+		IID:                     iid,
+		ProjectID:               project.ID,
+		SourceProjectID:         project.ID,
+		TargetProjectID:         project.ID,
+		Title:                   *options.Title,
+		Description:             valueOf(options.Description),
+		SourceBranch:            *options.SourceBranch,
+		TargetBranch:            *options.TargetBranch,
+		State:                   mergeRequestOpened,
+		CreatedAt:               &createdAt,
+		UpdatedAt:               &createdAt,
+		Author:                  toBasicUser(author),
+		Assignees:               []*gitlab.BasicUser{},
+		Reviewers:               []*gitlab.BasicUser{},
+		Labels:                  labels,
+		Squash:                  valueOf(options.Squash),
+		ForceRemoveSourceBranch: valueOf(options.RemoveSourceBranch),
+		AllowCollaboration:      valueOf(options.AllowCollaboration),
+		ApprovalsBeforeMerge:    valueOf(options.ApprovalsBeforeMerge),
+		Reference:               fmt.Sprintf("!%d", iid),
+		References: &gitlab.IssueReferences{
+			Short:    fmt.Sprintf("!%d", iid),
+			Relative: fmt.Sprintf("!%d", iid),
+			Full:     fmt.Sprintf("%s!%d", project.PathWithNamespace, iid),
+		},
+	}
+
+	mergeRequest.WebURL = fmt.Sprintf("/%s/-/merge_requests/%d", project.PathWithNamespace, iid)
+
+	if options.AssigneeIDs != nil {
+		mergeRequest.Assignees = mock.toBasicUsers(*options.AssigneeIDs)
+	} else if options.AssigneeID != nil {
+		mergeRequest.Assignees = mock.toBasicUsers([]int{*options.AssigneeID})
+	}
+
+	if options.ReviewerIDs != nil {
+		mergeRequest.Reviewers = mock.toBasicUsers(*options.ReviewerIDs)
+	}
+
+	mergeRequest.Milestone = mock.resolveMilestone(project, options.MilestoneID)
+
+	mock.mergeRequests[project.ID] = append(mock.mergeRequests[project.ID], mergeRequest)
+	mock.recordMilestoneChange(noteableMergeRequest, mergeRequest.ID, project.ID, mergeRequest.IID, 0, nil, mergeRequest.Milestone, author)
+	mock.copyProjectApprovalRules(project, mergeRequest)
+
+	err = mock.refreshMergeRequest(project, mergeRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	mock.fireMergeRequestHooks(project, mergeRequest, nil, "open", "", author)
+
+	return mergeRequest, nil
+}
+
+func (mock *GitlabMock) getMergeRequest(project *gitlab.Project, iid int) (*gitlab.MergeRequest, error) {
+	for _, mergeRequest := range mock.mergeRequests[project.ID] {
+		if mergeRequest.IID == iid {
+			err := mock.refreshMergeRequest(project, mergeRequest)
+			if err != nil {
+				return nil, err
+			}
+
+			return mergeRequest, nil
+		}
+	}
+
+	return nil, newAPIError(http.StatusNotFound, "404 Not found")
+}
+
+// getMergeRequests returns the merge requests of the project with a refreshed merge status.
+func (mock *GitlabMock) getMergeRequests(project *gitlab.Project) ([]*gitlab.MergeRequest, error) {
+	mergeRequests := []*gitlab.MergeRequest{}
+
+	for _, mergeRequest := range mock.mergeRequests[project.ID] {
+		err := mock.refreshMergeRequest(project, mergeRequest)
+		if err != nil {
+			return nil, err
+		}
+
+		mergeRequests = append(mergeRequests, mergeRequest)
+	}
+
+	return mergeRequests, nil
+}
+
+// refreshMergeRequest recomputes the fields that depend on the repository, which is what GitLab's
+// asynchronous mergeability check does. Merged and closed merge requests keep their last state.
+func (mock *GitlabMock) refreshMergeRequest(project *gitlab.Project, mergeRequest *gitlab.MergeRequest) error {
+	mergeRequest.Draft = draftTitlePattern.MatchString(mergeRequest.Title)
+	mergeRequest.WorkInProgress = mergeRequest.Draft
+	mergeRequest.UserNotesCount = mock.userNotesCount(mergeRequestNoteable(mergeRequest))
+	mergeRequest.BlockingDiscussionsResolved = mock.unresolvedDiscussions(mergeRequestNoteable(mergeRequest)) == 0
+
+	if mergeRequest.State != mergeRequestOpened {
+		mergeRequest.DetailedMergeStatus = "not_open"
+		return nil
+	}
+
+	repo, err := mock.getRepository(project)
+	if err != nil {
+		return err
+	}
+
+	sourceSHA, sourceErr := repo.resolve("refs/heads/" + mergeRequest.SourceBranch)
+	targetSHA, targetErr := repo.resolve("refs/heads/" + mergeRequest.TargetBranch)
+	if sourceErr != nil || targetErr != nil {
+		mergeRequest.MergeStatus = "cannot_be_merged"
+		mergeRequest.DetailedMergeStatus = "broken_status"
+		return nil
+	}
+
+	baseSHA, err := repo.mergeBase(targetSHA, sourceSHA)
+	if err != nil {
+		mergeRequest.MergeStatus = "cannot_be_merged"
+		mergeRequest.DetailedMergeStatus = "broken_status"
+		return nil
+	}
+
+	mergeRequest.SHA = sourceSHA
+	mergeRequest.DiffRefs.BaseSha = baseSHA
+	mergeRequest.DiffRefs.HeadSha = sourceSHA
+	mergeRequest.DiffRefs.StartSha = targetSHA
+	mergeRequest.DivergedCommitsCount = repo.countCommits(sourceSHA, targetSHA)
+
+	diffs, err := repo.diff(baseSHA, sourceSHA)
+	if err != nil {
+		return err
+	}
+
+	mergeRequest.ChangesCount = strconv.Itoa(len(diffs))
+
+	_, conflicts, err := repo.mergeTree(targetSHA, sourceSHA)
+	if err != nil {
+		return err
+	}
+
+	mergeRequest.HasConflicts = len(conflicts) > 0
+
+	mergeRequest.MergeStatus = "can_be_merged"
+	if mergeRequest.HasConflicts {
+		mergeRequest.MergeStatus = "cannot_be_merged"
+	}
+
+	switch {
+	case mergeRequest.Draft:
+		mergeRequest.DetailedMergeStatus = "draft_status"
+	case mergeRequest.HasConflicts:
+		mergeRequest.DetailedMergeStatus = "conflict"
+	case project.MergeMethod != gitlab.NoFastForwardMerge && mergeRequest.DivergedCommitsCount > 0:
+		mergeRequest.DetailedMergeStatus = "need_rebase"
+	case project.OnlyAllowMergeIfAllDiscussionsAreResolved && !mergeRequest.BlockingDiscussionsResolved:
+		mergeRequest.DetailedMergeStatus = "discussions_not_resolved"
+	case !mock.mergeRequestApproved(project, mergeRequest):
+		mergeRequest.DetailedMergeStatus = "not_approved"
+	default:
+		mergeRequest.DetailedMergeStatus = "mergeable"
+	}
+
+	return nil
+}
+
+func (mock *GitlabMock) updateMergeRequest(project *gitlab.Project, iid int, options *gitlab.UpdateMergeRequestOptions, user *gitlab.User) (*gitlab.MergeRequest, error) {
+	mergeRequest, err := mock.getMergeRequest(project, iid)
+	if err != nil {
+		return nil, err
+	}
+
+	if !mock.hasAccess(project, user, gitlab.DeveloperPermissions) {
+		return nil, newAPIError(http.StatusForbidden, "403 Forbidden")
+	}
+
+	labels, err := mock.normalizeLabels(project, applyLabelOptions(mergeRequest.Labels, options.Labels, options.AddLabels, options.RemoveLabels))
+	if err != nil {
+		return nil, err
+	}
+
+	if options.StateEvent != nil {
+		targetBranch := mergeRequest.TargetBranch
+		if options.TargetBranch != nil {
+			targetBranch = *options.TargetBranch
+		}
+
+		err = mock.validateMergeRequestStateEvent(project, mergeRequest, *options.StateEvent, targetBranch)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	before := *mergeRequest
+	titleBefore := mergeRequest.Title
+	draftBefore := mergeRequest.Draft
+	targetBranchBefore := mergeRequest.TargetBranch
+	labelsBefore := slices.Clone(mergeRequest.Labels)
+	assigneesBefore := basicUsernames(mergeRequest.Assignees)
+	reviewersBefore := basicUsernames(mergeRequest.Reviewers)
+	milestoneBefore := mergeRequest.Milestone
+
+	if options.TargetBranch != nil {
+		repo, err := mock.getRepository(project)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = repo.resolve("refs/heads/" + *options.TargetBranch)
+		if err != nil {
+			return nil, newAPIError(http.StatusBadRequest, []string{fmt.Sprintf("Validate branches Branch %q does not exist", *options.TargetBranch)})
+		}
+
+		mergeRequest.TargetBranch = *options.TargetBranch
+	}
+
+	if options.Title != nil {
+		mergeRequest.Title = *options.Title
+	}
+
+	if options.Description != nil {
+		mergeRequest.Description = *options.Description
+	}
+
+	if options.AssigneeIDs != nil {
+		mergeRequest.Assignees = mock.toBasicUsers(*options.AssigneeIDs)
+	} else if options.AssigneeID != nil {
+		mergeRequest.Assignees = mock.toBasicUsers([]int{*options.AssigneeID})
+	}
+
+	if options.ReviewerIDs != nil {
+		mergeRequest.Reviewers = mock.toBasicUsers(*options.ReviewerIDs)
+	}
+
+	mergeRequest.Labels = labels
+
+	if options.MilestoneID != nil {
+		mergeRequest.Milestone = mock.resolveMilestone(project, options.MilestoneID)
+	}
+
+	if options.Squash != nil {
+		mergeRequest.Squash = *options.Squash
+	}
+
+	if options.RemoveSourceBranch != nil {
+		mergeRequest.ForceRemoveSourceBranch = *options.RemoveSourceBranch
+	}
+
+	if options.DiscussionLocked != nil {
+		mergeRequest.DiscussionLocked = *options.DiscussionLocked
+	}
+
+	if options.AllowCollaboration != nil {
+		mergeRequest.AllowCollaboration = *options.AllowCollaboration
+	}
+
+	if options.StateEvent != nil {
+		mock.changeMergeRequestState(mergeRequest, *options.StateEvent, user)
+	}
+
+	updatedAt := mock.now()
+	mergeRequest.UpdatedAt = &updatedAt
+
+	err = mock.refreshMergeRequest(project, mergeRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	noteable := mergeRequestNoteable(mergeRequest)
+
+	if mergeRequest.Title != titleBefore {
+		mock.addSystemNote(noteable, fmt.Sprintf("changed title from **%s** to **%s**", titleBefore, mergeRequest.Title), user)
+	}
+
+	if mergeRequest.Draft != draftBefore {
+		if mergeRequest.Draft {
+			mock.addSystemNote(noteable, "marked this merge request as **draft**", user)
+		} else {
+			mock.addSystemNote(noteable, "marked this merge request as **ready**", user)
+		}
+	}
+
+	if mergeRequest.TargetBranch != targetBranchBefore {
+		mock.addSystemNote(noteable, fmt.Sprintf("changed target branch from `%s` to `%s`", targetBranchBefore, mergeRequest.TargetBranch), user)
+	}
+
+	if body := labelsSystemNote(labelsBefore, mergeRequest.Labels); body != "" {
+		mock.addSystemNote(noteable, body, user)
+	}
+
+	if body := usersSystemNote(assigneesBefore, basicUsernames(mergeRequest.Assignees), "assigned to", "unassigned"); body != "" {
+		mock.addSystemNote(noteable, body, user)
+	}
+
+	if body := usersSystemNote(reviewersBefore, basicUsernames(mergeRequest.Reviewers), "requested review from", "removed review request for"); body != "" {
+		mock.addSystemNote(noteable, body, user)
+	}
+
+	if body := milestoneSystemNote(milestoneBefore, mergeRequest.Milestone); body != "" {
+		mock.addSystemNote(noteable, body, user)
+	}
+
+	mock.recordMilestoneChange(noteableMergeRequest, mergeRequest.ID, project.ID, mergeRequest.IID, 0, milestoneBefore, mergeRequest.Milestone, user)
+
+	action := "update"
+	if mergeRequest.State != before.State {
+		action = *options.StateEvent
+	}
+
+	mock.fireMergeRequestHooks(project, mergeRequest, &before, action, "", user)
+
+	return mergeRequest, nil
+}
+
+// validateMergeRequestStateEvent checks the state event before the merge request is changed, a closed merge
+// request can't be reopened while another one is open for the same branches.
+func (mock *GitlabMock) validateMergeRequestStateEvent(project *gitlab.Project, mergeRequest *gitlab.MergeRequest, stateEvent string, targetBranch string) error {
+	switch {
+	case stateEvent != "close" && stateEvent != "reopen":
+		return newAPIError(http.StatusBadRequest, "state_event does not have a valid value")
+	case stateEvent == "reopen" && mergeRequest.State == mergeRequestClosed:
+		for _, other := range mock.mergeRequests[project.ID] {
+			if other != mergeRequest && other.State == mergeRequestOpened && other.SourceBranch == mergeRequest.SourceBranch && other.TargetBranch == targetBranch {
+				return newAPIError(http.StatusConflict, []string{fmt.Sprintf("Another open merge request already exists for this source branch: !%d", other.IID)})
+			}
+		}
+	}
+
+	return nil
+}
+
+// changeMergeRequestState closes or reopens the merge request, the state event has been validated before.
+func (mock *GitlabMock) changeMergeRequestState(mergeRequest *gitlab.MergeRequest, stateEvent string, user *gitlab.User) {
+	switch {
+	case stateEvent == "close" && mergeRequest.State == mergeRequestOpened:
+		closedAt := mock.now()
+		mergeRequest.State = mergeRequestClosed
+		mergeRequest.ClosedAt = &closedAt
+		mergeRequest.ClosedBy = toBasicUser(user)
+		mock.addSystemNote(mergeRequestNoteable(mergeRequest), "closed", user)
+	case stateEvent == "reopen" && mergeRequest.State == mergeRequestClosed:
+		mergeRequest.State = mergeRequestOpened
+		mergeRequest.ClosedAt = nil
+		mergeRequest.ClosedBy = nil
+		mock.addSystemNote(mergeRequestNoteable(mergeRequest), "reopened", user)
+	}
+}
+
+// deleteMergeRequest removes the merge request. Only admins and owners may do this.
+func (mock *GitlabMock) deleteMergeRequest(project *gitlab.Project, iid int, user *gitlab.User) error {
+	if !mock.hasAccess(project, user, gitlab.OwnerPermissions) {
+		return newAPIError(http.StatusForbidden, "403 Forbidden")
+	}
+
+	mergeRequests := mock.mergeRequests[project.ID]
+
+	for idx, mergeRequest := range mergeRequests {
+		if mergeRequest.IID == iid {
+			mock.mergeRequests[project.ID] = append(mergeRequests[:idx:idx], mergeRequests[idx+1:]...)
+			delete(mock.mergeRequestApprovals, mergeRequest.ID)
+			delete(mock.mergeRequestApprovalRules, mergeRequest.ID)
+			delete(mock.mergeRequestApprovalRulesOverwritten, mergeRequest.ID)
+			delete(mock.discussions, mergeRequestNoteable(mergeRequest).key())
+			return nil
+		}
+	}
+
+	return newAPIError(http.StatusNotFound, "404 Not found")
+}
+
+// acceptMergeRequest merges the source branch into the target branch using the merge method of the project.
+func (mock *GitlabMock) acceptMergeRequest(project *gitlab.Project, iid int, options *gitlab.AcceptMergeRequestOptions, user *gitlab.User) (*gitlab.MergeRequest, error) {
+	mergeRequest, err := mock.getMergeRequest(project, iid)
+	if err != nil {
+		return nil, err
+	}
+
+	if !mock.hasAccess(project, user, gitlab.DeveloperPermissions) {
+		return nil, newAPIError(http.StatusUnauthorized, "401 Unauthorized")
+	}
+
+	if options.Squash != nil {
+		mergeRequest.Squash = *options.Squash
+	}
+
+	err = mock.refreshMergeRequest(project, mergeRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	switch mergeRequest.DetailedMergeStatus {
+	case "mergeable":
+	case "conflict", "need_rebase":
+		return nil, newAPIError(http.StatusNotAcceptable, "Branch cannot be merged")
+	default:
+		return nil, newAPIError(http.StatusMethodNotAllowed, "405 Method Not Allowed")
+	}
+
+	if options.SHA != nil && *options.SHA != mergeRequest.SHA {
+		return nil, newAPIError(http.StatusConflict, "SHA does not match HEAD of source branch: "+mergeRequest.SHA)
+	}
+
+	repo, err := mock.getRepository(project)
+	if err != nil {
+		return nil, err
+	}
+
+	targetSHA := mergeRequest.DiffRefs.StartSha
+	sourceSHA := mergeRequest.SHA
+
+	mergeCommitMessage := fmt.Sprintf("Merge branch '%s' into '%s'\n\n%s\n\nSee merge request %s", mergeRequest.SourceBranch, mergeRequest.TargetBranch, mergeRequest.Title, mergeRequest.References.Full)
+	if options.MergeCommitMessage != nil {
+		mergeCommitMessage = *options.MergeCommitMessage
+	}
+
+	squashCommitMessage := mergeRequest.Title
+	if options.SquashCommitMessage != nil {
+		squashCommitMessage = *options.SquashCommitMessage
+	}
+
+	mergedSHA := sourceSHA
+
+	if mergeRequest.Squash {
+		sourceTree, err := repo.treeOf(sourceSHA)
+		if err != nil {
+			return nil, err
+		}
+
+		squashParent := mergeRequest.DiffRefs.BaseSha
+		if project.MergeMethod == gitlab.FastForwardMerge {
+			squashParent = targetSHA
+		}
+
+		mergedSHA, err = repo.commitTree(sourceTree, []string{squashParent}, squashCommitMessage, user)
+		if err != nil {
+			return nil, err
+		}
+
+		mergeRequest.SquashCommitSHA = mergedSHA
+	}
+
+	newTargetSHA := mergedSHA
+
+	if project.MergeMethod != gitlab.FastForwardMerge {
+		mergeTree, conflicts, err := repo.mergeTree(targetSHA, mergedSHA)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(conflicts) > 0 {
+			return nil, newAPIError(http.StatusNotAcceptable, "Branch cannot be merged")
+		}
+
+		newTargetSHA, err = repo.commitTree(mergeTree, []string{targetSHA, mergedSHA}, mergeCommitMessage, user)
+		if err != nil {
+			return nil, err
+		}
+
+		mergeRequest.MergeCommitSHA = newTargetSHA
+	}
+
+	updates := []refUpdate{}
+
+	update, err := repo.updateRef("refs/heads/"+mergeRequest.TargetBranch, newTargetSHA)
+	if err != nil {
+		return nil, err
+	}
+	updates = append(updates, *update)
+
+	shouldRemoveSourceBranch := mergeRequest.ForceRemoveSourceBranch
+	if options.ShouldRemoveSourceBranch != nil {
+		shouldRemoveSourceBranch = *options.ShouldRemoveSourceBranch
+	}
+
+	if shouldRemoveSourceBranch {
+		update, err = repo.updateRef("refs/heads/"+mergeRequest.SourceBranch, zeroSHA)
+		if err != nil {
+			return nil, err
+		}
+		updates = append(updates, *update)
+	}
+
+	mergedAt := mock.now()
+
+	mergeRequest.State = mergeRequestMerged
+	mergeRequest.MergedAt = &mergedAt
+	mergeRequest.MergedBy = toBasicUser(user)
+	mergeRequest.UpdatedAt = &mergedAt
+	mergeRequest.ShouldRemoveSourceBranch = shouldRemoveSourceBranch
+	mergeRequest.MergeStatus = "can_be_merged"
+	mergeRequest.DetailedMergeStatus = "not_open"
+
+	mock.addSystemNote(mergeRequestNoteable(mergeRequest), "merged", user)
+	mock.applyRefUpdates(project, user, updates)
+	mock.fireMergeRequestHooks(project, mergeRequest, nil, "merge", "", user)
+
+	return mergeRequest, nil
+}
+
+// rebaseMergeRequest rebases the source branch onto the target branch. Conflicts are reported in merge_error.
+func (mock *GitlabMock) rebaseMergeRequest(project *gitlab.Project, iid int, user *gitlab.User) (*gitlab.MergeRequest, error) {
+	mergeRequest, err := mock.getMergeRequest(project, iid)
+	if err != nil {
+		return nil, err
+	}
+
+	if !mock.hasAccess(project, user, gitlab.DeveloperPermissions) {
+		return nil, newAPIError(http.StatusForbidden, "403 Forbidden")
+	}
+
+	if mergeRequest.State != mergeRequestOpened {
+		return nil, newAPIError(http.StatusForbidden, "403 Forbidden")
+	}
+
+	repo, err := mock.getRepository(project)
+	if err != nil {
+		return nil, err
+	}
+
+	mergeRequest.MergeError = ""
+
+	rebasedSHA, err := repo.rebase(mergeRequest.DiffRefs.StartSha, mergeRequest.SHA, user)
+	if err != nil {
+		mergeRequest.MergeError = "Rebase failed: Rebase locally, resolve all conflicts, then push the branch."
+		return mergeRequest, nil
+	}
+
+	update, err := repo.updateRef("refs/heads/"+mergeRequest.SourceBranch, rebasedSHA)
+	if err != nil {
+		return nil, err
+	}
+
+	mock.applyRefUpdates(project, user, []refUpdate{*update})
+
+	err = mock.refreshMergeRequest(project, mergeRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	return mergeRequest, nil
+}
+
+// mergeRequestChanges returns the diff of the merge request against the merge base.
+func (mock *GitlabMock) mergeRequestChanges(project *gitlab.Project, mergeRequest *gitlab.MergeRequest) ([]*gitlab.MergeRequestDiff, error) {
+	if mergeRequest.DiffRefs.BaseSha == "" {
+		return []*gitlab.MergeRequestDiff{}, nil
+	}
+
+	repo, err := mock.getRepository(project)
+	if err != nil {
+		return nil, err
+	}
+
+	return repo.diff(mergeRequest.DiffRefs.BaseSha, mergeRequest.DiffRefs.HeadSha)
+}
+
+// mergeRequestCommits returns the commits of the merge request, newest first.
+func (mock *GitlabMock) mergeRequestCommits(project *gitlab.Project, mergeRequest *gitlab.MergeRequest) ([]*gitlab.Commit, error) {
+	if mergeRequest.DiffRefs.BaseSha == "" {
+		return []*gitlab.Commit{}, nil
+	}
+
+	repo, err := mock.getRepository(project)
+	if err != nil {
+		return nil, err
+	}
+
+	return repo.commitsBetween(mergeRequest.DiffRefs.BaseSha, mergeRequest.DiffRefs.HeadSha)
+}
+
+// approver returns the approver recorded for the user. Without authentication anonymous approvals are
+// recorded under an empty user.
+func (mock *GitlabMock) approver(user *gitlab.User) (*gitlab.BasicUser, error) {
+	if user != nil {
+		return toBasicUser(user), nil
+	}
+
+	if mock.authenticationRequired() {
+		return nil, errUnauthorized
+	}
+
+	return &gitlab.BasicUser{}, nil
+}
+
+// approveMergeRequest adds the approval of the user. The optional SHA has to match the source branch.
+func (mock *GitlabMock) approveMergeRequest(project *gitlab.Project, iid int, sha *string, user *gitlab.User) (*gitlab.MergeRequest, error) {
+	mergeRequest, err := mock.getMergeRequest(project, iid)
+	if err != nil {
+		return nil, err
+	}
+
+	approver, err := mock.approver(user)
+	if err != nil {
+		return nil, err
+	}
+
+	if !mock.hasAccess(project, user, gitlab.DeveloperPermissions) {
+		return nil, errUnauthorized
+	}
+
+	if mergeRequest.State != mergeRequestOpened {
+		return nil, newAPIError(http.StatusMethodNotAllowed, "405 Method Not Allowed")
+	}
+
+	if sha != nil && *sha != mergeRequest.SHA {
+		return nil, newAPIError(http.StatusConflict, "SHA does not match HEAD of source branch: "+mergeRequest.SHA)
+	}
+
+	for _, existingApprover := range mock.mergeRequestApprovals[mergeRequest.ID] {
+		if existingApprover.ID == approver.ID {
+			return nil, errUnauthorized
+		}
+	}
+
+	mock.mergeRequestApprovals[mergeRequest.ID] = append(mock.mergeRequestApprovals[mergeRequest.ID], approver)
+	mock.addSystemNote(mergeRequestNoteable(mergeRequest), "approved this merge request", user)
+	mock.fireMergeRequestHooks(project, mergeRequest, nil, "approved", "", user)
+
+	return mergeRequest, nil
+}
+
+// unapproveMergeRequest removes the approval of the user.
+func (mock *GitlabMock) unapproveMergeRequest(project *gitlab.Project, iid int, user *gitlab.User) (*gitlab.MergeRequest, error) {
+	mergeRequest, err := mock.getMergeRequest(project, iid)
+	if err != nil {
+		return nil, err
+	}
+
+	approver, err := mock.approver(user)
+	if err != nil {
+		return nil, err
+	}
+
+	approvers := mock.mergeRequestApprovals[mergeRequest.ID]
+
+	for idx, existingApprover := range approvers {
+		if existingApprover.ID == approver.ID {
+			mock.mergeRequestApprovals[mergeRequest.ID] = append(approvers[:idx:idx], approvers[idx+1:]...)
+			mock.addSystemNote(mergeRequestNoteable(mergeRequest), "unapproved this merge request", user)
+			mock.fireMergeRequestHooks(project, mergeRequest, nil, "unapproved", "", user)
+			return mergeRequest, nil
+		}
+	}
+
+	return nil, newAPIError(http.StatusNotFound, "404 Not found")
+}
+
+// addMergeRequestCommitNotes adds the "added 1 commit" system notes to the open merge requests whose
+// source branch has been pushed to and resets their approvals, like GitLab does by default.
+func (mock *GitlabMock) addMergeRequestCommitNotes(project *gitlab.Project, repo *repository, user *gitlab.User, updates []refUpdate) {
+	for _, update := range updates {
+		branch, isBranch := strings.CutPrefix(update.Ref, "refs/heads/")
+		if !isBranch || update.Before == zeroSHA || update.After == zeroSHA {
+			continue
+		}
+
+		for _, mergeRequest := range mock.mergeRequests[project.ID] {
+			if mergeRequest.State != mergeRequestOpened || mergeRequest.SourceBranch != branch {
+				continue
+			}
+
+			commits, err := repo.commitsBetween(update.Before, update.After)
+			if err != nil || len(commits) == 0 {
+				continue
+			}
+
+			lines := []string{}
+			for _, commit := range commits {
+				lines = append(lines, fmt.Sprintf("* %s - %s", commit.ShortID, commit.Title))
+			}
+
+			body := fmt.Sprintf("added %d %s\n\n%s", len(commits), pluralize(len(commits), "commit", "commits"), strings.Join(lines, "\n"))
+
+			mock.addSystemNote(mergeRequestNoteable(mergeRequest), body, user)
+			delete(mock.mergeRequestApprovals, mergeRequest.ID)
+
+			if mock.refreshMergeRequest(project, mergeRequest) == nil {
+				mock.fireMergeRequestHooks(project, mergeRequest, nil, "update", update.Before, user)
+			}
+		}
+	}
+}
@@ -0,0 +1,348 @@
+package gitlabapimock
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+const (
+	milestoneActive = "active"
+	milestoneClosed = "closed"
+)
+
+// milestoneEvent records that a milestone was added to or removed from an issue or merge request, see
+// https://docs.gitlab.com/ee/api/resource_milestone_events.html
+type milestoneEvent struct {
+	gitlab.MilestoneEvent
+	ProjectID int `json:"-"`
+	IID       int `json:"-"`
+}
+
+// refreshMilestone updates the fields of the milestone that depend on the current date.
+func refreshMilestone(milestone *gitlab.Milestone, now time.Time) {
+	expired := milestone.DueDate != nil && time.Time(*milestone.DueDate).AddDate(0, 0, 1).Before(now)
+	milestone.Expired = &expired
+}
+
+func validateMilestoneDates(startDate *gitlab.ISOTime, dueDate *gitlab.ISOTime) error {
+	if startDate != nil && dueDate != nil && time.Time(*dueDate).Before(time.Time(*startDate)) {
+		return newAPIError(http.StatusBadRequest, map[string][]string{"due_date": {"must be greater than start date"}})
+	}
+
+	return nil
+}
+
+// availableMilestones returns the milestones of the project together with the milestones of its group and
+// the ancestors of the group.
+func (mock *GitlabMock) availableMilestones(project *gitlab.Project) []*gitlab.Milestone {
+	milestones := slices.Clone(mock.projectMilestones[project.ID])
+
+	if project.Namespace != nil {
+		group, err := mock.getGroup(project.Namespace.ID)
+		if err == nil {
+			milestones = append(milestones, mock.availableGroupMilestones(group)...)
+		}
+	}
+
+	return milestones
+}
+
+// availableGroupMilestones returns the milestones of the group and its ancestors.
+func (mock *GitlabMock) availableGroupMilestones(group *gitlab.Group) []*gitlab.Milestone {
+	milestones := []*gitlab.Milestone{}
+
+	for _, ancestor := range mock.groupAncestors(group) {
+		milestones = append(milestones, mock.groupMilestones[ancestor.ID]...)
+	}
+
+	return milestones
+}
+
+func (mock *GitlabMock) newMilestone(options *gitlab.CreateMilestoneOptions, existingMilestones []*gitlab.Milestone) (*gitlab.Milestone, error) {
+	if options.Title == nil || *options.Title == "" {
+		return nil, newAPIError(http.StatusBadRequest, "title is missing")
+	}
+
+	for _, milestone := range existingMilestones {
+		if milestone.Title == *options.Title {
+			return nil, newAPIError(http.StatusBadRequest, "Title already being used for another group or project milestone.")
+		}
+	}
+
+	err := validateMilestoneDates(options.StartDate, options.DueDate)
+	if err != nil {
+		return nil, err
+	}
+
+	createdAt := mock.now()
+
+	milestone := &gitlab.Milestone{
+		ID:          int(mock.milestoneIds.Add(1)),
+		Title:       *options.Title,
+		Description: valueOf(options.Description),
+		StartDate:   options.StartDate,
+		DueDate:     options.DueDate,
+		State:       milestoneActive,
+		CreatedAt:   &createdAt,
+		UpdatedAt:   &createdAt,
+	}
+
+	refreshMilestone(milestone, mock.now())
+
+	return milestone, nil
+}
+
+// AddMilestone creates a milestone in the project. Its title must not be used by another milestone of
+// the project or of one of its groups.
+func (mock *GitlabMock) AddMilestone(project *gitlab.Project, options *gitlab.CreateMilestoneOptions) (*gitlab.Milestone, error) {
+	milestone, err := mock.newMilestone(options, mock.availableMilestones(project))
+	if err != nil {
+		return nil, err
+	}
+
+	milestone.ProjectID = project.ID
+	milestone.IID = mock.nextIID("milestone", project.ID)
+	milestone.WebURL = fmt.Sprintf("/%s/-/milestones/%d", project.PathWithNamespace, milestone.IID)
+
+	mock.projectMilestones[project.ID] = append(mock.projectMilestones[project.ID], milestone)
+
+	return milestone, nil
+}
+
+// AddGroupMilestone creates a milestone in the group, which is available in all its projects and subgroups.
+func (mock *GitlabMock) AddGroupMilestone(group *gitlab.Group, options *gitlab.CreateMilestoneOptions) (*gitlab.Milestone, error) {
+	milestone, err := mock.newMilestone(options, mock.availableGroupMilestones(group))
+	if err != nil {
+		return nil, err
+	}
+
+	milestone.GroupID = group.ID
+	milestone.IID = mock.nextIID("group_milestone", group.ID)
+	milestone.WebURL = fmt.Sprintf("/groups/%s/-/milestones/%d", group.FullPath, milestone.IID)
+
+	mock.groupMilestones[group.ID] = append(mock.groupMilestones[group.ID], milestone)
+
+	return milestone, nil
+}
+
+func (mock *GitlabMock) getMilestone(milestones []*gitlab.Milestone, milestoneID int) (*gitlab.Milestone, error) {
+	for _, milestone := range milestones {
+		if milestone.ID == milestoneID {
+			refreshMilestone(milestone, mock.now())
+			return milestone, nil
+		}
+	}
+
+	return nil, newAPIError(http.StatusNotFound, "404 Milestone Not Found")
+}
+
+// resolveMilestone returns the milestone available in the project for the milestone_id parameter of issues
+// and merge requests. Like GitLab, unknown milestones are ignored.
+func (mock *GitlabMock) resolveMilestone(project *gitlab.Project, milestoneID *int) *gitlab.Milestone {
+	if milestoneID == nil || *milestoneID == 0 {
+		return nil
+	}
+
+	milestone, err := mock.getMilestone(mock.availableMilestones(project), *milestoneID)
+	if err != nil {
+		return nil
+	}
+
+	return milestone
+}
+
+// updateMilestone edits the milestone and closes or activates it as requested by state_event.
+func (mock *GitlabMock) updateMilestone(milestone *gitlab.Milestone, existingMilestones []*gitlab.Milestone, options *gitlab.UpdateMilestoneOptions) (*gitlab.Milestone, error) {
+	if options.Title != nil && *options.Title != milestone.Title {
+		for _, other := range existingMilestones {
+			if other.Title == *options.Title {
+				return nil, newAPIError(http.StatusBadRequest, "Title already being used for another group or project milestone.")
+			}
+		}
+	}
+
+	startDate, dueDate := milestone.StartDate, milestone.DueDate
+	if options.StartDate != nil {
+		startDate = options.StartDate
+	}
+	if options.DueDate != nil {
+		dueDate = options.DueDate
+	}
+
+	err := validateMilestoneDates(startDate, dueDate)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.StateEvent != nil {
+		switch *options.StateEvent {
+		case "close":
+			milestone.State = milestoneClosed
+		case "activate":
+			milestone.State = milestoneActive
+		default:
+			return nil, newAPIError(http.StatusBadRequest, "state_event does not have a valid value")
+		}
+	}
+
+	if options.Title != nil {
+		milestone.Title = *options.Title
+	}
+
+	if options.Description != nil {
+		milestone.Description = *options.Description
+	}
+
+	milestone.StartDate, milestone.DueDate = startDate, dueDate
+
+	updatedAt := mock.now()
+	milestone.UpdatedAt = &updatedAt
+
+	refreshMilestone(milestone, mock.now())
+
+	return milestone, nil
+}
+
+// deleteMilestone removes the milestone of the project or group and unsets it on all issues and merge requests.
+func (mock *GitlabMock) deleteMilestone(project *gitlab.Project, group *gitlab.Group, milestone *gitlab.Milestone) error {
+	var milestones map[int][]*gitlab.Milestone
+	var ownerID int
+
+	if project != nil {
+		milestones, ownerID = mock.projectMilestones, project.ID
+	} else {
+		milestones, ownerID = mock.groupMilestones, group.ID
+	}
+
+	idx := slices.Index(milestones[ownerID], milestone)
+	if idx < 0 {
+		return newAPIError(http.StatusNotFound, "404 Milestone Not Found")
+	}
+
+	milestones[ownerID] = slices.Delete(milestones[ownerID], idx, idx+1)
+
+	for _, project := range mock.labelProjects(project, group) {
+		for _, issue := range mock.issues[project.ID] {
+			if issue.Milestone == milestone {
+				issue.Milestone = nil
+			}
+		}
+
+		for _, mergeRequest := range mock.mergeRequests[project.ID] {
+			if mergeRequest.Milestone == milestone {
+				mergeRequest.Milestone = nil
+			}
+		}
+	}
+
+	delete(mock.burndownEvents, milestone.ID)
+
+	return nil
+}
+
+// milestoneIssues returns the issues of the projects assigned to the milestone.
+func (mock *GitlabMock) milestoneIssues(projects []*gitlab.Project, milestone *gitlab.Milestone) []*gitlab.Issue {
+	issues := []*gitlab.Issue{}
+
+	for _, project := range projects {
+		for _, issue := range mock.issues[project.ID] {
+			if issue.Milestone == milestone {
+				mock.refreshIssue(issue)
+				issues = append(issues, issue)
+			}
+		}
+	}
+
+	return issues
+}
+
+// milestoneMergeRequests returns the merge requests of the projects assigned to the milestone.
+func (mock *GitlabMock) milestoneMergeRequests(projects []*gitlab.Project, milestone *gitlab.Milestone) ([]*gitlab.MergeRequest, error) {
+	mergeRequests := []*gitlab.MergeRequest{}
+
+	for _, project := range projects {
+		for _, mergeRequest := range mock.mergeRequests[project.ID] {
+			if mergeRequest.Milestone == milestone {
+				err := mock.refreshMergeRequest(project, mergeRequest)
+				if err != nil {
+					return nil, err
+				}
+
+				mergeRequests = append(mergeRequests, mergeRequest)
+			}
+		}
+	}
+
+	return mergeRequests, nil
+}
+
+// recordMilestoneChange adds the resource milestone events for a changed milestone of an issue or merge
+// request. Changes of the milestone of issues are also recorded as burndown events.
+func (mock *GitlabMock) recordMilestoneChange(resourceType string, resourceID int, projectID int, iid int, weight int, before *gitlab.Milestone, after *gitlab.Milestone, user *gitlab.User) {
+	if before == after {
+		return
+	}
+
+	add := func(milestone *gitlab.Milestone, action string) {
+		createdAt := mock.now()
+
+		mock.milestoneEvents = append(mock.milestoneEvents, &milestoneEvent{
+			MilestoneEvent: gitlab.MilestoneEvent{
+				ID:           int(mock.milestoneEventIds.Add(1)),
+				User:         toBasicUser(user),
+				CreatedAt:    &createdAt,
+				ResourceType: resourceType,
+				ResourceID:   resourceID,
+				Milestone:    milestone,
+				Action:       action,
+			},
+			ProjectID: projectID,
+			IID:       iid,
+		})
+
+		if resourceType == noteableIssue {
+			mock.addBurndownEvent(milestone, action, weight)
+		}
+	}
+
+	if before != nil {
+		add(before, "remove")
+	}
+
+	if after != nil {
+		add(after, "add")
+	}
+}
+
+// addBurndownEvent records an event for the burndown chart of the milestone, see
+// https://docs.gitlab.com/ee/api/milestones.html#get-all-burndown-chart-events-for-a-single-milestone
+func (mock *GitlabMock) addBurndownEvent(milestone *gitlab.Milestone, action string, weight int) {
+	if milestone == nil {
+		return
+	}
+
+	createdAt := mock.now()
+
+	mock.burndownEvents[milestone.ID] = append(mock.burndownEvents[milestone.ID], &gitlab.BurndownChartEvent{
+		CreatedAt: &createdAt,
+		Weight:    gitlab.Ptr(weight),
+		Action:    gitlab.Ptr(action),
+	})
+}
+
+// getMilestoneEvents returns the resource milestone events of the issue or merge request.
+func (mock *GitlabMock) getMilestoneEvents(resourceType string, projectID int, iid int) []*gitlab.MilestoneEvent {
+	events := []*gitlab.MilestoneEvent{}
+
+	for _, event := range mock.milestoneEvents {
+		if event.ResourceType == resourceType && event.ProjectID == projectID && event.IID == iid {
+			events = append(events, &event.MilestoneEvent)
+		}
+	}
+
+	return events
+}
@@ -0,0 +1,539 @@
+package gitlabapimock
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+const (
+	noteableIssue        = "Issue"
+	noteableMergeRequest = "MergeRequest"
+	noteableSnippet      = "Snippet"
+	noteableCommit       = "Commit"
+)
+
+// noteable identifies the issue, merge request, snippet or commit notes are attached to.
+type noteable struct {
+	Type      string
+	ID        int
+	IID       int
+	ProjectID int
+	CommitID  string
+}
+
+func (noteable noteable) key() string {
+	if noteable.Type == noteableCommit {
+		return fmt.Sprintf("%s/%d/%s", noteable.Type, noteable.ProjectID, noteable.CommitID)
+	}
+
+	return fmt.Sprintf("%s/%d", noteable.Type, noteable.ID)
+}
+
+// resolvable reports whether threads on the noteable can be resolved.
+func (noteable noteable) resolvable() bool {
+	return noteable.Type == noteableMergeRequest || noteable.Type == noteableIssue
+}
+
+func mergeRequestNoteable(mergeRequest *gitlab.MergeRequest) noteable {
+	return noteable{Type: noteableMergeRequest, ID: mergeRequest.ID, IID: mergeRequest.IID, ProjectID: mergeRequest.ProjectID}
+}
+
+func issueNoteable(issue *gitlab.Issue) noteable {
+	return noteable{Type: noteableIssue, ID: issue.ID, IID: issue.IID, ProjectID: issue.ProjectID}
+}
+
+func snippetNoteable(snippet *gitlab.Snippet) noteable {
+	return noteable{Type: noteableSnippet, ID: snippet.ID, ProjectID: snippet.ProjectID}
+}
+
+func commitNoteable(project *gitlab.Project, sha string) noteable {
+	return noteable{Type: noteableCommit, ProjectID: project.ID, CommitID: sha}
+}
+
+// discussionID returns a discussion ID in the format of GitLab, a SHA-1 hex digest.
+func discussionID(noteID int) string {
+	sum := sha1.Sum([]byte(fmt.Sprintf("discussion/%d", noteID)))
+
+	return hex.EncodeToString(sum[:])
+}
+
+func (mock *GitlabMock) newNote(noteable noteable, noteType gitlab.NoteTypeValue, body string, author *gitlab.User) *gitlab.Note {
+	createdAt := mock.now()
+
+	note := &gitlab.Note{
+		ID:           int(mock.noteIds.Add(1)),
+		Type:         noteType,
+		Body:         body,
+		CreatedAt:    &createdAt,
+		UpdatedAt:    &createdAt,
+		NoteableType: noteable.Type,
+		ProjectID:    noteable.ProjectID,
+		CommitID:     noteable.CommitID,
+	}
+
+	if noteable.Type != noteableCommit {
+		note.NoteableID = noteable.ID
+		note.NoteableIID = noteable.IID
+	}
+
+	if author != nil {
+		note.Author.ID = author.ID
+		note.Author.Username = author.Username
+		note.Author.Email = author.Email
+		note.Author.Name = author.Name
+		note.Author.State = author.State
+		note.Author.AvatarURL = author.AvatarURL
+		note.Author.WebURL = author.WebURL
+	}
+
+	return note
+}
+
+func (mock *GitlabMock) getDiscussions(noteable noteable) []*gitlab.Discussion {
+	discussions := mock.discussions[noteable.key()]
+	if discussions == nil {
+		discussions = []*gitlab.Discussion{}
+	}
+
+	return discussions
+}
+
+func (mock *GitlabMock) getDiscussion(noteable noteable, discussionID string) (*gitlab.Discussion, error) {
+	for _, discussion := range mock.discussions[noteable.key()] {
+		if discussion.ID == discussionID {
+			return discussion, nil
+		}
+	}
+
+	return nil, newAPIError(http.StatusNotFound, "404 Discussion Not Found")
+}
+
+// getNotes returns the notes of all discussions of the noteable, including system notes.
+func (mock *GitlabMock) getNotes(noteable noteable) []*gitlab.Note {
+	notes := []*gitlab.Note{}
+
+	for _, discussion := range mock.discussions[noteable.key()] {
+		notes = append(notes, discussion.Notes...)
+	}
+
+	return notes
+}
+
+func (mock *GitlabMock) getNote(noteable noteable, noteID int) (*gitlab.Discussion, *gitlab.Note, error) {
+	for _, discussion := range mock.discussions[noteable.key()] {
+		for _, note := range discussion.Notes {
+			if note.ID == noteID {
+				return discussion, note, nil
+			}
+		}
+	}
+
+	return nil, nil, newAPIError(http.StatusNotFound, "404 Note Not Found")
+}
+
+// validatePosition checks that a diff note points to a file and line of the merge request diff.
+func (mock *GitlabMock) validatePosition(project *gitlab.Project, position *gitlab.PositionOptions) (*gitlab.NotePosition, error) {
+	errInvalidPosition := newAPIError(http.StatusBadRequest, `400 Bad request - Note {:line_code=>["can't be blank", "must be a valid line code"]}`)
+
+	notePosition := &gitlab.NotePosition{
+		BaseSHA:      valueOf(position.BaseSHA),
+		StartSHA:     valueOf(position.StartSHA),
+		HeadSHA:      valueOf(position.HeadSHA),
+		PositionType: valueOf(position.PositionType),
+		NewPath:      valueOf(position.NewPath),
+		NewLine:      valueOf(position.NewLine),
+		OldPath:      valueOf(position.OldPath),
+		OldLine:      valueOf(position.OldLine),
+	}
+
+	if notePosition.BaseSHA == "" || notePosition.StartSHA == "" || notePosition.HeadSHA == "" {
+		return nil, errInvalidPosition
+	}
+
+	if notePosition.PositionType == "" {
+		notePosition.PositionType = "text"
+	}
+
+	if notePosition.NewPath == "" {
+		notePosition.NewPath = notePosition.OldPath
+	}
+
+	if notePosition.OldPath == "" {
+		notePosition.OldPath = notePosition.NewPath
+	}
+
+	if notePosition.PositionType != "text" {
+		return notePosition, nil
+	}
+
+	repo, err := mock.getRepository(project)
+	if err != nil {
+		return nil, err
+	}
+
+	diffs, err := repo.diff(notePosition.BaseSHA, notePosition.HeadSHA)
+	if err != nil {
+		return nil, errInvalidPosition
+	}
+
+	inDiff := slices.ContainsFunc(diffs, func(diff *gitlab.MergeRequestDiff) bool {
+		return diff.NewPath == notePosition.NewPath || diff.OldPath == notePosition.OldPath
+	})
+	if !inDiff || (notePosition.NewLine == 0 && notePosition.OldLine == 0) {
+		return nil, errInvalidPosition
+	}
+
+	for _, line := range []struct {
+		sha    string
+		path   string
+		number int
+	}{
+		{notePosition.HeadSHA, notePosition.NewPath, notePosition.NewLine},
+		{notePosition.BaseSHA, notePosition.OldPath, notePosition.OldLine},
+	} {
+		if line.number == 0 {
+			continue
+		}
+
+		content, err := repo.git(nil, nil, "cat-file", "-p", line.sha+":"+line.path)
+		if err != nil || line.number > strings.Count(string(content), "\n") {
+			return nil, errInvalidPosition
+		}
+	}
+
+	return notePosition, nil
+}
+
+// createDiscussion starts a discussion on the noteable. Individual discussions hold a single note as
+// created by the notes API, the other ones are threads that can be resolved on merge requests and issues.
+func (mock *GitlabMock) createDiscussion(project *gitlab.Project, noteable noteable, body *string, position *gitlab.PositionOptions, individual bool, user *gitlab.User) (*gitlab.Discussion, error) {
+	if body == nil || *body == "" {
+		return nil, newAPIError(http.StatusBadRequest, "body is missing")
+	}
+
+	if !mock.hasAccess(project, user, gitlab.GuestPermissions) {
+		return nil, newAPIError(http.StatusForbidden, "403 Forbidden")
+	}
+
+	noteType := gitlab.DiscussionNote
+	if individual {
+		noteType = ""
+	}
+
+	var notePosition *gitlab.NotePosition
+
+	if position != nil {
+		var err error
+
+		notePosition, err = mock.validatePosition(project, position)
+		if err != nil {
+			return nil, err
+		}
+
+		noteType = gitlab.DiffNote
+	}
+
+	note := mock.newNote(noteable, noteType, *body, user)
+	note.Position = notePosition
+	note.Resolvable = !individual && noteable.resolvable()
+
+	discussion := &gitlab.Discussion{
+		ID:             discussionID(note.ID),
+		IndividualNote: individual,
+		Notes:          []*gitlab.Note{note},
+	}
+
+	mock.discussions[noteable.key()] = append(mock.discussions[noteable.key()], discussion)
+	mock.fireNoteHooks(project, noteable, discussion.ID, note, "create", user)
+
+	return discussion, nil
+}
+
+// addDiscussionNote replies to a discussion. Replying to an individual note turns it into a thread.
+func (mock *GitlabMock) addDiscussionNote(project *gitlab.Project, noteable noteable, discussionID string, body *string, user *gitlab.User) (*gitlab.Note, error) {
+	discussion, err := mock.getDiscussion(noteable, discussionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if body == nil || *body == "" {
+		return nil, newAPIError(http.StatusBadRequest, "body is missing")
+	}
+
+	if !mock.hasAccess(project, user, gitlab.GuestPermissions) {
+		return nil, newAPIError(http.StatusForbidden, "403 Forbidden")
+	}
+
+	first := discussion.Notes[0]
+	if first.System {
+		return nil, newAPIError(http.StatusBadRequest, "400 Bad request - Note {:discussion_id=>[\"can't reply to system notes\"]}")
+	}
+
+	if discussion.IndividualNote {
+		discussion.IndividualNote = false
+		first.Type = gitlab.DiscussionNote
+		first.Resolvable = noteable.resolvable()
+	}
+
+	note := mock.newNote(noteable, first.Type, *body, user)
+	note.Position = first.Position
+	note.Resolvable = first.Resolvable
+	note.Resolved = first.Resolved
+	note.ResolvedAt = first.ResolvedAt
+	note.ResolvedBy = first.ResolvedBy
+
+	discussion.Notes = append(discussion.Notes, note)
+	mock.fireNoteHooks(project, noteable, discussion.ID, note, "create", user)
+
+	return note, nil
+}
+
+// updateNote changes the body of a note, which only its author may do.
+func (mock *GitlabMock) updateNote(project *gitlab.Project, noteable noteable, noteID int, body *string, user *gitlab.User) (*gitlab.Note, error) {
+	discussion, note, err := mock.getNote(noteable, noteID)
+	if err != nil {
+		return nil, err
+	}
+
+	if note.System {
+		return nil, newAPIError(http.StatusForbidden, "403 Forbidden")
+	}
+
+	if mock.authenticationRequired() && (user == nil || (user.ID != note.Author.ID && !user.IsAdmin)) {
+		return nil, newAPIError(http.StatusForbidden, "403 Forbidden")
+	}
+
+	if body != nil {
+		if *body == "" {
+			return nil, newAPIError(http.StatusBadRequest, "body is empty")
+		}
+
+		updatedAt := mock.now()
+		note.Body = *body
+		note.UpdatedAt = &updatedAt
+
+		mock.fireNoteHooks(project, noteable, discussion.ID, note, "update", user)
+	}
+
+	return note, nil
+}
+
+// deleteNote removes a note, which its author and maintainers may do. Empty discussions are removed as well.
+func (mock *GitlabMock) deleteNote(project *gitlab.Project, noteable noteable, noteID int, user *gitlab.User) error {
+	discussion, note, err := mock.getNote(noteable, noteID)
+	if err != nil {
+		return err
+	}
+
+	isAuthor := user != nil && user.ID == note.Author.ID
+	if note.System || (!isAuthor && !mock.hasAccess(project, user, gitlab.MaintainerPermissions)) {
+		return newAPIError(http.StatusForbidden, "403 Forbidden")
+	}
+
+	discussion.Notes = slices.DeleteFunc(discussion.Notes, func(other *gitlab.Note) bool {
+		return other.ID == noteID
+	})
+
+	if len(discussion.Notes) == 0 {
+		key := noteable.key()
+		mock.discussions[key] = slices.DeleteFunc(mock.discussions[key], func(other *gitlab.Discussion) bool {
+			return other == discussion
+		})
+	}
+
+	return nil
+}
+
+// resolveDiscussion resolves or unresolves all notes of the thread. Developers and the author of the
+// merge request or issue may do this.
+func (mock *GitlabMock) resolveDiscussion(project *gitlab.Project, noteable noteable, authorID int, discussionID string, resolved bool, user *gitlab.User) (*gitlab.Discussion, error) {
+	discussion, err := mock.getDiscussion(noteable, discussionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !discussion.Notes[0].Resolvable {
+		return nil, newAPIError(http.StatusBadRequest, "400 Bad request - Discussion is not resolvable")
+	}
+
+	isAuthor := user != nil && user.ID == authorID
+	if !isAuthor && !mock.hasAccess(project, user, gitlab.DeveloperPermissions) {
+		return nil, newAPIError(http.StatusForbidden, "403 Forbidden")
+	}
+
+	var resolvedAt *time.Time
+	resolvedBy := gitlab.Note{}.ResolvedBy
+
+	if resolved {
+		now := mock.now()
+		resolvedAt = &now
+
+		if user != nil {
+			resolvedBy.ID = user.ID
+			resolvedBy.Username = user.Username
+			resolvedBy.Email = user.Email
+			resolvedBy.Name = user.Name
+			resolvedBy.State = user.State
+			resolvedBy.AvatarURL = user.AvatarURL
+			resolvedBy.WebURL = user.WebURL
+		}
+	}
+
+	for _, note := range discussion.Notes {
+		note.Resolved = resolved
+		note.ResolvedAt = resolvedAt
+		note.ResolvedBy = resolvedBy
+	}
+
+	return discussion, nil
+}
+
+// unresolvedDiscussions returns the number of resolvable threads that are not resolved yet.
+func (mock *GitlabMock) unresolvedDiscussions(noteable noteable) int {
+	unresolved := 0
+
+	for _, discussion := range mock.discussions[noteable.key()] {
+		if discussion.Notes[0].Resolvable && !discussion.Notes[0].Resolved {
+			unresolved++
+		}
+	}
+
+	return unresolved
+}
+
+// userNotesCount returns the number of notes that are not system notes.
+func (mock *GitlabMock) userNotesCount(noteable noteable) int {
+	count := 0
+
+	for _, note := range mock.getNotes(noteable) {
+		if !note.System {
+			count++
+		}
+	}
+
+	return count
+}
+
+// addSystemNote records a change of the noteable, e.g. "added ~bug label", as GitLab does.
+func (mock *GitlabMock) addSystemNote(noteable noteable, body string, user *gitlab.User) {
+	note := mock.newNote(noteable, "", body, user)
+	note.System = true
+
+	discussion := &gitlab.Discussion{
+		ID:             discussionID(note.ID),
+		IndividualNote: true,
+		Notes:          []*gitlab.Note{note},
+	}
+
+	mock.discussions[noteable.key()] = append(mock.discussions[noteable.key()], discussion)
+}
+
+func labelReferences(labels []string) string {
+	references := make([]string, 0, len(labels))
+	for _, label := range labels {
+		if strings.Contains(label, " ") {
+			references = append(references, fmt.Sprintf("~%q", label))
+		} else {
+			references = append(references, "~"+label)
+		}
+	}
+
+	return strings.Join(references, " ")
+}
+
+func pluralize(count int, singular string, plural string) string {
+	if count == 1 {
+		return singular
+	}
+
+	return plural
+}
+
+// labelsSystemNote returns the system note for changed labels, e.g. "added ~bug label and removed ~feature label",
+// or an empty string if the labels are unchanged.
+func labelsSystemNote(before []string, after []string) string {
+	added := []string{}
+	for _, label := range after {
+		if !slices.Contains(before, label) {
+			added = append(added, label)
+		}
+	}
+
+	removed := []string{}
+	for _, label := range before {
+		if !slices.Contains(after, label) {
+			removed = append(removed, label)
+		}
+	}
+
+	parts := []string{}
+
+	if len(added) > 0 {
+		parts = append(parts, fmt.Sprintf("added %s %s", labelReferences(added), pluralize(len(added), "label", "labels")))
+	}
+
+	if len(removed) > 0 {
+		parts = append(parts, fmt.Sprintf("removed %s %s", labelReferences(removed), pluralize(len(removed), "label", "labels")))
+	}
+
+	return strings.Join(parts, " and ")
+}
+
+// usersSystemNote returns the system note for changed assignees or reviewers, e.g. "assigned to @peter.pan",
+// or an empty string if the users are unchanged.
+func usersSystemNote(before []string, after []string, addedVerb string, removedVerb string) string {
+	added := []string{}
+	for _, username := range after {
+		if !slices.Contains(before, username) {
+			added = append(added, "@"+username)
+		}
+	}
+
+	removed := []string{}
+	for _, username := range before {
+		if !slices.Contains(after, username) {
+			removed = append(removed, "@"+username)
+		}
+	}
+
+	parts := []string{}
+
+	if len(added) > 0 {
+		parts = append(parts, addedVerb+" "+strings.Join(added, " and "))
+	}
+
+	if len(removed) > 0 {
+		parts = append(parts, removedVerb+" "+strings.Join(removed, " and "))
+	}
+
+	return strings.Join(parts, " and ")
+}
+
+func basicUsernames(users []*gitlab.BasicUser) []string {
+	usernames := []string{}
+	for _, user := range users {
+		usernames = append(usernames, user.Username)
+	}
+
+	return usernames
+}
+
+// milestoneSystemNote returns the body of the system note for a changed milestone or an empty string if
+// the milestone didn't change.
+func milestoneSystemNote(before *gitlab.Milestone, after *gitlab.Milestone) string {
+	switch {
+	case before == after:
+		return ""
+	case after == nil:
+		return fmt.Sprintf("removed milestone %%%d", before.IID)
+	default:
+		return fmt.Sprintf("changed milestone to %%%d", after.IID)
+	}
+}
@@ -0,0 +1,425 @@
+package gitlabapimock
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+const (
+	applicationSecretPrefix = "gloas-"
+
+	oauthTokenLifetime = 2 * time.Hour
+	oauthCodeLifetime  = 10 * time.Minute
+
+	pkceMethodPlain = "plain"
+	pkceMethodS256  = "S256"
+)
+
+// oauthScopes are the scopes of OAuth applications, the scopes of personal access tokens and the OpenID Connect
+// scopes, see https://docs.gitlab.com/ee/integration/openid_connect_provider.html#shared-information.
+var oauthScopes = slices.Concat(personalAccessTokenScopes, []string{"openid", "profile", "email"})
+
+// OAuthConsent decides whether the signed in user authorizes the application to access the scopes, see
+// SetOAuthConsent.
+type OAuthConsent func(application *gitlab.Application, user *gitlab.User, scopes []string) bool
+
+// oauthApplication is an OAuth application, its callback URL holds the redirect URIs separated by whitespace.
+type oauthApplication struct {
+	gitlab.Application
+
+	scopes []string
+}
+
+// redirectURIs returns the redirect URIs the application may redirect to.
+func (application *oauthApplication) redirectURIs() []string {
+	return strings.Fields(application.CallbackURL)
+}
+
+// oauthGrant is an authorization code issued by /oauth/authorize, which is exchanged once for a token.
+type oauthGrant struct {
+	code                string
+	applicationID       int
+	userID              int
+	redirectURI         string
+	scopes              []string
+	codeChallenge       string
+	codeChallengeMethod string
+	expiresAt           time.Time
+	used                bool
+}
+
+// oauthToken is an OAuth access token and its refresh token. Tokens of the password grant without client
+// credentials belong to no application.
+type oauthToken struct {
+	accessToken   string
+	refreshToken  string
+	applicationID int
+	userID        int
+	scopes        []string
+	createdAt     time.Time
+	revoked       bool
+}
+
+// oauthTokenResponse is the response of the token endpoint, see https://www.rfc-editor.org/rfc/rfc6749#section-5.1.
+type oauthTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	Scope        string `json:"scope"`
+	CreatedAt    int64  `json:"created_at"`
+}
+
+// oauthError is an error of the OAuth endpoints, see https://www.rfc-editor.org/rfc/rfc6749#section-5.2.
+type oauthError struct {
+	statusCode  int
+	code        string
+	description string
+}
+
+func newOAuthError(statusCode int, code string, description string) error {
+	return &oauthError{statusCode: statusCode, code: code, description: description}
+}
+
+func (err *oauthError) Error() string {
+	return err.code + ": " + err.description
+}
+
+var (
+	errInvalidClient = newOAuthError(http.StatusUnauthorized, "invalid_client",
+		"Client authentication failed due to unknown client, no client authentication included, or unsupported authentication method.")
+	errInvalidGrant = newOAuthError(http.StatusBadRequest, "invalid_grant",
+		"The provided authorization grant is invalid, expired, revoked, does not match the redirection URI used in the authorization request, or was issued to another client.")
+	errInvalidScope = newOAuthError(http.StatusBadRequest, "invalid_scope",
+		"The requested scope is invalid, unknown, or malformed.")
+)
+
+// SetUserPassword sets the password the user signs in with, which is used by the OAuth password grant.
+func (mock *GitlabMock) SetUserPassword(user *gitlab.User, password string) {
+	mock.userPasswords[user.ID] = password
+}
+
+// SignIn signs the user in to the web UI of the mock. The signed in user authorizes OAuth applications at
+// /oauth/authorize, nil signs the user out.
+func (mock *GitlabMock) SignIn(user *gitlab.User) {
+	mock.sessionUser = user
+}
+
+// SetOAuthConsent sets the decision of the signed in user whether to authorize an OAuth application. Without
+// a consent every authorization is approved.
+func (mock *GitlabMock) SetOAuthConsent(consent OAuthConsent) {
+	mock.oauthConsent = consent
+}
+
+// parseOAuthScopes parses the space separated scopes, all of them have to be part of the allowed scopes.
+func parseOAuthScopes(scope string, allowedScopes []string) ([]string, error) {
+	scopes := strings.Fields(scope)
+
+	for _, scope := range scopes {
+		if !slices.Contains(allowedScopes, scope) {
+			return nil, errInvalidScope
+		}
+	}
+
+	return scopes, nil
+}
+
+// AddApplication registers an OAuth application, the redirect URIs are separated by whitespace.
+func (mock *GitlabMock) AddApplication(name string, redirectURI string, scopes []string, confidential bool) *gitlab.Application {
+	id := int(mock.applicationIds.Add(1))
+
+	application := &oauthApplication{
+		Application: gitlab.Application{
+			ID:              id,
+			ApplicationID:   newToken("") + newToken(""),
+			ApplicationName: name,
+			Secret:          applicationSecretPrefix + newToken("") + newToken(""),
+			CallbackURL:     redirectURI,
+			Confidential:    confidential,
+		},
+		scopes: scopes,
+	}
+
+	mock.applications = append(mock.applications, application)
+
+	return &application.Application
+}
+
+// createApplication validates the options and registers the application, applications are confidential by default.
+func (mock *GitlabMock) createApplication(options *gitlab.CreateApplicationOptions) (*gitlab.Application, error) {
+	if valueOf(options.Name) == "" {
+		return nil, newAPIError(http.StatusBadRequest, "name is missing")
+	}
+
+	if valueOf(options.RedirectURI) == "" {
+		return nil, newAPIError(http.StatusBadRequest, "redirect_uri is missing")
+	}
+
+	if valueOf(options.Scopes) == "" {
+		return nil, newAPIError(http.StatusBadRequest, "scopes is missing")
+	}
+
+	scopes, err := parseOAuthScopes(*options.Scopes, oauthScopes)
+	if err != nil {
+		return nil, newAPIError(http.StatusBadRequest, map[string][]string{"scopes": {"doesn't match configured on the server."}})
+	}
+
+	confidential := true
+	if options.Confidential != nil {
+		confidential = *options.Confidential
+	}
+
+	return mock.AddApplication(*options.Name, *options.RedirectURI, scopes, confidential), nil
+}
+
+func (mock *GitlabMock) getApplication(id int) (*oauthApplication, error) {
+	for _, application := range mock.applications {
+		if application.ID == id {
+			return application, nil
+		}
+	}
+
+	return nil, newAPIError(http.StatusNotFound, "404 Application Not Found")
+}
+
+// applicationByClientID returns the application of the client ID, its application_id.
+func (mock *GitlabMock) applicationByClientID(clientID string) (*oauthApplication, bool) {
+	for _, application := range mock.applications {
+		if application.ApplicationID == clientID {
+			return application, true
+		}
+	}
+
+	return nil, false
+}
+
+// visibleApplication returns the application without its secret, which GitLab only shows when it is created or
+// renewed.
+func visibleApplication(application *oauthApplication) *gitlab.Application {
+	visibleApplication := application.Application
+	visibleApplication.Secret = ""
+
+	return &visibleApplication
+}
+
+// deleteApplication removes the application and revokes the tokens it was issued.
+func (mock *GitlabMock) deleteApplication(application *oauthApplication) {
+	mock.applications = slices.DeleteFunc(mock.applications, func(other *oauthApplication) bool {
+		return other == application
+	})
+
+	for _, token := range mock.oauthTokens {
+		if token.applicationID == application.ID {
+			token.revoked = true
+		}
+	}
+}
+
+// renewApplicationSecret replaces the secret of the application.
+func (mock *GitlabMock) renewApplicationSecret(application *oauthApplication) *gitlab.Application {
+	application.Secret = applicationSecretPrefix + newToken("") + newToken("")
+
+	return &application.Application
+}
+
+// authenticateClient resolves the application of the client credentials. Confidential applications have to send
+// their secret, public ones like mobile apps cannot keep it and protect their codes with PKCE instead.
+func (mock *GitlabMock) authenticateClient(clientID string, clientSecret string) (*oauthApplication, error) {
+	application, applicationExists := mock.applicationByClientID(clientID)
+	if !applicationExists {
+		return nil, errInvalidClient
+	}
+
+	if clientSecret == "" && !application.Confidential {
+		return application, nil
+	}
+
+	if subtle.ConstantTimeCompare([]byte(clientSecret), []byte(application.Secret)) != 1 {
+		return nil, errInvalidClient
+	}
+
+	return application, nil
+}
+
+// createOAuthGrant issues an authorization code for the application on behalf of the user.
+func (mock *GitlabMock) createOAuthGrant(application *oauthApplication, user *gitlab.User, redirectURI string, scopes []string, codeChallenge string, codeChallengeMethod string) *oauthGrant {
+	grant := &oauthGrant{
+		code:                newToken(""),
+		applicationID:       application.ID,
+		userID:              user.ID,
+		redirectURI:         redirectURI,
+		scopes:              scopes,
+		codeChallenge:       codeChallenge,
+		codeChallengeMethod: codeChallengeMethod,
+		expiresAt:           mock.now().Add(oauthCodeLifetime),
+	}
+
+	mock.oauthGrants = append(mock.oauthGrants, grant)
+
+	return grant
+}
+
+// verifyCodeChallenge checks the PKCE code verifier against the code challenge of the grant, see
+// https://www.rfc-editor.org/rfc/rfc7636#section-4.6.
+func verifyCodeChallenge(grant *oauthGrant, codeVerifier string) bool {
+	if grant.codeChallenge == "" {
+		return true
+	}
+
+	challenge := codeVerifier
+	if grant.codeChallengeMethod == pkceMethodS256 {
+		sum := sha256.Sum256([]byte(codeVerifier))
+		challenge = base64.RawURLEncoding.EncodeToString(sum[:])
+	}
+
+	return codeVerifier != "" && subtle.ConstantTimeCompare([]byte(challenge), []byte(grant.codeChallenge)) == 1
+}
+
+// exchangeOAuthGrant exchanges the authorization code for a token. Codes are used once, expire after ten minutes
+// and have to be redeemed with the redirect URI and code verifier of the authorization request.
+func (mock *GitlabMock) exchangeOAuthGrant(application *oauthApplication, code string, redirectURI string, codeVerifier string) (*oauthToken, error) {
+	for _, grant := range mock.oauthGrants {
+		if grant.code != code || grant.applicationID != application.ID {
+			continue
+		}
+
+		if grant.used || !mock.now().Before(grant.expiresAt) || grant.redirectURI != redirectURI || !verifyCodeChallenge(grant, codeVerifier) {
+			return nil, errInvalidGrant
+		}
+
+		grant.used = true
+
+		return mock.createOAuthToken(application.ID, grant.userID, grant.scopes), nil
+	}
+
+	return nil, errInvalidGrant
+}
+
+// refreshOAuthToken issues a new token for the refresh token and revokes the refreshed one.
+func (mock *GitlabMock) refreshOAuthToken(application *oauthApplication, refreshToken string) (*oauthToken, error) {
+	for _, token := range mock.oauthTokens {
+		if token.refreshToken != refreshToken || token.applicationID != application.ID {
+			continue
+		}
+
+		if token.revoked {
+			return nil, errInvalidGrant
+		}
+
+		token.revoked = true
+
+		return mock.createOAuthToken(application.ID, token.userID, token.scopes), nil
+	}
+
+	return nil, errInvalidGrant
+}
+
+// passwordOAuthToken issues a token for the credentials of a user, see SetUserPassword. Without an application the
+// token gets the api scope unless other scopes are requested.
+func (mock *GitlabMock) passwordOAuthToken(application *oauthApplication, username string, password string, scope string) (*oauthToken, error) {
+	allowedScopes := oauthScopes
+	defaultScopes := []string{"api"}
+	applicationID := 0
+
+	if application != nil {
+		allowedScopes = application.scopes
+		defaultScopes = application.scopes
+		applicationID = application.ID
+	}
+
+	scopes, err := parseOAuthScopes(scope, allowedScopes)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(scopes) == 0 {
+		scopes = defaultScopes
+	}
+
+	for _, user := range mock.users {
+		if user.Username != username && user.Email != username {
+			continue
+		}
+
+		userPassword, hasPassword := mock.userPasswords[user.ID]
+		if !hasPassword || subtle.ConstantTimeCompare([]byte(password), []byte(userPassword)) != 1 || user.State == "blocked" {
+			break
+		}
+
+		return mock.createOAuthToken(applicationID, user.ID, scopes), nil
+	}
+
+	return nil, errInvalidGrant
+}
+
+func (mock *GitlabMock) createOAuthToken(applicationID int, userID int, scopes []string) *oauthToken {
+	token := &oauthToken{
+		accessToken:   newToken("") + newToken(""),
+		refreshToken:  newToken("") + newToken(""),
+		applicationID: applicationID,
+		userID:        userID,
+		scopes:        scopes,
+		createdAt:     mock.now(),
+	}
+
+	mock.oauthTokens = append(mock.oauthTokens, token)
+
+	return token
+}
+
+// oauthAccessToken returns the OAuth token of the access token.
+func (mock *GitlabMock) oauthAccessToken(accessToken string) (*oauthToken, bool) {
+	for _, token := range mock.oauthTokens {
+		if token.accessToken == accessToken {
+			return token, true
+		}
+	}
+
+	return nil, false
+}
+
+// oauthTokenExpiresIn returns the remaining lifetime of the token on the clock of the mock.
+func (mock *GitlabMock) oauthTokenExpiresIn(token *oauthToken) time.Duration {
+	return token.createdAt.Add(oauthTokenLifetime).Sub(mock.now())
+}
+
+// oauthTokenActive reports whether the access token is neither revoked nor expired.
+func (mock *GitlabMock) oauthTokenActive(token *oauthToken) bool {
+	return !token.revoked && mock.oauthTokenExpiresIn(token) > 0
+}
+
+func (mock *GitlabMock) oauthTokenResponse(token *oauthToken) *oauthTokenResponse {
+	return &oauthTokenResponse{
+		AccessToken:  token.accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(oauthTokenLifetime / time.Second),
+		RefreshToken: token.refreshToken,
+		Scope:        strings.Join(token.scopes, " "),
+		CreatedAt:    token.createdAt.Unix(),
+	}
+}
+
+// revokeOAuthToken revokes the token of the access or refresh token, see https://www.rfc-editor.org/rfc/rfc7009.
+// Tokens of an application may only be revoked by the application. Unknown tokens are ignored.
+func (mock *GitlabMock) revokeOAuthToken(application *oauthApplication, value string) error {
+	for _, token := range mock.oauthTokens {
+		if token.accessToken != value && token.refreshToken != value {
+			continue
+		}
+
+		if token.applicationID != 0 && (application == nil || application.ID != token.applicationID) {
+			return newOAuthError(http.StatusForbidden, "unauthorized_client", "You are not authorized to revoke this token")
+		}
+
+		token.revoked = true
+	}
+
+	return nil
+}
@@ -0,0 +1,475 @@
+package gitlabapimock
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+const defaultStage = "test"
+
+// PipelineJob describes a job of a pipeline, see AddPipeline. Jobs run stage by stage in the order in which
+// their stages first appear, unless Needs is set: then the job starts as soon as the needed jobs are finished,
+// and an empty non-nil Needs starts it right away. When is one of on_success (default), on_failure, always or
+// manual. Manual jobs that do not allow failure block the later stages until they are played, like in GitLab.
+// Tags select the runners that may pick up the job, Script, AfterScript and Variables are sent to them.
+type PipelineJob struct {
+	Name         string
+	Stage        string
+	When         string
+	AllowFailure bool
+	Needs        []string
+	Tags         []string
+	Script       []string
+	AfterScript  []string
+	Variables    map[string]string
+}
+
+// compositeStatus derives the status of a pipeline or stage from the statuses of its jobs, following
+// GitLab's Ci::Status::Composite. Failed jobs that are allowed to fail count as success with warnings,
+// canceled and manual jobs that are allowed to fail are ignored.
+func compositeStatus(jobs []*gitlab.Job) string {
+	statuses := map[string]bool{}
+
+	for _, job := range jobs {
+		status := job.Status
+
+		if job.AllowFailure {
+			switch gitlab.BuildStateValue(status) {
+			case gitlab.Failed:
+				status = "success_with_warnings"
+			case gitlab.Canceled, gitlab.Manual:
+				status = "ignored"
+			}
+		}
+
+		statuses[status] = true
+	}
+
+	onlyOf := func(allowed ...string) bool {
+		for status := range statuses {
+			if !slices.Contains(allowed, status) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	anyOf := func(wanted ...string) bool {
+		return slices.ContainsFunc(wanted, func(status string) bool {
+			return statuses[status]
+		})
+	}
+
+	switch {
+	case onlyOf("skipped", "ignored"):
+		return "skipped"
+	case onlyOf("success", "skipped", "success_with_warnings", "ignored"):
+		return "success"
+	case onlyOf("created", "success_with_warnings", "ignored"):
+		return "created"
+	case onlyOf("canceled", "success", "skipped", "success_with_warnings", "ignored"):
+		return "canceled"
+	case onlyOf("pending", "created", "skipped", "success_with_warnings", "ignored"):
+		return "pending"
+	case anyOf("running", "pending"):
+		return "running"
+	case anyOf("manual"):
+		return "manual"
+	case anyOf("created"):
+		return "running"
+	}
+
+	return "failed"
+}
+
+func pipelineFinished(status string) bool {
+	return jobFinished(&gitlab.Job{Status: status})
+}
+
+func detailedStatus(status string) *gitlab.DetailedStatus {
+	text := status
+	switch status {
+	case "success":
+		text = "passed"
+	case "manual":
+		text = "blocked"
+	}
+
+	return &gitlab.DetailedStatus{
+		Icon:  "status_" + status,
+		Text:  text,
+		Label: text,
+		Group: status,
+	}
+}
+
+func (mock *GitlabMock) getPipeline(project *gitlab.Project, pipelineID int) (*gitlab.Pipeline, error) {
+	for _, pipeline := range mock.pipelines[project.ID] {
+		if pipeline.ID == pipelineID {
+			return pipeline, nil
+		}
+	}
+
+	return nil, newAPIError(http.StatusNotFound, "404 Not found")
+}
+
+// pipelineStages returns the stages of the pipeline in the order in which they run.
+func (mock *GitlabMock) pipelineStages(pipeline *gitlab.Pipeline) []string {
+	stages := []string{}
+
+	for _, job := range mock.jobs[pipeline.ID] {
+		if !slices.Contains(stages, job.Stage) {
+			stages = append(stages, job.Stage)
+		}
+	}
+
+	return stages
+}
+
+// GetPipelines returns the pipelines of the project, oldest first.
+func (mock *GitlabMock) GetPipelines(project *gitlab.Project) []*gitlab.Pipeline {
+	return mock.pipelines[project.ID]
+}
+
+// SetPipelineJobs sets the jobs of the pipelines that are created for the project through the API while the
+// project has no .gitlab-ci.yml.
+func (mock *GitlabMock) SetPipelineJobs(project *gitlab.Project, jobs []PipelineJob) {
+	mock.pipelineJobSpecs[project.ID] = jobs
+}
+
+// AddPipeline creates a pipeline with the jobs for the branch or tag of the project, as if the user pushed
+// to it. Without jobs, they are created from the .gitlab-ci.yml of the project. The jobs of the first stage
+// are pending right away, use SetJobStatus and AdvanceJob to let them run.
+func (mock *GitlabMock) AddPipeline(project *gitlab.Project, ref string, jobs []PipelineJob, user *gitlab.User) (*gitlab.Pipeline, error) {
+	return mock.createPipeline(project, ref, "push", nil, jobs, user)
+}
+
+func (mock *GitlabMock) createPipeline(project *gitlab.Project, ref string, source string, variables []*gitlab.PipelineVariable, jobs []PipelineJob, user *gitlab.User) (*gitlab.Pipeline, error) {
+	if ref == "" {
+		return nil, newAPIError(http.StatusBadRequest, "ref is missing")
+	}
+
+	repo, err := mock.getRepository(project)
+	if err != nil {
+		return nil, err
+	}
+
+	refs, err := repo.refs()
+	if err != nil {
+		return nil, err
+	}
+
+	_, isTag := refs["refs/tags/"+ref]
+	_, isBranch := refs["refs/heads/"+ref]
+	if !isTag && !isBranch {
+		return nil, newAPIError(http.StatusBadRequest, map[string][]string{"base": {"Reference not found"}})
+	}
+
+	sha, err := repo.resolve(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	commit, err := repo.commit(sha)
+	if err != nil {
+		return nil, err
+	}
+
+	commit.ProjectID = project.ID
+
+	if jobs == nil {
+		jobs, err = mock.ciPipelineJobs(&ciPipelineContext{
+			project:   project,
+			repo:      repo,
+			ref:       ref,
+			sha:       sha,
+			beforeSHA: zeroSHA,
+			tag:       isTag,
+			source:    source,
+			commit:    commit,
+		}, variables)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(jobs) == 0 {
+		return nil, newAPIError(http.StatusBadRequest, map[string][]string{"base": {"No stages / jobs for this pipeline."}})
+	}
+
+	createdAt := mock.now()
+
+	pipeline := &gitlab.Pipeline{
+		ID:          int(mock.pipelineIds.Add(1)),
+		IID:         mock.nextIID("pipeline", project.ID),
+		ProjectID:   project.ID,
+		Status:      string(gitlab.Created),
+		Source:      source,
+		Ref:         ref,
+		SHA:         sha,
+		BeforeSHA:   zeroSHA,
+		Tag:         isTag,
+		User:        toBasicUser(user),
+		CreatedAt:   &createdAt,
+		UpdatedAt:   &createdAt,
+		CommittedAt: commit.CommittedDate,
+	}
+
+	pipeline.WebURL = fmt.Sprintf("/%s/-/pipelines/%d", project.PathWithNamespace, pipeline.ID)
+
+	mock.pipelines[project.ID] = append(mock.pipelines[project.ID], pipeline)
+	mock.pipelineVariables[pipeline.ID] = variables
+
+	for _, spec := range sortJobsByStage(jobs) {
+		mock.newJob(pipeline, spec, commit, user)
+	}
+
+	mock.processPipeline(pipeline)
+
+	return pipeline, nil
+}
+
+// sortJobsByStage applies the defaults to the jobs and orders them by the first appearance of their stage.
+func sortJobsByStage(jobs []PipelineJob) []PipelineJob {
+	stages := []string{}
+	sortedJobs := []PipelineJob{}
+
+	for _, job := range jobs {
+		if job.Stage == "" {
+			job.Stage = defaultStage
+		}
+
+		if job.When == "" {
+			job.When = whenOnSuccess
+		}
+
+		if !slices.Contains(stages, job.Stage) {
+			stages = append(stages, job.Stage)
+		}
+
+		sortedJobs = append(sortedJobs, job)
+	}
+
+	slices.SortStableFunc(sortedJobs, func(left PipelineJob, right PipelineJob) int {
+		return slices.Index(stages, left.Stage) - slices.Index(stages, right.Stage)
+	})
+
+	return sortedJobs
+}
+
+// processPipeline enqueues the created jobs whose previous stages or needed jobs are finished, depending on
+// their result, and updates the status of the pipeline. Jobs are processed until no job changes anymore, since
+// enqueuing a job can skip jobs that depend on it.
+func (mock *GitlabMock) processPipeline(pipeline *gitlab.Pipeline) {
+	jobs := mock.pipelineJobs(pipeline, false)
+	stages := mock.pipelineStages(pipeline)
+
+	for changed := true; changed; {
+		changed = false
+
+		for _, job := range jobs {
+			if job.Status != string(gitlab.Created) {
+				continue
+			}
+
+			dependencies := mock.jobDependencies(job, jobs, stages)
+
+			dependenciesStatus := compositeStatus(dependencies)
+			if !pipelineFinished(dependenciesStatus) {
+				continue
+			}
+
+			dependenciesFailed := dependenciesStatus == "failed" || dependenciesStatus == "canceled"
+
+			mock.transitionJob(job, enqueuedStatus(mock.jobSpecs[job.ID].When, dependenciesFailed))
+			changed = true
+		}
+	}
+
+	mock.updatePipelineStatus(pipeline, jobs)
+}
+
+// jobDependencies returns the jobs the job waits for: the needed jobs or the jobs of all previous stages.
+func (mock *GitlabMock) jobDependencies(job *gitlab.Job, jobs []*gitlab.Job, stages []string) []*gitlab.Job {
+	needs := mock.jobSpecs[job.ID].Needs
+	hasNeeds := needs != nil
+
+	dependencies := []*gitlab.Job{}
+
+	for _, other := range jobs {
+		if hasNeeds && slices.Contains(needs, other.Name) {
+			dependencies = append(dependencies, other)
+		} else if !hasNeeds && slices.Index(stages, other.Stage) < slices.Index(stages, job.Stage) {
+			dependencies = append(dependencies, other)
+		}
+	}
+
+	return dependencies
+}
+
+// enqueuedStatus returns the status a created job gets once its stage is reached.
+func enqueuedStatus(when string, previousFailed bool) gitlab.BuildStateValue {
+	switch when {
+	case whenAlways:
+		return gitlab.Pending
+	case whenOnFailure:
+		if previousFailed {
+			return gitlab.Pending
+		}
+	case whenManual:
+		if !previousFailed {
+			return gitlab.Manual
+		}
+	default:
+		if !previousFailed {
+			return gitlab.Pending
+		}
+	}
+
+	return gitlab.Skipped
+}
+
+func (mock *GitlabMock) updatePipelineStatus(pipeline *gitlab.Pipeline, jobs []*gitlab.Job) {
+	now := mock.now()
+	statusBefore := pipeline.Status
+
+	pipeline.Status = compositeStatus(jobs)
+	pipeline.DetailedStatus = detailedStatus(pipeline.Status)
+	pipeline.UpdatedAt = &now
+
+	if pipeline.StartedAt == nil && pipeline.Status == string(gitlab.Running) {
+		pipeline.StartedAt = &now
+	}
+
+	if pipelineFinished(pipeline.Status) {
+		if pipeline.FinishedAt == nil {
+			pipeline.FinishedAt = &now
+		}
+
+		if pipeline.StartedAt != nil {
+			pipeline.Duration = int(pipeline.FinishedAt.Sub(*pipeline.StartedAt).Seconds())
+		}
+	} else {
+		pipeline.FinishedAt = nil
+	}
+
+	for _, job := range mock.jobs[pipeline.ID] {
+		job.Pipeline.Status = pipeline.Status
+	}
+	if pipeline.Status != statusBefore {
+		mock.firePipelineHooks(pipeline)
+		publishedPipeline := *pipeline
+		mock.publish(PipelineStatusChanged{Pipeline: &publishedPipeline, OldStatus: statusBefore})
+	}
+}
+
+// retryPipeline retries the failed and canceled jobs of the pipeline.
+func (mock *GitlabMock) retryPipeline(project *gitlab.Project, pipeline *gitlab.Pipeline, user *gitlab.User) (*gitlab.Pipeline, error) {
+	if !mock.hasAccess(project, user, gitlab.DeveloperPermissions) {
+		return nil, newAPIError(http.StatusForbidden, "403 Forbidden")
+	}
+
+	stages := mock.pipelineStages(pipeline)
+	firstStage := len(stages)
+
+	for _, job := range mock.pipelineJobs(pipeline, false) {
+		if job.Status == string(gitlab.Failed) || job.Status == string(gitlab.Canceled) {
+			mock.recreateJob(pipeline, job, user)
+			firstStage = min(firstStage, slices.Index(stages, job.Stage))
+		}
+	}
+
+	if firstStage < len(stages) {
+		mock.resetSkippedJobs(pipeline, stages[firstStage])
+		mock.processPipeline(pipeline)
+	}
+
+	return pipeline, nil
+}
+
+// cancelPipeline cancels all created, pending and running jobs of the pipeline.
+func (mock *GitlabMock) cancelPipeline(project *gitlab.Project, pipeline *gitlab.Pipeline, user *gitlab.User) (*gitlab.Pipeline, error) {
+	if !mock.hasAccess(project, user, gitlab.DeveloperPermissions) {
+		return nil, newAPIError(http.StatusForbidden, "403 Forbidden")
+	}
+
+	for _, job := range mock.pipelineJobs(pipeline, false) {
+		if jobCancelable(job) {
+			mock.transitionJob(job, gitlab.Canceled)
+		}
+	}
+
+	mock.processPipeline(pipeline)
+
+	return pipeline, nil
+}
+
+// deletePipeline removes the pipeline together with its jobs. Only owners may delete pipelines.
+func (mock *GitlabMock) deletePipeline(project *gitlab.Project, pipeline *gitlab.Pipeline, user *gitlab.User) error {
+	if !mock.hasAccess(project, user, gitlab.OwnerPermissions) {
+		return newAPIError(http.StatusForbidden, "403 Forbidden")
+	}
+
+	for _, job := range mock.jobs[pipeline.ID] {
+		delete(mock.jobSpecs, job.ID)
+		delete(mock.retriedJobs, job.ID)
+	}
+
+	delete(mock.jobs, pipeline.ID)
+	delete(mock.pipelineVariables, pipeline.ID)
+
+	mock.pipelines[project.ID] = slices.DeleteFunc(mock.pipelines[project.ID], func(other *gitlab.Pipeline) bool {
+		return other.ID == pipeline.ID
+	})
+
+	return nil
+}
+
+// latestPipeline returns the newest pipeline for the ref, which defaults to the default branch.
+func (mock *GitlabMock) latestPipeline(project *gitlab.Project, ref string) (*gitlab.Pipeline, error) {
+	if ref == "" {
+		ref = project.DefaultBranch
+	}
+
+	var latest *gitlab.Pipeline
+
+	for _, pipeline := range mock.pipelines[project.ID] {
+		if pipeline.Ref == ref && (latest == nil || pipeline.ID > latest.ID) {
+			latest = pipeline
+		}
+	}
+
+	if latest == nil {
+		return nil, newAPIError(http.StatusNotFound, "404 Not found")
+	}
+
+	return latest, nil
+}
+
+func toPipelineVariables(options *[]*gitlab.PipelineVariableOptions) []*gitlab.PipelineVariable {
+	variables := []*gitlab.PipelineVariable{}
+
+	if options == nil {
+		return variables
+	}
+
+	for _, option := range *options {
+		variableType := gitlab.EnvVariableType
+		if option.VariableType != nil {
+			variableType = *option.VariableType
+		}
+
+		variables = append(variables, &gitlab.PipelineVariable{
+			Key:          strings.TrimSpace(valueOf(option.Key)),
+			Value:        valueOf(option.Value),
+			VariableType: variableType,
+		})
+	}
+
+	return variables
+}
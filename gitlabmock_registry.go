@@ -0,0 +1,135 @@
+package gitlabapimock
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+const (
+	registryPull = "pull"
+	registryPush = "push"
+
+	registryTokenIssuer   = "gitlab-issuer"
+	registryTokenLifetime = 5 * time.Minute
+)
+
+// registryAccess is an access claim of a container registry token, see
+// https://distribution.github.io/distribution/spec/auth/jwt/.
+type registryAccess struct {
+	Type    string   `json:"type"`
+	Name    string   `json:"name"`
+	Actions []string `json:"actions"`
+}
+
+// registryCredentials are the credentials a container registry token is requested with. Either the deploy token
+// or the user and the scopes of their token are set, CI_JOB_TOKENs are not scoped. All of them are unset for
+// anonymous requests.
+type registryCredentials struct {
+	deployToken *deployToken
+	user        *gitlab.User
+	scopes      []string
+	scoped      bool
+}
+
+// username returns the name of the credentials which is the subject of the registry token.
+func (credentials *registryCredentials) username() string {
+	switch {
+	case credentials.deployToken != nil:
+		return credentials.deployToken.Username
+	case credentials.user != nil:
+		return credentials.user.Username
+	}
+
+	return ""
+}
+
+// registryProject resolves the project of a container repository, images may be nested below the path of the
+// project, e.g. group/project/image.
+func (mock *GitlabMock) registryProject(repository string) (*gitlab.Project, bool) {
+	for path := repository; path != ""; {
+		project, err := mock.GetProjectByPath(path)
+		if err == nil {
+			return project, true
+		}
+
+		index := strings.LastIndex(path, "/")
+		if index < 0 {
+			break
+		}
+
+		path = path[:index]
+	}
+
+	return nil, false
+}
+
+// registryActions returns the requested actions the credentials are granted in the container repositories of
+// the project. Deploy tokens need read_registry to pull and write_registry to push, other tokens may also have
+// the api scope, and users need to be reporters to pull and developers to push.
+func (mock *GitlabMock) registryActions(credentials *registryCredentials, project *gitlab.Project, actions []string) []string {
+	granted := []string{}
+
+	for _, action := range actions {
+		if action != registryPull && action != registryPush {
+			continue
+		}
+
+		scope := "read_registry"
+		accessLevel := gitlab.ReporterPermissions
+		if action == registryPush {
+			scope = "write_registry"
+			accessLevel = gitlab.DeveloperPermissions
+		}
+
+		var allowed bool
+
+		switch {
+		case credentials.deployToken != nil:
+			allowed = slices.Contains(credentials.deployToken.Scopes, scope) && mock.deployTokenAllowsProject(credentials.deployToken, project)
+		case credentials.scoped && !slices.Contains(credentials.scopes, "api") && !slices.Contains(credentials.scopes, scope):
+			allowed = false
+		case action == registryPull && project.Visibility == gitlab.PublicVisibility:
+			allowed = true
+		default:
+			allowed = mock.hasAccess(project, credentials.user, accessLevel)
+		}
+
+		if allowed {
+			granted = append(granted, action)
+		}
+	}
+
+	return granted
+}
+
+// signRegistryToken issues a JSON web token for the container registry granting the access, signed with the key
+// of the mock.
+func (mock *GitlabMock) signRegistryToken(subject string, audience string, access []*registryAccess) (string, time.Time) {
+	issuedAt := mock.now()
+
+	header, _ := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]interface{}{
+		"iss":    registryTokenIssuer,
+		"sub":    subject,
+		"aud":    audience,
+		"iat":    issuedAt.Unix(),
+		"nbf":    issuedAt.Unix(),
+		"exp":    issuedAt.Add(registryTokenLifetime).Unix(),
+		"jti":    newToken(""),
+		"access": access,
+	})
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+
+	signature := hmac.New(sha256.New, mock.registryTokenKey)
+	signature.Write([]byte(unsigned))
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature.Sum(nil)), issuedAt
+}
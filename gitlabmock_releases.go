@@ -0,0 +1,349 @@
+package gitlabapimock
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// release extends gitlab.Release with the milestones and evidences GitLab returns, which the go-gitlab type lacks.
+type release struct {
+	gitlab.Release
+	Milestones []*gitlab.Milestone `json:"milestones,omitempty"`
+	Evidences  []*releaseEvidence  `json:"evidences"`
+}
+
+// releaseEvidence implements https://docs.gitlab.com/ee/user/project/releases/release_evidence.html
+type releaseEvidence struct {
+	SHA         string     `json:"sha"`
+	Filepath    string     `json:"filepath"`
+	CollectedAt *time.Time `json:"collected_at"`
+}
+
+// refresh updates the fields of the release that depend on the current time. Evidence is collected
+// as soon as an upcoming release has been released.
+func (release *release) refresh(project *gitlab.Project, now time.Time) {
+	release.UpcomingRelease = release.ReleasedAt != nil && release.ReleasedAt.After(now)
+	release.Assets.Count = len(release.Assets.Links)
+
+	if !release.UpcomingRelease && len(release.Evidences) == 0 {
+		release.collectEvidence(project, now)
+	}
+}
+
+// collectEvidence takes a snapshot of the release and adds it to the evidences.
+func (release *release) collectEvidence(project *gitlab.Project, now time.Time) *releaseEvidence {
+	collectedAt := now
+
+	snapshot, _ := json.Marshal(release.Release)
+	sum := sha256.Sum256(snapshot)
+
+	evidence := &releaseEvidence{
+		SHA:         hex.EncodeToString(sum[:]),
+		Filepath:    fmt.Sprintf("/%s/-/releases/%s/evidences/%d.json", project.PathWithNamespace, release.TagName, len(release.Evidences)+1),
+		CollectedAt: &collectedAt,
+	}
+
+	release.Evidences = append(release.Evidences, evidence)
+
+	return evidence
+}
+
+func toMilestones(titles *[]string) []*gitlab.Milestone {
+	if titles == nil {
+		return nil
+	}
+
+	milestones := []*gitlab.Milestone{}
+	for _, title := range *titles {
+		milestones = append(milestones, &gitlab.Milestone{Title: title})
+	}
+
+	return milestones
+}
+
+func (mock *GitlabMock) getRelease(project *gitlab.Project, tagName string) (*release, error) {
+	for _, release := range mock.releases[project.ID] {
+		if release.TagName == tagName {
+			release.refresh(project, mock.now())
+			return release, nil
+		}
+	}
+
+	return nil, newAPIError(http.StatusNotFound, "404 Not Found")
+}
+
+// getReleases returns the releases of the project ordered by released_at or created_at, newest first
+// unless sort is "asc".
+func (mock *GitlabMock) getReleases(project *gitlab.Project, orderBy string, sortOrder string) []*release {
+	releases := make([]*release, 0, len(mock.releases[project.ID]))
+
+	for _, release := range mock.releases[project.ID] {
+		release.refresh(project, mock.now())
+		releases = append(releases, release)
+	}
+
+	sort.SliceStable(releases, func(i, j int) bool {
+		left, right := releases[i].ReleasedAt, releases[j].ReleasedAt
+		if orderBy == "created_at" {
+			left, right = releases[i].CreatedAt, releases[j].CreatedAt
+		}
+
+		if sortOrder == "asc" {
+			return left.Before(*right)
+		}
+
+		return left.After(*right)
+	})
+
+	return releases
+}
+
+// getLatestRelease returns the latest release that is not upcoming.
+func (mock *GitlabMock) getLatestRelease(project *gitlab.Project) (*release, error) {
+	for _, release := range mock.getReleases(project, "released_at", "desc") {
+		if !release.UpcomingRelease {
+			return release, nil
+		}
+	}
+
+	return nil, newAPIError(http.StatusNotFound, "404 Not Found")
+}
+
+// AddRelease creates a release for the tag. The tag is created from the ref if it does not exist yet.
+func (mock *GitlabMock) AddRelease(project *gitlab.Project, options *gitlab.CreateReleaseOptions, user *gitlab.User) (*gitlab.Release, error) {
+	release, err := mock.createRelease(project, options, user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &release.Release, nil
+}
+
+func (mock *GitlabMock) createRelease(project *gitlab.Project, options *gitlab.CreateReleaseOptions, user *gitlab.User) (*release, error) {
+	if options.TagName == nil || *options.TagName == "" {
+		return nil, newAPIError(http.StatusBadRequest, "tag_name is missing")
+	}
+
+	if !mock.hasAccess(project, user, gitlab.DeveloperPermissions) {
+		return nil, newAPIError(http.StatusForbidden, "403 Forbidden")
+	}
+
+	tagName := *options.TagName
+
+	_, err := mock.getRelease(project, tagName)
+	if err == nil {
+		return nil, newAPIError(http.StatusConflict, "Release already exists")
+	}
+
+	tag, err := mock.GetTag(project, tagName)
+	if err != nil {
+		if options.Ref == nil {
+			return nil, newAPIError(http.StatusBadRequest, "Ref is not specified")
+		}
+
+		tag, err = mock.AddTag(project, tagName, *options.Ref, valueOf(options.TagMessage), user)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	createdAt := mock.now()
+	releasedAt := createdAt
+	if options.ReleasedAt != nil {
+		releasedAt = *options.ReleasedAt
+	}
+
+	release := &release{
+		Milestones: toMilestones(options.Milestones),
+		Evidences:  []*releaseEvidence{},
+	}
+
+	release.TagName = tagName
+	release.Name = tagName
+	if options.Name != nil {
+		release.Name = *options.Name
+	}
+	release.Description = valueOf(options.Description)
+	release.CreatedAt = &createdAt
+	release.ReleasedAt = &releasedAt
+	release.Commit = *tag.Commit
+	release.CommitPath = fmt.Sprintf("/%s/-/commit/%s", project.PathWithNamespace, tag.Commit.ID)
+	release.TagPath = fmt.Sprintf("/%s/-/tags/%s", project.PathWithNamespace, tagName)
+	release.Assets.Links = []*gitlab.ReleaseLink{}
+
+	if user != nil {
+		release.Author.ID = user.ID
+		release.Author.Name = user.Name
+		release.Author.Username = user.Username
+		release.Author.State = user.State
+	}
+
+	if options.Assets != nil {
+		for _, linkOptions := range options.Assets.Links {
+			_, err = mock.createReleaseLink(release, &gitlab.CreateReleaseLinkOptions{
+				Name:            linkOptions.Name,
+				URL:             linkOptions.URL,
+				FilePath:        linkOptions.FilePath,
+				DirectAssetPath: linkOptions.DirectAssetPath,
+				LinkType:        linkOptions.LinkType,
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	release.refresh(project, mock.now())
+
+	mock.releases[project.ID] = append(mock.releases[project.ID], release)
+
+	return release, nil
+}
+
+func (mock *GitlabMock) updateRelease(project *gitlab.Project, tagName string, options *gitlab.UpdateReleaseOptions, user *gitlab.User) (*release, error) {
+	release, err := mock.getRelease(project, tagName)
+	if err != nil {
+		return nil, err
+	}
+
+	if !mock.hasAccess(project, user, gitlab.DeveloperPermissions) {
+		return nil, newAPIError(http.StatusForbidden, "403 Forbidden")
+	}
+
+	if options.Name != nil {
+		release.Name = *options.Name
+	}
+
+	if options.Description != nil {
+		release.Description = *options.Description
+	}
+
+	if options.Milestones != nil {
+		release.Milestones = toMilestones(options.Milestones)
+	}
+
+	if options.ReleasedAt != nil {
+		releasedAt := *options.ReleasedAt
+		release.ReleasedAt = &releasedAt
+	}
+
+	release.refresh(project, mock.now())
+
+	return release, nil
+}
+
+func (mock *GitlabMock) removeRelease(project *gitlab.Project, tagName string) (*release, error) {
+	releases := mock.releases[project.ID]
+
+	for idx, release := range releases {
+		if release.TagName == tagName {
+			mock.releases[project.ID] = append(releases[:idx:idx], releases[idx+1:]...)
+			return release, nil
+		}
+	}
+
+	return nil, newAPIError(http.StatusNotFound, "404 Not Found")
+}
+
+func (mock *GitlabMock) createReleaseLink(release *release, options *gitlab.CreateReleaseLinkOptions) (*gitlab.ReleaseLink, error) {
+	if options.Name == nil || options.URL == nil {
+		return nil, newAPIError(http.StatusBadRequest, "name is missing, url is missing")
+	}
+
+	for _, link := range release.Assets.Links {
+		if link.Name == *options.Name {
+			return nil, newAPIError(http.StatusBadRequest, "Name has already been taken")
+		}
+
+		if link.URL == *options.URL {
+			return nil, newAPIError(http.StatusBadRequest, "Url has already been taken")
+		}
+	}
+
+	link := &gitlab.ReleaseLink{
+		ID:       int(mock.releaseLinkIds.Add(1)),
+		Name:     *options.Name,
+		URL:      *options.URL,
+		External: true,
+		LinkType: gitlab.OtherLinkType,
+	}
+
+	directAssetPath := options.DirectAssetPath
+	if directAssetPath == nil {
+		directAssetPath = options.FilePath
+	}
+
+	link.DirectAssetURL = link.URL
+	if directAssetPath != nil {
+		link.DirectAssetURL = fmt.Sprintf("%s/downloads%s", release.TagPath, *directAssetPath)
+	}
+
+	if options.LinkType != nil {
+		link.LinkType = *options.LinkType
+	}
+
+	release.Assets.Links = append(release.Assets.Links, link)
+	release.Assets.Count = len(release.Assets.Links)
+
+	return link, nil
+}
+
+func getReleaseLink(release *release, linkID int) (*gitlab.ReleaseLink, error) {
+	for _, link := range release.Assets.Links {
+		if link.ID == linkID {
+			return link, nil
+		}
+	}
+
+	return nil, newAPIError(http.StatusNotFound, "404 Not found")
+}
+
+func updateReleaseLink(release *release, linkID int, options *gitlab.UpdateReleaseLinkOptions) (*gitlab.ReleaseLink, error) {
+	link, err := getReleaseLink(release, linkID)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.Name != nil {
+		link.Name = *options.Name
+	}
+
+	if options.URL != nil {
+		link.URL = *options.URL
+		link.DirectAssetURL = link.URL
+	}
+
+	directAssetPath := options.DirectAssetPath
+	if directAssetPath == nil {
+		directAssetPath = options.FilePath
+	}
+
+	if directAssetPath != nil {
+		link.DirectAssetURL = fmt.Sprintf("%s/downloads%s", release.TagPath, *directAssetPath)
+	}
+
+	if options.LinkType != nil {
+		link.LinkType = *options.LinkType
+	}
+
+	return link, nil
+}
+
+func deleteReleaseLink(release *release, linkID int) (*gitlab.ReleaseLink, error) {
+	for idx, link := range release.Assets.Links {
+		if link.ID == linkID {
+			release.Assets.Links = append(release.Assets.Links[:idx:idx], release.Assets.Links[idx+1:]...)
+			release.Assets.Count = len(release.Assets.Links)
+			return link, nil
+		}
+	}
+
+	return nil, newAPIError(http.StatusNotFound, "404 Not found")
+}
@@ -0,0 +1,710 @@
+package gitlabapimock
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+const (
+	defaultBranch = "main"
+	zeroSHA       = "0000000000000000000000000000000000000000"
+)
+
+// repository is the bare Git repository backing a mock project. All operations shell out to the git binary.
+type repository struct {
+	path string
+}
+
+// refUpdate describes a single ref that was created, moved or deleted.
+type refUpdate struct {
+	Ref    string
+	Before string
+	After  string
+}
+
+func (repo *repository) git(env []string, stdin io.Reader, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = repo.path
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdin = stdin
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err != nil {
+		return nil, fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// refs returns all refs of the repository mapped to the object they point to.
+func (repo *repository) refs() (map[string]string, error) {
+	output, err := repo.git(nil, nil, "for-each-ref", "--format=%(objectname) %(refname)")
+	if err != nil {
+		return nil, err
+	}
+
+	refs := make(map[string]string)
+
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		sha, ref, found := strings.Cut(line, " ")
+		if found {
+			refs[ref] = sha
+		}
+	}
+
+	return refs, nil
+}
+
+// resolve returns the commit SHA the revision points to.
+func (repo *repository) resolve(revision string) (string, error) {
+	output, err := repo.git(nil, nil, "rev-parse", "--verify", "--quiet", revision+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("revision %s not found", revision)
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
+
+// commit returns the commit with the given SHA.
+func (repo *repository) commit(sha string) (*gitlab.Commit, error) {
+	output, err := repo.git(nil, nil, "show", "-s", "--format=%H%x00%P%x00%an%x00%ae%x00%aI%x00%cn%x00%ce%x00%cI%x00%s%x00%B", sha)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := strings.SplitN(string(output), "\x00", 10)
+	if len(fields) != 10 {
+		return nil, fmt.Errorf("unexpected output of git show for %s", sha)
+	}
+
+	authoredDate, _ := time.Parse(time.RFC3339, fields[4])
+	committedDate, _ := time.Parse(time.RFC3339, fields[7])
+
+	commit := &gitlab.Commit{
+		ID:             fields[0],
+		ShortID:        fields[0][:8],
+		ParentIDs:      strings.Fields(fields[1]),
+		AuthorName:     fields[2],
+		AuthorEmail:    fields[3],
+		AuthoredDate:   &authoredDate,
+		CommitterName:  fields[5],
+		CommitterEmail: fields[6],
+		CommittedDate:  &committedDate,
+		CreatedAt:      &committedDate,
+		Title:          fields[8],
+		Message:        strings.TrimSuffix(fields[9], "\n"),
+	}
+
+	return commit, nil
+}
+
+// identityEnv returns the environment that makes git use the user as author and committer.
+func identityEnv(user *gitlab.User) []string {
+	name, email := "Administrator", "admin@example.com"
+	if user != nil {
+		name, email = user.Name, user.Email
+	}
+
+	return []string{
+		"GIT_AUTHOR_NAME=" + name, "GIT_AUTHOR_EMAIL=" + email,
+		"GIT_COMMITTER_NAME=" + name, "GIT_COMMITTER_EMAIL=" + email,
+	}
+}
+
+// commitFiles creates a commit on the branch that adds or replaces the given files. The branch is
+// created from the default branch if it does not exist yet.
+func (repo *repository) commitFiles(branch string, message string, files map[string]string, author *gitlab.User) (*refUpdate, error) {
+	indexFile, err := os.CreateTemp("", "gitlabapimock-index-")
+	if err != nil {
+		return nil, err
+	}
+	indexFile.Close()
+	os.Remove(indexFile.Name())
+	defer os.Remove(indexFile.Name())
+
+	env := append(identityEnv(author), "GIT_INDEX_FILE="+indexFile.Name())
+
+	ref := "refs/heads/" + branch
+
+	before, err := repo.resolve(ref)
+	if err != nil {
+		before = zeroSHA
+	}
+
+	parent := before
+	if before == zeroSHA {
+		parent, err = repo.resolve("HEAD")
+		if err != nil {
+			parent = ""
+		}
+	}
+
+	if parent != "" {
+		_, err = repo.git(env, nil, "read-tree", parent)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		blob, err := repo.git(env, strings.NewReader(files[path]), "hash-object", "-w", "--stdin")
+		if err != nil {
+			return nil, err
+		}
+
+		cacheInfo := fmt.Sprintf("100644,%s,%s", strings.TrimSpace(string(blob)), path)
+
+		_, err = repo.git(env, nil, "update-index", "--add", "--cacheinfo", cacheInfo)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	tree, err := repo.git(env, nil, "write-tree")
+	if err != nil {
+		return nil, err
+	}
+
+	commitTreeArgs := []string{"commit-tree", strings.TrimSpace(string(tree)), "-m", message}
+	if parent != "" {
+		commitTreeArgs = append(commitTreeArgs, "-p", parent)
+	}
+
+	sha, err := repo.git(env, nil, commitTreeArgs...)
+	if err != nil {
+		return nil, err
+	}
+
+	update := &refUpdate{
+		Ref:    ref,
+		Before: before,
+		After:  strings.TrimSpace(string(sha)),
+	}
+
+	_, err = repo.git(nil, nil, "update-ref", update.Ref, update.After, strings.TrimPrefix(update.Before, zeroSHA))
+	if err != nil {
+		return nil, err
+	}
+
+	return update, nil
+}
+
+// updateRef points the ref to the SHA, or deletes it when the SHA is the zero SHA.
+func (repo *repository) updateRef(ref string, sha string) (*refUpdate, error) {
+	before, err := repo.resolveRef(ref)
+	if err != nil {
+		before = zeroSHA
+	}
+
+	if sha == zeroSHA {
+		_, err = repo.git(nil, nil, "update-ref", "-d", ref)
+	} else {
+		_, err = repo.git(nil, nil, "update-ref", ref, sha)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &refUpdate{Ref: ref, Before: before, After: sha}, nil
+}
+
+// resolveRef returns the object the ref points to without peeling annotated tags.
+func (repo *repository) resolveRef(ref string) (string, error) {
+	output, err := repo.git(nil, nil, "rev-parse", "--verify", "--quiet", ref)
+	if err != nil {
+		return "", fmt.Errorf("ref %s not found", ref)
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
+
+// createTag creates a lightweight tag, or an annotated tag when a message is given.
+func (repo *repository) createTag(name string, sha string, message string, tagger *gitlab.User) (*refUpdate, error) {
+	args := []string{"tag", name, sha}
+	if message != "" {
+		args = []string{"tag", "-a", "-m", message, name, sha}
+	}
+
+	_, err := repo.git(identityEnv(tagger), nil, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	after, err := repo.resolveRef("refs/tags/" + name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &refUpdate{Ref: "refs/tags/" + name, Before: zeroSHA, After: after}, nil
+}
+
+// repositoryTag is a tag as stored in the repository.
+type repositoryTag struct {
+	Name    string
+	Target  string
+	Commit  string
+	Message string
+}
+
+// tags returns the tags of the repository.
+func (repo *repository) tags() ([]*repositoryTag, error) {
+	output, err := repo.git(nil, nil, "for-each-ref", "refs/tags", "--format=%(refname:strip=2)%00%(objectname)%00%(*objectname)%00%(contents)%00%00")
+	if err != nil {
+		return nil, err
+	}
+
+	var tags []*repositoryTag
+
+	for _, record := range strings.Split(string(output), "\x00\x00\n") {
+		fields := strings.SplitN(record, "\x00", 4)
+		if len(fields) != 4 {
+			continue
+		}
+
+		tag := &repositoryTag{
+			Name:    fields[0],
+			Target:  fields[1],
+			Commit:  fields[2],
+			Message: strings.TrimSuffix(fields[3], "\n"),
+		}
+
+		// Lightweight tags point to the commit directly and have no message of their own.
+		if tag.Commit == "" {
+			tag.Commit = tag.Target
+			tag.Message = ""
+		}
+
+		tags = append(tags, tag)
+	}
+
+	return tags, nil
+}
+
+// diffRefs compares two ref snapshots and returns the refs that changed in between.
+func diffRefs(before map[string]string, after map[string]string) []refUpdate {
+	var updates []refUpdate
+
+	for ref, sha := range after {
+		if before[ref] != sha {
+			updates = append(updates, refUpdate{Ref: ref, Before: before[ref], After: sha})
+		}
+	}
+
+	for ref, sha := range before {
+		if _, exists := after[ref]; !exists {
+			updates = append(updates, refUpdate{Ref: ref, Before: sha, After: zeroSHA})
+		}
+	}
+
+	for idx := range updates {
+		if updates[idx].Before == "" {
+			updates[idx].Before = zeroSHA
+		}
+	}
+
+	sort.Slice(updates, func(i, j int) bool {
+		return updates[i].Ref < updates[j].Ref
+	})
+
+	return updates
+}
+
+// getRepository returns the repository of the project and initializes it on first use.
+func (mock *GitlabMock) getRepository(project *gitlab.Project) (*repository, error) {
+	repo, exists := mock.repositories[project.ID]
+	if exists {
+		return repo, nil
+	}
+
+	if mock.repositoriesDir == "" {
+		dir, err := os.MkdirTemp("", "gitlabapimock-")
+		if err != nil {
+			return nil, err
+		}
+
+		mock.repositoriesDir = dir
+	}
+
+	repo = &repository{
+		path: filepath.Join(mock.repositoriesDir, fmt.Sprintf("%d.git", project.ID)),
+	}
+
+	err := os.MkdirAll(repo.path, 0o755)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = repo.git(nil, nil, "init", "--bare", "--quiet", "--initial-branch="+defaultBranch)
+	if err != nil {
+		return nil, err
+	}
+
+	mock.repositories[project.ID] = repo
+
+	return repo, nil
+}
+
+// CommitFiles commits the files to the branch of the project repository, as if the author pushed them.
+func (mock *GitlabMock) CommitFiles(project *gitlab.Project, branch string, message string, files map[string]string, author *gitlab.User) (*gitlab.Commit, error) {
+	repo, err := mock.getRepository(project)
+	if err != nil {
+		return nil, err
+	}
+
+	update, err := repo.commitFiles(branch, message, files, author)
+	if err != nil {
+		return nil, err
+	}
+
+	mock.applyRefUpdates(project, author, []refUpdate{*update})
+
+	commit, err := repo.commit(update.After)
+	if err != nil {
+		return nil, err
+	}
+
+	commit.ProjectID = project.ID
+
+	return commit, nil
+}
+
+// applyRefUpdates updates the project after refs of its repository have been changed, either by a
+// push over HTTP or by the Go API.
+func (mock *GitlabMock) applyRefUpdates(project *gitlab.Project, user *gitlab.User, updates []refUpdate) {
+	if len(updates) == 0 {
+		return
+	}
+
+	repo, err := mock.getRepository(project)
+	if err != nil {
+		return
+	}
+
+	if project.DefaultBranch == "" {
+		for _, update := range updates {
+			branch, isBranch := strings.CutPrefix(update.Ref, "refs/heads/")
+			if isBranch && update.After != zeroSHA {
+				project.DefaultBranch = branch
+				repo.git(nil, nil, "symbolic-ref", "HEAD", update.Ref)
+				break
+			}
+		}
+	}
+
+	refs, err := repo.refs()
+	if err == nil {
+		project.EmptyRepo = len(refs) == 0
+	}
+
+	mock.firePushHooks(project, repo, user, updates)
+	mock.addMergeRequestCommitNotes(project, repo, user, updates)
+
+	lastActivityAt := mock.now()
+	project.LastActivityAt = &lastActivityAt
+}
+
+// branches returns the branches of the project repository sorted by name.
+func (mock *GitlabMock) branches(project *gitlab.Project) ([]*gitlab.Branch, error) {
+	repo, err := mock.getRepository(project)
+	if err != nil {
+		return nil, err
+	}
+
+	refs, err := repo.refs()
+	if err != nil {
+		return nil, err
+	}
+
+	branches := []*gitlab.Branch{}
+
+	for ref, sha := range refs {
+		name, isBranch := strings.CutPrefix(ref, "refs/heads/")
+		if !isBranch {
+			continue
+		}
+
+		commit, err := repo.commit(sha)
+		if err != nil {
+			return nil, err
+		}
+
+		branches = append(branches, &gitlab.Branch{
+			Name:    name,
+			Commit:  commit,
+			Default: name == project.DefaultBranch,
+			CanPush: true,
+		})
+	}
+
+	sort.Slice(branches, func(i, j int) bool {
+		return branches[i].Name < branches[j].Name
+	})
+
+	return branches, nil
+}
+
+// serviceRPC runs git-upload-pack or git-receive-pack in stateless RPC mode as used by the smart HTTP protocol.
+func (repo *repository) serviceRPC(service string, advertiseRefs bool, stdin io.Reader, stdout io.Writer) error {
+	args := []string{strings.TrimPrefix(service, "git-"), "--stateless-rpc"}
+	if advertiseRefs {
+		args = append(args, "--advertise-refs")
+	}
+	args = append(args, ".")
+
+	var stderr bytes.Buffer
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = repo.path
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err != nil {
+		return fmt.Errorf("%s: %w: %s", service, err, strings.TrimSpace(stderr.String()))
+	}
+
+	return nil
+}
+
+// mergeBase returns the best common ancestor of both commits.
+func (repo *repository) mergeBase(left string, right string) (string, error) {
+	output, err := repo.git(nil, nil, "merge-base", left, right)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
+
+// isAncestor reports whether the ancestor commit is reachable from the descendant commit.
+func (repo *repository) isAncestor(ancestor string, descendant string) bool {
+	_, err := repo.git(nil, nil, "merge-base", "--is-ancestor", ancestor, descendant)
+	return err == nil
+}
+
+// countCommits returns the number of commits reachable from head but not from base.
+func (repo *repository) countCommits(base string, head string) int {
+	output, err := repo.git(nil, nil, "rev-list", "--count", base+".."+head)
+	if err != nil {
+		return 0
+	}
+
+	count, _ := strconv.Atoi(strings.TrimSpace(string(output)))
+
+	return count
+}
+
+// commitsBetween returns the commits reachable from head but not from base, newest first.
+func (repo *repository) commitsBetween(base string, head string) ([]*gitlab.Commit, error) {
+	output, err := repo.git(nil, nil, "rev-list", base+".."+head)
+	if err != nil {
+		return nil, err
+	}
+
+	commits := []*gitlab.Commit{}
+
+	for _, sha := range strings.Fields(string(output)) {
+		commit, err := repo.commit(sha)
+		if err != nil {
+			return nil, err
+		}
+
+		commits = append(commits, commit)
+	}
+
+	return commits, nil
+}
+
+// mergeTree merges both commits without touching any ref and returns the resulting tree together
+// with the paths that conflict.
+func (repo *repository) mergeTree(left string, right string) (string, []string, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.Command("git", "merge-tree", "--write-tree", "--name-only", "--no-messages", left, right)
+	cmd.Dir = repo.path
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+
+	var exitErr *exec.ExitError
+	if err != nil && !(errors.As(err, &exitErr) && exitErr.ExitCode() == 1) {
+		return "", nil, fmt.Errorf("git merge-tree: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+
+	return lines[0], lines[1:], nil
+}
+
+// treeOf returns the tree of the commit.
+func (repo *repository) treeOf(sha string) (string, error) {
+	output, err := repo.git(nil, nil, "rev-parse", sha+"^{tree}")
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
+
+// commitTree creates a commit for the tree with the given parents without updating any ref.
+func (repo *repository) commitTree(tree string, parents []string, message string, user *gitlab.User) (string, error) {
+	args := []string{"commit-tree", tree, "-m", message}
+	for _, parent := range parents {
+		args = append(args, "-p", parent)
+	}
+
+	output, err := repo.git(identityEnv(user), nil, args...)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
+
+// rebase replays the commits of head that are not in onto on top of onto and returns the new head.
+// It uses a temporary worktree, since rebasing is not possible in a bare repository.
+func (repo *repository) rebase(onto string, head string, user *gitlab.User) (string, error) {
+	worktree, err := os.MkdirTemp("", "gitlabapimock-worktree-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(worktree)
+
+	_, err = repo.git(nil, nil, "worktree", "add", "--detach", worktree, head)
+	if err != nil {
+		return "", err
+	}
+	defer repo.git(nil, nil, "worktree", "remove", "--force", worktree)
+
+	_, err = repo.git(identityEnv(user), nil, "-C", worktree, "rebase", onto)
+	if err != nil {
+		repo.git(nil, nil, "-C", worktree, "rebase", "--abort")
+		return "", err
+	}
+
+	output, err := repo.git(nil, nil, "-C", worktree, "rev-parse", "HEAD")
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
+
+// diff returns the changes between both commits file by file, in the format of the merge request changes.
+func (repo *repository) diff(base string, head string) ([]*gitlab.MergeRequestDiff, error) {
+	output, err := repo.git(nil, nil, "diff", "--raw", "-z", "-M", base, head)
+	if err != nil {
+		return nil, err
+	}
+
+	diffs := []*gitlab.MergeRequestDiff{}
+
+	fields := strings.Split(strings.TrimSuffix(string(output), "\x00"), "\x00")
+	for idx := 0; idx+1 < len(fields); idx += 2 {
+		// :<old mode> <new mode> <old sha> <new sha> <status>
+		header := strings.Fields(strings.TrimPrefix(fields[idx], ":"))
+		if len(header) != 5 {
+			continue
+		}
+
+		status := header[4][:1]
+
+		diff := &gitlab.MergeRequestDiff{
+			OldPath:     fields[idx+1],
+			NewPath:     fields[idx+1],
+			AMode:       header[0],
+			BMode:       header[1],
+			NewFile:     status == "A",
+			DeletedFile: status == "D",
+		}
+
+		paths := []string{diff.OldPath}
+
+		if status == "R" || status == "C" {
+			idx++
+			diff.NewPath = fields[idx+1]
+			diff.RenamedFile = status == "R"
+			paths = append(paths, diff.NewPath)
+		}
+
+		patch, err := repo.git(nil, nil, append([]string{"diff", "-M", base, head, "--"}, paths...)...)
+		if err != nil {
+			return nil, err
+		}
+
+		hunks := string(patch)
+		if start := strings.Index(hunks, "\n@@"); start >= 0 {
+			hunks = hunks[start+1:]
+		} else {
+			hunks = ""
+		}
+
+		diff.Diff = hunks
+
+		diffs = append(diffs, diff)
+	}
+
+	return diffs, nil
+}
+
+// readFile returns the content of the file at the revision.
+func (repo *repository) readFile(revision string, path string) ([]byte, error) {
+	output, err := repo.git(nil, nil, "cat-file", "blob", revision+":"+path)
+	if err != nil {
+		return nil, fmt.Errorf("file %s not found at %s", path, revision)
+	}
+
+	return output, nil
+}
+
+// files returns the paths of all files at the revision.
+func (repo *repository) files(revision string) ([]string, error) {
+	output, err := repo.git(nil, nil, "ls-tree", "-r", "--name-only", revision)
+	if err != nil {
+		return nil, err
+	}
+
+	return splitLines(output), nil
+}
+
+// changedFiles returns the paths of the files that differ between the revisions.
+func (repo *repository) changedFiles(base string, head string) ([]string, error) {
+	output, err := repo.git(nil, nil, "diff", "--name-only", base, head)
+	if err != nil {
+		return nil, err
+	}
+
+	return splitLines(output), nil
+}
+
+func splitLines(output []byte) []string {
+	trimmed := strings.TrimSpace(string(output))
+	if trimmed == "" {
+		return []string{}
+	}
+
+	return strings.Split(trimmed, "\n")
+}
@@ -0,0 +1,904 @@
+package gitlabapimock
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+const (
+	runnerTypeInstance = "instance_type"
+	runnerTypeGroup    = "group_type"
+	runnerTypeProject  = "project_type"
+
+	runnerAccessLevelNotProtected = "not_protected"
+	runnerAccessLevelRefProtected = "ref_protected"
+
+	runnerStatusOnline         = "online"
+	runnerStatusOffline        = "offline"
+	runnerStatusStale          = "stale"
+	runnerStatusNeverContacted = "never_contacted"
+
+	// Runners are online while they ask for jobs regularly and stale once they have not done so for a week.
+	runnerOnlineTimeout = 2 * time.Hour
+	runnerStaleTimeout  = 7 * 24 * time.Hour
+
+	runnerRegistrationTokenPrefix   = "GR1348941"
+	runnerAuthenticationTokenPrefix = "glrt-"
+
+	defaultJobTimeout = 3600
+)
+
+// runnerProject and runnerGroup name the anonymous structs of the projects and groups a runner is assigned to.
+type runnerProject = struct {
+	ID                int    `json:"id"`
+	Name              string `json:"name"`
+	NameWithNamespace string `json:"name_with_namespace"`
+	Path              string `json:"path"`
+	PathWithNamespace string `json:"path_with_namespace"`
+}
+
+type runnerGroup = struct {
+	ID     int    `json:"id"`
+	Name   string `json:"name"`
+	WebURL string `json:"web_url"`
+}
+
+// registeredRunner is the response to the registration of a runner, the token authenticates the runner from
+// then on.
+type registeredRunner struct {
+	ID             int        `json:"id"`
+	Token          string     `json:"token"`
+	TokenExpiresAt *time.Time `json:"token_expires_at"`
+}
+
+// runnerInfo is the information about itself a runner sends with its requests.
+type runnerInfo struct {
+	Name         string `json:"name"`
+	Version      string `json:"version"`
+	Revision     string `json:"revision"`
+	Platform     string `json:"platform"`
+	Architecture string `json:"architecture"`
+}
+
+// runnerJob is the job payload a runner receives from POST /jobs/request, see JobResponse in
+// https://gitlab.com/gitlab-org/gitlab-runner/-/blob/main/common/network.go
+type runnerJob struct {
+	ID            int                    `json:"id"`
+	Token         string                 `json:"token"`
+	AllowGitFetch bool                   `json:"allow_git_fetch"`
+	JobInfo       runnerJobInfo          `json:"job_info"`
+	GitInfo       runnerGitInfo          `json:"git_info"`
+	RunnerInfo    runnerTimeout          `json:"runner_info"`
+	Variables     []*runnerJobVariable   `json:"variables"`
+	Steps         []*runnerJobStep       `json:"steps"`
+	Credentials   []*runnerCredentials   `json:"credentials"`
+	Dependencies  []*runnerJobDependency `json:"dependencies"`
+	Features      map[string]interface{} `json:"features"`
+}
+
+type runnerJobInfo struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Stage       string `json:"stage"`
+	ProjectID   int    `json:"project_id"`
+	ProjectName string `json:"project_name"`
+}
+
+type runnerGitInfo struct {
+	RepoURL   string   `json:"repo_url"`
+	Ref       string   `json:"ref"`
+	Sha       string   `json:"sha"`
+	BeforeSha string   `json:"before_sha"`
+	RefType   string   `json:"ref_type"`
+	Refspecs  []string `json:"refspecs"`
+	Depth     int      `json:"depth"`
+}
+
+type runnerTimeout struct {
+	Timeout int `json:"timeout"`
+}
+
+type runnerJobVariable struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Public bool   `json:"public"`
+	File   bool   `json:"file"`
+	Masked bool   `json:"masked"`
+	Raw    bool   `json:"raw"`
+}
+
+type runnerJobStep struct {
+	Name         string   `json:"name"`
+	Script       []string `json:"script"`
+	Timeout      int      `json:"timeout"`
+	When         string   `json:"when"`
+	AllowFailure bool     `json:"allow_failure"`
+}
+
+type runnerCredentials struct {
+	Type     string `json:"type"`
+	URL      string `json:"url"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type runnerJobDependency struct {
+	ID            int                  `json:"id"`
+	Name          string               `json:"name"`
+	Token         string               `json:"token"`
+	ArtifactsFile *runnerArtifactsFile `json:"artifacts_file,omitempty"`
+}
+
+type runnerArtifactsFile struct {
+	Filename string `json:"filename"`
+	Size     int    `json:"size"`
+}
+
+// GetRunnersRegistrationToken returns the token instance runners are registered with.
+func (mock *GitlabMock) GetRunnersRegistrationToken() string {
+	return mock.runnersRegistrationToken
+}
+
+// GetRunnerToken returns the authentication token the runner requests jobs with.
+func (mock *GitlabMock) GetRunnerToken(runner *gitlab.RunnerDetails) string {
+	return mock.runnerTokens[runner.ID]
+}
+
+// RegisterRunner registers a runner with the registration token of the instance, a group or a project, which
+// decides the type of the runner.
+func (mock *GitlabMock) RegisterRunner(options *gitlab.RegisterNewRunnerOptions) (*gitlab.RunnerDetails, error) {
+	token := valueOf(options.Token)
+
+	runner := &gitlab.RunnerDetails{
+		Description: valueOf(options.Description),
+		Paused:      valueOf(options.Paused) || (options.Active != nil && !*options.Active),
+		RunUntagged: true,
+		Locked:      valueOf(options.Locked),
+		AccessLevel: runnerAccessLevelNotProtected,
+		TagList:     []string{},
+	}
+
+	switch {
+	case token == mock.runnersRegistrationToken:
+		runner.RunnerType = runnerTypeInstance
+		runner.IsShared = true
+	default:
+		group, project := mock.registrationTokenOwner(token)
+
+		switch {
+		case group != nil:
+			runner.RunnerType = runnerTypeGroup
+			runner.Groups = []runnerGroup{{ID: group.ID, Name: group.Name, WebURL: group.WebURL}}
+		case project != nil:
+			runner.RunnerType = runnerTypeProject
+			runner.Projects = []runnerProject{toRunnerProject(project)}
+		default:
+			return nil, newAPIError(http.StatusForbidden, "403 Forbidden")
+		}
+	}
+
+	if options.TagList != nil {
+		runner.TagList = *options.TagList
+	}
+
+	if options.RunUntagged != nil {
+		runner.RunUntagged = *options.RunUntagged
+	}
+
+	if options.AccessLevel != nil {
+		runner.AccessLevel = *options.AccessLevel
+	}
+
+	if options.MaximumTimeout != nil {
+		runner.MaximumTimeout = *options.MaximumTimeout
+	}
+
+	if err := validateRunner(runner); err != nil {
+		return nil, err
+	}
+
+	if options.Info != nil {
+		runner.Name = valueOf(options.Info.Name)
+		runner.Version = valueOf(options.Info.Version)
+		runner.Revision = valueOf(options.Info.Revision)
+		runner.Platform = valueOf(options.Info.Platform)
+		runner.Architecture = valueOf(options.Info.Architecture)
+	}
+
+	runner.ID = int(mock.runnerIds.Add(1))
+	runner.Active = !runner.Paused
+
+	mock.runners = append(mock.runners, runner)
+	mock.runnerTokens[runner.ID] = newToken(runnerAuthenticationTokenPrefix)
+	mock.refreshRunnerStatus(runner)
+
+	return runner, nil
+}
+
+func toRunnerProject(project *gitlab.Project) runnerProject {
+	return runnerProject{
+		ID:                project.ID,
+		Name:              project.Name,
+		NameWithNamespace: project.NameWithNamespace,
+		Path:              project.Path,
+		PathWithNamespace: project.PathWithNamespace,
+	}
+}
+
+// validateRunner checks the options of a runner GitLab validates on registration and update.
+func validateRunner(runner *gitlab.RunnerDetails) error {
+	if runner.AccessLevel != runnerAccessLevelNotProtected && runner.AccessLevel != runnerAccessLevelRefProtected {
+		return newAPIError(http.StatusBadRequest, "access_level does not have a valid value")
+	}
+
+	if runner.MaximumTimeout != 0 && runner.MaximumTimeout < 600 {
+		return newAPIError(http.StatusBadRequest, map[string][]string{"maximum_timeout": {"needs to be at least 10 minutes"}})
+	}
+
+	if !runner.RunUntagged && len(runner.TagList) == 0 {
+		return newAPIError(http.StatusBadRequest, map[string][]string{"tags_list": {"can not be empty when runner is not allowed to pick untagged jobs"}})
+	}
+
+	return nil
+}
+
+// registrationTokenOwner returns the group or project the runner registration token belongs to.
+func (mock *GitlabMock) registrationTokenOwner(token string) (*gitlab.Group, *gitlab.Project) {
+	if token == "" {
+		return nil, nil
+	}
+
+	for _, group := range mock.groups {
+		if group.RunnersToken == token {
+			return group, nil
+		}
+	}
+
+	for _, project := range mock.projects {
+		if project.RunnersToken == token {
+			return nil, project
+		}
+	}
+
+	return nil, nil
+}
+
+// runnerByToken returns the runner the authentication token belongs to.
+func (mock *GitlabMock) runnerByToken(token string) (*gitlab.RunnerDetails, bool) {
+	if token == "" {
+		return nil, false
+	}
+
+	for _, runner := range mock.runners {
+		if mock.runnerTokens[runner.ID] == token {
+			return runner, true
+		}
+	}
+
+	return nil, false
+}
+
+func (mock *GitlabMock) getRunner(runnerID int) (*gitlab.RunnerDetails, error) {
+	for _, runner := range mock.runners {
+		if runner.ID == runnerID {
+			mock.refreshRunnerStatus(runner)
+			return runner, nil
+		}
+	}
+
+	return nil, newAPIError(http.StatusNotFound, "404 Runner Not Found")
+}
+
+// GetRunners returns all registered runners.
+func (mock *GitlabMock) GetRunners() []*gitlab.RunnerDetails {
+	for _, runner := range mock.runners {
+		mock.refreshRunnerStatus(runner)
+	}
+
+	return mock.runners
+}
+
+// refreshRunnerStatus derives the status of the runner from the last time it contacted the mock.
+func (mock *GitlabMock) refreshRunnerStatus(runner *gitlab.RunnerDetails) {
+	runner.Active = !runner.Paused
+
+	switch {
+	case runner.ContactedAt == nil:
+		runner.Status = runnerStatusNeverContacted
+	case time.Since(*runner.ContactedAt) < runnerOnlineTimeout:
+		runner.Status = runnerStatusOnline
+	case time.Since(*runner.ContactedAt) < runnerStaleTimeout:
+		runner.Status = runnerStatusOffline
+	default:
+		runner.Status = runnerStatusStale
+	}
+
+	runner.Online = runner.Status == runnerStatusOnline
+}
+
+func toRunner(runner *gitlab.RunnerDetails) *gitlab.Runner {
+	return &gitlab.Runner{
+		ID:          runner.ID,
+		Description: runner.Description,
+		Active:      !runner.Paused,
+		Paused:      runner.Paused,
+		IsShared:    runner.IsShared,
+		IPAddress:   runner.IPAddress,
+		RunnerType:  runner.RunnerType,
+		Name:        runner.Name,
+		Online:      runner.Online,
+		Status:      runner.Status,
+	}
+}
+
+// ownsRunner reports whether the user manages the runner: maintainers of one of the projects of a project
+// runner, owners of the group of a group runner and admins for instance runners.
+func (mock *GitlabMock) ownsRunner(runner *gitlab.RunnerDetails, user *gitlab.User) bool {
+	switch runner.RunnerType {
+	case runnerTypeGroup:
+		for _, runnerGroup := range runner.Groups {
+			if group, err := mock.getGroup(runnerGroup.ID); err == nil && mock.hasGroupAccess(group, user, gitlab.OwnerPermissions) {
+				return true
+			}
+		}
+	case runnerTypeProject:
+		for _, runnerProject := range runner.Projects {
+			if project, projectExists := mock.projects[runnerProject.ID]; projectExists && mock.hasAccess(project, user, gitlab.MaintainerPermissions) {
+				return true
+			}
+		}
+	}
+
+	return mock.isAdmin(user)
+}
+
+// ownedRunners returns the group and project runners the user manages.
+func (mock *GitlabMock) ownedRunners(user *gitlab.User) []*gitlab.RunnerDetails {
+	runners := []*gitlab.RunnerDetails{}
+
+	for _, runner := range mock.GetRunners() {
+		if runner.RunnerType != runnerTypeInstance && mock.ownsRunner(runner, user) {
+			runners = append(runners, runner)
+		}
+	}
+
+	return runners
+}
+
+func (mock *GitlabMock) getManagedRunner(runnerID int, user *gitlab.User) (*gitlab.RunnerDetails, error) {
+	runner, err := mock.getRunner(runnerID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !mock.ownsRunner(runner, user) {
+		return nil, newAPIError(http.StatusForbidden, "403 Forbidden")
+	}
+
+	return runner, nil
+}
+
+// updateRunner changes the details of the runner, tags are replaced as a whole.
+func (mock *GitlabMock) updateRunner(runner *gitlab.RunnerDetails, options *gitlab.UpdateRunnerDetailsOptions) error {
+	updated := *runner
+
+	if options.Description != nil {
+		updated.Description = *options.Description
+	}
+
+	if options.Active != nil {
+		updated.Paused = !*options.Active
+	}
+
+	if options.Paused != nil {
+		updated.Paused = *options.Paused
+	}
+
+	if options.TagList != nil {
+		updated.TagList = *options.TagList
+	}
+
+	if options.RunUntagged != nil {
+		updated.RunUntagged = *options.RunUntagged
+	}
+
+	if options.Locked != nil {
+		updated.Locked = *options.Locked
+	}
+
+	if options.AccessLevel != nil {
+		updated.AccessLevel = *options.AccessLevel
+	}
+
+	if options.MaximumTimeout != nil {
+		updated.MaximumTimeout = *options.MaximumTimeout
+	}
+
+	if err := validateRunner(&updated); err != nil {
+		return err
+	}
+
+	*runner = updated
+	mock.refreshRunnerStatus(runner)
+
+	return nil
+}
+
+// removeRunner unregisters the runner. The jobs it ran keep their runner.
+func (mock *GitlabMock) removeRunner(runner *gitlab.RunnerDetails) {
+	mock.runners = slices.DeleteFunc(mock.runners, func(other *gitlab.RunnerDetails) bool {
+		return other.ID == runner.ID
+	})
+
+	delete(mock.runnerTokens, runner.ID)
+}
+
+// resetRunnerToken replaces the authentication token of the runner.
+func (mock *GitlabMock) resetRunnerToken(runner *gitlab.RunnerDetails) string {
+	mock.runnerTokens[runner.ID] = newToken(runnerAuthenticationTokenPrefix)
+
+	return mock.runnerTokens[runner.ID]
+}
+
+// projectRunners returns the runners available to the project: its own runners, the runners of its groups and
+// the instance runners.
+func (mock *GitlabMock) projectRunners(project *gitlab.Project) []*gitlab.RunnerDetails {
+	runners := []*gitlab.RunnerDetails{}
+
+	for _, runner := range mock.GetRunners() {
+		if mock.runnerServesProject(runner, project) {
+			runners = append(runners, runner)
+		}
+	}
+
+	return runners
+}
+
+// groupRunners returns the runners available to the group: the runners of the group and its parent groups and
+// the instance runners.
+func (mock *GitlabMock) groupRunners(group *gitlab.Group) []*gitlab.RunnerDetails {
+	runners := []*gitlab.RunnerDetails{}
+
+	for _, runner := range mock.GetRunners() {
+		if runner.RunnerType == runnerTypeInstance || mock.runnerServesGroup(runner, group) {
+			runners = append(runners, runner)
+		}
+	}
+
+	return runners
+}
+
+func (mock *GitlabMock) runnerServesGroup(runner *gitlab.RunnerDetails, group *gitlab.Group) bool {
+	for _, ancestor := range mock.groupAncestors(group) {
+		if slices.ContainsFunc(runner.Groups, func(runnerGroup runnerGroup) bool { return runnerGroup.ID == ancestor.ID }) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (mock *GitlabMock) runnerServesProject(runner *gitlab.RunnerDetails, project *gitlab.Project) bool {
+	switch runner.RunnerType {
+	case runnerTypeInstance:
+		return true
+	case runnerTypeGroup:
+		if project.Namespace == nil {
+			return false
+		}
+
+		group, err := mock.getGroup(project.Namespace.ID)
+		if err != nil {
+			return false
+		}
+
+		return mock.runnerServesGroup(runner, group)
+	}
+
+	return slices.ContainsFunc(runner.Projects, func(runnerProject runnerProject) bool { return runnerProject.ID == project.ID })
+}
+
+// enableProjectRunner assigns a project runner of another project to the project.
+func (mock *GitlabMock) enableProjectRunner(project *gitlab.Project, runner *gitlab.RunnerDetails, user *gitlab.User) error {
+	switch {
+	case runner.RunnerType == runnerTypeInstance:
+		return newAPIError(http.StatusForbidden, "403 Forbidden - Runner is an instance runner")
+	case runner.RunnerType == runnerTypeGroup:
+		return newAPIError(http.StatusForbidden, "403 Forbidden - Runner is a group runner")
+	case runner.Locked:
+		return newAPIError(http.StatusForbidden, "403 Forbidden - Runner is locked")
+	case !mock.ownsRunner(runner, user):
+		return newAPIError(http.StatusForbidden, "403 Forbidden - No access granted")
+	case mock.runnerServesProject(runner, project):
+		return newAPIError(http.StatusConflict, "409 Conflict - Runner was already enabled for this project")
+	}
+
+	runner.Projects = append(runner.Projects, toRunnerProject(project))
+
+	return nil
+}
+
+// disableProjectRunner removes the project runner from the project. The last project of a runner cannot be
+// removed, the runner has to be deleted instead.
+func (mock *GitlabMock) disableProjectRunner(project *gitlab.Project, runner *gitlab.RunnerDetails) error {
+	if runner.RunnerType != runnerTypeProject || !mock.runnerServesProject(runner, project) {
+		return newAPIError(http.StatusNotFound, "404 Runner Not Found")
+	}
+
+	if len(runner.Projects) == 1 {
+		return newAPIError(http.StatusForbidden, "403 Forbidden - Only one project associated with the runner. Please remove the runner instead")
+	}
+
+	runner.Projects = slices.DeleteFunc(runner.Projects, func(runnerProject runnerProject) bool {
+		return runnerProject.ID == project.ID
+	})
+
+	return nil
+}
+
+// resetRunnersRegistrationToken, resetGroupRunnersToken and resetProjectRunnersToken replace the registration
+// token of the instance, group or project.
+func (mock *GitlabMock) resetRunnersRegistrationToken() string {
+	mock.runnersRegistrationToken = newToken(runnerRegistrationTokenPrefix)
+
+	return mock.runnersRegistrationToken
+}
+
+func (mock *GitlabMock) resetGroupRunnersToken(group *gitlab.Group) string {
+	group.RunnersToken = newToken(runnerRegistrationTokenPrefix)
+
+	return group.RunnersToken
+}
+
+func (mock *GitlabMock) resetProjectRunnersToken(project *gitlab.Project) string {
+	project.RunnersToken = newToken(runnerRegistrationTokenPrefix)
+
+	return project.RunnersToken
+}
+
+// runnerJobs returns the jobs the runner picked up, newest first.
+func (mock *GitlabMock) runnerJobs(runner *gitlab.RunnerDetails) []*gitlab.Job {
+	jobs := []*gitlab.Job{}
+
+	for _, pipelineJobs := range mock.jobs {
+		for _, job := range pipelineJobs {
+			if job.Runner.ID == runner.ID {
+				jobs = append(jobs, job)
+			}
+		}
+	}
+
+	slices.SortFunc(jobs, func(left *gitlab.Job, right *gitlab.Job) int {
+		return right.ID - left.ID
+	})
+
+	return jobs
+}
+
+// contactRunner records that the runner asked for a job.
+func (mock *GitlabMock) contactRunner(runner *gitlab.RunnerDetails, info *runnerInfo, ipAddress string) {
+	contactedAt := mock.now()
+	runner.ContactedAt = &contactedAt
+	runner.IPAddress = ipAddress
+
+	if info != nil {
+		runner.Name = info.Name
+		runner.Version = info.Version
+		runner.Revision = info.Revision
+		runner.Platform = info.Platform
+		runner.Architecture = info.Architecture
+	}
+
+	mock.refreshRunnerStatus(runner)
+}
+
+// isProtectedRef reports whether the ref of a pipeline is protected. The default branch and the tags matching
+// a protected tag rule are protected.
+func (mock *GitlabMock) isProtectedRef(project *gitlab.Project, ref string, tag bool) bool {
+	if tag {
+		return mock.matchingProtectedTag(project, ref) != nil
+	}
+
+	return ref == project.DefaultBranch
+}
+
+// runnerCanPick reports whether the runner may run the job: the project has to be served by the runner, the
+// runner needs all tags of the job and protected runners only run jobs for protected refs.
+func (mock *GitlabMock) runnerCanPick(runner *gitlab.RunnerDetails, job *gitlab.Job) bool {
+	project, projectExists := mock.projects[job.Pipeline.ProjectID]
+	if !projectExists || !mock.runnerServesProject(runner, project) {
+		return false
+	}
+
+	if len(job.TagList) == 0 && !runner.RunUntagged {
+		return false
+	}
+
+	for _, tag := range job.TagList {
+		if !slices.Contains(runner.TagList, tag) {
+			return false
+		}
+	}
+
+	return runner.AccessLevel != runnerAccessLevelRefProtected || mock.isProtectedRef(project, job.Ref, job.Tag)
+}
+
+// pickJob assigns the oldest pending job the runner can run to the runner and starts it. Paused runners and
+// runners without a matching job get nil.
+func (mock *GitlabMock) pickJob(runner *gitlab.RunnerDetails) (*gitlab.Job, error) {
+	if runner.Paused {
+		return nil, nil
+	}
+
+	var picked *gitlab.Job
+
+	for _, pipelineJobs := range mock.jobs {
+		for _, job := range pipelineJobs {
+			if job.Status != string(gitlab.Pending) || mock.retriedJobs[job.ID] || !mock.runnerCanPick(runner, job) {
+				continue
+			}
+
+			if picked == nil || job.ID < picked.ID {
+				picked = job
+			}
+		}
+	}
+
+	if picked == nil {
+		return nil, nil
+	}
+
+	picked.Runner.ID = runner.ID
+	picked.Runner.Description = runner.Description
+	picked.Runner.Active = !runner.Paused
+	picked.Runner.IsShared = runner.IsShared
+	picked.Runner.Name = runner.Name
+
+	return picked, mock.SetJobStatus(picked, gitlab.Running)
+}
+
+// runnerJobPayload builds the payload the runner executes the job with.
+func (mock *GitlabMock) runnerJobPayload(runner *gitlab.RunnerDetails, job *gitlab.Job, baseURL string) (*runnerJob, error) {
+	project, projectExists := mock.projects[job.Pipeline.ProjectID]
+	if !projectExists {
+		return nil, fmt.Errorf("project %d not found", job.Pipeline.ProjectID)
+	}
+
+	pipeline, err := mock.getPipeline(project, job.Pipeline.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	spec := mock.jobSpecs[job.ID]
+	token := mock.jobTokens[job.ID]
+
+	timeout := defaultJobTimeout
+	if runner.MaximumTimeout != 0 {
+		timeout = min(timeout, runner.MaximumTimeout)
+	}
+
+	server, _ := url.Parse(baseURL)
+
+	payload := &runnerJob{
+		ID:            job.ID,
+		Token:         token,
+		AllowGitFetch: true,
+		JobInfo: runnerJobInfo{
+			ID:          job.ID,
+			Name:        job.Name,
+			Stage:       job.Stage,
+			ProjectID:   project.ID,
+			ProjectName: project.Name,
+		},
+		GitInfo: runnerGitInfo{
+			RepoURL:   fmt.Sprintf("%s://gitlab-ci-token:%s@%s/%s.git", server.Scheme, token, server.Host, project.PathWithNamespace),
+			Ref:       pipeline.Ref,
+			Sha:       pipeline.SHA,
+			BeforeSha: pipeline.BeforeSHA,
+			RefType:   "branch",
+			Refspecs:  []string{fmt.Sprintf("+%s:refs/pipelines/%d", pipeline.SHA, pipeline.ID)},
+			Depth:     20,
+		},
+		RunnerInfo: runnerTimeout{Timeout: timeout},
+		Variables:  mock.runnerJobVariables(project, pipeline, job, runner, baseURL),
+		Steps: []*runnerJobStep{
+			{Name: "script", Script: spec.Script, Timeout: timeout, When: whenOnSuccess},
+		},
+		Credentials: []*runnerCredentials{
+			{Type: "registry", URL: server.Host, Username: "gitlab-ci-token", Password: token},
+		},
+		Dependencies: []*runnerJobDependency{},
+		Features: map[string]interface{}{
+			"trace_sections":  true,
+			"failure_reasons": []string{"unknown_failure", "script_failure", "runner_system_failure", "job_execution_timeout"},
+		},
+	}
+
+	if pipeline.Tag {
+		payload.GitInfo.RefType = "tag"
+		payload.GitInfo.Refspecs = append(payload.GitInfo.Refspecs, fmt.Sprintf("+refs/tags/%s:refs/tags/%s", pipeline.Ref, pipeline.Ref))
+	} else {
+		payload.GitInfo.Refspecs = append(payload.GitInfo.Refspecs, fmt.Sprintf("+refs/heads/%s:refs/remotes/origin/%s", pipeline.Ref, pipeline.Ref))
+	}
+
+	if len(spec.AfterScript) > 0 {
+		payload.Steps = append(payload.Steps, &runnerJobStep{Name: "after_script", Script: spec.AfterScript, Timeout: timeout, When: whenAlways, AllowFailure: true})
+	}
+
+	for _, dependency := range mock.jobDependencies(job, mock.pipelineJobs(pipeline, false), mock.pipelineStages(pipeline)) {
+		runnerDependency := &runnerJobDependency{ID: dependency.ID, Name: dependency.Name, Token: token}
+
+		if archive, err := mock.artifactsArchive(dependency); err == nil {
+			runnerDependency.ArtifactsFile = &runnerArtifactsFile{Filename: archive.filename, Size: len(archive.data)}
+		}
+
+		payload.Dependencies = append(payload.Dependencies, runnerDependency)
+	}
+
+	return payload, nil
+}
+
+// runnerJobVariables returns the variables of the job in the order of their precedence, later variables
+// override earlier ones: predefined variables, variables of the .gitlab-ci.yml, instance, group and project
+// variables and finally the variables of the pipeline. Protected variables are only passed to protected refs.
+func (mock *GitlabMock) runnerJobVariables(project *gitlab.Project, pipeline *gitlab.Pipeline, job *gitlab.Job, runner *gitlab.RunnerDetails, baseURL string) []*runnerJobVariable {
+	context := &ciPipelineContext{
+		project:   project,
+		ref:       pipeline.Ref,
+		sha:       pipeline.SHA,
+		beforeSHA: pipeline.BeforeSHA,
+		tag:       pipeline.Tag,
+		source:    pipeline.Source,
+		commit:    job.Commit,
+	}
+
+	predefined := context.predefinedVariables()
+	predefined["CI_SERVER_URL"] = baseURL
+	predefined["CI_API_V4_URL"] = baseURL + "/api/v4"
+	predefined["CI_PROJECT_URL"] = fmt.Sprintf("%s/%s", baseURL, project.PathWithNamespace)
+	predefined["CI_PIPELINE_ID"] = fmt.Sprint(pipeline.ID)
+	predefined["CI_PIPELINE_IID"] = fmt.Sprint(pipeline.IID)
+	predefined["CI_PIPELINE_URL"] = fmt.Sprintf("%s/%s/-/pipelines/%d", baseURL, project.PathWithNamespace, pipeline.ID)
+	predefined["CI_JOB_ID"] = fmt.Sprint(job.ID)
+	predefined["CI_JOB_NAME"] = job.Name
+	predefined["CI_JOB_STAGE"] = job.Stage
+	predefined["CI_JOB_URL"] = baseURL + job.WebURL
+	predefined["CI_RUNNER_ID"] = fmt.Sprint(runner.ID)
+	predefined["CI_RUNNER_DESCRIPTION"] = runner.Description
+	predefined["CI_RUNNER_TAGS"] = strings.Join(runner.TagList, ", ")
+	predefined["CI_REGISTRY_USER"] = "gitlab-ci-token"
+
+	deployToken, hasDeployToken := mock.gitlabDeployToken(project)
+	if hasDeployToken {
+		predefined["CI_DEPLOY_USER"] = deployToken.Username
+	}
+
+	if job.User != nil {
+		predefined["GITLAB_USER_ID"] = fmt.Sprint(job.User.ID)
+		predefined["GITLAB_USER_LOGIN"] = job.User.Username
+		predefined["GITLAB_USER_EMAIL"] = job.User.Email
+		predefined["GITLAB_USER_NAME"] = job.User.Name
+	}
+
+	variables := sortedRunnerVariables(predefined, true)
+
+	for _, key := range []string{"CI_JOB_TOKEN", "CI_REGISTRY_PASSWORD"} {
+		variables = append(variables, &runnerJobVariable{Key: key, Value: mock.jobTokens[job.ID], Masked: true})
+	}
+
+	if hasDeployToken {
+		variables = append(variables, &runnerJobVariable{Key: "CI_DEPLOY_PASSWORD", Value: deployToken.Token, Masked: true})
+	}
+
+	variables = append(variables, sortedRunnerVariables(mock.jobSpecs[job.ID].Variables, false)...)
+
+	protected := mock.isProtectedRef(project, pipeline.Ref, pipeline.Tag)
+
+	configured := slices.Clone(mock.instanceVariables)
+
+	if project.Namespace != nil {
+		if group, err := mock.getGroup(project.Namespace.ID); err == nil {
+			ancestors := mock.groupAncestors(group)
+
+			for i := len(ancestors) - 1; i >= 0; i-- {
+				configured = append(configured, mock.groupVariables[ancestors[i].ID]...)
+			}
+		}
+	}
+
+	configured = append(configured, mock.projectVariables[project.ID]...)
+
+	for _, variable := range configured {
+		if (variable.Protected && !protected) || variable.EnvironmentScope != allEnvironments {
+			continue
+		}
+
+		variables = append(variables, &runnerJobVariable{
+			Key:    variable.Key,
+			Value:  variable.Value,
+			File:   variable.VariableType == gitlab.FileVariableType,
+			Masked: variable.Masked,
+			Raw:    variable.Raw,
+		})
+	}
+
+	for _, variable := range mock.pipelineVariables[pipeline.ID] {
+		variables = append(variables, &runnerJobVariable{
+			Key:   variable.Key,
+			Value: variable.Value,
+			File:  variable.VariableType == gitlab.FileVariableType,
+		})
+	}
+
+	return variables
+}
+
+func sortedRunnerVariables(values map[string]string, public bool) []*runnerJobVariable {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	variables := make([]*runnerJobVariable, 0, len(keys))
+	for _, key := range keys {
+		variables = append(variables, &runnerJobVariable{Key: key, Value: values[key], Public: public})
+	}
+
+	return variables
+}
+
+// appendRunnerTrace appends a chunk of the trace a runner sends for the running job. The chunk has to start
+// where the trace ends, otherwise GitLab answers with 416 and the current length of the trace.
+func (mock *GitlabMock) appendRunnerTrace(job *gitlab.Job, contentRange string, data []byte) (int, error) {
+	length := len(mock.jobTraces[job.ID])
+
+	if job.Status != string(gitlab.Running) {
+		return length, newAPIError(http.StatusForbidden, "403 Forbidden - Job is not running")
+	}
+
+	if contentRange != "" {
+		var start, end int
+
+		_, err := fmt.Sscanf(contentRange, "%d-%d", &start, &end)
+		if err != nil || start != length {
+			return length, newAPIError(http.StatusRequestedRangeNotSatisfiable, "416 Range Not Satisfiable")
+		}
+	}
+
+	mock.jobTraces[job.ID] = append(mock.jobTraces[job.ID], data...)
+
+	return len(mock.jobTraces[job.ID]), nil
+}
+
+// updateRunnerJob applies the state a runner reports for the running job, finishing it with success or failure.
+func (mock *GitlabMock) updateRunnerJob(job *gitlab.Job, state string, failureReason string) error {
+	if job.Status != string(gitlab.Running) {
+		return newAPIError(http.StatusForbidden, "403 Forbidden - Job is not running")
+	}
+
+	switch gitlab.BuildStateValue(state) {
+	case gitlab.Running, "":
+		return nil
+	case gitlab.Success:
+		return mock.SetJobStatus(job, gitlab.Success)
+	case gitlab.Failed:
+		if failureReason == "" {
+			failureReason = "unknown_failure"
+		}
+
+		job.FailureReason = failureReason
+
+		return mock.SetJobStatus(job, gitlab.Failed)
+	}
+
+	return newAPIError(http.StatusBadRequest, "400 Bad request - state does not have a valid value")
+}
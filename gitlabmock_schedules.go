@@ -0,0 +1,304 @@
+package gitlabapimock
+
+import (
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+const defaultCronTimezone = "UTC"
+
+// AddPipelineSchedule creates a pipeline schedule for the project, owned by the user. The schedule runs when
+// the clock of the mock passes its next run, see SetClock and AdvanceClock.
+func (mock *GitlabMock) AddPipelineSchedule(project *gitlab.Project, options *gitlab.CreatePipelineScheduleOptions, owner *gitlab.User) (*gitlab.PipelineSchedule, error) {
+	switch {
+	case options.Description == nil:
+		return nil, newAPIError(http.StatusBadRequest, "description is missing")
+	case options.Ref == nil:
+		return nil, newAPIError(http.StatusBadRequest, "ref is missing")
+	case options.Cron == nil:
+		return nil, newAPIError(http.StatusBadRequest, "cron is missing")
+	}
+
+	createdAt := mock.now()
+
+	schedule := &gitlab.PipelineSchedule{
+		ID:           int(mock.pipelineScheduleIds.Add(1)),
+		Description:  *options.Description,
+		Ref:          *options.Ref,
+		Cron:         *options.Cron,
+		CronTimezone: defaultCronTimezone,
+		Active:       true,
+		CreatedAt:    &createdAt,
+		UpdatedAt:    &createdAt,
+		Owner:        owner,
+		Variables:    []*gitlab.PipelineVariable{},
+	}
+
+	if options.CronTimezone != nil {
+		schedule.CronTimezone = *options.CronTimezone
+	}
+
+	if options.Active != nil {
+		schedule.Active = *options.Active
+	}
+
+	err := mock.scheduleNextRun(schedule)
+	if err != nil {
+		return nil, err
+	}
+
+	mock.pipelineSchedules[project.ID] = append(mock.pipelineSchedules[project.ID], schedule)
+
+	return schedule, nil
+}
+
+// scheduleNextRun validates the cron expression of the schedule and sets its next run from the clock of the mock.
+func (mock *GitlabMock) scheduleNextRun(schedule *gitlab.PipelineSchedule) error {
+	if _, err := time.LoadLocation(schedule.CronTimezone); err != nil || schedule.CronTimezone == "" {
+		return newAPIError(http.StatusBadRequest, map[string][]string{"cron_timezone": {"is invalid syntax"}})
+	}
+
+	cron, err := parseCron(schedule.Cron, schedule.CronTimezone)
+	if err != nil {
+		return newAPIError(http.StatusBadRequest, map[string][]string{"cron": {"is invalid syntax"}})
+	}
+
+	schedule.NextRunAt = nil
+
+	if nextRunAt := cron.next(mock.now()); schedule.Active && !nextRunAt.IsZero() {
+		nextRunAt = nextRunAt.UTC()
+		schedule.NextRunAt = &nextRunAt
+	}
+
+	return nil
+}
+
+func (mock *GitlabMock) getPipelineSchedule(project *gitlab.Project, scheduleID int) (*gitlab.PipelineSchedule, error) {
+	for _, schedule := range mock.pipelineSchedules[project.ID] {
+		if schedule.ID == scheduleID {
+			mock.refreshLastPipeline(schedule)
+			return schedule, nil
+		}
+	}
+
+	return nil, newAPIError(http.StatusNotFound, "404 Pipeline Schedule Not Found")
+}
+
+// GetPipelineSchedules returns the pipeline schedules of the project.
+func (mock *GitlabMock) GetPipelineSchedules(project *gitlab.Project) []*gitlab.PipelineSchedule {
+	for _, schedule := range mock.pipelineSchedules[project.ID] {
+		mock.refreshLastPipeline(schedule)
+	}
+
+	return mock.pipelineSchedules[project.ID]
+}
+
+// GetSchedulePipelines returns the pipelines the schedule ran, oldest first.
+func (mock *GitlabMock) GetSchedulePipelines(schedule *gitlab.PipelineSchedule) []*gitlab.Pipeline {
+	return mock.schedulePipelines[schedule.ID]
+}
+
+// refreshLastPipeline updates the last pipeline of the schedule with the current status of the pipeline.
+func (mock *GitlabMock) refreshLastPipeline(schedule *gitlab.PipelineSchedule) {
+	pipelines := mock.schedulePipelines[schedule.ID]
+	if len(pipelines) == 0 {
+		return
+	}
+
+	pipeline := pipelines[len(pipelines)-1]
+
+	schedule.LastPipeline = &gitlab.LastPipeline{
+		ID:     pipeline.ID,
+		SHA:    pipeline.SHA,
+		Ref:    pipeline.Ref,
+		Status: pipeline.Status,
+		WebURL: pipeline.WebURL,
+	}
+}
+
+// ownsPipelineSchedule reports whether the user owns the schedule. Only owners may change a schedule, other
+// maintainers have to take ownership first.
+func (mock *GitlabMock) ownsPipelineSchedule(schedule *gitlab.PipelineSchedule, user *gitlab.User) bool {
+	if !mock.authenticationRequired() {
+		return true
+	}
+
+	return user != nil && schedule.Owner != nil && schedule.Owner.ID == user.ID
+}
+
+// editPipelineSchedule applies the options to the schedule. Invalid changes leave the schedule untouched.
+func (mock *GitlabMock) editPipelineSchedule(schedule *gitlab.PipelineSchedule, options *gitlab.EditPipelineScheduleOptions) error {
+	updated := *schedule
+
+	if options.Description != nil {
+		updated.Description = *options.Description
+	}
+
+	if options.Ref != nil {
+		updated.Ref = *options.Ref
+	}
+
+	if options.Cron != nil {
+		updated.Cron = *options.Cron
+	}
+
+	if options.CronTimezone != nil {
+		updated.CronTimezone = *options.CronTimezone
+	}
+
+	if options.Active != nil {
+		updated.Active = *options.Active
+	}
+
+	err := mock.scheduleNextRun(&updated)
+	if err != nil {
+		return err
+	}
+
+	updatedAt := mock.now()
+	updated.UpdatedAt = &updatedAt
+
+	*schedule = updated
+
+	return nil
+}
+
+func (mock *GitlabMock) takeOwnershipOfPipelineSchedule(schedule *gitlab.PipelineSchedule, user *gitlab.User) {
+	schedule.Owner = user
+
+	updatedAt := mock.now()
+	schedule.UpdatedAt = &updatedAt
+}
+
+func (mock *GitlabMock) removePipelineSchedule(project *gitlab.Project, schedule *gitlab.PipelineSchedule) {
+	mock.pipelineSchedules[project.ID] = slices.DeleteFunc(mock.pipelineSchedules[project.ID], func(other *gitlab.PipelineSchedule) bool {
+		return other.ID == schedule.ID
+	})
+
+	delete(mock.schedulePipelines, schedule.ID)
+}
+
+// runPipelineSchedule creates a pipeline for the ref of the schedule with its variables, as its owner.
+func (mock *GitlabMock) runPipelineSchedule(project *gitlab.Project, schedule *gitlab.PipelineSchedule) (*gitlab.Pipeline, error) {
+	ref := strings.TrimPrefix(strings.TrimPrefix(schedule.Ref, "refs/heads/"), "refs/tags/")
+
+	variables := make([]*gitlab.PipelineVariable, 0, len(schedule.Variables))
+	for _, variable := range schedule.Variables {
+		copied := *variable
+		variables = append(variables, &copied)
+	}
+
+	pipeline, err := mock.createPipeline(project, ref, "schedule", variables, nil, schedule.Owner)
+	if err != nil {
+		return nil, err
+	}
+
+	mock.schedulePipelines[schedule.ID] = append(mock.schedulePipelines[schedule.ID], pipeline)
+	mock.refreshLastPipeline(schedule)
+
+	return pipeline, nil
+}
+
+// runDueSchedules runs the active schedules whose next run has passed on the clock of the mock. Like GitLab,
+// a schedule that missed several runs only runs once and is scheduled again from the current time.
+func (mock *GitlabMock) runDueSchedules() {
+	now := mock.now()
+
+	projectIDs := make([]int, 0, len(mock.pipelineSchedules))
+	for projectID := range mock.pipelineSchedules {
+		projectIDs = append(projectIDs, projectID)
+	}
+
+	sort.Ints(projectIDs)
+
+	for _, projectID := range projectIDs {
+		project, projectExists := mock.projects[projectID]
+		if !projectExists {
+			continue
+		}
+
+		for _, schedule := range mock.pipelineSchedules[projectID] {
+			if !schedule.Active || schedule.NextRunAt == nil || schedule.NextRunAt.After(now) {
+				continue
+			}
+
+			// Pipelines that cannot be created, e.g. for deleted refs, are skipped like in GitLab.
+			_, _ = mock.runPipelineSchedule(project, schedule)
+			_ = mock.scheduleNextRun(schedule)
+		}
+	}
+}
+
+func findScheduleVariable(schedule *gitlab.PipelineSchedule, key string) (*gitlab.PipelineVariable, error) {
+	for _, variable := range schedule.Variables {
+		if variable.Key == key {
+			return variable, nil
+		}
+	}
+
+	return nil, newAPIError(http.StatusNotFound, "404 Pipeline Schedule Variable Not Found")
+}
+
+func (mock *GitlabMock) addScheduleVariable(schedule *gitlab.PipelineSchedule, options *gitlab.CreatePipelineScheduleVariableOptions) (*gitlab.PipelineVariable, error) {
+	switch {
+	case options.Key == nil:
+		return nil, newAPIError(http.StatusBadRequest, "key is missing")
+	case options.Value == nil:
+		return nil, newAPIError(http.StatusBadRequest, "value is missing")
+	case !variableKeyRegexp.MatchString(*options.Key):
+		return nil, newAPIError(http.StatusBadRequest, map[string][]string{"key": {"can contain only letters, digits and '_'."}})
+	}
+
+	if _, err := findScheduleVariable(schedule, *options.Key); err == nil {
+		return nil, newAPIError(http.StatusBadRequest, map[string][]string{"key": {"has already been taken"}})
+	}
+
+	variable := &gitlab.PipelineVariable{
+		Key:          *options.Key,
+		Value:        *options.Value,
+		VariableType: gitlab.EnvVariableType,
+	}
+
+	if options.VariableType != nil {
+		variable.VariableType = *options.VariableType
+	}
+
+	schedule.Variables = append(schedule.Variables, variable)
+
+	return variable, nil
+}
+
+func (mock *GitlabMock) editScheduleVariable(schedule *gitlab.PipelineSchedule, key string, options *gitlab.EditPipelineScheduleVariableOptions) (*gitlab.PipelineVariable, error) {
+	variable, err := findScheduleVariable(schedule, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.Value != nil {
+		variable.Value = *options.Value
+	}
+
+	if options.VariableType != nil {
+		variable.VariableType = *options.VariableType
+	}
+
+	return variable, nil
+}
+
+func (mock *GitlabMock) removeScheduleVariable(schedule *gitlab.PipelineSchedule, key string) (*gitlab.PipelineVariable, error) {
+	variable, err := findScheduleVariable(schedule, key)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule.Variables = slices.DeleteFunc(schedule.Variables, func(other *gitlab.PipelineVariable) bool {
+		return other.Key == key
+	})
+
+	return variable, nil
+}
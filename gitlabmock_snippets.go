@@ -0,0 +1,71 @@
+package gitlabapimock
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// AddProjectSnippet adds a snippet to the project. The content itself is not stored.
+func (mock *GitlabMock) AddProjectSnippet(project *gitlab.Project, options *gitlab.CreateProjectSnippetOptions, author *gitlab.User) (*gitlab.Snippet, error) {
+	if options.Title == nil || *options.Title == "" {
+		return nil, newAPIError(http.StatusBadRequest, "title is missing")
+	}
+
+	if !mock.hasAccess(project, author, gitlab.ReporterPermissions) {
+		return nil, newAPIError(http.StatusForbidden, "403 Forbidden")
+	}
+
+	createdAt := mock.now()
+
+	snippet := &gitlab.Snippet{
+		ID:          int(mock.snippetIds.Add(1)),
+		Title:       *options.Title,
+		FileName:    valueOf(options.FileName),
+		Description: valueOf(options.Description),
+		Visibility:  string(gitlab.PrivateVisibility),
+		ProjectID:   project.ID,
+		CreatedAt:   &createdAt,
+		UpdatedAt:   &createdAt,
+	}
+
+	if options.Visibility != nil {
+		snippet.Visibility = string(*options.Visibility)
+	}
+
+	if author != nil {
+		snippet.Author.ID = author.ID
+		snippet.Author.Username = author.Username
+		snippet.Author.Email = author.Email
+		snippet.Author.Name = author.Name
+		snippet.Author.State = author.State
+		snippet.Author.CreatedAt = author.CreatedAt
+	}
+
+	snippet.WebURL = fmt.Sprintf("/%s/-/snippets/%d", project.PathWithNamespace, snippet.ID)
+	snippet.RawURL = snippet.WebURL + "/raw"
+
+	mock.snippets[project.ID] = append(mock.snippets[project.ID], snippet)
+
+	return snippet, nil
+}
+
+func (mock *GitlabMock) getProjectSnippets(project *gitlab.Project) []*gitlab.Snippet {
+	snippets := mock.snippets[project.ID]
+	if snippets == nil {
+		snippets = []*gitlab.Snippet{}
+	}
+
+	return snippets
+}
+
+func (mock *GitlabMock) getProjectSnippet(project *gitlab.Project, snippetID int) (*gitlab.Snippet, error) {
+	for _, snippet := range mock.snippets[project.ID] {
+		if snippet.ID == snippetID {
+			return snippet, nil
+		}
+	}
+
+	return nil, newAPIError(http.StatusNotFound, "404 Snippet Not Found")
+}
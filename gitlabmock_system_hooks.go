@@ -0,0 +1,430 @@
+package gitlabapimock
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// The system hook payloads follow https://docs.gitlab.com/ee/administration/system_hooks.html and decode into
+// the system events of go-gitlab, see gitlab.ParseSystemhook.
+
+type projectSystemHookPayload struct {
+	CreatedAt         *time.Time `json:"created_at"`
+	UpdatedAt         *time.Time `json:"updated_at"`
+	EventName         string     `json:"event_name"`
+	Name              string     `json:"name"`
+	Path              string     `json:"path"`
+	PathWithNamespace string     `json:"path_with_namespace"`
+	ProjectID         int        `json:"project_id"`
+	OwnerName         string     `json:"owner_name"`
+	OwnerEmail        string     `json:"owner_email"`
+	ProjectVisibility string     `json:"project_visibility"`
+}
+
+type groupSystemHookPayload struct {
+	CreatedAt  *time.Time `json:"created_at"`
+	UpdatedAt  *time.Time `json:"updated_at"`
+	EventName  string     `json:"event_name"`
+	Name       string     `json:"name"`
+	Path       string     `json:"path"`
+	FullPath   string     `json:"full_path"`
+	GroupID    int        `json:"group_id"`
+	OwnerName  *string    `json:"owner_name"`
+	OwnerEmail *string    `json:"owner_email"`
+}
+
+type userSystemHookPayload struct {
+	CreatedAt *time.Time `json:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at"`
+	EventName string     `json:"event_name"`
+	UserID    int        `json:"user_id"`
+	Name      string     `json:"name"`
+	Username  string     `json:"username"`
+	Email     string     `json:"email"`
+	State     string     `json:"state"`
+}
+
+// groupMemberHookPayload is the payload of group member events, which group hooks and system hooks share.
+type groupMemberHookPayload struct {
+	CreatedAt    *time.Time `json:"created_at"`
+	UpdatedAt    *time.Time `json:"updated_at"`
+	EventName    string     `json:"event_name"`
+	GroupAccess  string     `json:"group_access"`
+	GroupID      int        `json:"group_id"`
+	GroupName    string     `json:"group_name"`
+	GroupPath    string     `json:"group_path"`
+	GroupPlan    *string    `json:"group_plan"`
+	UserID       int        `json:"user_id"`
+	UserName     string     `json:"user_name"`
+	UserUsername string     `json:"user_username"`
+	UserEmail    string     `json:"user_email"`
+	ExpiresAt    *time.Time `json:"expires_at"`
+}
+
+type subgroupHookPayload struct {
+	CreatedAt      *time.Time `json:"created_at"`
+	UpdatedAt      *time.Time `json:"updated_at"`
+	EventName      string     `json:"event_name"`
+	Name           string     `json:"name"`
+	Path           string     `json:"path"`
+	FullPath       string     `json:"full_path"`
+	GroupID        int        `json:"group_id"`
+	ParentGroupID  int        `json:"parent_group_id"`
+	ParentName     string     `json:"parent_name"`
+	ParentPath     string     `json:"parent_path"`
+	ParentFullPath string     `json:"parent_full_path"`
+}
+
+type repositoryUpdateChange struct {
+	Before string `json:"before"`
+	After  string `json:"after"`
+	Ref    string `json:"ref"`
+}
+
+type repositoryUpdateHookPayload struct {
+	EventName  string                    `json:"event_name"`
+	UserID     int                       `json:"user_id"`
+	UserName   string                    `json:"user_name"`
+	UserEmail  string                    `json:"user_email"`
+	UserAvatar string                    `json:"user_avatar"`
+	ProjectID  int                       `json:"project_id"`
+	Project    hookProject               `json:"project"`
+	Changes    []*repositoryUpdateChange `json:"changes"`
+	Refs       []string                  `json:"refs"`
+}
+
+// AddSystemHook adds a system hook, which receives the system events of the whole instance. Push, tag push and
+// merge request events are only sent when enabled by the options, repository update events unless disabled.
+func (mock *GitlabMock) AddSystemHook(options *gitlab.AddHookOptions) (*gitlab.Hook, error) {
+	if options.URL == nil {
+		return nil, newAPIError(http.StatusBadRequest, "url is missing")
+	}
+
+	createdAt := mock.now()
+
+	hook := &gitlab.Hook{
+		ID:                     int(mock.hookIds.Add(1)),
+		CreatedAt:              &createdAt,
+		RepositoryUpdateEvents: true,
+		EnableSSLVerification:  true,
+	}
+
+	err := mock.applySystemHookOptions(hook, options)
+	if err != nil {
+		return nil, err
+	}
+
+	mock.systemHooks = append(mock.systemHooks, hook)
+
+	return hook, nil
+}
+
+// applySystemHookOptions applies the options to the hook. An invalid URL leaves the hook untouched.
+func (mock *GitlabMock) applySystemHookOptions(hook *gitlab.Hook, options *gitlab.AddHookOptions) error {
+	if options.URL != nil {
+		err := validateHookURL(*options.URL)
+		if err != nil {
+			return err
+		}
+
+		hook.URL = *options.URL
+	}
+
+	flags := []struct {
+		value  *bool
+		target *bool
+	}{
+		{options.PushEvents, &hook.PushEvents},
+		{options.TagPushEvents, &hook.TagPushEvents},
+		{options.MergeRequestsEvents, &hook.MergeRequestsEvents},
+		{options.RepositoryUpdateEvents, &hook.RepositoryUpdateEvents},
+		{options.EnableSSLVerification, &hook.EnableSSLVerification},
+	}
+
+	for _, flag := range flags {
+		if flag.value != nil {
+			*flag.target = *flag.value
+		}
+	}
+
+	if options.Token != nil {
+		mock.hookTokens[hook.ID] = *options.Token
+	}
+
+	return nil
+}
+
+func (mock *GitlabMock) getSystemHook(hookID int) (*gitlab.Hook, error) {
+	for _, hook := range mock.systemHooks {
+		if hook.ID == hookID {
+			return hook, nil
+		}
+	}
+
+	return nil, newAPIError(http.StatusNotFound, "404 Not found")
+}
+
+// GetSystemHooks returns the system hooks.
+func (mock *GitlabMock) GetSystemHooks() []*gitlab.Hook {
+	return append([]*gitlab.Hook{}, mock.systemHooks...)
+}
+
+func (mock *GitlabMock) removeSystemHook(hook *gitlab.Hook) {
+	mock.systemHooks = slices.DeleteFunc(mock.systemHooks, func(other *gitlab.Hook) bool {
+		return other.ID == hook.ID
+	})
+
+	delete(mock.hookTokens, hook.ID)
+	mock.removeHookDeliveries(hook.ID)
+}
+
+// testSystemHook sends a sample project_create event to the hook, using the most recent project if there is any.
+func (mock *GitlabMock) testSystemHook(hook *gitlab.Hook) *gitlab.HookEvent {
+	event := &gitlab.HookEvent{
+		EventName:  "project_create",
+		Name:       "Ruby",
+		Path:       "ruby",
+		ProjectID:  1,
+		OwnerName:  "Someone",
+		OwnerEmail: "example@gitlabhq.com",
+	}
+
+	var latest *gitlab.Project
+	for _, project := range mock.projects {
+		if latest == nil || project.ID > latest.ID {
+			latest = project
+		}
+	}
+
+	if latest != nil {
+		event.Name = latest.Name
+		event.Path = latest.Path
+		event.ProjectID = latest.ID
+		event.OwnerEmail = ""
+
+		if latest.Namespace != nil {
+			event.OwnerName = latest.Namespace.Name
+		}
+	}
+
+	body, err := json.Marshal(event)
+	if err == nil {
+		mock.queueHookDelivery(hook.ID, hook.URL, nil, gitlab.EventTypeSystemHook, body)
+	}
+
+	return event
+}
+
+// systemHookSubscribes reports whether the system hook receives the event. System events, including the member
+// events of projects and groups, are sent to all system hooks.
+func systemHookSubscribes(hook *gitlab.Hook, event gitlab.EventType) bool {
+	switch event {
+	case gitlab.EventTypePush:
+		return hook.PushEvents
+	case gitlab.EventTypeTagPush:
+		return hook.TagPushEvents
+	case gitlab.EventTypeMergeRequest:
+		return hook.MergeRequestsEvents
+	case gitlab.EventTypeMember, gitlab.EventTypeSystemHook:
+		return true
+	}
+
+	return false
+}
+
+// queueSystemHookDeliveries sends the payload to the system hooks that subscribed to it. System hooks receive
+// every event with the System Hook event header.
+func (mock *GitlabMock) queueSystemHookDeliveries(payload []byte, subscribes func(hook *gitlab.Hook) bool) {
+	for _, hook := range mock.systemHooks {
+		if subscribes(hook) {
+			mock.queueHookDelivery(hook.ID, hook.URL, nil, gitlab.EventTypeSystemHook, payload)
+		}
+	}
+}
+
+// fireSystemHooks sends a system event to all system hooks.
+func (mock *GitlabMock) fireSystemHooks(payload interface{}) {
+	if len(mock.systemHooks) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+
+	mock.queueSystemHookDeliveries(body, func(hook *gitlab.Hook) bool {
+		return true
+	})
+}
+
+func (mock *GitlabMock) fireUserSystemHooks(user *gitlab.User, eventName string) {
+	now := mock.now()
+
+	mock.fireSystemHooks(&userSystemHookPayload{
+		CreatedAt: &now,
+		UpdatedAt: &now,
+		EventName: eventName,
+		UserID:    user.ID,
+		Name:      user.Name,
+		Username:  user.Username,
+		Email:     user.Email,
+		State:     "active",
+	})
+}
+
+func (mock *GitlabMock) fireProjectSystemHooks(project *gitlab.Project, eventName string) {
+	now := mock.now()
+
+	payload := &projectSystemHookPayload{
+		CreatedAt:         project.CreatedAt,
+		UpdatedAt:         &now,
+		EventName:         eventName,
+		Name:              project.Name,
+		Path:              project.Path,
+		PathWithNamespace: project.PathWithNamespace,
+		ProjectID:         project.ID,
+		ProjectVisibility: string(project.Visibility),
+	}
+
+	if project.Namespace != nil {
+		payload.OwnerName = project.Namespace.Name
+	}
+
+	mock.fireSystemHooks(payload)
+}
+
+// fireGroupCreateHooks sends the group_create system event, and the subgroup_create event to the hooks of the
+// parent groups of subgroups.
+func (mock *GitlabMock) fireGroupCreateHooks(group *gitlab.Group) {
+	now := mock.now()
+
+	mock.fireSystemHooks(&groupSystemHookPayload{
+		CreatedAt: &now,
+		UpdatedAt: &now,
+		EventName: "group_create",
+		Name:      group.Name,
+		Path:      group.Path,
+		FullPath:  group.FullPath,
+		GroupID:   group.ID,
+	})
+
+	if group.ParentID == 0 {
+		return
+	}
+
+	parent, err := mock.getGroup(group.ParentID)
+	if err != nil {
+		return
+	}
+
+	payload := &subgroupHookPayload{
+		CreatedAt:      &now,
+		UpdatedAt:      &now,
+		EventName:      "subgroup_create",
+		Name:           group.Name,
+		Path:           group.Path,
+		FullPath:       group.FullPath,
+		GroupID:        group.ID,
+		ParentGroupID:  parent.ID,
+		ParentName:     parent.Name,
+		ParentPath:     parent.Path,
+		ParentFullPath: parent.FullPath,
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+
+	for _, ancestor := range mock.groupAncestors(parent) {
+		for _, hook := range mock.groupHooks[ancestor.ID] {
+			if groupHookSubscribes(hook, gitlab.EventTypeSubGroup, "") {
+				mock.queueHookDelivery(hook.ID, hook.URL, hook.CustomHeaders, gitlab.EventTypeSubGroup, body)
+			}
+		}
+	}
+}
+
+// fireGroupMemberHooks sends a member event of the group, e.g. user_add_to_group, user_update_for_group or
+// user_remove_from_group.
+func (mock *GitlabMock) fireGroupMemberHooks(group *gitlab.Group, member *gitlab.GroupMember, eventName string) {
+	now := mock.now()
+
+	payload := &groupMemberHookPayload{
+		CreatedAt:    member.CreatedAt,
+		UpdatedAt:    &now,
+		EventName:    eventName,
+		GroupAccess:  accessLevelNames[member.AccessLevel],
+		GroupID:      group.ID,
+		GroupName:    group.Name,
+		GroupPath:    group.Path,
+		UserID:       member.ID,
+		UserName:     member.Name,
+		UserUsername: member.Username,
+		UserEmail:    member.Email,
+	}
+
+	if member.ExpiresAt != nil {
+		expiresAt := time.Time(*member.ExpiresAt)
+		payload.ExpiresAt = &expiresAt
+	}
+
+	if user, err := mock.getUser(member.ID); err == nil {
+		payload.UserName = user.Name
+		payload.UserUsername = user.Username
+		payload.UserEmail = user.Email
+	}
+
+	mock.fireGroupHooks(group, gitlab.EventTypeMember, payload)
+}
+
+// fireRepositoryUpdateHooks sends the repository_update event of a push to the system hooks that subscribed to
+// it.
+func (mock *GitlabMock) fireRepositoryUpdateHooks(project *gitlab.Project, user *gitlab.User, updates []refUpdate) {
+	if !slices.ContainsFunc(mock.systemHooks, func(hook *gitlab.Hook) bool { return hook.RepositoryUpdateEvents }) {
+		return
+	}
+
+	payload := &repositoryUpdateHookPayload{
+		EventName: "repository_update",
+		ProjectID: project.ID,
+		Project:   toHookProject(project),
+		Changes:   []*repositoryUpdateChange{},
+		Refs:      []string{},
+	}
+
+	if user != nil {
+		payload.UserID = user.ID
+		payload.UserName = user.Name
+		payload.UserEmail = user.Email
+		payload.UserAvatar = user.AvatarURL
+	}
+
+	for _, update := range updates {
+		if !strings.HasPrefix(update.Ref, "refs/heads/") && !strings.HasPrefix(update.Ref, "refs/tags/") {
+			continue
+		}
+
+		payload.Changes = append(payload.Changes, &repositoryUpdateChange{Before: update.Before, After: update.After, Ref: update.Ref})
+		payload.Refs = append(payload.Refs, update.Ref)
+	}
+
+	if len(payload.Changes) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+
+	mock.queueSystemHookDeliveries(body, func(hook *gitlab.Hook) bool {
+		return hook.RepositoryUpdateEvents
+	})
+}
@@ -0,0 +1,252 @@
+package gitlabapimock
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// accessLevelDescriptions maps access levels to the descriptions GitLab uses for protected refs.
+var accessLevelDescriptions = map[gitlab.AccessLevelValue]string{
+	gitlab.NoPermissions:         "No one",
+	gitlab.DeveloperPermissions:  "Developers + Maintainers",
+	gitlab.MaintainerPermissions: "Maintainers",
+	gitlab.OwnerPermissions:      "Owners",
+}
+
+// matchesWildcard reports whether the name matches a protected ref pattern like "release-*".
+func matchesWildcard(pattern string, name string) bool {
+	if !strings.Contains(pattern, "*") {
+		return pattern == name
+	}
+
+	parts := strings.Split(pattern, "*")
+	for idx, part := range parts {
+		parts[idx] = regexp.QuoteMeta(part)
+	}
+
+	return regexp.MustCompile("^" + strings.Join(parts, ".*") + "$").MatchString(name)
+}
+
+// AddProtectedTag protects the tags matching the name, which may contain * wildcards.
+func (mock *GitlabMock) AddProtectedTag(project *gitlab.Project, name string, createAccessLevel gitlab.AccessLevelValue) (*gitlab.ProtectedTag, error) {
+	for _, protectedTag := range mock.protectedTags[project.ID] {
+		if protectedTag.Name == name {
+			return nil, newAPIError(http.StatusConflict, "Protected tag '"+name+"' already exists")
+		}
+	}
+
+	protectedTag := &gitlab.ProtectedTag{
+		Name: name,
+		CreateAccessLevels: []*gitlab.TagAccessDescription{
+			{
+				ID:                     int(mock.protectedTagAccessIds.Add(1)),
+				AccessLevel:            createAccessLevel,
+				AccessLevelDescription: accessLevelDescriptions[createAccessLevel],
+			},
+		},
+	}
+
+	mock.protectedTags[project.ID] = append(mock.protectedTags[project.ID], protectedTag)
+
+	return protectedTag, nil
+}
+
+func (mock *GitlabMock) getProtectedTag(project *gitlab.Project, name string) (*gitlab.ProtectedTag, error) {
+	for _, protectedTag := range mock.protectedTags[project.ID] {
+		if protectedTag.Name == name {
+			return protectedTag, nil
+		}
+	}
+
+	return nil, newAPIError(http.StatusNotFound, "404 Not found")
+}
+
+func (mock *GitlabMock) removeProtectedTag(project *gitlab.Project, name string) error {
+	protectedTags := mock.protectedTags[project.ID]
+
+	for idx, protectedTag := range protectedTags {
+		if protectedTag.Name == name {
+			mock.protectedTags[project.ID] = append(protectedTags[:idx:idx], protectedTags[idx+1:]...)
+			return nil
+		}
+	}
+
+	return newAPIError(http.StatusNotFound, "404 Not found")
+}
+
+// matchingProtectedTag returns the protected tag rule the tag name falls under, or nil.
+func (mock *GitlabMock) matchingProtectedTag(project *gitlab.Project, tagName string) *gitlab.ProtectedTag {
+	for _, protectedTag := range mock.protectedTags[project.ID] {
+		if matchesWildcard(protectedTag.Name, tagName) {
+			return protectedTag
+		}
+	}
+
+	return nil
+}
+
+// canCreateTag checks the protected tag rules for the user.
+func (mock *GitlabMock) canCreateTag(project *gitlab.Project, tagName string, user *gitlab.User) bool {
+	requiredAccessLevel := gitlab.DeveloperPermissions
+
+	protectedTag := mock.matchingProtectedTag(project, tagName)
+	if protectedTag != nil {
+		requiredAccessLevel = gitlab.OwnerPermissions + 1
+		for _, createAccessLevel := range protectedTag.CreateAccessLevels {
+			if createAccessLevel.AccessLevel != gitlab.NoPermissions && createAccessLevel.AccessLevel < requiredAccessLevel {
+				requiredAccessLevel = createAccessLevel.AccessLevel
+			}
+		}
+	}
+
+	return mock.hasAccess(project, user, requiredAccessLevel)
+}
+
+func (mock *GitlabMock) toTag(project *gitlab.Project, repo *repository, repositoryTag *repositoryTag) (*gitlab.Tag, error) {
+	commit, err := repo.commit(repositoryTag.Commit)
+	if err != nil {
+		return nil, err
+	}
+
+	commit.ProjectID = project.ID
+
+	tag := &gitlab.Tag{
+		Commit:    commit,
+		Name:      repositoryTag.Name,
+		Message:   repositoryTag.Message,
+		Protected: mock.matchingProtectedTag(project, repositoryTag.Name) != nil,
+		Target:    repositoryTag.Target,
+	}
+
+	release, err := mock.getRelease(project, repositoryTag.Name)
+	if err == nil {
+		tag.Release = &gitlab.ReleaseNote{
+			TagName:     release.TagName,
+			Description: release.Description,
+		}
+	}
+
+	return tag, nil
+}
+
+// GetTags returns the tags of the project repository sorted by name.
+func (mock *GitlabMock) GetTags(project *gitlab.Project) ([]*gitlab.Tag, error) {
+	repo, err := mock.getRepository(project)
+	if err != nil {
+		return nil, err
+	}
+
+	repositoryTags, err := repo.tags()
+	if err != nil {
+		return nil, err
+	}
+
+	tags := []*gitlab.Tag{}
+
+	for _, repositoryTag := range repositoryTags {
+		tag, err := mock.toTag(project, repo, repositoryTag)
+		if err != nil {
+			return nil, err
+		}
+
+		tags = append(tags, tag)
+	}
+
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].Name < tags[j].Name
+	})
+
+	return tags, nil
+}
+
+// GetTag returns a single tag of the project repository.
+func (mock *GitlabMock) GetTag(project *gitlab.Project, name string) (*gitlab.Tag, error) {
+	tags, err := mock.GetTags(project)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, tag := range tags {
+		if tag.Name == name {
+			return tag, nil
+		}
+	}
+
+	return nil, newAPIError(http.StatusNotFound, "404 Tag Not Found")
+}
+
+// AddTag creates a tag pointing to the ref. A non-empty message creates an annotated tag.
+func (mock *GitlabMock) AddTag(project *gitlab.Project, name string, ref string, message string, user *gitlab.User) (*gitlab.Tag, error) {
+	if name == "" {
+		return nil, newAPIError(http.StatusBadRequest, "tag_name is missing")
+	}
+
+	if ref == "" {
+		return nil, newAPIError(http.StatusBadRequest, "ref is missing")
+	}
+
+	if !mock.canCreateTag(project, name, user) {
+		return nil, newAPIError(http.StatusForbidden, "You are not allowed to create this tag as it is protected.")
+	}
+
+	repo, err := mock.getRepository(project)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = repo.resolveRef("refs/tags/" + name)
+	if err == nil {
+		return nil, newAPIError(http.StatusBadRequest, fmt.Sprintf("Tag %s already exists", name))
+	}
+
+	sha, err := repo.resolve(ref)
+	if err != nil {
+		return nil, newAPIError(http.StatusBadRequest, "Target "+ref+" is invalid")
+	}
+
+	update, err := repo.createTag(name, sha, message, user)
+	if err != nil {
+		return nil, newAPIError(http.StatusBadRequest, err.Error())
+	}
+
+	mock.applyRefUpdates(project, user, []refUpdate{*update})
+
+	return mock.GetTag(project, name)
+}
+
+// deleteTag deletes the tag and the release belonging to it.
+func (mock *GitlabMock) deleteTag(project *gitlab.Project, name string, user *gitlab.User) error {
+	repo, err := mock.getRepository(project)
+	if err != nil {
+		return err
+	}
+
+	_, err = repo.resolveRef("refs/tags/" + name)
+	if err != nil {
+		return newAPIError(http.StatusNotFound, "404 Tag Not Found")
+	}
+
+	requiredAccessLevel := gitlab.DeveloperPermissions
+	if mock.matchingProtectedTag(project, name) != nil {
+		requiredAccessLevel = gitlab.MaintainerPermissions
+	}
+
+	if !mock.hasAccess(project, user, requiredAccessLevel) {
+		return newAPIError(http.StatusForbidden, "403 Forbidden")
+	}
+
+	update, err := repo.updateRef("refs/tags/"+name, zeroSHA)
+	if err != nil {
+		return err
+	}
+
+	mock.removeRelease(project, name)
+	mock.applyRefUpdates(project, user, []refUpdate{*update})
+
+	return nil
+}
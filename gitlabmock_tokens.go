@@ -0,0 +1,643 @@
+package gitlabapimock
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+const personalAccessTokenPrefix = "glpat-"
+
+var (
+	errUnauthorized = newAPIError(http.StatusUnauthorized, "401 Unauthorized")
+
+	// personalAccessTokenScopes are the scopes of personal access tokens, resourceAccessTokenScopes the ones of
+	// project and group access tokens, see
+	// https://docs.gitlab.com/ee/user/profile/personal_access_tokens.html#personal-access-token-scopes.
+	personalAccessTokenScopes = []string{
+		"api", "read_api", "read_user", "create_runner", "manage_runner", "k8s_proxy", "read_repository",
+		"write_repository", "read_registry", "write_registry", "ai_features", "sudo", "admin_mode",
+		"read_service_ping", "self_rotate",
+	}
+	resourceAccessTokenScopes = []string{
+		"api", "read_api", "read_repository", "write_repository", "read_registry", "write_registry",
+		"create_runner", "manage_runner", "ai_features", "k8s_proxy", "self_rotate",
+	}
+)
+
+// newToken generates a random token with the prefix GitLab uses for the kind of token.
+func newToken(prefix string) string {
+	token := make([]byte, 16)
+	_, _ = rand.Read(token)
+
+	return prefix + hex.EncodeToString(token)
+}
+
+// AddPersonalAccessToken registers a personal access token for the user. As soon as the first token is
+// registered, requests to the mock have to authenticate with one of the registered tokens.
+func (mock *GitlabMock) AddPersonalAccessToken(user *gitlab.User, name string, token string, scopes []string) *gitlab.PersonalAccessToken {
+	id := int(mock.personalAccessTokenIds.Add(1))
+
+	createdAt := mock.now()
+
+	personalAccessToken := &gitlab.PersonalAccessToken{
+		ID:        id,
+		Name:      name,
+		CreatedAt: &createdAt,
+		Scopes:    scopes,
+		UserID:    user.ID,
+		Active:    true,
+		Token:     token,
+	}
+
+	mock.personalAccessTokens = append(mock.personalAccessTokens, personalAccessToken)
+
+	return personalAccessToken
+}
+
+// authenticationRequired reports whether requests have to carry a valid token. Mocks without any
+// registered personal access token or issued OAuth token accept every request anonymously.
+func (mock *GitlabMock) authenticationRequired() bool {
+	return len(mock.personalAccessTokens) > 0 || len(mock.oauthTokens) > 0
+}
+
+// authenticate resolves the user owning the token and the personal access token it is, which is nil for
+// OAuth tokens and CI_JOB_TOKENs. An empty token results in an anonymous (nil) user. Revoked and expired tokens
+// are rejected.
+func (mock *GitlabMock) authenticate(token string) (*gitlab.User, *gitlab.PersonalAccessToken, error) {
+	if token == "" || !mock.authenticationRequired() {
+		return nil, nil, nil
+	}
+
+	for _, personalAccessToken := range mock.personalAccessTokens {
+		if personalAccessToken.Token != token {
+			continue
+		}
+
+		if personalAccessToken.Revoked || mock.tokenExpired(personalAccessToken) {
+			return nil, nil, errUnauthorized
+		}
+
+		lastUsedAt := mock.now()
+		personalAccessToken.LastUsedAt = &lastUsedAt
+
+		user, err := mock.getUser(personalAccessToken.UserID)
+		if err != nil {
+			return nil, nil, errUnauthorized
+		}
+
+		return user, personalAccessToken, nil
+	}
+
+	if oauthToken, isOAuthToken := mock.oauthAccessToken(token); isOAuthToken {
+		if !mock.oauthTokenActive(oauthToken) {
+			return nil, nil, errUnauthorized
+		}
+
+		user, err := mock.getUser(oauthToken.userID)
+		if err != nil {
+			return nil, nil, errUnauthorized
+		}
+
+		return user, nil, nil
+	}
+
+	// Running jobs act on behalf of the user who started them with their CI_JOB_TOKEN. The tokens of finished
+	// jobs are rejected, only the runner endpoints accept them because they check the job themselves.
+	if job, isJobToken := mock.jobByToken(token); isJobToken {
+		if job.Status == string(gitlab.Running) {
+			return job.User, nil, nil
+		}
+
+		return nil, nil, errUnauthorized
+	}
+
+	return nil, nil, errUnauthorized
+}
+
+// tokenExpired reports whether the expiry date of the token has been reached on the clock of the mock. Tokens
+// expire at midnight UTC of their expiry date.
+func (mock *GitlabMock) tokenExpired(token *gitlab.PersonalAccessToken) bool {
+	return token.ExpiresAt != nil && !mock.now().Before(time.Time(*token.ExpiresAt))
+}
+
+// tokenScopes returns the scopes of the personal access token or the OAuth token the token is, scoped is false
+// for tokens without scopes like CI_JOB_TOKENs.
+func (mock *GitlabMock) tokenScopes(token string, personalAccessToken *gitlab.PersonalAccessToken) ([]string, bool) {
+	if personalAccessToken != nil {
+		return personalAccessToken.Scopes, true
+	}
+
+	if oauthToken, isOAuthToken := mock.oauthAccessToken(token); isOAuthToken {
+		return oauthToken.scopes, true
+	}
+
+	return nil, false
+}
+
+// tokenAllowsAPIRequest reports whether the scopes of a token permit the API request. The path is relative to
+// the API prefix, e.g. projects/1. Every token may read itself, the self_rotate scope allows a token to rotate
+// itself.
+func tokenAllowsAPIRequest(scopes []string, method string, path string) bool {
+	if slices.Contains(scopes, "api") {
+		return true
+	}
+
+	read := method == http.MethodGet || method == http.MethodHead
+
+	switch {
+	case read && path == "personal_access_tokens/self":
+		return true
+	case method == http.MethodPost && path == "personal_access_tokens/self/rotate":
+		return slices.Contains(scopes, "self_rotate")
+	case read && slices.Contains(scopes, "read_api"):
+		return true
+	case read && slices.Contains(scopes, "read_user"):
+		return path == "user" || path == "users" || strings.HasPrefix(path, "user/") || strings.HasPrefix(path, "users/")
+	}
+
+	return false
+}
+
+// tokenAllowsGit reports whether the scopes of a token permit the Git service, read_repository allows to pull
+// and write_repository to push and pull.
+func tokenAllowsGit(scopes []string, service string) bool {
+	if slices.Contains(scopes, "api") || slices.Contains(scopes, "write_repository") {
+		return true
+	}
+
+	return service == gitUploadPack && slices.Contains(scopes, "read_repository")
+}
+
+// validateTokenScopes checks that the scopes are given and all of them are valid.
+func validateTokenScopes(scopes *[]string, validScopes []string) ([]string, error) {
+	if scopes == nil || len(*scopes) == 0 {
+		return nil, newAPIError(http.StatusBadRequest, "scopes is missing")
+	}
+
+	for _, scope := range *scopes {
+		if !slices.Contains(validScopes, scope) {
+			return nil, newAPIError(http.StatusBadRequest, "scopes does not have a valid value")
+		}
+	}
+
+	return *scopes, nil
+}
+
+// validateTokenExpiry checks that the expiry date of a new token lies in the future.
+func (mock *GitlabMock) validateTokenExpiry(expiresAt *gitlab.ISOTime) error {
+	if expiresAt != nil && !mock.now().Before(time.Time(*expiresAt)) {
+		return newAPIError(http.StatusBadRequest, map[string][]string{"expires_at": {"must be in the future"}})
+	}
+
+	return nil
+}
+
+// createPersonalAccessToken creates a token with a generated value for the user, the scopes and expiry date
+// are not validated.
+func (mock *GitlabMock) createPersonalAccessToken(user *gitlab.User, name string, scopes []string, expiresAt *gitlab.ISOTime) *gitlab.PersonalAccessToken {
+	personalAccessToken := mock.AddPersonalAccessToken(user, name, newToken(personalAccessTokenPrefix), scopes)
+	personalAccessToken.ExpiresAt = expiresAt
+
+	return personalAccessToken
+}
+
+func (mock *GitlabMock) getPersonalAccessToken(tokenID int) (*gitlab.PersonalAccessToken, error) {
+	for _, personalAccessToken := range mock.personalAccessTokens {
+		if personalAccessToken.ID == tokenID {
+			return personalAccessToken, nil
+		}
+	}
+
+	return nil, newAPIError(http.StatusNotFound, "404 Not Found")
+}
+
+// visiblePersonalAccessToken returns the token without its value, which GitLab only shows when the token is
+// created or rotated. Expired tokens are inactive.
+func (mock *GitlabMock) visiblePersonalAccessToken(token *gitlab.PersonalAccessToken) *gitlab.PersonalAccessToken {
+	visibleToken := *token
+	visibleToken.Token = ""
+	visibleToken.Active = !token.Revoked && !mock.tokenExpired(token)
+
+	return &visibleToken
+}
+
+func (mock *GitlabMock) revokePersonalAccessToken(token *gitlab.PersonalAccessToken) {
+	token.Revoked = true
+	token.Active = false
+}
+
+// rotatePersonalAccessToken revokes the token and creates a new one with the same name and scopes, which
+// expires after a week unless the expiry date is given. Impersonation tokens are rotated to impersonation tokens.
+func (mock *GitlabMock) rotatePersonalAccessToken(token *gitlab.PersonalAccessToken, expiresAt *gitlab.ISOTime) (*gitlab.PersonalAccessToken, error) {
+	if token.Revoked {
+		return nil, newAPIError(http.StatusBadRequest, "400 Bad request - Token already revoked")
+	}
+
+	if err := mock.validateTokenExpiry(expiresAt); err != nil {
+		return nil, err
+	}
+
+	if expiresAt == nil {
+		nextWeek := gitlab.ISOTime(mock.now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 7))
+		expiresAt = &nextWeek
+	}
+
+	user, err := mock.getUser(token.UserID)
+	if err != nil {
+		return nil, newAPIError(http.StatusNotFound, "404 User Not Found")
+	}
+
+	mock.revokePersonalAccessToken(token)
+
+	rotatedToken := mock.createPersonalAccessToken(user, token.Name, token.Scopes, expiresAt)
+	if mock.impersonationTokens[token.ID] {
+		mock.impersonationTokens[rotatedToken.ID] = true
+	}
+
+	return rotatedToken, nil
+}
+
+// addBotUser creates the bot user of a project or group access token, e.g. project_1_bot_<random> for a token
+// of the project with ID 1.
+func (mock *GitlabMock) addBotUser(resource string, resourceID int, name string) (*gitlab.User, error) {
+	username := fmt.Sprintf("%s_%d_bot_%s", resource, resourceID, newToken(""))
+
+	bot, err := mock.AddUser(name, username, username+"@noreply.example.com")
+	if err != nil {
+		return nil, err
+	}
+
+	bot.Bot = true
+	bot.State = "active"
+
+	return bot, nil
+}
+
+// userTokens returns the personal access tokens of the user, oldest first.
+func (mock *GitlabMock) userTokens(userID int) []*gitlab.PersonalAccessToken {
+	tokens := []*gitlab.PersonalAccessToken{}
+
+	for _, personalAccessToken := range mock.personalAccessTokens {
+		if personalAccessToken.UserID == userID {
+			tokens = append(tokens, personalAccessToken)
+		}
+	}
+
+	return tokens
+}
+
+// validateResourceAccessToken checks the options of a new project or group access token. The access level
+// defaults to maintainer and may not exceed the maximum access level, which is the one of the user creating the
+// token.
+func (mock *GitlabMock) validateResourceAccessToken(name *string, scopes *[]string, accessLevel *gitlab.AccessLevelValue, expiresAt *gitlab.ISOTime, maxAccessLevel gitlab.AccessLevelValue) ([]string, gitlab.AccessLevelValue, error) {
+	if name == nil || *name == "" {
+		return nil, 0, newAPIError(http.StatusBadRequest, "name is missing")
+	}
+
+	validScopes, err := validateTokenScopes(scopes, resourceAccessTokenScopes)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if err := mock.validateTokenExpiry(expiresAt); err != nil {
+		return nil, 0, err
+	}
+
+	level := gitlab.MaintainerPermissions
+	if accessLevel != nil {
+		level = *accessLevel
+	}
+
+	if _, validLevel := accessLevelNames[level]; !validLevel || level == gitlab.MinimalAccessPermissions {
+		return nil, 0, newAPIError(http.StatusBadRequest, "access_level does not have a valid value")
+	}
+
+	if level > maxAccessLevel {
+		return nil, 0, newAPIError(http.StatusBadRequest, "400 Bad request - User does not have permission to create access token with the specified access level.")
+	}
+
+	return validScopes, level, nil
+}
+
+// CreateProjectAccessToken creates a project access token, whose bot user becomes a member of the project with
+// the access level. The author is the user creating the token, which is nil for the Go API.
+func (mock *GitlabMock) CreateProjectAccessToken(project *gitlab.Project, options *gitlab.CreateProjectAccessTokenOptions, author *gitlab.User) (*gitlab.ProjectAccessToken, error) {
+	maxAccessLevel := gitlab.OwnerPermissions
+	if author != nil {
+		maxAccessLevel = mock.projectAccessLevel(project, author)
+	}
+
+	scopes, accessLevel, err := mock.validateResourceAccessToken(options.Name, options.Scopes, options.AccessLevel, options.ExpiresAt, maxAccessLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	bot, err := mock.addBotUser("project", project.ID, *options.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	mock.addProjectMember(&gitlab.ProjectMember{
+		ID:          bot.ID,
+		Username:    bot.Username,
+		Email:       bot.Email,
+		Name:        bot.Name,
+		State:       bot.State,
+		AccessLevel: accessLevel,
+	}, project, author)
+
+	token := mock.createPersonalAccessToken(bot, *options.Name, scopes, options.ExpiresAt)
+
+	projectAccessToken := mock.projectAccessToken(project, token)
+	projectAccessToken.Token = token.Token
+
+	return projectAccessToken, nil
+}
+
+// projectAccessTokens returns the tokens of the bot users that are members of the project.
+func (mock *GitlabMock) projectAccessTokens(project *gitlab.Project) []*gitlab.ProjectAccessToken {
+	tokens := []*gitlab.ProjectAccessToken{}
+
+	for _, personalAccessToken := range mock.personalAccessTokens {
+		if mock.projectBotMember(project, personalAccessToken.UserID) != nil {
+			tokens = append(tokens, mock.projectAccessToken(project, personalAccessToken))
+		}
+	}
+
+	return tokens
+}
+
+func (mock *GitlabMock) projectBotMember(project *gitlab.Project, userID int) *gitlab.ProjectMember {
+	for _, member := range mock.projectMembers[project.ID] {
+		if member.ID == userID {
+			if user, err := mock.getUser(userID); err == nil && user.Bot {
+				return member
+			}
+		}
+	}
+
+	return nil
+}
+
+// getProjectAccessToken returns the token of a bot user of the project.
+func (mock *GitlabMock) getProjectAccessToken(project *gitlab.Project, tokenID int) (*gitlab.PersonalAccessToken, error) {
+	token, err := mock.getPersonalAccessToken(tokenID)
+	if err != nil || mock.projectBotMember(project, token.UserID) == nil {
+		return nil, newAPIError(http.StatusNotFound, fmt.Sprintf("404 Could not find project access token with token_id: %d Not Found", tokenID))
+	}
+
+	return token, nil
+}
+
+// projectAccessToken returns the project access token view of the token of a bot user of the project.
+func (mock *GitlabMock) projectAccessToken(project *gitlab.Project, token *gitlab.PersonalAccessToken) *gitlab.ProjectAccessToken {
+	visibleToken := mock.visiblePersonalAccessToken(token)
+
+	projectAccessToken := &gitlab.ProjectAccessToken{
+		ID:         visibleToken.ID,
+		UserID:     visibleToken.UserID,
+		Name:       visibleToken.Name,
+		Scopes:     visibleToken.Scopes,
+		CreatedAt:  visibleToken.CreatedAt,
+		LastUsedAt: visibleToken.LastUsedAt,
+		ExpiresAt:  visibleToken.ExpiresAt,
+		Active:     visibleToken.Active,
+		Revoked:    visibleToken.Revoked,
+	}
+
+	if member := mock.projectBotMember(project, token.UserID); member != nil {
+		projectAccessToken.AccessLevel = member.AccessLevel
+	}
+
+	return projectAccessToken
+}
+
+// rotateProjectAccessToken replaces the token of the bot user by a new one, the bot keeps its membership.
+func (mock *GitlabMock) rotateProjectAccessToken(project *gitlab.Project, token *gitlab.PersonalAccessToken, expiresAt *gitlab.ISOTime) (*gitlab.ProjectAccessToken, error) {
+	rotatedToken, err := mock.rotatePersonalAccessToken(token, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	projectAccessToken := mock.projectAccessToken(project, rotatedToken)
+	projectAccessToken.Token = rotatedToken.Token
+
+	return projectAccessToken, nil
+}
+
+// revokeProjectAccessToken revokes the token and removes its bot user from the members of the project.
+func (mock *GitlabMock) revokeProjectAccessToken(project *gitlab.Project, token *gitlab.PersonalAccessToken, author *gitlab.User) error {
+	mock.revokePersonalAccessToken(token)
+
+	return mock.removeProjectMember(token.UserID, project, author)
+}
+
+// CreateGroupAccessToken creates a group access token, whose bot user becomes a member of the group with the
+// access level.
+func (mock *GitlabMock) CreateGroupAccessToken(group *gitlab.Group, options *gitlab.CreateGroupAccessTokenOptions, author *gitlab.User) (*gitlab.GroupAccessToken, error) {
+	maxAccessLevel := gitlab.OwnerPermissions
+	if author != nil {
+		maxAccessLevel = mock.groupAccessLevel(group, author)
+	}
+
+	scopes, accessLevel, err := mock.validateResourceAccessToken(options.Name, options.Scopes, options.AccessLevel, options.ExpiresAt, maxAccessLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	bot, err := mock.addBotUser("group", group.ID, *options.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	mock.AddGroupMember(&gitlab.GroupMember{
+		ID:          bot.ID,
+		Username:    bot.Username,
+		Email:       bot.Email,
+		Name:        bot.Name,
+		State:       bot.State,
+		AccessLevel: accessLevel,
+	}, group)
+
+	token := mock.createPersonalAccessToken(bot, *options.Name, scopes, options.ExpiresAt)
+
+	groupAccessToken := mock.groupAccessToken(group, token)
+	groupAccessToken.Token = token.Token
+
+	return groupAccessToken, nil
+}
+
+// groupAccessTokens returns the tokens of the bot users that are direct members of the group.
+func (mock *GitlabMock) groupAccessTokens(group *gitlab.Group) []*gitlab.GroupAccessToken {
+	tokens := []*gitlab.GroupAccessToken{}
+
+	for _, personalAccessToken := range mock.personalAccessTokens {
+		if mock.groupBotMember(group, personalAccessToken.UserID) != nil {
+			tokens = append(tokens, mock.groupAccessToken(group, personalAccessToken))
+		}
+	}
+
+	return tokens
+}
+
+func (mock *GitlabMock) groupBotMember(group *gitlab.Group, userID int) *gitlab.GroupMember {
+	for _, member := range mock.groupMembers[group.ID] {
+		if member.ID == userID {
+			if user, err := mock.getUser(userID); err == nil && user.Bot {
+				return member
+			}
+		}
+	}
+
+	return nil
+}
+
+// getGroupAccessToken returns the token of a bot user of the group.
+func (mock *GitlabMock) getGroupAccessToken(group *gitlab.Group, tokenID int) (*gitlab.PersonalAccessToken, error) {
+	token, err := mock.getPersonalAccessToken(tokenID)
+	if err != nil || mock.groupBotMember(group, token.UserID) == nil {
+		return nil, newAPIError(http.StatusNotFound, fmt.Sprintf("404 Could not find group access token with token_id: %d Not Found", tokenID))
+	}
+
+	return token, nil
+}
+
+// groupAccessToken returns the group access token view of the token of a bot user of the group.
+func (mock *GitlabMock) groupAccessToken(group *gitlab.Group, token *gitlab.PersonalAccessToken) *gitlab.GroupAccessToken {
+	visibleToken := mock.visiblePersonalAccessToken(token)
+
+	groupAccessToken := &gitlab.GroupAccessToken{
+		ID:         visibleToken.ID,
+		UserID:     visibleToken.UserID,
+		Name:       visibleToken.Name,
+		Scopes:     visibleToken.Scopes,
+		CreatedAt:  visibleToken.CreatedAt,
+		LastUsedAt: visibleToken.LastUsedAt,
+		ExpiresAt:  visibleToken.ExpiresAt,
+		Active:     visibleToken.Active,
+		Revoked:    visibleToken.Revoked,
+	}
+
+	if member := mock.groupBotMember(group, token.UserID); member != nil {
+		groupAccessToken.AccessLevel = member.AccessLevel
+	}
+
+	return groupAccessToken
+}
+
+// rotateGroupAccessToken replaces the token of the bot user by a new one, the bot keeps its membership.
+func (mock *GitlabMock) rotateGroupAccessToken(group *gitlab.Group, token *gitlab.PersonalAccessToken, expiresAt *gitlab.ISOTime) (*gitlab.GroupAccessToken, error) {
+	rotatedToken, err := mock.rotatePersonalAccessToken(token, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	groupAccessToken := mock.groupAccessToken(group, rotatedToken)
+	groupAccessToken.Token = rotatedToken.Token
+
+	return groupAccessToken, nil
+}
+
+// revokeGroupAccessToken revokes the token and removes its bot user from the members of the group.
+func (mock *GitlabMock) revokeGroupAccessToken(group *gitlab.Group, token *gitlab.PersonalAccessToken) error {
+	mock.revokePersonalAccessToken(token)
+
+	return mock.RemoveGroupMember(token.UserID, group)
+}
+
+// createImpersonationToken validates the options and creates a token an admin uses to act as the user, see
+// https://docs.gitlab.com/ee/api/rest/authentication.html#impersonation-tokens. Impersonation tokens are personal
+// access tokens of the user and expire at the start of the day of their expiry date.
+func (mock *GitlabMock) createImpersonationToken(user *gitlab.User, options *gitlab.CreateImpersonationTokenOptions) (*gitlab.ImpersonationToken, error) {
+	if valueOf(options.Name) == "" {
+		return nil, newAPIError(http.StatusBadRequest, "name is missing")
+	}
+
+	scopes, err := validateTokenScopes(options.Scopes, personalAccessTokenScopes)
+	if err != nil {
+		return nil, err
+	}
+
+	var expiresAt *gitlab.ISOTime
+	if options.ExpiresAt != nil {
+		year, month, day := options.ExpiresAt.UTC().Date()
+		expiryDate := gitlab.ISOTime(time.Date(year, month, day, 0, 0, 0, 0, time.UTC))
+		expiresAt = &expiryDate
+	}
+
+	if err := mock.validateTokenExpiry(expiresAt); err != nil {
+		return nil, err
+	}
+
+	token := mock.createPersonalAccessToken(user, *options.Name, scopes, expiresAt)
+	mock.impersonationTokens[token.ID] = true
+
+	impersonationToken := mock.impersonationToken(token)
+	impersonationToken.Token = token.Token
+
+	return impersonationToken, nil
+}
+
+// userImpersonationTokens returns the impersonation tokens of the user, oldest first.
+func (mock *GitlabMock) userImpersonationTokens(user *gitlab.User) []*gitlab.PersonalAccessToken {
+	return slices.DeleteFunc(mock.userTokens(user.ID), func(token *gitlab.PersonalAccessToken) bool {
+		return !mock.impersonationTokens[token.ID]
+	})
+}
+
+func (mock *GitlabMock) getImpersonationToken(user *gitlab.User, tokenID int) (*gitlab.PersonalAccessToken, error) {
+	for _, token := range mock.userImpersonationTokens(user) {
+		if token.ID == tokenID {
+			return token, nil
+		}
+	}
+
+	return nil, newAPIError(http.StatusNotFound, "404 Impersonation Token Not Found")
+}
+
+// impersonationToken returns the view of an impersonation token without its value.
+func (mock *GitlabMock) impersonationToken(token *gitlab.PersonalAccessToken) *gitlab.ImpersonationToken {
+	return &gitlab.ImpersonationToken{
+		ID:         token.ID,
+		Name:       token.Name,
+		Active:     !token.Revoked && !mock.tokenExpired(token),
+		Scopes:     token.Scopes,
+		Revoked:    token.Revoked,
+		CreatedAt:  token.CreatedAt,
+		ExpiresAt:  token.ExpiresAt,
+		LastUsedAt: token.LastUsedAt,
+	}
+}
+
+// sudo resolves the user an admin acts as with the Sudo header or sudo parameter, either by ID or by username, see
+// https://docs.gitlab.com/ee/api/rest/authentication.html#sudo. Only personal access tokens and OAuth tokens may
+// sudo, their sudo scope is checked by the caller. Without authentication every user may be impersonated.
+func (mock *GitlabMock) sudo(user *gitlab.User, scoped bool, sudo string) (*gitlab.User, error) {
+	if mock.authenticationRequired() {
+		if user == nil || !scoped {
+			return nil, newAPIError(http.StatusForbidden, "403 Forbidden - Must be authenticated using an OAuth or Personal Access Token to use sudo")
+		}
+
+		if !user.IsAdmin {
+			return nil, newAPIError(http.StatusForbidden, "403 Forbidden - Must be admin to use sudo")
+		}
+	}
+
+	userID, err := strconv.Atoi(sudo)
+
+	for _, sudoUser := range mock.users {
+		if (err == nil && sudoUser.ID == userID) || sudoUser.Username == sudo {
+			return sudoUser, nil
+		}
+	}
+
+	return nil, newAPIError(http.StatusNotFound, fmt.Sprintf("404 User with ID or username '%s' Not Found", sudo))
+}
@@ -0,0 +1,120 @@
+package gitlabapimock
+
+import (
+	"net/http"
+	"slices"
+	"sort"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+const pipelineTriggerTokenPrefix = "glptt-"
+
+// AddPipelineTrigger creates a trigger token for the project, which pipelines can be created with on behalf
+// of the owner.
+func (mock *GitlabMock) AddPipelineTrigger(project *gitlab.Project, description string, owner *gitlab.User) *gitlab.PipelineTrigger {
+	createdAt := mock.now()
+
+	trigger := &gitlab.PipelineTrigger{
+		ID:          int(mock.pipelineTriggerIds.Add(1)),
+		Description: description,
+		CreatedAt:   &createdAt,
+		UpdatedAt:   &createdAt,
+		Token:       newToken(pipelineTriggerTokenPrefix),
+		Owner:       owner,
+	}
+
+	mock.pipelineTriggers[project.ID] = append(mock.pipelineTriggers[project.ID], trigger)
+
+	return trigger
+}
+
+func (mock *GitlabMock) getPipelineTrigger(project *gitlab.Project, triggerID int) (*gitlab.PipelineTrigger, error) {
+	for _, trigger := range mock.pipelineTriggers[project.ID] {
+		if trigger.ID == triggerID {
+			return trigger, nil
+		}
+	}
+
+	return nil, newAPIError(http.StatusNotFound, "404 Trigger Not Found")
+}
+
+// visiblePipelineTrigger hides all but the first four characters of the token from users who do not own the
+// trigger, as GitLab does.
+func (mock *GitlabMock) visiblePipelineTrigger(trigger *gitlab.PipelineTrigger, user *gitlab.User) *gitlab.PipelineTrigger {
+	if !mock.authenticationRequired() || (user != nil && trigger.Owner != nil && trigger.Owner.ID == user.ID) {
+		return trigger
+	}
+
+	visibleTrigger := *trigger
+	visibleTrigger.Token = trigger.Token[:4]
+
+	return &visibleTrigger
+}
+
+func (mock *GitlabMock) editPipelineTrigger(trigger *gitlab.PipelineTrigger, options *gitlab.EditPipelineTriggerOptions) {
+	if options.Description != nil {
+		trigger.Description = *options.Description
+	}
+
+	updatedAt := mock.now()
+	trigger.UpdatedAt = &updatedAt
+}
+
+func (mock *GitlabMock) takeOwnershipOfPipelineTrigger(trigger *gitlab.PipelineTrigger, user *gitlab.User) {
+	trigger.Owner = user
+
+	updatedAt := mock.now()
+	trigger.UpdatedAt = &updatedAt
+}
+
+func (mock *GitlabMock) removePipelineTrigger(project *gitlab.Project, trigger *gitlab.PipelineTrigger) {
+	mock.pipelineTriggers[project.ID] = slices.DeleteFunc(mock.pipelineTriggers[project.ID], func(other *gitlab.PipelineTrigger) bool {
+		return other.ID == trigger.ID
+	})
+}
+
+// triggerPipeline creates a pipeline for the ref with a trigger token of the project or the CI_JOB_TOKEN of a
+// running job. Pipelines of trigger tokens run as the owner of the trigger, pipelines of jobs as the user of
+// the job.
+func (mock *GitlabMock) triggerPipeline(project *gitlab.Project, token string, ref string, variables map[string]string) (*gitlab.Pipeline, error) {
+	var (
+		user   *gitlab.User
+		source string
+	)
+
+	if index := slices.IndexFunc(mock.pipelineTriggers[project.ID], func(trigger *gitlab.PipelineTrigger) bool {
+		return token != "" && trigger.Token == token
+	}); index >= 0 {
+		trigger := mock.pipelineTriggers[project.ID][index]
+
+		lastUsed := mock.now()
+		trigger.LastUsed = &lastUsed
+
+		user = trigger.Owner
+		source = "trigger"
+	} else if job, isJobToken := mock.jobByToken(token); isJobToken && job.Status == string(gitlab.Running) {
+		user = job.User
+		source = "pipeline"
+	} else {
+		return nil, newAPIError(http.StatusNotFound, "404 Not Found")
+	}
+
+	if !mock.hasAccess(project, user, gitlab.DeveloperPermissions) {
+		return nil, newAPIError(http.StatusForbidden, "403 Forbidden")
+	}
+
+	keys := make([]string, 0, len(variables))
+	for key := range variables {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	pipelineVariables := []*gitlab.PipelineVariable{}
+	for _, key := range keys {
+		pipelineVariables = append(pipelineVariables, &gitlab.PipelineVariable{Key: key, Value: variables[key], VariableType: gitlab.EnvVariableType})
+	}
+
+	return mock.createPipeline(project, ref, source, pipelineVariables, nil, user)
+}
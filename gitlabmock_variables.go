@@ -0,0 +1,252 @@
+package gitlabapimock
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+const (
+	allEnvironments       = "*"
+	maxVariableKeyLength  = 255
+	multipleVariablesHint = "There are multiple variables with provided parameters. Please use 'filter[environment_scope]'"
+)
+
+var (
+	variableKeyRegexp = regexp.MustCompile(`\A[a-zA-Z0-9_]+\z`)
+
+	// maskedValueRegexp and maskedRawValueRegexp are the rules GitLab applies to the values of masked
+	// variables, raw variables are not expanded and may therefore contain any character but whitespace.
+	maskedValueRegexp    = regexp.MustCompile(`\A[a-zA-Z0-9_+=/@:.~-]{8,}\z`)
+	maskedRawValueRegexp = regexp.MustCompile(`\A\S{8,}\z`)
+)
+
+// variableErrors collects the validation errors of a variable per attribute, as GitLab reports them.
+type variableErrors map[string][]string
+
+func (errs variableErrors) add(attribute string, message string) {
+	errs[attribute] = append(errs[attribute], message)
+}
+
+// validateVariable checks the variable against the rules of GitLab, including the uniqueness of its key
+// and environment scope among the other variables of the same level. The existing variable is skipped in
+// that check when an update is validated.
+func validateVariable(variables []*gitlab.ProjectVariable, variable *gitlab.ProjectVariable, existing *gitlab.ProjectVariable) error {
+	errs := variableErrors{}
+
+	switch {
+	case len(variable.Key) > maxVariableKeyLength:
+		errs.add("key", fmt.Sprintf("is too long (maximum is %d characters)", maxVariableKeyLength))
+	case !variableKeyRegexp.MatchString(variable.Key):
+		errs.add("key", "can contain only letters, digits and '_'.")
+	}
+
+	for _, other := range variables {
+		if other != existing && other.Key == variable.Key && other.EnvironmentScope == variable.EnvironmentScope {
+			errs.add("key", fmt.Sprintf("(%s) has already been taken", variable.Key))
+		}
+	}
+
+	if variable.Masked {
+		valueRegexp := maskedValueRegexp
+		if variable.Raw {
+			valueRegexp = maskedRawValueRegexp
+		}
+
+		if !valueRegexp.MatchString(variable.Value) {
+			errs.add("value", "is invalid")
+		}
+	}
+
+	if len(errs) > 0 {
+		return newAPIError(http.StatusBadRequest, errs)
+	}
+
+	return nil
+}
+
+// applyVariableOptions sets the attributes given by the options. The key can only be set on creation.
+func applyVariableOptions(variable *gitlab.ProjectVariable, options *gitlab.CreateProjectVariableOptions) error {
+	if options.VariableType != nil {
+		switch *options.VariableType {
+		case gitlab.EnvVariableType, gitlab.FileVariableType:
+			variable.VariableType = *options.VariableType
+		default:
+			return newAPIError(http.StatusBadRequest, "variable_type does not have a valid value")
+		}
+	}
+
+	if options.Value != nil {
+		variable.Value = *options.Value
+	}
+
+	if options.Description != nil {
+		variable.Description = *options.Description
+	}
+
+	if options.EnvironmentScope != nil {
+		variable.EnvironmentScope = *options.EnvironmentScope
+	}
+
+	if options.Protected != nil {
+		variable.Protected = *options.Protected
+	}
+
+	if options.Masked != nil {
+		variable.Masked = *options.Masked
+	}
+
+	if options.Raw != nil {
+		variable.Raw = *options.Raw
+	}
+
+	return nil
+}
+
+// newVariable validates the options and adds the resulting variable to the variables.
+func newVariable(variables *[]*gitlab.ProjectVariable, options *gitlab.CreateProjectVariableOptions) (*gitlab.ProjectVariable, error) {
+	if options.Key == nil {
+		return nil, newAPIError(http.StatusBadRequest, "key is missing")
+	}
+
+	if options.Value == nil {
+		return nil, newAPIError(http.StatusBadRequest, "value is missing")
+	}
+
+	variable := &gitlab.ProjectVariable{
+		Key:              *options.Key,
+		VariableType:     gitlab.EnvVariableType,
+		EnvironmentScope: allEnvironments,
+	}
+
+	err := applyVariableOptions(variable, options)
+	if err != nil {
+		return nil, err
+	}
+
+	err = validateVariable(*variables, variable, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	*variables = append(*variables, variable)
+
+	return variable, nil
+}
+
+// findVariable returns the variable with the key. Without an environment scope the key has to be unique.
+func findVariable(variables []*gitlab.ProjectVariable, key string, environmentScope string) (*gitlab.ProjectVariable, error) {
+	var found []*gitlab.ProjectVariable
+
+	for _, variable := range variables {
+		if variable.Key == key && (environmentScope == "" || variable.EnvironmentScope == environmentScope) {
+			found = append(found, variable)
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return nil, newAPIError(http.StatusNotFound, "404 Variable Not Found")
+	case 1:
+		return found[0], nil
+	}
+
+	return nil, newAPIError(http.StatusConflict, multipleVariablesHint)
+}
+
+// updateVariable applies the options to the variable. Invalid changes leave the variable untouched.
+func updateVariable(variables []*gitlab.ProjectVariable, variable *gitlab.ProjectVariable, options *gitlab.CreateProjectVariableOptions) (*gitlab.ProjectVariable, error) {
+	updatedVariable := *variable
+
+	err := applyVariableOptions(&updatedVariable, options)
+	if err != nil {
+		return nil, err
+	}
+
+	err = validateVariable(variables, &updatedVariable, variable)
+	if err != nil {
+		return nil, err
+	}
+
+	*variable = updatedVariable
+
+	return variable, nil
+}
+
+// removeVariable removes the variable from the variables.
+func removeVariable(variables *[]*gitlab.ProjectVariable, variable *gitlab.ProjectVariable) {
+	for i, other := range *variables {
+		if other == variable {
+			*variables = append((*variables)[:i], (*variables)[i+1:]...)
+			return
+		}
+	}
+}
+
+func toInstanceVariable(variable *gitlab.ProjectVariable) *gitlab.InstanceVariable {
+	return &gitlab.InstanceVariable{
+		Key:          variable.Key,
+		Value:        variable.Value,
+		VariableType: variable.VariableType,
+		Protected:    variable.Protected,
+		Masked:       variable.Masked,
+		Raw:          variable.Raw,
+		Description:  variable.Description,
+	}
+}
+
+func toGroupVariable(variable *gitlab.ProjectVariable) *gitlab.GroupVariable {
+	groupVariable := gitlab.GroupVariable(*variable)
+
+	return &groupVariable
+}
+
+// AddInstanceVariable adds a CI/CD variable that is available in all projects.
+func (mock *GitlabMock) AddInstanceVariable(options *gitlab.CreateInstanceVariableOptions) (*gitlab.InstanceVariable, error) {
+	variable, err := newVariable(&mock.instanceVariables, &gitlab.CreateProjectVariableOptions{
+		Key:          options.Key,
+		Value:        options.Value,
+		Description:  options.Description,
+		Masked:       options.Masked,
+		Protected:    options.Protected,
+		Raw:          options.Raw,
+		VariableType: options.VariableType,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return toInstanceVariable(variable), nil
+}
+
+// AddGroupVariable adds a CI/CD variable that is available in the projects of the group and its subgroups.
+func (mock *GitlabMock) AddGroupVariable(group *gitlab.Group, options *gitlab.CreateGroupVariableOptions) (*gitlab.GroupVariable, error) {
+	variables := mock.groupVariables[group.ID]
+
+	projectOptions := gitlab.CreateProjectVariableOptions(*options)
+
+	variable, err := newVariable(&variables, &projectOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	mock.groupVariables[group.ID] = variables
+
+	return toGroupVariable(variable), nil
+}
+
+// AddProjectVariable adds a CI/CD variable to the project.
+func (mock *GitlabMock) AddProjectVariable(project *gitlab.Project, options *gitlab.CreateProjectVariableOptions) (*gitlab.ProjectVariable, error) {
+	variables := mock.projectVariables[project.ID]
+
+	variable, err := newVariable(&variables, options)
+	if err != nil {
+		return nil, err
+	}
+
+	mock.projectVariables[project.ID] = variables
+
+	return variable, nil
+}